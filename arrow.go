@@ -258,6 +258,60 @@ func (b *RecordBatchBuilder) Build() (arrow.Record, error) {
 	return record, nil
 }
 
+// DictionaryStringType is the type of columns built by BuildDictionaryColumn:
+// int32 indices into a dictionary of distinct strings
+var DictionaryStringType = &arrow.DictionaryType{
+	IndexType: arrow.PrimitiveTypes.Int32,
+	ValueType: arrow.BinaryTypes.String,
+}
+
+// BuildDictionaryColumn builds a dictionary-encoded string column, storing each
+// distinct value once and an int32 index per row. This suits categorical columns
+// with few distinct values. Use DictionaryStringType as the field type.
+//
+// The caller is responsible for calling Release on the returned array.
+func BuildDictionaryColumn(values []string) arrow.Array {
+	builder := array.NewDictionaryBuilder(ArrowAllocator, DictionaryStringType).(*array.BinaryDictionaryBuilder)
+	defer builder.Release()
+
+	for _, value := range values {
+		// Only fails when the index type overflows, which int32 indices can't for a []string
+		_ = builder.AppendString(value)
+	}
+	return builder.NewArray()
+}
+
+// StringColumnValues returns the logical values of a string column, decoding
+// dictionary-encoded columns. Null entries are returned as empty strings.
+func StringColumnValues(arr arrow.Array) ([]string, error) {
+	values := make([]string, arr.Len())
+
+	switch col := arr.(type) {
+	case *array.String:
+		for i := range values {
+			values[i] = col.Value(i)
+		}
+	case *array.LargeString:
+		for i := range values {
+			values[i] = col.Value(i)
+		}
+	case *array.Dictionary:
+		dictionary, err := StringColumnValues(col.Dictionary())
+		if err != nil {
+			return nil, err
+		}
+		for i := range values {
+			if col.IsValid(i) {
+				values[i] = dictionary[col.GetValueIndex(i)]
+			}
+		}
+	default:
+		return nil, fmt.Errorf("column of type %s is not a string column", arr.DataType())
+	}
+
+	return values, nil
+}
+
 // RecordReader provides streaming access to record batches
 type RecordReader struct {
 	records []arrow.Record
@@ -114,6 +114,145 @@ func TestRecordBatchConversion(t *testing.T) {
 	}
 }
 
+func TestRecordBatchConversionBinaryTypes(t *testing.T) {
+	pool := memory.NewGoAllocator()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "blob", Type: arrow.BinaryTypes.Binary, Nullable: true},
+			{Name: "body", Type: arrow.BinaryTypes.LargeString, Nullable: true},
+		},
+		nil,
+	)
+
+	blob := make([]byte, 256)
+	for i := range blob {
+		blob[i] = byte(i)
+	}
+
+	blobBuilder := array.NewBinaryBuilder(pool, arrow.BinaryTypes.Binary)
+	defer blobBuilder.Release()
+	blobBuilder.Append(blob)
+	blobBuilder.AppendNull()
+	blobBuilder.Append([]byte{})
+	blobArray := blobBuilder.NewArray()
+	defer blobArray.Release()
+
+	bodyBuilder := array.NewLargeStringBuilder(pool)
+	defer bodyBuilder.Release()
+	bodyBuilder.Append("hello")
+	bodyBuilder.Append("")
+	bodyBuilder.AppendNull()
+	bodyArray := bodyBuilder.NewArray()
+	defer bodyArray.Release()
+
+	record := array.NewRecord(schema, []arrow.Array{blobArray, bodyArray}, 3)
+	defer record.Release()
+
+	cArray, cSchema, err := RecordToC(record)
+	if err != nil {
+		t.Fatalf("Failed to export record to C: %v", err)
+	}
+	defer ReleaseArrowArray(cArray)
+	defer ReleaseArrowSchema(cSchema)
+
+	importedRecord, err := RecordFromC(cArray, cSchema)
+	if err != nil {
+		t.Fatalf("Failed to import record from C: %v", err)
+	}
+	defer importedRecord.Release()
+
+	if !importedRecord.Schema().Equal(record.Schema()) {
+		t.Fatalf("Schema mismatch: expected %s, got %s", record.Schema(), importedRecord.Schema())
+	}
+	if !array.RecordEqual(importedRecord, record) {
+		t.Errorf("Record data mismatch after C round trip")
+	}
+}
+
+func TestBuildDictionaryColumn(t *testing.T) {
+	values := []string{"tech", "science", "tech", "art", "tech", "science"}
+
+	column := BuildDictionaryColumn(values)
+	defer column.Release()
+
+	dict, ok := column.(*array.Dictionary)
+	if !ok {
+		t.Fatalf("Expected a dictionary array, got %T", column)
+	}
+	if !arrow.TypeEqual(dict.DataType(), DictionaryStringType) {
+		t.Errorf("Expected type %s, got %s", DictionaryStringType, dict.DataType())
+	}
+	if dict.Dictionary().Len() != 3 {
+		t.Errorf("Expected 3 distinct values, got %d", dict.Dictionary().Len())
+	}
+
+	decoded, err := StringColumnValues(column)
+	if err != nil {
+		t.Fatalf("Failed to decode column: %v", err)
+	}
+	for i, value := range values {
+		if decoded[i] != value {
+			t.Errorf("Row %d: expected %q, got %q", i, value, decoded[i])
+		}
+	}
+}
+
+func TestDictionaryRecordConversion(t *testing.T) {
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "category", Type: DictionaryStringType, Nullable: false},
+		},
+		nil,
+	)
+
+	values := []string{"b", "a", "b", "c"}
+	column := BuildDictionaryColumn(values)
+	defer column.Release()
+
+	record := array.NewRecord(schema, []arrow.Array{column}, int64(len(values)))
+	defer record.Release()
+
+	cArray, cSchema, err := RecordToC(record)
+	if err != nil {
+		t.Fatalf("Failed to export record to C: %v", err)
+	}
+	defer ReleaseArrowArray(cArray)
+	defer ReleaseArrowSchema(cSchema)
+
+	importedRecord, err := RecordFromC(cArray, cSchema)
+	if err != nil {
+		t.Fatalf("Failed to import record from C: %v", err)
+	}
+	defer importedRecord.Release()
+
+	if importedRecord.Schema().Field(0).Type.ID() != arrow.DICTIONARY {
+		t.Errorf("Expected a dictionary column, got %s", importedRecord.Schema().Field(0).Type)
+	}
+
+	decoded, err := StringColumnValues(importedRecord.Column(0))
+	if err != nil {
+		t.Fatalf("Failed to decode column: %v", err)
+	}
+	for i, value := range values {
+		if decoded[i] != value {
+			t.Errorf("Row %d: expected %q, got %q", i, value, decoded[i])
+		}
+	}
+}
+
+func TestStringColumnValuesRejectsNonStrings(t *testing.T) {
+	builder := array.NewInt32Builder(memory.NewGoAllocator())
+	defer builder.Release()
+	builder.AppendValues([]int32{1, 2}, nil)
+	arr := builder.NewArray()
+	defer arr.Release()
+
+	if _, err := StringColumnValues(arr); err == nil {
+		t.Error("Expected error for an int32 column")
+	}
+}
+
 func TestRecordBatchBuilder(t *testing.T) {
 	pool := memory.NewGoAllocator()
 
@@ -0,0 +1,212 @@
+package lancedb
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/arrow/util"
+)
+
+const (
+	// DefaultBatchWriterMaxRows is the number of buffered rows that triggers a flush
+	DefaultBatchWriterMaxRows = 100_000
+	// DefaultBatchWriterMaxBytes is the buffered size in bytes that triggers a flush
+	DefaultBatchWriterMaxBytes = 64 << 20
+)
+
+// BatchWriterOptions configures a BatchWriter
+type BatchWriterOptions struct {
+	MaxRows  int64 // Rows buffered before they are written (default: 100,000)
+	MaxBytes int64 // Bytes buffered before they are written (default: 64 MiB)
+}
+
+// BatchWriter buffers records appended to a table and writes them in fewer, larger
+// adds, which is much faster than adding many small records one by one.
+// Buffered records are written once MaxRows or MaxBytes is reached, and by Flush and Close.
+// A BatchWriter is safe for concurrent use.
+//
+// Example:
+//
+//	writer := table.NewBatchWriter(nil)
+//	for _, record := range records {
+//		if err := writer.Append(record); err != nil {
+//			return err
+//		}
+//	}
+//	return writer.Close()
+type BatchWriter struct {
+	table *Table
+	opts  BatchWriterOptions
+
+	mu      sync.Mutex
+	schema  *arrow.Schema
+	pending []arrow.Record
+	rows    int64
+	bytes   int64
+	commits int
+	closed  bool
+}
+
+// NewBatchWriter creates a BatchWriter that appends to the table.
+// Pass nil to use the default thresholds.
+func (t *Table) NewBatchWriter(opts *BatchWriterOptions) *BatchWriter {
+	w := &BatchWriter{
+		table: t,
+		opts: BatchWriterOptions{
+			MaxRows:  DefaultBatchWriterMaxRows,
+			MaxBytes: DefaultBatchWriterMaxBytes,
+		},
+	}
+	if opts != nil {
+		if opts.MaxRows > 0 {
+			w.opts.MaxRows = opts.MaxRows
+		}
+		if opts.MaxBytes > 0 {
+			w.opts.MaxBytes = opts.MaxBytes
+		}
+	}
+	return w
+}
+
+// Append buffers a record, writing the buffered records if a threshold is reached.
+// The writer retains the record, so the caller may release it after Append returns.
+// Every record must have the same schema.
+func (w *BatchWriter) Append(record arrow.Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return &Error{Code: ErrorCodeClosed, Message: "batch writer is closed"}
+	}
+	if record.NumRows() == 0 {
+		return nil
+	}
+	if w.schema == nil {
+		w.schema = record.Schema()
+	} else if !w.schema.Equal(record.Schema()) {
+		return &Error{Code: ErrorCodeInvalidSchema, Message: fmt.Sprintf("record schema does not match the writer's schema: %s", record.Schema())}
+	}
+
+	record.Retain()
+	w.pending = append(w.pending, record)
+	w.rows += record.NumRows()
+	w.bytes += util.TotalRecordSize(record)
+
+	if w.rows >= w.opts.MaxRows || w.bytes >= w.opts.MaxBytes {
+		return w.flush()
+	}
+	return nil
+}
+
+// Flush writes all buffered records to the table in a single add.
+// If the write fails, the records stay buffered and Flush can be retried.
+func (w *BatchWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return &Error{Code: ErrorCodeClosed, Message: "batch writer is closed"}
+	}
+	return w.flush()
+}
+
+// Close writes any buffered records and closes the writer. Records that could not
+// be written are discarded, and the write error is returned. Close is safe to call
+// multiple times.
+func (w *BatchWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	err := w.flush()
+	w.release()
+	return err
+}
+
+// Commits returns the number of adds the writer has made to the table
+func (w *BatchWriter) Commits() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.commits
+}
+
+// Pending returns the number of buffered rows not yet written
+func (w *BatchWriter) Pending() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rows
+}
+
+// flush writes the buffered records; the caller must hold w.mu
+func (w *BatchWriter) flush() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	record := w.pending[0]
+	if len(w.pending) > 1 {
+		merged, err := concatenateRecords(w.schema, w.pending)
+		if err != nil {
+			return err
+		}
+		defer merged.Release()
+		record = merged
+	}
+
+	if err := w.table.Add(record, AddModeAppend); err != nil {
+		return err
+	}
+	w.commits++
+	w.release()
+	return nil
+}
+
+// release drops the buffered records; the caller must hold w.mu
+func (w *BatchWriter) release() {
+	for _, record := range w.pending {
+		record.Release()
+	}
+	w.pending = nil
+	w.rows = 0
+	w.bytes = 0
+}
+
+// concatenateRecords combines records with the same schema into a single record.
+// The caller must release the returned record.
+func concatenateRecords(schema *arrow.Schema, records []arrow.Record) (arrow.Record, error) {
+	mem := memory.NewGoAllocator()
+	columns := make([]arrow.Array, schema.NumFields())
+	defer func() {
+		for _, column := range columns {
+			if column != nil {
+				column.Release()
+			}
+		}
+	}()
+
+	var rows int64
+	for _, record := range records {
+		rows += record.NumRows()
+	}
+
+	chunks := make([]arrow.Array, len(records))
+	for i := range columns {
+		for j, record := range records {
+			chunks[j] = record.Column(i)
+		}
+		column, err := array.Concatenate(chunks, mem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to concatenate column %s: %w", schema.Field(i).Name, err)
+		}
+		columns[i] = column
+	}
+
+	return array.NewRecord(schema, columns, rows), nil
+}
@@ -0,0 +1,195 @@
+package lancedb
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+var batchWriterSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "name", Type: arrow.BinaryTypes.String},
+}, nil)
+
+// createBatchWriterTable creates an empty table with batchWriterSchema
+func createBatchWriterTable(t *testing.T) (*Connection, *Table) {
+	db, err := Connect(filepath.Join(t.TempDir(), "batch_db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	table, err := db.CreateTableWithSchema("batch_table", batchWriterSchema)
+	if err != nil {
+		db.Close()
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	return db, table
+}
+
+// makeBatchRecord builds a record with ids [start, start+n)
+func makeBatchRecord(start, n int64) arrow.Record {
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), batchWriterSchema)
+	defer builder.Release()
+
+	for i := start; i < start+n; i++ {
+		builder.Field(0).(*array.Int64Builder).Append(i)
+		builder.Field(1).(*array.StringBuilder).Append("row")
+	}
+	return builder.NewRecord()
+}
+
+// TestBatchWriter tests that many small appends are written in a few large adds
+func TestBatchWriter(t *testing.T) {
+	db, table := createBatchWriterTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	const totalRows = 100_000
+	const rowsPerRecord = 1_000
+
+	writer := table.NewBatchWriter(&BatchWriterOptions{MaxRows: 30_000})
+	for start := int64(0); start < totalRows; start += rowsPerRecord {
+		record := makeBatchRecord(start, rowsPerRecord)
+		err := writer.Append(record)
+		record.Release()
+		if err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// 100k rows with a 30k threshold leaves 10k rows for Close to write
+	if pending := writer.Pending(); pending != 10_000 {
+		t.Errorf("Expected 10000 pending rows, got %d", pending)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	count, err := table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != totalRows {
+		t.Errorf("Expected %d rows, got %d", totalRows, count)
+	}
+
+	commits := writer.Commits()
+	if commits >= totalRows/rowsPerRecord {
+		t.Errorf("Expected fewer than %d adds, got %d", totalRows/rowsPerRecord, commits)
+	}
+	if commits != 4 {
+		t.Errorf("Expected 4 adds, got %d", commits)
+	}
+
+	// The writer can't be used after Close
+	record := makeBatchRecord(0, 1)
+	defer record.Release()
+	if err := writer.Append(record); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Errorf("Second Close failed: %v", err)
+	}
+}
+
+// TestBatchWriterConcurrent tests appending from several goroutines
+func TestBatchWriterConcurrent(t *testing.T) {
+	db, table := createBatchWriterTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	writer := table.NewBatchWriter(&BatchWriterOptions{MaxRows: 5_000})
+
+	var wg sync.WaitGroup
+	for g := int64(0); g < 8; g++ {
+		wg.Add(1)
+		go func(g int64) {
+			defer wg.Done()
+			for i := int64(0); i < 10; i++ {
+				record := makeBatchRecord(g*10_000+i*100, 100)
+				if err := writer.Append(record); err != nil {
+					t.Errorf("Append failed: %v", err)
+				}
+				record.Release()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if pending := writer.Pending(); pending != 0 {
+		t.Errorf("Expected no pending rows after Flush, got %d", pending)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	count, err := table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 8_000 {
+		t.Errorf("Expected 8000 rows, got %d", count)
+	}
+}
+
+// TestBatchWriterSchemaMismatch tests that records with another schema are rejected
+func TestBatchWriterSchemaMismatch(t *testing.T) {
+	db, table := createBatchWriterTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	writer := table.NewBatchWriter(nil)
+	defer writer.Close()
+
+	record := makeBatchRecord(0, 10)
+	defer record.Release()
+	if err := writer.Append(record); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	other := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int32}}, nil)
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), other)
+	defer builder.Release()
+	builder.Field(0).(*array.Int32Builder).Append(1)
+	mismatched := builder.NewRecord()
+	defer mismatched.Release()
+
+	if err := writer.Append(mismatched); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("Expected ErrInvalidSchema, got %v", err)
+	}
+	if pending := writer.Pending(); pending != 10 {
+		t.Errorf("Expected 10 pending rows, got %d", pending)
+	}
+}
+
+// TestConcatenateRecords tests merging buffered records into one
+func TestConcatenateRecords(t *testing.T) {
+	first := makeBatchRecord(0, 3)
+	defer first.Release()
+	second := makeBatchRecord(3, 2)
+	defer second.Release()
+
+	merged, err := concatenateRecords(batchWriterSchema, []arrow.Record{first, second})
+	if err != nil {
+		t.Fatalf("concatenateRecords failed: %v", err)
+	}
+	defer merged.Release()
+
+	if merged.NumRows() != 5 {
+		t.Fatalf("Expected 5 rows, got %d", merged.NumRows())
+	}
+	ids := merged.Column(0).(*array.Int64)
+	for i := 0; i < 5; i++ {
+		if ids.Value(i) != int64(i) {
+			t.Errorf("Row %d: expected id %d, got %d", i, i, ids.Value(i))
+		}
+	}
+}
@@ -5,14 +5,24 @@
 //
 //	go run github.com/aqua777/go-lancedb/cmd/lancedb-install@latest
 //	go run github.com/aqua777/go-lancedb/cmd/lancedb-install@latest --version v0.0.7
+//	go run github.com/aqua777/go-lancedb/cmd/lancedb-install@latest --skip-checksum
+//	go run github.com/aqua777/go-lancedb/cmd/lancedb-install@latest --base-url https://artifacts.example.com/lancedb
+//
+// Release assets are downloaded from {base-url}/{version}/{asset}. The base URL defaults to
+// the GitHub releases of this repository and can also be set with the LANCEDB_MIRROR environment
+// variable. HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honored.
 //
 // The installer:
 // 1. Downloads the correct library for your OS/architecture to $GOPATH/lib/lancedb/{os}-{arch}/
-// 2. Generates a lancedb.pc file in $GOPATH/lib/pkgconfig/
-// 3. Outputs instructions to set PKG_CONFIG_PATH
+// 2. Verifies it against the SHA256 checksum published with the release
+// 3. Generates a lancedb.pc file in $GOPATH/lib/pkgconfig/
+// 4. Outputs instructions to set PKG_CONFIG_PATH
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -20,26 +30,118 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"time"
 )
 
 const (
 	repoOwner     = "aqua777"
 	repoName      = "go-lancedb"
-	libraryName   = "liblancedb_cgo.a"
 	latestVersion = "v0.0.7" // Updated when new releases are made
 )
 
 // Platform-specific linker flags (excluding the library path which we add dynamically)
 var platformFlags = map[string]string{
-	"darwin-arm64": "-lm -ldl -lresolv -framework CoreFoundation -framework Security -framework SystemConfiguration",
-	"darwin-amd64": "-lm -ldl -lresolv -framework CoreFoundation -framework Security -framework SystemConfiguration",
-	"linux-arm64":  "-lm -ldl -lpthread",
-	"linux-amd64":  "-lm -ldl -lpthread",
+	"darwin-arm64":  "-lm -ldl -lresolv -framework CoreFoundation -framework Security -framework SystemConfiguration",
+	"darwin-amd64":  "-lm -ldl -lresolv -framework CoreFoundation -framework Security -framework SystemConfiguration",
+	"linux-arm64":   "-lm -ldl -lpthread",
+	"linux-amd64":   "-lm -ldl -lpthread",
+	"windows-arm64": "-lws2_32 -luserenv -lbcrypt -lntdll -ladvapi32 -lcrypt32 -lsecur32 -lncrypt -lole32 -loleaut32 -luser32 -lkernel32",
+	"windows-amd64": "-lws2_32 -luserenv -lbcrypt -lntdll -ladvapi32 -lcrypt32 -lsecur32 -lncrypt -lole32 -loleaut32 -luser32 -lkernel32",
+}
+
+// mirrorEnvVar names the environment variable that overrides the release base URL
+const mirrorEnvVar = "LANCEDB_MIRROR"
+
+// defaultBaseURL is where release assets are downloaded from unless a mirror is configured
+var defaultBaseURL = fmt.Sprintf("https://github.com/%s/%s/releases/download", repoOwner, repoName)
+
+// errAssetNotFound is returned when a release has no asset at the download URL
+var errAssetNotFound = errors.New("release asset not found")
+
+// errVerificationFailed is returned when a complete download fails verification
+var errVerificationFailed = errors.New("verification failed")
+
+// minLibrarySize is the smallest plausible library; smaller downloads are likely Git LFS pointers
+const minLibrarySize = 10000
+
+// libraryFileName returns the installed library's file name.
+// Windows uses the name.lib convention, which MinGW ld and lld both find for -llancedb_cgo.
+func libraryFileName(goos string) string {
+	if goos == "windows" {
+		return "lancedb_cgo.lib"
+	}
+	return "liblancedb_cgo.a"
+}
+
+// assetName returns the release asset name of the library for a platform
+func assetName(platform string) string {
+	if strings.HasPrefix(platform, "windows-") {
+		return fmt.Sprintf("lancedb_cgo-%s.lib", platform)
+	}
+	return fmt.Sprintf("liblancedb_cgo-%s.a", platform)
+}
+
+// releaseAssetURL returns the download URL of the library for a version and platform
+func releaseAssetURL(baseURL, version, platform string) string {
+	return fmt.Sprintf("%s/%s/%s", strings.TrimRight(baseURL, "/"), version, assetName(platform))
+}
+
+// resolveBaseURL returns the release base URL: the --base-url flag if set,
+// then the LANCEDB_MIRROR environment variable, then GitHub releases
+func resolveBaseURL(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if mirror := os.Getenv(mirrorEnvVar); mirror != "" {
+		return mirror
+	}
+	return defaultBaseURL
+}
+
+// newHTTPClient returns the client used for all downloads.
+// Proxies are taken from HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			TLSHandshakeTimeout:   30 * time.Second,
+			ResponseHeaderTimeout: 60 * time.Second,
+		},
+	}
+}
+
+// supportedPlatforms returns the platform keys in sorted order
+func supportedPlatforms() []string {
+	platforms := make([]string, 0, len(platformFlags))
+	for platform := range platformFlags {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+// pkgConfigContent returns the lancedb.pc file contents for a platform.
+// pkg-config treats backslashes as escapes, so Windows paths use forward slashes and spaces are escaped.
+func pkgConfigContent(version, libDir, platform string) string {
+	if strings.HasPrefix(platform, "windows-") {
+		libDir = strings.ReplaceAll(libDir, `\`, "/")
+	}
+	libDir = strings.ReplaceAll(libDir, " ", `\ `)
+
+	return fmt.Sprintf(`Name: lancedb
+Description: LanceDB Static Library
+Version: %s
+Libs: -L%s -llancedb_cgo %s
+Cflags: 
+`, version, libDir, platformFlags[platform])
 }
 
 func main() {
 	version := flag.String("version", latestVersion, "Version to download (e.g., v0.0.7)")
+	skipChecksum := flag.Bool("skip-checksum", false, "Skip SHA256 verification (for mirrors without .sha256 files)")
+	baseURLFlag := flag.String("base-url", "", "Release base URL, e.g. an internal mirror (default: $"+mirrorEnvVar+" or GitHub releases)")
 	flag.Parse()
 
 	goos := runtime.GOOS
@@ -49,7 +151,7 @@ func main() {
 	// Validate platform
 	if _, ok := platformFlags[platform]; !ok {
 		fmt.Fprintf(os.Stderr, "Error: Unsupported platform: %s\n", platform)
-		fmt.Fprintf(os.Stderr, "Supported platforms: darwin-arm64, darwin-amd64, linux-arm64, linux-amd64\n")
+		fmt.Fprintf(os.Stderr, "Supported platforms: %s\n", strings.Join(supportedPlatforms(), ", "))
 		os.Exit(1)
 	}
 
@@ -67,7 +169,7 @@ func main() {
 
 	// Library Install Path
 	libDir := filepath.Join(gopath, "lib", "lancedb", platform)
-	libPath := filepath.Join(libDir, libraryName)
+	libPath := filepath.Join(libDir, libraryFileName(goos))
 
 	// PkgConfig Install Path
 	pkgConfigDir := filepath.Join(gopath, "lib", "pkgconfig")
@@ -84,51 +186,77 @@ func main() {
 	}
 
 	// Construct download URL
-	downloadURL := fmt.Sprintf(
-		"https://github.com/%s/%s/releases/download/%s/liblancedb_cgo-%s.a",
-		repoOwner, repoName, *version, platform,
-	)
+	baseURL := resolveBaseURL(*baseURLFlag)
+	downloadURL := releaseAssetURL(baseURL, *version, platform)
+	client := newHTTPClient()
 
 	fmt.Printf("Downloading LanceDB library for %s...\n", platform)
+	if baseURL != defaultBaseURL {
+		fmt.Printf("Mirror: %s\n", baseURL)
+	}
 	fmt.Printf("URL: %s\n", downloadURL)
 
-	// Download the library
-	if err := downloadFile(libPath, downloadURL); err != nil {
+	// Download the library. It only replaces an installed library once it has been verified.
+	checksumURL := downloadURL + ".sha256"
+	var size int64
+	tooSmall := false
+	verify := func(tmpPath string) error {
+		info, err := os.Stat(tmpPath)
+		if err != nil {
+			return fmt.Errorf("cannot stat downloaded file: %w", err)
+		}
+		size = info.Size()
+		if size < minLibrarySize {
+			tooSmall = true
+			return fmt.Errorf("downloaded file is suspiciously small (%d bytes)", size)
+		}
+		if *skipChecksum {
+			return nil
+		}
+		return verifyChecksum(client, tmpPath, checksumURL)
+	}
+	if err := downloadFile(client, libPath, downloadURL, os.Stdout, verify); err != nil {
+		if errors.Is(err, errVerificationFailed) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if tooSmall {
+				fmt.Fprintf(os.Stderr, "This might be a Git LFS pointer file, not the actual library.\n")
+				fmt.Fprintf(os.Stderr, "Please check the release assets at:\n")
+				fmt.Fprintf(os.Stderr, "  https://github.com/%s/%s/releases/tag/%s\n", repoOwner, repoName, *version)
+			} else {
+				fmt.Fprintf(os.Stderr, "The downloaded library was discarded. It may be corrupted or tampered with.\n")
+				fmt.Fprintf(os.Stderr, "If you are installing from a mirror without checksum files, re-run with --skip-checksum.\n")
+			}
+			os.Exit(1)
+		}
 		fmt.Fprintf(os.Stderr, "Error: Download failed: %v\n", err)
+		if goos == "windows" && errors.Is(err, errAssetNotFound) {
+			fmt.Fprintf(os.Stderr, "\nRelease %s has no Windows library for %s.\n", *version, goarch)
+			fmt.Fprintf(os.Stderr, "Build it from source with the x86_64-pc-windows-gnu (or aarch64-pc-windows-gnullvm) Rust target:\n")
+			fmt.Fprintf(os.Stderr, "  https://github.com/%s/%s/blob/main/USAGE_AS_DEPENDENCY.md#building-from-source\n", repoOwner, repoName)
+			os.Exit(1)
+		}
 		fmt.Fprintf(os.Stderr, "\nPossible causes:\n")
 		fmt.Fprintf(os.Stderr, "  - Version %s doesn't exist\n", *version)
 		fmt.Fprintf(os.Stderr, "  - Binary for %s not available in this release\n", platform)
-		fmt.Fprintf(os.Stderr, "  - Network connectivity issues\n")
+		fmt.Fprintf(os.Stderr, "  - Network connectivity issues (check HTTP_PROXY/HTTPS_PROXY)\n")
+		if baseURL != defaultBaseURL {
+			fmt.Fprintf(os.Stderr, "  - The mirror %s doesn't host this release\n", baseURL)
+		}
 		fmt.Fprintf(os.Stderr, "\nCheck available releases at:\n")
 		fmt.Fprintf(os.Stderr, "  https://github.com/%s/%s/releases\n", repoOwner, repoName)
 		os.Exit(1)
 	}
 
-	// Verify download
-	info, err := os.Stat(libPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Cannot stat downloaded file: %v\n", err)
-		os.Exit(1)
-	}
-
-	if info.Size() < 10000 {
-		fmt.Fprintf(os.Stderr, "Warning: Downloaded file is suspiciously small (%d bytes).\n", info.Size())
-		fmt.Fprintf(os.Stderr, "This might be a Git LFS pointer file, not the actual library.\n")
-		fmt.Fprintf(os.Stderr, "Please check the release assets at:\n")
-		fmt.Fprintf(os.Stderr, "  https://github.com/%s/%s/releases/tag/%s\n", repoOwner, repoName, *version)
-		os.Exit(1)
+	if *skipChecksum {
+		fmt.Printf("Skipped checksum verification\n")
+	} else {
+		fmt.Printf("Checksum verified (SHA256)\n")
 	}
 
-	fmt.Printf("Library installed to: %s (%d MB)\n", libPath, info.Size()/(1024*1024))
+	fmt.Printf("Library installed to: %s (%d MB)\n", libPath, size/(1024*1024))
 
 	// Generate pkg-config file
-	flags := platformFlags[platform]
-	pcContent := fmt.Sprintf(`Name: lancedb
-Description: LanceDB Static Library
-Version: %s
-Libs: -L%s -llancedb_cgo %s
-Cflags: 
-`, *version, libDir, flags)
+	pcContent := pkgConfigContent(*version, libDir, platform)
 
 	if err := os.WriteFile(pkgConfigPath, []byte(pcContent), 0644); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: Cannot write pkg-config file: %v\n", err)
@@ -140,51 +268,276 @@ Cflags:
 	fmt.Printf("\n%s\n", strings.Repeat("=", 60))
 	fmt.Printf("Setup Instructions:\n")
 	fmt.Printf("%s\n\n", strings.Repeat("=", 60))
-	fmt.Printf("1. Add this to your shell profile (~/.bashrc, ~/.zshrc, etc.):\n\n")
-	fmt.Printf("   export PKG_CONFIG_PATH=\"$PKG_CONFIG_PATH:%s\"\n\n", pkgConfigDir)
-	fmt.Printf("2. Apply changes (or open a new terminal):\n\n")
-	fmt.Printf("   source ~/.zshrc  # or ~/.bashrc\n\n")
+	if goos == "windows" {
+		fmt.Printf("1. Add the pkg-config directory to your user environment (PowerShell):\n\n")
+		fmt.Printf("   [Environment]::SetEnvironmentVariable(\"PKG_CONFIG_PATH\", \"%s\", \"User\")\n\n", pkgConfigDir)
+		fmt.Printf("2. Open a new terminal so the change takes effect\n\n")
+	} else {
+		fmt.Printf("1. Add this to your shell profile (~/.bashrc, ~/.zshrc, etc.):\n\n")
+		fmt.Printf("   export PKG_CONFIG_PATH=\"$PKG_CONFIG_PATH:%s\"\n\n", pkgConfigDir)
+		fmt.Printf("2. Apply changes (or open a new terminal):\n\n")
+		fmt.Printf("   source ~/.zshrc  # or ~/.bashrc\n\n")
+	}
 	fmt.Printf("3. Build your project:\n\n")
 	fmt.Printf("   go build ./...\n")
 	fmt.Printf("\n%s\n", strings.Repeat("=", 60))
 }
 
-func downloadFile(filepath string, url string) error {
-	// Create temporary file
-	tmpPath := filepath + ".tmp"
-	out, err := os.Create(tmpPath)
+// downloadFile downloads url to path, printing a progress bar to progress.
+// Data is written to the temp file partialDownloadPath(path, url) first; if a previous run
+// left a partial download of the same URL, the download resumes from its end with an HTTP
+// range request. Partial downloads of other URLs (e.g., another version) are discarded.
+// If verify isn't nil, it is called with the complete temp file before it is renamed to path;
+// if it fails, the temp file is removed, path is left untouched and the error wraps
+// errVerificationFailed.
+func downloadFile(client *http.Client, path string, url string, progress io.Writer, verify func(tmpPath string) error) error {
+	tmpPath := partialDownloadPath(path, url)
+	discardOtherPartialDownloads(path, tmpPath)
+
+	// Resume from a partial download if there is one
+	var offset int64
+	if info, err := os.Stat(tmpPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return fmt.Errorf("cannot create file: %w", err)
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
 	}
-	defer out.Close()
 
-	// Download
-	resp, err := http.Get(url)
+	resp, err := client.Do(req)
 	if err != nil {
-		os.Remove(tmpPath)
 		return fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		os.Remove(tmpPath)
+	var total int64 // expected size of the complete file, or -1 if unknown
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil || start != offset {
+			os.Remove(tmpPath)
+			return fmt.Errorf("server returned an unexpected range %q; partial download discarded, please retry", resp.Header.Get("Content-Range"))
+		}
+		total = size
+		flags |= os.O_APPEND
+		fmt.Fprintf(progress, "Resuming download at %s\n", formatBytes(offset))
+	case http.StatusOK:
+		// Full content (the server may not support ranges), so start over
+		offset = 0
+		total = resp.ContentLength
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is not a prefix of this asset; discard it and start over
+		resp.Body.Close()
+		if err := os.Remove(tmpPath); err != nil {
+			return fmt.Errorf("cannot remove partial download: %w", err)
+		}
+		return downloadFile(client, path, url, progress, verify)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: HTTP %d: %s", errAssetNotFound, resp.StatusCode, resp.Status)
+	default:
 		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
 	}
 
-	// Copy with progress indication
-	_, err = io.Copy(out, resp.Body)
+	out, err := os.OpenFile(tmpPath, flags, 0644)
 	if err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("download interrupted: %w", err)
+		return fmt.Errorf("cannot create file: %w", err)
 	}
+	defer out.Close()
 
-	out.Close()
+	// Copy with progress indication. On failure the partial file is kept so a re-run can resume.
+	bar := &progressBar{out: progress, current: offset, total: total}
+	_, err = io.Copy(io.MultiWriter(out, bar), resp.Body)
+	bar.finish()
+	if err != nil {
+		return fmt.Errorf("download interrupted at %s (re-run to resume): %w", formatBytes(bar.current), err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("cannot write file: %w", err)
+	}
+
+	// Verify the complete file has the advertised size before installing it
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		return fmt.Errorf("cannot stat downloaded file: %w", err)
+	}
+	if total >= 0 && info.Size() != total {
+		if info.Size() > total {
+			os.Remove(tmpPath)
+		}
+		return fmt.Errorf("incomplete download: got %d bytes, expected %d", info.Size(), total)
+	}
+
+	if verify != nil {
+		if err := verify(tmpPath); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("%w: %w", errVerificationFailed, err)
+		}
+	}
 
 	// Rename temp file to final destination
-	if err := os.Rename(tmpPath, filepath); err != nil {
+	if err := os.Rename(tmpPath, path); err != nil {
 		os.Remove(tmpPath)
 		return fmt.Errorf("cannot rename file: %w", err)
 	}
 
 	return nil
 }
+
+// partialDownloadPath returns the temp file a download of url to path is written to.
+// It is keyed by the URL, which includes the release version, so a partial download of
+// one version is never resumed as another.
+func partialDownloadPath(path, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return fmt.Sprintf("%s.%s.tmp", path, hex.EncodeToString(sum[:8]))
+}
+
+// discardOtherPartialDownloads removes partial downloads to path other than keep
+func discardOtherPartialDownloads(path, keep string) {
+	matches, _ := filepath.Glob(path + ".*.tmp")
+	// Earlier installers wrote the partial download to path + ".tmp"
+	matches = append(matches, path+".tmp")
+	for _, match := range matches {
+		if match != keep {
+			os.Remove(match)
+		}
+	}
+}
+
+// parseContentRange parses a "bytes start-end/size" Content-Range header
+func parseContentRange(header string) (start int64, size int64, err error) {
+	var end int64
+	if _, err := fmt.Sscanf(header, "bytes %d-%d/%d", &start, &end, &size); err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q: %w", header, err)
+	}
+	if start < 0 || end < start || end >= size {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	return start, size, nil
+}
+
+// progressBar prints download progress as data is written through it
+type progressBar struct {
+	out        io.Writer
+	current    int64     // bytes downloaded, including any resumed part
+	total      int64     // expected total bytes, or -1 if unknown
+	lastRender time.Time // throttles redraws
+}
+
+// progressBarWidth is the number of characters in the bar
+const progressBarWidth = 40
+
+func (p *progressBar) Write(b []byte) (int, error) {
+	p.current += int64(len(b))
+	if time.Since(p.lastRender) >= 100*time.Millisecond {
+		p.render()
+	}
+	return len(b), nil
+}
+
+// render redraws the progress line in place
+func (p *progressBar) render() {
+	p.lastRender = time.Now()
+	if p.total <= 0 {
+		fmt.Fprintf(p.out, "\r  %s", formatBytes(p.current))
+		return
+	}
+
+	filled := int(p.current * progressBarWidth / p.total)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	fmt.Fprintf(p.out, "\r  [%s%s] %s / %s (%d%%)",
+		strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled),
+		formatBytes(p.current), formatBytes(p.total), p.current*100/p.total)
+}
+
+// finish draws the final state and ends the progress line
+func (p *progressBar) finish() {
+	p.render()
+	fmt.Fprintln(p.out)
+}
+
+// formatBytes formats a byte count for display (e.g. "12.3 MB")
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// verifyChecksum compares the SHA256 of the file at path with the checksum published at checksumURL
+func verifyChecksum(client *http.Client, path string, checksumURL string) error {
+	expected, err := fetchChecksum(client, checksumURL)
+	if err != nil {
+		return err
+	}
+
+	actual, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+
+	if actual != expected {
+		return fmt.Errorf("SHA256 mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
+
+// fetchChecksum downloads a checksum file and returns the hex digest it contains.
+// Both a bare digest and the "digest  filename" format of sha256sum are accepted.
+func fetchChecksum(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("cannot download checksum: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cannot download checksum from %s: HTTP %d: %s", url, resp.StatusCode, resp.Status)
+	}
+
+	// Checksum files are a single line; anything larger is not a checksum file
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", fmt.Errorf("cannot read checksum: %w", err)
+	}
+
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("checksum file %s is empty", url)
+	}
+
+	digest := strings.ToLower(fields[0])
+	if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("checksum file %s does not contain a SHA256 digest", url)
+	}
+	return digest, nil
+}
+
+// fileSHA256 returns the hex-encoded SHA256 digest of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot open file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("cannot read file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newReleaseServer serves a library file and a checksum file, like a GitHub release
+func newReleaseServer(t *testing.T, library []byte, checksum string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/liblancedb_cgo-linux-amd64.a", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(library)
+	})
+	mux.HandleFunc("/liblancedb_cgo-linux-amd64.a.sha256", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(checksum))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestVerifyChecksumMatch tests that a download matching its published checksum is accepted
+func TestVerifyChecksumMatch(t *testing.T) {
+	library := []byte(strings.Repeat("lancedb static library ", 1000))
+	digest := sha256.Sum256(library)
+	// Published in sha256sum format
+	checksum := hex.EncodeToString(digest[:]) + "  liblancedb_cgo-linux-amd64.a\n"
+	server := newReleaseServer(t, library, checksum)
+
+	libPath := filepath.Join(t.TempDir(), libraryFileName("linux"))
+	downloadURL := server.URL + "/liblancedb_cgo-linux-amd64.a"
+	if err := downloadFile(newHTTPClient(), libPath, downloadURL, io.Discard, nil); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	if err := verifyChecksum(newHTTPClient(), libPath, downloadURL+".sha256"); err != nil {
+		t.Fatalf("Expected checksum to match, got: %v", err)
+	}
+}
+
+// TestVerifyChecksumMismatch tests that a download not matching its checksum is rejected
+func TestVerifyChecksumMismatch(t *testing.T) {
+	library := []byte(strings.Repeat("lancedb static library ", 1000))
+	digest := sha256.Sum256([]byte("a different library"))
+	server := newReleaseServer(t, library, hex.EncodeToString(digest[:]))
+
+	libPath := filepath.Join(t.TempDir(), libraryFileName("linux"))
+	downloadURL := server.URL + "/liblancedb_cgo-linux-amd64.a"
+	if err := downloadFile(newHTTPClient(), libPath, downloadURL, io.Discard, nil); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	err := verifyChecksum(newHTTPClient(), libPath, downloadURL+".sha256")
+	if err == nil {
+		t.Fatal("Expected checksum mismatch error")
+	}
+	if !strings.Contains(err.Error(), "mismatch") {
+		t.Errorf("Expected mismatch error, got: %v", err)
+	}
+}
+
+// TestFailedVerificationKeepsInstalledLibrary tests that a download failing its checksum
+// never replaces the installed library
+func TestFailedVerificationKeepsInstalledLibrary(t *testing.T) {
+	library := []byte(strings.Repeat("lancedb static library ", 1000))
+	digest := sha256.Sum256([]byte("a different library"))
+	server := newReleaseServer(t, library, hex.EncodeToString(digest[:]))
+
+	libPath := filepath.Join(t.TempDir(), libraryFileName("linux"))
+	if err := os.WriteFile(libPath, []byte("installed library"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	downloadURL := server.URL + "/liblancedb_cgo-linux-amd64.a"
+	err := downloadFile(newHTTPClient(), libPath, downloadURL, io.Discard, func(tmpPath string) error {
+		return verifyChecksum(newHTTPClient(), tmpPath, downloadURL+".sha256")
+	})
+	if !errors.Is(err, errVerificationFailed) {
+		t.Fatalf("Expected verification error, got: %v", err)
+	}
+
+	got, err := os.ReadFile(libPath)
+	if err != nil {
+		t.Fatalf("Failed to read library: %v", err)
+	}
+	if string(got) != "installed library" {
+		t.Error("Expected the installed library to be kept")
+	}
+	if _, err := os.Stat(partialDownloadPath(libPath, downloadURL)); !os.IsNotExist(err) {
+		t.Error("Expected the unverified download to be removed")
+	}
+}
+
+// TestVerifyChecksumMissing tests that a release without a checksum file fails verification
+func TestVerifyChecksumMissing(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	libPath := filepath.Join(t.TempDir(), libraryFileName("linux"))
+	if err := os.WriteFile(libPath, []byte("library"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if err := verifyChecksum(newHTTPClient(), libPath, server.URL+"/liblancedb_cgo-linux-amd64.a.sha256"); err == nil {
+		t.Fatal("Expected error for missing checksum file")
+	}
+}
+
+// TestFetchChecksumInvalid tests that checksum files without a SHA256 digest are rejected
+func TestFetchChecksumInvalid(t *testing.T) {
+	for _, body := range []string{"", "not-a-digest", "abcd1234"} {
+		server := newReleaseServer(t, nil, body)
+		if _, err := fetchChecksum(newHTTPClient(), server.URL+"/liblancedb_cgo-linux-amd64.a.sha256"); err == nil {
+			t.Errorf("Expected error for checksum file %q", body)
+		}
+	}
+}
+
+// TestPlatforms tests the linker flags, download URL and pkg-config file of every supported platform
+func TestPlatforms(t *testing.T) {
+	flagPattern := regexp.MustCompile(`^-l[A-Za-z0-9_]+$`)
+	frameworkPattern := regexp.MustCompile(`^[A-Za-z]+$`)
+
+	for _, platform := range supportedPlatforms() {
+		t.Run(platform, func(t *testing.T) {
+			goos, goarch, ok := strings.Cut(platform, "-")
+			if !ok || goos == "" || goarch == "" {
+				t.Fatalf("Platform key %q is not {os}-{arch}", platform)
+			}
+
+			// Linker flags are -l libraries, or -framework followed by a framework name
+			fields := strings.Fields(platformFlags[platform])
+			if len(fields) == 0 {
+				t.Fatal("No linker flags")
+			}
+			for i := 0; i < len(fields); i++ {
+				if fields[i] == "-framework" {
+					if goos != "darwin" {
+						t.Errorf("-framework used on %s", goos)
+					}
+					i++
+					if i == len(fields) || !frameworkPattern.MatchString(fields[i]) {
+						t.Errorf("-framework without a framework name in %q", platformFlags[platform])
+					}
+					continue
+				}
+				if !flagPattern.MatchString(fields[i]) {
+					t.Errorf("Invalid linker flag %q", fields[i])
+				}
+			}
+
+			// The download URL points at this platform's release asset
+			downloadURL, err := url.Parse(releaseAssetURL(defaultBaseURL, "v1.2.3", platform))
+			if err != nil {
+				t.Fatalf("Invalid download URL: %v", err)
+			}
+			if downloadURL.Scheme != "https" || downloadURL.Host != "github.com" {
+				t.Errorf("Unexpected download URL %s", downloadURL)
+			}
+			if want := "/" + repoOwner + "/" + repoName + "/releases/download/v1.2.3/" + assetName(platform); downloadURL.Path != want {
+				t.Errorf("Download path %s, want %s", downloadURL.Path, want)
+			}
+			if !strings.Contains(assetName(platform), platform) {
+				t.Errorf("Asset name %s does not name the platform", assetName(platform))
+			}
+
+			// The library file name must be found by -llancedb_cgo
+			wantExt := ".a"
+			if goos == "windows" {
+				wantExt = ".lib"
+			}
+			if ext := filepath.Ext(libraryFileName(goos)); ext != wantExt {
+				t.Errorf("Library file %s, want extension %s", libraryFileName(goos), wantExt)
+			}
+			if ext := filepath.Ext(assetName(platform)); ext != wantExt {
+				t.Errorf("Asset %s, want extension %s", assetName(platform), wantExt)
+			}
+
+			pc := pkgConfigContent("v1.2.3", "/go/lib/lancedb/"+platform, platform)
+			if !strings.Contains(pc, "Libs: -L/go/lib/lancedb/"+platform+" -llancedb_cgo "+platformFlags[platform]+"\n") {
+				t.Errorf("Unexpected pkg-config content:\n%s", pc)
+			}
+		})
+	}
+}
+
+// TestPkgConfigWindowsPath tests that Windows paths are written in a form pkg-config can parse
+func TestPkgConfigWindowsPath(t *testing.T) {
+	pc := pkgConfigContent("v1.2.3", `C:\Users\Jane Doe\go\lib\lancedb\windows-amd64`, "windows-amd64")
+
+	want := `Libs: -LC:/Users/Jane\ Doe/go/lib/lancedb/windows-amd64 -llancedb_cgo `
+	if !strings.Contains(pc, want) {
+		t.Errorf("Expected %q in pkg-config content:\n%s", want, pc)
+	}
+}
+
+// TestDownloadResume tests that an interrupted download continues from the partial file
+func TestDownloadResume(t *testing.T) {
+	library := []byte(strings.Repeat("0123456789abcdef", 64*1024))
+	half := len(library) / 2
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("Range"))
+		if len(requests) == 1 {
+			// Drop the connection halfway through the first download
+			w.Header().Set("Content-Length", strconv.Itoa(len(library)))
+			w.Write(library[:half])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "lib.a", time.Time{}, bytes.NewReader(library))
+	}))
+	defer server.Close()
+
+	libPath := filepath.Join(t.TempDir(), libraryFileName("linux"))
+
+	if err := downloadFile(newHTTPClient(), libPath, server.URL+"/lib.a", io.Discard, nil); err == nil {
+		t.Fatal("Expected the first download to be interrupted")
+	}
+	info, err := os.Stat(partialDownloadPath(libPath, server.URL+"/lib.a"))
+	if err != nil {
+		t.Fatalf("Expected partial download to be kept: %v", err)
+	}
+	if info.Size() != int64(half) {
+		t.Fatalf("Expected %d bytes in partial download, got %d", half, info.Size())
+	}
+
+	var progress bytes.Buffer
+	if err := downloadFile(newHTTPClient(), libPath, server.URL+"/lib.a", &progress, nil); err != nil {
+		t.Fatalf("Resumed download failed: %v", err)
+	}
+
+	if want := fmt.Sprintf("bytes=%d-", half); requests[1] != want {
+		t.Errorf("Expected resume request with Range %q, got %q", want, requests[1])
+	}
+	got, err := os.ReadFile(libPath)
+	if err != nil {
+		t.Fatalf("Failed to read library: %v", err)
+	}
+	if !bytes.Equal(got, library) {
+		t.Fatalf("Resumed file differs from the original (%d vs %d bytes)", len(got), len(library))
+	}
+	if _, err := os.Stat(partialDownloadPath(libPath, server.URL+"/lib.a")); !os.IsNotExist(err) {
+		t.Error("Expected temp file to be renamed")
+	}
+	if !strings.Contains(progress.String(), "Resuming download") || !strings.Contains(progress.String(), "(100%)") {
+		t.Errorf("Unexpected progress output: %q", progress.String())
+	}
+}
+
+// TestDownloadRestartsWithoutRangeSupport tests that a partial file is replaced when the server ignores ranges
+func TestDownloadRestartsWithoutRangeSupport(t *testing.T) {
+	library := []byte(strings.Repeat("lancedb", 10000))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(library)
+	}))
+	defer server.Close()
+
+	libPath := filepath.Join(t.TempDir(), libraryFileName("linux"))
+	if err := os.WriteFile(partialDownloadPath(libPath, server.URL+"/lib.a"), []byte("stale partial data"), 0644); err != nil {
+		t.Fatalf("Failed to write partial file: %v", err)
+	}
+
+	if err := downloadFile(newHTTPClient(), libPath, server.URL+"/lib.a", io.Discard, nil); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	got, err := os.ReadFile(libPath)
+	if err != nil {
+		t.Fatalf("Failed to read library: %v", err)
+	}
+	if !bytes.Equal(got, library) {
+		t.Fatal("Downloaded file differs from the original")
+	}
+}
+
+// TestDownloadDiscardsOversizedPartial tests that a partial file longer than the asset is discarded
+func TestDownloadDiscardsOversizedPartial(t *testing.T) {
+	library := []byte(strings.Repeat("lancedb", 1000))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "lib.a", time.Time{}, bytes.NewReader(library))
+	}))
+	defer server.Close()
+
+	libPath := filepath.Join(t.TempDir(), libraryFileName("linux"))
+	if err := os.WriteFile(partialDownloadPath(libPath, server.URL+"/lib.a"), bytes.Repeat([]byte("x"), len(library)*2), 0644); err != nil {
+		t.Fatalf("Failed to write partial file: %v", err)
+	}
+
+	if err := downloadFile(newHTTPClient(), libPath, server.URL+"/lib.a", io.Discard, nil); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	got, err := os.ReadFile(libPath)
+	if err != nil {
+		t.Fatalf("Failed to read library: %v", err)
+	}
+	if !bytes.Equal(got, library) {
+		t.Fatal("Downloaded file differs from the original")
+	}
+}
+
+// TestDownloadDiscardsPartialOfOtherVersion tests that a partial download of another version
+// is discarded instead of being resumed
+func TestDownloadDiscardsPartialOfOtherVersion(t *testing.T) {
+	library := []byte(strings.Repeat("lancedb v2", 1000))
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "lib.a", time.Time{}, bytes.NewReader(library))
+	}))
+	defer server.Close()
+
+	libPath := filepath.Join(t.TempDir(), libraryFileName("linux"))
+	stale := partialDownloadPath(libPath, server.URL+"/v1/lib.a")
+	if err := os.WriteFile(stale, []byte("lancedb v1"), 0644); err != nil {
+		t.Fatalf("Failed to write partial file: %v", err)
+	}
+
+	if err := downloadFile(newHTTPClient(), libPath, server.URL+"/v2/lib.a", io.Discard, nil); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if len(ranges) != 1 || ranges[0] != "" {
+		t.Errorf("Expected one request without a range, got %q", ranges)
+	}
+	got, err := os.ReadFile(libPath)
+	if err != nil {
+		t.Fatalf("Failed to read library: %v", err)
+	}
+	if !bytes.Equal(got, library) {
+		t.Fatal("Downloaded file differs from the original")
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("Expected the partial download of the other version to be removed")
+	}
+}
+
+// TestParseContentRange tests parsing of Content-Range headers
+func TestParseContentRange(t *testing.T) {
+	start, size, err := parseContentRange("bytes 100-199/200")
+	if err != nil || start != 100 || size != 200 {
+		t.Errorf("Got start=%d size=%d err=%v", start, size, err)
+	}
+
+	for _, header := range []string{"", "bytes */200", "bytes 100-99/200", "bytes 0-200/200"} {
+		if _, _, err := parseContentRange(header); err == nil {
+			t.Errorf("Expected error for %q", header)
+		}
+	}
+}
+
+// TestReleaseAssetURLCustomBase tests that download URLs are built from a mirror base URL
+func TestReleaseAssetURLCustomBase(t *testing.T) {
+	for _, base := range []string{"https://artifacts.example.com/lancedb", "https://artifacts.example.com/lancedb/"} {
+		got := releaseAssetURL(base, "v1.2.3", "linux-amd64")
+		want := "https://artifacts.example.com/lancedb/v1.2.3/liblancedb_cgo-linux-amd64.a"
+		if got != want {
+			t.Errorf("releaseAssetURL(%q) = %s, want %s", base, got, want)
+		}
+	}
+}
+
+// TestResolveBaseURL tests the precedence of the --base-url flag, LANCEDB_MIRROR and the default
+func TestResolveBaseURL(t *testing.T) {
+	t.Setenv(mirrorEnvVar, "")
+	if got := resolveBaseURL(""); got != defaultBaseURL {
+		t.Errorf("Expected default base URL, got %s", got)
+	}
+
+	t.Setenv(mirrorEnvVar, "https://env.example.com")
+	if got := resolveBaseURL(""); got != "https://env.example.com" {
+		t.Errorf("Expected base URL from %s, got %s", mirrorEnvVar, got)
+	}
+	if got := resolveBaseURL("https://flag.example.com"); got != "https://flag.example.com" {
+		t.Errorf("Expected the flag to take precedence, got %s", got)
+	}
+}
+
+// TestDownloadThroughProxy tests that downloads go through the proxy set in HTTP_PROXY.
+// Proxy settings are read once per process, so the download runs in a child process.
+func TestDownloadThroughProxy(t *testing.T) {
+	if os.Getenv("LANCEDB_INSTALL_PROXY_CHILD") == "1" {
+		libPath := filepath.Join(os.Getenv("LANCEDB_INSTALL_PROXY_DIR"), libraryFileName("linux"))
+		url := releaseAssetURL(os.Getenv(mirrorEnvVar), "v1.2.3", "linux-amd64")
+		if err := downloadFile(newHTTPClient(), libPath, url, io.Discard, nil); err != nil {
+			t.Fatalf("Download through proxy failed: %v", err)
+		}
+		return
+	}
+
+	library := []byte(strings.Repeat("lancedb", 1000))
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A proxied request carries the absolute URL of the mirror
+		proxied = append(proxied, r.URL.String())
+		w.Write(library)
+	}))
+	defer proxy.Close()
+
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDownloadThroughProxy$")
+	cmd.Env = append(os.Environ(),
+		"LANCEDB_INSTALL_PROXY_CHILD=1",
+		"LANCEDB_INSTALL_PROXY_DIR="+dir,
+		mirrorEnvVar+"=http://mirror.internal.example/lancedb",
+		"HTTP_PROXY="+proxy.URL,
+		"NO_PROXY=",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Child process failed: %v\n%s", err, out)
+	}
+
+	want := "http://mirror.internal.example/lancedb/v1.2.3/liblancedb_cgo-linux-amd64.a"
+	if len(proxied) != 1 || proxied[0] != want {
+		t.Errorf("Expected one proxied request for %s, got %v", want, proxied)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, libraryFileName("linux")))
+	if err != nil {
+		t.Fatalf("Failed to read library: %v", err)
+	}
+	if !bytes.Equal(got, library) {
+		t.Error("Downloaded file differs from the original")
+	}
+}
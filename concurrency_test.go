@@ -162,3 +162,61 @@ func TestStreaming(t *testing.T) {
 
 	assert.Equal(t, 1000, rowCount)
 }
+
+func TestCreateTableIfNotExistsConcurrent(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lancedb_create_if_not_exists_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	db, err := lancedb.Connect(tmpDir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+	}, nil)
+
+	const numGoroutines = 20
+	tables := make([]*lancedb.Table, numGoroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			table, err := db.CreateTableIfNotExists("shared_table", schema)
+			assert.NoError(t, err)
+			tables[i] = table
+		}(i)
+	}
+	wg.Wait()
+
+	for _, table := range tables {
+		require.NotNil(t, table)
+		defer table.Close()
+
+		// A table created more than once would have a later version
+		version, err := table.Version()
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), version)
+	}
+
+	names, err := db.TableNames()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shared_table"}, names)
+
+	// An existing table is opened with its data intact
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer builder.Release()
+	builder.Field(0).(*array.Int32Builder).AppendValues([]int32{1, 2, 3}, nil)
+	record := builder.NewRecord()
+	defer record.Release()
+	require.NoError(t, tables[0].Add(record, lancedb.AddModeAppend))
+
+	table, err := db.CreateTableIfNotExists("shared_table", schema)
+	require.NoError(t, err)
+	defer table.Close()
+
+	count, err := table.CountRows()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
@@ -0,0 +1,128 @@
+package lancedb
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+// createCategoryTable creates a table with 30 rows, every third row in category "tech"
+func createCategoryTable(t *testing.T) (*Connection, *Table) {
+	db, err := Connect(filepath.Join(t.TempDir(), "count_db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "category", Type: arrow.BinaryTypes.String},
+	}, nil)
+
+	table, err := db.CreateTableWithSchema("count_table", schema)
+	if err != nil {
+		db.Close()
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer builder.Release()
+
+	categories := []string{"tech", "science", "art"}
+	for i := 0; i < 30; i++ {
+		builder.Field(0).(*array.Int32Builder).Append(int32(i))
+		builder.Field(1).(*array.StringBuilder).Append(categories[i%len(categories)])
+	}
+
+	record := builder.NewRecord()
+	defer record.Release()
+
+	if err := table.Add(record, AddModeAppend); err != nil {
+		table.Close()
+		db.Close()
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	return db, table
+}
+
+// TestCountRowsWhere tests counting rows that match a predicate
+func TestCountRowsWhere(t *testing.T) {
+	db, table := createCategoryTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	count, err := table.CountRowsWhere("category = 'tech'")
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+
+	// Compare with counting the rows returned by a filtered query
+	query := table.Query().Where("category = 'tech'")
+	defer query.Close()
+	records, err := query.Execute()
+	if err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+	var expected int64
+	for _, record := range records {
+		expected += record.NumRows()
+		record.Release()
+	}
+
+	if count != expected {
+		t.Errorf("CountRowsWhere returned %d, query returned %d rows", count, expected)
+	}
+	if count != 10 {
+		t.Errorf("Expected 10 tech rows, got %d", count)
+	}
+
+	for predicate, want := range map[string]int64{
+		"id < 5":                                5,
+		"category = 'science' AND id >= 15":     5,
+		fmt.Sprintf("category = '%s'", "music"): 0,
+	} {
+		got, err := table.CountRowsWhere(predicate)
+		if err != nil {
+			t.Errorf("CountRowsWhere(%q) failed: %v", predicate, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("CountRowsWhere(%q) = %d, want %d", predicate, got, want)
+		}
+	}
+}
+
+// TestCountRowsWhereEmptyPredicate tests that an empty predicate counts every row
+func TestCountRowsWhereEmptyPredicate(t *testing.T) {
+	db, table := createCategoryTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	count, err := table.CountRowsWhere("")
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+
+	total, err := table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != total || count != 30 {
+		t.Errorf("Expected %d rows, got %d", total, count)
+	}
+}
+
+// TestCountRowsWhereInvalidPredicate tests that a malformed predicate returns an error
+func TestCountRowsWhereInvalidPredicate(t *testing.T) {
+	db, table := createCategoryTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	if _, err := table.CountRowsWhere("no_such_column = 1"); err == nil {
+		t.Error("Expected error for a predicate on a missing column")
+	}
+}
@@ -4,7 +4,9 @@
 package lancedb
 
 import (
+	"bytes"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/apache/arrow/go/v17/arrow"
@@ -534,6 +536,119 @@ func TestReadEmptyTable(t *testing.T) {
 	}
 }
 
+func TestReadDataStream(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_db")
+
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "x", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
+		},
+		nil,
+	)
+
+	table, err := db.CreateTableWithSchema("stream_table", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	// Add 50,000 rows in batches of 10,000
+	const numRows = 50000
+	const batchSize = 10000
+	var expectedSum int64
+	for start := 0; start < numRows; start += batchSize {
+		builder := array.NewInt64Builder(pool)
+		for i := start; i < start+batchSize; i++ {
+			builder.Append(int64(i))
+			expectedSum += int64(i)
+		}
+		arr := builder.NewArray()
+		record := array.NewRecord(schema, []arrow.Array{arr}, batchSize)
+		builder.Release()
+		arr.Release()
+
+		err = table.Add(record, AddModeAppend)
+		record.Release()
+		if err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+	}
+
+	iter, err := table.ToArrowStream()
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer iter.Close()
+
+	// Consume one batch at a time, releasing each before reading the next
+	totalRows := int64(0)
+	sum := int64(0)
+	batches := 0
+	for {
+		record, err := iter.Next()
+		if err != nil {
+			t.Fatalf("Failed to read batch: %v", err)
+		}
+		if record == nil {
+			break
+		}
+
+		col := record.Column(0).(*array.Int64)
+		for i := 0; i < col.Len(); i++ {
+			sum += col.Value(i)
+		}
+		totalRows += record.NumRows()
+		batches++
+		record.Release()
+	}
+
+	if totalRows != numRows {
+		t.Errorf("Expected %d rows, got %d", numRows, totalRows)
+	}
+	if sum != expectedSum {
+		t.Errorf("Expected sum %d, got %d", expectedSum, sum)
+	}
+	if batches < 2 {
+		t.Errorf("Expected the table to be streamed in multiple batches, got %d", batches)
+	}
+}
+
+func TestReadDataStreamClosedTable(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_db")
+
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "x", Type: arrow.PrimitiveTypes.Int32, Nullable: false},
+		},
+		nil,
+	)
+
+	table, err := db.CreateTableWithSchema("closed_table", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	table.Close()
+
+	if _, err := table.ToArrowStream(); err == nil {
+		t.Error("Expected error when streaming a closed table")
+	}
+}
+
 func TestDataRoundtrip(t *testing.T) {
 	pool := memory.NewGoAllocator()
 	tmpDir := t.TempDir()
@@ -624,6 +739,280 @@ func TestDataRoundtrip(t *testing.T) {
 	}
 }
 
+func TestBinaryDataRoundtrip(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_db")
+
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: false},
+			{Name: "thumbnail", Type: arrow.BinaryTypes.Binary, Nullable: true},
+		},
+		nil,
+	)
+
+	table, err := db.CreateTableWithSchema("binary_table", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	// Blobs covering every byte value, an empty value, a null and a larger payload
+	allBytes := make([]byte, 256)
+	for i := range allBytes {
+		allBytes[i] = byte(i)
+	}
+	large := bytes.Repeat([]byte{0x00, 0xff, 0x7f, 0x80}, 256*1024)
+	blobs := [][]byte{allBytes, {}, nil, large}
+
+	idBuilder := array.NewInt32Builder(pool)
+	blobBuilder := array.NewBinaryBuilder(pool, arrow.BinaryTypes.Binary)
+	for i, blob := range blobs {
+		idBuilder.Append(int32(i))
+		if blob == nil {
+			blobBuilder.AppendNull()
+		} else {
+			blobBuilder.Append(blob)
+		}
+	}
+
+	idArray := idBuilder.NewArray()
+	blobArray := blobBuilder.NewArray()
+	record := array.NewRecord(schema, []arrow.Array{idArray, blobArray}, int64(len(blobs)))
+
+	err = table.Add(record, AddModeAppend)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	idBuilder.Release()
+	blobBuilder.Release()
+	idArray.Release()
+	blobArray.Release()
+	record.Release()
+
+	records, err := table.ToArrow(-1)
+	if err != nil {
+		t.Fatalf("Failed to read data: %v", err)
+	}
+	defer func() {
+		for _, r := range records {
+			r.Release()
+		}
+	}()
+
+	seen := 0
+	for _, r := range records {
+		if r.Schema().Field(1).Type.ID() != arrow.BINARY {
+			t.Fatalf("Expected thumbnail column to be BINARY, got %s", r.Schema().Field(1).Type)
+		}
+		ids := r.Column(0).(*array.Int32)
+		thumbnails := r.Column(1).(*array.Binary)
+		for row := 0; row < int(r.NumRows()); row++ {
+			want := blobs[ids.Value(row)]
+			if want == nil {
+				if !thumbnails.IsNull(row) {
+					t.Errorf("Row %d: expected null", ids.Value(row))
+				}
+			} else if thumbnails.IsNull(row) || !bytes.Equal(thumbnails.Value(row), want) {
+				t.Errorf("Row %d: blob mismatch (got %d bytes, want %d)", ids.Value(row), len(thumbnails.Value(row)), len(want))
+			}
+			seen++
+		}
+	}
+
+	if seen != len(blobs) {
+		t.Errorf("Expected %d rows, got %d", len(blobs), seen)
+	}
+}
+
+func TestLargeStringRoundtrip(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_db")
+
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: false},
+			{Name: "body", Type: arrow.BinaryTypes.LargeString, Nullable: true},
+		},
+		nil,
+	)
+
+	table, err := db.CreateTableWithSchema("large_string_table", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	// A few multi-megabyte documents; LargeString uses 64-bit offsets so
+	// the same path works for columns beyond 2GB
+	bodies := []string{
+		strings.Repeat("lorem ipsum dolor sit amet ", 200000), // ~5.4MB
+		strings.Repeat("héllo wörld ✓ ", 150000),              // multi-byte UTF-8
+		"",
+		"short",
+	}
+
+	idBuilder := array.NewInt32Builder(pool)
+	bodyBuilder := array.NewLargeStringBuilder(pool)
+	for i, body := range bodies {
+		idBuilder.Append(int32(i))
+		bodyBuilder.Append(body)
+	}
+
+	idArray := idBuilder.NewArray()
+	bodyArray := bodyBuilder.NewArray()
+	record := array.NewRecord(schema, []arrow.Array{idArray, bodyArray}, int64(len(bodies)))
+
+	err = table.Add(record, AddModeAppend)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	idBuilder.Release()
+	bodyBuilder.Release()
+	idArray.Release()
+	bodyArray.Release()
+	record.Release()
+
+	tableSchema, err := table.Schema()
+	if err != nil {
+		t.Fatalf("Failed to get schema: %v", err)
+	}
+	if tableSchema.Field(1).Type.ID() != arrow.LARGE_STRING {
+		t.Errorf("Expected body column to be LARGE_STRING, got %s", tableSchema.Field(1).Type)
+	}
+
+	records, err := table.ToArrow(-1)
+	if err != nil {
+		t.Fatalf("Failed to read data: %v", err)
+	}
+	defer func() {
+		for _, r := range records {
+			r.Release()
+		}
+	}()
+
+	seen := 0
+	for _, r := range records {
+		ids := r.Column(0).(*array.Int32)
+		texts := r.Column(1).(*array.LargeString)
+		for row := 0; row < int(r.NumRows()); row++ {
+			want := bodies[ids.Value(row)]
+			if got := texts.Value(row); got != want {
+				t.Errorf("Row %d: text mismatch (got %d bytes, want %d)", ids.Value(row), len(got), len(want))
+			}
+			seen++
+		}
+	}
+
+	if seen != len(bodies) {
+		t.Errorf("Expected %d rows, got %d", len(bodies), seen)
+	}
+}
+
+func TestDictionaryColumnRoundtrip(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_db")
+
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: false},
+			{Name: "category", Type: DictionaryStringType, Nullable: false},
+		},
+		nil,
+	)
+
+	table, err := db.CreateTableWithSchema("dictionary_table", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	// 1,000 rows drawn from 5 categories
+	categories := []string{"tech", "science", "art", "sports", "news"}
+	const numRows = 1000
+	values := make([]string, numRows)
+	idBuilder := array.NewInt32Builder(pool)
+	for i := range values {
+		idBuilder.Append(int32(i))
+		values[i] = categories[(i*7)%len(categories)]
+	}
+
+	idArray := idBuilder.NewArray()
+	categoryArray := BuildDictionaryColumn(values)
+	record := array.NewRecord(schema, []arrow.Array{idArray, categoryArray}, numRows)
+
+	err = table.Add(record, AddModeAppend)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	idBuilder.Release()
+	idArray.Release()
+	categoryArray.Release()
+	record.Release()
+
+	records, err := table.ToArrow(-1)
+	if err != nil {
+		t.Fatalf("Failed to read data: %v", err)
+	}
+	defer func() {
+		for _, r := range records {
+			r.Release()
+		}
+	}()
+
+	seen := 0
+	for _, r := range records {
+		ids := r.Column(0).(*array.Int32)
+		decoded, err := StringColumnValues(r.Column(1))
+		if err != nil {
+			t.Fatalf("Failed to decode category column: %v", err)
+		}
+		for row := range decoded {
+			if want := values[ids.Value(row)]; decoded[row] != want {
+				t.Errorf("Row %d: expected %q, got %q", ids.Value(row), want, decoded[row])
+			}
+			seen++
+		}
+	}
+
+	if seen != numRows {
+		t.Errorf("Expected %d rows, got %d", numRows, seen)
+	}
+
+	// Filters compare against the logical string values
+	count, err := table.CountRowsWhere("category = 'tech'")
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if want := int64(numRows / len(categories)); count != want {
+		t.Errorf("Expected %d tech rows, got %d", want, count)
+	}
+}
+
 // BenchmarkDataInsertion measures insertion performance
 func BenchmarkDataInsertion(b *testing.B) {
 	pool := memory.NewGoAllocator()
@@ -35,7 +35,7 @@ func (d *DeleteBuilder) Where(predicate string) *DeleteBuilder {
 // Returns an error if the predicate is empty or the delete operation fails.
 func (d *DeleteBuilder) Execute() error {
 	if d.predicate == "" {
-		return &Error{Message: "predicate must be set using Where() before calling Execute()"}
+		return &Error{Code: ErrorCodeInvalidArgument, Message: "predicate must be set using Where() before calling Execute()"}
 	}
 
 	// Use the simple Delete method which handles the C API call
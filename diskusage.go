@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: Copyright The LanceDB Authors
+
+package lancedb
+
+/*
+#include <stdlib.h>
+
+typedef void* TableHandle;
+
+extern int lancedb_table_uri(TableHandle, char**);
+extern void lancedb_free_string(char*);
+*/
+import "C"
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// DiskUsageStats breaks down the bytes a table occupies on disk
+type DiskUsageStats struct {
+	DataBytes     int64 // Data files of every version not yet removed by cleanup
+	IndexBytes    int64 // Index files
+	MetadataBytes int64 // Manifests, deletion files and transaction files
+	TotalBytes    int64 // Sum of the above
+}
+
+// DiskUsage returns the approximate number of bytes the table occupies on disk, including
+// data files of old versions that haven't been cleaned up yet. Only tables on the local
+// filesystem are supported.
+func (t *Table) DiskUsage() (int64, error) {
+	stats, err := t.DiskUsageStats()
+	if err != nil {
+		return 0, err
+	}
+	return stats.TotalBytes, nil
+}
+
+// DiskUsageStats returns the table's disk usage split into data, index and metadata bytes.
+// Only tables on the local filesystem are supported.
+func (t *Table) DiskUsageStats() (*DiskUsageStats, error) {
+	uri, err := t.uri()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := localPath(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &DiskUsageStats{}
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		switch strings.SplitN(filepath.ToSlash(rel), "/", 2)[0] {
+		case "data":
+			stats.DataBytes += info.Size()
+		case "_indices":
+			stats.IndexBytes += info.Size()
+		default:
+			stats.MetadataBytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure table %s: %w", dir, err)
+	}
+
+	stats.TotalBytes = stats.DataBytes + stats.IndexBytes + stats.MetadataBytes
+	return stats, nil
+}
+
+// uri returns the URI of the table's dataset
+func (t *Table) uri() (string, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.handle == nil {
+		return "", &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var cURI *C.char
+	if C.lancedb_table_uri(t.handle, &cURI) < 0 {
+		return "", getLastError()
+	}
+	defer C.lancedb_free_string(cURI)
+	return C.GoString(cURI), nil
+}
+
+// localPath returns the filesystem path of a dataset URI, or an error for remote URIs
+func localPath(uri string) (string, error) {
+	if !strings.Contains(uri, "://") {
+		return uri, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", &Error{Code: ErrorCodeInvalidArgument, Message: fmt.Sprintf("invalid table URI %q: %v", uri, err)}
+	}
+	if u.Scheme != "file" {
+		return "", &Error{Code: ErrorCodeInvalidArgument, Message: fmt.Sprintf("disk usage is only available for local tables, not %s", u.Scheme)}
+	}
+	return u.Path, nil
+}
@@ -0,0 +1,81 @@
+package lancedb
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestTableDiskUsage tests that disk usage is reported and grows with more data
+func TestTableDiskUsage(t *testing.T) {
+	dbPath := "./test_disk_usage_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	before, err := table.DiskUsageStats()
+	if err != nil {
+		t.Fatalf("DiskUsageStats failed: %v", err)
+	}
+	if before.DataBytes <= 0 || before.MetadataBytes <= 0 {
+		t.Fatalf("Expected data and metadata bytes, got %+v", before)
+	}
+	if before.TotalBytes != before.DataBytes+before.IndexBytes+before.MetadataBytes {
+		t.Fatalf("Total %d is not the sum of %+v", before.TotalBytes, before)
+	}
+
+	record, err := buildUpdatedRows(table, 1000)
+	if err != nil {
+		t.Fatalf("Failed to build rows: %v", err)
+	}
+	defer record.Release()
+	if err := table.Add(record, AddModeAppend); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	after, err := table.DiskUsage()
+	if err != nil {
+		t.Fatalf("DiskUsage failed: %v", err)
+	}
+	if after <= before.TotalBytes {
+		t.Fatalf("Expected disk usage to grow past %d bytes, got %d", before.TotalBytes, after)
+	}
+}
+
+// TestTableDiskUsageClosed tests that a closed table reports an error
+func TestTableDiskUsageClosed(t *testing.T) {
+	dbPath := "./test_disk_usage_closed_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	table.Close()
+
+	_, err := table.DiskUsage()
+	var lerr *Error
+	if !errors.As(err, &lerr) || lerr.Code != ErrorCodeClosed {
+		t.Fatalf("Expected a closed table error, got %v", err)
+	}
+}
+
+// TestLocalPath tests resolving dataset URIs to filesystem paths
+func TestLocalPath(t *testing.T) {
+	for uri, want := range map[string]string{
+		"./db/t.lance":            "./db/t.lance",
+		"/data/db/t.lance":        "/data/db/t.lance",
+		"file:///data/db/t.lance": "/data/db/t.lance",
+	} {
+		got, err := localPath(uri)
+		if err != nil || got != want {
+			t.Fatalf("localPath(%q) = %q, %v; want %q", uri, got, err, want)
+		}
+	}
+
+	_, err := localPath("s3://bucket/db/t.lance")
+	var lerr *Error
+	if !errors.As(err, &lerr) || lerr.Code != ErrorCodeInvalidArgument {
+		t.Fatalf("Expected an invalid argument error for a remote URI, got %v", err)
+	}
+}
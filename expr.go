@@ -0,0 +1,257 @@
+package lancedb
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Expr is a filter predicate built from columns and literal values.
+// Literals are escaped when the expression is built, so values from user input
+// can be used without risking SQL injection.
+//
+// Example:
+//
+//	filter := lancedb.Col("views").Gt(1000).And(lancedb.Col("category").Eq("tech"))
+//	results, err := table.Query().WhereExpr(filter).Execute()
+type Expr struct {
+	sql string
+	op  string // "AND" or "OR" for combined expressions, empty otherwise
+	err error
+}
+
+// String returns the predicate as a SQL-like string, or an empty string if the
+// expression is invalid
+func (e Expr) String() string {
+	if e.err != nil {
+		return ""
+	}
+	return e.sql
+}
+
+// Err returns the first error found while building the expression, such as an
+// unsupported literal type
+func (e Expr) Err() error {
+	if e.err == nil && e.sql == "" {
+		return &Error{Code: ErrorCodeInvalidArgument, Message: "empty filter expression"}
+	}
+	return e.err
+}
+
+// And combines two expressions, matching rows that satisfy both
+func (e Expr) And(other Expr) Expr {
+	return combine("AND", e, other)
+}
+
+// Or combines two expressions, matching rows that satisfy either
+func (e Expr) Or(other Expr) Expr {
+	return combine("OR", e, other)
+}
+
+// Not negates an expression
+func Not(e Expr) Expr {
+	if err := e.Err(); err != nil {
+		return Expr{err: err}
+	}
+	return Expr{sql: "NOT (" + e.sql + ")"}
+}
+
+// combine joins two expressions with op, adding parentheses where precedence requires them
+func combine(op string, left, right Expr) Expr {
+	if err := left.Err(); err != nil {
+		return Expr{err: err}
+	}
+	if err := right.Err(); err != nil {
+		return Expr{err: err}
+	}
+	return Expr{sql: operand(op, left) + " " + op + " " + operand(op, right), op: op}
+}
+
+// operand returns the SQL for e as an operand of op
+func operand(op string, e Expr) string {
+	if e.op != "" && e.op != op {
+		return "(" + e.sql + ")"
+	}
+	return e.sql
+}
+
+// Column refers to a table column in a filter expression
+type Column struct {
+	name string
+}
+
+// Col returns a reference to the named column. Nested fields can be referenced
+// with dots, e.g. "metadata.author".
+func Col(name string) Column {
+	return Column{name: name}
+}
+
+// Eq matches rows where the column equals value
+func (c Column) Eq(value interface{}) Expr {
+	return c.compare("=", value)
+}
+
+// Ne matches rows where the column does not equal value
+func (c Column) Ne(value interface{}) Expr {
+	return c.compare("!=", value)
+}
+
+// Gt matches rows where the column is greater than value
+func (c Column) Gt(value interface{}) Expr {
+	return c.compare(">", value)
+}
+
+// Gte matches rows where the column is greater than or equal to value
+func (c Column) Gte(value interface{}) Expr {
+	return c.compare(">=", value)
+}
+
+// Lt matches rows where the column is less than value
+func (c Column) Lt(value interface{}) Expr {
+	return c.compare("<", value)
+}
+
+// Lte matches rows where the column is less than or equal to value
+func (c Column) Lte(value interface{}) Expr {
+	return c.compare("<=", value)
+}
+
+// In matches rows where the column equals any of values.
+// An empty list matches no rows.
+func (c Column) In(values ...interface{}) Expr {
+	column, err := quoteIdentifier(c.name)
+	if err != nil {
+		return Expr{err: err}
+	}
+	if len(values) == 0 {
+		return Expr{sql: "FALSE"}
+	}
+
+	literals := make([]string, len(values))
+	for i, value := range values {
+		literal, err := formatLiteral(value)
+		if err != nil {
+			return Expr{err: err}
+		}
+		literals[i] = literal
+	}
+	return Expr{sql: column + " IN (" + strings.Join(literals, ", ") + ")"}
+}
+
+// Like matches rows where the column matches a SQL LIKE pattern,
+// where % matches any sequence of characters and _ matches a single character
+func (c Column) Like(pattern string) Expr {
+	return c.compare("LIKE", pattern)
+}
+
+// IsNull matches rows where the column is null
+func (c Column) IsNull() Expr {
+	column, err := quoteIdentifier(c.name)
+	if err != nil {
+		return Expr{err: err}
+	}
+	return Expr{sql: column + " IS NULL"}
+}
+
+// compare builds a "column op literal" expression
+func (c Column) compare(op string, value interface{}) Expr {
+	column, err := quoteIdentifier(c.name)
+	if err != nil {
+		return Expr{err: err}
+	}
+	if value == nil {
+		return Expr{err: &Error{Code: ErrorCodeInvalidArgument, Message: fmt.Sprintf("cannot compare column %s with nil, use IsNull instead", c.name)}}
+	}
+
+	literal, err := formatLiteral(value)
+	if err != nil {
+		return Expr{err: err}
+	}
+	return Expr{sql: column + " " + op + " " + literal}
+}
+
+// quoteIdentifier returns a column reference, quoting each dotted part with
+// backticks unless it is a plain identifier
+func quoteIdentifier(name string) (string, error) {
+	if name == "" {
+		return "", &Error{Code: ErrorCodeInvalidArgument, Message: "column name cannot be empty"}
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part == "" {
+			return "", &Error{Code: ErrorCodeInvalidArgument, Message: fmt.Sprintf("invalid column name %q", name)}
+		}
+		if !isPlainIdentifier(part) {
+			parts[i] = "`" + strings.ReplaceAll(part, "`", "``") + "`"
+		}
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// isPlainIdentifier reports whether s can be used as a column name without quoting
+func isPlainIdentifier(s string) bool {
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// formatLiteral renders a Go value as a SQL literal
+func formatLiteral(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'", nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return formatFloat(float64(v), 32)
+	case float64:
+		return formatFloat(v, 64)
+	default:
+		return "", &Error{Code: ErrorCodeInvalidArgument, Message: fmt.Sprintf("unsupported filter value type %T", value)}
+	}
+}
+
+// formatFloat renders a finite float as a SQL literal
+func formatFloat(f float64, bitSize int) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", &Error{Code: ErrorCodeInvalidArgument, Message: fmt.Sprintf("cannot use %v in a filter", f)}
+	}
+
+	literal := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.ContainsAny(literal, ".e") {
+		// Keep the literal a float so the comparison isn't done as integers
+		literal += ".0"
+	}
+	return literal, nil
+}
@@ -0,0 +1,208 @@
+package lancedb
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+func TestExprSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		expr Expr
+		want string
+	}{
+		{"eq string", Col("category").Eq("tech"), "category = 'tech'"},
+		{"ne int", Col("views").Ne(3), "views != 3"},
+		{"gt", Col("views").Gt(1000), "views > 1000"},
+		{"gte negative", Col("score").Gte(-5), "score >= -5"},
+		{"lt float", Col("price").Lt(9.99), "price < 9.99"},
+		{"lte whole float", Col("price").Lte(float64(10)), "price <= 10.0"},
+		{"eq bool", Col("active").Eq(true), "active = TRUE"},
+		{"eq uint64", Col("id").Eq(uint64(math.MaxUint64)), "id = 18446744073709551615"},
+		{"escaped quote", Col("name").Eq("O'Brien"), "name = 'O''Brien'"},
+		{"injection attempt", Col("name").Eq("x' OR '1'='1"), "name = 'x'' OR ''1''=''1'"},
+		{"in", Col("category").In("tech", "science"), "category IN ('tech', 'science')"},
+		{"in numbers", Col("id").In(1, 2, 3), "id IN (1, 2, 3)"},
+		{"in empty", Col("id").In(), "FALSE"},
+		{"like", Col("title").Like("intro%"), "title LIKE 'intro%'"},
+		{"is null", Col("deleted_at").IsNull(), "deleted_at IS NULL"},
+		{"not", Not(Col("deleted_at").IsNull()), "NOT (deleted_at IS NULL)"},
+		{"quoted column", Col("my col").Eq(1), "`my col` = 1"},
+		{"backtick in column", Col("a`b").Eq(1), "`a``b` = 1"},
+		{"nested column", Col("meta.author").Eq("ann"), "meta.author = 'ann'"},
+		{
+			"and",
+			Col("views").Gt(1000).And(Col("category").Eq("tech")),
+			"views > 1000 AND category = 'tech'",
+		},
+		{
+			"and chain",
+			Col("a").Eq(1).And(Col("b").Eq(2)).And(Col("c").Eq(3)),
+			"a = 1 AND b = 2 AND c = 3",
+		},
+		{
+			"or inside and",
+			Col("a").Eq(1).And(Col("b").Eq(2).Or(Col("c").Eq(3))),
+			"a = 1 AND (b = 2 OR c = 3)",
+		},
+		{
+			"and inside or",
+			Col("a").Eq(1).And(Col("b").Eq(2)).Or(Col("c").Eq(3)),
+			"(a = 1 AND b = 2) OR c = 3",
+		},
+		{
+			"not combined",
+			Not(Col("a").Eq(1).Or(Col("b").Eq(2))),
+			"NOT (a = 1 OR b = 2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.expr.Err(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := tt.expr.String(); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr Expr
+	}{
+		{"empty", Expr{}},
+		{"nil value", Col("a").Eq(nil)},
+		{"unsupported type", Col("a").Eq(struct{}{})},
+		{"NaN", Col("a").Gt(math.NaN())},
+		{"infinity", Col("a").Lt(math.Inf(1))},
+		{"empty column", Col("").Eq(1)},
+		{"empty column part", Col("a..b").Eq(1)},
+		{"bad in value", Col("a").In(1, []int{2})},
+		{"error in and", Col("a").Eq(1).And(Col("b").Eq(nil))},
+		{"error in or", Col("").IsNull().Or(Col("b").Eq(1))},
+		{"error in not", Not(Col("a").Eq(nil))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.expr.Err() == nil {
+				t.Errorf("Expected error, got predicate %q", tt.expr.String())
+			}
+			if tt.expr.String() != "" {
+				t.Errorf("Expected empty predicate for invalid expression, got %q", tt.expr.String())
+			}
+		})
+	}
+}
+
+func TestQueryWhereExpr(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_db")
+
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: false},
+			{Name: "views", Type: arrow.PrimitiveTypes.Int64, Nullable: false},
+			{Name: "category", Type: arrow.BinaryTypes.String, Nullable: false},
+		},
+		nil,
+	)
+
+	table, err := db.CreateTableWithSchema("expr_test", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	// 20 rows: views 0, 200, ..., 3800; categories alternate tech/science, with one quoted name
+	idBuilder := array.NewInt32Builder(pool)
+	viewsBuilder := array.NewInt64Builder(pool)
+	categoryBuilder := array.NewStringBuilder(pool)
+	for i := 0; i < 20; i++ {
+		idBuilder.Append(int32(i))
+		viewsBuilder.Append(int64(i) * 200)
+		switch {
+		case i == 19:
+			categoryBuilder.Append("tech' OR '1'='1")
+		case i%2 == 0:
+			categoryBuilder.Append("tech")
+		default:
+			categoryBuilder.Append("science")
+		}
+	}
+
+	idArray := idBuilder.NewArray()
+	viewsArray := viewsBuilder.NewArray()
+	categoryArray := categoryBuilder.NewArray()
+	record := array.NewRecord(schema, []arrow.Array{idArray, viewsArray, categoryArray}, 20)
+
+	err = table.Add(record, AddModeAppend)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	idBuilder.Release()
+	viewsBuilder.Release()
+	categoryBuilder.Release()
+	idArray.Release()
+	viewsArray.Release()
+	categoryArray.Release()
+	record.Release()
+
+	countRows := func(e Expr) int64 {
+		t.Helper()
+		query := table.Query().WhereExpr(e)
+		defer query.Close()
+		results, err := query.Execute()
+		if err != nil {
+			t.Fatalf("Failed to execute query %q: %v", e.String(), err)
+		}
+		total := int64(0)
+		for _, r := range results {
+			total += r.NumRows()
+			r.Release()
+		}
+		return total
+	}
+
+	// views > 1000 means i >= 6; even i in 6..18 gives 7 tech rows
+	if got := countRows(Col("views").Gt(1000).And(Col("category").Eq("tech"))); got != 7 {
+		t.Errorf("Expected 7 rows, got %d", got)
+	}
+
+	// The injection-style value only matches the row that literally contains it
+	if got := countRows(Col("category").Eq("tech' OR '1'='1")); got != 1 {
+		t.Errorf("Expected 1 row, got %d", got)
+	}
+
+	if got := countRows(Col("id").In(1, 2, 3).Or(Not(Col("views").Lt(3600)))); got != 5 {
+		t.Errorf("Expected 5 rows, got %d", got)
+	}
+
+	if got := countRows(Col("category").Like("sci%")); got != 9 {
+		t.Errorf("Expected 9 rows, got %d", got)
+	}
+
+	// Invalid expressions surface as query errors
+	query := table.Query().WhereExpr(Col("views").Gt(math.NaN()))
+	defer query.Close()
+	if _, err := query.Execute(); err == nil {
+		t.Error("Expected error for invalid filter expression")
+	}
+}
@@ -409,3 +409,130 @@ func BenchmarkCreateIndex(b *testing.B) {
 	}
 }
 
+
+// TestFullTextSearch tests creating an FTS index and running keyword queries against it
+func TestFullTextSearch(t *testing.T) {
+	dbPath := t.TempDir() + "/test_fts.db"
+	defer os.RemoveAll(dbPath)
+
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+			{Name: "text", Type: arrow.BinaryTypes.String},
+		},
+		nil,
+	)
+
+	table, err := db.CreateTableWithSchema("documents", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	texts := []string{
+		"the quick brown fox jumps over the lazy dog",
+		"lancedb stores vectors and supports full text search",
+		"a lazy afternoon in the park",
+		"vectors are arrays of floating point numbers",
+	}
+
+	mem := memory.NewGoAllocator()
+	recordBuilder := array.NewRecordBuilder(mem, schema)
+	defer recordBuilder.Release()
+
+	idBuilder := recordBuilder.Field(0).(*array.Int32Builder)
+	textBuilder := recordBuilder.Field(1).(*array.StringBuilder)
+	for i, text := range texts {
+		idBuilder.Append(int32(i))
+		textBuilder.Append(text)
+	}
+
+	record := recordBuilder.NewRecord()
+	defer record.Release()
+
+	if err := table.Add(record, AddModeAppend); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	if err := table.CreateIndex("text", &IndexOptions{IndexType: IndexTypeFTS, Replace: true}); err != nil {
+		t.Fatalf("Failed to create FTS index: %v", err)
+	}
+
+	indices, err := table.ListIndices()
+	if err != nil {
+		t.Fatalf("Failed to list indices: %v", err)
+	}
+	found := false
+	for _, idx := range indices {
+		if len(idx.Columns) > 0 && idx.Columns[0] == "text" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Expected to find index on 'text' column, got %+v", indices)
+	}
+
+	query := table.Query()
+	defer query.Close()
+
+	results, err := query.FullTextSearch("lazy").Select("id", "text").Limit(10).Execute()
+	if err != nil {
+		t.Fatalf("Full-text search failed: %v", err)
+	}
+
+	matched := map[int32]bool{}
+	for _, rec := range results {
+		ids := rec.Column(0).(*array.Int32)
+		for i := 0; i < ids.Len(); i++ {
+			matched[ids.Value(i)] = true
+		}
+		if rec.Schema().FieldIndices("_score") == nil {
+			t.Errorf("Expected _score column in full-text search results")
+		}
+		rec.Release()
+	}
+
+	if len(matched) != 2 || !matched[0] || !matched[2] {
+		t.Errorf("Expected rows 0 and 2 to match 'lazy', got %v", matched)
+	}
+}
+
+// TestFullTextSearchWithNearestTo tests that full-text search cannot be combined with vector search
+func TestFullTextSearchWithNearestTo(t *testing.T) {
+	dbPath := t.TempDir() + "/test_fts_vector.db"
+	defer os.RemoveAll(dbPath)
+
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "text", Type: arrow.BinaryTypes.String},
+			{Name: "vector", Type: arrow.FixedSizeListOf(2, arrow.PrimitiveTypes.Float32)},
+		},
+		nil,
+	)
+
+	table, err := db.CreateTableWithSchema("documents", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	query := table.Query()
+	defer query.Close()
+
+	_, err = query.NearestTo([]float32{0.1, 0.2}).FullTextSearch("anything").Execute()
+	if err == nil {
+		t.Errorf("Expected error combining FullTextSearch with NearestTo")
+	}
+}
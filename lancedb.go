@@ -40,19 +40,24 @@ typedef void* TableHandle;
 extern int lancedb_init();
 extern void lancedb_cleanup();
 extern const char* lancedb_get_last_error();
+extern int lancedb_get_last_error_code();
 extern void lancedb_free_string(char*);
 
 extern ConnectionHandle lancedb_connect(const char* dataset_uri);
 extern void lancedb_connection_close(ConnectionHandle);
 extern int lancedb_connection_table_names(ConnectionHandle, const char*, int, char***, int*);
+extern int lancedb_connection_drop_table(ConnectionHandle, const char* name);
 
 extern TableHandle lancedb_table_open(ConnectionHandle, const char* name);
 extern TableHandle lancedb_table_create(ConnectionHandle, const char* name);
 extern void lancedb_table_close(TableHandle);
 extern int64_t lancedb_table_count_rows(TableHandle);
+extern int64_t lancedb_table_count_rows_filtered(TableHandle, const char* predicate);
 extern int lancedb_table_add(TableHandle, struct ArrowArray*, struct ArrowSchema*, int);
+extern int lancedb_table_add_columns(TableHandle, struct ArrowSchema*);
 extern int lancedb_table_schema(TableHandle, struct ArrowSchema*);
 extern TableHandle lancedb_table_create_with_schema(ConnectionHandle, const char* name, struct ArrowSchema*);
+extern TableHandle lancedb_table_create_if_not_exists(ConnectionHandle, const char* name, struct ArrowSchema*);
 extern int lancedb_table_to_arrow(TableHandle, int64_t, struct ArrowArray**, struct ArrowSchema**, int*);
 
 // Index management functions
@@ -61,19 +66,71 @@ extern int lancedb_table_list_indices(TableHandle, char**);
 
 // Delete operations
 extern int lancedb_table_delete(TableHandle, const char* predicate);
+
+// Versioning
+extern int64_t lancedb_table_version(TableHandle);
+extern int lancedb_table_checkout(TableHandle, uint64_t version);
+extern int lancedb_table_checkout_latest(TableHandle);
+
+// Maintenance
+extern int lancedb_table_optimize(TableHandle, int64_t older_than_secs, char**);
+extern int lancedb_table_set_metadata(TableHandle, const char* metadata_json);
+extern int lancedb_table_get_metadata(TableHandle, char**);
 */
 import "C"
 import (
 	"encoding/json"
+	"fmt"
 	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+)
+
+// ErrorCode classifies a LanceDB error
+type ErrorCode int
+
+const (
+	// ErrorCodeUnknown is used for errors without a more specific code
+	ErrorCodeUnknown ErrorCode = 0
+	// ErrorCodeInvalidArgument means an argument was malformed, e.g. an invalid filter
+	ErrorCodeInvalidArgument ErrorCode = 1
+	// ErrorCodeTableNotFound means the table does not exist
+	ErrorCodeTableNotFound ErrorCode = 2
+	// ErrorCodeTableAlreadyExists means a table with the same name exists
+	ErrorCodeTableAlreadyExists ErrorCode = 3
+	// ErrorCodeInvalidTableName means the table name is not allowed
+	ErrorCodeInvalidTableName ErrorCode = 4
+	// ErrorCodeInvalidSchema means data or a schema doesn't match what the table expects
+	ErrorCodeInvalidSchema ErrorCode = 5
+	// ErrorCodeIndexNotFound means the requested index does not exist
+	ErrorCodeIndexNotFound ErrorCode = 6
+	// ErrorCodeIO means reading or writing storage failed
+	ErrorCodeIO ErrorCode = 7
+	// ErrorCodeClosed means the connection or table was used after Close
+	ErrorCodeClosed ErrorCode = 8
+)
+
+// Sentinel errors for use with errors.Is. An *Error matches a sentinel when
+// their codes are equal, e.g. errors.Is(err, lancedb.ErrTableNotFound).
+var (
+	ErrInvalidArgument    = &Error{Code: ErrorCodeInvalidArgument, Message: "invalid argument"}
+	ErrTableNotFound      = &Error{Code: ErrorCodeTableNotFound, Message: "table not found"}
+	ErrTableAlreadyExists = &Error{Code: ErrorCodeTableAlreadyExists, Message: "table already exists"}
+	ErrInvalidTableName   = &Error{Code: ErrorCodeInvalidTableName, Message: "invalid table name"}
+	ErrInvalidSchema      = &Error{Code: ErrorCodeInvalidSchema, Message: "invalid schema"}
+	ErrIndexNotFound      = &Error{Code: ErrorCodeIndexNotFound, Message: "index not found"}
+	ErrIO                 = &Error{Code: ErrorCodeIO, Message: "I/O error"}
+	ErrClosed             = &Error{Code: ErrorCodeClosed, Message: "closed"}
 )
 
 // Error represents a LanceDB error
 type Error struct {
+	Code    ErrorCode
 	Message string
 }
 
@@ -81,14 +138,21 @@ func (e *Error) Error() string {
 	return e.Message
 }
 
-// getLastError retrieves the last error message from the C library
+// Is reports whether target is an *Error with the same code, so errors.Is
+// matches the sentinel errors. Errors with ErrorCodeUnknown only match themselves.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && e.Code != ErrorCodeUnknown && e.Code == t.Code
+}
+
+// getLastError retrieves the last error message and code from the C library
 func getLastError() error {
 	cErr := C.lancedb_get_last_error()
 	if cErr == nil {
 		return nil
 	}
 	errStr := C.GoString(cErr)
-	return &Error{Message: errStr}
+	return &Error{Code: ErrorCode(C.lancedb_get_last_error_code()), Message: errStr}
 }
 
 // Connection represents a connection to a LanceDB database
@@ -115,6 +179,17 @@ func Connect(uri string) (*Connection, error) {
 	return conn, nil
 }
 
+// memoryDatabases numbers the databases created by ConnectMemory
+var memoryDatabases atomic.Int64
+
+// ConnectMemory creates a new, empty database held in memory by LanceDB's in-memory
+// object store, e.g. for tests that shouldn't touch the disk. Nothing is written to the
+// filesystem, and the data is lost when the process exits. Each call returns a separate
+// database; share the returned connection to share the data.
+func ConnectMemory() (*Connection, error) {
+	return Connect(fmt.Sprintf("memory://lancedb-%d", memoryDatabases.Add(1)))
+}
+
 // Close closes the database connection
 func (c *Connection) Close() {
 	c.mu.Lock()
@@ -128,13 +203,51 @@ func (c *Connection) Close() {
 	}
 }
 
+// IsOpen reports whether the connection has not been closed
+func (c *Connection) IsOpen() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.handle != nil
+}
+
+// Ping checks that the connection is usable by listing at most one table name.
+// It returns an ErrClosed error if the connection has been closed.
+func (c *Connection) Ping() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.handle == nil {
+		return &Error{Code: ErrorCodeClosed, Message: "connection is closed"}
+	}
+
+	var cNames **C.char
+	var count C.int
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_connection_table_names(c.handle, nil, 1, &cNames, &count)
+	if int(result) != 0 {
+		return getLastError()
+	}
+
+	// Free the C strings and array
+	cNamesSlice := (*[1 << 30]*C.char)(unsafe.Pointer(cNames))[:count:count]
+	for i := 0; i < int(count); i++ {
+		C.free(unsafe.Pointer(cNamesSlice[i]))
+	}
+	C.free(unsafe.Pointer(cNames))
+
+	return nil
+}
+
 // TableNames returns a list of table names in the database
 func (c *Connection) TableNames() ([]string, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	if c.handle == nil {
-		return nil, &Error{Message: "connection is closed"}
+		return nil, &Error{Code: ErrorCodeClosed, Message: "connection is closed"}
 	}
 
 	var cNames **C.char
@@ -164,11 +277,42 @@ func (c *Connection) TableNames() ([]string, error) {
 	return names, nil
 }
 
+// DropTable drops a table from the database, deleting all of its data.
+// Any open handles to the table become invalid. Dropping a table that doesn't
+// exist returns an error matching ErrTableNotFound.
+func (c *Connection) DropTable(name string) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.handle == nil {
+		return &Error{Code: ErrorCodeClosed, Message: "connection is closed"}
+	}
+
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_connection_drop_table(c.handle, cName)
+	if int(result) != 0 {
+		return getLastError()
+	}
+
+	return nil
+}
+
 // Table represents a LanceDB table
 type Table struct {
 	mu     sync.RWMutex
 	handle C.TableHandle
 	conn   *Connection // Keep reference to prevent GC
+
+	schemaMu sync.Mutex
+	schema   *arrow.Schema // Cached for validating records in Add; nil until first needed
+
+	pinned        atomic.Bool // Set by Checkout until CheckoutLatest
+	noAutoRefresh atomic.Bool // Disables refreshing after Add and Delete
 }
 
 // OpenTable opens an existing table
@@ -177,7 +321,7 @@ func (c *Connection) OpenTable(name string) (*Table, error) {
 	defer c.mu.RUnlock()
 
 	if c.handle == nil {
-		return nil, &Error{Message: "connection is closed"}
+		return nil, &Error{Code: ErrorCodeClosed, Message: "connection is closed"}
 	}
 
 	cName := C.CString(name)
@@ -202,7 +346,7 @@ func (c *Connection) CreateTable(name string) (*Table, error) {
 	defer c.mu.RUnlock()
 
 	if c.handle == nil {
-		return nil, &Error{Message: "connection is closed"}
+		return nil, &Error{Code: ErrorCodeClosed, Message: "connection is closed"}
 	}
 
 	cName := C.CString(name)
@@ -227,11 +371,11 @@ func (c *Connection) CreateTableWithSchema(name string, schema *arrow.Schema) (*
 	defer c.mu.RUnlock()
 
 	if c.handle == nil {
-		return nil, &Error{Message: "connection is closed"}
+		return nil, &Error{Code: ErrorCodeClosed, Message: "connection is closed"}
 	}
 
 	if schema == nil {
-		return nil, &Error{Message: "schema cannot be nil"}
+		return nil, &Error{Code: ErrorCodeInvalidArgument, Message: "schema cannot be nil"}
 	}
 
 	cName := C.CString(name)
@@ -257,6 +401,45 @@ func (c *Connection) CreateTableWithSchema(name string, schema *arrow.Schema) (*
 	return table, nil
 }
 
+// CreateTableIfNotExists creates a table with the given schema, or opens it if a table
+// with that name already exists. Unlike calling OpenTable and then CreateTableWithSchema,
+// it is safe when several goroutines or processes create the same table at once: one
+// creates it and the others open it. The schema of an existing table is not checked.
+func (c *Connection) CreateTableIfNotExists(name string, schema *arrow.Schema) (*Table, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.handle == nil {
+		return nil, &Error{Code: ErrorCodeClosed, Message: "connection is closed"}
+	}
+
+	if schema == nil {
+		return nil, &Error{Code: ErrorCodeInvalidArgument, Message: "schema cannot be nil"}
+	}
+
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	// Export schema to C
+	cSchema, err := SchemaToC(schema)
+	if err != nil {
+		return nil, err
+	}
+	defer ReleaseArrowSchema(cSchema)
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	handle := C.lancedb_table_create_if_not_exists(c.handle, cName, cSchema)
+	if handle == nil {
+		return nil, getLastError()
+	}
+
+	table := &Table{handle: handle, conn: c}
+	runtime.SetFinalizer(table, (*Table).Close)
+	return table, nil
+}
+
 // Close closes the table
 func (t *Table) Close() {
 	t.mu.Lock()
@@ -270,13 +453,20 @@ func (t *Table) Close() {
 	}
 }
 
+// IsClosed reports whether the table has been closed
+func (t *Table) IsClosed() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.handle == nil
+}
+
 // CountRows returns the number of rows in the table
 func (t *Table) CountRows() (int64, error) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
 	if t.handle == nil {
-		return 0, &Error{Message: "table is closed"}
+		return 0, &Error{Code: ErrorCodeClosed, Message: "table is closed"}
 	}
 
 	runtime.LockOSThread()
@@ -289,6 +479,33 @@ func (t *Table) CountRows() (int64, error) {
 	return int64(count), nil
 }
 
+// CountRowsWhere returns the number of rows matching a SQL-like predicate
+// (e.g. "category = 'tech'"). An empty predicate counts every row, like CountRows.
+func (t *Table) CountRowsWhere(predicate string) (int64, error) {
+	if predicate == "" {
+		return t.CountRows()
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.handle == nil {
+		return 0, &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	cPredicate := C.CString(predicate)
+	defer C.free(unsafe.Pointer(cPredicate))
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	count := C.lancedb_table_count_rows_filtered(t.handle, cPredicate)
+	if count == -1 {
+		return 0, getLastError()
+	}
+	return int64(count), nil
+}
+
 // AddMode specifies how to add data to a table
 type AddMode int
 
@@ -297,19 +514,47 @@ const (
 	AddModeAppend AddMode = 0
 	// AddModeOverwrite replaces the table contents
 	AddModeOverwrite AddMode = 1
+	// AddModeMergeAppend appends data, first adding any new columns of the record to the
+	// table (existing rows get nulls there). New columns must be nullable. Table columns
+	// missing from the record are filled with nulls if they are nullable.
+	AddModeMergeAppend AddMode = 2
 )
 
 // Add inserts a RecordBatch into the table
 func (t *Table) Add(record arrow.Record, mode AddMode) error {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
+	// Adding columns and appending must not interleave with other writes through this
+	// handle, or another Add could validate against the schema before the new columns
+	if mode == AddModeMergeAppend {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+	} else {
+		t.mu.RLock()
+		defer t.mu.RUnlock()
+	}
 
 	if t.handle == nil {
-		return &Error{Message: "table is closed"}
+		return &Error{Code: ErrorCodeClosed, Message: "table is closed"}
 	}
 
 	if record == nil {
-		return &Error{Message: "record cannot be nil"}
+		return &Error{Code: ErrorCodeInvalidArgument, Message: "record cannot be nil"}
+	}
+
+	if mode == AddModeMergeAppend {
+		merged, err := t.mergeRecordSchema(record)
+		if err != nil {
+			return err
+		}
+		defer merged.Release()
+		record = merged
+		mode = AddModeAppend
+	}
+
+	// Appended records must match the table's columns. Overwrite may replace the schema.
+	if mode != AddModeOverwrite {
+		if err := t.validateRecord(record); err != nil {
+			return err
+		}
 	}
 
 	// Export record to C
@@ -328,6 +573,102 @@ func (t *Table) Add(record arrow.Record, mode AddMode) error {
 		return getLastError()
 	}
 
+	if mode == AddModeOverwrite {
+		t.invalidateSchema()
+	}
+	return t.autoRefresh()
+}
+
+// mergeRecordSchema adds the record's new columns to the table and returns the record
+// with the table's columns in table order, filling missing nullable columns with nulls.
+// The caller must hold t.mu for writing and release the returned record.
+func (t *Table) mergeRecordSchema(record arrow.Record) (arrow.Record, error) {
+	schema, err := t.readSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	var newFields []arrow.Field
+	for _, field := range record.Schema().Fields() {
+		if schema.HasField(field.Name) {
+			continue
+		}
+		if !field.Nullable {
+			return nil, schemaMismatchError("new column %q must be nullable", field.Name)
+		}
+		newFields = append(newFields, field)
+	}
+	if len(newFields) > 0 {
+		if err := t.addColumns(newFields); err != nil {
+			return nil, err
+		}
+		if schema, err = t.readSchema(); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := make([]arrow.Field, 0, schema.NumFields())
+	columns := make([]arrow.Array, 0, schema.NumFields())
+	defer func() {
+		for _, column := range columns {
+			column.Release()
+		}
+	}()
+	for _, field := range schema.Fields() {
+		if indices := record.Schema().FieldIndices(field.Name); len(indices) > 0 {
+			column := record.Column(indices[0])
+			column.Retain()
+			fields = append(fields, record.Schema().Field(indices[0]))
+			columns = append(columns, column)
+			continue
+		}
+		if !field.Nullable {
+			return nil, schemaMismatchError("record is missing column %q (%s), which is not nullable", field.Name, field.Type)
+		}
+		fields = append(fields, field)
+		columns = append(columns, array.MakeArrayOfNull(ArrowAllocator, field.Type, int(record.NumRows())))
+	}
+
+	return array.NewRecord(arrow.NewSchema(fields, nil), columns, record.NumRows()), nil
+}
+
+// AddColumns adds nullable columns to the table. Existing rows have null in them.
+func (t *Table) AddColumns(fields []arrow.Field) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.handle == nil {
+		return &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	if len(fields) == 0 {
+		return &Error{Code: ErrorCodeInvalidArgument, Message: "fields cannot be empty"}
+	}
+	for _, field := range fields {
+		if !field.Nullable {
+			return &Error{Code: ErrorCodeInvalidArgument, Message: fmt.Sprintf("column %q must be nullable", field.Name)}
+		}
+	}
+
+	return t.addColumns(fields)
+}
+
+// addColumns adds nullable columns to the table; the caller must hold t.mu for writing
+func (t *Table) addColumns(fields []arrow.Field) error {
+	cSchema, err := SchemaToC(arrow.NewSchema(fields, nil))
+	if err != nil {
+		return err
+	}
+	defer ReleaseArrowSchema(cSchema)
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_table_add_columns(t.handle, cSchema)
+	t.invalidateSchema()
+	if int(result) != 0 {
+		return getLastError()
+	}
 	return nil
 }
 
@@ -337,9 +678,14 @@ func (t *Table) Schema() (*arrow.Schema, error) {
 	defer t.mu.RUnlock()
 
 	if t.handle == nil {
-		return nil, &Error{Message: "table is closed"}
+		return nil, &Error{Code: ErrorCodeClosed, Message: "table is closed"}
 	}
 
+	return t.readSchema()
+}
+
+// readSchema fetches the table's schema from the native table; the caller must hold t.mu
+func (t *Table) readSchema() (*arrow.Schema, error) {
 	// We need to use the struct type from the C import block in arrow.go
 	// For now, we'll create a temp schema C structure
 	cSchema := (*C.struct_ArrowSchema)(C.malloc(C.size_t(unsafe.Sizeof(C.struct_ArrowSchema{}))))
@@ -364,6 +710,51 @@ func (t *Table) Schema() (*arrow.Schema, error) {
 	return schema, nil
 }
 
+// validateRecord checks that the record can be added to the table: its columns
+// must match the table's, and its fixed-size lists must have the declared length.
+// The table's schema is cached; on a mismatch it is fetched again in case another
+// handle changed it. The caller must hold t.mu.
+func (t *Table) validateRecord(record arrow.Record) error {
+	t.schemaMu.Lock()
+	defer t.schemaMu.Unlock()
+
+	check := func(schema *arrow.Schema) error {
+		if err := ValidateRecordAgainstSchema(record, schema); err != nil {
+			return err
+		}
+		return checkRecordSchema(schema, record.Schema())
+	}
+
+	fresh := false
+	if t.schema == nil {
+		schema, err := t.readSchema()
+		if err != nil {
+			return err
+		}
+		t.schema = schema
+		fresh = true
+	}
+
+	err := check(t.schema)
+	if err == nil || fresh {
+		return err
+	}
+
+	schema, readErr := t.readSchema()
+	if readErr != nil {
+		return readErr
+	}
+	t.schema = schema
+	return check(t.schema)
+}
+
+// invalidateSchema drops the cached schema after an operation that may have changed it
+func (t *Table) invalidateSchema() {
+	t.schemaMu.Lock()
+	t.schema = nil
+	t.schemaMu.Unlock()
+}
+
 // ToArrow reads all data from the table and returns it as Arrow RecordBatch slices
 // limit: maximum number of rows to read (-1 for no limit)
 func (t *Table) ToArrow(limit int64) ([]arrow.Record, error) {
@@ -371,7 +762,7 @@ func (t *Table) ToArrow(limit int64) ([]arrow.Record, error) {
 	defer t.mu.RUnlock()
 
 	if t.handle == nil {
-		return nil, &Error{Message: "table is closed"}
+		return nil, &Error{Code: ErrorCodeClosed, Message: "table is closed"}
 	}
 
 	var cArrays *C.struct_ArrowArray
@@ -417,6 +808,16 @@ func (t *Table) ToArrow(limit int64) ([]arrow.Record, error) {
 	return records, nil
 }
 
+// ToArrowStream reads all data from the table one batch at a time, so large
+// tables can be processed without holding every batch in memory.
+// Callers must Release each record returned by Next and Close the iterator.
+func (t *Table) ToArrowStream() (RecordIterator, error) {
+	query := t.Query()
+	defer query.Close()
+
+	return query.ExecuteStreaming()
+}
+
 // DistanceMetric represents the distance metric for vector indices
 type DistanceMetric int
 
@@ -437,6 +838,8 @@ const (
 	IndexTypeIVFPQ IndexType = "IVF_PQ"
 	// IndexTypeAuto automatically chooses the best index type
 	IndexTypeAuto IndexType = "AUTO"
+	// IndexTypeFTS is a full-text search (inverted) index on a string column; Metric and IVF/PQ options are ignored
+	IndexTypeFTS IndexType = "FTS"
 )
 
 // IndexOptions contains options for creating an index
@@ -466,7 +869,7 @@ func (t *Table) CreateIndex(column string, opts *IndexOptions) error {
 	defer t.mu.RUnlock()
 
 	if t.handle == nil {
-		return &Error{Message: "table is closed"}
+		return &Error{Code: ErrorCodeClosed, Message: "table is closed"}
 	}
 
 	if opts == nil {
@@ -516,7 +919,7 @@ func (t *Table) ListIndices() ([]IndexInfo, error) {
 	defer t.mu.RUnlock()
 
 	if t.handle == nil {
-		return nil, &Error{Message: "table is closed"}
+		return nil, &Error{Code: ErrorCodeClosed, Message: "table is closed"}
 	}
 
 	var cJSON *C.char
@@ -558,11 +961,11 @@ func (t *Table) Delete(predicate string) error {
 	defer t.mu.RUnlock()
 
 	if t.handle == nil {
-		return &Error{Message: "table is closed"}
+		return &Error{Code: ErrorCodeClosed, Message: "table is closed"}
 	}
 
 	if predicate == "" {
-		return &Error{Message: "predicate cannot be empty"}
+		return &Error{Code: ErrorCodeInvalidArgument, Message: "predicate cannot be empty"}
 	}
 
 	cPredicate := C.CString(predicate)
@@ -576,9 +979,252 @@ func (t *Table) Delete(predicate string) error {
 		return getLastError()
 	}
 
+	return t.autoRefresh()
+}
+
+// Version returns the version of the table this handle reads.
+// Every write (add, delete, index creation) produces a new version.
+func (t *Table) Version() (uint64, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.handle == nil {
+		return 0, &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	version := C.lancedb_table_version(t.handle)
+	if version == -1 {
+		return 0, getLastError()
+	}
+	return uint64(version), nil
+}
+
+// Checkout pins this table handle to a historical version.
+// Queries then read the data as of that version, and writes fail until CheckoutLatest is called.
+// Other handles to the same table are not affected.
+func (t *Table) Checkout(version uint64) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.handle == nil {
+		return &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_table_checkout(t.handle, C.uint64_t(version))
+	if int(result) != 0 {
+		return getLastError()
+	}
+	t.pinned.Store(true)
+	t.invalidateSchema()
+	return nil
+}
+
+// CheckoutLatest returns this table handle to the latest version after a Checkout
+func (t *Table) CheckoutLatest() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.handle == nil {
+		return &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_table_checkout_latest(t.handle)
+	if int(result) != 0 {
+		return getLastError()
+	}
+	t.pinned.Store(false)
+	t.invalidateSchema()
 	return nil
 }
 
+// Refresh moves this table handle to the latest version, picking up writes made through
+// other handles or processes. A handle pinned by Checkout stays on its version.
+func (t *Table) Refresh() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.handle == nil {
+		return &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	if err := t.refresh(); err != nil {
+		return err
+	}
+	t.invalidateSchema()
+	return nil
+}
+
+// SetAutoRefresh controls whether Add and Delete refresh this handle after writing, so
+// that CountRows and queries on it reflect the write immediately. It is enabled by
+// default; disabling it saves reading the latest version after every write, for handles
+// that only write or call Refresh themselves.
+func (t *Table) SetAutoRefresh(enabled bool) {
+	t.noAutoRefresh.Store(!enabled)
+}
+
+// RefreshError is returned by Add and Delete when the write was committed but refreshing
+// the handle afterwards failed, so the handle may still read the previous version. The
+// write must not be retried: it would be applied twice. Call Refresh to catch up.
+type RefreshError struct {
+	Err error // The refresh failure
+}
+
+func (e *RefreshError) Error() string {
+	return fmt.Sprintf("write committed, but refreshing the table failed: %v", e.Err)
+}
+
+// Unwrap returns the refresh failure
+func (e *RefreshError) Unwrap() error {
+	return e.Err
+}
+
+// autoRefresh refreshes the handle after a write unless disabled; the caller must hold t.mu.
+// Failures are reported as a *RefreshError, since the write itself has been committed.
+func (t *Table) autoRefresh() error {
+	if t.noAutoRefresh.Load() {
+		return nil
+	}
+	if err := t.refresh(); err != nil {
+		return &RefreshError{Err: err}
+	}
+	return nil
+}
+
+// refresh checks out the latest version unless the handle is pinned; the caller must hold t.mu
+func (t *Table) refresh() error {
+	if t.pinned.Load() {
+		return nil
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_table_checkout_latest(t.handle)
+	if int(result) != 0 {
+		return getLastError()
+	}
+	return nil
+}
+
+// OptimizeStats reports the work done by Table.Optimize
+type OptimizeStats struct {
+	FragmentsRemoved int64 `json:"fragments_removed"` // Fragments merged away by compaction
+	FragmentsAdded   int64 `json:"fragments_added"`   // Fragments written by compaction
+	FilesRemoved     int64 `json:"files_removed"`     // Data files replaced by compaction
+	FilesAdded       int64 `json:"files_added"`       // Data files written by compaction
+	BytesRemoved     int64 `json:"bytes_removed"`     // Bytes deleted from disk when pruning versions
+	VersionsRemoved  int64 `json:"versions_removed"`  // Old versions pruned
+}
+
+// Optimize compacts the table's data files and then removes versions older than
+// olderThan, deleting files that are no longer referenced by a remaining version.
+// Pruned versions can no longer be checked out. The latest version is always kept,
+// so an olderThan of 0 removes every other version.
+func (t *Table) Optimize(olderThan time.Duration) (*OptimizeStats, error) {
+	if olderThan < 0 {
+		return nil, &Error{Code: ErrorCodeInvalidArgument, Message: "olderThan cannot be negative"}
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.handle == nil {
+		return nil, &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	var cJSON *C.char
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_table_optimize(t.handle, C.int64_t(olderThan/time.Second), &cJSON)
+	if int(result) != 0 {
+		return nil, getLastError()
+	}
+	defer C.lancedb_free_string(cJSON)
+
+	var stats OptimizeStats
+	if err := json.Unmarshal([]byte(C.GoString(cJSON)), &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// SetMetadata stores key/value pairs with the table, such as the name of the model
+// that produced its embeddings. Keys that are already set are overwritten and other
+// keys are kept. Metadata is saved with the table data, so every handle and process
+// that opens the table sees it. Each call creates a new table version.
+func (t *Table) SetMetadata(kv map[string]string) error {
+	for key := range kv {
+		if key == "" {
+			return &Error{Code: ErrorCodeInvalidArgument, Message: "metadata key cannot be empty"}
+		}
+	}
+	if len(kv) == 0 {
+		return nil
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.handle == nil {
+		return &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	data, err := json.Marshal(kv)
+	if err != nil {
+		return err
+	}
+	cJSON := C.CString(string(data))
+	defer C.free(unsafe.Pointer(cJSON))
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_table_set_metadata(t.handle, cJSON)
+	if int(result) != 0 {
+		return getLastError()
+	}
+	return nil
+}
+
+// GetMetadata returns the key/value pairs stored with SetMetadata, as of the latest
+// table version. It returns an empty map if no metadata has been set.
+func (t *Table) GetMetadata() (map[string]string, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.handle == nil {
+		return nil, &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	var cJSON *C.char
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_table_get_metadata(t.handle, &cJSON)
+	if int(result) != 0 {
+		return nil, getLastError()
+	}
+	defer C.lancedb_free_string(cJSON)
+
+	metadata := make(map[string]string)
+	if err := json.Unmarshal([]byte(C.GoString(cJSON)), &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
 // Initialize the LanceDB runtime
 func init() {
 	result := C.lancedb_init()
@@ -4,6 +4,8 @@
 package lancedb
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -34,6 +36,79 @@ func TestConnect(t *testing.T) {
 	db.Close()
 }
 
+func TestConnectMemory(t *testing.T) {
+	// Relative paths would land in the working directory, so watch it for files
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	db, err := ConnectMemory()
+	if err != nil {
+		t.Fatalf("Failed to connect to memory database: %v", err)
+	}
+	defer db.Close()
+
+	table, err := db.CreateTableWithSchema("memory_table", schemaCheckSchema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	for i := 0; i < 3; i++ {
+		record := buildRecord(t, schemaCheckSchema)
+		err := table.Add(record, AddModeAppend)
+		record.Release()
+		if err != nil {
+			t.Fatalf("Failed to add rows: %v", err)
+		}
+	}
+
+	results, err := table.Query().NearestTo([]float32{0.5, 0.5, 0.5, 0.5}).Limit(2).Execute()
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	var found int64
+	for _, record := range results {
+		found += record.NumRows()
+		record.Release()
+	}
+	if found != 2 {
+		t.Errorf("Expected 2 search results, got %d", found)
+	}
+
+	// The data is visible when the table is reopened through the connection
+	reopened, err := db.OpenTable("memory_table")
+	if err != nil {
+		t.Fatalf("Failed to reopen table: %v", err)
+	}
+	defer reopened.Close()
+	if count, err := reopened.CountRows(); err != nil || count != 3 {
+		t.Errorf("Expected 3 rows after reopening, got %d (%v)", count, err)
+	}
+
+	// Disk usage doesn't apply to memory tables
+	if _, err := table.DiskUsage(); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument from DiskUsage, got %v", err)
+	}
+
+	// Each memory database is separate
+	other, err := ConnectMemory()
+	if err != nil {
+		t.Fatalf("Failed to connect to second memory database: %v", err)
+	}
+	defer other.Close()
+	if names, err := other.TableNames(); err != nil || len(names) != 0 {
+		t.Errorf("Expected an empty second database, got %v (%v)", names, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read working directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no files to be written, found %d entries", len(entries))
+	}
+}
+
 func TestConnectInvalidPath(t *testing.T) {
 	// Test with empty path - should still work (creates local db)
 	dbPath := createTempDB(t)
@@ -167,6 +242,66 @@ func TestOpenNonexistentTable(t *testing.T) {
 	}
 }
 
+func TestDropTable(t *testing.T) {
+	dbPath := createTempDB(t)
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	table, err := db.CreateTable("to_drop")
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	table.Close()
+
+	if err := db.DropTable("to_drop"); err != nil {
+		t.Fatalf("Failed to drop table: %v", err)
+	}
+
+	tables, err := db.TableNames()
+	if err != nil {
+		t.Fatalf("Failed to get table names: %v", err)
+	}
+	for _, name := range tables {
+		if name == "to_drop" {
+			t.Error("Dropped table still listed in table names")
+		}
+	}
+
+	if _, err := db.OpenTable("to_drop"); err == nil {
+		t.Error("Expected error when opening dropped table, got nil")
+	}
+}
+
+func TestDropTableNotFound(t *testing.T) {
+	dbPath := createTempDB(t)
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	err = db.DropTable("missing")
+	if !errors.Is(err, ErrTableNotFound) {
+		t.Fatalf("Expected ErrTableNotFound when dropping a missing table, got %v", err)
+	}
+}
+
+func TestDropTableClosedConnection(t *testing.T) {
+	dbPath := createTempDB(t)
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	db.Close()
+
+	if err := db.DropTable("any"); err == nil {
+		t.Error("Expected error when dropping table on closed connection, got nil")
+	}
+}
+
 func TestTableClose(t *testing.T) {
 	dbPath := createTempDB(t)
 	db, err := Connect(dbPath)
@@ -285,6 +420,162 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorCodeTableNotFound(t *testing.T) {
+	dbPath := createTempDB(t)
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.OpenTable("nonexistent")
+	if err == nil {
+		t.Fatal("Expected error opening nonexistent table")
+	}
+
+	var lanceErr *Error
+	if !errors.As(err, &lanceErr) {
+		t.Fatalf("Expected *lancedb.Error, got %T", err)
+	}
+	if lanceErr.Code != ErrorCodeTableNotFound {
+		t.Errorf("Expected code %d, got %d (%v)", ErrorCodeTableNotFound, lanceErr.Code, err)
+	}
+	if !errors.Is(err, ErrTableNotFound) {
+		t.Errorf("Expected errors.Is(err, ErrTableNotFound) for %v", err)
+	}
+	if errors.Is(err, ErrTableAlreadyExists) {
+		t.Errorf("Did not expect errors.Is(err, ErrTableAlreadyExists) for %v", err)
+	}
+}
+
+func TestErrorCodeTableAlreadyExists(t *testing.T) {
+	dbPath := createTempDB(t)
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	table, err := db.CreateTable("duplicate")
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	table.Close()
+
+	_, err = db.CreateTable("duplicate")
+	if !errors.Is(err, ErrTableAlreadyExists) {
+		t.Errorf("Expected ErrTableAlreadyExists, got %v", err)
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("opening docs: %w", &Error{Code: ErrorCodeTableNotFound, Message: "Table 'docs' was not found"})
+	if !errors.Is(err, ErrTableNotFound) {
+		t.Error("Expected wrapped error to match ErrTableNotFound")
+	}
+	if errors.Is(err, ErrIndexNotFound) {
+		t.Error("Did not expect wrapped error to match ErrIndexNotFound")
+	}
+
+	// Unclassified errors don't match each other
+	unknown := &Error{Message: "something failed"}
+	if errors.Is(unknown, &Error{Message: "something else failed"}) {
+		t.Error("Did not expect unknown errors to match")
+	}
+	if !errors.Is(unknown, unknown) {
+		t.Error("Expected an error to match itself")
+	}
+}
+
+func TestErrorClosedHandles(t *testing.T) {
+	dbPath := createTempDB(t)
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	table, err := db.CreateTable("closing")
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	table.Close()
+
+	if _, err := table.CountRows(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from a closed table, got %v", err)
+	}
+
+	db.Close()
+	if _, err := db.OpenTable("closing"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from a closed connection, got %v", err)
+	}
+	if errors.Is(ErrClosed, ErrTableNotFound) {
+		t.Error("Did not expect ErrClosed to match ErrTableNotFound")
+	}
+}
+
+func TestConnectionIsOpenAndPing(t *testing.T) {
+	dbPath := createTempDB(t)
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	if !db.IsOpen() {
+		t.Error("Expected new connection to be open")
+	}
+	if err := db.Ping(); err != nil {
+		t.Errorf("Ping failed on an empty database: %v", err)
+	}
+
+	// Ping still succeeds with tables present
+	for _, name := range []string{"ping1", "ping2"} {
+		table, err := db.CreateTable(name)
+		if err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+		table.Close()
+	}
+	if err := db.Ping(); err != nil {
+		t.Errorf("Ping failed: %v", err)
+	}
+
+	db.Close()
+	if db.IsOpen() {
+		t.Error("Expected connection to report closed after Close")
+	}
+	if err := db.Ping(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from Ping on a closed connection, got %v", err)
+	}
+}
+
+func TestTableIsClosed(t *testing.T) {
+	dbPath := createTempDB(t)
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	table, err := db.CreateTable("closable")
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if table.IsClosed() {
+		t.Error("Expected new table to be open")
+	}
+
+	table.Close()
+	if !table.IsClosed() {
+		t.Error("Expected table to report closed after Close")
+	}
+
+	// Closing twice is safe
+	table.Close()
+	if !table.IsClosed() {
+		t.Error("Expected table to stay closed")
+	}
+}
+
 // TestDatabasePersistence verifies that data persists across connections
 func TestDatabasePersistence(t *testing.T) {
 	dbPath := createTempDB(t)
@@ -0,0 +1,85 @@
+package lancedb
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestTableMetadata tests that metadata round-trips and survives reopening the table
+func TestTableMetadata(t *testing.T) {
+	dbPath := "./test_table_metadata_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	metadata, err := table.GetMetadata()
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if len(metadata) != 0 {
+		t.Fatalf("Expected no metadata on a new table, got %v", metadata)
+	}
+
+	if err := table.SetMetadata(map[string]string{"embedding_model": "text-embedding-ada-002", "created_by": "ingest"}); err != nil {
+		t.Fatalf("SetMetadata failed: %v", err)
+	}
+	// Later calls merge into the existing metadata
+	if err := table.SetMetadata(map[string]string{"embedding_model": "all-MiniLM-L6-v2"}); err != nil {
+		t.Fatalf("SetMetadata failed: %v", err)
+	}
+
+	expected := map[string]string{"embedding_model": "all-MiniLM-L6-v2", "created_by": "ingest"}
+	reopened, err := db.OpenTable("test_table")
+	if err != nil {
+		t.Fatalf("Failed to reopen table: %v", err)
+	}
+	defer reopened.Close()
+
+	for name, tbl := range map[string]*Table{"original": table, "reopened": reopened} {
+		metadata, err := tbl.GetMetadata()
+		if err != nil {
+			t.Fatalf("%s: GetMetadata failed: %v", name, err)
+		}
+		if len(metadata) != len(expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, metadata)
+		}
+		for key, value := range expected {
+			if metadata[key] != value {
+				t.Errorf("%s: expected %s=%q, got %q", name, key, value, metadata[key])
+			}
+		}
+	}
+
+	// The data is untouched
+	count, err := table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count == 0 {
+		t.Error("Expected rows to remain after setting metadata")
+	}
+}
+
+// TestTableMetadataErrors tests invalid keys and closed tables
+func TestTableMetadataErrors(t *testing.T) {
+	dbPath := "./test_table_metadata_errors_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+
+	if err := table.SetMetadata(map[string]string{"": "value"}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument for an empty key, got %v", err)
+	}
+
+	table.Close()
+	if err := table.SetMetadata(map[string]string{"key": "value"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from SetMetadata, got %v", err)
+	}
+	if _, err := table.GetMetadata(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from GetMetadata, got %v", err)
+	}
+}
@@ -42,12 +42,22 @@ extern QueryHandle lancedb_query_new(TableHandle table);
 extern void lancedb_query_close(QueryHandle);
 extern int lancedb_query_nearest_to(QueryHandle, float*, int);
 extern int lancedb_query_distance_type(QueryHandle, int);
+extern int lancedb_query_bypass_vector_index(QueryHandle);
 extern int lancedb_query_limit(QueryHandle, int);
+extern int lancedb_query_batch_size(QueryHandle, int);
 extern int lancedb_query_offset(QueryHandle, int);
 extern int lancedb_query_filter(QueryHandle, const char*);
+extern int lancedb_query_order_by(QueryHandle, const char*, int);
+extern int lancedb_query_full_text_search(QueryHandle, const char*);
 extern int lancedb_query_select(QueryHandle, char**, int);
 extern int lancedb_query_execute(QueryHandle, struct ArrowArray**, struct ArrowSchema**, int*);
 
+typedef void* CancelToken;
+extern CancelToken lancedb_cancel_token_new();
+extern void lancedb_cancel_token_cancel(CancelToken);
+extern void lancedb_cancel_token_free(CancelToken);
+extern int lancedb_query_set_cancel_token(QueryHandle, CancelToken);
+
 typedef void* QueryStreamHandle;
 extern QueryStreamHandle lancedb_query_execute_stream(QueryHandle);
 extern int lancedb_stream_next(QueryStreamHandle, struct ArrowArray*, struct ArrowSchema*);
@@ -55,6 +65,7 @@ extern void lancedb_stream_close(QueryStreamHandle);
 */
 import "C"
 import (
+	"context"
 	"runtime"
 	"unsafe"
 
@@ -86,7 +97,7 @@ func (t *Table) Query() *Query {
 	defer t.mu.RUnlock()
 
 	if t.handle == nil {
-		return &Query{err: &Error{Message: "table is closed"}}
+		return &Query{err: &Error{Code: ErrorCodeClosed, Message: "table is closed"}}
 	}
 
 	runtime.LockOSThread()
@@ -150,6 +161,24 @@ func (q *Query) SetDistanceType(dt DistanceType) *Query {
 	return q
 }
 
+// BypassVectorIndex makes a vector query scan every row instead of using the vector index.
+// Results are exact and use the query's distance type even if the index was built with
+// a different metric, at the cost of a slower search. Must be called after NearestTo.
+func (q *Query) BypassVectorIndex() *Query {
+	if q.err != nil {
+		return q
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_query_bypass_vector_index(q.handle)
+	if int(result) != 0 {
+		q.err = getLastError()
+	}
+	return q
+}
+
 // Limit sets the maximum number of results to return
 func (q *Query) Limit(limit int) *Query {
 	if q.err != nil {
@@ -166,6 +195,23 @@ func (q *Query) Limit(limit int) *Query {
 	return q
 }
 
+// BatchSize sets the maximum number of rows in each batch returned by Execute
+// and ExecuteStreaming. Values of zero or less are ignored.
+func (q *Query) BatchSize(rows int) *Query {
+	if q.err != nil || rows <= 0 {
+		return q
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_query_batch_size(q.handle, C.int(rows))
+	if int(result) != 0 {
+		q.err = getLastError()
+	}
+	return q
+}
+
 // Offset sets the number of results to skip
 func (q *Query) Offset(offset int) *Query {
 	if q.err != nil {
@@ -202,6 +248,66 @@ func (q *Query) Where(filter string) *Query {
 	return q
 }
 
+// OrderBy sorts the results by column, ascending (nulls first) or descending (nulls last).
+// Call it again to break ties by further columns. Limit and Offset apply to the sorted
+// results, so ordering by a unique column gives stable pages. It cannot be combined with
+// NearestTo or FullTextSearch, whose results are ranked.
+func (q *Query) OrderBy(column string, ascending bool) *Query {
+	if q.err != nil {
+		return q
+	}
+
+	cColumn := C.CString(column)
+	defer C.free(unsafe.Pointer(cColumn))
+
+	cAscending := C.int(0)
+	if ascending {
+		cAscending = 1
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_query_order_by(q.handle, cColumn, cAscending)
+	if int(result) != 0 {
+		q.err = getLastError()
+	}
+	return q
+}
+
+// WhereExpr sets a filter predicate built with Col, with literal values escaped
+func (q *Query) WhereExpr(e Expr) *Query {
+	if q.err != nil {
+		return q
+	}
+	if err := e.Err(); err != nil {
+		q.err = err
+		return q
+	}
+	return q.Where(e.String())
+}
+
+// FullTextSearch runs the query as a keyword search over columns with an FTS index
+// (see IndexTypeFTS). Results include a "_score" column with the BM25 relevance (higher is better).
+// It cannot be combined with NearestTo.
+func (q *Query) FullTextSearch(query string) *Query {
+	if q.err != nil {
+		return q
+	}
+
+	cQuery := C.CString(query)
+	defer C.free(unsafe.Pointer(cQuery))
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_query_full_text_search(q.handle, cQuery)
+	if int(result) != 0 {
+		q.err = getLastError()
+	}
+	return q
+}
+
 // Select specifies which columns to return in the results
 func (q *Query) Select(columns ...string) *Query {
 	if q.err != nil {
@@ -277,6 +383,75 @@ func (q *Query) Execute() ([]arrow.Record, error) {
 	return records, nil
 }
 
+// ExecuteContext executes the query like Execute, stopping early if ctx is done.
+// When ctx is done the running query is cancelled, and ExecuteContext returns ctx's
+// error once the native call has stopped, which is normally immediately.
+func (q *Query) ExecuteContext(ctx context.Context) ([]arrow.Record, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		// The context can never be cancelled
+		return q.Execute()
+	}
+
+	token := C.lancedb_cancel_token_new()
+	defer C.lancedb_cancel_token_free(token)
+
+	// Setting the token only fails for a closed query
+	if int(C.lancedb_query_set_cancel_token(q.handle, token)) != 0 {
+		return nil, &Error{Code: ErrorCodeClosed, Message: "query is closed"}
+	}
+	// Detach the token so later executions of the query aren't cancelled
+	defer C.lancedb_query_set_cancel_token(q.handle, nil)
+
+	type outcome struct {
+		records []arrow.Record
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		records, err := q.Execute()
+		done <- outcome{records, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.records, o.err
+	case <-ctx.Done():
+		C.lancedb_cancel_token_cancel(token)
+		// Wait for the native call, which still uses the query handle
+		o := <-done
+		for _, record := range o.records {
+			record.Release()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// ExecuteResults runs the query like Execute, returning the batches wrapped in a
+// QueryResults that releases them on Close
+func (q *Query) ExecuteResults() (*QueryResults, error) {
+	records, err := q.Execute()
+	if err != nil {
+		return nil, err
+	}
+	return NewQueryResults(records), nil
+}
+
+// ExecuteResultsContext runs the query like ExecuteContext, returning the batches
+// wrapped in a QueryResults that releases them on Close
+func (q *Query) ExecuteResultsContext(ctx context.Context) (*QueryResults, error) {
+	records, err := q.ExecuteContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewQueryResults(records), nil
+}
+
 // RecordIterator iterates over query results
 type RecordIterator interface {
 	Next() (arrow.Record, error)
@@ -4,8 +4,11 @@
 package lancedb
 
 import (
+	"context"
+	"errors"
 	"math"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/apache/arrow/go/v17/arrow"
@@ -351,6 +354,170 @@ func TestQueryWithOffset(t *testing.T) {
 	}
 }
 
+func TestQueryBatchSize(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_db")
+
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: false},
+		},
+		nil,
+	)
+
+	table, err := db.CreateTableWithSchema("batch_size_test", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	// Add 10,000 rows in a single fragment
+	const numRows = 10000
+	idBuilder := array.NewInt32Builder(pool)
+	for i := 0; i < numRows; i++ {
+		idBuilder.Append(int32(i))
+	}
+	idArray := idBuilder.NewArray()
+	record := array.NewRecord(schema, []arrow.Array{idArray}, numRows)
+
+	err = table.Add(record, AddModeAppend)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	idBuilder.Release()
+	idArray.Release()
+	record.Release()
+
+	// Stream with a batch size of 500
+	query := table.Query().BatchSize(500)
+	defer query.Close()
+	iter, err := query.ExecuteStreaming()
+	if err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+	defer iter.Close()
+
+	totalRows := int64(0)
+	batches := 0
+	for {
+		batch, err := iter.Next()
+		if err != nil {
+			t.Fatalf("Failed to read batch: %v", err)
+		}
+		if batch == nil {
+			break
+		}
+		if batch.NumRows() > 500 {
+			t.Errorf("Expected batches of at most 500 rows, got %d", batch.NumRows())
+		}
+		totalRows += batch.NumRows()
+		batches++
+		batch.Release()
+	}
+
+	if totalRows != numRows {
+		t.Errorf("Expected %d rows, got %d", numRows, totalRows)
+	}
+	if batches < numRows/500 {
+		t.Errorf("Expected at least %d batches, got %d", numRows/500, batches)
+	}
+
+	// Invalid batch sizes are ignored
+	query = table.Query().BatchSize(0).BatchSize(-1)
+	defer query.Close()
+	results, err := query.Execute()
+	if err != nil {
+		t.Fatalf("Failed to execute query with ignored batch size: %v", err)
+	}
+	totalRows = 0
+	for _, r := range results {
+		totalRows += r.NumRows()
+		r.Release()
+	}
+	if totalRows != numRows {
+		t.Errorf("Expected %d rows, got %d", numRows, totalRows)
+	}
+}
+
+func TestQueryOrderBy(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_db")
+
+	db, err := Connect(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: false},
+		},
+		nil,
+	)
+
+	table, err := db.CreateTableWithSchema("order_by_test", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	// Append in descending order, so storage order differs from the sorted order
+	idBuilder := array.NewInt32Builder(pool)
+	for i := 99; i >= 0; i-- {
+		idBuilder.Append(int32(i))
+	}
+	idArray := idBuilder.NewArray()
+	record := array.NewRecord(schema, []arrow.Array{idArray}, 100)
+	if err := table.Add(record, AddModeAppend); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	idBuilder.Release()
+	idArray.Release()
+	record.Release()
+
+	readIDs := func(query *Query) []int32 {
+		defer query.Close()
+		results, err := query.Execute()
+		if err != nil {
+			t.Fatalf("Failed to execute query: %v", err)
+		}
+		var ids []int32
+		for _, r := range results {
+			ids = append(ids, r.Column(0).(*array.Int32).Int32Values()...)
+			r.Release()
+		}
+		return ids
+	}
+
+	// Offset and Limit apply to the sorted rows
+	ids := readIDs(table.Query().OrderBy("id", true).Offset(10).Limit(5))
+	if want := []int32{10, 11, 12, 13, 14}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Expected %v, got %v", want, ids)
+	}
+
+	ids = readIDs(table.Query().Where("id < 50").OrderBy("id", false).Limit(3))
+	if want := []int32{49, 48, 47}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Expected %v, got %v", want, ids)
+	}
+
+	// Vector searches are ranked and can't be ordered
+	query := table.Query().NearestTo([]float32{1}).OrderBy("id", true)
+	defer query.Close()
+	if _, err := query.Execute(); err == nil {
+		t.Error("Expected an error ordering a vector search")
+	}
+}
+
 func TestQuerySelect(t *testing.T) {
 	pool := memory.NewGoAllocator()
 	tmpDir := t.TempDir()
@@ -521,6 +688,82 @@ func TestDistanceTypes(t *testing.T) {
 	}
 }
 
+// TestQueryBypassVectorIndex tests flat-scan vector queries
+func TestQueryBypassVectorIndex(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	tmpDir := t.TempDir()
+
+	db, err := Connect(filepath.Join(tmpDir, "test_db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema(
+		[]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: false},
+			{Name: "vector", Type: arrow.FixedSizeListOf(2, arrow.PrimitiveTypes.Float32), Nullable: false},
+		},
+		nil,
+	)
+
+	table, err := db.CreateTableWithSchema("bypass_test", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	idBuilder := array.NewInt32Builder(pool)
+	defer idBuilder.Release()
+	vectorBuilder := array.NewFixedSizeListBuilder(pool, 2, arrow.PrimitiveTypes.Float32)
+	defer vectorBuilder.Release()
+	float32Builder := vectorBuilder.ValueBuilder().(*array.Float32Builder)
+	for i := 0; i < 5; i++ {
+		idBuilder.Append(int32(i))
+		vectorBuilder.Append(true)
+		float32Builder.Append(float32(i))
+		float32Builder.Append(1)
+	}
+
+	idArray := idBuilder.NewArray()
+	defer idArray.Release()
+	vectorArray := vectorBuilder.NewArray()
+	defer vectorArray.Release()
+	record := array.NewRecord(schema, []arrow.Array{idArray, vectorArray}, 5)
+	defer record.Release()
+
+	if err := table.Add(record, AddModeAppend); err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	query := table.Query().
+		NearestTo([]float32{4, 1}).
+		SetDistanceType(DistanceTypeL2).
+		BypassVectorIndex().
+		Limit(2)
+	defer query.Close()
+
+	results, err := query.Execute()
+	if err != nil {
+		t.Fatalf("Failed to execute flat-scan query: %v", err)
+	}
+	totalRows := int64(0)
+	for _, r := range results {
+		totalRows += r.NumRows()
+		r.Release()
+	}
+	if totalRows != 2 {
+		t.Errorf("Expected 2 results, got %d", totalRows)
+	}
+
+	// Bypassing the index only applies to vector queries
+	plain := table.Query().BypassVectorIndex()
+	defer plain.Close()
+	if _, err := plain.Execute(); err == nil {
+		t.Error("Expected error bypassing the index on a non-vector query")
+	}
+}
+
 // BenchmarkVectorSearch measures vector search performance
 func BenchmarkVectorSearch(b *testing.B) {
 	pool := memory.NewGoAllocator()
@@ -587,3 +830,78 @@ func BenchmarkVectorSearch(b *testing.B) {
 	}
 }
 
+
+func TestQueryExecuteContext(t *testing.T) {
+	db, table := createCategoryTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	query := table.Query().Where("category = 'tech'")
+	defer query.Close()
+
+	records, err := query.ExecuteContext(ctx)
+	if err != nil {
+		t.Fatalf("ExecuteContext failed: %v", err)
+	}
+	var total int64
+	for _, record := range records {
+		total += record.NumRows()
+		record.Release()
+	}
+	if total != 10 {
+		t.Errorf("Expected 10 rows, got %d", total)
+	}
+
+	// The query can be executed again without a context
+	records, err = query.Execute()
+	if err != nil {
+		t.Fatalf("Execute after ExecuteContext failed: %v", err)
+	}
+	for _, record := range records {
+		record.Release()
+	}
+}
+
+func TestQueryExecuteContextCancelled(t *testing.T) {
+	db, table := createCategoryTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	query := table.Query()
+	defer query.Close()
+
+	// An already cancelled context doesn't run the query
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := query.ExecuteContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	// Cancelling while the query runs either stops it or loses the race to completion
+	ctx, cancel = context.WithCancel(context.Background())
+	go cancel()
+	records, err := query.ExecuteContext(ctx)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	for _, record := range records {
+		record.Release()
+	}
+
+	// The cancelled token doesn't affect later executions
+	records, err = query.Execute()
+	if err != nil {
+		t.Fatalf("Execute after cancellation failed: %v", err)
+	}
+	var total int64
+	for _, record := range records {
+		total += record.NumRows()
+		record.Release()
+	}
+	if total != 30 {
+		t.Errorf("Expected 30 rows, got %d", total)
+	}
+}
@@ -1,13 +1,19 @@
 package rag
 
 import (
+	"bufio"
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
 )
 
 // BackupFormat specifies the format for backup files
@@ -18,16 +24,23 @@ const (
 	BackupFormatJSON BackupFormat = "json"
 	// BackupFormatJSONGzip exports data as compressed JSON (smaller files)
 	BackupFormatJSONGzip BackupFormat = "json.gz"
+	// BackupFormatParquet exports data as columnar, compressed Parquet (smallest files, streamed on export)
+	BackupFormatParquet BackupFormat = "parquet"
+	// BackupFormatCSV exports IDs, document names, text and metadata as CSV.
+	// Embeddings are not included, so CSV exports cannot be imported.
+	BackupFormatCSV BackupFormat = "csv"
 )
 
 // BackupMetadata contains metadata about a backup file
 type BackupMetadata struct {
-	Version       string    `json:"version"`        // Backup format version
-	UserID        string    `json:"user_id"`        // User ID for this backup
-	Created       time.Time `json:"created"`        // When the backup was created
-	DocumentCount int       `json:"document_count"` // Number of documents in backup
-	EmbeddingDim  int       `json:"embedding_dim"`  // Embedding dimension
-	Format        string    `json:"format"`         // Backup format (json, json.gz)
+	Version       string    `json:"version"`                  // Backup format version
+	UserID        string    `json:"user_id"`                  // User ID for this backup
+	Created       time.Time `json:"created"`                  // When the backup was created
+	DocumentCount int       `json:"document_count"`           // Number of documents in backup
+	EmbeddingDim  int       `json:"embedding_dim"`            // Embedding dimension
+	Format        string    `json:"format"`                   // Backup format (json, json.gz, parquet, csv)
+	BaseVersion   uint64    `json:"base_version,omitempty"`   // Table version an incremental backup starts from (0 for full backups)
+	TargetVersion uint64    `json:"target_version,omitempty"` // Table version the backup reflects
 }
 
 // BackupDocument represents a document in the backup format
@@ -41,97 +54,23 @@ type BackupDocument struct {
 
 // BackupData represents the complete backup data structure
 type BackupData struct {
-	Metadata  BackupMetadata   `json:"metadata"`
-	Documents []BackupDocument `json:"documents"`
+	Metadata   BackupMetadata   `json:"metadata"`
+	Documents  []BackupDocument `json:"documents"`
+	DeletedIDs []string         `json:"deleted_ids,omitempty"` // Incremental backups only: IDs removed since BaseVersion
 }
 
 // ExportUserData exports all data for a user to a backup file.
-// The format parameter determines the output format (JSON or compressed JSON).
+// The format parameter determines the output format (JSON, compressed JSON, Parquet or CSV).
 func (s *RAGStore) ExportUserData(ctx context.Context, userID string, outputPath string, format BackupFormat) error {
-	// Validate user ID
-	if err := validateUserID(userID); err != nil {
-		return err
-	}
-
-	// Check if table exists
-	exists, err := s.TableExists(ctx, userID)
-	if err != nil {
-		return fmt.Errorf("failed to check if table exists: %w", err)
-	}
-	if !exists {
-		return fmt.Errorf("no data exists for user %s", userID)
-	}
-
-	// Open table
-	table, err := s.conn.OpenTable(s.getTableName(userID))
-	if err != nil {
-		return fmt.Errorf("failed to open table: %w", err)
-	}
-	defer table.Close()
-
-	// Get document count
-	count, err := table.CountRows()
-	if err != nil {
-		return fmt.Errorf("failed to count documents: %w", err)
-	}
-
-	// Build metadata
-	metadata := BackupMetadata{
-		Version:       "1.0",
-		UserID:        userID,
-		Created:       time.Now(),
-		DocumentCount: int(count),
-		EmbeddingDim:  s.embeddingDim,
-		Format:        string(format),
-	}
-
-	// Query all documents
-	query := table.Query()
-	defer query.Close()
-
-	records, err := query.Select("id", "text", "document_name", "embedding", "metadata").Execute()
-	if err != nil {
-		return fmt.Errorf("failed to query documents: %w", err)
-	}
-
-	// Convert to backup format
-	var documents []BackupDocument
-	for _, record := range records {
-		results, err := parseSearchResults(record, s.embeddingDim)
-		if err != nil {
-			// Clean up
-			for _, r := range records {
-				r.Release()
-			}
-			return fmt.Errorf("failed to parse documents: %w", err)
-		}
-
-		for _, result := range results {
-			documents = append(documents, BackupDocument{
-				ID:           result.ID,
-				Text:         result.Text,
-				DocumentName: result.DocumentName,
-				Embedding:    result.Embedding,
-				Metadata:     result.Metadata,
-			})
-		}
-		record.Release()
-	}
-
-	// Create backup data structure
-	backupData := BackupData{
-		Metadata:  metadata,
-		Documents: documents,
-	}
-
-	// Write to file
-	return writeBackupFile(outputPath, backupData, format)
+	return s.ExportUserDataWithProgress(ctx, userID, outputPath, format, nil)
 }
 
-// ExportUserDataWithProgress exports user data with progress reporting
+// ExportUserDataWithProgress exports user data with progress reporting.
+// Documents are streamed into the backup file one record batch at a time,
+// so memory use does not grow with the size of the converted backup.
 func (s *RAGStore) ExportUserDataWithProgress(ctx context.Context, userID string, outputPath string, format BackupFormat, callback ProgressCallback) error {
 	// Validate user ID
-	if err := validateUserID(userID); err != nil {
+	if err := s.validateUserID(userID); err != nil {
 		return err
 	}
 
@@ -150,7 +89,7 @@ func (s *RAGStore) ExportUserDataWithProgress(ctx context.Context, userID string
 	}
 
 	// Open table
-	table, err := s.conn.OpenTable(s.getTableName(userID))
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
 	if err != nil {
 		return fmt.Errorf("failed to open table: %w", err)
 	}
@@ -161,6 +100,15 @@ func (s *RAGStore) ExportUserDataWithProgress(ctx context.Context, userID string
 		tracker.SetStage("counting")
 	}
 
+	// Pin the handle to the current version so the count and the documents agree
+	version, err := table.Version()
+	if err != nil {
+		return fmt.Errorf("failed to get table version: %w", err)
+	}
+	if err := table.Checkout(version); err != nil {
+		return fmt.Errorf("failed to check out table version %d: %w", version, err)
+	}
+
 	// Get document count
 	count, err := table.CountRows()
 	if err != nil {
@@ -174,72 +122,67 @@ func (s *RAGStore) ExportUserDataWithProgress(ctx context.Context, userID string
 	}
 
 	// Build metadata
+	dim := s.userEmbeddingDim(userID)
 	metadata := BackupMetadata{
 		Version:       "1.0",
 		UserID:        userID,
 		Created:       time.Now(),
 		DocumentCount: int(count),
-		EmbeddingDim:  s.embeddingDim,
+		EmbeddingDim:  dim,
 		Format:        string(format),
+		TargetVersion: version,
 	}
 
-	// Query all documents
-	query := table.Query()
-	defer query.Close()
-
-	records, err := query.Select("id", "text", "document_name", "embedding", "metadata").Execute()
-	if err != nil {
-		return fmt.Errorf("failed to query documents: %w", err)
+	if tracker != nil {
+		tracker.SetStage("writing")
+		tracker.SetMessage("Writing backup file")
 	}
 
-	// Convert to backup format
-	var documents []BackupDocument
-	for _, record := range records {
-		// Check for cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	// Read all documents from the pinned version as a stream of record batches
+	columns := documentColumns(s.userEmbeddingStorage(userID))
+	readRecords := func(fn func(arrow.Record) error) error {
+		return streamRecords(ctx, table, "", columns, fn)
+	}
 
-		results, err := parseSearchResults(record, s.embeddingDim)
-		if err != nil {
-			// Clean up
-			for _, r := range records {
-				r.Release()
-			}
-			return fmt.Errorf("failed to parse documents: %w", err)
+	// Parquet and CSV are written record by record
+	if format == BackupFormatParquet || format == BackupFormatCSV {
+		if err := writeRecordsBackup(outputPath, metadata, readRecords, s.getMetadataCodec(), tracker); err != nil {
+			return err
 		}
-
-		for _, result := range results {
-			documents = append(documents, BackupDocument{
-				ID:           result.ID,
-				Text:         result.Text,
-				DocumentName: result.DocumentName,
-				Embedding:    result.Embedding,
-				Metadata:     result.Metadata,
-			})
-
-			if tracker != nil {
-				tracker.Increment()
-			}
+		if tracker != nil {
+			tracker.Complete()
 		}
-		record.Release()
+		return nil
 	}
 
-	if tracker != nil {
-		tracker.SetStage("writing")
-		tracker.SetMessage("Writing backup file")
-	}
+	// Stream documents into the file, converting one record at a time
+	codec := s.getMetadataCodec()
+	err = writeBackupStream(outputPath, metadata, format, nil, func(emit func(BackupDocument) error) error {
+		return readRecords(func(record arrow.Record) error {
+			results, err := parseSearchResults(record, dim, codec)
+			if err != nil {
+				return fmt.Errorf("failed to parse documents: %w", err)
+			}
 
-	// Create backup data structure
-	backupData := BackupData{
-		Metadata:  metadata,
-		Documents: documents,
-	}
+			for _, result := range results {
+				if err := emit(BackupDocument{
+					ID:           result.ID,
+					Text:         result.Text,
+					DocumentName: result.DocumentName,
+					Embedding:    result.Embedding,
+					Metadata:     result.Metadata,
+				}); err != nil {
+					return err
+				}
 
-	// Write to file
-	if err := writeBackupFile(outputPath, backupData, format); err != nil {
+				if tracker != nil {
+					tracker.Increment()
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
 		return err
 	}
 
@@ -252,34 +195,160 @@ func (s *RAGStore) ExportUserDataWithProgress(ctx context.Context, userID string
 
 // writeBackupFile writes backup data to a file in the specified format
 func writeBackupFile(path string, data BackupData, format BackupFormat) error {
-	file, err := os.Create(path)
+	return writeBackupStream(path, data.Metadata, format, data.DeletedIDs, func(emit func(BackupDocument) error) error {
+		for _, doc := range data.Documents {
+			if err := emit(doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeBackupStream writes a JSON (optionally gzipped) backup file without holding all documents in memory.
+// The metadata is written first; produce then emits documents one at a time, and each is encoded immediately.
+// deletedIDs is written after the documents (incremental backups only).
+// The output is identical to encoding a BackupData value with two-space indentation.
+func writeBackupStream(path string, metadata BackupMetadata, format BackupFormat, deletedIDs []string, produce func(emit func(BackupDocument) error) error) error {
+	file, err := createBackupFile(path)
 	if err != nil {
-		return fmt.Errorf("failed to create backup file: %w", err)
+		return err
 	}
-	defer file.Close()
+	defer file.Abort()
 
 	var writer io.Writer = file
 
 	// Add compression if requested
+	var gzipWriter *gzip.Writer
 	if format == BackupFormatJSONGzip {
-		gzipWriter := gzip.NewWriter(file)
+		gzipWriter = gzip.NewWriter(file)
 		defer gzipWriter.Close()
 		writer = gzipWriter
 	}
 
-	// Encode as JSON
-	encoder := json.NewEncoder(writer)
-	encoder.SetIndent("", "  ") // Pretty print for readability
+	buffered := bufio.NewWriter(writer)
+
+	// Metadata first, so readers such as ValidateBackupFile only need the beginning of the file
+	metaJSON, err := json.MarshalIndent(metadata, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup metadata: %w", err)
+	}
+	buffered.WriteString("{\n  \"metadata\": ")
+	buffered.Write(metaJSON)
+	buffered.WriteString(",\n  \"documents\": [")
+
+	written := 0
+	emit := func(doc BackupDocument) error {
+		docJSON, err := json.MarshalIndent(doc, "    ", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode document %s: %w", doc.ID, err)
+		}
+		if written > 0 {
+			buffered.WriteString(",")
+		}
+		buffered.WriteString("\n    ")
+		if _, err := buffered.Write(docJSON); err != nil {
+			return fmt.Errorf("failed to write backup data: %w", err)
+		}
+		written++
+		return nil
+	}
+
+	if err := produce(emit); err != nil {
+		return err
+	}
+
+	// The metadata has already been written, so it must match what was streamed
+	if written != metadata.DocumentCount {
+		return fmt.Errorf("document count changed during export: metadata declares %d, wrote %d", metadata.DocumentCount, written)
+	}
+
+	if written > 0 {
+		buffered.WriteString("\n  ")
+	}
+	buffered.WriteString("]")
+
+	if len(deletedIDs) > 0 {
+		deletedJSON, err := json.MarshalIndent(deletedIDs, "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode deleted IDs: %w", err)
+		}
+		buffered.WriteString(",\n  \"deleted_ids\": ")
+		buffered.Write(deletedJSON)
+	}
+	buffered.WriteString("\n}\n")
 
-	if err := encoder.Encode(data); err != nil {
-		return fmt.Errorf("failed to encode backup data: %w", err)
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write backup data: %w", err)
+	}
+	if gzipWriter != nil {
+		if err := gzipWriter.Close(); err != nil {
+			return fmt.Errorf("failed to finish compressed backup: %w", err)
+		}
+	}
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("failed to close backup file: %w", err)
 	}
 
 	return nil
 }
 
+// backupFile is a backup being written under a temporary name in the target's directory.
+// Commit renames it to the target path, so a failed or cancelled export never leaves a
+// truncated file there and never replaces an existing backup.
+type backupFile struct {
+	*os.File
+	path      string
+	committed bool
+}
+
+// createBackupFile creates the temporary file for a backup written to path
+func createBackupFile(path string) (*backupFile, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup file: %w", err)
+	}
+	// CreateTemp restricts the file to its owner; make backups readable like files from os.Create
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to create backup file: %w", err)
+	}
+	return &backupFile{File: file, path: path}, nil
+}
+
+// Commit closes the file (unless its writer already did) and renames it to the target path
+func (f *backupFile) Commit() error {
+	if err := f.File.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		return err
+	}
+	f.committed = true
+	return nil
+}
+
+// Abort closes and removes the temporary file unless it has been committed
+func (f *backupFile) Abort() {
+	if f.committed {
+		return
+	}
+	f.File.Close()
+	os.Remove(f.Name())
+}
+
 // ValidateBackupFile validates a backup file and returns its metadata
 func ValidateBackupFile(path string) (*BackupMetadata, error) {
+	// Parquet backups carry their metadata in the file footer
+	isParquet, err := isParquetFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if isParquet {
+		return validateParquetBackupFile(path)
+	}
+
 	// Open file
 	file, err := os.Open(path)
 	if err != nil {
@@ -331,6 +400,14 @@ func ValidateBackupFile(path string) (*BackupMetadata, error) {
 
 // readBackupFile reads and parses a backup file
 func readBackupFile(path string) (*BackupData, error) {
+	isParquet, err := isParquetFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if isParquet {
+		return readParquetBackupFile(path)
+	}
+
 	// Open file
 	file, err := os.Open(path)
 	if err != nil {
@@ -381,8 +458,15 @@ func (s *RAGStore) ImportUserData(ctx context.Context, userID string, inputPath
 
 // ImportUserDataWithProgress imports data with progress reporting
 func (s *RAGStore) ImportUserDataWithProgress(ctx context.Context, userID string, inputPath string, clearExisting bool, callback ProgressCallback) error {
+	return s.importUserData(ctx, userID, inputPath, &ImportOptions{ClearExisting: clearExisting}, callback)
+}
+
+// importUserData restores a full backup into a user's table according to opts
+func (s *RAGStore) importUserData(ctx context.Context, userID string, inputPath string, opts *ImportOptions, callback ProgressCallback) error {
+	clearExisting := opts.ClearExisting
+
 	// Validate user ID
-	if err := validateUserID(userID); err != nil {
+	if err := s.validateUserID(userID); err != nil {
 		return err
 	}
 
@@ -397,10 +481,15 @@ func (s *RAGStore) ImportUserDataWithProgress(ctx context.Context, userID string
 		return fmt.Errorf("backup file validation failed: %w", err)
 	}
 
-	// Check embedding dimensions match
-	if metadata.EmbeddingDim != s.embeddingDim {
+	// Incremental backups only hold changes and must be applied on top of existing data
+	if metadata.BaseVersion != 0 {
+		return fmt.Errorf("backup is incremental (since version %d); use ImportUserDataIncremental", metadata.BaseVersion)
+	}
+
+	// Check embedding dimensions match the user's dimension
+	if dim := s.userEmbeddingDim(userID); metadata.EmbeddingDim != dim {
 		return fmt.Errorf("backup embedding dimension (%d) does not match store dimension (%d)",
-			metadata.EmbeddingDim, s.embeddingDim)
+			metadata.EmbeddingDim, dim)
 	}
 
 	if tracker != nil {
@@ -415,6 +504,18 @@ func (s *RAGStore) ImportUserDataWithProgress(ctx context.Context, userID string
 		return fmt.Errorf("failed to read backup file: %w", err)
 	}
 
+	// Check every document before touching existing data
+	if !opts.SkipValidation {
+		valid, invalid := validateBackupDocuments(backupData.Documents, metadata.EmbeddingDim)
+		if len(invalid) > 0 {
+			if !opts.SkipErrors {
+				return fmt.Errorf("backup contains %d invalid documents: %s", len(invalid), summarizeInvalidDocuments(invalid))
+			}
+			s.logger.Printf("Skipping %d invalid documents in %s: %s", len(invalid), inputPath, summarizeInvalidDocuments(invalid))
+			backupData.Documents = valid
+		}
+	}
+
 	if tracker != nil {
 		tracker.Add(10)
 		tracker.SetStage("preparing")
@@ -506,9 +607,10 @@ func (s *RAGStore) ImportUserDataWithProgress(ctx context.Context, userID string
 
 // ImportOptions configures the import behavior
 type ImportOptions struct {
-	ClearExisting bool // If true, clear existing data before import
-	ValidateOnly  bool // If true, only validate the backup file without importing
-	SkipErrors    bool // If true, skip documents that fail validation and continue
+	ClearExisting  bool // If true, clear existing data before import
+	ValidateOnly   bool // If true, only validate the backup file without importing
+	SkipErrors     bool // If true, skip documents that fail validation and continue
+	SkipValidation bool // If true, don't check each document's ID and embedding before inserting
 }
 
 // ImportUserDataWithOptions provides advanced import options
@@ -528,6 +630,55 @@ func (s *RAGStore) ImportUserDataWithOptions(ctx context.Context, userID string,
 	}
 
 	// Regular import
-	return s.ImportUserDataWithProgress(ctx, userID, inputPath, opts.ClearExisting, callback)
+	return s.importUserData(ctx, userID, inputPath, opts, callback)
+}
+
+// maxReportedInvalidDocuments limits how many offending document IDs an import error lists
+const maxReportedInvalidDocuments = 10
+
+// invalidBackupDocument describes a backup document that failed validation
+type invalidBackupDocument struct {
+	index  int    // position of the document in the backup
+	id     string // document ID (may be empty)
+	reason string // why the document is invalid
+}
+
+// validateBackupDocuments checks that every document has an ID and a finite embedding of length dim.
+// It returns the valid documents and a description of each invalid one.
+func validateBackupDocuments(docs []BackupDocument, dim int) ([]BackupDocument, []invalidBackupDocument) {
+	var invalid []invalidBackupDocument
+	valid := make([]BackupDocument, 0, len(docs))
+
+	for i, doc := range docs {
+		reason := "missing ID"
+		if doc.ID != "" {
+			reason = documentFieldsError(doc.Text, doc.Embedding, dim)
+		}
+
+		if reason != "" {
+			invalid = append(invalid, invalidBackupDocument{index: i, id: doc.ID, reason: reason})
+			continue
+		}
+		valid = append(valid, doc)
+	}
+
+	return valid, invalid
+}
+
+// summarizeInvalidDocuments describes the first maxReportedInvalidDocuments invalid documents
+func summarizeInvalidDocuments(invalid []invalidBackupDocument) string {
+	parts := make([]string, 0, maxReportedInvalidDocuments+1)
+	for i, doc := range invalid {
+		if i == maxReportedInvalidDocuments {
+			parts = append(parts, fmt.Sprintf("and %d more", len(invalid)-i))
+			break
+		}
+		if doc.id == "" {
+			parts = append(parts, fmt.Sprintf("document #%d (%s)", doc.index, doc.reason))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s (%s)", doc.id, doc.reason))
+		}
+	}
+	return strings.Join(parts, ", ")
 }
 
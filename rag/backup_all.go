@@ -0,0 +1,217 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BulkExportOptions configures ExportAllUsersWithOptions
+type BulkExportOptions struct {
+	SkipErrors bool // If true, continue with the remaining users when one user's export fails
+}
+
+// ExportAllUsers writes one backup file per user into outputDir, named "<userID>.<format>".
+// Tables that don't belong to a user are skipped.
+func (s *RAGStore) ExportAllUsers(ctx context.Context, outputDir string, format BackupFormat) error {
+	return s.ExportAllUsersWithOptions(ctx, outputDir, format, nil, nil)
+}
+
+// ExportAllUsersWithOptions exports every user with options and aggregate progress reporting.
+// Progress counts documents across all users; the message names the user being exported.
+// With SkipErrors set, failed users are skipped and reported together in the returned error
+// once all other users have been exported.
+func (s *RAGStore) ExportAllUsersWithOptions(ctx context.Context, outputDir string, format BackupFormat, opts *BulkExportOptions, callback ProgressCallback) error {
+	if opts == nil {
+		opts = &BulkExportOptions{}
+	}
+
+	ext, err := backupFileExtension(format)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	userIDs, err := s.listUserIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Count documents up front so progress spans all users
+	counts := make(map[string]int64, len(userIDs))
+	var total int64
+	for _, userID := range userIDs {
+		count, err := s.CountDocuments(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to count documents for user %s: %w", userID, err)
+		}
+		counts[userID] = count
+		total += count
+	}
+
+	var tracker *ProgressTracker
+	if callback != nil {
+		tracker = NewProgressTracker("exporting", total, callback)
+	}
+
+	var failures []error
+	for i, userID := range userIDs {
+		if tracker != nil {
+			tracker.SetMessage(fmt.Sprintf("Exporting user %s (%d/%d)", userID, i+1, len(userIDs)))
+		}
+
+		outputPath := filepath.Join(outputDir, userID+ext)
+		if err := s.ExportUserData(ctx, userID, outputPath, format); err != nil {
+			// Never skip past a cancellation
+			if !opts.SkipErrors || ctx.Err() != nil {
+				return fmt.Errorf("failed to export user %s: %w", userID, err)
+			}
+			s.logger.Printf("Skipping user %s: export failed: %v", userID, err)
+			failures = append(failures, fmt.Errorf("user %s: %w", userID, err))
+		}
+
+		if tracker != nil {
+			tracker.Add(counts[userID])
+		}
+	}
+
+	if tracker != nil {
+		tracker.Complete()
+		tracker.SetMessage("Export complete")
+	}
+
+	s.logger.Printf("Exported %d of %d users to %s", len(userIDs)-len(failures), len(userIDs), outputDir)
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to export %d users: %w", len(failures), errors.Join(failures...))
+	}
+	return nil
+}
+
+// ImportAllUsers restores every backup file in inputDir, as written by ExportAllUsers.
+// Each file is restored into the user recorded in its metadata, replacing that user's data.
+// CSV exports hold no embeddings and are reported as failures; other files that aren't
+// backups (by extension) are skipped.
+func (s *RAGStore) ImportAllUsers(ctx context.Context, inputDir string) error {
+	return s.ImportAllUsersWithOptions(ctx, inputDir, nil, nil)
+}
+
+// ImportAllUsersWithOptions restores every backup in inputDir with options and aggregate progress reporting.
+// ClearExisting applies to each user. With SkipErrors set, files that fail to restore are skipped
+// and reported together in the returned error once all other users have been restored.
+func (s *RAGStore) ImportAllUsersWithOptions(ctx context.Context, inputDir string, opts *ImportOptions, callback ProgressCallback) error {
+	if opts == nil {
+		opts = &ImportOptions{ClearExisting: true}
+	}
+
+	entries, err := os.ReadDir(inputDir)
+	if err != nil {
+		return fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isBackupName(entry.Name()) {
+			paths = append(paths, filepath.Join(inputDir, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+
+	// Validate every file up front so progress spans all users
+	var failures []error
+	fail := func(path string, err error) error {
+		if !opts.SkipErrors || ctx.Err() != nil {
+			return fmt.Errorf("failed to import %s: %w", filepath.Base(path), err)
+		}
+		s.logger.Printf("Skipping %s: %v", path, err)
+		failures = append(failures, fmt.Errorf("%s: %w", filepath.Base(path), err))
+		return nil
+	}
+
+	metadatas := make(map[string]*BackupMetadata, len(paths))
+	var total int64
+	for _, path := range paths {
+		if strings.HasSuffix(path, "."+string(BackupFormatCSV)) {
+			if err := fail(path, fmt.Errorf("CSV exports contain no embeddings and cannot be imported")); err != nil {
+				return err
+			}
+			continue
+		}
+
+		metadata, err := ValidateBackupFile(path)
+		if err != nil {
+			if err := fail(path, err); err != nil {
+				return err
+			}
+			continue
+		}
+		metadatas[path] = metadata
+		total += int64(metadata.DocumentCount)
+	}
+
+	var tracker *ProgressTracker
+	if callback != nil {
+		tracker = NewProgressTracker("importing", total, callback)
+	}
+
+	imported := 0
+	for _, path := range paths {
+		metadata, ok := metadatas[path]
+		if !ok {
+			continue
+		}
+
+		if tracker != nil {
+			tracker.SetMessage(fmt.Sprintf("Importing user %s", metadata.UserID))
+		}
+
+		if err := s.ImportUserDataWithOptions(ctx, metadata.UserID, path, opts, nil); err != nil {
+			if err := fail(path, err); err != nil {
+				return err
+			}
+		} else {
+			imported++
+		}
+
+		if tracker != nil {
+			tracker.Add(int64(metadata.DocumentCount))
+		}
+	}
+
+	if tracker != nil {
+		tracker.Complete()
+		tracker.SetMessage("Import complete")
+	}
+
+	s.logger.Printf("Imported %d of %d backups from %s", imported, len(paths), inputDir)
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to import %d backups: %w", len(failures), errors.Join(failures...))
+	}
+	return nil
+}
+
+// backupFileExtension returns the file extension used for a backup format
+func backupFileExtension(format BackupFormat) (string, error) {
+	switch format {
+	case BackupFormatJSON, BackupFormatJSONGzip, BackupFormatParquet, BackupFormatCSV:
+		return "." + string(format), nil
+	default:
+		return "", fmt.Errorf("unsupported backup format: %s", format)
+	}
+}
+
+// isBackupName reports whether a file name has the extension of a backup format
+func isBackupName(name string) bool {
+	for _, format := range []BackupFormat{BackupFormatJSON, BackupFormatJSONGzip, BackupFormatParquet, BackupFormatCSV} {
+		if strings.HasSuffix(name, "."+string(format)) {
+			return true
+		}
+	}
+	return false
+}
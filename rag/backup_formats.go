@@ -0,0 +1,302 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/parquet"
+	"github.com/apache/arrow/go/v17/parquet/compress"
+	"github.com/apache/arrow/go/v17/parquet/file"
+	"github.com/apache/arrow/go/v17/parquet/pqarrow"
+)
+
+// parquetBackupMetadataKey is the Parquet key-value metadata entry holding the BackupMetadata JSON
+const parquetBackupMetadataKey = "rag_backup_metadata"
+
+// parquetBackupBatchSize is the number of rows read per batch when importing a Parquet backup
+const parquetBackupBatchSize = 1024
+
+// parquetMagic is the marker at the start of every Parquet file
+var parquetMagic = []byte("PAR1")
+
+// recordBackupWriter writes backup documents to a file one batch at a time
+type recordBackupWriter interface {
+	WriteBatch(results []SearchResult) error
+	Close() error
+}
+
+// writeRecordsBackup streams query records into a Parquet or CSV backup file.
+// readRecords calls its function with each record in turn (e.g., streamRecords); records
+// are converted and written one at a time, so no full copy of the user's documents is
+// built in memory. Record metadata is decoded with codec.
+func writeRecordsBackup(path string, metadata BackupMetadata, readRecords func(func(arrow.Record) error) error, codec MetadataCodec, tracker *ProgressTracker) error {
+	format := BackupFormat(metadata.Format)
+	if format != BackupFormatParquet && format != BackupFormatCSV {
+		return fmt.Errorf("unsupported streaming backup format: %s", metadata.Format)
+	}
+
+	file, err := createBackupFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+
+	var writer recordBackupWriter
+	if format == BackupFormatParquet {
+		writer, err = newParquetBackupWriter(file, metadata)
+	} else {
+		writer, err = newCSVBackupWriter(file)
+	}
+	if err != nil {
+		return err
+	}
+
+	written := 0
+	err = readRecords(func(record arrow.Record) error {
+		results, err := parseSearchResults(record, metadata.EmbeddingDim, codec)
+		if err != nil {
+			return fmt.Errorf("failed to parse documents: %w", err)
+		}
+
+		if err := writer.WriteBatch(results); err != nil {
+			return err
+		}
+
+		written += len(results)
+		if tracker != nil {
+			tracker.Add(int64(len(results)))
+		}
+		return nil
+	})
+	if err != nil {
+		writer.Close()
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finalize backup file: %w", err)
+	}
+
+	// The metadata was fixed before writing (and is embedded in Parquet files), so it must match
+	if written != metadata.DocumentCount {
+		return fmt.Errorf("document count changed during export: metadata declares %d, wrote %d", metadata.DocumentCount, written)
+	}
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("failed to close backup file: %w", err)
+	}
+	return nil
+}
+
+// parquetBackupWriter writes documents as Parquet row groups.
+// The backup metadata is stored in the file's key-value metadata.
+type parquetBackupWriter struct {
+	writer *pqarrow.FileWriter
+	schema *arrow.Schema
+}
+
+// newParquetBackupWriter creates a Parquet backup writing to f
+func newParquetBackupWriter(f io.Writer, metadata BackupMetadata) (*parquetBackupWriter, error) {
+	metaJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode backup metadata: %w", err)
+	}
+
+	kv := arrow.NewMetadata([]string{parquetBackupMetadataKey}, []string{string(metaJSON)})
+	schema := arrow.NewSchema(documentSchema(metadata.EmbeddingDim).Fields(), &kv)
+
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Zstd))
+	// Storing the Arrow schema restores the fixed-size embedding lists on read
+	arrowProps := pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema())
+
+	writer, err := pqarrow.NewFileWriter(schema, f, props, arrowProps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Parquet writer: %w", err)
+	}
+
+	return &parquetBackupWriter{writer: writer, schema: schema}, nil
+}
+
+// WriteBatch writes the documents as a single row group
+func (w *parquetBackupWriter) WriteBatch(results []SearchResult) error {
+	docs := make([]Document, len(results))
+	for i, result := range results {
+		docs[i] = Document{
+			ID:           result.ID,
+			Text:         result.Text,
+			DocumentName: result.DocumentName,
+			Embedding:    result.Embedding,
+			Metadata:     result.Metadata,
+		}
+	}
+
+	record, err := buildDocumentRecord(w.schema, docs, JSONMetadataCodec{})
+	if err != nil {
+		return err
+	}
+	defer record.Release()
+
+	if err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write Parquet row group: %w", err)
+	}
+	return nil
+}
+
+// Close writes the Parquet footer
+func (w *parquetBackupWriter) Close() error {
+	return w.writer.Close()
+}
+
+// csvBackupWriter writes document IDs, names, text and metadata as CSV.
+// Embeddings are not included.
+type csvBackupWriter struct {
+	writer *csv.Writer
+}
+
+// newCSVBackupWriter creates a CSV export writing to f and writes the header row
+func newCSVBackupWriter(f io.Writer) (*csvBackupWriter, error) {
+	writer := csv.NewWriter(f)
+	if err := writer.Write([]string{"id", "document_name", "text", "metadata"}); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	return &csvBackupWriter{writer: writer}, nil
+}
+
+// WriteBatch writes one CSV row per document
+func (w *csvBackupWriter) WriteBatch(results []SearchResult) error {
+	for _, result := range results {
+		metaJSON, err := encodeMetadata(result.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata for document %s: %w", result.ID, err)
+		}
+		if err := w.writer.Write([]string{result.ID, result.DocumentName, result.Text, metaJSON}); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close flushes buffered rows
+func (w *csvBackupWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// isParquetFile reports whether the file at path starts with the Parquet magic number
+func isParquetFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, len(parquetMagic))
+	if _, err := io.ReadFull(f, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read file header: %w", err)
+	}
+
+	return bytes.Equal(header, parquetMagic), nil
+}
+
+// readParquetBackupMetadata decodes the backup metadata stored in a Parquet backup's footer
+func readParquetBackupMetadata(reader *file.Reader) (*BackupMetadata, error) {
+	value := reader.MetaData().KeyValueMetadata().FindValue(parquetBackupMetadataKey)
+	if value == nil {
+		return nil, fmt.Errorf("Parquet file is not a backup: missing %s metadata", parquetBackupMetadataKey)
+	}
+
+	var metadata BackupMetadata
+	if err := json.Unmarshal([]byte(*value), &metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode backup metadata: %w", err)
+	}
+
+	// Validate version
+	if metadata.Version != "1.0" {
+		return nil, fmt.Errorf("unsupported backup version: %s", metadata.Version)
+	}
+
+	if reader.NumRows() != int64(metadata.DocumentCount) {
+		return nil, fmt.Errorf("backup declares %d documents but contains %d", metadata.DocumentCount, reader.NumRows())
+	}
+
+	return &metadata, nil
+}
+
+// validateParquetBackupFile validates a Parquet backup file and returns its metadata
+func validateParquetBackupFile(path string) (*BackupMetadata, error) {
+	reader, err := file.OpenParquetFile(path, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Parquet backup: %w", err)
+	}
+	defer reader.Close()
+
+	return readParquetBackupMetadata(reader)
+}
+
+// readParquetBackupFile reads all documents of a Parquet backup file
+func readParquetBackupFile(path string) (*BackupData, error) {
+	reader, err := file.OpenParquetFile(path, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Parquet backup: %w", err)
+	}
+	defer reader.Close()
+
+	metadata, err := readParquetBackupMetadata(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	arrowReader, err := pqarrow.NewFileReader(reader, pqarrow.ArrowReadProperties{BatchSize: parquetBackupBatchSize}, memory.DefaultAllocator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Parquet reader: %w", err)
+	}
+
+	schema, err := arrowReader.Schema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Parquet schema: %w", err)
+	}
+	if dim, ok := embeddingDimFromSchema(schema); !ok || dim != metadata.EmbeddingDim {
+		return nil, fmt.Errorf("Parquet schema does not match backup embedding dimension %d", metadata.EmbeddingDim)
+	}
+
+	records, err := arrowReader.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Parquet records: %w", err)
+	}
+	defer records.Release()
+
+	documents := make([]BackupDocument, 0, metadata.DocumentCount)
+	for records.Next() {
+		results, err := parseSearchResults(records.Record(), metadata.EmbeddingDim, JSONMetadataCodec{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse documents: %w", err)
+		}
+
+		for _, result := range results {
+			documents = append(documents, BackupDocument{
+				ID:           result.ID,
+				Text:         result.Text,
+				DocumentName: result.DocumentName,
+				Embedding:    result.Embedding,
+				Metadata:     result.Metadata,
+			})
+		}
+	}
+	// The reader reports io.EOF once all row groups have been read
+	if err := records.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read Parquet records: %w", err)
+	}
+
+	return &BackupData{Metadata: *metadata, Documents: documents}, nil
+}
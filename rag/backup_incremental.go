@@ -0,0 +1,305 @@
+package rag
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/aqua777/go-lancedb"
+)
+
+// ExportUserDataIncremental exports only the documents added or changed since sinceVersion,
+// plus the IDs of documents deleted since then. Use the TargetVersion of a previous backup's
+// metadata (full or incremental) as sinceVersion to chain backups.
+// The format must be BackupFormatJSON or BackupFormatJSONGzip.
+func (s *RAGStore) ExportUserDataIncremental(ctx context.Context, userID string, outputPath string, sinceVersion uint64, format BackupFormat) error {
+	// Validate user ID
+	if err := s.validateUserID(userID); err != nil {
+		return err
+	}
+	if sinceVersion == 0 {
+		return fmt.Errorf("since version must be a table version (1 or greater)")
+	}
+	if format != BackupFormatJSON && format != BackupFormatJSONGzip {
+		return fmt.Errorf("incremental backups must be JSON or compressed JSON, got %s", format)
+	}
+
+	// Check if table exists
+	exists, err := s.TableExists(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to check if table exists: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("no data exists for user %s", userID)
+	}
+
+	tableName := s.getTableName(userID)
+	table, err := s.getConn().OpenTable(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to open table: %w", err)
+	}
+	defer table.Close()
+
+	// Pin the handle to the current version so the diff is consistent
+	targetVersion, err := table.Version()
+	if err != nil {
+		return fmt.Errorf("failed to get table version: %w", err)
+	}
+	if sinceVersion > targetVersion {
+		return fmt.Errorf("since version %d is newer than the current table version %d", sinceVersion, targetVersion)
+	}
+	if err := table.Checkout(targetVersion); err != nil {
+		return fmt.Errorf("failed to check out table version %d: %w", targetVersion, err)
+	}
+
+	dim := s.userEmbeddingDim(userID)
+	codec := s.getMetadataCodec()
+	columns := documentColumns(s.userEmbeddingStorage(userID))
+
+	// Fingerprint the documents as of the base version through a second handle
+	base, err := s.getConn().OpenTable(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to open table: %w", err)
+	}
+	defer base.Close()
+
+	if err := base.Checkout(sinceVersion); err != nil {
+		return fmt.Errorf("failed to check out table version %d: %w", sinceVersion, err)
+	}
+	baseFingerprints := make(map[string]uint64)
+	err = streamRecords(ctx, base, "", columns, func(record arrow.Record) error {
+		results, fingerprints, err := recordFingerprints(record, dim, codec)
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			baseFingerprints[result.ID] = fingerprints[i]
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read version %d: %w", sinceVersion, err)
+	}
+
+	// First pass: find new and changed documents, so the count is known before writing
+	changed := make(map[string]bool)
+	err = streamRecords(ctx, table, "", columns, func(record arrow.Record) error {
+		results, fingerprints, err := recordFingerprints(record, dim, codec)
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			if old, ok := baseFingerprints[result.ID]; !ok || old != fingerprints[i] {
+				changed[result.ID] = true
+			}
+			delete(baseFingerprints, result.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read version %d: %w", targetVersion, err)
+	}
+
+	// Whatever remains of the base version no longer exists
+	deletedIDs := make([]string, 0, len(baseFingerprints))
+	for id := range baseFingerprints {
+		deletedIDs = append(deletedIDs, id)
+	}
+	sort.Strings(deletedIDs)
+
+	metadata := BackupMetadata{
+		Version:       "1.0",
+		UserID:        userID,
+		Created:       time.Now(),
+		DocumentCount: len(changed),
+		EmbeddingDim:  dim,
+		Format:        string(format),
+		BaseVersion:   sinceVersion,
+		TargetVersion: targetVersion,
+	}
+
+	// Second pass: stream the pinned version again and write the changed documents
+	return writeBackupStream(outputPath, metadata, format, deletedIDs, func(emit func(BackupDocument) error) error {
+		return streamRecords(ctx, table, "", columns, func(record arrow.Record) error {
+			results, err := parseSearchResults(record, dim, codec)
+			if err != nil {
+				return fmt.Errorf("failed to parse documents: %w", err)
+			}
+
+			for _, result := range results {
+				if !changed[result.ID] {
+					continue
+				}
+				// Emit each ID once, even if the table holds duplicates
+				changed[result.ID] = false
+
+				if err := emit(BackupDocument{
+					ID:           result.ID,
+					Text:         result.Text,
+					DocumentName: result.DocumentName,
+					Embedding:    result.Embedding,
+					Metadata:     result.Metadata,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// ImportUserDataIncremental applies an incremental backup to a user's data:
+// documents in the backup are upserted and deleted IDs are removed.
+// Incremental backups must be applied in order, on top of a restore of the backup
+// whose TargetVersion matches their BaseVersion.
+func (s *RAGStore) ImportUserDataIncremental(ctx context.Context, userID string, inputPath string) error {
+	// Validate user ID
+	if err := s.validateUserID(userID); err != nil {
+		return err
+	}
+
+	metadata, err := ValidateBackupFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("backup file validation failed: %w", err)
+	}
+	if metadata.BaseVersion == 0 {
+		return fmt.Errorf("backup is not incremental; use ImportUserData")
+	}
+
+	// Check embedding dimensions match the user's dimension
+	if dim := s.userEmbeddingDim(userID); metadata.EmbeddingDim != dim {
+		return fmt.Errorf("backup embedding dimension (%d) does not match store dimension (%d)",
+			metadata.EmbeddingDim, dim)
+	}
+
+	backupData, err := readBackupFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
+	}
+
+	if _, invalid := validateBackupDocuments(backupData.Documents, metadata.EmbeddingDim); len(invalid) > 0 {
+		return fmt.Errorf("backup contains %d invalid documents: %s", len(invalid), summarizeInvalidDocuments(invalid))
+	}
+
+	if len(backupData.DeletedIDs) > 0 {
+		if err := s.deleteDocumentsByID(ctx, userID, backupData.DeletedIDs); err != nil {
+			return fmt.Errorf("failed to apply deletions: %w", err)
+		}
+	}
+
+	if len(backupData.Documents) > 0 {
+		documents := make([]Document, len(backupData.Documents))
+		for i, backupDoc := range backupData.Documents {
+			documents[i] = Document{
+				ID:           backupDoc.ID,
+				Text:         backupDoc.Text,
+				DocumentName: backupDoc.DocumentName,
+				Embedding:    backupDoc.Embedding,
+				Metadata:     backupDoc.Metadata,
+			}
+		}
+		if err := s.UpsertDocuments(ctx, userID, documents); err != nil {
+			return fmt.Errorf("failed to import documents: %w", err)
+		}
+	}
+
+	s.logger.Printf("Applied incremental backup for user %s from %s: %d upserted, %d deleted (versions %d→%d)",
+		userID, inputPath, len(backupData.Documents), len(backupData.DeletedIDs), metadata.BaseVersion, metadata.TargetVersion)
+	return nil
+}
+
+// recordFingerprints parses the documents in a record and fingerprints each one
+func recordFingerprints(record arrow.Record, dim int, codec MetadataCodec) ([]SearchResult, []uint64, error) {
+	results, err := parseSearchResults(record, dim, codec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse documents: %w", err)
+	}
+
+	// Hash the stored metadata rather than re-encoding the decoded maps
+	var metadata []string
+	if column := recordColumn(record, "metadata"); column != nil {
+		if metadata, err = lancedb.StringColumnValues(column); err != nil {
+			return nil, nil, fmt.Errorf("failed to read metadata: %w", err)
+		}
+	}
+
+	fingerprints := make([]uint64, len(results))
+	for i, result := range results {
+		var rawMetadata string
+		if metadata != nil {
+			rawMetadata = metadata[i]
+		}
+		fingerprints[i] = documentFingerprint(result, rawMetadata)
+	}
+	return results, fingerprints, nil
+}
+
+// documentFingerprint hashes a document's text, name, stored metadata and embedding.
+// The hash only has to tell versions of a document apart, so a fast 64-bit hash is enough.
+func documentFingerprint(result SearchResult, metadata string) uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, field := range []string{result.Text, result.DocumentName, metadata} {
+		// Length-prefix each field so boundaries can't shift between fields
+		binary.LittleEndian.PutUint64(buf, uint64(len(field)))
+		h.Write(buf)
+		h.Write([]byte(field))
+	}
+	for _, v := range result.Embedding {
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(v))
+		h.Write(buf[:4])
+	}
+	return h.Sum64()
+}
+
+// deleteDocumentsByID removes the documents with the given IDs from a user's table
+// in one transaction
+func (s *RAGStore) deleteDocumentsByID(ctx context.Context, userID string, ids []string) error {
+	exists, err := s.TableExists(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	// Acquire per-user lock for write protection
+	unlock := s.lockUser(userID)
+	defer unlock()
+	defer s.invalidateTable(userID)
+
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
+	if err != nil {
+		return fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	defer table.Close()
+
+	txn, err := table.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer txn.Rollback()
+
+	for _, predicate := range idPredicates(ids) {
+		// Check for context cancellation between batches
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := txn.Delete(predicate); err != nil {
+			return fmt.Errorf("failed to delete documents: %w", err)
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit deletions: %w", err)
+	}
+	return nil
+}
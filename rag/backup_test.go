@@ -1,11 +1,17 @@
 package rag
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -358,3 +364,546 @@ func (s *BackupTestSuite) TestImportUserDataDimensionMismatch() {
 	s.Contains(err.Error(), "dimension")
 }
 
+
+// addBackupTestDocs adds 300 documents with deterministic embeddings (enough for the index to build)
+func (s *BackupTestSuite) addBackupTestDocs() []Document {
+	docs := make([]Document, 300)
+	for i := 0; i < 300; i++ {
+		docs[i] = Document{
+			ID:           fmt.Sprintf("doc%d", i),
+			Text:         fmt.Sprintf("test document %d", i),
+			DocumentName: "test.txt",
+			Embedding:    make([]float32, 128),
+			Metadata:     map[string]interface{}{"key": fmt.Sprintf("value%d", i)},
+		}
+		for j := range docs[i].Embedding {
+			docs[i].Embedding[j] = float32(i + j)
+		}
+	}
+
+	s.Require().NoError(s.store.AddDocuments(s.ctx, s.userID, docs))
+	return docs
+}
+
+func (s *BackupTestSuite) TestExportImportParquet() {
+	docs := s.addBackupTestDocs()
+
+	backupPath := filepath.Join(s.tmpDir, "backup.parquet")
+	err := s.store.ExportUserData(s.ctx, s.userID, backupPath, BackupFormatParquet)
+	s.Require().NoError(err)
+
+	// Metadata is read from the Parquet footer
+	metadata, err := ValidateBackupFile(backupPath)
+	s.Require().NoError(err)
+	s.Equal(string(BackupFormatParquet), metadata.Format)
+	s.Equal(300, metadata.DocumentCount)
+	s.Equal(128, metadata.EmbeddingDim)
+
+	// Sample a document straight from the file
+	backupData, err := readBackupFile(backupPath)
+	s.Require().NoError(err)
+	s.Require().Len(backupData.Documents, 300)
+	var sample *BackupDocument
+	for i := range backupData.Documents {
+		if backupData.Documents[i].ID == "doc42" {
+			sample = &backupData.Documents[i]
+		}
+	}
+	s.Require().NotNil(sample)
+	s.Equal(docs[42].Text, sample.Text)
+	s.Equal(docs[42].Embedding, sample.Embedding)
+	s.Equal("value42", sample.Metadata["key"])
+
+	// Restore into a different user
+	restoredUser := "restoreduser"
+	err = s.store.ImportUserData(s.ctx, restoredUser, backupPath, true)
+	s.Require().NoError(err)
+
+	count, err := s.store.CountDocuments(s.ctx, restoredUser)
+	s.NoError(err)
+	s.Equal(int64(300), count)
+
+	results, err := s.store.Search(s.ctx, restoredUser, docs[7].Embedding, &SearchOptions{Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.Equal("doc7", results[0].ID)
+	s.Equal(docs[7].Embedding, results[0].Embedding)
+}
+
+func (s *BackupTestSuite) TestExportCSV() {
+	s.addBackupTestDocs()
+
+	backupPath := filepath.Join(s.tmpDir, "backup.csv")
+	err := s.store.ExportUserData(s.ctx, s.userID, backupPath, BackupFormatCSV)
+	s.Require().NoError(err)
+
+	f, err := os.Open(backupPath)
+	s.Require().NoError(err)
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	s.Require().NoError(err)
+	s.Require().Len(rows, 301)
+	s.Equal([]string{"id", "document_name", "text", "metadata"}, rows[0])
+	s.Len(rows[1], 4)
+
+	// CSV exports have no embeddings and can't be restored
+	err = s.store.ImportUserData(s.ctx, "restoreduser", backupPath, true)
+	s.Error(err)
+}
+
+func (s *BackupTestSuite) TestStreamedExportContent() {
+	docs := s.addBackupTestDocs()
+
+	backupPath := filepath.Join(s.tmpDir, "backup.json")
+	err := s.store.ExportUserData(s.ctx, s.userID, backupPath, BackupFormatJSON)
+	s.Require().NoError(err)
+
+	backupData, err := readBackupFile(backupPath)
+	s.Require().NoError(err)
+	s.Equal(300, backupData.Metadata.DocumentCount)
+	s.Require().Len(backupData.Documents, 300)
+
+	// Every document must round-trip unchanged
+	byID := make(map[string]BackupDocument, len(backupData.Documents))
+	for _, doc := range backupData.Documents {
+		byID[doc.ID] = doc
+	}
+	for _, doc := range docs {
+		exported, ok := byID[doc.ID]
+		s.Require().True(ok, "missing %s", doc.ID)
+		s.Equal(doc.Text, exported.Text)
+		s.Equal(doc.DocumentName, exported.DocumentName)
+		s.Equal(doc.Embedding, exported.Embedding)
+		s.Equal(doc.Metadata["key"], exported.Metadata["key"])
+	}
+
+	// And import back into a fresh user
+	err = s.store.ImportUserData(s.ctx, "restoreduser", backupPath, true)
+	s.Require().NoError(err)
+	count, err := s.store.CountDocuments(s.ctx, "restoreduser")
+	s.NoError(err)
+	s.Equal(int64(300), count)
+}
+
+func (s *BackupTestSuite) TestIncrementalExportImport() {
+	docs := s.addBackupTestDocs()
+
+	// Full backup
+	fullPath := filepath.Join(s.tmpDir, "full.json")
+	err := s.store.ExportUserData(s.ctx, s.userID, fullPath, BackupFormatJSON)
+	s.Require().NoError(err)
+	fullMetadata, err := ValidateBackupFile(fullPath)
+	s.Require().NoError(err)
+	s.Require().NotZero(fullMetadata.TargetVersion)
+
+	// Add 10 documents and change an existing one
+	added := make([]Document, 10)
+	for i := range added {
+		added[i] = Document{
+			ID:           fmt.Sprintf("new%d", i),
+			Text:         fmt.Sprintf("new document %d", i),
+			DocumentName: "new.txt",
+			Embedding:    make([]float32, 128),
+			Metadata:     map[string]interface{}{"key": fmt.Sprintf("new%d", i)},
+		}
+		for j := range added[i].Embedding {
+			added[i].Embedding[j] = float32(1000 + i + j)
+		}
+	}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, s.userID, added))
+
+	modified := docs[42]
+	modified.Text = "modified document 42"
+	s.Require().NoError(s.store.UpsertDocuments(s.ctx, s.userID, []Document{modified}))
+
+	// Incremental backup on top of the full one
+	incrementalPath := filepath.Join(s.tmpDir, "incremental.json.gz")
+	err = s.store.ExportUserDataIncremental(s.ctx, s.userID, incrementalPath, fullMetadata.TargetVersion, BackupFormatJSONGzip)
+	s.Require().NoError(err)
+
+	incremental, err := readBackupFile(incrementalPath)
+	s.Require().NoError(err)
+	s.Equal(fullMetadata.TargetVersion, incremental.Metadata.BaseVersion)
+	s.Greater(incremental.Metadata.TargetVersion, incremental.Metadata.BaseVersion)
+	s.Equal(11, incremental.Metadata.DocumentCount)
+	s.Len(incremental.Documents, 11)
+	s.Empty(incremental.DeletedIDs)
+
+	// Incremental backups can't be restored on their own
+	err = s.store.ImportUserData(s.ctx, "restoreduser", incrementalPath, true)
+	s.Error(err)
+	s.Contains(err.Error(), "incremental")
+
+	// Restoring base + incremental yields the full dataset
+	restoredUser := "restoreduser"
+	s.Require().NoError(s.store.ImportUserData(s.ctx, restoredUser, fullPath, true))
+	s.Require().NoError(s.store.ImportUserDataIncremental(s.ctx, restoredUser, incrementalPath))
+
+	count, err := s.store.CountDocuments(s.ctx, restoredUser)
+	s.NoError(err)
+	s.Equal(int64(310), count)
+
+	results, err := s.store.Search(s.ctx, restoredUser, modified.Embedding, &SearchOptions{Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.Equal("doc42", results[0].ID)
+	s.Equal("modified document 42", results[0].Text)
+
+	results, err = s.store.Search(s.ctx, restoredUser, added[3].Embedding, &SearchOptions{Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.Equal("new3", results[0].ID)
+}
+
+func (s *BackupTestSuite) TestIncrementalExportInvalidVersion() {
+	s.addBackupTestDocs()
+
+	path := filepath.Join(s.tmpDir, "incremental.json")
+	s.Error(s.store.ExportUserDataIncremental(s.ctx, s.userID, path, 0, BackupFormatJSON))
+	s.Error(s.store.ExportUserDataIncremental(s.ctx, s.userID, path, 1_000_000, BackupFormatJSON))
+
+	// Deleted IDs can only be recorded in JSON backups
+	s.Error(s.store.ExportUserDataIncremental(s.ctx, s.userID, path, 1, BackupFormatParquet))
+}
+
+func (s *BackupTestSuite) TestExportImportAllUsers() {
+	s.addBackupTestDocs()
+
+	otherDocs := make([]Document, 20)
+	for i := range otherDocs {
+		otherDocs[i] = Document{
+			ID:           fmt.Sprintf("other%d", i),
+			Text:         fmt.Sprintf("other document %d", i),
+			DocumentName: "other.txt",
+			Embedding:    make([]float32, 128),
+		}
+		otherDocs[i].Embedding[i] = 1
+	}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "otheruser", otherDocs))
+
+	// Tables that don't belong to a user are skipped
+	table, err := s.store.conn.CreateTableWithSchema("unrelated", documentSchema(128))
+	s.Require().NoError(err)
+	table.Close()
+
+	backupDir := filepath.Join(s.tmpDir, "backups")
+	var lastProgress Progress
+	err = s.store.ExportAllUsersWithOptions(s.ctx, backupDir, BackupFormatJSONGzip, nil, func(p *Progress) {
+		lastProgress = *p
+	})
+	s.Require().NoError(err)
+	s.Equal(int64(320), lastProgress.Total)
+	s.Equal(int64(320), lastProgress.Current)
+
+	entries, err := os.ReadDir(backupDir)
+	s.Require().NoError(err)
+	s.Require().Len(entries, 2)
+	s.Equal("otheruser.json.gz", entries[0].Name())
+	s.Equal("testuser.json.gz", entries[1].Name())
+
+	// Restore into a fresh store
+	restored, err := NewRAGStore(filepath.Join(s.tmpDir, "restored.db"), 128)
+	s.Require().NoError(err)
+	defer restored.Close()
+
+	s.Require().NoError(restored.ImportAllUsers(s.ctx, backupDir))
+
+	count, err := restored.CountDocuments(s.ctx, s.userID)
+	s.NoError(err)
+	s.Equal(int64(300), count)
+	count, err = restored.CountDocuments(s.ctx, "otheruser")
+	s.NoError(err)
+	s.Equal(int64(20), count)
+
+	exists, err := restored.TableExists(s.ctx, "unrelated")
+	s.NoError(err)
+	s.False(exists)
+}
+
+func (s *BackupTestSuite) TestImportAllUsersSkipErrors() {
+	s.addBackupTestDocs()
+
+	backupDir := filepath.Join(s.tmpDir, "backups")
+	s.Require().NoError(s.store.ExportAllUsers(s.ctx, backupDir, BackupFormatJSON))
+	s.Require().NoError(os.WriteFile(filepath.Join(backupDir, "broken.json"), []byte("{not json"), 0644))
+	s.Require().NoError(os.WriteFile(filepath.Join(backupDir, "notes.txt"), []byte("ignored"), 0644))
+
+	restored, err := NewRAGStore(filepath.Join(s.tmpDir, "restored.db"), 128)
+	s.Require().NoError(err)
+	defer restored.Close()
+
+	// Without SkipErrors the corrupt file stops the import
+	err = restored.ImportAllUsers(s.ctx, backupDir)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "broken.json")
+
+	// With SkipErrors the remaining users are restored and the failure is reported
+	err = restored.ImportAllUsersWithOptions(s.ctx, backupDir, &ImportOptions{ClearExisting: true, SkipErrors: true}, nil)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "broken.json")
+	s.NotContains(err.Error(), "notes.txt")
+
+	count, err := restored.CountDocuments(s.ctx, s.userID)
+	s.NoError(err)
+	s.Equal(int64(300), count)
+}
+
+func (s *BackupTestSuite) TestImportAllUsersReportsCSV() {
+	s.addBackupTestDocs()
+
+	backupDir := filepath.Join(s.tmpDir, "backups")
+	s.Require().NoError(s.store.ExportAllUsers(s.ctx, backupDir, BackupFormatCSV))
+
+	restored, err := NewRAGStore(filepath.Join(s.tmpDir, "restored.db"), 128)
+	s.Require().NoError(err)
+	defer restored.Close()
+
+	// CSV exports can't be restored, so they are reported instead of silently skipped
+	err = restored.ImportAllUsersWithOptions(s.ctx, backupDir, &ImportOptions{ClearExisting: true, SkipErrors: true}, nil)
+	s.Require().Error(err)
+	s.Contains(err.Error(), s.userID+".csv")
+	s.Contains(err.Error(), "cannot be imported")
+}
+
+func (s *BackupTestSuite) TestImportRejectsWrongLengthEmbedding() {
+	docs := make([]BackupDocument, 300)
+	for i := range docs {
+		docs[i] = BackupDocument{
+			ID:           fmt.Sprintf("doc%d", i),
+			Text:         fmt.Sprintf("test document %d", i),
+			DocumentName: "test.txt",
+			Embedding:    make([]float32, 128),
+		}
+		docs[i].Embedding[i%128] = 1
+	}
+	docs[17].Embedding = docs[17].Embedding[:100]
+
+	backupPath := filepath.Join(s.tmpDir, "corrupt.json")
+	s.Require().NoError(writeBackupFile(backupPath, BackupData{
+		Metadata: BackupMetadata{
+			Version:       "1.0",
+			UserID:        s.userID,
+			Created:       time.Now(),
+			DocumentCount: len(docs),
+			EmbeddingDim:  128,
+			Format:        string(BackupFormatJSON),
+		},
+		Documents: docs,
+	}, BackupFormatJSON))
+
+	// The bad document is named and nothing is imported
+	err := s.store.ImportUserData(s.ctx, s.userID, backupPath, true)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "doc17")
+	s.Contains(err.Error(), "100 dimensions")
+	exists, err := s.store.TableExists(s.ctx, s.userID)
+	s.NoError(err)
+	s.False(exists)
+
+	// SkipErrors drops the bad document and imports the rest
+	err = s.store.ImportUserDataWithOptions(s.ctx, s.userID, backupPath, &ImportOptions{ClearExisting: true, SkipErrors: true}, nil)
+	s.Require().NoError(err)
+	count, err := s.store.CountDocuments(s.ctx, s.userID)
+	s.NoError(err)
+	s.Equal(int64(299), count)
+}
+
+// BackupStreamTestSuite tests the streaming backup writer without a database
+type BackupStreamTestSuite struct {
+	suite.Suite
+	tmpDir string
+}
+
+func TestBackupStreamSuite(t *testing.T) {
+	suite.Run(t, new(BackupStreamTestSuite))
+}
+
+func (s *BackupStreamTestSuite) SetupTest() {
+	s.tmpDir = s.T().TempDir()
+}
+
+// streamTestData returns a backup with n documents
+func streamTestData(n int) BackupData {
+	docs := make([]BackupDocument, n)
+	for i := range docs {
+		docs[i] = BackupDocument{
+			ID:           fmt.Sprintf("doc%d", i),
+			Text:         fmt.Sprintf("text <%d> & \"quoted\"", i),
+			DocumentName: "test.txt",
+			Embedding:    []float32{float32(i), 0.5, -1.25},
+			Metadata:     map[string]interface{}{"key": fmt.Sprintf("value%d", i), "n": i},
+		}
+	}
+	return BackupData{
+		Metadata: BackupMetadata{
+			Version:       "1.0",
+			UserID:        "testuser",
+			Created:       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			DocumentCount: n,
+			EmbeddingDim:  3,
+			Format:        string(BackupFormatJSON),
+		},
+		Documents: docs,
+	}
+}
+
+func (s *BackupStreamTestSuite) TestStreamedOutputMatchesBufferedEncoding() {
+	for _, n := range []int{0, 1, 25} {
+		data := streamTestData(n)
+
+		// The previous implementation encoded the whole BackupData at once
+		var buffered bytes.Buffer
+		encoder := json.NewEncoder(&buffered)
+		encoder.SetIndent("", "  ")
+		s.Require().NoError(encoder.Encode(data))
+
+		path := filepath.Join(s.tmpDir, fmt.Sprintf("backup_%d.json", n))
+		s.Require().NoError(writeBackupFile(path, data, BackupFormatJSON))
+
+		streamed, err := os.ReadFile(path)
+		s.Require().NoError(err)
+		s.Equal(buffered.String(), string(streamed), "%d documents", n)
+	}
+}
+
+func (s *BackupStreamTestSuite) TestStreamedGzipRoundTrip() {
+	data := streamTestData(50)
+	data.Metadata.Format = string(BackupFormatJSONGzip)
+
+	path := filepath.Join(s.tmpDir, "backup.json.gz")
+	s.Require().NoError(writeBackupFile(path, data, BackupFormatJSONGzip))
+
+	metadata, err := ValidateBackupFile(path)
+	s.Require().NoError(err)
+	s.Equal(50, metadata.DocumentCount)
+
+	restored, err := readBackupFile(path)
+	s.Require().NoError(err)
+	s.Equal(data.Metadata.UserID, restored.Metadata.UserID)
+	s.Require().Len(restored.Documents, 50)
+	s.Equal(data.Documents[17].Text, restored.Documents[17].Text)
+	s.Equal(data.Documents[17].Embedding, restored.Documents[17].Embedding)
+}
+
+func (s *BackupStreamTestSuite) TestStreamRejectsCountMismatch() {
+	data := streamTestData(3)
+	data.Metadata.DocumentCount = 4
+
+	err := writeBackupFile(filepath.Join(s.tmpDir, "backup.json"), data, BackupFormatJSON)
+	s.Error(err)
+	s.Contains(err.Error(), "document count")
+}
+
+func (s *BackupStreamTestSuite) TestStreamPropagatesProducerError() {
+	data := streamTestData(1)
+	path := filepath.Join(s.tmpDir, "backup.json")
+
+	err := writeBackupStream(path, data.Metadata, BackupFormatJSON, nil, func(emit func(BackupDocument) error) error {
+		return fmt.Errorf("source failed")
+	})
+	s.EqualError(err, "source failed")
+}
+
+func (s *BackupStreamTestSuite) TestIDPredicatesAreBatched() {
+	ids := make([]string, idPredicateBatchSize+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("doc%d", i)
+	}
+	ids[0] = "it's"
+
+	predicates := idPredicates(ids)
+	s.Require().Len(predicates, 2)
+	s.True(strings.HasPrefix(predicates[0], "id IN ('it''s', 'doc1', "))
+	s.Equal(fmt.Sprintf("id IN ('doc%d')", idPredicateBatchSize), predicates[1])
+	s.Empty(idPredicates(nil))
+}
+
+func (s *BackupStreamTestSuite) TestDocumentFingerprintSeparatesFields() {
+	doc := SearchResult{Text: "ab", DocumentName: "c", Embedding: []float32{1, 2}}
+	fingerprint := documentFingerprint(doc, `{"k":"v"}`)
+
+	shifted := doc
+	shifted.Text, shifted.DocumentName = "a", "bc"
+	s.NotEqual(fingerprint, documentFingerprint(shifted, `{"k":"v"}`))
+	s.NotEqual(fingerprint, documentFingerprint(doc, `{"k":"w"}`))
+
+	moved := doc
+	moved.Embedding = []float32{1, 2.5}
+	s.NotEqual(fingerprint, documentFingerprint(moved, `{"k":"v"}`))
+	s.Equal(fingerprint, documentFingerprint(doc, `{"k":"v"}`))
+}
+
+func (s *BackupStreamTestSuite) TestFailedStreamKeepsExistingFile() {
+	path := filepath.Join(s.tmpDir, "backup.json")
+	s.Require().NoError(writeBackupFile(path, streamTestData(2), BackupFormatJSON))
+	previous, err := os.ReadFile(path)
+	s.Require().NoError(err)
+
+	data := streamTestData(3)
+	data.Metadata.DocumentCount = 4
+	s.Error(writeBackupFile(path, data, BackupFormatJSON))
+
+	// The previous backup is untouched and no temporary file is left behind
+	current, err := os.ReadFile(path)
+	s.Require().NoError(err)
+	s.Equal(previous, current)
+	files, err := os.ReadDir(s.tmpDir)
+	s.Require().NoError(err)
+	s.Len(files, 1)
+}
+
+func (s *BackupStreamTestSuite) TestStreamedDeletedIDsMatchBufferedEncoding() {
+	data := streamTestData(2)
+	data.Metadata.BaseVersion = 3
+	data.Metadata.TargetVersion = 5
+	data.DeletedIDs = []string{"doc7", "doc9"}
+
+	var buffered bytes.Buffer
+	encoder := json.NewEncoder(&buffered)
+	encoder.SetIndent("", "  ")
+	s.Require().NoError(encoder.Encode(data))
+
+	path := filepath.Join(s.tmpDir, "incremental.json")
+	s.Require().NoError(writeBackupFile(path, data, BackupFormatJSON))
+
+	streamed, err := os.ReadFile(path)
+	s.Require().NoError(err)
+	s.Equal(buffered.String(), string(streamed))
+
+	restored, err := readBackupFile(path)
+	s.Require().NoError(err)
+	s.Equal(data.DeletedIDs, restored.DeletedIDs)
+	s.Equal(uint64(3), restored.Metadata.BaseVersion)
+}
+
+func (s *BackupStreamTestSuite) TestValidateBackupDocuments() {
+	docs := streamTestData(5).Documents
+	docs[1].ID = ""
+	docs[2].Embedding = []float32{1, 2}
+	docs[3].Text = ""
+	docs[4].Embedding[0] = float32(math.NaN())
+
+	valid, invalid := validateBackupDocuments(docs, 3)
+	s.Require().Len(valid, 1)
+	s.Equal("doc0", valid[0].ID)
+	s.Require().Len(invalid, 4)
+
+	summary := summarizeInvalidDocuments(invalid)
+	s.Contains(summary, "document #1 (missing ID)")
+	s.Contains(summary, "doc2 (embedding dimension mismatch: expected 3, got 2)")
+	s.Contains(summary, "doc3 (missing text)")
+	s.Contains(summary, "doc4 (embedding contains NaN or Inf)")
+}
+
+func (s *BackupStreamTestSuite) TestSummarizeInvalidDocumentsTruncates() {
+	invalid := make([]invalidBackupDocument, 25)
+	for i := range invalid {
+		invalid[i] = invalidBackupDocument{index: i, id: fmt.Sprintf("doc%d", i), reason: "missing ID"}
+	}
+
+	summary := summarizeInvalidDocuments(invalid)
+	s.Contains(summary, "doc9 ")
+	s.NotContains(summary, "doc10 ")
+	s.True(strings.HasSuffix(summary, "and 15 more"))
+}
@@ -0,0 +1,433 @@
+package rag
+
+import (
+	"container/heap"
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/aqua777/go-lancedb"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.5
+	bm25B  = 0.75
+)
+
+// defaultBM25CacheSize is the default number of BM25 corpora kept by a RAGStore
+const defaultBM25CacheSize = 8
+
+// bm25Corpus holds the statistics BM25 needs about a set of documents, so that the
+// documents are tokenized once rather than on every query
+type bm25Corpus struct {
+	docs         []SearchResult
+	termFreqs    []map[string]int // term counts of each document
+	docLengths   []int            // number of terms in each document
+	docFreqs     map[string]int   // number of documents containing each term
+	avgDocLength float32
+}
+
+// newBM25Corpus tokenizes documents with tokenizer and collects their statistics
+func newBM25Corpus(documents []SearchResult, tokenizer Tokenizer) *bm25Corpus {
+	c := &bm25Corpus{
+		docs:       documents,
+		termFreqs:  make([]map[string]int, len(documents)),
+		docLengths: make([]int, len(documents)),
+		docFreqs:   make(map[string]int),
+	}
+
+	totalLength := 0
+	for i, doc := range documents {
+		tokens := tokenizer.Tokenize(doc.Text)
+		termFreq := make(map[string]int)
+		for _, token := range tokens {
+			termFreq[token]++
+		}
+		for term := range termFreq {
+			c.docFreqs[term]++
+		}
+		c.termFreqs[i] = termFreq
+		c.docLengths[i] = len(tokens)
+		totalLength += len(tokens)
+	}
+	if len(documents) > 0 {
+		c.avgDocLength = float32(totalLength) / float32(len(documents))
+	}
+	return c
+}
+
+// score returns a copy of the corpus documents with their BM25 scores for queryTerms, in corpus order
+func (c *bm25Corpus) score(queryTerms []string) []SearchResult {
+	scored := make([]SearchResult, len(c.docs))
+	copy(scored, c.docs)
+	if len(c.docs) == 0 || len(queryTerms) == 0 {
+		return scored
+	}
+
+	idf := bm25IDF(queryTerms, c.docFreqs, len(c.docs))
+	for i := range scored {
+		scored[i].Score = bm25Score(queryTerms, idf, c.termFreqs[i], c.docLengths[i], c.avgDocLength)
+	}
+	return scored
+}
+
+// bm25IDF calculates the IDF of each query term that occurs in the corpus, given the number
+// of documents containing each term and the corpus size
+func bm25IDF(queryTerms []string, docFreqs map[string]int, numDocs int) map[string]float32 {
+	idf := make(map[string]float32)
+	for _, term := range queryTerms {
+		if docCount := docFreqs[term]; docCount > 0 {
+			numerator := float64(numDocs-docCount) + 0.5
+			denominator := float64(docCount) + 0.5
+			idf[term] = float32(math.Log(numerator / denominator))
+		}
+	}
+	return idf
+}
+
+// bm25Score calculates the BM25 score of a document with the given term counts and length
+func bm25Score(queryTerms []string, idf map[string]float32, termFreq map[string]int, docLength int, avgDocLength float32) float32 {
+	score := float32(0.0)
+	for _, term := range queryTerms {
+		if termIDF, ok := idf[term]; ok {
+			tf := float32(termFreq[term])
+			docLen := float32(docLength)
+
+			numerator := tf * (bm25K1 + 1)
+			denominator := tf + bm25K1*(1-bm25B+bm25B*(docLen/avgDocLength))
+
+			score += termIDF * (numerator / denominator)
+		}
+	}
+	return score
+}
+
+// streamBM25 scores the user's documents matching predicate with BM25 in two streaming
+// passes over the table, so memory use doesn't grow with the number of documents. The first
+// pass reads only the text to count documents, their total length and the documents
+// containing each query term; the second scores every document and keeps the best limit in
+// a min-heap. Scores are identical to in-memory BM25's. Results are sorted best first.
+// Both passes read the given table version, so documents written in between can't skew
+// the statistics.
+func (s *RAGStore) streamBM25(ctx context.Context, userID string, version uint64, predicate string, queryTerms []string, tokenizer Tokenizer, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		return []SearchResult{}, nil
+	}
+
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open table: %w", err)
+	}
+	defer table.Close()
+	if err := table.Checkout(version); err != nil {
+		return nil, fmt.Errorf("failed to check out table version %d: %w", version, err)
+	}
+
+	// First pass: corpus statistics
+	numDocs := 0
+	totalLength := 0
+	docFreqs := make(map[string]int, len(queryTerms))
+	err = streamRecords(ctx, table, predicate, []string{"text"}, func(record arrow.Record) error {
+		texts, err := recordTexts(record)
+		if err != nil {
+			return err
+		}
+		for _, text := range texts {
+			tokens := tokenizer.Tokenize(text)
+			numDocs++
+			totalLength += len(tokens)
+			for _, term := range queryTerms {
+				if containsTerm(tokens, term) {
+					docFreqs[term]++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if numDocs == 0 {
+		return []SearchResult{}, nil
+	}
+
+	idf := bm25IDF(queryTerms, docFreqs, numDocs)
+	avgDocLength := float32(totalLength) / float32(numDocs)
+	dim := s.userEmbeddingDim(userID)
+	codec := s.getMetadataCodec()
+
+	// Second pass: score every document, keeping the best limit
+	best := &bm25Heap{}
+	err = streamRecords(ctx, table, predicate, documentColumns(s.userEmbeddingStorage(userID)), func(record arrow.Record) error {
+		texts, err := recordTexts(record)
+		if err != nil {
+			return err
+		}
+		var batch []SearchResult
+		for i, text := range texts {
+			tokens := tokenizer.Tokenize(text)
+			termFreq := make(map[string]int, len(queryTerms))
+			for _, token := range tokens {
+				termFreq[token]++
+			}
+			score := bm25Score(queryTerms, idf, termFreq, len(tokens), avgDocLength)
+			if best.Len() == limit && score <= (*best)[0].Score {
+				continue
+			}
+
+			// Parse the record only once one of its documents is good enough
+			if batch == nil {
+				parsed, err := parseSearchResults(record, dim, codec)
+				if err != nil {
+					return fmt.Errorf("failed to parse results: %w", err)
+				}
+				batch = parsed
+			}
+			result := batch[i]
+			result.Score = score
+			if best.Len() == limit {
+				heap.Pop(best)
+			}
+			heap.Push(best, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, best.Len())
+	for i := len(results) - 1; i >= 0; i-- {
+		results[i] = heap.Pop(best).(SearchResult)
+	}
+	return results, nil
+}
+
+// recordTexts returns the values of record's text column, found by name
+func recordTexts(record arrow.Record) ([]string, error) {
+	return recordStrings(record, "text")
+}
+
+// recordStrings returns the values of the string column of record with the given name,
+// whichever string encoding it uses. The values point into the record's buffers.
+func recordStrings(record arrow.Record, name string) ([]string, error) {
+	column := recordColumn(record, name)
+	if column == nil {
+		return nil, fmt.Errorf("record has no %s column", name)
+	}
+	values, err := lancedb.StringColumnValues(column)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s column: %w", name, err)
+	}
+	return values, nil
+}
+
+// streamRecords runs a query selecting columns of the rows matching predicate and calls fn
+// with each record as it is read. Records are released after fn returns.
+func streamRecords(ctx context.Context, table *lancedb.Table, predicate string, columns []string, fn func(arrow.Record) error) error {
+	query := table.Query().Select(columns...)
+	defer query.Close()
+	if predicate != "" {
+		query = query.Where(predicate)
+	}
+
+	iter, err := query.ExecuteStreaming()
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer iter.Close()
+
+	for {
+		// Check for context cancellation between batches
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		record, err := iter.Next()
+		if err != nil {
+			return fmt.Errorf("failed to read documents: %w", err)
+		}
+		if record == nil {
+			return nil // End of stream
+		}
+
+		err = fn(record)
+		record.Release()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// containsTerm checks if a token list contains a specific term
+func containsTerm(tokens []string, term string) bool {
+	for _, token := range tokens {
+		if token == term {
+			return true
+		}
+	}
+	return false
+}
+
+// bm25Heap is a min-heap of results by score, holding the best results seen so far
+type bm25Heap []SearchResult
+
+func (h bm25Heap) Len() int            { return len(h) }
+func (h bm25Heap) Less(i, j int) bool  { return h[i].Score < h[j].Score }
+func (h bm25Heap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *bm25Heap) Push(x interface{}) { *h = append(*h, x.(SearchResult)) }
+func (h *bm25Heap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// bm25CacheKey identifies a corpus: the documents of a user's table at a version that
+// match a predicate, tokenized by the store's tokenizer at a generation
+type bm25CacheKey struct {
+	userID       string
+	predicate    string
+	version      uint64
+	tokenizerGen uint64
+}
+
+type bm25CacheEntry struct {
+	key    bm25CacheKey
+	corpus *bm25Corpus
+}
+
+// bm25Cache is a thread-safe LRU of BM25 corpora. A nil cache caches nothing.
+type bm25Cache struct {
+	mu      sync.Mutex
+	maxSize int
+	ll      *list.List
+	items   map[bm25CacheKey]*list.Element
+}
+
+// newBM25Cache creates a BM25 cache holding at most maxSize corpora; 0 disables caching
+func newBM25Cache(maxSize int) *bm25Cache {
+	return &bm25Cache{
+		maxSize: maxSize,
+		ll:      list.New(),
+		items:   make(map[bm25CacheKey]*list.Element),
+	}
+}
+
+// get returns the cached corpus for key
+func (c *bm25Cache) get(key bm25CacheKey) (*bm25Corpus, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*bm25CacheEntry).corpus, true
+}
+
+// put caches corpus under key, evicting the least recently used corpora over the limit
+func (c *bm25Cache) put(key bm25CacheKey, corpus *bm25Corpus) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxSize <= 0 {
+		return
+	}
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*bm25CacheEntry).corpus = corpus
+		c.ll.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&bm25CacheEntry{key: key, corpus: corpus})
+	c.evictOverflow()
+}
+
+// evictOverflow removes least recently used corpora until the cache fits. The caller must hold c.mu.
+func (c *bm25Cache) evictOverflow() {
+	for c.ll.Len() > c.maxSize {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*bm25CacheEntry).key)
+	}
+}
+
+// invalidateUser drops every corpus of userID
+func (c *bm25Cache) invalidateUser(userID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.items {
+		if key.userID == userID {
+			c.ll.Remove(elem)
+			delete(c.items, key)
+		}
+	}
+}
+
+// setMaxSize changes the cache limit, evicting corpora over it
+func (c *bm25Cache) setMaxSize(maxSize int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxSize = maxSize
+	c.evictOverflow()
+}
+
+// len returns the number of cached corpora
+func (c *bm25Cache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// SetBM25CacheSize sets how many document sets the in-memory BM25 keyword search keeps
+// tokenized between queries. A cached set is reused until the user's table changes, so
+// repeated hybrid searches don't re-tokenize every document. Each cached set holds the
+// documents it covers in memory. Default is 8; 0 disables caching.
+func (s *RAGStore) SetBM25CacheSize(size int) error {
+	if size < 0 {
+		return fmt.Errorf("BM25 cache size must be non-negative, got %d", size)
+	}
+	s.bm25Cache.setMaxSize(size)
+	return nil
+}
+
+// cloneSearchResult copies the embedding and metadata of a result, so that callers can
+// modify it without affecting a cached corpus
+func cloneSearchResult(result SearchResult) SearchResult {
+	if result.Embedding != nil {
+		result.Embedding = append([]float32(nil), result.Embedding...)
+	}
+	if result.Metadata != nil {
+		metadata := make(map[string]interface{}, len(result.Metadata))
+		for k, v := range result.Metadata {
+			metadata[k] = v
+		}
+		result.Metadata = metadata
+	}
+	return result
+}
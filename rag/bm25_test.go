@@ -0,0 +1,204 @@
+package rag
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// BM25TestSuite tests in-memory BM25 scoring and its corpus cache
+type BM25TestSuite struct {
+	suite.Suite
+}
+
+// TestBM25TestSuite runs the BM25 test suite
+func TestBM25TestSuite(t *testing.T) {
+	suite.Run(t, new(BM25TestSuite))
+}
+
+// referenceBM25 is the original BM25 implementation, which re-tokenizes the documents for
+// every query term. The corpus-based implementation must give identical scores.
+func referenceBM25(documents []SearchResult, queryTerms []string, tokenizer Tokenizer) []SearchResult {
+	if len(documents) == 0 || len(queryTerms) == 0 {
+		return documents
+	}
+
+	k1 := float32(1.5)
+	b := float32(0.75)
+
+	totalLength := 0
+	docLengths := make([]int, len(documents))
+	for i, doc := range documents {
+		tokens := tokenizer.Tokenize(doc.Text)
+		docLengths[i] = len(tokens)
+		totalLength += len(tokens)
+	}
+	avgDocLength := float32(totalLength) / float32(len(documents))
+
+	contains := func(tokens []string, term string) bool {
+		for _, token := range tokens {
+			if token == term {
+				return true
+			}
+		}
+		return false
+	}
+
+	idf := make(map[string]float32)
+	for _, term := range queryTerms {
+		docCount := 0
+		for _, doc := range documents {
+			if contains(tokenizer.Tokenize(doc.Text), term) {
+				docCount++
+			}
+		}
+		if docCount > 0 {
+			numerator := float64(len(documents)-docCount) + 0.5
+			denominator := float64(docCount) + 0.5
+			idf[term] = float32(math.Log(numerator / denominator))
+		}
+	}
+
+	scored := make([]SearchResult, len(documents))
+	copy(scored, documents)
+	for i, doc := range documents {
+		termFreq := make(map[string]int)
+		for _, token := range tokenizer.Tokenize(doc.Text) {
+			termFreq[token]++
+		}
+
+		score := float32(0.0)
+		for _, term := range queryTerms {
+			if termIDF, ok := idf[term]; ok {
+				tf := float32(termFreq[term])
+				docLen := float32(docLengths[i])
+
+				numerator := tf * (k1 + 1)
+				denominator := tf + k1*(1-b+b*(docLen/avgDocLength))
+
+				score += termIDF * (numerator / denominator)
+			}
+		}
+		scored[i].Score = score
+	}
+	return scored
+}
+
+// bm25TestCorpus returns n documents drawn from a small vocabulary with varied term
+// frequencies and lengths
+func bm25TestCorpus(n int) []SearchResult {
+	vocabulary := []string{"the", "quick", "brown", "fox", "jumps", "over", "lazy", "dog",
+		"running", "runner", "runs", "report", "reports", "quarterly", "annual", "zebra",
+		"crossing", "stripes", "database", "vector", "search", "index", "indexing", "of"}
+	docs := make([]SearchResult, n)
+	for i := range docs {
+		words := make([]string, 5+i%17)
+		for j := range words {
+			words[j] = vocabulary[(i*7+j*j*3+j)%len(vocabulary)]
+		}
+		docs[i] = SearchResult{ID: fmt.Sprintf("doc_%d", i), Text: strings.Join(words, " ")}
+	}
+	return docs
+}
+
+func (s *BM25TestSuite) TestScoresMatchReference() {
+	docs := bm25TestCorpus(500)
+	queries := []string{"zebra", "the quick fox", "running reports", "vector search indexing", "missing", ""}
+
+	for _, tokenizer := range []Tokenizer{SimpleTokenizer{}, NewStemmingTokenizer()} {
+		corpus := newBM25Corpus(docs, tokenizer)
+		for _, query := range queries {
+			terms := tokenizer.Tokenize(query)
+			expected := referenceBM25(docs, terms, tokenizer)
+
+			// The corpus is reused across queries, as it is when cached
+			actual := corpus.score(terms)
+			s.Require().Len(actual, len(expected))
+			for i := range expected {
+				s.Equal(expected[i].ID, actual[i].ID)
+				s.Equal(expected[i].Score, actual[i].Score, "query %q doc %s", query, expected[i].ID)
+			}
+			s.Equal(expected, calculateBM25(docs, terms, tokenizer))
+		}
+	}
+}
+
+func (s *BM25TestSuite) TestCache() {
+	cache := newBM25Cache(2)
+	corpus := newBM25Corpus(bm25TestCorpus(3), SimpleTokenizer{})
+	key := func(userID string, version uint64) bm25CacheKey {
+		return bm25CacheKey{userID: userID, version: version}
+	}
+
+	cache.put(key("a", 1), corpus)
+	cache.put(key("b", 1), corpus)
+	got, ok := cache.get(key("a", 1))
+	s.True(ok)
+	s.Same(corpus, got)
+
+	// A new table version is a different corpus
+	_, ok = cache.get(key("a", 2))
+	s.False(ok)
+
+	// "b" is least recently used, so it is evicted first
+	cache.put(key("c", 1), corpus)
+	_, ok = cache.get(key("b", 1))
+	s.False(ok)
+	s.Equal(2, cache.len())
+
+	cache.invalidateUser("a")
+	_, ok = cache.get(key("a", 1))
+	s.False(ok)
+	s.Equal(1, cache.len())
+
+	cache.setMaxSize(0)
+	s.Equal(0, cache.len())
+	cache.put(key("a", 1), corpus)
+	s.Equal(0, cache.len())
+
+	// A nil cache caches nothing
+	var disabled *bm25Cache
+	disabled.put(key("a", 1), corpus)
+	_, ok = disabled.get(key("a", 1))
+	s.False(ok)
+}
+
+func (s *BM25TestSuite) TestCloneSearchResult() {
+	original := SearchResult{ID: "a", Embedding: []float32{1, 2}, Metadata: map[string]interface{}{"k": "v"}}
+	clone := cloneSearchResult(original)
+	clone.Embedding[0] = 9
+	clone.Metadata["k"] = "changed"
+	s.Equal(float32(1), original.Embedding[0])
+	s.Equal("v", original.Metadata["k"])
+}
+
+// BenchmarkBM25 compares scoring queries against a 5k-document corpus by tokenizing every
+// document per query (Uncached) with reusing a tokenized corpus (Cached)
+func BenchmarkBM25(b *testing.B) {
+	docs := bm25TestCorpus(5000)
+	tokenizer := NewStemmingTokenizer()
+	terms := tokenizer.Tokenize("running vector search reports")
+
+	b.Run("Reference", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			referenceBM25(docs, terms, tokenizer)
+		}
+	})
+
+	b.Run("Uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			calculateBM25(docs, terms, tokenizer)
+		}
+	})
+
+	b.Run("Cached", func(b *testing.B) {
+		corpus := newBM25Corpus(docs, tokenizer)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			corpus.score(terms)
+		}
+	})
+}
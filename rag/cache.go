@@ -6,6 +6,8 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // EmbeddingCache is an interface for caching query embeddings.
@@ -24,6 +26,49 @@ type EmbeddingCache interface {
 	Size() int
 }
 
+// CacheStats reports cumulative cache activity. Counters are not reset by Clear.
+type CacheStats struct {
+	Hits      uint64 // Lookups that returned an embedding
+	Misses    uint64 // Lookups that found nothing (including expired entries)
+	Evictions uint64 // Entries removed to respect capacity or because they expired
+	Size      int    // Current number of cached entries
+}
+
+// HitRate returns the fraction of lookups that were hits (0 if there were none)
+func (s CacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// StatsEmbeddingCache is an EmbeddingCache that tracks hit/miss statistics.
+// All caches in this package implement it.
+type StatsEmbeddingCache interface {
+	EmbeddingCache
+
+	// Stats returns a snapshot of the cache counters
+	Stats() CacheStats
+}
+
+// cacheCounters holds atomically updated cache statistics
+type cacheCounters struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// snapshot returns the counters together with the given size
+func (c *cacheCounters) snapshot(size int) CacheStats {
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      size,
+	}
+}
+
 // LRUEmbeddingCache implements a thread-safe LRU (Least Recently Used) cache for embeddings.
 // When the cache is full, the least recently used entry is evicted.
 type LRUEmbeddingCache struct {
@@ -31,6 +76,7 @@ type LRUEmbeddingCache struct {
 	capacity int
 	cache    map[string]*list.Element
 	lru      *list.List
+	stats    cacheCounters
 }
 
 // cacheEntry represents a cached embedding with its query key
@@ -67,9 +113,11 @@ func (c *LRUEmbeddingCache) Get(query string) ([]float32, bool) {
 		// Move to front (most recently used)
 		c.lru.MoveToFront(elem)
 		entry := elem.Value.(*cacheEntry)
+		c.stats.hits.Add(1)
 		return entry.embedding, true
 	}
 	
+	c.stats.misses.Add(1)
 	return nil, false
 }
 
@@ -120,6 +168,11 @@ func (c *LRUEmbeddingCache) Size() int {
 	return c.lru.Len()
 }
 
+// Stats returns a snapshot of the cache's hit/miss/eviction counters
+func (c *LRUEmbeddingCache) Stats() CacheStats {
+	return c.stats.snapshot(c.Size())
+}
+
 // evictOldest removes the least recently used entry (must be called with lock held)
 func (c *LRUEmbeddingCache) evictOldest() {
 	elem := c.lru.Back()
@@ -127,9 +180,186 @@ func (c *LRUEmbeddingCache) evictOldest() {
 		c.lru.Remove(elem)
 		entry := elem.Value.(*cacheEntry)
 		delete(c.cache, entry.key)
+		c.stats.evictions.Add(1)
+	}
+}
+
+// TTLEmbeddingCache is a thread-safe LRU embedding cache whose entries also expire after a TTL.
+// Expired entries are treated as misses and removed lazily on access, or eagerly by Sweep
+// and the optional background sweeper. Capacity-based LRU eviction still applies.
+type TTLEmbeddingCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	cache    map[string]*list.Element
+	lru      *list.List
+	now      func() time.Time // clock, replaceable in tests
+	stats    cacheCounters
+}
+
+// ttlCacheEntry is a cached embedding with its expiry time
+type ttlCacheEntry struct {
+	key       string
+	embedding []float32
+	expiresAt time.Time
+}
+
+// NewTTLEmbeddingCache creates an LRU cache with the given capacity whose entries expire
+// ttl after they were last set. A ttl <= 0 disables expiration.
+func NewTTLEmbeddingCache(capacity int, ttl time.Duration) *TTLEmbeddingCache {
+	if capacity <= 0 {
+		capacity = 1000 // default capacity
+	}
+
+	return &TTLEmbeddingCache{
+		capacity: capacity,
+		ttl:      ttl,
+		cache:    make(map[string]*list.Element),
+		lru:      list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get retrieves a cached embedding for the given query text.
+// Expired entries are removed and reported as misses.
+func (c *TTLEmbeddingCache) Get(query string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := hashQuery(query)
+
+	elem, found := c.cache[key]
+	if !found {
+		c.stats.misses.Add(1)
+		return nil, false
+	}
+
+	entry := elem.Value.(*ttlCacheEntry)
+	if c.expired(entry) {
+		c.removeElement(elem)
+		c.stats.evictions.Add(1)
+		c.stats.misses.Add(1)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	c.stats.hits.Add(1)
+	return entry.embedding, true
+}
+
+// Set stores an embedding for the given query text, resetting its expiry.
+// If the cache is full, the least recently used entry is evicted.
+func (c *TTLEmbeddingCache) Set(query string, embedding []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := hashQuery(query)
+	expiresAt := c.now().Add(c.ttl)
+
+	if elem, found := c.cache[key]; found {
+		c.lru.MoveToFront(elem)
+		entry := elem.Value.(*ttlCacheEntry)
+		entry.embedding = embedding
+		entry.expiresAt = expiresAt
+		return
+	}
+
+	elem := c.lru.PushFront(&ttlCacheEntry{
+		key:       key,
+		embedding: embedding,
+		expiresAt: expiresAt,
+	})
+	c.cache[key] = elem
+
+	if c.lru.Len() > c.capacity {
+		c.removeElement(c.lru.Back())
+		c.stats.evictions.Add(1)
+	}
+}
+
+// Clear removes all cached entries
+func (c *TTLEmbeddingCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache = make(map[string]*list.Element)
+	c.lru = list.New()
+}
+
+// Size returns the current number of cached entries, including expired entries not yet swept
+func (c *TTLEmbeddingCache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// Sweep removes all expired entries and returns how many were removed
+func (c *TTLEmbeddingCache) Sweep() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for elem := c.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if c.expired(elem.Value.(*ttlCacheEntry)) {
+			c.removeElement(elem)
+			removed++
+		}
+		elem = prev
+	}
+	c.stats.evictions.Add(uint64(removed))
+	return removed
+}
+
+// Stats returns a snapshot of the cache's hit/miss/eviction counters
+func (c *TTLEmbeddingCache) Stats() CacheStats {
+	return c.stats.snapshot(c.Size())
+}
+
+// StartSweeper runs Sweep every interval in a background goroutine. An interval <= 0
+// sweeps once per TTL, or every minute if expiration is disabled.
+// Call the returned function to stop the sweeper.
+func (c *TTLEmbeddingCache) StartSweeper(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = c.ttl
+		if interval <= 0 {
+			interval = time.Minute // default sweep interval
+		}
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				c.Sweep()
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(done) })
 	}
 }
 
+// expired reports whether an entry has outlived the TTL (must be called with lock held)
+func (c *TTLEmbeddingCache) expired(entry *ttlCacheEntry) bool {
+	return c.ttl > 0 && !c.now().Before(entry.expiresAt)
+}
+
+// removeElement removes an entry from the cache (must be called with lock held)
+func (c *TTLEmbeddingCache) removeElement(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.cache, elem.Value.(*ttlCacheEntry).key)
+}
+
 // hashQuery creates a consistent hash key for a query string.
 // Using SHA256 to avoid collision issues with map keys.
 func hashQuery(query string) string {
@@ -238,3 +468,12 @@ func (p *CachedEmbeddingProvider) CacheSize() int {
 	return p.cache.Size()
 }
 
+// CacheStats returns the wrapped cache's statistics.
+// The second return value is false if the cache doesn't track statistics.
+func (p *CachedEmbeddingProvider) CacheStats() (CacheStats, bool) {
+	if cache, ok := p.cache.(StatsEmbeddingCache); ok {
+		return cache.Stats(), true
+	}
+	return CacheStats{}, false
+}
+
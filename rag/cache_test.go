@@ -0,0 +1,206 @@
+package rag
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// CacheTestSuite tests embedding caches
+type CacheTestSuite struct {
+	suite.Suite
+}
+
+// TestCacheTestSuite runs the cache test suite
+func TestCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
+
+// fakeClock is a manually advanced clock for TTL tests
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// newTestTTLCache returns a TTL cache driven by a fake clock
+func newTestTTLCache(capacity int, ttl time.Duration) (*TTLEmbeddingCache, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := NewTTLEmbeddingCache(capacity, ttl)
+	cache.now = clock.now
+	return cache, clock
+}
+
+func (s *CacheTestSuite) TestTTLCacheHitBeforeExpiry() {
+	cache, clock := newTestTTLCache(10, time.Minute)
+
+	cache.Set("query", []float32{1, 2, 3})
+	clock.advance(59 * time.Second)
+
+	embedding, found := cache.Get("query")
+	s.True(found)
+	s.Equal([]float32{1, 2, 3}, embedding)
+}
+
+func (s *CacheTestSuite) TestTTLCacheMissAfterExpiry() {
+	cache, clock := newTestTTLCache(10, time.Minute)
+
+	cache.Set("query", []float32{1, 2, 3})
+	clock.advance(time.Minute)
+
+	_, found := cache.Get("query")
+	s.False(found)
+	s.Equal(0, cache.Size(), "expired entry should be removed lazily on Get")
+}
+
+func (s *CacheTestSuite) TestTTLCacheSetRefreshesExpiry() {
+	cache, clock := newTestTTLCache(10, time.Minute)
+
+	cache.Set("query", []float32{1})
+	clock.advance(45 * time.Second)
+	cache.Set("query", []float32{2})
+	clock.advance(45 * time.Second)
+
+	embedding, found := cache.Get("query")
+	s.True(found)
+	s.Equal([]float32{2}, embedding)
+}
+
+func (s *CacheTestSuite) TestTTLCacheSweep() {
+	cache, clock := newTestTTLCache(10, time.Minute)
+
+	cache.Set("old1", []float32{1})
+	cache.Set("old2", []float32{2})
+	clock.advance(30 * time.Second)
+	cache.Set("fresh", []float32{3})
+	clock.advance(30 * time.Second)
+
+	s.Equal(2, cache.Sweep())
+	s.Equal(1, cache.Size())
+
+	_, found := cache.Get("fresh")
+	s.True(found)
+}
+
+func (s *CacheTestSuite) TestTTLCacheLRUEviction() {
+	cache, _ := newTestTTLCache(2, time.Hour)
+
+	cache.Set("a", []float32{1})
+	cache.Set("b", []float32{2})
+	cache.Get("a") // "b" becomes least recently used
+	cache.Set("c", []float32{3})
+
+	s.Equal(2, cache.Size())
+	_, found := cache.Get("b")
+	s.False(found)
+	_, found = cache.Get("a")
+	s.True(found)
+	_, found = cache.Get("c")
+	s.True(found)
+}
+
+func (s *CacheTestSuite) TestTTLCacheNoExpiry() {
+	cache, clock := newTestTTLCache(10, 0)
+
+	cache.Set("query", []float32{1})
+	clock.advance(24 * time.Hour)
+
+	_, found := cache.Get("query")
+	s.True(found)
+	s.Equal(0, cache.Sweep())
+}
+
+func (s *CacheTestSuite) TestTTLCacheBackgroundSweeper() {
+	cache := NewTTLEmbeddingCache(10, time.Millisecond)
+	cache.Set("query", []float32{1})
+
+	stop := cache.StartSweeper(5 * time.Millisecond)
+	defer stop()
+
+	s.Eventually(func() bool {
+		return cache.Size() == 0
+	}, time.Second, 5*time.Millisecond)
+
+	stop()
+	stop() // stopping twice is safe
+}
+
+func (s *CacheTestSuite) TestTTLCacheSweeperDefaultInterval() {
+	cache := NewTTLEmbeddingCache(10, time.Millisecond)
+	cache.Set("query", []float32{1})
+
+	// A zero interval sweeps once per TTL instead of panicking
+	stop := cache.StartSweeper(0)
+	defer stop()
+
+	s.Eventually(func() bool {
+		return cache.Size() == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func (s *CacheTestSuite) TestLRUCacheStats() {
+	cache := NewLRUEmbeddingCache(2)
+
+	cache.Set("a", []float32{1})
+	cache.Set("b", []float32{2})
+	_, found := cache.Get("a") // hit
+	s.True(found)
+	_, found = cache.Get("c") // miss
+	s.False(found)
+	cache.Set("c", []float32{3}) // evicts b
+	_, found = cache.Get("b")    // miss
+	s.False(found)
+	_, found = cache.Get("a") // hit
+	s.True(found)
+	_, found = cache.Get("c") // hit
+	s.True(found)
+
+	stats := cache.Stats()
+	s.Equal(CacheStats{Hits: 3, Misses: 2, Evictions: 1, Size: 2}, stats)
+	s.InDelta(0.6, stats.HitRate(), 1e-9)
+
+	// Clear drops entries but keeps the counters
+	cache.Clear()
+	s.Equal(CacheStats{Hits: 3, Misses: 2, Evictions: 1, Size: 0}, cache.Stats())
+}
+
+func (s *CacheTestSuite) TestTTLCacheStats() {
+	cache, clock := newTestTTLCache(2, time.Minute)
+
+	cache.Set("a", []float32{1})
+	cache.Get("a") // hit
+	clock.advance(time.Minute)
+	cache.Get("a") // miss, expired entry evicted
+	cache.Set("b", []float32{2})
+	clock.advance(30 * time.Second)
+	cache.Set("c", []float32{3})
+	clock.advance(10 * time.Second)
+	cache.Set("d", []float32{4}) // evicts b by capacity
+	clock.advance(55 * time.Second)
+	s.Equal(1, cache.Sweep()) // c expired, d still live
+
+	s.Equal(CacheStats{Hits: 1, Misses: 1, Evictions: 3, Size: 1}, cache.Stats())
+}
+
+func (s *CacheTestSuite) TestCacheStatsEmpty() {
+	s.Equal(0.0, CacheStats{}.HitRate())
+}
+
+func (s *CacheTestSuite) TestCachedEmbeddingProviderStats() {
+	var cache EmbeddingCache = NewLRUEmbeddingCache(10)
+	_, ok := cache.(StatsEmbeddingCache)
+	s.True(ok)
+
+	provider := NewCachedEmbeddingProvider(nil, cache, nil)
+	cache.Get("missing")
+	stats, ok := provider.CacheStats()
+	s.True(ok)
+	s.Equal(uint64(1), stats.Misses)
+}
@@ -1,9 +1,13 @@
 package rag
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Chunk represents a text chunk with position information
@@ -169,15 +173,22 @@ func (c *ParagraphChunker) Chunk(text string) ([]Chunk, error) {
 	return chunks, nil
 }
 
-// TokenChunker splits text into chunks based on approximate token count
-// This uses a simple heuristic: 1 token ≈ 4 characters (works well for English)
+// TokenChunker splits text into chunks based on token count.
+// Without a Counter it uses a simple heuristic: 1 token ≈ 4 characters (works well for English).
+// With a Counter (e.g. a BPETokenCounter) chunks are split on word boundaries using true token counts.
 type TokenChunker struct {
-	MaxTokens int // Maximum tokens per chunk
-	Overlap   int // Number of tokens to overlap
+	MaxTokens int          // Maximum tokens per chunk
+	Overlap   int          // Number of tokens to overlap
+	Counter   TokenCounter // Token counter (nil uses the 4 chars ≈ 1 token heuristic)
 }
 
 // NewTokenChunker creates a chunker that splits text by approximate token count
 func NewTokenChunker(maxTokens, overlap int) (*TokenChunker, error) {
+	return NewTokenChunkerWithCounter(maxTokens, overlap, nil)
+}
+
+// NewTokenChunkerWithCounter creates a chunker that splits text using the given token counter
+func NewTokenChunkerWithCounter(maxTokens, overlap int, counter TokenCounter) (*TokenChunker, error) {
 	if maxTokens <= 0 {
 		return nil, fmt.Errorf("max tokens must be positive, got %d", maxTokens)
 	}
@@ -190,11 +201,16 @@ func NewTokenChunker(maxTokens, overlap int) (*TokenChunker, error) {
 	return &TokenChunker{
 		MaxTokens: maxTokens,
 		Overlap:   overlap,
+		Counter:   counter,
 	}, nil
 }
 
-// Chunk splits text into token-based chunks (using 4 chars ≈ 1 token heuristic)
+// Chunk splits text into token-based chunks
 func (c *TokenChunker) Chunk(text string) ([]Chunk, error) {
+	if c.Counter != nil {
+		return c.chunkWithCounter(text)
+	}
+
 	// Simple heuristic: 1 token ≈ 4 characters for English text
 	chunkSizeChars := c.MaxTokens * 4
 	overlapChars := c.Overlap * 4
@@ -207,6 +223,753 @@ func (c *TokenChunker) Chunk(text string) ([]Chunk, error) {
 	return chunker.Chunk(text)
 }
 
+// tokenUnitPattern matches a word together with its leading whitespace
+var tokenUnitPattern = regexp.MustCompile(`\s*\S+`)
+
+// chunkWithCounter greedily packs words into chunks of at most MaxTokens tokens as measured by Counter
+func (c *TokenChunker) chunkWithCounter(text string) ([]Chunk, error) {
+	if strings.TrimSpace(text) == "" {
+		return []Chunk{}, nil
+	}
+
+	// Split into words, hard-splitting any single word that exceeds MaxTokens
+	units := make([]string, 0)
+	for _, unit := range tokenUnitPattern.FindAllString(text, -1) {
+		if c.Counter.CountTokens(unit) <= c.MaxTokens {
+			units = append(units, unit)
+			continue
+		}
+		units = append(units, c.splitOversizedUnit(unit)...)
+	}
+
+	counts := make([]int, len(units))
+	for i, unit := range units {
+		counts[i] = c.Counter.CountTokens(unit)
+	}
+
+	chunks := make([]Chunk, 0)
+	for start := 0; start < len(units); {
+		// Extend the chunk while the summed word counts fit
+		end, total := start, 0
+		for end < len(units) && (end == start || total+counts[end] <= c.MaxTokens) {
+			total += counts[end]
+			end++
+		}
+
+		// Token counts are not strictly additive across word boundaries; shrink until the chunk fits
+		chunkText := strings.TrimSpace(strings.Join(units[start:end], ""))
+		tokens := c.Counter.CountTokens(chunkText)
+		for tokens > c.MaxTokens && end-start > 1 {
+			end--
+			chunkText = strings.TrimSpace(strings.Join(units[start:end], ""))
+			tokens = c.Counter.CountTokens(chunkText)
+		}
+
+		chunks = append(chunks, Chunk{
+			Text:  chunkText,
+			Index: len(chunks),
+			Metadata: map[string]interface{}{
+				"tokens": tokens,
+			},
+		})
+
+		if end >= len(units) {
+			break
+		}
+
+		// Step back over trailing words worth at most Overlap tokens, always making progress
+		next, overlap := end, 0
+		for next-1 > start && overlap+counts[next-1] <= c.Overlap {
+			next--
+			overlap += counts[next]
+		}
+		start = next
+	}
+
+	return chunks, nil
+}
+
+// splitOversizedUnit cuts a single word that exceeds MaxTokens into pieces that fit
+func (c *TokenChunker) splitOversizedUnit(unit string) []string {
+	pieces := make([]string, 0)
+	runes := []rune(unit)
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && c.Counter.CountTokens(string(runes[start:end+1])) <= c.MaxTokens {
+			end++
+		}
+		pieces = append(pieces, string(runes[start:end]))
+		start = end
+	}
+	return pieces
+}
+
+// DefaultRecursiveSeparators is the separator hierarchy used by RecursiveChunker when none is given:
+// paragraphs, lines, sentences, words, then individual characters.
+var DefaultRecursiveSeparators = []string{"\n\n", "\n", ". ", " ", ""}
+
+// RecursiveChunker splits text recursively using a hierarchy of separators (LangChain-style).
+// It tries each separator in order, only descending to finer separators for pieces that are
+// still larger than ChunkSize, then merges adjacent pieces up to ChunkSize with Overlap
+// characters carried over between consecutive chunks.
+type RecursiveChunker struct {
+	ChunkSize  int      // Maximum size of each chunk in characters
+	Overlap    int      // Number of characters to overlap between chunks
+	Separators []string // Separators to try, from coarsest to finest ("" splits into characters)
+}
+
+// NewRecursiveChunker creates a recursive chunker. Pass nil separators to use DefaultRecursiveSeparators.
+func NewRecursiveChunker(chunkSize, overlap int, separators []string) (*RecursiveChunker, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	if overlap < 0 {
+		return nil, fmt.Errorf("overlap must be non-negative, got %d", overlap)
+	}
+	if overlap >= chunkSize {
+		return nil, fmt.Errorf("overlap (%d) must be less than chunk size (%d)", overlap, chunkSize)
+	}
+	if len(separators) == 0 {
+		separators = DefaultRecursiveSeparators
+	}
+	return &RecursiveChunker{
+		ChunkSize:  chunkSize,
+		Overlap:    overlap,
+		Separators: separators,
+	}, nil
+}
+
+// Chunk splits text recursively along the separator hierarchy
+func (c *RecursiveChunker) Chunk(text string) ([]Chunk, error) {
+	if strings.TrimSpace(text) == "" {
+		return []Chunk{}, nil
+	}
+
+	pieces := c.split(text, c.Separators)
+
+	chunks := make([]Chunk, 0, len(pieces))
+	searchFrom := 0
+	for _, piece := range pieces {
+		// Locate the chunk in the original text to record its character offsets
+		start, end := -1, -1
+		if idx := strings.Index(text[searchFrom:], piece); idx >= 0 {
+			startByte := searchFrom + idx
+			start = utf8.RuneCountInString(text[:startByte])
+			end = start + utf8.RuneCountInString(piece)
+			searchFrom = startByte + 1
+		}
+
+		chunks = append(chunks, Chunk{
+			Text:  piece,
+			Index: len(chunks),
+			Metadata: map[string]interface{}{
+				"start": start,
+				"end":   end,
+			},
+		})
+	}
+
+	return chunks, nil
+}
+
+// split recursively splits text with the first separator present in it,
+// descending to finer separators only for pieces that are still too large
+func (c *RecursiveChunker) split(text string, separators []string) []string {
+	// Find the coarsest separator that occurs in the text
+	separator := separators[len(separators)-1]
+	var finer []string
+	for i, sep := range separators {
+		if sep == "" {
+			separator = ""
+			break
+		}
+		if strings.Contains(text, sep) {
+			separator = sep
+			finer = separators[i+1:]
+			break
+		}
+	}
+
+	var splits []string
+	if separator == "" {
+		splits = strings.Split(text, "")
+	} else {
+		splits = strings.Split(text, separator)
+	}
+
+	result := make([]string, 0)
+	pending := make([]string, 0)
+	for _, piece := range splits {
+		if piece == "" {
+			continue
+		}
+		if utf8.RuneCountInString(piece) <= c.ChunkSize {
+			pending = append(pending, piece)
+			continue
+		}
+
+		// Piece too large: flush what we have and recurse with finer separators
+		if len(pending) > 0 {
+			result = append(result, c.merge(pending, separator)...)
+			pending = pending[:0]
+		}
+		if len(finer) == 0 {
+			result = append(result, hardSplit(piece, c.ChunkSize)...)
+		} else {
+			result = append(result, c.split(piece, finer)...)
+		}
+	}
+	if len(pending) > 0 {
+		result = append(result, c.merge(pending, separator)...)
+	}
+
+	return result
+}
+
+// merge joins small pieces into chunks of at most ChunkSize characters,
+// carrying up to Overlap characters of trailing pieces into the next chunk
+func (c *RecursiveChunker) merge(pieces []string, separator string) []string {
+	sepLen := utf8.RuneCountInString(separator)
+	chunks := make([]string, 0)
+	window := make([]string, 0)
+	total := 0
+
+	emit := func() {
+		if chunk := strings.TrimSpace(strings.Join(window, separator)); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	for _, piece := range pieces {
+		pieceLen := utf8.RuneCountInString(piece)
+		joinLen := 0
+		if len(window) > 0 {
+			joinLen = sepLen
+		}
+
+		if total+joinLen+pieceLen > c.ChunkSize && len(window) > 0 {
+			emit()
+
+			// Drop leading pieces until the remainder fits in the overlap and leaves room for the new piece
+			for len(window) > 0 && (total > c.Overlap || total+sepLen+pieceLen > c.ChunkSize) {
+				total -= utf8.RuneCountInString(window[0])
+				if len(window) > 1 {
+					total -= sepLen
+				}
+				window = window[1:]
+			}
+		}
+
+		if len(window) > 0 {
+			total += sepLen
+		}
+		window = append(window, piece)
+		total += pieceLen
+	}
+	emit()
+
+	return chunks
+}
+
+// MarkdownChunker splits markdown on heading boundaries first, then on paragraphs, then on sentences,
+// only falling back to hard character cuts when a single sentence exceeds MaxChars.
+// Chunks never straddle headings, and each chunk records its nearest heading as Metadata["section"].
+type MarkdownChunker struct {
+	MaxChars int // Maximum number of characters per chunk
+}
+
+// NewMarkdownChunker creates a chunker that splits markdown along its structure
+func NewMarkdownChunker(maxChars int) (*MarkdownChunker, error) {
+	if maxChars <= 0 {
+		return nil, fmt.Errorf("max chars must be positive, got %d", maxChars)
+	}
+	return &MarkdownChunker{
+		MaxChars: maxChars,
+	}, nil
+}
+
+// Chunk splits markdown text into section-aligned chunks
+func (c *MarkdownChunker) Chunk(text string) ([]Chunk, error) {
+	if strings.TrimSpace(text) == "" {
+		return []Chunk{}, nil
+	}
+
+	chunks := make([]Chunk, 0)
+	for _, section := range splitMarkdownSections(text) {
+		for _, piece := range c.splitSection(section.text) {
+			chunks = append(chunks, Chunk{
+				Text:  piece,
+				Index: len(chunks),
+				Metadata: map[string]interface{}{
+					"section":       section.heading,
+					"section_level": section.level,
+				},
+			})
+		}
+	}
+
+	return chunks, nil
+}
+
+// splitSection splits a single section into pieces of at most MaxChars characters,
+// preferring paragraph boundaries, then sentence boundaries
+func (c *MarkdownChunker) splitSection(text string) []string {
+	if utf8.RuneCountInString(text) <= c.MaxChars {
+		return []string{text}
+	}
+
+	pieces := make([]string, 0)
+	for _, para := range splitMarkdownParagraphs(text) {
+		if utf8.RuneCountInString(para) <= c.MaxChars {
+			pieces = append(pieces, para)
+			continue
+		}
+
+		// Paragraph too large: fall back to sentences, then hard cuts
+		sentences := make([]string, 0)
+		for _, sentence := range splitSentences(para) {
+			if utf8.RuneCountInString(sentence) <= c.MaxChars {
+				sentences = append(sentences, sentence)
+				continue
+			}
+			sentences = append(sentences, hardSplit(sentence, c.MaxChars)...)
+		}
+		pieces = append(pieces, mergePieces(sentences, " ", c.MaxChars)...)
+	}
+
+	return mergePieces(pieces, "\n\n", c.MaxChars)
+}
+
+// markdownHeadingPattern matches ATX headings such as "## Installation"
+var markdownHeadingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+// markdownSection is the text under a single heading
+type markdownSection struct {
+	heading string // Heading text ("" for content before the first heading)
+	level   int    // Heading level (1-6, 0 for content before the first heading)
+	text    string // Section text including the heading line
+}
+
+// isMarkdownFence reports whether a line opens or closes a fenced code block
+func isMarkdownFence(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+// splitMarkdownSections splits markdown into sections at headings outside fenced code blocks
+func splitMarkdownSections(text string) []markdownSection {
+	sections := make([]markdownSection, 0)
+	current := markdownSection{}
+	lines := make([]string, 0)
+	inFence := false
+
+	flush := func() {
+		body := strings.TrimSpace(strings.Join(lines, "\n"))
+		if body != "" {
+			current.text = body
+			sections = append(sections, current)
+		}
+		lines = lines[:0]
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if isMarkdownFence(line) {
+			inFence = !inFence
+		} else if !inFence {
+			if m := markdownHeadingPattern.FindStringSubmatch(line); m != nil {
+				flush()
+				current = markdownSection{heading: m[2], level: len(m[1])}
+			}
+		}
+		lines = append(lines, line)
+	}
+	flush()
+
+	return sections
+}
+
+// splitMarkdownParagraphs splits text on blank lines, keeping fenced code blocks intact
+func splitMarkdownParagraphs(text string) []string {
+	paragraphs := make([]string, 0)
+	lines := make([]string, 0)
+	inFence := false
+
+	flush := func() {
+		para := strings.TrimSpace(strings.Join(lines, "\n"))
+		if para != "" {
+			paragraphs = append(paragraphs, para)
+		}
+		lines = lines[:0]
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if isMarkdownFence(line) {
+			inFence = !inFence
+		}
+		if !inFence && strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+
+	return paragraphs
+}
+
+// mergePieces greedily joins consecutive pieces with sep while the result stays within maxChars
+func mergePieces(pieces []string, sep string, maxChars int) []string {
+	merged := make([]string, 0, len(pieces))
+	current := ""
+	for _, piece := range pieces {
+		if current == "" {
+			current = piece
+			continue
+		}
+		if utf8.RuneCountInString(current)+utf8.RuneCountInString(sep)+utf8.RuneCountInString(piece) <= maxChars {
+			current += sep + piece
+			continue
+		}
+		merged = append(merged, current)
+		current = piece
+	}
+	if current != "" {
+		merged = append(merged, current)
+	}
+	return merged
+}
+
+// hardSplit cuts text into pieces of at most maxChars characters
+func hardSplit(text string, maxChars int) []string {
+	runes := []rune(text)
+	pieces := make([]string, 0, len(runes)/maxChars+1)
+	for start := 0; start < len(runes); start += maxChars {
+		end := start + maxChars
+		if end > len(runes) {
+			end = len(runes)
+		}
+		pieces = append(pieces, string(runes[start:end]))
+	}
+	return pieces
+}
+
+// CodeChunker splits source code along top-level declarations (functions, types, classes)
+// using brace and indentation heuristics. Leading comments and decorators stay with the
+// declaration they document, and each chunk records the declared name as Metadata["symbol"]
+// when it can be detected. Declarations larger than MaxChars are split on line boundaries,
+// and code without recognizable structure falls back to line-based splitting.
+type CodeChunker struct {
+	Language string // Source language ("go", "python", "javascript" or "typescript")
+	MaxChars int    // Maximum number of characters per chunk
+
+	lang *codeLanguage
+}
+
+// codeLanguage describes how to find top-level declarations in a language
+type codeLanguage struct {
+	braces         bool             // Blocks are delimited by braces (otherwise by indentation)
+	declarations   []*regexp.Regexp // Top-level declaration patterns; group 1 captures the symbol if any
+	leadingPrefix  []string         // Line prefixes (comments, decorators) that attach to the next declaration
+	stringDelims   string           // Characters that open string literals
+	lineCommentTok string           // Token starting a line comment
+}
+
+var (
+	goLanguage = &codeLanguage{
+		braces: true,
+		declarations: []*regexp.Regexp{
+			regexp.MustCompile(`^func\s+(?:\([^)]*\)\s*)?([A-Za-z_]\w*)`),
+			regexp.MustCompile(`^type\s+([A-Za-z_]\w*)`),
+			regexp.MustCompile(`^(?:var|const)\s+([A-Za-z_]\w*)`),
+			regexp.MustCompile(`^(?:var|const|type)\s*\(`),
+		},
+		leadingPrefix:  []string{"//", "/*", "*"},
+		stringDelims:   "\"'`",
+		lineCommentTok: "//",
+	}
+
+	pythonLanguage = &codeLanguage{
+		braces: false,
+		declarations: []*regexp.Regexp{
+			regexp.MustCompile(`^(?:async\s+)?def\s+([A-Za-z_]\w*)`),
+			regexp.MustCompile(`^class\s+([A-Za-z_]\w*)`),
+		},
+		leadingPrefix:  []string{"#", "@"},
+		stringDelims:   "\"'",
+		lineCommentTok: "#",
+	}
+
+	javascriptLanguage = &codeLanguage{
+		braces: true,
+		declarations: []*regexp.Regexp{
+			regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*([A-Za-z_$][\w$]*)`),
+			regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`),
+			regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*=`),
+			regexp.MustCompile(`^(?:export\s+)?(?:interface|type|enum)\s+([A-Za-z_$][\w$]*)`),
+		},
+		leadingPrefix:  []string{"//", "/*", "*", "@"},
+		stringDelims:   "\"'`",
+		lineCommentTok: "//",
+	}
+)
+
+// codeLanguages maps supported language names and aliases to their heuristics
+var codeLanguages = map[string]*codeLanguage{
+	"go":         goLanguage,
+	"golang":     goLanguage,
+	"python":     pythonLanguage,
+	"py":         pythonLanguage,
+	"javascript": javascriptLanguage,
+	"js":         javascriptLanguage,
+	"typescript": javascriptLanguage,
+	"ts":         javascriptLanguage,
+}
+
+// NewCodeChunker creates a chunker that splits source code on declaration boundaries
+func NewCodeChunker(language string, maxChars int) (*CodeChunker, error) {
+	if maxChars <= 0 {
+		return nil, fmt.Errorf("max chars must be positive, got %d", maxChars)
+	}
+	lang, ok := codeLanguages[strings.ToLower(language)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %q (supported: go, python, javascript, typescript)", language)
+	}
+	return &CodeChunker{
+		Language: strings.ToLower(language),
+		MaxChars: maxChars,
+		lang:     lang,
+	}, nil
+}
+
+// codeBlock is a run of source lines forming one top-level unit
+type codeBlock struct {
+	symbol    string   // Declared name ("" if none was detected)
+	startLine int      // 1-based line number of the first line
+	lines     []string // Source lines
+}
+
+// Chunk splits source code into declaration-aligned chunks
+func (c *CodeChunker) Chunk(text string) ([]Chunk, error) {
+	if strings.TrimSpace(text) == "" {
+		return []Chunk{}, nil
+	}
+
+	chunks := make([]Chunk, 0)
+	for _, block := range c.splitBlocks(text) {
+		for _, piece := range splitCodeLines(block.lines, block.startLine, c.MaxChars) {
+			metadata := map[string]interface{}{
+				"language":   c.Language,
+				"start_line": piece.startLine,
+				"end_line":   piece.endLine,
+			}
+			if block.symbol != "" {
+				metadata["symbol"] = block.symbol
+			}
+			chunks = append(chunks, Chunk{
+				Text:     piece.text,
+				Index:    len(chunks),
+				Metadata: metadata,
+			})
+		}
+	}
+
+	return chunks, nil
+}
+
+// splitBlocks groups source lines into top-level blocks, starting a new block at every declaration
+func (c *CodeChunker) splitBlocks(text string) []codeBlock {
+	blocks := make([]codeBlock, 0)
+	current := codeBlock{startLine: 1}
+	leading := make([]string, 0) // comments/decorators waiting for the next declaration
+	leadingStart := 0
+	depth := 0
+
+	flush := func() {
+		if len(current.lines) > 0 {
+			blocks = append(blocks, current)
+		}
+	}
+	// attachLeading moves pending comment lines into the current block
+	attachLeading := func() {
+		if len(leading) > 0 {
+			current.lines = append(current.lines, leading...)
+			leading = leading[:0]
+		}
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		lineNo := i + 1
+		trimmed := strings.TrimSpace(line)
+
+		topLevel := depth == 0
+		if !c.lang.braces {
+			topLevel = trimmed != "" && !unicode.IsSpace(rune(line[0]))
+		}
+
+		switch {
+		case topLevel && trimmed != "" && c.isLeading(trimmed):
+			if len(leading) == 0 {
+				leadingStart = lineNo
+			}
+			leading = append(leading, line)
+		case topLevel && trimmed != "":
+			symbol, isDecl := c.matchDeclaration(trimmed)
+			if isDecl || current.symbol != "" {
+				// Declarations always start a new block; so does top-level code following one
+				flush()
+				current = codeBlock{symbol: symbol, startLine: lineNo}
+				if len(leading) > 0 {
+					current.startLine = leadingStart
+				}
+			}
+			attachLeading()
+			current.lines = append(current.lines, line)
+		default:
+			attachLeading()
+			if len(current.lines) == 0 {
+				current.startLine = lineNo
+			}
+			current.lines = append(current.lines, line)
+		}
+
+		if c.lang.braces {
+			depth += c.braceDelta(line)
+			if depth < 0 {
+				depth = 0
+			}
+		}
+	}
+	attachLeading()
+	flush()
+
+	return blocks
+}
+
+// isLeading reports whether a top-level line documents or decorates the following declaration
+func (c *CodeChunker) isLeading(trimmed string) bool {
+	for _, prefix := range c.lang.leadingPrefix {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDeclaration reports whether a top-level line starts a declaration and returns its symbol
+func (c *CodeChunker) matchDeclaration(trimmed string) (string, bool) {
+	for _, pattern := range c.lang.declarations {
+		if m := pattern.FindStringSubmatch(trimmed); m != nil {
+			if len(m) > 1 {
+				return m[1], true
+			}
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// braceDelta returns the net change in brace depth for a line, ignoring strings and line comments
+func (c *CodeChunker) braceDelta(line string) int {
+	delta := 0
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\' && quote != '`':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+		if strings.HasPrefix(line[i:], c.lang.lineCommentTok) {
+			break
+		}
+		switch {
+		case strings.ContainsRune(c.lang.stringDelims, r):
+			quote = r
+		case r == '{':
+			delta++
+		case r == '}':
+			delta--
+		}
+	}
+	return delta
+}
+
+// codePiece is a chunk of source lines with its line range
+type codePiece struct {
+	text      string
+	startLine int
+	endLine   int
+}
+
+// splitCodeLines trims blank lines around a block and splits it on line boundaries
+// into pieces of at most maxChars characters, hard-cutting single lines that are too long
+func splitCodeLines(lines []string, firstLine int, maxChars int) []codePiece {
+	start, end := 0, len(lines)
+	for start < end && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+
+	pieces := make([]codePiece, 0)
+	var current []string
+	currentStart := 0
+	size := 0
+
+	emit := func(lastLine int) {
+		if len(current) > 0 {
+			pieces = append(pieces, codePiece{
+				text:      strings.Join(current, "\n"),
+				startLine: currentStart,
+				endLine:   lastLine,
+			})
+		}
+		current = nil
+		size = 0
+	}
+
+	for i := start; i < end; i++ {
+		lineNo := firstLine + i
+		line := strings.TrimRight(lines[i], " \t\r")
+		if len(current) == 0 && line == "" {
+			continue // don't start a piece with blank lines
+		}
+		lineLen := utf8.RuneCountInString(line)
+
+		if lineLen > maxChars {
+			emit(lineNo - 1)
+			for _, part := range hardSplit(line, maxChars) {
+				pieces = append(pieces, codePiece{text: part, startLine: lineNo, endLine: lineNo})
+			}
+			continue
+		}
+
+		added := lineLen
+		if len(current) > 0 {
+			added++ // newline separator
+		}
+		if size+added > maxChars {
+			emit(lineNo - 1)
+			added = lineLen
+		}
+		if len(current) == 0 {
+			currentStart = lineNo
+		}
+		current = append(current, line)
+		size += added
+	}
+	emit(firstLine + end - 1)
+
+	return pieces
+}
+
 // splitSentences splits text into sentences using basic punctuation rules
 func splitSentences(text string) []string {
 	// Simple sentence splitting on .!? followed by whitespace or end of string
@@ -239,8 +1002,11 @@ func splitSentences(text string) []string {
 	return sentences
 }
 
-// ChunkDocument is a convenience function to chunk a document and create Document objects
-func ChunkDocument(text string, documentName string, chunker ChunkingStrategy, embeddingFunc func(string) ([]float32, error)) ([]Document, error) {
+// ChunkDocument is a convenience function to chunk a document and create Document objects.
+// baseMetadata (may be nil) is copied into every chunk's metadata; chunk-level metadata takes precedence.
+// Chunk IDs combine the document name, chunk index and a hash of the chunk text, so re-ingesting
+// identical content yields identical IDs.
+func ChunkDocument(text string, documentName string, chunker ChunkingStrategy, embeddingFunc func(string) ([]float32, error), baseMetadata map[string]interface{}) ([]Document, error) {
 	chunks, err := chunker.Chunk(text)
 	if err != nil {
 		return nil, fmt.Errorf("failed to chunk text: %w", err)
@@ -257,14 +1023,18 @@ func ChunkDocument(text string, documentName string, chunker ChunkingStrategy, e
 			embedding = emb
 		}
 
+		metadata := make(map[string]interface{}, len(baseMetadata)+len(chunk.Metadata)+1)
+		for k, v := range baseMetadata {
+			metadata[k] = v
+		}
+		metadata["chunk_index"] = chunk.Index
+
 		docs[i] = Document{
-			ID:           fmt.Sprintf("%s_chunk_%d", documentName, chunk.Index),
+			ID:           DocumentID(documentName, chunk.Index) + "_" + shortHash(chunk.Text),
 			Text:         chunk.Text,
 			DocumentName: documentName,
 			Embedding:    embedding,
-			Metadata: map[string]interface{}{
-				"chunk_index": chunk.Index,
-			},
+			Metadata:     metadata,
 		}
 
 		// Merge any chunk metadata
@@ -276,3 +1046,8 @@ func ChunkDocument(text string, documentName string, chunker ChunkingStrategy, e
 	return docs, nil
 }
 
+// shortHash returns the first 12 hex characters of the SHA256 of text
+func shortHash(text string) string {
+	hash := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(hash[:6])
+}
@@ -1,6 +1,8 @@
 package rag
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -16,3 +18,382 @@ func TestChunkingTestSuite(t *testing.T) {
 	suite.Run(t, new(ChunkingTestSuite))
 }
 
+const testMarkdown = `Intro text before any heading.
+
+# Getting Started
+
+This guide explains the basics.
+
+## Installation
+
+Run the installer. Then verify the install.
+
+` + "```" + `bash
+# not a heading, just a shell comment
+make install
+` + "```" + `
+
+## Usage
+
+Call the API. It returns results.
+
+Another paragraph about usage that adds more words to this section.
+`
+
+func (s *ChunkingTestSuite) TestMarkdownChunkerInvalid() {
+	_, err := NewMarkdownChunker(0)
+	s.Error(err)
+}
+
+func (s *ChunkingTestSuite) TestMarkdownChunkerSections() {
+	chunker, err := NewMarkdownChunker(1000)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk(testMarkdown)
+	s.Require().NoError(err)
+	s.Require().Len(chunks, 4)
+
+	s.Equal("", chunks[0].Metadata["section"])
+	s.Equal("Getting Started", chunks[1].Metadata["section"])
+	s.Equal(1, chunks[1].Metadata["section_level"])
+	s.Equal("Installation", chunks[2].Metadata["section"])
+	s.Equal(2, chunks[2].Metadata["section_level"])
+	s.Equal("Usage", chunks[3].Metadata["section"])
+
+	// The shell comment inside the code block must not start a new section
+	s.Contains(chunks[2].Text, "# not a heading")
+	s.Contains(chunks[2].Text, "make install")
+
+	for i, chunk := range chunks {
+		s.Equal(i, chunk.Index)
+	}
+}
+
+func (s *ChunkingTestSuite) TestMarkdownChunkerDoesNotStraddleHeadings() {
+	chunker, err := NewMarkdownChunker(60)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk(testMarkdown)
+	s.Require().NoError(err)
+	s.Greater(len(chunks), 4)
+
+	for _, chunk := range chunks {
+		s.LessOrEqual(len([]rune(chunk.Text)), 60)
+
+		// A heading may only appear as the first line of a chunk
+		lines := strings.Split(chunk.Text, "\n")
+		inFence := false
+		for i, line := range lines {
+			if isMarkdownFence(line) {
+				inFence = !inFence
+				continue
+			}
+			if i > 0 && !inFence {
+				s.False(markdownHeadingPattern.MatchString(line), "chunk straddles heading: %q", chunk.Text)
+			}
+		}
+	}
+
+	// Long sections are split but keep their section metadata
+	usageChunks := 0
+	for _, chunk := range chunks {
+		if strings.Contains(chunk.Text, "usage") || strings.Contains(chunk.Text, "Call the API") {
+			s.Equal("Usage", chunk.Metadata["section"])
+			usageChunks++
+		}
+	}
+	s.GreaterOrEqual(usageChunks, 2)
+}
+
+func (s *ChunkingTestSuite) TestMarkdownChunkerHardCut() {
+	chunker, err := NewMarkdownChunker(10)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk("# Title\n\n" + strings.Repeat("x", 35))
+	s.Require().NoError(err)
+	for _, chunk := range chunks {
+		s.LessOrEqual(len([]rune(chunk.Text)), 10)
+		s.Equal("Title", chunk.Metadata["section"])
+	}
+	s.Require().Len(chunks, 5)
+	s.Equal("# Title", chunks[0].Text)
+	joined := ""
+	for _, chunk := range chunks[1:] {
+		joined += chunk.Text
+	}
+	s.Equal(strings.Repeat("x", 35), joined)
+}
+
+func (s *ChunkingTestSuite) TestMarkdownChunkerEmpty() {
+	chunker, err := NewMarkdownChunker(100)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk("   \n\n ")
+	s.Require().NoError(err)
+	s.Empty(chunks)
+}
+
+func (s *ChunkingTestSuite) TestRecursiveChunkerInvalid() {
+	_, err := NewRecursiveChunker(0, 0, nil)
+	s.Error(err)
+	_, err = NewRecursiveChunker(10, -1, nil)
+	s.Error(err)
+	_, err = NewRecursiveChunker(10, 10, nil)
+	s.Error(err)
+
+	chunker, err := NewRecursiveChunker(10, 2, nil)
+	s.Require().NoError(err)
+	s.Equal(DefaultRecursiveSeparators, chunker.Separators)
+}
+
+func (s *ChunkingTestSuite) TestRecursiveChunkerPrefersParagraphs() {
+	text := "The first paragraph talks about cats.\n\nThe second paragraph talks about dogs.\n\nThe third one is about birds."
+
+	chunker, err := NewRecursiveChunker(45, 0, nil)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk(text)
+	s.Require().NoError(err)
+	s.Require().Len(chunks, 3)
+	s.Equal("The first paragraph talks about cats.", chunks[0].Text)
+	s.Equal("The second paragraph talks about dogs.", chunks[1].Text)
+	s.Equal("The third one is about birds.", chunks[2].Text)
+
+	s.Equal(0, chunks[0].Metadata["start"])
+	s.Equal(len("The first paragraph talks about cats."), chunks[0].Metadata["end"])
+}
+
+func (s *ChunkingTestSuite) TestRecursiveChunkerNoMidWordCuts() {
+	text := "alpha bravo charlie delta echo foxtrot golf hotel india juliet kilo lima"
+	words := strings.Fields(text)
+
+	chunker, err := NewRecursiveChunker(20, 0, nil)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk(text)
+	s.Require().NoError(err)
+	s.Greater(len(chunks), 1)
+
+	rebuilt := make([]string, 0)
+	for _, chunk := range chunks {
+		s.LessOrEqual(len([]rune(chunk.Text)), 20)
+		for _, word := range strings.Fields(chunk.Text) {
+			s.Contains(words, word, "chunk cut a word: %q", chunk.Text)
+			rebuilt = append(rebuilt, word)
+		}
+	}
+	s.Equal(words, rebuilt)
+}
+
+func (s *ChunkingTestSuite) TestRecursiveChunkerOverlap() {
+	text := "one two three four five six seven eight nine ten"
+
+	chunker, err := NewRecursiveChunker(20, 10, []string{" "})
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk(text)
+	s.Require().NoError(err)
+	s.Greater(len(chunks), 1)
+
+	for i := 1; i < len(chunks); i++ {
+		prev := strings.Fields(chunks[i-1].Text)
+		curr := strings.Fields(chunks[i].Text)
+		// The next chunk starts with the trailing words of the previous one
+		shared := 0
+		for k := 1; k <= len(prev) && k <= len(curr); k++ {
+			if strings.Join(prev[len(prev)-k:], " ") == strings.Join(curr[:k], " ") {
+				shared = k
+			}
+		}
+		s.Greater(shared, 0, "no overlap between %q and %q", chunks[i-1].Text, chunks[i].Text)
+		s.LessOrEqual(len([]rune(chunks[i].Text)), 20)
+	}
+}
+
+func (s *ChunkingTestSuite) TestRecursiveChunkerFallsBackToCharacters() {
+	chunker, err := NewRecursiveChunker(4, 0, nil)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk("abcdefghij")
+	s.Require().NoError(err)
+	s.Require().Len(chunks, 3)
+	s.Equal("abcd", chunks[0].Text)
+	s.Equal("efgh", chunks[1].Text)
+	s.Equal("ij", chunks[2].Text)
+}
+
+const testGoSource = `package sample
+
+import "fmt"
+
+// Add returns the sum of a and b
+func Add(a, b int) int {
+	return a + b
+}
+
+type Point struct {
+	X, Y int
+}
+
+// String formats the point, e.g. "{1 2}"
+func (p *Point) String() string {
+	if p == nil {
+		return "{}"
+	}
+	return fmt.Sprintf("{%d %d}", p.X, p.Y)
+}
+
+func main() {
+	fmt.Println(Add(1, 2))
+}
+`
+
+func (s *ChunkingTestSuite) TestCodeChunkerInvalid() {
+	_, err := NewCodeChunker("go", 0)
+	s.Error(err)
+	_, err = NewCodeChunker("cobol", 100)
+	s.Error(err)
+}
+
+func (s *ChunkingTestSuite) TestCodeChunkerGoFunctions() {
+	chunker, err := NewCodeChunker("go", 1000)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk(testGoSource)
+	s.Require().NoError(err)
+	s.Require().Len(chunks, 5)
+
+	s.Equal("package sample\n\nimport \"fmt\"", chunks[0].Text)
+	s.NotContains(chunks[0].Metadata, "symbol")
+
+	s.Equal("Add", chunks[1].Metadata["symbol"])
+	s.True(strings.HasPrefix(chunks[1].Text, "// Add returns the sum"))
+	s.True(strings.HasSuffix(chunks[1].Text, "return a + b\n}"))
+	s.Equal(5, chunks[1].Metadata["start_line"])
+	s.Equal(8, chunks[1].Metadata["end_line"])
+
+	s.Equal("Point", chunks[2].Metadata["symbol"])
+	s.Equal("String", chunks[3].Metadata["symbol"])
+	s.Contains(chunks[3].Text, `return fmt.Sprintf("{%d %d}", p.X, p.Y)`)
+	s.True(strings.HasSuffix(chunks[3].Text, "}"))
+
+	s.Equal("main", chunks[4].Metadata["symbol"])
+	s.Equal("go", chunks[4].Metadata["language"])
+
+	for i, chunk := range chunks {
+		s.Equal(i, chunk.Index)
+	}
+}
+
+func (s *ChunkingTestSuite) TestCodeChunkerPython() {
+	source := "import os\n\n@decorator\ndef first(x):\n    if x:\n        return 1\n\n    return 2\n\nclass Second:\n    def method(self):\n        pass\n"
+
+	chunker, err := NewCodeChunker("python", 1000)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk(source)
+	s.Require().NoError(err)
+	s.Require().Len(chunks, 3)
+	s.Equal("import os", chunks[0].Text)
+	s.Equal("first", chunks[1].Metadata["symbol"])
+	s.True(strings.HasPrefix(chunks[1].Text, "@decorator\ndef first"))
+	s.Contains(chunks[1].Text, "return 2")
+	s.Equal("Second", chunks[2].Metadata["symbol"])
+	s.Contains(chunks[2].Text, "def method")
+}
+
+func (s *ChunkingTestSuite) TestCodeChunkerJavaScript() {
+	source := "export function greet(name) {\n  return `hi ${name}`;\n}\n\nconst answer = () => {\n  return 42;\n};\n\nexport class Widget {\n  render() {}\n}\n"
+
+	chunker, err := NewCodeChunker("js", 1000)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk(source)
+	s.Require().NoError(err)
+	s.Require().Len(chunks, 3)
+	s.Equal("greet", chunks[0].Metadata["symbol"])
+	s.Equal("answer", chunks[1].Metadata["symbol"])
+	s.Equal("Widget", chunks[2].Metadata["symbol"])
+}
+
+func (s *ChunkingTestSuite) TestCodeChunkerSplitsLargeDeclarations() {
+	var b strings.Builder
+	b.WriteString("func Big() {\n")
+	for i := 0; i < 20; i++ {
+		b.WriteString("\tdoSomethingUseful()\n")
+	}
+	b.WriteString("}\n")
+
+	chunker, err := NewCodeChunker("go", 100)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk(b.String())
+	s.Require().NoError(err)
+	s.Greater(len(chunks), 1)
+	for _, chunk := range chunks {
+		s.LessOrEqual(len([]rune(chunk.Text)), 100)
+		s.Equal("Big", chunk.Metadata["symbol"])
+		s.NotContains(chunk.Text, "doSomethingUse\n", "lines must not be cut")
+	}
+}
+
+func (s *ChunkingTestSuite) TestCodeChunkerFallsBackToLines() {
+	source := "first line of text\nsecond line of text\nthird line of text\nfourth line of text"
+
+	chunker, err := NewCodeChunker("go", 40)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk(source)
+	s.Require().NoError(err)
+	s.Require().Len(chunks, 2)
+	s.Equal("first line of text\nsecond line of text", chunks[0].Text)
+	s.Equal("third line of text\nfourth line of text", chunks[1].Text)
+	s.Equal(3, chunks[1].Metadata["start_line"])
+	s.NotContains(chunks[0].Metadata, "symbol")
+}
+
+func (s *ChunkingTestSuite) TestChunkDocumentBaseMetadata() {
+	base := map[string]interface{}{
+		"source":      "handbook.md",
+		"chunk_index": -1, // overridden by the chunk's own index
+	}
+
+	docs, err := ChunkDocument("First paragraph.\n\nSecond paragraph.\n\nThird paragraph.", "handbook", NewParagraphChunker(), nil, base)
+	s.Require().NoError(err)
+	s.Require().Len(docs, 3)
+
+	for i, doc := range docs {
+		s.Equal("handbook.md", doc.Metadata["source"])
+		s.Equal(i, doc.Metadata["chunk_index"])
+		s.Equal("handbook", doc.DocumentName)
+	}
+
+	// Each chunk gets its own copy of the metadata
+	docs[0].Metadata["source"] = "changed"
+	s.Equal("handbook.md", docs[1].Metadata["source"])
+	s.Equal("handbook.md", base["source"])
+	s.Equal(-1, base["chunk_index"])
+}
+
+func (s *ChunkingTestSuite) TestChunkDocumentStableIDs() {
+	text := "Alpha paragraph.\n\nBeta paragraph."
+
+	first, err := ChunkDocument(text, "doc", NewParagraphChunker(), nil, nil)
+	s.Require().NoError(err)
+	second, err := ChunkDocument(text, "doc", NewParagraphChunker(), nil, nil)
+	s.Require().NoError(err)
+
+	s.Require().Len(first, 2)
+	s.Require().Len(second, 2)
+	for i := range first {
+		s.Equal(first[i].ID, second[i].ID)
+		s.True(strings.HasPrefix(first[i].ID, fmt.Sprintf("doc_%d_", i)))
+	}
+	s.NotEqual(first[0].ID, first[1].ID)
+
+	// Same position, different content: different ID
+	changed, err := ChunkDocument("Gamma paragraph.\n\nBeta paragraph.", "doc", NewParagraphChunker(), nil, nil)
+	s.Require().NoError(err)
+	s.NotEqual(first[0].ID, changed[0].ID)
+	s.Equal(first[1].ID, changed[1].ID)
+}
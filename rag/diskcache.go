@@ -0,0 +1,339 @@
+package rag
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// diskCacheIndexFile logs the keys set in a DiskEmbeddingCache, one per line, oldest first
+	diskCacheIndexFile = "index.log"
+	// diskCacheEntryExt is the file extension of cached embeddings
+	diskCacheEntryExt = ".emb"
+	// diskCacheTempPrefix prefixes temporary files used for atomic writes
+	diskCacheTempPrefix = ".tmp-"
+	// defaultDiskCacheEntries is the default capacity of a DiskEmbeddingCache
+	defaultDiskCacheEntries = 10000
+)
+
+// DiskEmbeddingCache is a persistent LRU embedding cache for processes that restart often (e.g., CLI tools).
+// Each embedding is stored in its own small file named after the SHA256 of the query, and an
+// index file records the LRU order. Every Set appends the key to the index; recency changes
+// from Get are kept in memory and persisted on Close. The index is compacted when the cache
+// is opened, closed, or cleared, and once it grows past twice the capacity.
+// It is safe for concurrent use within a process, but not across processes sharing a directory.
+type DiskEmbeddingCache struct {
+	mu         sync.Mutex
+	dir        string
+	maxEntries int
+	entries    map[string]*list.Element // key -> element holding the key
+	lru        *list.List               // front = most recently used
+	index      *os.File                 // index opened for appending, nil once closed
+	indexLines int                      // keys in the index file
+	stats      cacheCounters
+}
+
+// NewDiskEmbeddingCache opens (or creates) a disk cache in dir holding at most maxEntries embeddings.
+// Entries from a previous run are loaded in the order recorded by the index. Entry files missing
+// from the index (e.g., written just before a crash) are kept as the least recently used entries.
+func NewDiskEmbeddingCache(dir string, maxEntries int) (*DiskEmbeddingCache, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cache directory cannot be empty")
+	}
+	if maxEntries <= 0 {
+		maxEntries = defaultDiskCacheEntries
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	c := &DiskEmbeddingCache{
+		dir:        dir,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// load restores the LRU order from the index and the entry files on disk, then compacts the index
+func (c *DiskEmbeddingCache) load() error {
+	// Collect the entry files and remove leftovers of interrupted writes
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list cache directory: %w", err)
+	}
+	var stored []string
+	present := make(map[string]bool)
+	for _, file := range files {
+		name := file.Name()
+		switch {
+		case strings.HasPrefix(name, diskCacheTempPrefix):
+			os.Remove(filepath.Join(c.dir, name))
+		case strings.HasSuffix(name, diskCacheEntryExt):
+			key := strings.TrimSuffix(name, diskCacheEntryExt)
+			stored = append(stored, key)
+			present[key] = true
+		}
+	}
+
+	// Replay the index, oldest first; keys of evicted entries have no file and are skipped.
+	// A corrupt or truncated line only loses recency information.
+	data, err := os.ReadFile(filepath.Join(c.dir, diskCacheIndexFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read cache index: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key := scanner.Text()
+		if !present[key] || !isCacheKey(key) {
+			continue
+		}
+		if elem, exists := c.entries[key]; exists {
+			c.lru.MoveToFront(elem)
+		} else {
+			c.entries[key] = c.lru.PushFront(key)
+		}
+	}
+
+	// Entries written without reaching the index are the least recently used
+	for _, key := range stored {
+		if _, exists := c.entries[key]; !exists && isCacheKey(key) {
+			c.entries[key] = c.lru.PushBack(key)
+		}
+	}
+
+	for c.lru.Len() > c.maxEntries {
+		c.evictOldest()
+	}
+
+	return c.saveIndex()
+}
+
+// Get retrieves a cached embedding for the given query text.
+// Accessing an entry marks it as recently used.
+func (c *DiskEmbeddingCache) Get(query string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := hashQuery(query)
+	elem, found := c.entries[key]
+	if !found {
+		c.stats.misses.Add(1)
+		return nil, false
+	}
+
+	embedding, err := readEmbeddingFile(c.entryPath(key))
+	if err != nil {
+		// Unreadable entry: drop it and report a miss
+		c.removeElement(elem)
+		c.stats.misses.Add(1)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	c.stats.hits.Add(1)
+	return embedding, true
+}
+
+// Set stores an embedding for the given query text.
+// If the cache is full, the least recently used entry is evicted.
+// Write errors are ignored; the entry simply isn't cached.
+func (c *DiskEmbeddingCache) Set(query string, embedding []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := hashQuery(query)
+	if err := writeFileAtomic(c.entryPath(key), encodeEmbedding(embedding)); err != nil {
+		return
+	}
+
+	if elem, found := c.entries[key]; found {
+		c.lru.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.lru.PushFront(key)
+	}
+
+	for c.lru.Len() > c.maxEntries {
+		c.evictOldest()
+		c.stats.evictions.Add(1)
+	}
+
+	c.appendIndex(key)
+}
+
+// Clear removes all cached entries from memory and disk
+func (c *DiskEmbeddingCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		os.Remove(c.entryPath(key))
+	}
+	c.entries = make(map[string]*list.Element)
+	c.lru = list.New()
+	c.saveIndex()
+}
+
+// Size returns the current number of cached entries
+func (c *DiskEmbeddingCache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// Stats returns a snapshot of the cache's hit/miss/eviction counters for this process
+func (c *DiskEmbeddingCache) Stats() CacheStats {
+	return c.stats.snapshot(c.Size())
+}
+
+// Close persists the current LRU order. The cache must not be used afterwards.
+func (c *DiskEmbeddingCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.saveIndex()
+	if c.index != nil {
+		c.index.Close()
+		c.index = nil
+	}
+	return err
+}
+
+// entryPath returns the file holding the embedding for key
+func (c *DiskEmbeddingCache) entryPath(key string) string {
+	return filepath.Join(c.dir, key+diskCacheEntryExt)
+}
+
+// evictOldest removes the least recently used entry (must be called with lock held)
+func (c *DiskEmbeddingCache) evictOldest() {
+	if elem := c.lru.Back(); elem != nil {
+		c.removeElement(elem)
+	}
+}
+
+// removeElement removes an entry from memory and disk (must be called with lock held)
+func (c *DiskEmbeddingCache) removeElement(elem *list.Element) {
+	key := elem.Value.(string)
+	c.lru.Remove(elem)
+	delete(c.entries, key)
+	os.Remove(c.entryPath(key))
+}
+
+// appendIndex records key as the most recently used entry, compacting the index once it has
+// grown past twice the capacity (must be called with lock held). Write errors are ignored; an
+// entry missing from the index is still loaded on the next open.
+func (c *DiskEmbeddingCache) appendIndex(key string) {
+	if c.index == nil {
+		return
+	}
+	if c.indexLines >= 2*c.maxEntries {
+		c.saveIndex()
+		return
+	}
+	if _, err := c.index.WriteString(key + "\n"); err == nil {
+		c.indexLines++
+	}
+}
+
+// saveIndex rewrites the index with the current LRU order and reopens it for appending
+// (must be called with lock held)
+func (c *DiskEmbeddingCache) saveIndex() error {
+	var data bytes.Buffer
+	for elem := c.lru.Back(); elem != nil; elem = elem.Prev() {
+		data.WriteString(elem.Value.(string) + "\n")
+	}
+
+	path := filepath.Join(c.dir, diskCacheIndexFile)
+	if err := writeFileAtomic(path, data.Bytes()); err != nil {
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+	c.indexLines = c.lru.Len()
+
+	if c.index != nil {
+		c.index.Close()
+	}
+	index, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		c.index = nil
+		return fmt.Errorf("failed to open cache index: %w", err)
+	}
+	c.index = index
+	return nil
+}
+
+// isCacheKey reports whether name is a key produced by hashQuery
+func isCacheKey(name string) bool {
+	if len(name) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+// encodeEmbedding serializes an embedding as little-endian float32 values
+func encodeEmbedding(embedding []float32) []byte {
+	data := make([]byte, 4*len(embedding))
+	for i, v := range embedding {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+// readEmbeddingFile reads an embedding written by encodeEmbedding
+func readEmbeddingFile(path string) ([]float32, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("corrupt embedding file %s: %d bytes", path, len(data))
+	}
+
+	embedding := make([]float32, len(data)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return embedding, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so readers never observe a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), diskCacheTempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
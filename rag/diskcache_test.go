@@ -0,0 +1,224 @@
+package rag
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// DiskCacheTestSuite tests the persistent embedding cache
+type DiskCacheTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+// TestDiskCacheTestSuite runs the disk cache test suite
+func TestDiskCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(DiskCacheTestSuite))
+}
+
+func (s *DiskCacheTestSuite) SetupTest() {
+	s.dir = filepath.Join(s.T().TempDir(), "embeddings")
+}
+
+func (s *DiskCacheTestSuite) TestInvalidDir() {
+	_, err := NewDiskEmbeddingCache("", 10)
+	s.Error(err)
+}
+
+func (s *DiskCacheTestSuite) TestSurvivesRestart() {
+	cache, err := NewDiskEmbeddingCache(s.dir, 10)
+	s.Require().NoError(err)
+
+	cache.Set("first query", []float32{0.1, 0.2, 0.3})
+	cache.Set("second query", []float32{-1.5, 2.25})
+	s.Require().NoError(cache.Close())
+
+	reopened, err := NewDiskEmbeddingCache(s.dir, 10)
+	s.Require().NoError(err)
+	s.Equal(2, reopened.Size())
+
+	embedding, found := reopened.Get("first query")
+	s.True(found)
+	s.Equal([]float32{0.1, 0.2, 0.3}, embedding)
+
+	embedding, found = reopened.Get("second query")
+	s.True(found)
+	s.Equal([]float32{-1.5, 2.25}, embedding)
+
+	_, found = reopened.Get("unknown query")
+	s.False(found)
+}
+
+func (s *DiskCacheTestSuite) TestSurvivesRestartWithoutClose() {
+	cache, err := NewDiskEmbeddingCache(s.dir, 10)
+	s.Require().NoError(err)
+	cache.Set("query", []float32{1, 2})
+
+	// Every Set persists the index, so a crash without Close keeps the entry
+	reopened, err := NewDiskEmbeddingCache(s.dir, 10)
+	s.Require().NoError(err)
+	_, found := reopened.Get("query")
+	s.True(found)
+}
+
+func (s *DiskCacheTestSuite) TestLRUEvictionPersists() {
+	cache, err := NewDiskEmbeddingCache(s.dir, 2)
+	s.Require().NoError(err)
+
+	cache.Set("a", []float32{1})
+	cache.Set("b", []float32{2})
+	cache.Get("a") // "b" becomes least recently used
+	cache.Set("c", []float32{3})
+	s.Require().NoError(cache.Close())
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "*"+diskCacheEntryExt))
+	s.Require().NoError(err)
+	s.Len(files, 2, "evicted entry file should be deleted")
+
+	reopened, err := NewDiskEmbeddingCache(s.dir, 2)
+	s.Require().NoError(err)
+	_, found := reopened.Get("b")
+	s.False(found)
+	_, found = reopened.Get("a")
+	s.True(found)
+	_, found = reopened.Get("c")
+	s.True(found)
+}
+
+func (s *DiskCacheTestSuite) TestReopenWithSmallerCapacity() {
+	cache, err := NewDiskEmbeddingCache(s.dir, 10)
+	s.Require().NoError(err)
+	for i := 0; i < 5; i++ {
+		cache.Set(fmt.Sprintf("q%d", i), []float32{float32(i)})
+	}
+	s.Require().NoError(cache.Close())
+
+	reopened, err := NewDiskEmbeddingCache(s.dir, 3)
+	s.Require().NoError(err)
+	s.Equal(3, reopened.Size())
+
+	// The most recently set entries are kept
+	for i := 2; i < 5; i++ {
+		_, found := reopened.Get(fmt.Sprintf("q%d", i))
+		s.True(found)
+	}
+}
+
+func (s *DiskCacheTestSuite) TestClear() {
+	cache, err := NewDiskEmbeddingCache(s.dir, 10)
+	s.Require().NoError(err)
+	cache.Set("a", []float32{1})
+	cache.Set("b", []float32{2})
+
+	cache.Clear()
+	s.Equal(0, cache.Size())
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "*"+diskCacheEntryExt))
+	s.Require().NoError(err)
+	s.Empty(files)
+
+	reopened, err := NewDiskEmbeddingCache(s.dir, 10)
+	s.Require().NoError(err)
+	s.Equal(0, reopened.Size())
+}
+
+func (s *DiskCacheTestSuite) TestCorruptEntryIsMiss() {
+	cache, err := NewDiskEmbeddingCache(s.dir, 10)
+	s.Require().NoError(err)
+	cache.Set("query", []float32{1, 2})
+
+	s.Require().NoError(os.WriteFile(filepath.Join(s.dir, hashQuery("query")+diskCacheEntryExt), []byte{1, 2, 3}, 0644))
+
+	_, found := cache.Get("query")
+	s.False(found)
+	s.Equal(0, cache.Size())
+}
+
+func (s *DiskCacheTestSuite) TestKeepsUnindexedFiles() {
+	s.Require().NoError(os.MkdirAll(s.dir, 0755))
+	unindexed := filepath.Join(s.dir, hashQuery("unindexed")+diskCacheEntryExt)
+	temp := filepath.Join(s.dir, diskCacheTempPrefix+"123")
+	s.Require().NoError(os.WriteFile(unindexed, encodeEmbedding([]float32{1}), 0644))
+	s.Require().NoError(os.WriteFile(temp, []byte("partial"), 0644))
+
+	cache, err := NewDiskEmbeddingCache(s.dir, 10)
+	s.Require().NoError(err)
+	s.Equal(1, cache.Size())
+
+	embedding, found := cache.Get("unindexed")
+	s.True(found)
+	s.Equal([]float32{1}, embedding)
+	s.NoFileExists(temp)
+}
+
+func (s *DiskCacheTestSuite) TestIndexIsAppendedAndCompacted() {
+	cache, err := NewDiskEmbeddingCache(s.dir, 2)
+	s.Require().NoError(err)
+	defer cache.Close()
+
+	indexLines := func() int {
+		data, err := os.ReadFile(filepath.Join(s.dir, diskCacheIndexFile))
+		s.Require().NoError(err)
+		return strings.Count(string(data), "\n")
+	}
+
+	cache.Set("a", []float32{1})
+	cache.Set("a", []float32{1})
+	s.Equal(2, indexLines(), "each Set appends its key")
+
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprintf("q%d", i), []float32{float32(i)})
+		s.LessOrEqual(indexLines(), 4, "the index is compacted at twice the capacity")
+	}
+
+	reopened, err := NewDiskEmbeddingCache(s.dir, 2)
+	s.Require().NoError(err)
+	_, found := reopened.Get("q9")
+	s.True(found)
+	_, found = reopened.Get("q8")
+	s.True(found)
+}
+
+func (s *DiskCacheTestSuite) TestConcurrentAccess() {
+	cache, err := NewDiskEmbeddingCache(s.dir, 20)
+	s.Require().NoError(err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			query := fmt.Sprintf("q%d", i%30)
+			cache.Set(query, []float32{float32(i)})
+			cache.Get(query)
+		}(i)
+	}
+	wg.Wait()
+
+	s.LessOrEqual(cache.Size(), 20)
+	s.Require().NoError(cache.Close())
+
+	reopened, err := NewDiskEmbeddingCache(s.dir, 20)
+	s.Require().NoError(err)
+	s.Equal(cache.Size(), reopened.Size())
+}
+
+func (s *DiskCacheTestSuite) TestStats() {
+	cache, err := NewDiskEmbeddingCache(s.dir, 1)
+	s.Require().NoError(err)
+	defer cache.Close()
+
+	cache.Set("a", []float32{1})
+	cache.Get("a")               // hit
+	cache.Get("b")               // miss
+	cache.Set("b", []float32{2}) // evicts a
+	cache.Get("a")               // miss
+
+	s.Equal(CacheStats{Hits: 1, Misses: 2, Evictions: 1, Size: 1}, cache.Stats())
+}
@@ -0,0 +1,19 @@
+package rag
+
+import "fmt"
+
+// DocumentID returns the ID of the chunk at chunkIndex within documentName.
+// The ID depends only on its inputs, so re-ingesting a document with the same
+// chunking yields the same IDs, and UpsertDocuments replaces the old chunks
+// instead of duplicating them.
+func DocumentID(documentName string, chunkIndex int) string {
+	return fmt.Sprintf("%s_%d", documentName, chunkIndex)
+}
+
+// ContentDocumentID returns an ID derived from documentName and the chunk's text.
+// Like DocumentID it is stable across runs, but it doesn't depend on the chunk's
+// position: the same text in the same document always gets the same ID, even if
+// chunks before it were added or removed.
+func ContentDocumentID(documentName, text string) string {
+	return fmt.Sprintf("%s_%s", documentName, shortHash(text))
+}
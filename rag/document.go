@@ -2,10 +2,14 @@ package rag
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/float16"
 	"github.com/apache/arrow/go/v17/arrow/memory"
 	"github.com/aqua777/go-lancedb"
 )
@@ -21,14 +25,83 @@ type Document struct {
 
 // AddDocuments adds documents to the user's table with automatic indexing.
 // Large document sets are automatically batched to prevent memory exhaustion.
+// If writing fails part way, the error is a *PartialWriteError reporting how many
+// documents were stored.
 func (s *RAGStore) AddDocuments(ctx context.Context, userID string, docs []Document) error {
 	return s.AddDocumentsWithProgress(ctx, userID, docs, nil)
 }
 
+// AddDocumentsForDim adds documents for a user whose embeddings use a dimension other than the store default.
+// The dimension is recorded for the user when their table is created; subsequent inserts and searches
+// for that user are validated against it. Returns an error if the user's table already uses a different dimension.
+// If the user's first insert fails, the dimension is forgotten, so it can be retried with another.
+func (s *RAGStore) AddDocumentsForDim(ctx context.Context, userID string, docs []Document, dim int) error {
+	if err := s.validateUserID(userID); err != nil {
+		return err
+	}
+	unregister, err := s.registerUserEmbeddingDim(userID, dim)
+	if err != nil {
+		return err
+	}
+	if err := s.AddDocumentsWithProgress(ctx, userID, docs, nil); err != nil {
+		unregister()
+		return err
+	}
+	return nil
+}
+
+// PartialWriteError is returned when inserting documents fails after the write started.
+// Documents are written in batches of the store's batch size, and each batch is committed
+// on its own, so the first Committed documents are stored even though the call failed.
+// Retry with docs[Committed:] to finish the write without duplicates.
+type PartialWriteError struct {
+	Committed int   // Documents stored before the failure, counted from the start of the input
+	Total     int   // Documents in the failed call
+	Err       error // The underlying failure
+}
+
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("stored %d of %d documents before failing: %v", e.Committed, e.Total, e.Err)
+}
+
+// Unwrap returns the underlying failure
+func (e *PartialWriteError) Unwrap() error {
+	return e.Err
+}
+
 // AddDocumentsWithProgress adds documents with progress reporting.
 // The callback receives progress updates during the operation.
 // Pass nil for callback to disable progress reporting (equivalent to AddDocuments).
 func (s *RAGStore) AddDocumentsWithProgress(ctx context.Context, userID string, docs []Document, callback ProgressCallback) error {
+	return s.addDocuments(ctx, userID, docs, s.GetEmbeddingModel(), false, nil, callback)
+}
+
+// AddDocumentsOptions configures AddDocumentsWithOptions
+type AddDocumentsOptions struct {
+	NormalizeEmbeddings bool             // If true, scale embeddings to unit length before inserting them when the user's index uses the cosine metric
+	Progress            ProgressCallback // Receives progress updates as AddDocumentsWithProgress describes; may be nil
+}
+
+// AddDocumentsWithOptions adds documents like AddDocuments. With opts.NormalizeEmbeddings,
+// embeddings are scaled to unit length before insert for users whose index uses the cosine
+// metric (the default), where embeddings of different lengths skew results; the documents
+// passed in are not modified. A nil opts is equivalent to AddDocuments.
+func (s *RAGStore) AddDocumentsWithOptions(ctx context.Context, userID string, docs []Document, opts *AddDocumentsOptions) error {
+	if opts == nil {
+		opts = &AddDocumentsOptions{}
+	}
+	return s.addDocuments(ctx, userID, docs, s.GetEmbeddingModel(), opts.NormalizeEmbeddings, nil, opts.Progress)
+}
+
+// addDocuments inserts documents whose embeddings were produced by model ("" if unknown),
+// normalizing their embeddings first if normalize is set and the user's index uses cosine.
+// If assignIDs isn't nil, it is called with the user's lock held before anything is
+// written, to set the IDs of the documents to insert.
+func (s *RAGStore) addDocuments(ctx context.Context, userID string, docs []Document, model string, normalize bool, assignIDs func([]Document) error, callback ProgressCallback) (err error) {
+	ctx, span := s.startSpan(ctx, "rag.AddDocuments", userID)
+	span.SetAttribute(SpanAttrDocumentCount, len(docs))
+	defer func() { endSpan(span, err) }()
+
 	if len(docs) == 0 {
 		return fmt.Errorf("no documents to add")
 	}
@@ -40,12 +113,13 @@ func (s *RAGStore) AddDocumentsWithProgress(ctx context.Context, userID string,
 	default:
 	}
 
-	// Validate embeddings
-	for i, doc := range docs {
-		if len(doc.Embedding) != s.embeddingDim {
-			return fmt.Errorf("document %d: embedding dimension mismatch: expected %d, got %d",
-				i, s.embeddingDim, len(doc.Embedding))
-		}
+	// Validate every document against the user's dimension before writing any
+	dim := s.userEmbeddingDim(userID)
+	if invalid := validateDocuments(docs, dim, s.getMetadataCodec()); len(invalid) > 0 {
+		return fmt.Errorf("%d invalid documents: %s", len(invalid), summarizeDocumentErrors(invalid))
+	}
+	if normalize {
+		docs = s.normalizeEmbeddings(userID, docs)
 	}
 
 	// Initialize progress tracker
@@ -56,9 +130,20 @@ func (s *RAGStore) AddDocumentsWithProgress(ctx context.Context, userID string,
 	}
 
 	// Acquire per-user lock for concurrent write protection
-	lock := s.getUserLock(userID)
-	lock.Lock()
-	defer lock.Unlock()
+	unlock := s.lockUser(userID)
+	defer unlock()
+	defer s.invalidateTable(userID)
+
+	if assignIDs != nil {
+		if err := assignIDs(docs); err != nil {
+			return err
+		}
+	}
+
+	// Check the quota first, so a rejected write neither creates the table nor records the model in it
+	if err := s.checkQuota(ctx, userID, docs, false); err != nil {
+		return err
+	}
 
 	// Get or create table
 	table, err := s.getOrCreateTable(userID)
@@ -67,12 +152,17 @@ func (s *RAGStore) AddDocumentsWithProgress(ctx context.Context, userID string,
 	}
 	defer table.Close()
 
+	if err := s.recordEmbeddingModel(table, userID, model, dim); err != nil {
+		return err
+	}
+	storage := s.userEmbeddingStorage(userID)
+
 	// Process documents in batches to prevent memory exhaustion
 	for batchStart := 0; batchStart < len(docs); batchStart += s.maxBatchSize {
 		// Check for context cancellation between batches
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return &PartialWriteError{Committed: batchStart, Total: len(docs), Err: ctx.Err()}
 		default:
 		}
 
@@ -82,8 +172,15 @@ func (s *RAGStore) AddDocumentsWithProgress(ctx context.Context, userID string,
 		}
 		batch := docs[batchStart:batchEnd]
 
-		if err := s.addDocumentsBatch(table, batch); err != nil {
-			return fmt.Errorf("failed to add batch [%d:%d]: %w", batchStart, batchEnd, err)
+		err := s.withRetry(ctx, "rag.AddDocuments", func() error {
+			return s.addDocumentsBatch(table, batch, dim, storage)
+		})
+		if err != nil {
+			return &PartialWriteError{
+				Committed: batchStart,
+				Total:     len(docs),
+				Err:       fmt.Errorf("failed to add batch [%d:%d]: %w", batchStart, batchEnd, err),
+			}
 		}
 
 		// Update progress
@@ -96,7 +193,7 @@ func (s *RAGStore) AddDocumentsWithProgress(ctx context.Context, userID string,
 	if tracker != nil {
 		tracker.SetStage("indexing")
 	}
-	if err := s.ensureIndex(table, userID); err != nil {
+	if err := s.ensureIndex(ctx, table, userID); err != nil {
 		return fmt.Errorf("failed to create index: %w", err)
 	}
 
@@ -108,19 +205,121 @@ func (s *RAGStore) AddDocumentsWithProgress(ctx context.Context, userID string,
 	return nil
 }
 
-// addDocumentsBatch inserts a single batch of documents
-func (s *RAGStore) addDocumentsBatch(table *lancedb.Table, docs []Document) error {
-	// Build Arrow record
-	schema := arrow.NewSchema(
-		[]arrow.Field{
-			{Name: "id", Type: arrow.BinaryTypes.String, Nullable: false},
-			{Name: "text", Type: arrow.BinaryTypes.String, Nullable: false},
-			{Name: "document_name", Type: arrow.BinaryTypes.String, Nullable: false},
-			{Name: "embedding", Type: arrow.FixedSizeListOf(int32(s.embeddingDim), arrow.PrimitiveTypes.Float32), Nullable: false},
-			{Name: "metadata", Type: arrow.BinaryTypes.String, Nullable: true},
-		},
-		nil,
-	)
+// DocumentError describes a document that failed validation
+type DocumentError struct {
+	Index  int    // Position of the document in the validated slice
+	ID     string // Document ID (may be empty)
+	Reason string // Why the document is invalid
+}
+
+func (e DocumentError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("document %d: %s", e.Index, e.Reason)
+	}
+	return fmt.Sprintf("document %d (%s): %s", e.Index, e.ID, e.Reason)
+}
+
+// ValidateDocuments checks documents without touching the database, as AddDocuments does
+// before writing: every document needs text, a finite embedding of the store's default
+// dimension and metadata the store's codec can encode. It returns one DocumentError per
+// invalid document, or nil if all are valid.
+func (s *RAGStore) ValidateDocuments(docs []Document) []DocumentError {
+	return validateDocuments(docs, s.embeddingDim, s.getMetadataCodec())
+}
+
+// validateDocuments checks every document's required fields against the embedding dimension,
+// and its metadata against the codec
+func validateDocuments(docs []Document, dim int, codec MetadataCodec) []DocumentError {
+	var invalid []DocumentError
+	for i, doc := range docs {
+		reason := documentFieldsError(doc.Text, doc.Embedding, dim)
+		if reason == "" {
+			if _, err := codec.Encode(doc.Metadata); err != nil {
+				reason = fmt.Sprintf("metadata cannot be encoded: %v", err)
+			}
+		}
+
+		if reason != "" {
+			invalid = append(invalid, DocumentError{Index: i, ID: doc.ID, Reason: reason})
+		}
+	}
+	return invalid
+}
+
+// documentFieldsError returns why a document's text or embedding is invalid for dim, or ""
+// if both are valid. Documents and backup documents share these required-field checks.
+func documentFieldsError(text string, embedding []float32, dim int) string {
+	if text == "" {
+		return "missing text"
+	}
+	if len(embedding) != dim {
+		return fmt.Sprintf("embedding dimension mismatch: expected %d, got %d", dim, len(embedding))
+	}
+	for _, v := range embedding {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return "embedding contains NaN or Inf"
+		}
+	}
+	return ""
+}
+
+// summarizeDocumentErrors describes the first maxReportedInvalidDocuments invalid documents
+func summarizeDocumentErrors(invalid []DocumentError) string {
+	parts := make([]string, 0, maxReportedInvalidDocuments+1)
+	for i, docErr := range invalid {
+		if i == maxReportedInvalidDocuments {
+			parts = append(parts, fmt.Sprintf("and %d more", len(invalid)-i))
+			break
+		}
+		parts = append(parts, docErr.Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// addDocumentsBatch inserts a single batch of documents with the given embedding dimension
+func (s *RAGStore) addDocumentsBatch(table *lancedb.Table, docs []Document, embeddingDim int, storage EmbeddingStorage) error {
+	schema := documentSchemaFor(embeddingDim, storage)
+	record, err := buildDocumentRecord(schema, docs, s.getMetadataCodec())
+	if err != nil {
+		return err
+	}
+	defer record.Release()
+
+	if err := lancedb.ValidateRecordAgainstSchema(record, schema); err != nil {
+		return fmt.Errorf("invalid embeddings: %w", err)
+	}
+
+	// Insert data
+	if err := table.Add(record, lancedb.AddModeAppend); err != nil {
+		return fmt.Errorf("failed to add documents: %w", err)
+	}
+
+	return nil
+}
+
+// stageDocumentsBatch stages a batch of documents to be inserted when txn commits
+func (s *RAGStore) stageDocumentsBatch(txn *lancedb.Txn, docs []Document, embeddingDim int, storage EmbeddingStorage) error {
+	schema := documentSchemaFor(embeddingDim, storage)
+	record, err := buildDocumentRecord(schema, docs, s.getMetadataCodec())
+	if err != nil {
+		return err
+	}
+	defer record.Release()
+
+	if err := lancedb.ValidateRecordAgainstSchema(record, schema); err != nil {
+		return fmt.Errorf("invalid embeddings: %w", err)
+	}
+
+	if err := txn.Add(record); err != nil {
+		return fmt.Errorf("failed to add documents: %w", err)
+	}
+
+	return nil
+}
+
+// buildDocumentRecord converts documents into an Arrow record with the given document schema,
+// encoding metadata with codec. The caller must release the returned record.
+func buildDocumentRecord(schema *arrow.Schema, docs []Document, codec MetadataCodec) (arrow.Record, error) {
 
 	mem := memory.NewGoAllocator()
 	recordBuilder := array.NewRecordBuilder(mem, schema)
@@ -130,41 +329,55 @@ func (s *RAGStore) addDocumentsBatch(table *lancedb.Table, docs []Document) erro
 	textBuilder := recordBuilder.Field(1).(*array.StringBuilder)
 	docNameBuilder := recordBuilder.Field(2).(*array.StringBuilder)
 	embeddingBuilder := recordBuilder.Field(3).(*array.FixedSizeListBuilder)
-	embeddingValueBuilder := embeddingBuilder.ValueBuilder().(*array.Float32Builder)
 	metadataBuilder := recordBuilder.Field(4).(*array.StringBuilder)
 
+	// Int8 tables store each embedding's scale in an extra column
+	var scaleBuilder *array.Float32Builder
+	if indices := schema.FieldIndices(embeddingScaleColumn); len(indices) > 0 {
+		scaleBuilder = recordBuilder.Field(indices[0]).(*array.Float32Builder)
+	}
+
 	for _, doc := range docs {
 		idBuilder.Append(doc.ID)
 		textBuilder.Append(doc.Text)
 		docNameBuilder.Append(doc.DocumentName)
 
-		// Append embedding
+		// Append embedding, converted to the table's storage type
 		embeddingBuilder.Append(true)
-		for _, val := range doc.Embedding {
-			embeddingValueBuilder.Append(val)
+		switch valueBuilder := embeddingBuilder.ValueBuilder().(type) {
+		case *array.Float32Builder:
+			valueBuilder.AppendValues(doc.Embedding, nil)
+		case *array.Float16Builder:
+			for _, val := range doc.Embedding {
+				valueBuilder.Append(float16.New(val))
+			}
+		case *array.Int8Builder:
+			codes, scale := QuantizeInt8(doc.Embedding)
+			valueBuilder.AppendValues(codes, nil)
+			if scaleBuilder != nil {
+				scaleBuilder.Append(scale)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported embedding type %s", embeddingBuilder.Type())
 		}
 
 		// Encode and append metadata
-		metaJSON, err := encodeMetadata(doc.Metadata)
+		meta, err := codec.Encode(doc.Metadata)
 		if err != nil {
-			return fmt.Errorf("failed to encode metadata for document %s: %w", doc.ID, err)
+			return nil, fmt.Errorf("failed to encode metadata for document %s: %w", doc.ID, err)
 		}
-		metadataBuilder.Append(metaJSON)
-	}
-
-	record := recordBuilder.NewRecord()
-	defer record.Release()
-
-	// Insert data
-	if err := table.Add(record, lancedb.AddModeAppend); err != nil {
-		return fmt.Errorf("failed to add documents: %w", err)
+		metadataBuilder.Append(meta)
 	}
 
-	return nil
+	return recordBuilder.NewRecord(), nil
 }
 
 // DeleteByDocumentName removes all chunks associated with a document name
-func (s *RAGStore) DeleteByDocumentName(ctx context.Context, userID string, documentName string) error {
+func (s *RAGStore) DeleteByDocumentName(ctx context.Context, userID string, documentName string) (err error) {
+	ctx, span := s.startSpan(ctx, "rag.DeleteByDocumentName", userID)
+	span.SetAttribute(SpanAttrDocumentName, documentName)
+	defer func() { endSpan(span, err) }()
+
 	if documentName == "" {
 		return fmt.Errorf("document name cannot be empty")
 	}
@@ -177,11 +390,11 @@ func (s *RAGStore) DeleteByDocumentName(ctx context.Context, userID string, docu
 	}
 
 	// Acquire per-user lock for write protection
-	lock := s.getUserLock(userID)
-	lock.Lock()
-	defer lock.Unlock()
+	unlock := s.lockUser(userID)
+	defer unlock()
+	defer s.invalidateTable(userID)
 
-	table, err := s.conn.OpenTable(s.getTableName(userID))
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
 	if err != nil {
 		return fmt.Errorf("failed to open table for user %s: %w", userID, err)
 	}
@@ -189,13 +402,93 @@ func (s *RAGStore) DeleteByDocumentName(ctx context.Context, userID string, docu
 
 	// Delete rows matching the document name
 	predicate := fmt.Sprintf("document_name = '%s'", escapeSQLString(documentName))
-	if err := table.Delete(predicate); err != nil {
+	err = s.withRetry(ctx, "rag.DeleteByDocumentName", func() error { return table.Delete(predicate) })
+	if err != nil {
 		return fmt.Errorf("failed to delete documents with name %s: %w", documentName, err)
 	}
 
 	return nil
 }
 
+// DeleteByDocumentNames removes all chunks associated with any of the given document names
+// in a single delete, which is much cheaper than calling DeleteByDocumentName for each name.
+func (s *RAGStore) DeleteByDocumentNames(ctx context.Context, userID string, names []string) (err error) {
+	ctx, span := s.startSpan(ctx, "rag.DeleteByDocumentNames", userID)
+	span.SetAttribute(SpanAttrDocumentCount, len(names))
+	defer func() { endSpan(span, err) }()
+
+	if len(names) == 0 {
+		return fmt.Errorf("no document names to delete")
+	}
+
+	literals := make([]string, len(names))
+	for i, name := range names {
+		if name == "" {
+			return fmt.Errorf("document name %d cannot be empty", i)
+		}
+		literals[i] = "'" + escapeSQLString(name) + "'"
+	}
+
+	// Check for context cancellation
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	// Acquire per-user lock for write protection
+	unlock := s.lockUser(userID)
+	defer unlock()
+	defer s.invalidateTable(userID)
+
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
+	if err != nil {
+		return fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	defer table.Close()
+
+	predicate := fmt.Sprintf("document_name IN (%s)", strings.Join(literals, ", "))
+	err = s.withRetry(ctx, "rag.DeleteByDocumentNames", func() error { return table.Delete(predicate) })
+	if err != nil {
+		return fmt.Errorf("failed to delete %d documents: %w", len(names), err)
+	}
+
+	return nil
+}
+
+// idPredicateBatchSize is the maximum number of IDs per "id IN (...)" predicate
+const idPredicateBatchSize = 500
+
+// idPredicates returns "id IN (...)" predicates that together match the given IDs,
+// each listing at most idPredicateBatchSize of them
+func idPredicates(ids []string) []string {
+	var predicates []string
+	for start := 0; start < len(ids); start += idPredicateBatchSize {
+		end := start + idPredicateBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		literals := make([]string, end-start)
+		for i, id := range ids[start:end] {
+			literals[i] = "'" + escapeSQLString(id) + "'"
+		}
+		predicates = append(predicates, fmt.Sprintf("id IN (%s)", strings.Join(literals, ", ")))
+	}
+	return predicates
+}
+
+// documentIDs returns the non-empty IDs of docs
+func documentIDs(docs []Document) []string {
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		if doc.ID != "" {
+			ids = append(ids, doc.ID)
+		}
+	}
+	return ids
+}
+
 // ClearUserData deletes all rows from the user's table but keeps the table structure
 func (s *RAGStore) ClearUserData(ctx context.Context, userID string) error {
 	exists, err := s.TableExists(ctx, userID)
@@ -214,11 +507,11 @@ func (s *RAGStore) ClearUserData(ctx context.Context, userID string) error {
 	}
 
 	// Acquire per-user lock for write protection
-	lock := s.getUserLock(userID)
-	lock.Lock()
-	defer lock.Unlock()
+	unlock := s.lockUser(userID)
+	defer unlock()
+	defer s.invalidateTable(userID)
 
-	table, err := s.conn.OpenTable(s.getTableName(userID))
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
 	if err != nil {
 		return fmt.Errorf("failed to open table for user %s: %w", userID, err)
 	}
@@ -233,6 +526,7 @@ func (s *RAGStore) ClearUserData(ctx context.Context, userID string) error {
 	// Reset index tracking
 	s.mu.Lock()
 	delete(s.indexCreated, userID)
+	delete(s.indexMetrics, userID)
 	s.mu.Unlock()
 
 	return nil
@@ -244,6 +538,47 @@ func (s *RAGStore) ClearUserTable(ctx context.Context, userID string) error {
 	return s.ClearUserData(ctx, userID)
 }
 
+// DropUserTable removes a user completely by dropping their table and
+// forgetting all per-user state (index tracking, index config, embedding dimension, lock).
+// The lock is removed once no other operation holds or waits for it.
+// This is idempotent: dropping a user without a table is not an error.
+func (s *RAGStore) DropUserTable(ctx context.Context, userID string) error {
+	if err := s.validateUserID(userID); err != nil {
+		return err
+	}
+
+	// Check for context cancellation
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	// Acquire per-user lock for write protection, so a table a concurrent write just
+	// created is dropped too
+	unlock := s.lockUser(userID)
+	defer unlock()
+
+	s.invalidateTable(userID)
+	err := s.getConn().DropTable(s.getTableName(userID))
+	if err != nil && !errors.Is(err, lancedb.ErrTableNotFound) {
+		return fmt.Errorf("failed to drop table for user %s: %w", userID, err)
+	}
+
+	// Reset index tracking and per-user configuration
+	s.mu.Lock()
+	delete(s.indexCreated, userID)
+	delete(s.indexMetrics, userID)
+	delete(s.indexConfigs, userID)
+	delete(s.userDims, userID)
+	delete(s.userStorages, userID)
+	delete(s.userModels, userID)
+	s.mu.Unlock()
+
+	s.logger.Printf("Dropped table for user %s", userID)
+	return nil
+}
+
 // CountDocuments returns the total number of document chunks for a user
 func (s *RAGStore) CountDocuments(ctx context.Context, userID string) (int64, error) {
 	exists, err := s.TableExists(ctx, userID)
@@ -261,7 +596,7 @@ func (s *RAGStore) CountDocuments(ctx context.Context, userID string) (int64, er
 	default:
 	}
 
-	table, err := s.conn.OpenTable(s.getTableName(userID))
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
 	if err != nil {
 		return 0, fmt.Errorf("failed to open table for user %s: %w", userID, err)
 	}
@@ -289,19 +624,20 @@ func (s *RAGStore) UpdateDocument(ctx context.Context, userID string, doc Docume
 	default:
 	}
 
-	// Validate embedding
-	if len(doc.Embedding) != s.embeddingDim {
+	// Validate embedding against the user's dimension
+	dim := s.userEmbeddingDim(userID)
+	if len(doc.Embedding) != dim {
 		return fmt.Errorf("embedding dimension mismatch: expected %d, got %d",
-			s.embeddingDim, len(doc.Embedding))
+			dim, len(doc.Embedding))
 	}
 
 	// Acquire per-user lock for write protection
-	lock := s.getUserLock(userID)
-	lock.Lock()
-	defer lock.Unlock()
+	unlock := s.lockUser(userID)
+	defer unlock()
+	defer s.invalidateTable(userID)
 
 	// Delete old document with this ID
-	table, err := s.conn.OpenTable(s.getTableName(userID))
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
 	if err != nil {
 		return fmt.Errorf("failed to open table for user %s: %w", userID, err)
 	}
@@ -313,22 +649,184 @@ func (s *RAGStore) UpdateDocument(ctx context.Context, userID string, doc Docume
 	}
 
 	// Insert new version
-	if err := s.addDocumentsBatch(table, []Document{doc}); err != nil {
+	if err := s.addDocumentsBatch(table, []Document{doc}, dim, s.userEmbeddingStorage(userID)); err != nil {
+		return fmt.Errorf("failed to insert updated document: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateDocumentMetadata replaces the metadata of a single document by ID, keeping its
+// text, document name and stored embedding. The row is replaced in one transaction, so if
+// the metadata can't be encoded or the write fails, the document is left unchanged. If the
+// document doesn't exist, returns an error. Use MergeDocumentMetadata to change only some keys.
+func (s *RAGStore) UpdateDocumentMetadata(ctx context.Context, userID, docID string, metadata map[string]interface{}) error {
+	return s.updateDocumentMetadata(ctx, userID, docID, metadata, false)
+}
+
+// MergeDocumentMetadata sets the given metadata keys on a single document by ID, keeping
+// its other metadata keys, text, document name and stored embedding. Otherwise it behaves
+// like UpdateDocumentMetadata.
+func (s *RAGStore) MergeDocumentMetadata(ctx context.Context, userID, docID string, metadata map[string]interface{}) error {
+	return s.updateDocumentMetadata(ctx, userID, docID, metadata, true)
+}
+
+// updateDocumentMetadata rewrites a document's metadata, merging metadata into the stored
+// keys if merge is set and replacing them otherwise
+func (s *RAGStore) updateDocumentMetadata(ctx context.Context, userID, docID string, metadata map[string]interface{}, merge bool) error {
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	// Check for context cancellation
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	// Acquire per-user lock for write protection
+	unlock := s.lockUser(userID)
+	defer unlock()
+	defer s.invalidateTable(userID)
+
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
+	if err != nil {
+		return fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	defer table.Close()
+
+	// Read the current row so it can be rewritten with the new metadata
+	predicate := fmt.Sprintf("id = '%s'", escapeSQLString(docID))
+	query := table.Query().Where(predicate).Limit(1)
+	defer query.Close()
+
+	records, err := query.Select(documentColumns(s.userEmbeddingStorage(userID))...).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to read document %s: %w", docID, err)
+	}
+	defer func() {
+		for _, record := range records {
+			record.Release()
+		}
+	}()
+
+	var current arrow.Record
+	for _, record := range records {
+		if record.NumRows() > 0 {
+			current = record.NewSlice(0, 1)
+			break
+		}
+	}
+	if current == nil {
+		return fmt.Errorf("document %s not found", docID)
+	}
+	defer current.Release()
+
+	codec := s.getMetadataCodec()
+	if merge {
+		stored, err := storedMetadata(current, codec)
+		if err != nil {
+			return fmt.Errorf("failed to read metadata of document %s: %w", docID, err)
+		}
+		for key, value := range metadata {
+			stored[key] = value
+		}
+		metadata = stored
+	}
+
+	// Encode the new metadata before touching the table, so a failure leaves the document as it was
+	meta, err := codec.Encode(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata for document %s: %w", docID, err)
+	}
+
+	// Keep the stored columns as they are, so embeddings aren't converted again
+	// (int8 tables keep their codes and embedding_scale), and swap in the metadata
+	updated, err := replaceMetadataColumn(current, meta)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite document %s: %w", docID, err)
+	}
+	defer updated.Release()
+
+	// Replace the row in one transaction, so a failed insert keeps the old row
+	txn, err := table.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer txn.Rollback()
+
+	if err := txn.Delete(predicate); err != nil {
+		return fmt.Errorf("failed to delete old document: %w", err)
+	}
+	if err := txn.Add(updated); err != nil {
 		return fmt.Errorf("failed to insert updated document: %w", err)
 	}
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit updated document: %w", err)
+	}
 
 	return nil
 }
 
+// storedMetadata decodes the metadata of the first row of record into a new, non-nil map
+func storedMetadata(record arrow.Record, codec MetadataCodec) (map[string]interface{}, error) {
+	column, ok := recordColumn(record, "metadata").(*array.String)
+	if !ok {
+		return nil, fmt.Errorf("record has no metadata column")
+	}
+
+	stored := make(map[string]interface{})
+	decoded, err := codec.Decode(column.Value(0))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range decoded {
+		stored[key] = value
+	}
+	return stored, nil
+}
+
+// replaceMetadataColumn returns a copy of record whose metadata column holds meta in
+// every row. The caller must release the returned record.
+func replaceMetadataColumn(record arrow.Record, meta string) (arrow.Record, error) {
+	indices := record.Schema().FieldIndices("metadata")
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("record has no metadata column")
+	}
+	if field := record.Schema().Field(indices[0]); !arrow.TypeEqual(field.Type, arrow.BinaryTypes.String) {
+		return nil, fmt.Errorf("metadata column has unexpected type %s", field.Type)
+	}
+
+	builder := array.NewStringBuilder(memory.NewGoAllocator())
+	defer builder.Release()
+	for i := int64(0); i < record.NumRows(); i++ {
+		builder.Append(meta)
+	}
+	metadataColumn := builder.NewArray()
+	defer metadataColumn.Release()
+
+	columns := make([]arrow.Array, record.NumCols())
+	copy(columns, record.Columns())
+	columns[indices[0]] = metadataColumn
+	return array.NewRecord(record.Schema(), columns, record.NumRows()), nil
+}
+
 // UpsertDocuments inserts or updates documents. If a document with the same ID exists, it's updated.
 // Otherwise, it's inserted. This is more efficient than calling UpdateDocument multiple times.
+// The documents are replaced in a single table version: readers see either the old or the
+// new documents, and an upsert that fails or is cancelled writes nothing.
 func (s *RAGStore) UpsertDocuments(ctx context.Context, userID string, docs []Document) error {
 	return s.UpsertDocumentsWithProgress(ctx, userID, docs, nil)
 }
 
 // UpsertDocumentsWithProgress upserts documents with progress reporting.
 // The callback receives progress updates during the operation.
-func (s *RAGStore) UpsertDocumentsWithProgress(ctx context.Context, userID string, docs []Document, callback ProgressCallback) error {
+func (s *RAGStore) UpsertDocumentsWithProgress(ctx context.Context, userID string, docs []Document, callback ProgressCallback) (err error) {
+	ctx, span := s.startSpan(ctx, "rag.UpsertDocuments", userID)
+	span.SetAttribute(SpanAttrDocumentCount, len(docs))
+	defer func() { endSpan(span, err) }()
+
 	if len(docs) == 0 {
 		return fmt.Errorf("no documents to upsert")
 	}
@@ -340,12 +838,10 @@ func (s *RAGStore) UpsertDocumentsWithProgress(ctx context.Context, userID strin
 	default:
 	}
 
-	// Validate embeddings
-	for i, doc := range docs {
-		if len(doc.Embedding) != s.embeddingDim {
-			return fmt.Errorf("document %d: embedding dimension mismatch: expected %d, got %d",
-				i, s.embeddingDim, len(doc.Embedding))
-		}
+	// Validate every document against the user's dimension before writing any
+	dim := s.userEmbeddingDim(userID)
+	if invalid := validateDocuments(docs, dim, s.getMetadataCodec()); len(invalid) > 0 {
+		return fmt.Errorf("%d invalid documents: %s", len(invalid), summarizeDocumentErrors(invalid))
 	}
 
 	// Initialize progress tracker
@@ -356,9 +852,14 @@ func (s *RAGStore) UpsertDocumentsWithProgress(ctx context.Context, userID strin
 	}
 
 	// Acquire per-user lock for write protection
-	lock := s.getUserLock(userID)
-	lock.Lock()
-	defer lock.Unlock()
+	unlock := s.lockUser(userID)
+	defer unlock()
+	defer s.invalidateTable(userID)
+
+	// Check the quota first, so a rejected write neither creates the table nor records the model in it
+	if err := s.checkQuota(ctx, userID, docs, true); err != nil {
+		return err
+	}
 
 	table, err := s.getOrCreateTable(userID)
 	if err != nil {
@@ -366,24 +867,23 @@ func (s *RAGStore) UpsertDocumentsWithProgress(ctx context.Context, userID strin
 	}
 	defer table.Close()
 
+	if err := s.recordEmbeddingModel(table, userID, s.GetEmbeddingModel(), dim); err != nil {
+		return err
+	}
+	storage := s.userEmbeddingStorage(userID)
+
+	// Replace the existing documents in one transaction, so readers never see the
+	// documents deleted but not yet re-inserted, and a failure leaves them untouched
+	txn, err := table.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer txn.Rollback()
+
 	// Delete all documents with IDs that match the incoming documents
-	// Batch delete by building a predicate with OR conditions
-	if len(docs) > 0 {
-		idPredicates := make([]string, 0, len(docs))
-		for _, doc := range docs {
-			if doc.ID != "" {
-				idPredicates = append(idPredicates, fmt.Sprintf("id = '%s'", escapeSQLString(doc.ID)))
-			}
-		}
-		
-		if len(idPredicates) > 0 {
-			predicate := idPredicates[0]
-			for i := 1; i < len(idPredicates); i++ {
-				predicate += " OR " + idPredicates[i]
-			}
-			
-			// Delete existing documents (ignore errors if documents don't exist)
-			_ = table.Delete(predicate)
+	for _, predicate := range idPredicates(documentIDs(docs)) {
+		if err := txn.Delete(predicate); err != nil {
+			return fmt.Errorf("failed to delete existing documents: %w", err)
 		}
 	}
 
@@ -391,7 +891,7 @@ func (s *RAGStore) UpsertDocumentsWithProgress(ctx context.Context, userID strin
 		tracker.SetStage("inserting")
 	}
 
-	// Insert all documents in batches
+	// Stage all documents in batches
 	for batchStart := 0; batchStart < len(docs); batchStart += s.maxBatchSize {
 		// Check for context cancellation between batches
 		select {
@@ -406,7 +906,7 @@ func (s *RAGStore) UpsertDocumentsWithProgress(ctx context.Context, userID strin
 		}
 		batch := docs[batchStart:batchEnd]
 
-		if err := s.addDocumentsBatch(table, batch); err != nil {
+		if err := s.stageDocumentsBatch(txn, batch, dim, storage); err != nil {
 			return fmt.Errorf("failed to upsert batch [%d:%d]: %w", batchStart, batchEnd, err)
 		}
 
@@ -416,11 +916,15 @@ func (s *RAGStore) UpsertDocumentsWithProgress(ctx context.Context, userID strin
 		}
 	}
 
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit upsert: %w", err)
+	}
+
 	// Ensure index exists
 	if tracker != nil {
 		tracker.SetStage("indexing")
 	}
-	if err := s.ensureIndex(table, userID); err != nil {
+	if err := s.ensureIndex(ctx, table, userID); err != nil {
 		return fmt.Errorf("failed to create index: %w", err)
 	}
 
@@ -2,10 +2,18 @@ package rag
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/aqua777/go-lancedb"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -44,3 +52,642 @@ func TestDocumentTestSuite(t *testing.T) {
 	suite.Run(t, new(DocumentTestSuite))
 }
 
+
+func (s *DocumentTestSuite) TestDropUserTable() {
+	userID := "drop_user"
+	s.Require().NoError(s.store.SetIndexConfig(userID, DefaultIndexConfig()))
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(300, 128, "doc.txt")))
+
+	exists, err := s.store.TableExists(s.ctx, userID)
+	s.Require().NoError(err)
+	s.True(exists)
+
+	s.Require().NoError(s.store.DropUserTable(s.ctx, userID))
+
+	exists, err = s.store.TableExists(s.ctx, userID)
+	s.Require().NoError(err)
+	s.False(exists)
+
+	// Internal per-user state is gone
+	s.store.mu.RLock()
+	_, hasIndex := s.store.indexCreated[userID]
+	_, hasConfig := s.store.indexConfigs[userID]
+	_, hasDim := s.store.userDims[userID]
+	s.store.mu.RUnlock()
+	s.False(hasIndex)
+	s.False(hasConfig)
+	s.False(hasDim)
+
+	s.store.locksMu.Lock()
+	_, hasLock := s.store.userLocks[userID]
+	s.store.locksMu.Unlock()
+	s.False(hasLock)
+
+	// Dropping again is a no-op
+	s.NoError(s.store.DropUserTable(s.ctx, userID))
+
+	// The user can be recreated from scratch
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(300, 128, "doc.txt")))
+	count, err := s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(300), count)
+}
+
+func (s *DocumentTestSuite) TestDropUserTableConcurrentWrites() {
+	userID := "drop_race_user"
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- s.store.AddDocuments(s.ctx, userID, makeTestDocs(5, 128, "doc.txt"))
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- s.store.DropUserTable(s.ctx, userID)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		s.NoError(err)
+	}
+
+	// A drop after the writes always removes the table
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(5, 128, "doc.txt")))
+	s.Require().NoError(s.store.DropUserTable(s.ctx, userID))
+	exists, err := s.store.TableExists(s.ctx, userID)
+	s.Require().NoError(err)
+	s.False(exists)
+
+	// Every lock entry was removed by its last user
+	s.store.locksMu.Lock()
+	s.Empty(s.store.userLocks)
+	s.store.locksMu.Unlock()
+}
+
+func (s *DocumentTestSuite) TestDropUserTableInvalidUser() {
+	s.Error(s.store.DropUserTable(s.ctx, "bad user!"))
+}
+
+func (s *DocumentTestSuite) TestAddDocumentsWithEmbeddingVerifiesDimensions() {
+	// The provider claims 128 dimensions but the model returns 64
+	server := newOpenAITestServer(64, nil)
+	defer server.Close()
+
+	provider := NewOpenAIEmbeddingProvider("key", "text-embedding-ada-002", 128)
+	provider.BaseURL = server.URL
+
+	err := s.store.AddDocumentsWithEmbedding(s.ctx, "embed_user", []string{"hello"}, []string{"doc.txt"}, provider)
+	s.Error(err)
+	s.Contains(err.Error(), "dimension verification failed")
+
+	exists, err := s.store.TableExists(s.ctx, "embed_user")
+	s.Require().NoError(err)
+	s.False(exists)
+}
+
+func (s *DocumentTestSuite) TestProviderDimensionsVerifiedOnce() {
+	provider := &concurrencyTrackingProvider{dim: 128}
+
+	// The first ingest probes the provider, then embeds the texts in one batch
+	s.Require().NoError(s.store.AddDocumentsWithEmbedding(s.ctx, "probe_user", []string{"a", "b"}, []string{"doc.txt", "doc.txt"}, provider))
+	s.Equal(int32(2), atomic.LoadInt32(&provider.calls))
+
+	// Later ingests with the same provider skip the probe, for any user
+	s.Require().NoError(s.store.AddDocumentsWithEmbedding(s.ctx, "probe_user", []string{"c"}, []string{"more.txt"}, provider))
+	s.Require().NoError(s.store.AddDocumentsWithEmbedding(s.ctx, "other_user", []string{"d"}, []string{"doc.txt"}, provider))
+	s.Equal(int32(4), atomic.LoadInt32(&provider.calls))
+}
+
+func (s *DocumentTestSuite) TestDocumentIDsAreDeterministic() {
+	s.Equal("doc.txt_3", DocumentID("doc.txt", 3))
+	s.Equal(DocumentID("doc.txt", 3), DocumentID("doc.txt", 3))
+	s.NotEqual(DocumentID("doc.txt", 3), DocumentID("doc.txt", 4))
+	s.NotEqual(DocumentID("a.txt", 0), DocumentID("b.txt", 0))
+
+	s.Equal(ContentDocumentID("doc.txt", "hello"), ContentDocumentID("doc.txt", "hello"))
+	s.NotEqual(ContentDocumentID("doc.txt", "hello"), ContentDocumentID("doc.txt", "world"))
+	s.NotEqual(ContentDocumentID("a.txt", "hello"), ContentDocumentID("b.txt", "hello"))
+}
+
+func (s *DocumentTestSuite) TestAddDocumentsWithEmbeddingStableIDs() {
+	server := newOpenAITestServer(128, nil)
+	defer server.Close()
+
+	provider := NewOpenAIEmbeddingProvider("key", "text-embedding-ada-002", 128)
+	provider.BaseURL = server.URL
+
+	// IDs count each document's texts, not positions in the whole call
+	texts := []string{"first a", "only b", "second a"}
+	names := []string{"a.txt", "b.txt", "a.txt"}
+	s.Require().NoError(s.store.AddDocumentsWithEmbedding(s.ctx, "ids_full", texts, names, provider))
+	s.ElementsMatch([]string{"a.txt_0", "b.txt_0", "a.txt_1"}, s.storedIDs("ids_full"))
+
+	// Ingesting a subset gives the same IDs as the full run
+	s.Require().NoError(s.store.AddDocumentsWithEmbedding(s.ctx, "ids_subset", texts[1:2], names[1:2], provider))
+	s.Equal([]string{"b.txt_0"}, s.storedIDs("ids_subset"))
+}
+
+func (s *DocumentTestSuite) TestAddDocumentsWithEmbeddingConcurrently() {
+	provider := &concurrencyTrackingProvider{dim: 128, delay: 10 * time.Millisecond}
+	texts := distinctTexts(20)
+	names := make([]string, len(texts))
+	for i := range names {
+		names[i] = "parallel.txt"
+	}
+
+	err := s.store.AddDocumentsWithEmbeddingConfig(s.ctx, "parallel_user", texts, names, provider, &EmbeddingIngestConfig{
+		BatchSize:   3,
+		Concurrency: 4,
+	})
+	s.Require().NoError(err)
+	s.LessOrEqual(atomic.LoadInt32(&provider.maxCalls), int32(4))
+
+	// IDs follow the order of texts, whichever batch finished first
+	results, err := s.store.Search(s.ctx, "parallel_user", deterministicEmbedding("query", 128), &SearchOptions{Limit: 100})
+	s.Require().NoError(err)
+	s.Require().Len(results, len(texts))
+	for _, result := range results {
+		var i int
+		_, err := fmt.Sscanf(result.ID, "parallel.txt_%d", &i)
+		s.Require().NoError(err)
+		s.Equal(texts[i], result.Text)
+	}
+
+	// A failed batch adds nothing
+	failing := &concurrencyTrackingProvider{dim: 128, failOn: texts[7]}
+	err = s.store.AddDocumentsWithEmbeddingConfig(s.ctx, "failed_user", texts, names, failing, &EmbeddingIngestConfig{Concurrency: 2, BatchSize: 2})
+	s.Error(err)
+	exists, err := s.store.TableExists(s.ctx, "failed_user")
+	s.Require().NoError(err)
+	s.False(exists)
+}
+
+func (s *DocumentTestSuite) TestAddDocumentsWithEmbeddingProgressStages() {
+	provider := &concurrencyTrackingProvider{dim: 128}
+	texts := distinctTexts(250) // Several embedding batches
+	names := make([]string, len(texts))
+	for i := range names {
+		names[i] = "progress.txt"
+	}
+
+	var updates []Progress
+	err := s.store.AddDocumentsWithEmbeddingProgress(s.ctx, "progress_user", texts, names, provider, func(p *Progress) {
+		updates = append(updates, *p)
+	})
+	s.Require().NoError(err)
+	s.Require().NotEmpty(updates)
+
+	// Stages run in order and Current never goes back
+	order := map[string]int{"embedding": 0, "inserting": 1, "indexing": 2}
+	stages := map[string]bool{}
+	for i, update := range updates {
+		stage, ok := order[update.Stage]
+		s.Require().True(ok, "unexpected stage %q", update.Stage)
+		stages[update.Stage] = true
+		s.EqualValues(2*len(texts), update.Total)
+		if i > 0 {
+			s.GreaterOrEqual(update.Current, updates[i-1].Current, "update %d", i)
+			s.GreaterOrEqual(stage, order[updates[i-1].Stage], "update %d", i)
+		}
+	}
+	s.Len(stages, 3)
+
+	last := updates[len(updates)-1]
+	s.EqualValues(2*len(texts), last.Current)
+	s.True(last.IsComplete())
+	s.Equal(updates[0].StartTime, last.StartTime)
+}
+
+// writeJSONL writes lines to a file in the suite's temporary directory
+func (s *DocumentTestSuite) writeJSONL(name string, lines ...string) string {
+	path := filepath.Join(filepath.Dir(s.dbPath), name)
+	s.Require().NoError(os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644))
+	return path
+}
+
+func (s *DocumentTestSuite) TestAddDocumentsFromJSONL() {
+	path := s.writeJSONL("docs.jsonl",
+		`{"text": "Go has goroutines", "document_name": "go.md", "metadata": {"topic": "go"}}`,
+		`{"text": "Rust has ownership and borrowing", "document_name": "rust.md"}`,
+		``,
+		`{"text": "Channels connect goroutines together", "document_name": "go.md"}`,
+		`{"text": "LanceDB stores vectors", "document_name": "lance.md", "metadata": {"topic": "db", "stars": 5}}`,
+	)
+	provider := &concurrencyTrackingProvider{dim: 128}
+
+	added, err := s.store.AddDocumentsFromJSONLWithConfig(s.ctx, "jsonl_user", path, provider, &JSONLIngestConfig{BatchSize: 3})
+	s.Require().NoError(err)
+	s.Equal(4, added)
+	s.EqualValues(2, atomic.LoadInt32(&provider.calls), "lines are embedded in batches")
+
+	count, err := s.store.CountDocuments(s.ctx, "jsonl_user")
+	s.Require().NoError(err)
+	s.EqualValues(4, count)
+	s.ElementsMatch([]string{"go.md_0", "rust.md_0", "go.md_1", "lance.md_0"}, s.storedIDs("jsonl_user"))
+
+	results, err := s.store.SearchWithText(s.ctx, "jsonl_user", "LanceDB stores vectors", provider,
+		&SearchOptions{Limit: 1, DistanceType: lancedb.DistanceTypeL2})
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.Equal("lance.md_0", results[0].ID)
+	s.Equal("db", results[0].Metadata["topic"])
+	s.EqualValues(5, results[0].Metadata["stars"])
+}
+
+func (s *DocumentTestSuite) TestAddDocumentsFromJSONLContinuesChunkNumbering() {
+	first := s.writeJSONL("first.jsonl",
+		`{"text": "Go has goroutines", "document_name": "go.md"}`,
+		`{"text": "Channels connect goroutines", "document_name": "go.md"}`,
+	)
+	second := s.writeJSONL("second.jsonl",
+		`{"text": "Select waits on channels", "document_name": "go.md"}`,
+		`{"text": "A brand new document", "document_name": "new.md"}`,
+	)
+	provider := &concurrencyTrackingProvider{dim: 128}
+
+	added, err := s.store.AddDocumentsFromJSONL(s.ctx, "reingest_user", first, provider)
+	s.Require().NoError(err)
+	s.Equal(2, added)
+
+	// Chunks of an existing document continue after its stored IDs
+	added, err = s.store.AddDocumentsFromJSONL(s.ctx, "reingest_user", second, provider)
+	s.Require().NoError(err)
+	s.Equal(2, added)
+
+	// Ingesting a file again adds its chunks under new IDs instead of reusing the old ones
+	added, err = s.store.AddDocumentsFromJSONL(s.ctx, "reingest_user", first, provider)
+	s.Require().NoError(err)
+	s.Equal(2, added)
+
+	s.ElementsMatch([]string{"go.md_0", "go.md_1", "go.md_2", "new.md_0", "go.md_3", "go.md_4"}, s.storedIDs("reingest_user"))
+}
+
+func (s *DocumentTestSuite) TestAddDocumentsFromJSONLConcurrentIngestions() {
+	paths := make([]string, 4)
+	for i := range paths {
+		paths[i] = s.writeJSONL(fmt.Sprintf("part%d.jsonl", i),
+			fmt.Sprintf(`{"text": "part %d chunk a", "document_name": "shared.md"}`, i),
+			fmt.Sprintf(`{"text": "part %d chunk b", "document_name": "shared.md"}`, i),
+		)
+	}
+	provider := &concurrencyTrackingProvider{dim: 128}
+
+	// Ingestions of the same document name never hand out the same IDs
+	var wg sync.WaitGroup
+	errs := make([]error, len(paths))
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			_, errs[i] = s.store.AddDocumentsFromJSONL(s.ctx, "concurrent_jsonl_user", path, provider)
+		}(i, path)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		s.Require().NoError(err)
+	}
+
+	expected := make([]string, 2*len(paths))
+	for i := range expected {
+		expected[i] = DocumentID("shared.md", i)
+	}
+	s.ElementsMatch(expected, s.storedIDs("concurrent_jsonl_user"))
+}
+
+func (s *DocumentTestSuite) TestAddDocumentsFromJSONLMalformedLines() {
+	path := s.writeJSONL("mixed.jsonl",
+		`{"text": "first", "document_name": "a.md"}`,
+		`{"text": "second", "document_name": "a.md"}`,
+		`{"text": "broken", "document_name":`,
+		`{"text": "third", "document_name": "a.md"}`,
+		`{"document_name": "a.md"}`,
+		`["not", "an", "object"]`,
+		`{"text": "fourth", "document_name": "a.md"}`,
+	)
+	provider := &concurrencyTrackingProvider{dim: 128}
+
+	// By default the first malformed line fails the ingestion before anything is added
+	added, err := s.store.AddDocumentsFromJSONL(s.ctx, "strict_user", path, provider)
+	var lineErr *MalformedLineError
+	s.Require().ErrorAs(err, &lineErr)
+	s.Equal(3, lineErr.Line)
+	s.Contains(err.Error(), "mixed.jsonl:3")
+	s.Zero(added)
+	exists, err := s.store.TableExists(s.ctx, "strict_user")
+	s.Require().NoError(err)
+	s.False(exists, "the lines before the malformed one are not added")
+
+	// Skipping reports each malformed line and adds the rest
+	var skipped []int
+	added, err = s.store.AddDocumentsFromJSONLWithConfig(s.ctx, "lenient_user", path, provider, &JSONLIngestConfig{
+		SkipMalformed: true,
+		OnMalformed:   func(lineErr *MalformedLineError) { skipped = append(skipped, lineErr.Line) },
+	})
+	s.Require().NoError(err)
+	s.Equal(4, added)
+	s.Equal([]int{3, 5, 6}, skipped)
+	s.ElementsMatch([]string{"a.md_0", "a.md_1", "a.md_2", "a.md_3"}, s.storedIDs("lenient_user"))
+
+	_, err = s.store.AddDocumentsFromJSONL(s.ctx, "missing_user", filepath.Join(filepath.Dir(s.dbPath), "missing.jsonl"), provider)
+	s.ErrorIs(err, os.ErrNotExist)
+}
+
+// storedIDs returns the IDs of every document stored for the user
+func (s *DocumentTestSuite) storedIDs(userID string) []string {
+	results, err := s.store.Search(s.ctx, userID, deterministicEmbedding("query", 128), &SearchOptions{Limit: 100})
+	s.Require().NoError(err)
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return ids
+}
+
+func (s *DocumentTestSuite) TestDeleteByDocumentNames() {
+	userID := "bulk_delete_user"
+	names := []string{"a.txt", "b.txt", "it's.txt", "d.txt", "e.txt"}
+	for _, name := range names {
+		s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(2, 128, name)))
+	}
+
+	s.Require().NoError(s.store.DeleteByDocumentNames(s.ctx, userID, []string{"a.txt", "it's.txt", "e.txt"}))
+
+	count, err := s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(4), count)
+
+	remaining, err := s.store.ListDocumentNames(s.ctx, userID)
+	s.Require().NoError(err)
+	s.ElementsMatch([]string{"b.txt", "d.txt"}, remaining)
+
+	s.Error(s.store.DeleteByDocumentNames(s.ctx, userID, nil))
+	s.Error(s.store.DeleteByDocumentNames(s.ctx, userID, []string{"b.txt", ""}))
+}
+
+func (s *DocumentTestSuite) TestUpdateDocumentMetadata() {
+	userID := "metadata_user"
+	docs := makeTestDocs(5, 128, "doc.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	target := docs[2]
+	s.Require().NoError(s.store.UpdateDocumentMetadata(s.ctx, userID, target.ID, map[string]interface{}{"status": "reviewed"}))
+
+	results, err := s.store.Search(s.ctx, userID, target.Embedding, &SearchOptions{Limit: 10})
+	s.Require().NoError(err)
+	s.Len(results, 5)
+
+	var updated *SearchResult
+	for i := range results {
+		if results[i].ID == target.ID {
+			updated = &results[i]
+		} else {
+			s.NotContains(results[i].Metadata, "status")
+		}
+	}
+	s.Require().NotNil(updated)
+	s.Equal(map[string]interface{}{"status": "reviewed"}, updated.Metadata)
+	s.Equal(target.Text, updated.Text)
+	s.Equal(target.DocumentName, updated.DocumentName)
+	s.Equal(target.Embedding, updated.Embedding)
+
+	count, err := s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(5), count)
+
+	s.Error(s.store.UpdateDocumentMetadata(s.ctx, userID, "missing", map[string]interface{}{"status": "x"}))
+	s.Error(s.store.UpdateDocumentMetadata(s.ctx, userID, "", nil))
+}
+
+func (s *DocumentTestSuite) TestMergeDocumentMetadata() {
+	userID := "metadata_merge_user"
+	docs := makeTestDocs(2, 128, "doc.txt")
+	docs[0].Metadata = map[string]interface{}{"status": "draft", "author": "ann"}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	s.Require().NoError(s.store.MergeDocumentMetadata(s.ctx, userID, docs[0].ID, map[string]interface{}{"status": "reviewed"}))
+	// A document stored without metadata gains the merged keys
+	s.Require().NoError(s.store.MergeDocumentMetadata(s.ctx, userID, docs[1].ID, map[string]interface{}{"status": "new"}))
+
+	results, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(results, 2)
+	for _, result := range results {
+		switch result.ID {
+		case docs[0].ID:
+			s.Equal(map[string]interface{}{"status": "reviewed", "author": "ann"}, result.Metadata)
+			s.Equal(docs[0].Embedding, result.Embedding)
+		case docs[1].ID:
+			s.Equal(map[string]interface{}{"status": "new"}, result.Metadata)
+		}
+	}
+
+	s.Error(s.store.MergeDocumentMetadata(s.ctx, userID, "missing", map[string]interface{}{"status": "x"}))
+}
+
+func (s *DocumentTestSuite) TestUpdateDocumentMetadataKeepsDocumentOnFailure() {
+	userID := "metadata_fail_user"
+	docs := makeTestDocs(3, 128, "doc.txt")
+	docs[1].Metadata = map[string]interface{}{"status": "draft"}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	// Channels can't be encoded as JSON
+	err := s.store.UpdateDocumentMetadata(s.ctx, userID, docs[1].ID, map[string]interface{}{"bad": make(chan int)})
+	s.Require().Error(err)
+
+	results, err := s.store.Search(s.ctx, userID, docs[1].Embedding, &SearchOptions{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(results, 3)
+	found := false
+	for _, result := range results {
+		if result.ID == docs[1].ID {
+			found = true
+			s.Equal(map[string]interface{}{"status": "draft"}, result.Metadata)
+			s.Equal(docs[1].Text, result.Text)
+		}
+	}
+	s.True(found, "the document must survive a failed update")
+}
+
+func (s *DocumentTestSuite) TestUpdateDocumentMetadataInt8() {
+	userID := "metadata_int8_user"
+	docs := makeTestDocs(3, 128, "doc.txt")
+	s.Require().NoError(s.store.AddDocumentsQuantized(s.ctx, userID, docs, EmbeddingStorageInt8))
+
+	stored := func() SearchResult {
+		results, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 10})
+		s.Require().NoError(err)
+		for _, result := range results {
+			if result.ID == docs[0].ID {
+				return result
+			}
+		}
+		s.FailNow("document not found")
+		return SearchResult{}
+	}
+	before := stored()
+
+	// The stored codes and scale are kept, not quantized a second time
+	s.Require().NoError(s.store.UpdateDocumentMetadata(s.ctx, userID, docs[0].ID, map[string]interface{}{"status": "reviewed"}))
+	after := stored()
+	s.Equal(before.Embedding, after.Embedding)
+	s.Equal(map[string]interface{}{"status": "reviewed"}, after.Metadata)
+}
+
+func (s *DocumentTestSuite) TestDocumentQuota() {
+	userID := "quota_user"
+	s.Require().NoError(s.store.SetMaxDocumentsPerUser(300))
+	s.Equal(300, s.store.GetMaxDocumentsPerUser())
+
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(250, 128, "first.txt")))
+
+	err := s.store.AddDocuments(s.ctx, userID, makeTestDocs(100, 128, "second.txt"))
+	s.Require().Error(err)
+	s.True(errors.Is(err, ErrQuotaExceeded), "unexpected error: %v", err)
+
+	var quotaErr *QuotaExceededError
+	s.Require().True(errors.As(err, &quotaErr))
+	s.Equal(int64(250), quotaErr.Current)
+	s.Equal(int64(100), quotaErr.Adding)
+	s.Equal(int64(300), quotaErr.Limit)
+
+	// Nothing from the rejected insert was written
+	count, err := s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(250), count)
+
+	// Upserted documents that already exist replace rows instead of adding them
+	upsert := append(makeTestDocs(50, 128, "first.txt"), makeTestDocs(50, 128, "third.txt")...)
+	s.Require().NoError(s.store.UpsertDocuments(s.ctx, userID, upsert))
+	count, err = s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(300), count)
+
+	err = s.store.UpsertDocuments(s.ctx, userID, makeTestDocs(1, 128, "fourth.txt"))
+	s.True(errors.Is(err, ErrQuotaExceeded), "unexpected error: %v", err)
+
+	// Zero removes the limit
+	s.Require().NoError(s.store.SetMaxDocumentsPerUser(0))
+	s.NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(100, 128, "second.txt")))
+	s.Error(s.store.SetMaxDocumentsPerUser(-1))
+}
+
+func (s *DocumentTestSuite) TestDocumentQuotaFirstWriteCreatesNoTable() {
+	s.Require().NoError(s.store.SetMaxDocumentsPerUser(10))
+
+	err := s.store.AddDocuments(s.ctx, "quota_new_user", makeTestDocs(11, 128, "doc.txt"))
+	s.True(errors.Is(err, ErrQuotaExceeded), "unexpected error: %v", err)
+
+	exists, err := s.store.TableExists(s.ctx, "quota_new_user")
+	s.Require().NoError(err)
+	s.False(exists)
+}
+
+func (s *DocumentTestSuite) TestDocumentQuotaLargeUpsert() {
+	userID := "quota_large_user"
+	s.Require().NoError(s.store.SetMaxDocumentsPerUser(1200))
+
+	// More IDs than fit one IN predicate, all already stored
+	docs := makeTestDocs(1200, 128, "doc.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+	s.Require().NoError(s.store.UpsertDocuments(s.ctx, userID, docs))
+
+	count, err := s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(1200), count)
+}
+
+func (s *DocumentTestSuite) TestValidateDocuments() {
+	docs := makeTestDocs(7, 128, "doc.txt")
+	docs[0].ID = "" // IDs are optional
+	docs[1].Embedding[3] = float32(math.Inf(1))
+	docs[2].Embedding = make([]float32, 64)
+	docs[4].Embedding[0] = float32(math.NaN())
+	docs[5].Metadata = map[string]interface{}{"callback": func() {}}
+	docs[6].Text = ""
+
+	invalid := s.store.ValidateDocuments(docs)
+	s.Require().Len(invalid, 5)
+
+	indexes := make([]int, len(invalid))
+	for i, docErr := range invalid {
+		indexes[i] = docErr.Index
+		s.NotEmpty(docErr.Reason)
+	}
+	s.Equal([]int{1, 2, 4, 5, 6}, indexes)
+	s.Equal(docs[1].ID, invalid[0].ID)
+	s.Contains(invalid[0].Reason, "Inf")
+	s.Equal(docs[2].ID, invalid[1].ID)
+	s.Contains(invalid[1].Reason, "dimension")
+	s.Contains(invalid[2].Reason, "NaN")
+	s.Contains(invalid[3].Reason, "metadata")
+	s.Equal("missing text", invalid[4].Reason)
+
+	s.Nil(s.store.ValidateDocuments(makeTestDocs(3, 128, "ok.txt")))
+
+	// AddDocuments reports every invalid document and writes nothing
+	err := s.store.AddDocuments(s.ctx, "validate_user", docs)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "5 invalid documents")
+
+	exists, err := s.store.TableExists(s.ctx, "validate_user")
+	s.Require().NoError(err)
+	s.False(exists)
+}
+
+// poisonCodec encodes metadata as JSON, failing the second time it sees a "poison" key,
+// so documents pass validation and fail when their batch is written
+type poisonCodec struct {
+	JSONMetadataCodec
+	seen atomic.Int32
+}
+
+func (c *poisonCodec) Encode(metadata map[string]interface{}) (string, error) {
+	if _, ok := metadata["poison"]; ok && c.seen.Add(1) > 1 {
+		return "", errors.New("poisoned metadata")
+	}
+	return c.JSONMetadataCodec.Encode(metadata)
+}
+
+func (s *DocumentTestSuite) TestAddDocumentsReportsPartialWrite() {
+	userID := "partial_user"
+	s.store.SetMetadataCodec(&poisonCodec{})
+
+	// The store writes batches of 100, so the third batch fails
+	docs := makeTestDocs(500, 128, "doc.txt")
+	docs[250].Metadata = map[string]interface{}{"poison": true}
+
+	err := s.store.AddDocuments(s.ctx, userID, docs)
+	s.Require().Error(err)
+
+	var partial *PartialWriteError
+	s.Require().True(errors.As(err, &partial), "unexpected error: %v", err)
+	s.Equal(200, partial.Committed)
+	s.Equal(500, partial.Total)
+	s.Contains(err.Error(), "poisoned metadata")
+
+	count, err := s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(partial.Committed), count)
+
+	// Resuming from the committed offset stores every document exactly once
+	docs[250].Metadata = map[string]interface{}{"index": 250}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs[partial.Committed:]))
+	count, err = s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(500), count)
+}
+
+func (s *DocumentTestSuite) TestMissingTableErrorsMatchSentinel() {
+	err := s.store.DeleteByDocumentName(s.ctx, "nobody", "doc.txt")
+	s.Require().Error(err)
+	s.True(errors.Is(err, lancedb.ErrTableNotFound), "unexpected error: %v", err)
+
+	embedding := make([]float32, 128)
+	err = s.store.UpdateDocument(s.ctx, "nobody", Document{ID: "doc1", Text: "text", DocumentName: "doc.txt", Embedding: embedding})
+	s.Require().Error(err)
+	s.True(errors.Is(err, lancedb.ErrTableNotFound), "unexpected error: %v", err)
+}
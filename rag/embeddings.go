@@ -3,11 +3,24 @@ package rag
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
-
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 )
 
@@ -23,13 +36,48 @@ type EmbeddingProvider interface {
 	Dimensions() int
 }
 
-// OpenAIEmbeddingProvider generates embeddings using OpenAI's API
+// DimensionVerifier is implemented by embedding providers with their own way of checking
+// their declared dimensionality against what the backing model actually returns. The store
+// checks other providers with VerifyEmbeddingDimensions.
+type DimensionVerifier interface {
+	// VerifyDimensions returns an error if the model's embeddings don't have
+	// Dimensions() values
+	VerifyDimensions(ctx context.Context) error
+}
+
+// dimensionProbeText is embedded to detect a provider's actual output dimension
+const dimensionProbeText = "dimension probe"
+
+// VerifyEmbeddingDimensions generates an embedding for a probe string and checks that its length
+// matches provider.Dimensions(). Works with any EmbeddingProvider.
+func VerifyEmbeddingDimensions(ctx context.Context, provider EmbeddingProvider) error {
+	embedding, err := provider.GenerateEmbedding(ctx, dimensionProbeText)
+	if err != nil {
+		return fmt.Errorf("failed to generate probe embedding: %w", err)
+	}
+	if len(embedding) != provider.Dimensions() {
+		return fmt.Errorf("embedding dimension mismatch: provider declares %d dimensions but model returned %d",
+			provider.Dimensions(), len(embedding))
+	}
+	return nil
+}
+
+// OpenAI embedding response encodings
+const (
+	OpenAIEncodingFloat  = "float"  // JSON arrays of floats (default)
+	OpenAIEncodingBase64 = "base64" // base64-encoded little-endian float32 arrays (~2x smaller payloads)
+)
+
+// OpenAIEmbeddingProvider generates embeddings using OpenAI's API.
+// For text-embedding-3-* models the declared dimensions are requested from the API,
+// so shortened vectors (e.g., 256 dimensions) can be used.
 type OpenAIEmbeddingProvider struct {
-	APIKey     string
-	Model      string // e.g., "text-embedding-ada-002", "text-embedding-3-small"
-	BaseURL    string
-	dimensions int
-	httpClient *http.Client
+	APIKey         string
+	Model          string // e.g., "text-embedding-ada-002", "text-embedding-3-small"
+	BaseURL        string
+	EncodingFormat string // OpenAIEncodingFloat (default) or OpenAIEncodingBase64
+	dimensions     int
+	httpClient     *http.Client
 }
 
 // NewOpenAIEmbeddingProvider creates a new OpenAI embedding provider
@@ -48,6 +96,11 @@ func (p *OpenAIEmbeddingProvider) Dimensions() int {
 	return p.dimensions
 }
 
+// EmbeddingModel returns the OpenAI model name
+func (p *OpenAIEmbeddingProvider) EmbeddingModel() string {
+	return p.Model
+}
+
 // GenerateEmbedding generates a single embedding
 func (p *OpenAIEmbeddingProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	embeddings, err := p.GenerateEmbeddings(ctx, []string{text})
@@ -71,6 +124,14 @@ func (p *OpenAIEmbeddingProvider) GenerateEmbeddings(ctx context.Context, texts
 		"model": p.Model,
 	}
 
+	// text-embedding-3-* models can return shortened vectors of the requested size
+	if supportsDimensionsParam(p.Model) && p.dimensions > 0 {
+		requestBody["dimensions"] = p.dimensions
+	}
+	if p.EncodingFormat != "" {
+		requestBody["encoding_format"] = p.EncodingFormat
+	}
+
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -97,8 +158,8 @@ func (p *OpenAIEmbeddingProvider) GenerateEmbeddings(ctx context.Context, texts
 
 	var response struct {
 		Data []struct {
-			Embedding []float32 `json:"embedding"`
-			Index     int       `json:"index"`
+			Embedding json.RawMessage `json:"embedding"`
+			Index     int             `json:"index"`
 		} `json:"data"`
 	}
 
@@ -112,15 +173,52 @@ func (p *OpenAIEmbeddingProvider) GenerateEmbeddings(ctx context.Context, texts
 
 	embeddings := make([][]float32, len(texts))
 	for _, item := range response.Data {
-		if item.Index >= len(embeddings) {
+		if item.Index < 0 || item.Index >= len(embeddings) {
 			return nil, fmt.Errorf("invalid embedding index: %d", item.Index)
 		}
-		embeddings[item.Index] = item.Embedding
+		embedding, err := decodeOpenAIEmbedding(item.Embedding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode embedding %d: %w", item.Index, err)
+		}
+		embeddings[item.Index] = embedding
 	}
 
 	return embeddings, nil
 }
 
+// decodeOpenAIEmbedding decodes an embedding returned either as a JSON float array
+// or as a base64 string of little-endian float32 values
+func decodeOpenAIEmbedding(raw json.RawMessage) ([]float32, error) {
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		// Not a string: plain float array
+		var embedding []float32
+		if err := json.Unmarshal(raw, &embedding); err != nil {
+			return nil, err
+		}
+		return embedding, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 embedding: %w", err)
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid base64 embedding: %d bytes is not a multiple of 4", len(data))
+	}
+
+	embedding := make([]float32, len(data)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return embedding, nil
+}
+
+// supportsDimensionsParam reports whether an OpenAI model accepts the "dimensions" request parameter
+func supportsDimensionsParam(model string) bool {
+	return strings.HasPrefix(model, "text-embedding-3-")
+}
+
 // HTTPEmbeddingProvider calls a custom HTTP endpoint for embeddings
 // Useful for local models served via HTTP (e.g., sentence-transformers, FastEmbed)
 type HTTPEmbeddingProvider struct {
@@ -203,14 +301,182 @@ func (p *HTTPEmbeddingProvider) GenerateEmbeddings(ctx context.Context, texts []
 	return response.Embeddings, nil
 }
 
-// AddDocumentsWithEmbedding adds documents to the store, generating embeddings automatically
+// OllamaEmbeddingProvider generates embeddings using a local Ollama server's /api/embeddings endpoint.
+// Ollama embeds one prompt per request, so batches are processed by a bounded pool of workers.
+type OllamaEmbeddingProvider struct {
+	BaseURL     string // e.g., "http://localhost:11434"
+	Model       string // e.g., "nomic-embed-text"
+	Concurrency int    // maximum concurrent requests for batch operations (default: 4)
+	dimensions  int
+	httpClient  *http.Client
+}
+
+// NewOllamaEmbeddingProvider creates a provider for a local Ollama server
+func NewOllamaEmbeddingProvider(baseURL, model string, dimensions int) *OllamaEmbeddingProvider {
+	return &OllamaEmbeddingProvider{
+		BaseURL:     baseURL,
+		Model:       model,
+		Concurrency: 4,
+		dimensions:  dimensions,
+		httpClient:  &http.Client{},
+	}
+}
+
+// Dimensions returns the embedding dimensionality
+func (p *OllamaEmbeddingProvider) Dimensions() int {
+	return p.dimensions
+}
+
+// EmbeddingModel returns the Ollama model name
+func (p *OllamaEmbeddingProvider) EmbeddingModel() string {
+	return p.Model
+}
+
+// GenerateEmbedding generates a single embedding
+func (p *OllamaEmbeddingProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	requestBody := map[string]interface{}{
+		"model":  p.Model,
+		"prompt": text,
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.BaseURL+"/api/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Embedding []float32 `json:"embedding"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(response.Embedding) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+
+	return response.Embedding, nil
+}
+
+// GenerateEmbeddings generates multiple embeddings.
+// Since Ollama has no native batching, texts are embedded concurrently with at most Concurrency requests in flight.
+func (p *OllamaEmbeddingProvider) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	workers := p.Concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > len(texts) {
+		workers = len(texts)
+	}
+
+	// Cancel outstanding requests on the first failure
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	embeddings := make([][]float32, len(texts))
+	jobs := make(chan int)
+	errOnce := sync.Once{}
+	var firstErr error
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				embedding, err := p.GenerateEmbedding(ctx, texts[idx])
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("failed to embed text %d: %w", idx, err)
+						cancel()
+					})
+					continue
+				}
+				embeddings[idx] = embedding
+			}
+		}()
+	}
+
+	// Feed work, stopping early on cancellation
+feed:
+	for i := range texts {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return embeddings, nil
+}
+
+// AddDocumentsWithEmbedding adds documents to the store, generating embeddings automatically.
+// Each text's ID is DocumentID(documentName, n), where n counts that document's texts from 0,
+// so ingesting the same texts again produces the same IDs.
 func (s *RAGStore) AddDocumentsWithEmbedding(ctx context.Context, userID string, texts []string, documentNames []string, provider EmbeddingProvider) error {
 	return s.AddDocumentsWithEmbeddingProgress(ctx, userID, texts, documentNames, provider, nil)
 }
 
 // AddDocumentsWithEmbeddingProgress adds documents with automatic embedding generation and progress reporting.
-// The callback receives progress updates for both embedding generation and document insertion.
+// The callback receives progress updates for both embedding generation and document insertion,
+// with Stage "embedding", then "inserting", then "indexing". Total counts every text twice, once
+// when it is embedded and once when it is inserted, so Current only increases across the stages.
 func (s *RAGStore) AddDocumentsWithEmbeddingProgress(ctx context.Context, userID string, texts []string, documentNames []string, provider EmbeddingProvider, callback ProgressCallback) error {
+	return s.AddDocumentsWithEmbeddingConfig(ctx, userID, texts, documentNames, provider, &EmbeddingIngestConfig{Progress: callback})
+}
+
+// EmbeddingIngestConfig configures AddDocumentsWithEmbeddingConfig
+type EmbeddingIngestConfig struct {
+	BatchSize   int              // Texts per GenerateEmbeddings call (default: 100)
+	Concurrency int              // Maximum GenerateEmbeddings calls in flight (default: 1)
+	Progress    ProgressCallback // Receives progress updates as AddDocumentsWithEmbeddingProgress describes; may be nil
+}
+
+// defaultEmbeddingBatchSize is the number of texts embedded per provider call by default.
+// Most providers support batches of 100+.
+const defaultEmbeddingBatchSize = 100
+
+// AddDocumentsWithEmbeddingConfig adds documents like AddDocumentsWithEmbedding, generating
+// embeddings for up to config.Concurrency batches in parallel, which speeds up ingestion
+// against embedding services that handle concurrent requests. Documents are added in the
+// order of texts whatever order the batches complete in. The first failed batch cancels the
+// others, and nothing is added. A nil config embeds one batch of 100 texts at a time.
+func (s *RAGStore) AddDocumentsWithEmbeddingConfig(ctx context.Context, userID string, texts []string, documentNames []string, provider EmbeddingProvider, config *EmbeddingIngestConfig) error {
+	if config == nil {
+		config = &EmbeddingIngestConfig{}
+	}
 	if len(texts) == 0 {
 		return fmt.Errorf("no texts to add")
 	}
@@ -218,76 +484,170 @@ func (s *RAGStore) AddDocumentsWithEmbeddingProgress(ctx context.Context, userID
 		return fmt.Errorf("number of document names (%d) must match number of texts (%d)", len(documentNames), len(texts))
 	}
 
-	// Check embedding dimensions match
-	if provider.Dimensions() != s.embeddingDim {
-		return fmt.Errorf("provider embedding dimension (%d) does not match store dimension (%d)",
-			provider.Dimensions(), s.embeddingDim)
+	if err := s.checkEmbeddingProvider(ctx, userID, provider); err != nil {
+		return err
 	}
 
-	// Initialize progress tracker for embedding generation
+	callback := config.Progress
+
+	// Initialize progress tracker; each text is counted once embedded and once inserted
 	var tracker *ProgressTracker
 	if callback != nil {
-		tracker = NewProgressTracker("generating_embeddings", int64(len(texts)), callback)
+		tracker = NewProgressTracker("embedding", 2*int64(len(texts)), callback)
 	}
 
-	// Generate embeddings in batches to avoid overwhelming the provider
-	batchSize := 100 // Most providers support batches of 100+
-	docs := make([]Document, 0, len(texts))
-
-	for i := 0; i < len(texts); i += batchSize {
-		// Check for context cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		end := i + batchSize
-		if end > len(texts) {
-			end = len(texts)
-		}
-
-		batch := texts[i:end]
-		embeddings, err := provider.GenerateEmbeddings(ctx, batch)
-		if err != nil {
-			return fmt.Errorf("failed to generate embeddings for batch [%d:%d]: %w", i, end, err)
-		}
-
-		for j, embedding := range embeddings {
-			idx := i + j
-			docs = append(docs, Document{
-				ID:           fmt.Sprintf("%s_%d", documentNames[idx], idx),
-				Text:         texts[idx],
-				DocumentName: documentNames[idx],
-				Embedding:    embedding,
-				Metadata:     map[string]interface{}{},
-			})
-		}
+	embeddings, err := generateEmbeddingBatches(ctx, provider, texts, config.BatchSize, config.Concurrency, tracker)
+	if err != nil {
+		return err
+	}
 
-		// Update progress for embedding generation
-		if tracker != nil {
-			tracker.Add(int64(len(batch)))
-		}
+	// IDs number each document's texts from 0, so re-ingesting a document
+	// produces the same IDs regardless of what else is in the call
+	chunkIndexes := make(map[string]int)
+	docs := make([]Document, 0, len(texts))
+	for idx, embedding := range embeddings {
+		chunkIndex := chunkIndexes[documentNames[idx]]
+		chunkIndexes[documentNames[idx]]++
+		docs = append(docs, Document{
+			ID:           DocumentID(documentNames[idx], chunkIndex),
+			Text:         texts[idx],
+			DocumentName: documentNames[idx],
+			Embedding:    embedding,
+			Metadata:     map[string]interface{}{},
+		})
 	}
 
 	// Now add all documents with progress reporting
 	if tracker != nil {
-		tracker.SetStage("inserting_documents")
+		tracker.SetStage("inserting")
 	}
 
-	// Use the progress-aware version of AddDocuments
-	// We'll create a wrapper callback that updates our tracker
+	// Forward the insert's progress on top of the embedding phase's,
+	// keeping our original start time
 	var insertCallback ProgressCallback
 	if callback != nil {
+		embedded := int64(len(texts))
 		insertCallback = func(p *Progress) {
-			// Forward the progress but keep our original start time
 			progress := *p
+			if progress.Stage == "validating" {
+				progress.Stage = "inserting"
+			}
+			progress.Current = embedded + p.Current
+			progress.Total = embedded + p.Total
 			progress.StartTime = tracker.progress.StartTime
 			callback(&progress)
 		}
 	}
 
-	return s.AddDocumentsWithProgress(ctx, userID, docs, insertCallback)
+	return s.addDocuments(ctx, userID, docs, s.providerEmbeddingModel(provider), false, nil, insertCallback)
+}
+
+// checkEmbeddingProvider checks that provider's embeddings fit the user's table
+func (s *RAGStore) checkEmbeddingProvider(ctx context.Context, userID string, provider EmbeddingProvider) error {
+	// Check embedding dimensions match
+	if dim := s.userEmbeddingDim(userID); provider.Dimensions() != dim {
+		return fmt.Errorf("provider embedding dimension (%d) does not match store dimension (%d)",
+			provider.Dimensions(), dim)
+	}
+
+	// Verify the provider actually returns the declared dimension before generating everything
+	if err := s.verifyProviderDimensions(ctx, provider); err != nil {
+		return fmt.Errorf("provider dimension verification failed: %w", err)
+	}
+	return nil
+}
+
+// verifyProviderDimensions checks that provider returns embeddings of its declared dimension,
+// with its own DimensionVerifier if it has one and a probe embedding otherwise. A provider
+// that passed is remembered with its declared dimension, so later ingests don't pay for
+// another probe.
+func (s *RAGStore) verifyProviderDimensions(ctx context.Context, provider EmbeddingProvider) error {
+	// Only comparable providers can key the cache; others are checked every time
+	cacheable := reflect.TypeOf(provider).Comparable()
+	if cacheable {
+		s.mu.RLock()
+		dim, verified := s.verifiedProviders[provider]
+		s.mu.RUnlock()
+		if verified && dim == provider.Dimensions() {
+			return nil
+		}
+	}
+
+	var err error
+	if verifier, ok := provider.(DimensionVerifier); ok {
+		err = verifier.VerifyDimensions(ctx)
+	} else {
+		err = VerifyEmbeddingDimensions(ctx, provider)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cacheable {
+		s.mu.Lock()
+		if s.verifiedProviders == nil {
+			s.verifiedProviders = make(map[EmbeddingProvider]int)
+		}
+		s.verifiedProviders[provider] = provider.Dimensions()
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// generateEmbeddingBatches embeds texts in batches of batchSize with up to concurrency
+// batches in flight, returning the embeddings in the order of texts. Progress is added to
+// tracker, if not nil, as each batch completes.
+func generateEmbeddingBatches(ctx context.Context, provider EmbeddingProvider, texts []string, batchSize, concurrency int, tracker *ProgressTracker) ([][]float32, error) {
+	if batchSize <= 0 {
+		batchSize = defaultEmbeddingBatchSize
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	embeddings := make([][]float32, len(texts))
+	var progressMu sync.Mutex // The tracker reports from one batch at a time
+
+	// The first failure cancels the batches in flight and stops new ones starting
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i := 0; i < len(texts); i += batchSize {
+		// Check for context cancellation
+		if gctx.Err() != nil {
+			break
+		}
+
+		start, end := i, i+batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		g.Go(func() error {
+			batch, err := provider.GenerateEmbeddings(gctx, texts[start:end])
+			if err != nil {
+				return fmt.Errorf("failed to generate embeddings for batch [%d:%d]: %w", start, end, err)
+			}
+			if len(batch) != end-start {
+				return fmt.Errorf("provider returned %d embeddings for batch [%d:%d]", len(batch), start, end)
+			}
+			copy(embeddings[start:end], batch)
+
+			// Update progress for embedding generation
+			if tracker != nil {
+				progressMu.Lock()
+				tracker.Add(int64(end - start))
+				progressMu.Unlock()
+			}
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return embeddings, nil
 }
 
 // SearchWithText performs a search using text query instead of pre-computed embedding
@@ -298,10 +658,28 @@ func (s *RAGStore) SearchWithText(ctx context.Context, userID string, queryText
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
 
+	// Search with the provider's model unless the caller named one
+	if model := s.providerEmbeddingModel(provider); model != "" && (opts == nil || opts.EmbeddingModel == "") {
+		searchOpts := defaultSearchOptions()
+		if opts != nil {
+			searchOpts = *opts
+		}
+		searchOpts.EmbeddingModel = model
+		opts = &searchOpts
+	}
+
 	// Perform regular search with the embedding
 	return s.Search(ctx, userID, embedding, opts)
 }
 
+// wrappedEmbeddingModel returns the model of a provider wrapped by another, or "" if it can't name one
+func wrappedEmbeddingModel(provider EmbeddingProvider) string {
+	if identifier, ok := provider.(EmbeddingModelIdentifier); ok {
+		return identifier.EmbeddingModel()
+	}
+	return ""
+}
+
 // RateLimitedEmbeddingProvider wraps an embedding provider with rate limiting.
 // This prevents overwhelming external APIs and helps avoid rate limit errors.
 // Useful for production deployments with high request volumes.
@@ -326,6 +704,11 @@ func (p *RateLimitedEmbeddingProvider) Dimensions() int {
 	return p.provider.Dimensions()
 }
 
+// EmbeddingModel returns the wrapped provider's model, or "" if it can't name one
+func (p *RateLimitedEmbeddingProvider) EmbeddingModel() string {
+	return wrappedEmbeddingModel(p.provider)
+}
+
 // GenerateEmbedding generates a single embedding with rate limiting
 func (p *RateLimitedEmbeddingProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	// Wait for rate limiter (respects context cancellation)
@@ -346,3 +729,134 @@ func (p *RateLimitedEmbeddingProvider) GenerateEmbeddings(ctx context.Context, t
 
 	return p.provider.GenerateEmbeddings(ctx, texts)
 }
+
+// RetryingEmbeddingProvider wraps an embedding provider with retries for transient failures.
+// Rate limiting (HTTP 429), server errors (HTTP 5xx) and network failures are retried
+// with exponential backoff and jitter, up to the configured maximum number of attempts.
+// Combine with RateLimitedEmbeddingProvider to both throttle and retry.
+type RetryingEmbeddingProvider struct {
+	provider EmbeddingProvider
+	config   *RetryConfig
+
+	// IsRetryable decides whether an error is transient. Defaults to isTransientEmbeddingError.
+	IsRetryable func(error) bool
+}
+
+// NewRetryingEmbeddingProvider creates a retrying wrapper around an embedding provider.
+// Pass nil for cfg to use DefaultRetryConfig().
+func NewRetryingEmbeddingProvider(provider EmbeddingProvider, cfg *RetryConfig) *RetryingEmbeddingProvider {
+	if cfg == nil {
+		cfg = DefaultRetryConfig()
+	}
+	return &RetryingEmbeddingProvider{
+		provider:    provider,
+		config:      cfg,
+		IsRetryable: isTransientEmbeddingError,
+	}
+}
+
+// Dimensions returns the embedding dimensionality from the wrapped provider
+func (p *RetryingEmbeddingProvider) Dimensions() int {
+	return p.provider.Dimensions()
+}
+
+// EmbeddingModel returns the wrapped provider's model, or "" if it can't name one
+func (p *RetryingEmbeddingProvider) EmbeddingModel() string {
+	return wrappedEmbeddingModel(p.provider)
+}
+
+// GenerateEmbedding generates a single embedding, retrying transient failures
+func (p *RetryingEmbeddingProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	var embedding []float32
+	err := p.retry(ctx, func() error {
+		var err error
+		embedding, err = p.provider.GenerateEmbedding(ctx, text)
+		return err
+	})
+	return embedding, err
+}
+
+// GenerateEmbeddings generates multiple embeddings, retrying the whole batch on transient failures
+func (p *RetryingEmbeddingProvider) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	var embeddings [][]float32
+	err := p.retry(ctx, func() error {
+		var err error
+		embeddings, err = p.provider.GenerateEmbeddings(ctx, texts)
+		return err
+	})
+	return embeddings, err
+}
+
+// retry runs fn until it succeeds, fails with a non-transient error, or attempts run out
+func (p *RetryingEmbeddingProvider) retry(ctx context.Context, fn func() error) error {
+	isRetryable := p.IsRetryable
+	if isRetryable == nil {
+		isRetryable = isTransientEmbeddingError
+	}
+
+	maxAttempts := p.config.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		// Check for context cancellation before attempting
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if attempt >= maxAttempts-1 || !isRetryable(err) {
+			break
+		}
+
+		// Equal jitter: wait between half and the full backoff delay
+		delay := backoffDelay(p.config, attempt)
+		if half := int64(delay / 2); half > 0 {
+			delay = time.Duration(half + rand.Int63n(half+1))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	return lastErr
+}
+
+// transientStatusPattern matches the HTTP status embedded in provider error messages
+var transientStatusPattern = regexp.MustCompile(`status (\d{3})`)
+
+// isTransientEmbeddingError reports whether an embedding provider error is worth retrying:
+// rate limiting (429), server errors (5xx) and network failures.
+func isTransientEmbeddingError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	errStr := err.Error()
+	if match := transientStatusPattern.FindStringSubmatch(errStr); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code == http.StatusTooManyRequests || code >= 500
+	}
+
+	return strings.Contains(errStr, "failed to send request")
+}
@@ -0,0 +1,480 @@
+package rag
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// EmbeddingsTestSuite tests embedding providers
+type EmbeddingsTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func TestEmbeddingsSuite(t *testing.T) {
+	suite.Run(t, new(EmbeddingsTestSuite))
+}
+
+func (s *EmbeddingsTestSuite) SetupTest() {
+	s.ctx = context.Background()
+}
+
+// deterministicEmbedding returns a fixed vector derived from the text length
+func deterministicEmbedding(text string, dim int) []float32 {
+	embedding := make([]float32, dim)
+	for i := range embedding {
+		embedding[i] = float32(len(text)*(i+1)) / 100.0
+	}
+	return embedding
+}
+
+// newOllamaTestServer starts a fake Ollama server returning deterministic vectors
+func newOllamaTestServer(dim int, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embeddings" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(requests, 1)
+
+		var req struct {
+			Model  string `json:"model"`
+			Prompt string `json:"prompt"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"embedding": deterministicEmbedding(req.Prompt, dim),
+		})
+	}))
+}
+
+func (s *EmbeddingsTestSuite) TestOllamaGenerateEmbedding() {
+	var requests int32
+	server := newOllamaTestServer(8, &requests)
+	defer server.Close()
+
+	provider := NewOllamaEmbeddingProvider(server.URL, "nomic-embed-text", 8)
+	s.Equal(8, provider.Dimensions())
+
+	embedding, err := provider.GenerateEmbedding(s.ctx, "hello")
+	s.Require().NoError(err)
+	s.Equal(deterministicEmbedding("hello", 8), embedding)
+	s.Equal(int32(1), requests)
+}
+
+func (s *EmbeddingsTestSuite) TestOllamaGenerateEmbeddingsPreservesOrder() {
+	var requests int32
+	server := newOllamaTestServer(4, &requests)
+	defer server.Close()
+
+	provider := NewOllamaEmbeddingProvider(server.URL, "nomic-embed-text", 4)
+	provider.Concurrency = 3
+
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee", "ffffff", "ggggggg"}
+	embeddings, err := provider.GenerateEmbeddings(s.ctx, texts)
+	s.Require().NoError(err)
+	s.Require().Len(embeddings, len(texts))
+	for i, text := range texts {
+		s.Equal(deterministicEmbedding(text, 4), embeddings[i])
+	}
+	s.Equal(int32(len(texts)), requests)
+}
+
+func (s *EmbeddingsTestSuite) TestOllamaServerError() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	provider := NewOllamaEmbeddingProvider(server.URL, "missing", 4)
+	_, err := provider.GenerateEmbeddings(s.ctx, []string{"a", "b"})
+	s.Error(err)
+	s.Contains(err.Error(), "model not found")
+}
+
+func (s *EmbeddingsTestSuite) TestOllamaContextCancelled() {
+	var requests int32
+	server := newOllamaTestServer(4, &requests)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	provider := NewOllamaEmbeddingProvider(server.URL, "nomic-embed-text", 4)
+	_, err := provider.GenerateEmbeddings(ctx, []string{"a", "b", "c"})
+	s.Error(err)
+}
+
+// flakyEmbeddingProvider fails a fixed number of times before succeeding
+type flakyEmbeddingProvider struct {
+	failures int
+	err      error
+	attempts int32
+}
+
+func (p *flakyEmbeddingProvider) Dimensions() int { return 4 }
+
+func (p *flakyEmbeddingProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if int(atomic.AddInt32(&p.attempts, 1)) <= p.failures {
+		return nil, p.err
+	}
+	return deterministicEmbedding(text, 4), nil
+}
+
+func (p *flakyEmbeddingProvider) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if int(atomic.AddInt32(&p.attempts, 1)) <= p.failures {
+		return nil, p.err
+	}
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i] = deterministicEmbedding(text, 4)
+	}
+	return embeddings, nil
+}
+
+// fastRetryConfig returns a retry config with tiny delays for tests
+func fastRetryConfig(maxAttempts int) *RetryConfig {
+	return &RetryConfig{
+		MaxAttempts:     maxAttempts,
+		InitialDelay:    time.Millisecond,
+		MaxDelay:        5 * time.Millisecond,
+		BackoffMultiple: 2.0,
+	}
+}
+
+func (s *EmbeddingsTestSuite) TestRetryingProviderSucceedsAfterTransientFailures() {
+	flaky := &flakyEmbeddingProvider{failures: 2, err: fmt.Errorf("OpenAI API error (status 503): unavailable")}
+	provider := NewRetryingEmbeddingProvider(flaky, fastRetryConfig(5))
+
+	embedding, err := provider.GenerateEmbedding(s.ctx, "hello")
+	s.Require().NoError(err)
+	s.Equal(deterministicEmbedding("hello", 4), embedding)
+	s.Equal(int32(3), atomic.LoadInt32(&flaky.attempts))
+	s.Equal(4, provider.Dimensions())
+}
+
+func (s *EmbeddingsTestSuite) TestRetryingProviderBatch() {
+	flaky := &flakyEmbeddingProvider{failures: 2, err: fmt.Errorf("OpenAI API error (status 429): rate limited")}
+	provider := NewRetryingEmbeddingProvider(flaky, fastRetryConfig(3))
+
+	embeddings, err := provider.GenerateEmbeddings(s.ctx, []string{"a", "bb"})
+	s.Require().NoError(err)
+	s.Len(embeddings, 2)
+	s.Equal(int32(3), atomic.LoadInt32(&flaky.attempts))
+}
+
+func (s *EmbeddingsTestSuite) TestRetryingProviderMaxAttempts() {
+	flaky := &flakyEmbeddingProvider{failures: 10, err: fmt.Errorf("HTTP embedding service error (status 500): boom")}
+	provider := NewRetryingEmbeddingProvider(flaky, fastRetryConfig(3))
+
+	_, err := provider.GenerateEmbedding(s.ctx, "hello")
+	s.Error(err)
+	s.Contains(err.Error(), "status 500")
+	s.Equal(int32(3), atomic.LoadInt32(&flaky.attempts))
+}
+
+func (s *EmbeddingsTestSuite) TestRetryingProviderNonTransientError() {
+	flaky := &flakyEmbeddingProvider{failures: 10, err: fmt.Errorf("OpenAI API error (status 401): invalid api key")}
+	provider := NewRetryingEmbeddingProvider(flaky, fastRetryConfig(5))
+
+	_, err := provider.GenerateEmbedding(s.ctx, "hello")
+	s.Error(err)
+	s.Equal(int32(1), atomic.LoadInt32(&flaky.attempts))
+}
+
+func (s *EmbeddingsTestSuite) TestRetryingProviderContextCancelled() {
+	flaky := &flakyEmbeddingProvider{failures: 10, err: fmt.Errorf("OpenAI API error (status 503): unavailable")}
+	provider := NewRetryingEmbeddingProvider(flaky, &RetryConfig{
+		MaxAttempts:     5,
+		InitialDelay:    time.Second,
+		MaxDelay:        time.Second,
+		BackoffMultiple: 1.0,
+	})
+
+	ctx, cancel := context.WithTimeout(s.ctx, 20*time.Millisecond)
+	defer cancel()
+
+	_, err := provider.GenerateEmbedding(ctx, "hello")
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.Equal(int32(1), atomic.LoadInt32(&flaky.attempts))
+}
+
+func (s *EmbeddingsTestSuite) TestIsTransientEmbeddingError() {
+	s.True(isTransientEmbeddingError(fmt.Errorf("OpenAI API error (status 429): slow down")))
+	s.True(isTransientEmbeddingError(fmt.Errorf("Ollama API error (status 502): bad gateway")))
+	s.True(isTransientEmbeddingError(fmt.Errorf("failed to send request: connection refused")))
+	s.False(isTransientEmbeddingError(fmt.Errorf("OpenAI API error (status 400): bad input")))
+	s.False(isTransientEmbeddingError(context.Canceled))
+	s.False(isTransientEmbeddingError(nil))
+}
+
+// openAIRequest captures the fields of an embeddings request the tests care about
+type openAIRequest struct {
+	Input          []string `json:"input"`
+	Model          string   `json:"model"`
+	Dimensions     int      `json:"dimensions"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+// encodeBase64Embedding encodes a vector as base64 little-endian float32, as OpenAI does
+func encodeBase64Embedding(embedding []float32) string {
+	buf := make([]byte, len(embedding)*4)
+	for i, v := range embedding {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// newOpenAITestServer starts a fake OpenAI embeddings endpoint returning nativeDim vectors,
+// or shortened vectors when the request carries a "dimensions" parameter
+func newOpenAITestServer(nativeDim int, last *openAIRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if last != nil {
+			*last = req
+		}
+
+		dim := nativeDim
+		if req.Dimensions > 0 {
+			dim = req.Dimensions
+		}
+
+		data := make([]map[string]interface{}, len(req.Input))
+		for i, text := range req.Input {
+			var embedding interface{} = deterministicEmbedding(text, dim)
+			if req.EncodingFormat == OpenAIEncodingBase64 {
+				embedding = encodeBase64Embedding(deterministicEmbedding(text, dim))
+			}
+			data[i] = map[string]interface{}{
+				"index":     i,
+				"embedding": embedding,
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+}
+
+func (s *EmbeddingsTestSuite) TestVerifyDimensionsMatching() {
+	server := newOpenAITestServer(8, nil)
+	defer server.Close()
+
+	provider := NewOpenAIEmbeddingProvider("key", "text-embedding-ada-002", 8)
+	provider.BaseURL = server.URL
+
+	s.NoError(VerifyEmbeddingDimensions(s.ctx, provider))
+	s.NoError(VerifyEmbeddingDimensions(s.ctx, NewRateLimitedEmbeddingProvider(provider, 100, 10)))
+}
+
+func (s *EmbeddingsTestSuite) TestVerifyDimensionsMismatch() {
+	server := newOpenAITestServer(8, nil)
+	defer server.Close()
+
+	provider := NewOpenAIEmbeddingProvider("key", "text-embedding-ada-002", 16)
+	provider.BaseURL = server.URL
+
+	err := VerifyEmbeddingDimensions(s.ctx, provider)
+	s.Error(err)
+	s.Contains(err.Error(), "declares 16")
+	s.Contains(err.Error(), "returned 8")
+}
+
+func (s *EmbeddingsTestSuite) TestOpenAIDimensionsParameter() {
+	var last openAIRequest
+	server := newOpenAITestServer(1536, &last)
+	defer server.Close()
+
+	// text-embedding-3-* models get the dimensions parameter and return shortened vectors
+	provider := NewOpenAIEmbeddingProvider("key", "text-embedding-3-small", 256)
+	provider.BaseURL = server.URL
+
+	embedding, err := provider.GenerateEmbedding(s.ctx, "hello")
+	s.Require().NoError(err)
+	s.Equal(256, last.Dimensions)
+	s.Len(embedding, 256)
+	s.NoError(VerifyEmbeddingDimensions(s.ctx, provider))
+
+	// Older models don't support the parameter
+	legacy := NewOpenAIEmbeddingProvider("key", "text-embedding-ada-002", 1536)
+	legacy.BaseURL = server.URL
+
+	_, err = legacy.GenerateEmbedding(s.ctx, "hello")
+	s.Require().NoError(err)
+	s.Equal(0, last.Dimensions)
+}
+
+func (s *EmbeddingsTestSuite) TestOpenAIFloatEncoding() {
+	var last openAIRequest
+	server := newOpenAITestServer(8, &last)
+	defer server.Close()
+
+	provider := NewOpenAIEmbeddingProvider("key", "text-embedding-ada-002", 8)
+	provider.BaseURL = server.URL
+
+	embeddings, err := provider.GenerateEmbeddings(s.ctx, []string{"a", "bbb"})
+	s.Require().NoError(err)
+	s.Equal("", last.EncodingFormat)
+	s.Equal(deterministicEmbedding("a", 8), embeddings[0])
+	s.Equal(deterministicEmbedding("bbb", 8), embeddings[1])
+}
+
+func (s *EmbeddingsTestSuite) TestOpenAIBase64Encoding() {
+	var last openAIRequest
+	server := newOpenAITestServer(1536, &last)
+	defer server.Close()
+
+	provider := NewOpenAIEmbeddingProvider("key", "text-embedding-3-small", 256)
+	provider.BaseURL = server.URL
+	provider.EncodingFormat = OpenAIEncodingBase64
+
+	embeddings, err := provider.GenerateEmbeddings(s.ctx, []string{"hello", "world!"})
+	s.Require().NoError(err)
+	s.Equal(OpenAIEncodingBase64, last.EncodingFormat)
+	s.Equal(256, last.Dimensions)
+	s.Equal(256, provider.Dimensions())
+	s.Require().Len(embeddings, 2)
+	s.Equal(deterministicEmbedding("hello", 256), embeddings[0])
+	s.Equal(deterministicEmbedding("world!", 256), embeddings[1])
+}
+
+func (s *EmbeddingsTestSuite) TestDecodeOpenAIEmbedding() {
+	values := []float32{1.5, -2.25, 0, 3.4028235e38}
+
+	decoded, err := decodeOpenAIEmbedding(json.RawMessage(`"` + encodeBase64Embedding(values) + `"`))
+	s.Require().NoError(err)
+	s.Equal(values, decoded)
+
+	decoded, err = decodeOpenAIEmbedding(json.RawMessage(`[1.5, -2.25]`))
+	s.Require().NoError(err)
+	s.Equal([]float32{1.5, -2.25}, decoded)
+
+	_, err = decodeOpenAIEmbedding(json.RawMessage(`"AAA="`))
+	s.Error(err)
+
+	_, err = decodeOpenAIEmbedding(json.RawMessage(`"not base64!"`))
+	s.Error(err)
+}
+
+// concurrencyTrackingProvider records the most GenerateEmbeddings calls in flight at once
+type concurrencyTrackingProvider struct {
+	dim      int
+	delay    time.Duration
+	failOn   string // Batches containing this text fail
+	inFlight int32
+	maxCalls int32
+	calls    int32
+}
+
+func (p *concurrencyTrackingProvider) Dimensions() int { return p.dim }
+
+func (p *concurrencyTrackingProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := p.GenerateEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (p *concurrencyTrackingProvider) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	atomic.AddInt32(&p.calls, 1)
+	current := atomic.AddInt32(&p.inFlight, 1)
+	defer atomic.AddInt32(&p.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&p.maxCalls)
+		if current <= max || atomic.CompareAndSwapInt32(&p.maxCalls, max, current) {
+			break
+		}
+	}
+
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		if p.failOn != "" && text == p.failOn {
+			return nil, fmt.Errorf("embedding service unavailable")
+		}
+		embeddings[i] = deterministicEmbedding(text, p.dim)
+	}
+	return embeddings, nil
+}
+
+// distinctTexts returns n texts of different lengths, so their embeddings differ
+func distinctTexts(n int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i+1)
+	}
+	return texts
+}
+
+func (s *EmbeddingsTestSuite) TestGenerateEmbeddingBatchesBoundsConcurrency() {
+	provider := &concurrencyTrackingProvider{dim: 4, delay: 20 * time.Millisecond}
+	texts := distinctTexts(25)
+
+	var mu sync.Mutex
+	var reported int64
+	tracker := NewProgressTracker("embedding", int64(len(texts)), func(p *Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = p.Current
+	})
+
+	embeddings, err := generateEmbeddingBatches(s.ctx, provider, texts, 2, 3, tracker)
+	s.Require().NoError(err)
+	s.Require().Len(embeddings, len(texts))
+	for i, text := range texts {
+		s.Equal(deterministicEmbedding(text, 4), embeddings[i], "embedding %d is out of order", i)
+	}
+	s.EqualValues(13, atomic.LoadInt32(&provider.calls))
+	s.LessOrEqual(atomic.LoadInt32(&provider.maxCalls), int32(3))
+	s.Greater(atomic.LoadInt32(&provider.maxCalls), int32(1), "batches should overlap")
+	s.EqualValues(len(texts), reported)
+
+	// The default runs one batch at a time
+	serial := &concurrencyTrackingProvider{dim: 4, delay: time.Millisecond}
+	_, err = generateEmbeddingBatches(s.ctx, serial, texts, 5, 0, nil)
+	s.Require().NoError(err)
+	s.EqualValues(1, atomic.LoadInt32(&serial.maxCalls))
+}
+
+func (s *EmbeddingsTestSuite) TestGenerateEmbeddingBatchesStopsOnError() {
+	provider := &concurrencyTrackingProvider{dim: 4, delay: 5 * time.Millisecond, failOn: "xxx"}
+	_, err := generateEmbeddingBatches(s.ctx, provider, distinctTexts(100), 1, 2, nil)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "batch [2:3]")
+	s.Less(atomic.LoadInt32(&provider.calls), int32(100), "no batches should start after the failure")
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+	_, err = generateEmbeddingBatches(ctx, provider, distinctTexts(10), 1, 2, nil)
+	s.ErrorIs(err, context.Canceled)
+}
@@ -214,19 +214,14 @@ func createSampleDocuments(dim int) []rag.Document {
 // In a real application, you would use an embedding model (OpenAI, etc.)
 func generateRandomEmbedding(dim int) []float32 {
 	embedding := make([]float32, dim)
-	var sum float32
 
 	// Generate random values
 	for i := 0; i < dim; i++ {
 		embedding[i] = rand.Float32()
-		sum += embedding[i] * embedding[i]
 	}
 
-	// Normalize the vector
-	norm := float32(1.0) / float32(1e-8+sum)
-	for i := 0; i < dim; i++ {
-		embedding[i] *= norm
-	}
+	// Normalize the vector to unit length
+	rag.NormalizeVectorInPlace(embedding)
 
 	return embedding
 }
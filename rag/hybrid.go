@@ -3,9 +3,21 @@ package rag
 import (
 	"context"
 	"fmt"
-	"math"
 	"sort"
 	"strings"
+
+	"github.com/aqua777/go-lancedb"
+	"golang.org/x/sync/errgroup"
+)
+
+// FusionMethod selects how hybrid search combines vector and keyword results
+type FusionMethod int
+
+const (
+	// FusionWeighted normalizes both score lists and combines them with VectorWeight/KeywordWeight
+	FusionWeighted FusionMethod = iota
+	// FusionRRF combines the ranked lists with Reciprocal Rank Fusion, ignoring raw scores and weights
+	FusionRRF
 )
 
 // HybridSearchOptions configures hybrid search behavior
@@ -15,25 +27,53 @@ type HybridSearchOptions struct {
 	KeywordWeight  float32                // Weight for keyword search (0-1, default: 0.5)
 	Filters        map[string]interface{} // Metadata filters
 	MinKeywordScore float32               // Minimum BM25 score to include (default: 0)
+	FusionMethod   FusionMethod           // How to combine results (default: FusionWeighted)
+	RRFK           float32                // RRF constant k for FusionRRF (default: 60)
+	DistanceType   lancedb.DistanceType   // Distance metric for the vector search (default: Cosine)
+	EmbeddingModel string                 // Model that produced the query embedding; see SearchOptions.EmbeddingModel
+
+	// ReturnPartialOnCancel makes a search whose context is cancelled after one of the vector
+	// and keyword searches finished return that search's results, fused as if the other
+	// found nothing, instead of the context's error
+	ReturnPartialOnCancel bool
+}
+
+// defaultHybridSearchOptions returns the options used when a hybrid search is given none
+func defaultHybridSearchOptions() HybridSearchOptions {
+	return HybridSearchOptions{
+		Limit:         10,
+		VectorWeight:  0.5,
+		KeywordWeight: 0.5,
+		DistanceType:  lancedb.DistanceTypeCosine,
+	}
 }
 
 // HybridSearch performs both vector and keyword search, then combines results
-func (s *RAGStore) HybridSearch(ctx context.Context, userID string, queryText string, queryEmbedding []float32, opts *HybridSearchOptions) ([]SearchResult, error) {
+func (s *RAGStore) HybridSearch(ctx context.Context, userID string, queryText string, queryEmbedding []float32, opts *HybridSearchOptions) (results []SearchResult, err error) {
+	ctx, span := s.startSpan(ctx, "rag.HybridSearch", userID)
+	defer func() {
+		span.SetAttribute(SpanAttrResultCount, len(results))
+		endSpan(span, err)
+	}()
+
 	if opts == nil {
-		opts = &HybridSearchOptions{
-			Limit:         10,
-			VectorWeight:  0.5,
-			KeywordWeight: 0.5,
-		}
+		hybridOpts := defaultHybridSearchOptions()
+		opts = &hybridOpts
 	}
 
-	// Normalize weights
-	totalWeight := opts.VectorWeight + opts.KeywordWeight
-	if totalWeight == 0 {
-		return nil, fmt.Errorf("at least one of VectorWeight or KeywordWeight must be non-zero")
+	switch opts.FusionMethod {
+	case FusionWeighted:
+		// Normalize weights
+		totalWeight := opts.VectorWeight + opts.KeywordWeight
+		if totalWeight == 0 {
+			return nil, fmt.Errorf("at least one of VectorWeight or KeywordWeight must be non-zero")
+		}
+		opts.VectorWeight = opts.VectorWeight / totalWeight
+		opts.KeywordWeight = opts.KeywordWeight / totalWeight
+	case FusionRRF:
+	default:
+		return nil, fmt.Errorf("unknown fusion method: %d", opts.FusionMethod)
 	}
-	opts.VectorWeight = opts.VectorWeight / totalWeight
-	opts.KeywordWeight = opts.KeywordWeight / totalWeight
 
 	// Check for context cancellation
 	select {
@@ -42,31 +82,37 @@ func (s *RAGStore) HybridSearch(ctx context.Context, userID string, queryText st
 	default:
 	}
 
-	// Perform vector search (fetch more results for better fusion)
+	// Fetch more results from each search for better fusion
 	vectorLimit := opts.Limit * 3
 	if vectorLimit > 100 {
 		vectorLimit = 100
 	}
 
 	vectorSearchOpts := &SearchOptions{
-		Limit:   vectorLimit,
-		Filters: opts.Filters,
+		Limit:          vectorLimit,
+		Filters:        opts.Filters,
+		DistanceType:   opts.DistanceType,
+		EmbeddingModel: opts.EmbeddingModel,
 	}
 
-	vectorResults, err := s.Search(ctx, userID, queryEmbedding, vectorSearchOpts)
+	// Run the vector and keyword searches concurrently
+	vectorResults, keywordResults, err := runHybridSearches(ctx, opts.ReturnPartialOnCancel,
+		func(ctx context.Context) ([]SearchResult, error) {
+			return s.Search(ctx, userID, queryEmbedding, vectorSearchOpts)
+		},
+		func(ctx context.Context) ([]SearchResult, error) {
+			return s.keywordSearch(ctx, userID, queryText, vectorLimit, opts.Filters)
+		})
 	if err != nil {
-		return nil, fmt.Errorf("vector search failed: %w", err)
+		return nil, err
 	}
 
-	// Perform keyword search
-	keywordResults, err := s.keywordSearch(ctx, userID, queryText, vectorLimit, opts.Filters)
+	// Combine results using RRF or weighted scoring
+	combined, err := s.fuseResults(ctx, vectorResults, keywordResults, opts)
 	if err != nil {
-		return nil, fmt.Errorf("keyword search failed: %w", err)
+		return nil, fmt.Errorf("failed to combine results: %w", err)
 	}
 
-	// Combine results using RRF or weighted scoring
-	combined := s.combineResults(vectorResults, keywordResults, opts)
-
 	// Limit final results
 	if len(combined) > opts.Limit {
 		combined = combined[:opts.Limit]
@@ -75,10 +121,50 @@ func (s *RAGStore) HybridSearch(ctx context.Context, userID string, queryText st
 	return combined, nil
 }
 
+// runHybridSearches runs the vector and keyword searches concurrently. An error from either
+// cancels the other and is returned, except that when ctx itself is cancelled and partial is
+// set, the results of whichever searches completed are returned, with nil for the others.
+func runHybridSearches(ctx context.Context, partial bool, vectorSearch, keywordSearch func(context.Context) ([]SearchResult, error)) ([]SearchResult, []SearchResult, error) {
+	var vectorResults, keywordResults []SearchResult
+	var vectorErr, keywordErr error
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		vectorResults, vectorErr = vectorSearch(gctx)
+		if vectorErr != nil {
+			return fmt.Errorf("vector search failed: %w", vectorErr)
+		}
+		return nil
+	})
+	g.Go(func() error {
+		keywordResults, keywordErr = keywordSearch(gctx)
+		if keywordErr != nil {
+			return fmt.Errorf("keyword search failed: %w", keywordErr)
+		}
+		return nil
+	})
+
+	err := g.Wait()
+	if err == nil {
+		return vectorResults, keywordResults, nil
+	}
+	if !partial || ctx.Err() == nil || (vectorErr != nil && keywordErr != nil) {
+		return nil, nil, err
+	}
+	if vectorErr != nil {
+		vectorResults = nil
+	}
+	if keywordErr != nil {
+		keywordResults = nil
+	}
+	return vectorResults, keywordResults, nil
+}
+
 // keywordSearch performs BM25-based keyword search.
-// WARNING: This loads ALL documents into memory to calculate BM25 scores.
-// For large document collections, this can cause memory exhaustion.
-// Use the MaxDocumentsForBM25 limit to prevent issues (default: 10,000).
+// If the user's table has a full-text index on "text" (see CreateTextIndex), the search runs
+// natively in LanceDB. Otherwise collections of up to MaxDocumentsForBM25 documents
+// (default: 10,000) are scored in memory, caching the tokenized documents, and larger ones
+// are scored by streaming the table twice, which is slower but bounds memory use.
 func (s *RAGStore) keywordSearch(ctx context.Context, userID string, queryText string, limit int, filters map[string]interface{}) ([]SearchResult, error) {
 	// Check if table exists
 	exists, err := s.TableExists(ctx, userID)
@@ -89,35 +175,87 @@ func (s *RAGStore) keywordSearch(ctx context.Context, userID string, queryText s
 		return []SearchResult{}, nil
 	}
 
-	table, err := s.conn.OpenTable(s.getTableName(userID))
+	table, release, err := s.acquireTable(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open table: %w", err)
 	}
-	defer table.Close()
+	defer release()
 
-	// Check document count before loading all documents into memory
-	// BM25 calculation requires all documents, which doesn't scale well
-	if s.maxDocumentsForBM25 > 0 {
-		count, err := table.CountRows()
-		if err != nil {
-			return nil, fmt.Errorf("failed to count documents: %w", err)
+	// Use the native full-text index when available; it doesn't load documents into memory
+	if hasTextIndex(table) {
+		return s.fullTextSearch(ctx, table, userID, queryText, limit, filters)
+	}
+
+	// Reuse the tokenized documents of an earlier search while the table is unchanged
+	tokenizer, tokenizerGen := s.getTokenizer()
+	version, err := table.Version()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table version: %w", err)
+	}
+	key := bm25CacheKey{userID: userID, predicate: buildPredicate(filters), version: version, tokenizerGen: tokenizerGen}
+	corpus, ok := s.bm25Cache.get(key)
+	if !ok {
+		// Collections too large to hold in memory are scored by streaming them instead
+		if s.maxDocumentsForBM25 > 0 {
+			count, err := countMatchingRows(table, key.predicate)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count documents: %w", err)
+			}
+			if count > int64(s.maxDocumentsForBM25) {
+				return s.streamBM25(ctx, userID, version, key.predicate, tokenizer.Tokenize(queryText), tokenizer, limit)
+			}
 		}
-		if count > int64(s.maxDocumentsForBM25) {
-			return nil, fmt.Errorf("document count (%d) exceeds BM25 limit (%d); use pure vector search instead or increase limit with SetMaxDocumentsForBM25()", count, s.maxDocumentsForBM25)
+
+		corpus, err = s.loadBM25Corpus(ctx, table, userID, key.predicate, tokenizer)
+		if err != nil {
+			return nil, err
 		}
+		s.bm25Cache.put(key, corpus)
+	}
+
+	// Calculate BM25 scores
+	scoredResults := corpus.score(tokenizer.Tokenize(queryText))
+
+	// Sort by BM25 score descending
+	sort.Slice(scoredResults, func(i, j int) bool {
+		return scoredResults[i].Score > scoredResults[j].Score
+	})
+
+	// Limit results
+	if len(scoredResults) > limit {
+		scoredResults = scoredResults[:limit]
 	}
 
+	// The corpus may be cached, so hand out copies
+	for i := range scoredResults {
+		scoredResults[i] = cloneSearchResult(scoredResults[i])
+	}
+
+	return scoredResults, nil
+}
+
+// countMatchingRows counts the rows matching predicate, or all rows if it is empty
+func countMatchingRows(table *lancedb.Table, predicate string) (int64, error) {
+	if predicate == "" {
+		return table.CountRows()
+	}
+	return table.CountRowsWhere(predicate)
+}
+
+// loadBM25Corpus reads the user's documents matching predicate and tokenizes them for BM25.
+// It loads ALL matching documents into memory; keywordSearch only calls it for collections
+// within the MaxDocumentsForBM25 limit.
+func (s *RAGStore) loadBM25Corpus(ctx context.Context, table *lancedb.Table, userID, predicate string, tokenizer Tokenizer) (*bm25Corpus, error) {
 	// Get all documents (for BM25 calculation)
 	query := table.Query()
 	defer query.Close()
 
 	// Apply filters if provided
-	if len(filters) > 0 {
-		predicate := buildPredicate(filters)
+	if predicate != "" {
 		query = query.Where(predicate)
 	}
 
-	records, err := query.Select("id", "text", "document_name", "embedding", "metadata").Execute()
+	records, err := query.Select(documentColumns(s.userEmbeddingStorage(userID))...).ExecuteContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -125,7 +263,7 @@ func (s *RAGStore) keywordSearch(ctx context.Context, userID string, queryText s
 	// Parse all results
 	var allResults []SearchResult
 	for _, record := range records {
-		results, err := parseSearchResults(record, s.embeddingDim)
+		results, err := parseSearchResults(record, s.userEmbeddingDim(userID), s.getMetadataCodec())
 		if err != nil {
 			for _, r := range records {
 				r.Release()
@@ -136,55 +274,154 @@ func (s *RAGStore) keywordSearch(ctx context.Context, userID string, queryText s
 		record.Release()
 	}
 
-	// Calculate BM25 scores
-	queryTerms := tokenize(queryText)
-	scoredResults := calculateBM25(allResults, queryTerms)
+	// Check for context cancellation before tokenizing
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
 
-	// Sort by BM25 score descending
-	sort.Slice(scoredResults, func(i, j int) bool {
-		return scoredResults[i].Score > scoredResults[j].Score
+	return newBM25Corpus(allResults, tokenizer), nil
+}
+
+// fullTextSearch runs a keyword query against the table's full-text index.
+// Result scores are LanceDB's BM25 scores (higher is better).
+func (s *RAGStore) fullTextSearch(ctx context.Context, table *lancedb.Table, userID string, queryText string, limit int, filters map[string]interface{}) ([]SearchResult, error) {
+	if len(tokenize(queryText)) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	query := table.Query()
+	defer query.Close()
+
+	query = query.FullTextSearch(queryText)
+	if len(filters) > 0 {
+		query = query.Where(buildPredicate(filters))
+	}
+
+	records, err := query.Select(documentColumns(s.userEmbeddingStorage(userID))...).Limit(limit).ExecuteContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute full-text search: %w", err)
+	}
+
+	// Results carry a trailing _score column, which parseSearchResults reads as the score
+	var results []SearchResult
+	for _, record := range records {
+		parsed, err := parseSearchResults(record, s.userEmbeddingDim(userID), s.getMetadataCodec())
+		if err != nil {
+			for _, r := range records {
+				r.Release()
+			}
+			return nil, fmt.Errorf("failed to parse results: %w", err)
+		}
+		results = append(results, parsed...)
+		record.Release()
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
 	})
 
-	// Limit results
-	if len(scoredResults) > limit {
-		scoredResults = scoredResults[:limit]
+	return results, nil
+}
+
+// hasTextIndex reports whether the table has a full-text index on the text column
+func hasTextIndex(table *lancedb.Table) bool {
+	indices, err := table.ListIndices()
+	if err != nil {
+		return false
+	}
+	for _, idx := range indices {
+		if len(idx.Columns) == 1 && idx.Columns[0] == "text" {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateTextIndex builds a full-text search index on the user's document text.
+// Once it exists, HybridSearch runs keyword search natively in LanceDB instead of
+// loading every document for in-memory BM25, so the MaxDocumentsForBM25 limit no longer applies.
+// Call it again after large ingestions to index new documents.
+func (s *RAGStore) CreateTextIndex(ctx context.Context, userID string) (err error) {
+	if err := s.validateUserID(userID); err != nil {
+		return err
 	}
 
-	return scoredResults, nil
+	ctx, span := s.startSpan(ctx, "rag.CreateTextIndex", userID)
+	defer func() { endSpan(span, err) }()
+
+	// Check for context cancellation
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	unlock := s.lockUser(userID)
+	defer unlock()
+	defer s.invalidateTable(userID)
+
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
+	if err != nil {
+		return fmt.Errorf("failed to open table: %w", err)
+	}
+	defer table.Close()
+
+	s.logger.Printf("Creating full-text index for user %s", userID)
+
+	indexOpts := &lancedb.IndexOptions{
+		IndexType: lancedb.IndexTypeFTS,
+		Replace:   true,
+	}
+	if err := table.CreateIndex("text", indexOpts); err != nil {
+		return fmt.Errorf("failed to create full-text index: %w", err)
+	}
+
+	s.logger.Printf("Successfully created full-text index for user %s", userID)
+	return nil
+}
+
+// fuseResults combines vector and keyword results with the configured fusion method
+func (s *RAGStore) fuseResults(ctx context.Context, vectorResults, keywordResults []SearchResult, opts *HybridSearchOptions) ([]SearchResult, error) {
+	if opts.FusionMethod == FusionRRF {
+		rrf := NewReciprocalRankFusionReranker(opts.RRFK)
+		combined, err := rrf.CombineRankedLists(ctx, [][]SearchResult{vectorResults, keywordResults})
+		if err != nil {
+			return nil, err
+		}
+		annotateRanks(combined, vectorResults, keywordResults)
+		return combined, nil
+	}
+	return s.combineResults(vectorResults, keywordResults, opts), nil
 }
 
-// combineResults merges vector and keyword results with weighted scoring
+// combineResults merges vector and keyword results with weighted scoring.
+// Input results are not modified; see annotateRanks for the rank metadata on the output.
 func (s *RAGStore) combineResults(vectorResults, keywordResults []SearchResult, opts *HybridSearchOptions) []SearchResult {
 	// Build maps for quick lookup
 	resultMap := make(map[string]SearchResult)
 	scoreMap := make(map[string]float32)
 
-	// Normalize vector scores (lower distance = higher score for cosine)
+	// Normalize vector scores (distances are converted to similarities for the search's metric)
 	maxVectorScore := float32(0.0)
 	for _, result := range vectorResults {
-		// For cosine similarity, distance is 1 - similarity, so invert
-		score := 1.0 - result.Score
+		score := NormalizeScore(opts.DistanceType, result.Score)
 		if score > maxVectorScore {
 			maxVectorScore = score
 		}
 	}
 
-	for i, result := range vectorResults {
+	for _, result := range vectorResults {
 		normalizedScore := float32(0.0)
 		if maxVectorScore > 0 {
-			normalizedScore = (1.0 - result.Score) / maxVectorScore
+			normalizedScore = NormalizeScore(opts.DistanceType, result.Score) / maxVectorScore
 		}
-		
-		weightedScore := normalizedScore * opts.VectorWeight
-		
-		resultMap[result.ID] = result
-		scoreMap[result.ID] = weightedScore
-		
-		// Adjust rank in metadata
-		if vectorResults[i].Metadata == nil {
-			vectorResults[i].Metadata = make(map[string]interface{})
+
+		if _, exists := resultMap[result.ID]; !exists {
+			resultMap[result.ID] = result
+			scoreMap[result.ID] = normalizedScore * opts.VectorWeight
 		}
-		vectorResults[i].Metadata["vector_rank"] = i
 	}
 
 	// Normalize keyword scores
@@ -195,29 +432,26 @@ func (s *RAGStore) combineResults(vectorResults, keywordResults []SearchResult,
 		}
 	}
 
-	for i, result := range keywordResults {
+	seenKeyword := make(map[string]bool, len(keywordResults))
+	for _, result := range keywordResults {
+		if seenKeyword[result.ID] {
+			continue
+		}
+		seenKeyword[result.ID] = true
+
 		normalizedScore := float32(0.0)
 		if maxKeywordScore > 0 {
 			normalizedScore = result.Score / maxKeywordScore
 		}
-		
+
 		weightedScore := normalizedScore * opts.KeywordWeight
-		
+
 		if existing, exists := scoreMap[result.ID]; exists {
 			scoreMap[result.ID] = existing + weightedScore
 		} else {
 			resultMap[result.ID] = result
 			scoreMap[result.ID] = weightedScore
 		}
-		
-		// Adjust rank in metadata
-		if existingResult, exists := resultMap[result.ID]; exists {
-			if existingResult.Metadata == nil {
-				existingResult.Metadata = make(map[string]interface{})
-			}
-			existingResult.Metadata["keyword_rank"] = i
-			resultMap[result.ID] = existingResult
-		}
 	}
 
 	// Convert to sorted list
@@ -227,97 +461,67 @@ func (s *RAGStore) combineResults(vectorResults, keywordResults []SearchResult,
 		combined = append(combined, result)
 	}
 
-	// Sort by combined score descending
+	// Sort by combined score descending (ties broken by ID for deterministic output)
 	sort.Slice(combined, func(i, j int) bool {
-		return combined[i].Score > combined[j].Score
+		if combined[i].Score != combined[j].Score {
+			return combined[i].Score > combined[j].Score
+		}
+		return combined[i].ID < combined[j].ID
 	})
 
+	annotateRanks(combined, vectorResults, keywordResults)
 	return combined
 }
 
-// tokenize splits text into lowercase tokens
-func tokenize(text string) []string {
-	text = strings.ToLower(text)
-	tokens := strings.FieldsFunc(text, func(r rune) bool {
-		return !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'))
-	})
-	return tokens
-}
+// annotateRanks gives every combined result its own copy of its metadata with
+// "vector_rank" and "keyword_rank" set to the result's 0-based position in each input list.
+// A rank key is present only if the corresponding list returned the document.
+func annotateRanks(combined, vectorResults, keywordResults []SearchResult) {
+	vectorRanks := rankPositions(vectorResults)
+	keywordRanks := rankPositions(keywordResults)
+
+	for i := range combined {
+		metadata := make(map[string]interface{}, len(combined[i].Metadata)+2)
+		for k, v := range combined[i].Metadata {
+			metadata[k] = v
+		}
+		delete(metadata, "vector_rank")
+		delete(metadata, "keyword_rank")
 
-// calculateBM25 computes BM25 scores for documents given query terms
-// BM25 parameters: k1=1.5, b=0.75
-func calculateBM25(documents []SearchResult, queryTerms []string) []SearchResult {
-	if len(documents) == 0 || len(queryTerms) == 0 {
-		return documents
-	}
-
-	k1 := float32(1.5)
-	b := float32(0.75)
-
-	// Calculate average document length
-	totalLength := 0
-	docLengths := make([]int, len(documents))
-	for i, doc := range documents {
-		tokens := tokenize(doc.Text)
-		docLengths[i] = len(tokens)
-		totalLength += len(tokens)
-	}
-	avgDocLength := float32(totalLength) / float32(len(documents))
-
-	// Calculate IDF for each query term
-	idf := make(map[string]float32)
-	for _, term := range queryTerms {
-		docCount := 0
-		for _, doc := range documents {
-			if containsTerm(tokenize(doc.Text), term) {
-				docCount++
-			}
+		if rank, ok := vectorRanks[combined[i].ID]; ok {
+			metadata["vector_rank"] = rank
 		}
-		if docCount > 0 {
-			numerator := float64(len(documents)-docCount) + 0.5
-			denominator := float64(docCount) + 0.5
-			idf[term] = float32(math.Log(numerator / denominator))
+		if rank, ok := keywordRanks[combined[i].ID]; ok {
+			metadata["keyword_rank"] = rank
 		}
+		combined[i].Metadata = metadata
 	}
+}
 
-	// Calculate BM25 score for each document
-	scored := make([]SearchResult, len(documents))
-	copy(scored, documents)
-
-	for i, doc := range documents {
-		docTokens := tokenize(doc.Text)
-		termFreq := make(map[string]int)
-		for _, token := range docTokens {
-			termFreq[token]++
+// rankPositions maps each result ID to its first position in results
+func rankPositions(results []SearchResult) map[string]int {
+	ranks := make(map[string]int, len(results))
+	for i, result := range results {
+		if _, exists := ranks[result.ID]; !exists {
+			ranks[result.ID] = i
 		}
-
-		score := float32(0.0)
-		for _, term := range queryTerms {
-			if termIDF, ok := idf[term]; ok {
-				tf := float32(termFreq[term])
-				docLen := float32(docLengths[i])
-				
-				numerator := tf * (k1 + 1)
-				denominator := tf + k1*(1-b+b*(docLen/avgDocLength))
-				
-				score += termIDF * (numerator / denominator)
-			}
-		}
-
-		scored[i].Score = score
 	}
+	return ranks
+}
 
-	return scored
+// tokenize splits text into lowercase tokens
+func tokenize(text string) []string {
+	text = strings.ToLower(text)
+	tokens := strings.FieldsFunc(text, func(r rune) bool {
+		return !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'))
+	})
+	return tokens
 }
 
-// containsTerm checks if a token list contains a specific term
-func containsTerm(tokens []string, term string) bool {
-	for _, token := range tokens {
-		if token == term {
-			return true
-		}
-	}
-	return false
+// calculateBM25 computes BM25 scores for documents given query terms, splitting documents
+// into terms with tokenizer. BM25 parameters: k1=1.5, b=0.75
+func calculateBM25(documents []SearchResult, queryTerms []string, tokenizer Tokenizer) []SearchResult {
+	return newBM25Corpus(documents, tokenizer).score(queryTerms)
 }
 
 // HybridSearchWithText performs hybrid search using text query (generates embedding automatically)
@@ -328,6 +532,16 @@ func (s *RAGStore) HybridSearchWithText(ctx context.Context, userID string, quer
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
 
+	// Search with the provider's model unless the caller named one
+	if model := s.providerEmbeddingModel(provider); model != "" && (opts == nil || opts.EmbeddingModel == "") {
+		hybridOpts := defaultHybridSearchOptions()
+		if opts != nil {
+			hybridOpts = *opts
+		}
+		hybridOpts.EmbeddingModel = model
+		opts = &hybridOpts
+	}
+
 	return s.HybridSearch(ctx, userID, queryText, embedding, opts)
 }
 
@@ -0,0 +1,461 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/aqua777/go-lancedb"
+	"github.com/stretchr/testify/suite"
+)
+
+// HybridTestSuite tests hybrid (vector + keyword) search
+type HybridTestSuite struct {
+	suite.Suite
+	store  *RAGStore
+	dbPath string
+	ctx    context.Context
+}
+
+// SetupTest runs before each test
+func (s *HybridTestSuite) SetupTest() {
+	tmpDir, err := os.MkdirTemp("", "rag_hybrid_test_*")
+	s.Require().NoError(err)
+	s.dbPath = filepath.Join(tmpDir, "test.db")
+	s.ctx = context.Background()
+
+	store, err := NewRAGStoreWithConfig(s.dbPath, 128, 100, &noopLogger{}, DefaultRetryConfig(), nil)
+	s.Require().NoError(err)
+	s.store = store
+}
+
+// TearDownTest runs after each test
+func (s *HybridTestSuite) TearDownTest() {
+	if s.store != nil {
+		s.store.Close()
+	}
+	if s.dbPath != "" {
+		os.RemoveAll(filepath.Dir(s.dbPath))
+	}
+}
+
+// TestHybridTestSuite runs the hybrid search test suite
+func TestHybridTestSuite(t *testing.T) {
+	suite.Run(t, new(HybridTestSuite))
+}
+
+func (s *HybridTestSuite) TestHybridSearchUsesTextIndex() {
+	docs := makeTestDocs(300, 128, "corpus.txt")
+	docs[42].Text = "the zebra crossing guide"
+	docs[137].Text = "zebra stripes and other patterns"
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user1", docs))
+
+	// More documents than in-memory BM25 allows: they are streamed instead of cached
+	s.store.SetMaxDocumentsForBM25(100)
+
+	query := docs[0].Embedding
+	_, err := s.store.HybridSearch(s.ctx, "user1", "zebra", query, nil)
+	s.Require().NoError(err)
+	s.Equal(0, s.store.bm25Cache.len())
+
+	s.Require().NoError(s.store.CreateTextIndex(s.ctx, "user1"))
+
+	results, err := s.store.HybridSearch(s.ctx, "user1", "zebra", query, &HybridSearchOptions{
+		Limit:         300,
+		VectorWeight:  0.5,
+		KeywordWeight: 0.5,
+	})
+	s.Require().NoError(err)
+
+	found := map[string]bool{}
+	for _, result := range results {
+		if _, ok := result.Metadata["keyword_rank"]; ok {
+			found[result.ID] = true
+		}
+	}
+	s.True(found[docs[42].ID])
+	s.True(found[docs[137].ID])
+	s.Len(found, 2, "only documents containing the keyword should have a keyword rank")
+}
+
+func (s *HybridTestSuite) TestCreateTextIndexInvalidUser() {
+	s.Error(s.store.CreateTextIndex(s.ctx, ""))
+	s.Error(s.store.CreateTextIndex(s.ctx, "missing_user"))
+}
+
+func (s *HybridTestSuite) TestKeywordSearchCachesCorpus() {
+	docs := makeTestDocs(20, 128, "corpus.txt")
+	docs[3].Text = "zebra crossing"
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user1", docs))
+
+	results, err := s.store.keywordSearch(s.ctx, "user1", "zebra", 5, nil)
+	s.Require().NoError(err)
+	s.Equal(docs[3].ID, results[0].ID)
+	s.Equal(1, s.store.bm25Cache.len())
+
+	// Modifying a result doesn't affect the cached corpus
+	results[0].Metadata["index"] = "changed"
+	results, err = s.store.keywordSearch(s.ctx, "user1", "zebra", 5, nil)
+	s.Require().NoError(err)
+	s.EqualValues(3, results[0].Metadata["index"])
+	s.Equal(1, s.store.bm25Cache.len())
+
+	// Writes invalidate the cache, so new documents are found
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user1", []Document{{
+		ID: "new", Text: "zebra zebra stripes", DocumentName: "new.txt", Embedding: make([]float32, 128),
+	}}))
+	s.Equal(0, s.store.bm25Cache.len())
+	results, err = s.store.keywordSearch(s.ctx, "user1", "zebra", 5, nil)
+	s.Require().NoError(err)
+	s.ElementsMatch([]string{docs[3].ID, "new"}, resultIDs(results[:2]))
+}
+
+func (s *HybridTestSuite) TestStreamingBM25MatchesInMemory() {
+	docs := makeTestDocs(300, 128, "corpus.txt")
+	corpus := bm25TestCorpus(len(docs))
+	for i := range docs {
+		docs[i].Text = corpus[i].Text
+		corpus[i].ID = docs[i].ID
+	}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user1", docs))
+	s.store.SetMaxDocumentsForBM25(100)
+
+	tokenizer := NewStemmingTokenizer()
+	for _, query := range []string{"zebra", "running reports", "the quick brown fox"} {
+		results, err := s.store.keywordSearch(s.ctx, "user1", query, 10, nil)
+		s.Require().NoError(err, "collections above the limit are streamed rather than refused")
+		s.Require().Len(results, 10)
+		s.Equal(0, s.store.bm25Cache.len())
+
+		expected := calculateBM25(corpus, tokenizer.Tokenize(query), tokenizer)
+		sort.SliceStable(expected, func(i, j int) bool { return expected[i].Score > expected[j].Score })
+		expectedScores := make(map[string]float32)
+		for _, result := range expected {
+			expectedScores[result.ID] = result.Score
+		}
+
+		for i, result := range results {
+			s.Equal(expected[i].Score, result.Score, "query %q rank %d", query, i)
+			s.Equal(expectedScores[result.ID], result.Score, "query %q doc %s", query, result.ID)
+			s.Len(result.Embedding, 128)
+		}
+	}
+
+	// Filters apply to both passes
+	other := makeTestDocs(5, 128, "other.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user1", other))
+	s.store.SetMaxDocumentsForBM25(2)
+	results, err := s.store.keywordSearch(s.ctx, "user1", "document", 10, map[string]interface{}{"document_name": "other.txt"})
+	s.Require().NoError(err)
+	s.Len(results, len(other))
+	for _, result := range results {
+		s.Equal("other.txt", result.DocumentName)
+	}
+}
+
+// HybridFusionTestSuite tests how vector and keyword results are combined
+type HybridFusionTestSuite struct {
+	suite.Suite
+	store *RAGStore
+}
+
+// TestHybridFusionTestSuite runs the fusion test suite
+func TestHybridFusionTestSuite(t *testing.T) {
+	suite.Run(t, new(HybridFusionTestSuite))
+}
+
+func (s *HybridFusionTestSuite) SetupTest() {
+	s.store = &RAGStore{}
+}
+
+// fusionInputs returns vector results (scores are distances) and keyword results (scores are BM25).
+// "both" appears in both lists but ranks second in each; "vec" and "kw" each top a single list.
+func fusionInputs() ([]SearchResult, []SearchResult) {
+	vector := []SearchResult{
+		{ID: "vec", Score: 0.1},
+		{ID: "both", Score: 0.2},
+		{ID: "vec_only", Score: 0.3},
+	}
+	keyword := []SearchResult{
+		{ID: "kw", Score: 10},
+		{ID: "both", Score: 1},
+	}
+	return vector, keyword
+}
+
+// rankOf returns the position of id in results, or -1
+func rankOf(results []SearchResult, id string) int {
+	for i, result := range results {
+		if result.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *HybridFusionTestSuite) TestRRFRanksSharedDocumentFirst() {
+	ctx := context.Background()
+
+	vector, keyword := fusionInputs()
+	weighted, err := s.store.fuseResults(ctx, vector, keyword, &HybridSearchOptions{
+		VectorWeight:  0.5,
+		KeywordWeight: 0.5,
+		FusionMethod:  FusionWeighted,
+		DistanceType:  lancedb.DistanceTypeCosine,
+	})
+	s.Require().NoError(err)
+
+	vector, keyword = fusionInputs()
+	rrf, err := s.store.fuseResults(ctx, vector, keyword, &HybridSearchOptions{
+		FusionMethod: FusionRRF,
+	})
+	s.Require().NoError(err)
+
+	s.Len(weighted, 4)
+	s.Len(rrf, 4)
+
+	// Weighted scoring lets the keyword-only top hit outrank the shared document
+	s.Less(rankOf(weighted, "kw"), rankOf(weighted, "both"))
+
+	// RRF rewards appearing in both lists
+	s.Equal("both", rrf[0].ID)
+	s.Less(rankOf(rrf, "both"), rankOf(rrf, "kw"))
+	s.InDelta(2.0/62.0, rrf[0].Score, 1e-6)
+}
+
+func (s *HybridFusionTestSuite) TestNormalizeScoreMonotonic() {
+	query := []float32{1, 0, 0}
+	// Ordered from closest to farthest from the query under every metric
+	vectors := [][]float32{
+		{1, 0, 0},
+		{0.9, 0.1, 0},
+		{0.8, 0.3, 0},
+		{0.6, 0.5, 0.2},
+		{0.2, 0.8, 0.5},
+		{0, 1, 1},
+	}
+	distances := func(dt lancedb.DistanceType, v []float32) float32 {
+		var dot, l2, norm float32
+		for i := range query {
+			dot += query[i] * v[i]
+			l2 += (query[i] - v[i]) * (query[i] - v[i])
+			norm += v[i] * v[i]
+		}
+		switch dt {
+		case lancedb.DistanceTypeL2:
+			return l2
+		case lancedb.DistanceTypeDot:
+			return 1 - dot
+		default:
+			return 1 - dot/float32(math.Sqrt(float64(norm)))
+		}
+	}
+
+	for _, dt := range []lancedb.DistanceType{lancedb.DistanceTypeCosine, lancedb.DistanceTypeL2, lancedb.DistanceTypeDot} {
+		prev := float32(2)
+		for i, v := range vectors {
+			score := NormalizeScore(dt, distances(dt, v))
+			s.GreaterOrEqual(score, float32(0), "metric %d vector %d", dt, i)
+			s.LessOrEqual(score, float32(1), "metric %d vector %d", dt, i)
+			s.Less(score, prev, "metric %d vector %d", dt, i)
+			prev = score
+		}
+	}
+
+	s.Equal(float32(1), NormalizeScore(lancedb.DistanceTypeCosine, 0))
+	s.Equal(float32(0), NormalizeScore(lancedb.DistanceTypeCosine, 2))
+	// Orthogonal vectors sit halfway, and opposing ones still rank by their angle
+	s.Equal(float32(0.5), NormalizeScore(lancedb.DistanceTypeCosine, 1))
+	s.Greater(NormalizeScore(lancedb.DistanceTypeCosine, 1.5), NormalizeScore(lancedb.DistanceTypeCosine, 1.9))
+	s.Equal(float32(1), NormalizeScore(lancedb.DistanceTypeL2, 0))
+	s.InDelta(0.5, NormalizeScore(lancedb.DistanceTypeDot, 1), 1e-6)
+}
+
+func (s *HybridFusionTestSuite) TestRecordTextsByName() {
+	// The text column isn't first, and is dictionary encoded
+	dictType := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.BinaryTypes.String},
+		{Name: "text", Type: dictType},
+	}, nil)
+
+	ids := array.NewStringBuilder(memory.DefaultAllocator)
+	defer ids.Release()
+	ids.AppendValues([]string{"a", "b", "c"}, nil)
+	texts := array.NewDictionaryBuilder(memory.DefaultAllocator, dictType).(*array.BinaryDictionaryBuilder)
+	defer texts.Release()
+	for _, text := range []string{"red fox", "blue fox", "red fox"} {
+		s.Require().NoError(texts.AppendString(text))
+	}
+
+	idArr, textArr := ids.NewArray(), texts.NewArray()
+	defer idArr.Release()
+	defer textArr.Release()
+	record := array.NewRecord(schema, []arrow.Array{idArr, textArr}, 3)
+	defer record.Release()
+
+	values, err := recordTexts(record)
+	s.Require().NoError(err)
+	s.Equal([]string{"red fox", "blue fox", "red fox"}, values)
+
+	noText := array.NewRecord(arrow.NewSchema(schema.Fields()[:1], nil), []arrow.Array{idArr}, 3)
+	defer noText.Release()
+	_, err = recordTexts(noText)
+	s.Error(err)
+}
+
+func (s *HybridFusionTestSuite) TestWeightedFusionL2() {
+	// Squared L2 distances above 1 used to be inverted to negative scores and ignored
+	vector := []SearchResult{
+		{ID: "near", Score: 4},
+		{ID: "mid", Score: 9},
+		{ID: "far", Score: 16},
+	}
+	results, err := s.store.fuseResults(context.Background(), vector, nil, &HybridSearchOptions{
+		VectorWeight:  1,
+		KeywordWeight: 0,
+		FusionMethod:  FusionWeighted,
+		DistanceType:  lancedb.DistanceTypeL2,
+	})
+	s.Require().NoError(err)
+	s.Equal([]string{"near", "mid", "far"}, resultIDs(results))
+	s.InDelta(1, results[0].Score, 1e-6)
+	s.Greater(results[1].Score, results[2].Score)
+	s.Greater(results[2].Score, float32(0))
+}
+
+// blockingSearch returns a search that waits for ctx to be cancelled
+func blockingSearch(started chan<- struct{}) func(context.Context) ([]SearchResult, error) {
+	return func(ctx context.Context) ([]SearchResult, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+}
+
+func (s *HybridFusionTestSuite) TestPartialResultsOnCancel() {
+	vector, _ := fusionInputs()
+	vectorSearch := func(ctx context.Context) ([]SearchResult, error) {
+		return vector, nil
+	}
+
+	for _, partial := range []bool{true, false} {
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		vectorDone := make(chan struct{})
+		go func() {
+			// Cancel once the vector search has finished and the keyword search is running
+			<-started
+			<-vectorDone
+			cancel()
+		}()
+
+		vectorResults, keywordResults, err := runHybridSearches(ctx, partial,
+			func(ctx context.Context) ([]SearchResult, error) {
+				defer close(vectorDone)
+				return vectorSearch(ctx)
+			},
+			blockingSearch(started))
+
+		if partial {
+			s.Require().NoError(err)
+			s.Equal([]string{"vec", "both", "vec_only"}, resultIDs(vectorResults))
+			s.Nil(keywordResults)
+		} else {
+			s.ErrorIs(err, context.Canceled)
+			s.Nil(vectorResults)
+		}
+		cancel()
+	}
+}
+
+func (s *HybridFusionTestSuite) TestSearchErrorCancelsOther() {
+	started := make(chan struct{})
+	failure := errors.New("vector failure")
+	_, _, err := runHybridSearches(context.Background(), true,
+		func(ctx context.Context) ([]SearchResult, error) {
+			<-started
+			return nil, failure
+		},
+		blockingSearch(started))
+
+	// Errors other than the caller cancelling are returned even with partial results enabled
+	s.ErrorIs(err, failure)
+}
+
+func (s *HybridFusionTestSuite) TestRRFCustomK() {
+	vector, keyword := fusionInputs()
+	results, err := s.store.fuseResults(context.Background(), vector, keyword, &HybridSearchOptions{
+		FusionMethod: FusionRRF,
+		RRFK:         10,
+	})
+	s.Require().NoError(err)
+	s.Equal("both", results[0].ID)
+	s.InDelta(2.0/12.0, results[0].Score, 1e-6)
+}
+
+func (s *HybridFusionTestSuite) TestUnknownFusionMethod() {
+	_, err := s.store.HybridSearch(context.Background(), "user1", "query", []float32{0.1}, &HybridSearchOptions{
+		Limit:        10,
+		FusionMethod: FusionMethod(99),
+	})
+	s.Error(err)
+}
+
+func (s *HybridFusionTestSuite) TestRankMetadata() {
+	shared := map[string]interface{}{"source": "shared"}
+	vector := []SearchResult{
+		{ID: "a", Score: 0.1, Metadata: shared},
+		{ID: "b", Score: 0.2, Metadata: map[string]interface{}{"source": "vector"}},
+		{ID: "c", Score: 0.3, Metadata: shared},
+	}
+	keyword := []SearchResult{
+		{ID: "d", Score: 5, Metadata: nil},
+		{ID: "b", Score: 3, Metadata: map[string]interface{}{"source": "keyword"}},
+	}
+
+	for _, method := range []FusionMethod{FusionWeighted, FusionRRF} {
+		results, err := s.store.fuseResults(context.Background(), vector, keyword, &HybridSearchOptions{
+			VectorWeight:  0.5,
+			KeywordWeight: 0.5,
+			FusionMethod:  method,
+		})
+		s.Require().NoError(err)
+		s.Require().Len(results, 4)
+
+		byID := map[string]SearchResult{}
+		for _, result := range results {
+			byID[result.ID] = result
+		}
+
+		// Present in both lists: both ranks are set
+		s.Equal(1, byID["b"].Metadata["vector_rank"])
+		s.Equal(1, byID["b"].Metadata["keyword_rank"])
+		s.Equal("vector", byID["b"].Metadata["source"])
+
+		// Present in one list: only that rank is set
+		s.Equal(0, byID["a"].Metadata["vector_rank"])
+		s.NotContains(byID["a"].Metadata, "keyword_rank")
+		s.Equal(2, byID["c"].Metadata["vector_rank"])
+		s.NotContains(byID["c"].Metadata, "keyword_rank")
+		s.Equal(0, byID["d"].Metadata["keyword_rank"])
+		s.NotContains(byID["d"].Metadata, "vector_rank")
+
+		// Results sharing an input metadata map don't leak ranks into each other
+		byID["a"].Metadata["extra"] = true
+		s.NotContains(byID["c"].Metadata, "extra")
+	}
+
+	// Inputs are left untouched
+	s.Equal(map[string]interface{}{"source": "shared"}, shared)
+	s.Equal(map[string]interface{}{"source": "vector"}, vector[1].Metadata)
+	s.Equal(map[string]interface{}{"source": "keyword"}, keyword[1].Metadata)
+	s.Nil(keyword[0].Metadata)
+}
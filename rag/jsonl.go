@@ -0,0 +1,337 @@
+package rag
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+)
+
+// jsonlDocument is one line of a JSON-lines document file
+type jsonlDocument struct {
+	Text         string                 `json:"text"`
+	DocumentName string                 `json:"document_name"`
+	Metadata     map[string]interface{} `json:"metadata"`
+}
+
+// MalformedLineError reports a line of a JSON-lines file that couldn't be ingested
+type MalformedLineError struct {
+	Path string // File the line was read from
+	Line int    // Line number, counted from 1
+	Err  error  // Why the line is malformed
+}
+
+func (e *MalformedLineError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+}
+
+// Unwrap returns why the line is malformed
+func (e *MalformedLineError) Unwrap() error {
+	return e.Err
+}
+
+// JSONLIngestConfig configures AddDocumentsFromJSONLWithConfig
+type JSONLIngestConfig struct {
+	BatchSize     int                               // Lines per GenerateEmbeddings call (default: 100)
+	Concurrency   int                               // Maximum GenerateEmbeddings calls in flight (default: 1)
+	SkipMalformed bool                              // Log and skip malformed lines instead of failing
+	OnMalformed   func(lineErr *MalformedLineError) // Called for each skipped line; may be nil
+}
+
+// AddDocumentsFromJSONL adds the documents of a JSON-lines file, one
+// {"text", "document_name", "metadata"} object per line, generating their embeddings
+// with provider. A malformed line fails the ingestion before anything is added; see
+// AddDocumentsFromJSONLWithConfig to skip them instead. Returns the number of documents added.
+func (s *RAGStore) AddDocumentsFromJSONL(ctx context.Context, userID string, path string, provider EmbeddingProvider) (int, error) {
+	return s.AddDocumentsFromJSONLWithConfig(ctx, userID, path, provider, nil)
+}
+
+// AddDocumentsFromJSONLWithConfig adds the documents of a JSON-lines file like
+// AddDocumentsFromJSONL. The file is streamed: lines are embedded and inserted
+// config.BatchSize × config.Concurrency at a time, so files larger than memory can be
+// ingested. Blank lines are ignored. A line is malformed if it isn't a JSON object, or its
+// text or document_name is empty; the error is a *MalformedLineError with its line number.
+// Unless config.SkipMalformed is set, the whole file is checked before any line is
+// embedded, so a malformed line fails the ingestion without adding anything.
+//
+// Each line's ID is DocumentID(documentName, n). Unlike AddDocumentsWithEmbedding, which
+// numbers every call's texts from 0, n continues after the highest chunk index already
+// stored for that name, so ingesting more chunks of a document, from this file or another,
+// never reuses an existing ID. Ingesting the same file twice therefore stores its documents
+// twice, under new IDs.
+//
+// Documents that fail to embed or insert stop the ingestion, but batches added before the
+// failure stay added, and the returned count includes them. A nil config embeds one batch
+// of 100 lines at a time and fails on malformed lines.
+func (s *RAGStore) AddDocumentsFromJSONLWithConfig(ctx context.Context, userID string, path string, provider EmbeddingProvider, config *JSONLIngestConfig) (int, error) {
+	if config == nil {
+		config = &JSONLIngestConfig{}
+	}
+	if err := s.validateUserID(userID); err != nil {
+		return 0, err
+	}
+	if err := s.checkEmbeddingProvider(ctx, userID, provider); err != nil {
+		return 0, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open JSON-lines file: %w", err)
+	}
+	defer file.Close()
+
+	// Check every line first, so a malformed line fails before anything is added
+	if !config.SkipMalformed {
+		err := readJSONLines(ctx, file, path, func(_ jsonlDocument, lineErr *MalformedLineError) error {
+			if lineErr != nil {
+				return lineErr
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return 0, fmt.Errorf("failed to rewind %s: %w", path, err)
+		}
+	}
+
+	batchSize := config.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultEmbeddingBatchSize
+	}
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	chunkSize := batchSize * concurrency
+	model := s.providerEmbeddingModel(provider)
+
+	added := 0
+	chunkIndexes := make(map[string]int)
+	pending := make([]jsonlDocument, 0, chunkSize)
+
+	// flush embeds and inserts the pending lines
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		texts := make([]string, len(pending))
+		for i, line := range pending {
+			texts[i] = line.Text
+		}
+		embeddings, err := generateEmbeddingBatches(ctx, provider, texts, batchSize, concurrency, nil)
+		if err != nil {
+			return err
+		}
+
+		docs := make([]Document, len(pending))
+		for i, line := range pending {
+			metadata := line.Metadata
+			if metadata == nil {
+				metadata = map[string]interface{}{}
+			}
+			docs[i] = Document{
+				Text:         line.Text,
+				DocumentName: line.DocumentName,
+				Embedding:    embeddings[i],
+				Metadata:     metadata,
+			}
+		}
+
+		// The chunks are numbered under the user's lock, so concurrent ingestions can't
+		// take the same IDs; the counters only advance once the documents are added
+		var next map[string]int
+		assignIDs := func(docs []Document) error {
+			var err error
+			next, err = s.numberChunks(ctx, userID, docs, chunkIndexes)
+			return err
+		}
+		if err := s.addDocuments(ctx, userID, docs, model, false, assignIDs, nil); err != nil {
+			var partial *PartialWriteError
+			if errors.As(err, &partial) {
+				added += partial.Committed
+			}
+			return err
+		}
+		added += len(docs)
+		chunkIndexes = next
+		pending = pending[:0]
+		return nil
+	}
+
+	err = readJSONLines(ctx, file, path, func(doc jsonlDocument, lineErr *MalformedLineError) error {
+		if lineErr != nil {
+			if !config.SkipMalformed {
+				return lineErr // The file changed since it was checked
+			}
+			s.logger.Printf("Skipping malformed line %s", lineErr)
+			if config.OnMalformed != nil {
+				config.OnMalformed(lineErr)
+			}
+			return nil
+		}
+
+		pending = append(pending, doc)
+		if len(pending) == chunkSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return added, err
+	}
+
+	if err := flush(); err != nil {
+		return added, err
+	}
+	return added, nil
+}
+
+// readJSONLines parses each non-blank line of r, read from path, and calls fn with the
+// document or, if the line is malformed, with why. Reading stops at the first error fn
+// returns, or once ctx is done.
+func readJSONLines(ctx context.Context, r io.Reader, path string, fn func(doc jsonlDocument, lineErr *MalformedLineError) error) error {
+	reader := bufio.NewReader(r)
+	for lineNumber := 1; ; lineNumber++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		raw, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("failed to read %s: %w", path, readErr)
+		}
+
+		if line := bytes.TrimSpace(raw); len(line) > 0 {
+			doc, err := parseJSONLDocument(line)
+			var lineErr *MalformedLineError
+			if err != nil {
+				lineErr = &MalformedLineError{Path: path, Line: lineNumber, Err: err}
+			}
+			if err := fn(doc, lineErr); err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
+
+// numberChunks sets the ID of each of docs to DocumentID(documentName, n), numbering each
+// document name's chunks on from its counter in next or, for names not in next, from after
+// the highest chunk index among the user's stored IDs. It returns the advanced counters and
+// leaves next unchanged. The caller holds the user's lock.
+func (s *RAGStore) numberChunks(ctx context.Context, userID string, docs []Document, next map[string]int) (map[string]int, error) {
+	counters := make(map[string]int, len(next))
+	for name, index := range next {
+		counters[name] = index
+	}
+
+	var unseen []string
+	for _, doc := range docs {
+		if _, ok := counters[doc.DocumentName]; !ok {
+			counters[doc.DocumentName] = 0
+			unseen = append(unseen, doc.DocumentName)
+		}
+	}
+	if len(unseen) > 0 {
+		stored, err := s.storedChunkIndexes(ctx, userID, unseen)
+		if err != nil {
+			return nil, err
+		}
+		for name, index := range stored {
+			counters[name] = index
+		}
+	}
+
+	for i := range docs {
+		name := docs[i].DocumentName
+		docs[i].ID = DocumentID(name, counters[name])
+		counters[name]++
+	}
+	return counters, nil
+}
+
+// storedChunkIndexes returns the chunk index following the highest one among the user's
+// stored IDs of the form DocumentID(name, n), for each of names that has any. The IDs are
+// read with one scan per idPredicateBatchSize names.
+func (s *RAGStore) storedChunkIndexes(ctx context.Context, userID string, names []string) (map[string]int, error) {
+	next := make(map[string]int)
+	exists, err := s.TableExists(ctx, userID)
+	if err != nil || !exists {
+		return next, err
+	}
+
+	table, release, err := s.acquireTable(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	defer release()
+
+	for start := 0; start < len(names); start += idPredicateBatchSize {
+		end := start + idPredicateBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+		literals := make([]string, end-start)
+		for i, name := range names[start:end] {
+			literals[i] = "'" + escapeSQLString(name) + "'"
+		}
+		predicate := fmt.Sprintf("document_name IN (%s)", strings.Join(literals, ", "))
+
+		err := streamRecords(ctx, table, predicate, []string{"id", "document_name"}, func(record arrow.Record) error {
+			ids, err := recordStrings(record, "id")
+			if err != nil {
+				return err
+			}
+			docNames, err := recordStrings(record, "document_name")
+			if err != nil {
+				return err
+			}
+			for i, id := range ids {
+				prefix := DocumentID(docNames[i], 0)
+				prefix = prefix[:len(prefix)-1]
+				if !strings.HasPrefix(id, prefix) {
+					continue
+				}
+				n, err := strconv.Atoi(id[len(prefix):])
+				if err != nil || n < next[docNames[i]] {
+					continue
+				}
+				// Copy the name out of the record's buffer, which is freed after fn returns
+				next[string([]byte(docNames[i]))] = n + 1
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stored chunk IDs: %w", err)
+		}
+	}
+	return next, nil
+}
+
+// parseJSONLDocument decodes and checks one non-blank line
+func parseJSONLDocument(line []byte) (jsonlDocument, error) {
+	var doc jsonlDocument
+	if err := json.Unmarshal(line, &doc); err != nil {
+		return doc, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if doc.Text == "" {
+		return doc, fmt.Errorf("missing text")
+	}
+	if doc.DocumentName == "" {
+		return doc, fmt.Errorf("missing document_name")
+	}
+	return doc, nil
+}
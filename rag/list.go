@@ -0,0 +1,218 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// ListByFilter returns a page of a user's documents matching filter, along with the total
+// number of matches. Unlike Search it involves no query embedding, which suits browsing
+// documents in admin tools. Results have no Score.
+//
+// Filter keys naming a column (id, text, document_name) are matched by the query; any other
+// key is matched against the metadata field of that name. Values must be strings, integers,
+// floats or bools, and a document must match every key. A nil or empty filter lists all
+// documents.
+//
+// Documents are returned in ID order, and each call reads a single table version for both
+// the page and the count, so consecutive pages neither overlap nor skip documents while the
+// table is unchanged. Filters on metadata fields are applied while reading, so their pages
+// scan every document matching the column filters.
+func (s *RAGStore) ListByFilter(ctx context.Context, userID string, filter map[string]interface{}, offset, limit int) ([]SearchResult, int64, error) {
+	if err := s.validateUserID(userID); err != nil {
+		return nil, 0, err
+	}
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("offset must be non-negative, got %d", offset)
+	}
+	if limit <= 0 {
+		limit = 100 // default page size
+	}
+
+	columnFilters := make(map[string]interface{})
+	metadataFilters := make(map[string]interface{})
+	for key, value := range filter {
+		switch value.(type) {
+		case string, int, int32, int64, float32, float64, bool:
+		default:
+			return nil, 0, fmt.Errorf("unsupported filter value type %T for key %q", value, key)
+		}
+		if isValidFilterKey(key) && key != "metadata" {
+			columnFilters[key] = value
+		} else {
+			metadataFilters[key] = value
+		}
+	}
+
+	// Check for context cancellation
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	default:
+	}
+
+	exists, err := s.TableExists(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !exists {
+		return []SearchResult{}, 0, nil
+	}
+
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	defer table.Close()
+
+	// Pin the handle to the current version so the count and the page agree
+	version, err := table.Version()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get table version: %w", err)
+	}
+	if err := table.Checkout(version); err != nil {
+		return nil, 0, fmt.Errorf("failed to check out table version %d: %w", version, err)
+	}
+
+	dim := s.userEmbeddingDim(userID)
+	codec := s.getMetadataCodec()
+	predicate := buildPredicate(columnFilters)
+
+	query := table.Query().Select(documentColumns(s.userEmbeddingStorage(userID))...).OrderBy("id", true)
+	defer query.Close()
+	if predicate != "" {
+		query = query.Where(predicate)
+	}
+
+	// Without metadata filters the page and the count are both computed by LanceDB
+	if len(metadataFilters) == 0 {
+		var total int64
+		if predicate != "" {
+			total, err = table.CountRowsWhere(predicate)
+		} else {
+			total, err = table.CountRows()
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count documents: %w", err)
+		}
+
+		records, err := query.Offset(offset).Limit(limit).ExecuteContext(ctx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to list documents: %w", err)
+		}
+		defer func() {
+			for _, record := range records {
+				record.Release()
+			}
+		}()
+
+		page := make([]SearchResult, 0, limit)
+		for _, record := range records {
+			batch, err := parseSearchResults(record, dim, codec)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to parse documents: %w", err)
+			}
+			page = append(page, batch...)
+		}
+		return page, total, nil
+	}
+
+	iter, err := query.ExecuteStreaming()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer iter.Close()
+
+	page := make([]SearchResult, 0, limit)
+	var total int64
+	for {
+		// Check for context cancellation between batches
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		default:
+		}
+
+		record, err := iter.Next()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read documents: %w", err)
+		}
+		if record == nil {
+			break // End of stream
+		}
+
+		batch, err := parseSearchResults(record, dim, codec)
+		record.Release()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to parse documents: %w", err)
+		}
+
+		for _, result := range batch {
+			if !matchesMetadata(result.Metadata, metadataFilters) {
+				continue
+			}
+			if total >= int64(offset) && len(page) < limit {
+				page = append(page, result)
+			}
+			total++
+		}
+	}
+
+	return page, total, nil
+}
+
+// matchesMetadata reports whether metadata has every key in filters with an equal value.
+// Numbers are compared by value, so an int filter matches a float64 decoded from JSON.
+func matchesMetadata(metadata, filters map[string]interface{}) bool {
+	for key, want := range filters {
+		got, ok := metadata[key]
+		if !ok {
+			return false
+		}
+		wantNum, wantIsNum := filterNumber(want)
+		gotNum, gotIsNum := filterNumber(got)
+		if wantIsNum || gotIsNum {
+			if !wantIsNum || !gotIsNum || wantNum != gotNum {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterNumber converts a numeric value to float64
+func filterNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
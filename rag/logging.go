@@ -1,7 +1,9 @@
 package rag
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -279,3 +281,97 @@ func (l *FileLogger) Flush() error {
 	return nil
 }
 
+// OperationEvent is a structured record of a completed store operation
+type OperationEvent struct {
+	Level       LogLevel      // LogLevelError if the operation failed, otherwise LogLevelInfo
+	Operation   string        // Operation name, e.g. "rag.AddDocuments"
+	UserID      string        // User the operation ran for
+	DocCount    int           // Documents written or deleted; zero for other operations
+	ResultCount int           // Results returned by a search; zero for other operations
+	Duration    time.Duration // Time taken by the operation
+	Err         error         // Why the operation failed, if it did
+}
+
+// StructuredLogger is a Logger that also receives a structured event at the end of each
+// store operation (adding, upserting and deleting documents, searches, and index builds).
+// Stores given a plain Logger log each event as a formatted line instead.
+type StructuredLogger interface {
+	Logger
+	LogOperation(event OperationEvent)
+}
+
+// String formats the event as a single key=value line
+func (e OperationEvent) String() string {
+	line := fmt.Sprintf("%s user=%s docs=%d results=%d duration=%s",
+		e.Operation, e.UserID, e.DocCount, e.ResultCount, e.Duration)
+	if e.Err != nil {
+		line += fmt.Sprintf(" error=%q", e.Err.Error())
+	}
+	return line
+}
+
+// logOperation sends an event to logger, formatting it for loggers that aren't structured
+func logOperation(logger Logger, event OperationEvent) {
+	if structured, ok := logger.(StructuredLogger); ok {
+		structured.LogOperation(event)
+		return
+	}
+	logger.Printf("%s", event)
+}
+
+// LogOperation implements StructuredLogger, logging the event at its level
+func (l *FileLogger) LogOperation(event OperationEvent) {
+	l.log(event.Level, "%s", []interface{}{event})
+}
+
+// JSONLogger is a StructuredLogger that writes one JSON object per line, for ingestion
+// into log aggregators. Messages are logged at info level with a "msg" field; operation
+// events carry their fields, with the duration in milliseconds.
+type JSONLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLogger creates a JSONLogger writing to w
+func NewJSONLogger(w io.Writer) *JSONLogger {
+	return &JSONLogger{w: w}
+}
+
+// Printf implements Logger interface
+func (l *JSONLogger) Printf(format string, v ...interface{}) {
+	l.write(map[string]interface{}{"level": LogLevelInfo.String(), "msg": fmt.Sprintf(format, v...)})
+}
+
+// Println implements Logger interface
+func (l *JSONLogger) Println(v ...interface{}) {
+	l.write(map[string]interface{}{"level": LogLevelInfo.String(), "msg": fmt.Sprint(v...)})
+}
+
+// LogOperation implements StructuredLogger
+func (l *JSONLogger) LogOperation(event OperationEvent) {
+	entry := map[string]interface{}{
+		"level":        event.Level.String(),
+		"operation":    event.Operation,
+		"user_id":      event.UserID,
+		"doc_count":    event.DocCount,
+		"result_count": event.ResultCount,
+		"duration_ms":  float64(event.Duration) / float64(time.Millisecond),
+	}
+	if event.Err != nil {
+		entry["error"] = event.Err.Error()
+	}
+	l.write(entry)
+}
+
+// write encodes entry as a line, adding the time
+func (l *JSONLogger) write(entry map[string]interface{}) {
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(append(line, '\n'))
+}
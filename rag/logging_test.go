@@ -1,10 +1,16 @@
 package rag
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -263,3 +269,120 @@ func (s *LoggingTestSuite) TestFileLoggerConcurrentWrites() {
 	s.Equal(50, messageCount)
 }
 
+// eventLogger is a StructuredLogger that keeps every operation event
+type eventLogger struct {
+	noopLogger
+	mu     sync.Mutex
+	events []OperationEvent
+}
+
+func (l *eventLogger) LogOperation(event OperationEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+// find returns the events of the named operation
+func (l *eventLogger) find(operation string) []OperationEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var found []OperationEvent
+	for _, event := range l.events {
+		if event.Operation == operation {
+			found = append(found, event)
+		}
+	}
+	return found
+}
+
+func (s *LoggingTestSuite) TestOperationSpanLogsEvent() {
+	logger := &eventLogger{}
+	store := &RAGStore{logger: newSwappableLogger(logger), tracer: &noopTracer{}}
+
+	_, span := store.startSpan(context.Background(), "rag.AddDocuments", "alice")
+	span.SetAttribute(SpanAttrDocumentCount, 7)
+	time.Sleep(time.Millisecond)
+	endSpan(span, nil)
+
+	_, span = store.startSpan(context.Background(), "rag.Search", "bob")
+	span.SetAttribute(SpanAttrResultCount, 3)
+	endSpan(span, errors.New("boom"))
+
+	added := logger.find("rag.AddDocuments")
+	s.Require().Len(added, 1)
+	s.Equal("alice", added[0].UserID)
+	s.Equal(7, added[0].DocCount)
+	s.Equal(LogLevelInfo, added[0].Level)
+	s.GreaterOrEqual(added[0].Duration, time.Millisecond)
+	s.NoError(added[0].Err)
+
+	searched := logger.find("rag.Search")
+	s.Require().Len(searched, 1)
+	s.Equal(3, searched[0].ResultCount)
+	s.Equal(LogLevelError, searched[0].Level)
+	s.EqualError(searched[0].Err, "boom")
+}
+
+func (s *LoggingTestSuite) TestOperationEventFallsBackToPrintf() {
+	logger := &recordingLogger{}
+	logOperation(logger, OperationEvent{
+		Operation: "rag.DeleteByDocumentName",
+		UserID:    "alice",
+		DocCount:  2,
+		Duration:  1500 * time.Microsecond,
+		Err:       errors.New("table is closed"),
+	})
+	s.Equal(1, logger.count(`rag.DeleteByDocumentName user=alice docs=2 results=0 duration=1.5ms error="table is closed"`))
+
+	// The store's swappable logger forwards events to structured loggers as they are
+	events := &eventLogger{}
+	logOperation(newSwappableLogger(events), OperationEvent{Operation: "rag.Search"})
+	s.Len(events.find("rag.Search"), 1)
+}
+
+func (s *LoggingTestSuite) TestJSONLogger() {
+	var buf bytes.Buffer
+	logger := NewJSONLogger(&buf)
+	logger.Printf("hello %s", "world")
+	logger.LogOperation(OperationEvent{
+		Level:     LogLevelError,
+		Operation: "rag.AddDocuments",
+		UserID:    "alice",
+		DocCount:  4,
+		Duration:  2 * time.Millisecond,
+		Err:       errors.New("quota exceeded"),
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	s.Require().Len(lines, 2)
+
+	var message map[string]interface{}
+	s.Require().NoError(json.Unmarshal([]byte(lines[0]), &message))
+	s.Equal("INFO", message["level"])
+	s.Equal("hello world", message["msg"])
+	s.NotEmpty(message["time"])
+
+	var event map[string]interface{}
+	s.Require().NoError(json.Unmarshal([]byte(lines[1]), &event))
+	s.Equal("ERROR", event["level"])
+	s.Equal("rag.AddDocuments", event["operation"])
+	s.Equal("alice", event["user_id"])
+	s.Equal(float64(4), event["doc_count"])
+	s.Equal(float64(2), event["duration_ms"])
+	s.Equal("quota exceeded", event["error"])
+}
+
+func (s *LoggingTestSuite) TestFileLoggerLogOperation() {
+	logPath := filepath.Join(s.tmpDir, "events.log")
+	logger, err := NewFileLogger(&FileLoggerConfig{Path: logPath, MinLevel: LogLevelInfo})
+	s.Require().NoError(err)
+	defer logger.Close()
+
+	logger.LogOperation(OperationEvent{Level: LogLevelError, Operation: "rag.Search", UserID: "alice", Err: errors.New("boom")})
+	s.Require().NoError(logger.Flush())
+
+	content, err := os.ReadFile(logPath)
+	s.Require().NoError(err)
+	s.Contains(string(content), "[ERROR] rag.Search user=alice")
+	s.Contains(string(content), `error="boom"`)
+}
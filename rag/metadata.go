@@ -3,8 +3,59 @@ package rag
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
+// MetadataCodec serializes document metadata into the table's metadata column.
+// Encoded values must be valid UTF-8, since the column is a string column.
+type MetadataCodec interface {
+	// Encode serializes a metadata map
+	Encode(meta map[string]interface{}) (string, error)
+
+	// Decode deserializes a value produced by Encode
+	Decode(data string) (map[string]interface{}, error)
+}
+
+// JSONMetadataCodec stores metadata as JSON (the default).
+// Integers decode as int64 and other numbers as float64.
+type JSONMetadataCodec struct{}
+
+// Encode serializes metadata to a JSON string
+func (JSONMetadataCodec) Encode(meta map[string]interface{}) (string, error) {
+	return encodeMetadata(meta)
+}
+
+// Decode deserializes metadata, accepting both JSON and MessagePack encoded values
+// so tables written with either codec stay readable
+func (JSONMetadataCodec) Decode(data string) (map[string]interface{}, error) {
+	if strings.HasPrefix(data, msgpackMetadataPrefix) {
+		return decodeMsgpackMetadata(data)
+	}
+	return decodeMetadata(data)
+}
+
+// SetMetadataCodec sets the codec used to encode metadata of newly written documents
+// and to decode metadata in search results. Pass nil to restore the default JSON codec.
+// Both built-in codecs read values written by either one, so the codec can be changed
+// on an existing table.
+func (s *RAGStore) SetMetadataCodec(codec MetadataCodec) {
+	s.mu.Lock()
+	s.metadataCodec = codec
+	s.mu.Unlock()
+}
+
+// getMetadataCodec returns the configured metadata codec, or JSON if none is set
+func (s *RAGStore) getMetadataCodec() MetadataCodec {
+	s.mu.RLock()
+	codec := s.metadataCodec
+	s.mu.RUnlock()
+
+	if codec == nil {
+		return JSONMetadataCodec{}
+	}
+	return codec
+}
+
 // encodeMetadata serializes a metadata map to JSON string
 func encodeMetadata(meta map[string]interface{}) (string, error) {
 	if meta == nil {
@@ -19,17 +70,54 @@ func encodeMetadata(meta map[string]interface{}) (string, error) {
 	return string(bytes), nil
 }
 
-// decodeMetadata deserializes a JSON string to metadata map
+// decodeMetadata deserializes a JSON string to metadata map.
+// Integers decode as int64 rather than float64, so they round-trip with their type.
 func decodeMetadata(jsonStr string) (map[string]interface{}, error) {
 	if jsonStr == "" {
 		return make(map[string]interface{}), nil
 	}
 
+	decoder := json.NewDecoder(strings.NewReader(jsonStr))
+	decoder.UseNumber()
+
 	var meta map[string]interface{}
-	if err := json.Unmarshal([]byte(jsonStr), &meta); err != nil {
+	if err := decoder.Decode(&meta); err != nil {
 		return nil, fmt.Errorf("failed to decode metadata: %w", err)
 	}
+	if decoder.More() {
+		return nil, fmt.Errorf("failed to decode metadata: unexpected data after JSON object")
+	}
+	if meta == nil {
+		return make(map[string]interface{}), nil
+	}
 
+	for key, value := range meta {
+		meta[key] = normalizeJSONNumbers(value)
+	}
 	return meta, nil
 }
 
+// normalizeJSONNumbers replaces json.Number values, including inside nested maps and arrays,
+// with int64 when the number is an integer that fits and float64 otherwise
+func normalizeJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64() // the decoder only produces valid numbers
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeJSONNumbers(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeJSONNumbers(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
@@ -0,0 +1,238 @@
+package rag
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// MetadataCodecTestSuite tests the JSON and MessagePack metadata codecs
+type MetadataCodecTestSuite struct {
+	suite.Suite
+}
+
+func TestMetadataCodecSuite(t *testing.T) {
+	suite.Run(t, new(MetadataCodecTestSuite))
+}
+
+func (s *MetadataCodecTestSuite) TestMessagePackPreservesTypes() {
+	codec := MessagePackMetadataCodec{}
+	meta := map[string]interface{}{
+		"page":     42,
+		"negative": -70000,
+		"big":      int64(math.MaxInt64),
+		"huge":     uint64(math.MaxUint64),
+		"ratio":    0.25,
+		"single":   float32(1.5),
+		"flag":     true,
+		"missing":  nil,
+		"title":    strings.Repeat("long title ", 10),
+		"raw":      []byte{0, 1, 2},
+		"tags":     []string{"a", "b"},
+		"nested":   map[string]interface{}{"depth": 2, "items": []interface{}{1, "two", 3.5}},
+	}
+
+	encoded, err := codec.Encode(meta)
+	s.Require().NoError(err)
+	s.True(strings.HasPrefix(encoded, msgpackMetadataPrefix))
+
+	decoded, err := codec.Decode(encoded)
+	s.Require().NoError(err)
+	s.Equal(int64(42), decoded["page"])
+	s.Equal(int64(-70000), decoded["negative"])
+	s.Equal(int64(math.MaxInt64), decoded["big"])
+	s.Equal(uint64(math.MaxUint64), decoded["huge"])
+	s.Equal(0.25, decoded["ratio"])
+	s.Equal(float32(1.5), decoded["single"])
+	s.Equal(true, decoded["flag"])
+	s.Contains(decoded, "missing")
+	s.Nil(decoded["missing"])
+	s.Equal(meta["title"], decoded["title"])
+	s.Equal([]byte{0, 1, 2}, decoded["raw"])
+	s.Equal([]interface{}{"a", "b"}, decoded["tags"])
+	s.Equal(map[string]interface{}{"depth": int64(2), "items": []interface{}{int64(1), "two", 3.5}}, decoded["nested"])
+}
+
+func (s *MetadataCodecTestSuite) TestJSONNumberTypes() {
+	encoded, err := JSONMetadataCodec{}.Encode(map[string]interface{}{
+		"version": 2,
+		"big":     int64(math.MaxInt64),
+		"ratio":   2.5,
+		"huge":    1e300,
+		"nested":  map[string]interface{}{"count": 3, "items": []interface{}{1, 0.5}},
+	})
+	s.Require().NoError(err)
+
+	decoded, err := JSONMetadataCodec{}.Decode(encoded)
+	s.Require().NoError(err)
+	s.Equal(int64(2), decoded["version"])
+	s.Equal(int64(math.MaxInt64), decoded["big"])
+	s.Equal(2.5, decoded["ratio"])
+	s.Equal(1e300, decoded["huge"])
+	s.Equal(map[string]interface{}{"count": int64(3), "items": []interface{}{int64(1), 0.5}}, decoded["nested"])
+}
+
+func (s *MetadataCodecTestSuite) TestJSONRejectsTrailingData() {
+	_, err := decodeMetadata(`{"a": 1} {"b": 2}`)
+	s.Error(err)
+}
+
+func (s *MetadataCodecTestSuite) TestMessagePackConvertsOtherTypesThroughJSON() {
+	type source struct {
+		Name  string `json:"name"`
+		Pages int    `json:"pages"`
+	}
+
+	encoded, err := MessagePackMetadataCodec{}.Encode(map[string]interface{}{
+		"source": source{Name: "manual", Pages: 12},
+		"counts": map[string]int{"x": 1},
+	})
+	s.Require().NoError(err)
+
+	decoded, err := MessagePackMetadataCodec{}.Decode(encoded)
+	s.Require().NoError(err)
+	s.Equal(map[string]interface{}{"name": "manual", "pages": int64(12)}, decoded["source"])
+	s.Equal(map[string]interface{}{"x": int64(1)}, decoded["counts"])
+}
+
+func (s *MetadataCodecTestSuite) TestCodecsReadEachOther() {
+	meta := map[string]interface{}{"page": 3}
+
+	jsonEncoded, err := JSONMetadataCodec{}.Encode(meta)
+	s.Require().NoError(err)
+	msgpackEncoded, err := MessagePackMetadataCodec{}.Encode(meta)
+	s.Require().NoError(err)
+
+	decoded, err := MessagePackMetadataCodec{}.Decode(jsonEncoded)
+	s.Require().NoError(err)
+	s.Equal(int64(3), decoded["page"])
+
+	decoded, err = JSONMetadataCodec{}.Decode(msgpackEncoded)
+	s.Require().NoError(err)
+	s.Equal(int64(3), decoded["page"])
+}
+
+func (s *MetadataCodecTestSuite) TestMessagePackRejectsCorruptData() {
+	encoded, err := MessagePackMetadataCodec{}.Encode(map[string]interface{}{"title": "hello"})
+	s.Require().NoError(err)
+
+	for _, data := range []string{
+		msgpackMetadataPrefix + "not base64!",
+		encoded[:len(encoded)-4],           // truncated
+		msgpackMetadataPrefix + "kQE=",     // an array, not a map
+		msgpackMetadataPrefix + "3////w==", // map claiming 4 billion entries
+		msgpackMetadataPrefix + "gaFh1AEA", // ext type
+	} {
+		_, err := MessagePackMetadataCodec{}.Decode(data)
+		s.Error(err, "data %q", data)
+	}
+}
+
+func (s *MetadataCodecTestSuite) TestMessagePackDepthLimit() {
+	// 200 nested single-element arrays inside a map
+	raw := []byte{0x81, 0xa1, 'a'}
+	for i := 0; i < 200; i++ {
+		raw = append(raw, 0x91)
+	}
+	raw = append(raw, 0x01)
+
+	d := msgpackDecoder{data: raw}
+	_, err := d.decode(0)
+	s.Error(err)
+}
+
+// MetadataCodecStoreTestSuite tests metadata codecs through the store
+type MetadataCodecStoreTestSuite struct {
+	suite.Suite
+	tmpDir string
+	store  *RAGStore
+	ctx    context.Context
+}
+
+func TestMetadataCodecStoreSuite(t *testing.T) {
+	suite.Run(t, new(MetadataCodecStoreTestSuite))
+}
+
+func (s *MetadataCodecStoreTestSuite) SetupTest() {
+	tmpDir, err := os.MkdirTemp("", "rag_metadata_test_*")
+	s.Require().NoError(err)
+	s.tmpDir = tmpDir
+
+	store, err := NewRAGStoreWithConfig(filepath.Join(tmpDir, "test.db"), 128, 100, &noopLogger{}, DefaultRetryConfig(), nil)
+	s.Require().NoError(err)
+	s.store = store
+	s.ctx = context.Background()
+}
+
+func (s *MetadataCodecStoreTestSuite) TearDownTest() {
+	if s.store != nil {
+		s.store.Close()
+	}
+	if s.tmpDir != "" {
+		os.RemoveAll(s.tmpDir)
+	}
+}
+
+func (s *MetadataCodecStoreTestSuite) addDoc(id string, metadata map[string]interface{}) []float32 {
+	embedding := make([]float32, 128)
+	embedding[0] = 1
+	err := s.store.AddDocuments(s.ctx, "user1", []Document{{
+		ID:           id,
+		Text:         "text " + id,
+		DocumentName: "doc.txt",
+		Embedding:    embedding,
+		Metadata:     metadata,
+	}})
+	s.Require().NoError(err)
+	return embedding
+}
+
+func (s *MetadataCodecStoreTestSuite) TestMessagePackIntRoundTrip() {
+	s.store.SetMetadataCodec(MessagePackMetadataCodec{})
+	embedding := s.addDoc("doc1", map[string]interface{}{"page": 7, "source": "manual"})
+
+	results, err := s.store.Search(s.ctx, "user1", embedding, &SearchOptions{Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+
+	page, ok := results[0].Metadata["page"].(int)
+	s.Require().True(ok, "expected int, got %T", results[0].Metadata["page"])
+	s.Equal(7, page)
+	s.Equal("manual", results[0].Metadata["source"])
+}
+
+func (s *MetadataCodecStoreTestSuite) TestJSONNumberRoundTrip() {
+	embedding := s.addDoc("doc1", map[string]interface{}{"version": int64(2), "score": 0.75})
+
+	results, err := s.store.Search(s.ctx, "user1", embedding, &SearchOptions{Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+
+	s.IsType(int64(0), results[0].Metadata["version"])
+	s.Equal(int64(2), results[0].Metadata["version"])
+	s.IsType(float64(0), results[0].Metadata["score"])
+	s.Equal(0.75, results[0].Metadata["score"])
+}
+
+func (s *MetadataCodecStoreTestSuite) TestSwitchingCodecKeepsExistingRowsReadable() {
+	embedding := s.addDoc("json-doc", map[string]interface{}{"page": 1})
+	s.store.SetMetadataCodec(MessagePackMetadataCodec{})
+	s.addDoc("msgpack-doc", map[string]interface{}{"page": 2})
+	s.store.SetMetadataCodec(nil)
+
+	results, err := s.store.Search(s.ctx, "user1", embedding, &SearchOptions{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(results, 2)
+
+	pages := make(map[string]interface{})
+	for _, result := range results {
+		pages[result.ID] = result.Metadata["page"]
+	}
+	s.Equal(int64(1), pages["json-doc"])
+	s.Equal(int64(2), pages["msgpack-doc"])
+}
@@ -29,6 +29,50 @@ func (n *noopMetrics) RecordDocumentCount(operation string, count int)
 func (n *noopMetrics) RecordSearchResults(count int)                                          {}
 func (n *noopMetrics) RecordError(operation string, errorType string)                         {}
 
+// swappableMetrics forwards to a MetricsCollector that can be replaced while operations run
+type swappableMetrics struct {
+	mu        sync.RWMutex
+	collector MetricsCollector
+}
+
+// newSwappableMetrics returns a swappableMetrics forwarding to collector, or discarding if nil
+func newSwappableMetrics(collector MetricsCollector) *swappableMetrics {
+	m := &swappableMetrics{}
+	m.set(collector)
+	return m
+}
+
+func (m *swappableMetrics) set(collector MetricsCollector) {
+	if collector == nil {
+		collector = &noopMetrics{}
+	}
+	m.mu.Lock()
+	m.collector = collector
+	m.mu.Unlock()
+}
+
+func (m *swappableMetrics) get() MetricsCollector {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.collector
+}
+
+func (m *swappableMetrics) RecordOperation(operation string, duration time.Duration, success bool) {
+	m.get().RecordOperation(operation, duration, success)
+}
+
+func (m *swappableMetrics) RecordDocumentCount(operation string, count int) {
+	m.get().RecordDocumentCount(operation, count)
+}
+
+func (m *swappableMetrics) RecordSearchResults(count int) {
+	m.get().RecordSearchResults(count)
+}
+
+func (m *swappableMetrics) RecordError(operation string, errorType string) {
+	m.get().RecordError(operation, errorType)
+}
+
 // simpleMetrics is a basic in-memory metrics collector for development/debugging.
 // Thread-safe for concurrent use.
 type simpleMetrics struct {
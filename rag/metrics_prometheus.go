@@ -0,0 +1,107 @@
+package rag
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// prometheusMetrics exports RAG operation metrics to Prometheus.
+// The underlying collectors are safe for concurrent use.
+type prometheusMetrics struct {
+	duration      *prometheus.HistogramVec // operation latency in seconds, by operation
+	operations    *prometheus.CounterVec   // completed operations, by operation and status
+	documents     *prometheus.CounterVec   // documents processed, by operation
+	searchResults prometheus.Histogram     // results returned per search
+	errors        *prometheus.CounterVec   // errors, by operation and error type
+}
+
+// NewPrometheusMetrics creates a MetricsCollector that exports metrics to Prometheus.
+// The metrics are registered with registerer (use prometheus.DefaultRegisterer to expose them
+// on the default /metrics handler). Creating several collectors on the same registerer is safe:
+// metrics that are already registered are reused rather than registered twice.
+//
+// Exported metrics:
+//   - rag_operation_duration_seconds{operation}: histogram of operation latencies
+//   - rag_operations_total{operation,status}: operations completed, status is "success" or "error"
+//   - rag_documents_total{operation}: documents processed
+//   - rag_search_results: histogram of results returned per search
+//   - rag_errors_total{operation,error_type}: errors by type
+func NewPrometheusMetrics(registerer prometheus.Registerer) MetricsCollector {
+	m := &prometheusMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "rag",
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of RAG store operations in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16), // 1ms to ~33s
+		}, []string{"operation"}),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rag",
+			Name:      "operations_total",
+			Help:      "Number of completed RAG store operations.",
+		}, []string{"operation", "status"}),
+		documents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rag",
+			Name:      "documents_total",
+			Help:      "Number of documents processed by RAG store operations.",
+		}, []string{"operation"}),
+		searchResults: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "rag",
+			Name:      "search_results",
+			Help:      "Number of results returned per search.",
+			Buckets:   []float64{0, 1, 5, 10, 25, 50, 100, 250, 1000},
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rag",
+			Name:      "errors_total",
+			Help:      "Number of RAG store errors by type.",
+		}, []string{"operation", "error_type"}),
+	}
+
+	if registerer != nil {
+		m.duration = registerOrReuse(registerer, m.duration)
+		m.operations = registerOrReuse(registerer, m.operations)
+		m.documents = registerOrReuse(registerer, m.documents)
+		m.searchResults = registerOrReuse(registerer, m.searchResults)
+		m.errors = registerOrReuse(registerer, m.errors)
+	}
+
+	return m
+}
+
+// registerOrReuse registers c, returning the already registered collector if an identical one exists.
+// Any other registration error is a programming error (e.g. a conflicting metric definition) and panics.
+func registerOrReuse[C prometheus.Collector](registerer prometheus.Registerer, c C) C {
+	if err := registerer.Register(c); err != nil {
+		var already prometheus.AlreadyRegisteredError
+		if errors.As(err, &already) {
+			if existing, ok := already.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return c
+}
+
+func (m *prometheusMetrics) RecordOperation(operation string, duration time.Duration, success bool) {
+	status := "success"
+	if !success {
+		status = "error"
+	}
+	m.duration.WithLabelValues(operation).Observe(duration.Seconds())
+	m.operations.WithLabelValues(operation, status).Inc()
+}
+
+func (m *prometheusMetrics) RecordDocumentCount(operation string, count int) {
+	m.documents.WithLabelValues(operation).Add(float64(count))
+}
+
+func (m *prometheusMetrics) RecordSearchResults(count int) {
+	m.searchResults.Observe(float64(count))
+}
+
+func (m *prometheusMetrics) RecordError(operation string, errorType string) {
+	m.errors.WithLabelValues(operation, errorType).Inc()
+}
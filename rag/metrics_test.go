@@ -0,0 +1,93 @@
+package rag
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+// PrometheusMetricsTestSuite tests the Prometheus metrics exporter
+type PrometheusMetricsTestSuite struct {
+	suite.Suite
+	registry *prometheus.Registry
+	metrics  MetricsCollector
+}
+
+func TestPrometheusMetricsSuite(t *testing.T) {
+	suite.Run(t, new(PrometheusMetricsTestSuite))
+}
+
+func (s *PrometheusMetricsTestSuite) SetupTest() {
+	s.registry = prometheus.NewRegistry()
+	s.metrics = NewPrometheusMetrics(s.registry)
+}
+
+func (s *PrometheusMetricsTestSuite) TestRecordedOperationsAreScraped() {
+	s.metrics.RecordOperation("search", 5*time.Millisecond, true)
+	s.metrics.RecordOperation("search", 12*time.Millisecond, true)
+	s.metrics.RecordOperation("add_documents", 40*time.Millisecond, false)
+	s.metrics.RecordDocumentCount("add_documents", 25)
+	s.metrics.RecordSearchResults(10)
+	s.metrics.RecordError("add_documents", "insert_failed")
+
+	families, err := s.registry.Gather()
+	s.Require().NoError(err)
+
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	for _, name := range []string{
+		"rag_operation_duration_seconds",
+		"rag_operations_total",
+		"rag_documents_total",
+		"rag_search_results",
+		"rag_errors_total",
+	} {
+		s.True(names[name], "missing metric family %s", name)
+	}
+
+	m := s.metrics.(*prometheusMetrics)
+	s.Equal(2.0, testutil.ToFloat64(m.operations.WithLabelValues("search", "success")))
+	s.Equal(1.0, testutil.ToFloat64(m.operations.WithLabelValues("add_documents", "error")))
+	s.Equal(25.0, testutil.ToFloat64(m.documents.WithLabelValues("add_documents")))
+	s.Equal(1.0, testutil.ToFloat64(m.errors.WithLabelValues("add_documents", "insert_failed")))
+
+	// One latency series per operation
+	s.Equal(2, testutil.CollectAndCount(m.duration))
+}
+
+func (s *PrometheusMetricsTestSuite) TestRegisteringTwiceReusesMetrics() {
+	var second MetricsCollector
+	s.NotPanics(func() {
+		second = NewPrometheusMetrics(s.registry)
+	})
+
+	s.metrics.RecordOperation("search", time.Millisecond, true)
+	second.RecordOperation("search", time.Millisecond, true)
+
+	m := s.metrics.(*prometheusMetrics)
+	s.Equal(2.0, testutil.ToFloat64(m.operations.WithLabelValues("search", "success")))
+}
+
+func (s *PrometheusMetricsTestSuite) TestConcurrentRecording() {
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				s.metrics.RecordOperation("search", time.Millisecond, true)
+				s.metrics.RecordSearchResults(j)
+			}
+		}()
+	}
+	wg.Wait()
+
+	m := s.metrics.(*prometheusMetrics)
+	s.Equal(1000.0, testutil.ToFloat64(m.operations.WithLabelValues("search", "success")))
+}
@@ -0,0 +1,150 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/aqua777/go-lancedb"
+)
+
+// MMROptions configures SearchMMR
+type MMROptions struct {
+	Lambda  float32                // Relevance vs. novelty trade-off in [0, 1]; 1 is plain top-k, 0 only novelty. Not defaulted: start from DefaultMMROptions for 0.5
+	Limit   int                    // Maximum number of results (default: 10)
+	FetchK  int                    // Candidates fetched before re-ranking (default: 4 * Limit)
+	Filters map[string]interface{} // Metadata filters (applied as SQL predicates)
+}
+
+// DefaultMMROptions returns options balancing relevance and novelty equally.
+// SearchMMR uses them when given nil options.
+func DefaultMMROptions() *MMROptions {
+	return &MMROptions{
+		Lambda: 0.5,
+		Limit:  10,
+	}
+}
+
+// SearchMMR performs a vector search and re-ranks the results by maximal marginal relevance,
+// so near-duplicate chunks don't crowd out other relevant ones. It fetches FetchK candidates
+// and greedily selects Limit of them, each time taking the candidate maximising
+//
+//	Lambda*sim(query, candidate) - (1-Lambda)*max(sim(selected, candidate))
+//
+// where sim is the cosine similarity of the stored embeddings. Results are returned in
+// selection order with the Score of the underlying cosine search.
+func (s *RAGStore) SearchMMR(ctx context.Context, userID string, queryEmbedding []float32, opts *MMROptions) ([]SearchResult, error) {
+	if opts == nil {
+		opts = DefaultMMROptions()
+	}
+	// Written so that NaN fails too
+	if !(opts.Lambda >= 0 && opts.Lambda <= 1) {
+		return nil, fmt.Errorf("lambda must be between 0 and 1, got %g", opts.Lambda)
+	}
+	for _, v := range queryEmbedding {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return nil, fmt.Errorf("query embedding contains NaN or Inf")
+		}
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	fetchK := opts.FetchK
+	if fetchK <= 0 {
+		fetchK = 4 * limit
+	}
+	if fetchK < limit {
+		fetchK = limit
+	}
+
+	// The candidates' embeddings are only read while selecting, so they aren't copied
+	candidates, err := s.Search(ctx, userID, queryEmbedding, &SearchOptions{
+		Limit:                    fetchK,
+		Filters:                  opts.Filters,
+		DistanceType:             lancedb.DistanceTypeCosine,
+		UnsafeZeroCopyEmbeddings: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) > 0 {
+		// The results of a search share one handle
+		defer candidates[0].EmbeddingHandle.Release()
+	}
+
+	results := selectMMR(queryEmbedding, candidates, opts.Lambda, limit)
+	for i := range results {
+		// Copy the selected embeddings out of the buffers released on return
+		results[i].Embedding = append([]float32(nil), results[i].Embedding...)
+		results[i].EmbeddingHandle = nil
+	}
+	return results, nil
+}
+
+// selectMMR greedily picks up to limit candidates by maximal marginal relevance
+func selectMMR(query []float32, candidates []SearchResult, lambda float32, limit int) []SearchResult {
+	if len(candidates) <= 1 {
+		return candidates
+	}
+
+	relevance := make([]float64, len(candidates))
+	for i, candidate := range candidates {
+		relevance[i] = cosineSimilarity(query, candidate.Embedding)
+	}
+
+	// maxSim[i] is the highest similarity of candidate i to any selected result
+	maxSim := make([]float64, len(candidates))
+	for i := range maxSim {
+		maxSim[i] = math.Inf(-1)
+	}
+	selected := make([]bool, len(candidates))
+	results := make([]SearchResult, 0, limit)
+
+	for len(results) < limit && len(results) < len(candidates) {
+		best := -1
+		bestScore := math.Inf(-1)
+		for i := range candidates {
+			if selected[i] {
+				continue
+			}
+			score := float64(lambda) * relevance[i]
+			if len(results) > 0 {
+				score -= float64(1-lambda) * maxSim[i]
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break // Only NaN scores are left, e.g. from non-finite embeddings
+		}
+
+		selected[best] = true
+		results = append(results, candidates[best])
+		for i := range candidates {
+			if !selected[i] {
+				maxSim[i] = math.Max(maxSim[i], cosineSimilarity(candidates[best].Embedding, candidates[i].Embedding))
+			}
+		}
+	}
+	return results
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0 if either is
+// zero or their lengths differ
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,154 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// MMRTestSuite tests maximal marginal relevance search
+type MMRTestSuite struct {
+	suite.Suite
+	store  *RAGStore
+	dbPath string
+	ctx    context.Context
+}
+
+// SetupTest runs before each test
+func (s *MMRTestSuite) SetupTest() {
+	tmpDir, err := os.MkdirTemp("", "rag_mmr_test_*")
+	s.Require().NoError(err)
+	s.dbPath = filepath.Join(tmpDir, "test.db")
+	s.ctx = context.Background()
+
+	store, err := NewRAGStoreWithConfig(s.dbPath, 128, 100, &noopLogger{}, DefaultRetryConfig(), nil)
+	s.Require().NoError(err)
+	s.store = store
+}
+
+// TearDownTest runs after each test
+func (s *MMRTestSuite) TearDownTest() {
+	if s.store != nil {
+		s.store.Close()
+	}
+	if s.dbPath != "" {
+		os.RemoveAll(filepath.Dir(s.dbPath))
+	}
+}
+
+// TestMMRTestSuite runs the MMR test suite
+func TestMMRTestSuite(t *testing.T) {
+	suite.Run(t, new(MMRTestSuite))
+}
+
+// mmrTestDocs returns near-duplicate chunks closest to the query along axis 0, plus chunks
+// that are less relevant but point in distinct directions
+func mmrTestDocs() []Document {
+	var docs []Document
+	for i := 0; i < 8; i++ {
+		embedding := make([]float32, 128)
+		embedding[0] = 1
+		embedding[1] = float32(i) * 0.01
+		docs = append(docs, Document{ID: DocumentID("dup.txt", i), Text: "duplicate", DocumentName: "dup.txt", Embedding: embedding})
+	}
+	for i := 0; i < 4; i++ {
+		embedding := make([]float32, 128)
+		embedding[0] = 1
+		embedding[2+i] = 0.6
+		name := fmt.Sprintf("distinct_%d.txt", i)
+		docs = append(docs, Document{ID: DocumentID(name, 0), Text: "distinct", DocumentName: name, Embedding: embedding})
+	}
+	return docs
+}
+
+// meanPairwiseSimilarity returns the mean cosine similarity between the results' embeddings
+func meanPairwiseSimilarity(results []SearchResult) float64 {
+	var total float64
+	var pairs int
+	for i := range results {
+		for j := i + 1; j < len(results); j++ {
+			total += cosineSimilarity(results[i].Embedding, results[j].Embedding)
+			pairs++
+		}
+	}
+	return total / float64(pairs)
+}
+
+func (s *MMRTestSuite) TestLowLambdaSpreadsResults() {
+	userID := "mmr_user"
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, mmrTestDocs()))
+	query := make([]float32, 128)
+	query[0] = 1
+
+	topK, err := s.store.Search(s.ctx, userID, query, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	s.Require().Len(topK, 5)
+	for _, r := range topK {
+		s.Equal("dup.txt", r.DocumentName)
+	}
+
+	diverse, err := s.store.SearchMMR(s.ctx, userID, query, &MMROptions{Lambda: 0.2, Limit: 5, FetchK: 12})
+	s.Require().NoError(err)
+	s.Require().Len(diverse, 5)
+	s.Equal(topK[0].ID, diverse[0].ID, "the most relevant result is always selected first")
+	s.Less(meanPairwiseSimilarity(diverse), meanPairwiseSimilarity(topK))
+
+	names := make(map[string]bool)
+	for _, r := range diverse {
+		names[r.DocumentName] = true
+	}
+	s.Len(names, 5)
+}
+
+func (s *MMRTestSuite) TestLambdaOneIsTopK() {
+	userID := "mmr_topk_user"
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, mmrTestDocs()))
+	query := make([]float32, 128)
+	query[0] = 1
+
+	topK, err := s.store.Search(s.ctx, userID, query, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	results, err := s.store.SearchMMR(s.ctx, userID, query, &MMROptions{Lambda: 1, Limit: 5})
+	s.Require().NoError(err)
+	s.Equal(resultIDs(topK), resultIDs(results))
+}
+
+func (s *MMRTestSuite) TestInvalidOptions() {
+	_, err := s.store.SearchMMR(s.ctx, "mmr_user", make([]float32, 128), &MMROptions{Lambda: 1.5})
+	s.Error(err)
+	_, err = s.store.SearchMMR(s.ctx, "mmr_user", make([]float32, 64), nil)
+	s.Error(err)
+	_, err = s.store.SearchMMR(s.ctx, "mmr_user", make([]float32, 128), &MMROptions{Lambda: float32(math.NaN())})
+	s.Error(err)
+
+	query := make([]float32, 128)
+	query[0] = float32(math.Inf(1))
+	_, err = s.store.SearchMMR(s.ctx, "mmr_user", query, nil)
+	s.Error(err)
+}
+
+func (s *MMRTestSuite) TestSelectMMR() {
+	query := []float32{1, 0, 0}
+	candidates := []SearchResult{
+		{ID: "a", Embedding: []float32{1, 0, 0}},
+		{ID: "a2", Embedding: []float32{1, 0.01, 0}},
+		{ID: "b", Embedding: []float32{1, 0, 1}},
+	}
+	s.Equal([]string{"a", "a2"}, resultIDs(selectMMR(query, candidates, 1, 2)))
+	s.Equal([]string{"a", "b"}, resultIDs(selectMMR(query, candidates, 0.3, 2)))
+	s.Len(selectMMR(query, candidates, 0.5, 10), 3)
+	s.Empty(selectMMR(query, nil, 0.5, 3))
+
+	// Candidates scoring NaN are never selected
+	nan := float32(math.NaN())
+	withNaN := []SearchResult{
+		{ID: "a", Embedding: []float32{1, 0, 0}},
+		{ID: "nan", Embedding: []float32{nan, 0, 0}},
+	}
+	s.Equal([]string{"a"}, resultIDs(selectMMR(query, withNaN, 0.5, 2)))
+}
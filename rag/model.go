@@ -0,0 +1,176 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aqua777/go-lancedb"
+)
+
+// Table metadata keys under which a user's embedding model and dimension are recorded
+const (
+	embeddingModelMetadataKey = "rag.embedding_model"
+	embeddingDimMetadataKey   = "rag.embedding_dim"
+)
+
+// EmbeddingModelIdentifier is implemented by embedding providers that can name the model
+// producing their embeddings. Operations that take a provider record the model in the
+// user's table on first write and reject embeddings from a different model afterwards.
+type EmbeddingModelIdentifier interface {
+	EmbeddingModel() string
+}
+
+// ErrEmbeddingModelMismatch is matched by errors.Is when embeddings come from a different
+// model than the one recorded for the user. Use errors.As with *EmbeddingModelMismatchError
+// for the model names.
+var ErrEmbeddingModelMismatch = errors.New("embedding model mismatch")
+
+// EmbeddingModelMismatchError reports embeddings from a model other than the user's
+type EmbeddingModelMismatchError struct {
+	UserID     string
+	TableModel string // Model recorded in the user's table
+	Model      string // Model that produced the rejected embeddings
+}
+
+func (e *EmbeddingModelMismatchError) Error() string {
+	return fmt.Sprintf("embedding model mismatch for user %s: table was built with %q, got embeddings from %q",
+		e.UserID, e.TableModel, e.Model)
+}
+
+// Is reports whether target is ErrEmbeddingModelMismatch
+func (e *EmbeddingModelMismatchError) Is(target error) bool {
+	return target == ErrEmbeddingModelMismatch
+}
+
+// SetEmbeddingModel sets the model assumed to have produced embeddings passed to AddDocuments,
+// UpsertDocuments and Search. The first write for a user records the model in their table,
+// and later writes and searches with another model fail with ErrEmbeddingModelMismatch.
+// Operations that take an EmbeddingProvider use the provider's model instead, and
+// SearchOptions.EmbeddingModel overrides it for a single search. Default is "", which
+// records and checks nothing.
+func (s *RAGStore) SetEmbeddingModel(model string) {
+	s.mu.Lock()
+	s.embeddingModel = model
+	s.mu.Unlock()
+}
+
+// GetEmbeddingModel returns the model set with SetEmbeddingModel
+func (s *RAGStore) GetEmbeddingModel() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.embeddingModel
+}
+
+// UserEmbeddingModel returns the embedding model recorded in a user's table.
+// It returns "" if the user has no table or no model was recorded when it was written.
+func (s *RAGStore) UserEmbeddingModel(ctx context.Context, userID string) (string, error) {
+	if err := s.validateUserID(userID); err != nil {
+		return "", err
+	}
+
+	// Check for context cancellation
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	default:
+	}
+
+	exists, err := s.TableExists(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", nil
+	}
+
+	table, release, err := s.acquireTable(userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	defer release()
+
+	return s.lookupUserEmbeddingModel(table, userID)
+}
+
+// providerEmbeddingModel returns the provider's model, or the store's if the provider can't name one
+func (s *RAGStore) providerEmbeddingModel(provider EmbeddingProvider) string {
+	if model := wrappedEmbeddingModel(provider); model != "" {
+		return model
+	}
+	return s.GetEmbeddingModel()
+}
+
+// lookupUserEmbeddingModel returns the model recorded in the user's table, or "" if there is none
+func (s *RAGStore) lookupUserEmbeddingModel(table *lancedb.Table, userID string) (string, error) {
+	s.mu.RLock()
+	model, ok := s.userModels[userID]
+	s.mu.RUnlock()
+	if ok {
+		return model, nil
+	}
+
+	metadata, err := table.GetMetadata()
+	if err != nil {
+		return "", fmt.Errorf("failed to read table metadata for user %s: %w", userID, err)
+	}
+	model = metadata[embeddingModelMetadataKey]
+
+	// Only a recorded model is cached, so one recorded later by another store is seen
+	if model != "" {
+		s.mu.Lock()
+		s.userModels[userID] = model
+		s.mu.Unlock()
+	}
+	return model, nil
+}
+
+// checkEmbeddingModel returns an EmbeddingModelMismatchError if the user's table records a
+// model other than model. Tables without a recorded model accept any model.
+func (s *RAGStore) checkEmbeddingModel(table *lancedb.Table, userID, model string) error {
+	if model == "" {
+		return nil
+	}
+	tableModel, err := s.lookupUserEmbeddingModel(table, userID)
+	if err != nil {
+		return err
+	}
+	if tableModel != "" && tableModel != model {
+		return &EmbeddingModelMismatchError{UserID: userID, TableModel: tableModel, Model: model}
+	}
+	return nil
+}
+
+// recordEmbeddingModel checks model against the user's table like checkEmbeddingModel, and
+// records model and dim in the table if it has no model yet. The caller must hold the user lock.
+func (s *RAGStore) recordEmbeddingModel(table *lancedb.Table, userID, model string, dim int) error {
+	if model == "" {
+		return nil
+	}
+	tableModel, err := s.lookupUserEmbeddingModel(table, userID)
+	if err != nil {
+		return err
+	}
+	if tableModel != "" {
+		if tableModel != model {
+			return &EmbeddingModelMismatchError{UserID: userID, TableModel: tableModel, Model: model}
+		}
+		return nil
+	}
+
+	err = table.SetMetadata(map[string]string{
+		embeddingModelMetadataKey: model,
+		embeddingDimMetadataKey:   strconv.Itoa(dim),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record embedding model for user %s: %w", userID, err)
+	}
+
+	s.mu.Lock()
+	s.userModels[userID] = model
+	s.mu.Unlock()
+
+	s.logger.Printf("Recorded embedding model %s (%d dimensions) for user %s", model, dim, userID)
+	return nil
+}
@@ -0,0 +1,158 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// ModelTestSuite tests recording and enforcing users' embedding models
+type ModelTestSuite struct {
+	suite.Suite
+	store  *RAGStore
+	dbPath string
+	ctx    context.Context
+}
+
+// SetupTest runs before each test
+func (s *ModelTestSuite) SetupTest() {
+	tmpDir, err := os.MkdirTemp("", "rag_model_test_*")
+	s.Require().NoError(err)
+	s.dbPath = filepath.Join(tmpDir, "test.db")
+	s.ctx = context.Background()
+
+	store, err := NewRAGStoreWithConfig(s.dbPath, 128, 100, &noopLogger{}, DefaultRetryConfig(), nil)
+	s.Require().NoError(err)
+	s.store = store
+}
+
+// TearDownTest runs after each test
+func (s *ModelTestSuite) TearDownTest() {
+	if s.store != nil {
+		s.store.Close()
+	}
+	if s.dbPath != "" {
+		os.RemoveAll(filepath.Dir(s.dbPath))
+	}
+}
+
+// TestModelTestSuite runs the embedding model test suite
+func TestModelTestSuite(t *testing.T) {
+	suite.Run(t, new(ModelTestSuite))
+}
+
+func (s *ModelTestSuite) TestStoreModelRecordedAndEnforced() {
+	userID := "model_user"
+	docs := makeTestDocs(10, 128, "doc.txt")
+
+	s.store.SetEmbeddingModel("text-embedding-ada-002")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	model, err := s.store.UserEmbeddingModel(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal("text-embedding-ada-002", model)
+
+	// Searching with the recorded model works
+	results, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	s.Len(results, 5)
+
+	// A query from another model is rejected
+	_, err = s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5, EmbeddingModel: "all-MiniLM-L6-v2"})
+	s.Require().ErrorIs(err, ErrEmbeddingModelMismatch)
+	var mismatch *EmbeddingModelMismatchError
+	s.Require().True(errors.As(err, &mismatch))
+	s.Equal("text-embedding-ada-002", mismatch.TableModel)
+	s.Equal("all-MiniLM-L6-v2", mismatch.Model)
+
+	// So are writes, before anything is stored
+	s.store.SetEmbeddingModel("all-MiniLM-L6-v2")
+	err = s.store.AddDocuments(s.ctx, userID, makeTestDocs(5, 128, "other.txt"))
+	s.ErrorIs(err, ErrEmbeddingModelMismatch)
+	_, err = s.store.SearchBatch(s.ctx, userID, [][]float32{docs[0].Embedding}, nil)
+	s.ErrorIs(err, ErrEmbeddingModelMismatch)
+
+	count, err := s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(10), count)
+}
+
+func (s *ModelTestSuite) TestProviderModelRecordedAndEnforced() {
+	userID := "provider_user"
+	openAI := newOpenAITestServer(128, nil)
+	defer openAI.Close()
+	ada := NewOpenAIEmbeddingProvider("key", "text-embedding-ada-002", 128)
+	ada.BaseURL = openAI.URL
+
+	s.Require().NoError(s.store.AddDocumentsWithEmbedding(s.ctx, userID, []string{"hello", "world"}, []string{"a.txt", "a.txt"}, ada))
+
+	model, err := s.store.UserEmbeddingModel(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal("text-embedding-ada-002", model)
+
+	// Wrappers report the model of the provider they wrap
+	results, err := s.store.SearchWithText(s.ctx, userID, "hello", NewRetryingEmbeddingProvider(ada, nil), nil)
+	s.Require().NoError(err)
+	s.NotEmpty(results)
+
+	var requests int32
+	ollama := newOllamaTestServer(128, &requests)
+	defer ollama.Close()
+	miniLM := NewOllamaEmbeddingProvider(ollama.URL, "all-minilm", 128)
+
+	_, err = s.store.SearchWithText(s.ctx, userID, "hello", miniLM, nil)
+	s.ErrorIs(err, ErrEmbeddingModelMismatch)
+	_, err = s.store.HybridSearchWithText(s.ctx, userID, "hello", miniLM, nil)
+	s.ErrorIs(err, ErrEmbeddingModelMismatch)
+}
+
+func (s *ModelTestSuite) TestNoModelRecordsNothing() {
+	userID := "plain_user"
+	docs := makeTestDocs(5, 128, "doc.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	model, err := s.store.UserEmbeddingModel(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Empty(model)
+
+	// Without a recorded model, queries from any model are accepted
+	_, err = s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{EmbeddingModel: "anything"})
+	s.NoError(err)
+
+	model, err = s.store.UserEmbeddingModel(s.ctx, "missing_user")
+	s.Require().NoError(err)
+	s.Empty(model)
+
+	_, err = s.store.UserEmbeddingModel(s.ctx, "bad user!")
+	s.Error(err)
+}
+
+func (s *ModelTestSuite) TestQuotaRejectionRecordsNoModel() {
+	s.Require().NoError(s.store.SetMaxDocumentsPerUser(3))
+	s.store.SetEmbeddingModel("text-embedding-ada-002")
+
+	for _, userID := range []string{"quota_add_user", "quota_upsert_user"} {
+		var err error
+		if userID == "quota_add_user" {
+			err = s.store.AddDocuments(s.ctx, userID, makeTestDocs(5, 128, "doc.txt"))
+		} else {
+			err = s.store.UpsertDocuments(s.ctx, userID, makeTestDocs(5, 128, "doc.txt"))
+		}
+		s.Require().ErrorIs(err, ErrQuotaExceeded, userID)
+
+		model, err := s.store.UserEmbeddingModel(s.ctx, userID)
+		s.Require().NoError(err)
+		s.Empty(model, "%s: a rejected write must not pin the model", userID)
+	}
+
+	// The user can still start over with another model
+	s.store.SetEmbeddingModel("all-MiniLM-L6-v2")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "quota_add_user", makeTestDocs(2, 128, "doc.txt")))
+	model, err := s.store.UserEmbeddingModel(s.ctx, "quota_add_user")
+	s.Require().NoError(err)
+	s.Equal("all-MiniLM-L6-v2", model)
+}
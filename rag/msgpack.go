@@ -0,0 +1,471 @@
+package rag
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// msgpackMetadataPrefix marks metadata values written by MessagePackMetadataCodec.
+// JSON metadata always starts with '{', so the two encodings can't be confused.
+const msgpackMetadataPrefix = "msgpack:"
+
+// maxMsgpackDepth bounds nesting when decoding, so corrupt input can't exhaust the stack
+const maxMsgpackDepth = 100
+
+// MessagePackMetadataCodec stores metadata as MessagePack, preserving numeric types:
+// integers decode as int64 like they do with the JSON codec (uint64 for values above
+// math.MaxInt64), float32 as float32 and float64 as float64. Nested maps decode as map[string]interface{}, arrays as
+// []interface{} and binary values as []byte. Values of other types (structs, typed
+// slices) are converted through their JSON representation.
+//
+// The encoded bytes are stored base64 encoded with a "msgpack:" prefix, since the
+// metadata column holds strings. Values written by the JSON codec are still decoded.
+type MessagePackMetadataCodec struct{}
+
+// Encode serializes metadata to a prefixed, base64 encoded MessagePack map
+func (MessagePackMetadataCodec) Encode(meta map[string]interface{}) (string, error) {
+	return encodeMsgpackMetadata(meta)
+}
+
+// Decode deserializes metadata, accepting both MessagePack and JSON encoded values
+func (MessagePackMetadataCodec) Decode(data string) (map[string]interface{}, error) {
+	if strings.HasPrefix(data, msgpackMetadataPrefix) {
+		return decodeMsgpackMetadata(data)
+	}
+	return decodeMetadata(data)
+}
+
+// encodeMsgpackMetadata serializes a metadata map to a prefixed MessagePack string
+func encodeMsgpackMetadata(meta map[string]interface{}) (string, error) {
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+
+	var e msgpackEncoder
+	if err := e.encode(meta); err != nil {
+		return "", fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	return msgpackMetadataPrefix + base64.StdEncoding.EncodeToString(e.buf), nil
+}
+
+// decodeMsgpackMetadata deserializes a string produced by encodeMsgpackMetadata
+func decodeMsgpackMetadata(data string) (map[string]interface{}, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(data, msgpackMetadataPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+
+	d := msgpackDecoder{data: raw}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("failed to decode metadata: %d trailing bytes", len(d.data)-d.pos)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case nil:
+		return make(map[string]interface{}), nil
+	default:
+		return nil, fmt.Errorf("failed to decode metadata: expected a map, got %T", value)
+	}
+}
+
+// msgpackEncoder appends MessagePack encoded values to buf
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (e *msgpackEncoder) encode(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		e.buf = append(e.buf, 0xc0)
+	case bool:
+		if v {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case int:
+		e.writeInt(int64(v))
+	case int8:
+		e.writeInt(int64(v))
+	case int16:
+		e.writeInt(int64(v))
+	case int32:
+		e.writeInt(int64(v))
+	case int64:
+		e.writeInt(v)
+	case uint:
+		e.writeUint(uint64(v))
+	case uint8:
+		e.writeUint(uint64(v))
+	case uint16:
+		e.writeUint(uint64(v))
+	case uint32:
+		e.writeUint(uint64(v))
+	case uint64:
+		e.writeUint(v)
+	case float32:
+		e.buf = append(e.buf, 0xca)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(v))
+	case float64:
+		e.buf = append(e.buf, 0xcb)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v))
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			e.writeInt(i)
+		} else if f, err := v.Float64(); err == nil {
+			return e.encode(f)
+		} else {
+			return fmt.Errorf("invalid number %q", v)
+		}
+	case string:
+		e.writeString(v)
+	case []byte:
+		e.writeHeader(len(v), 0xc4, 0xc5, 0xc6)
+		e.buf = append(e.buf, v...)
+	case []interface{}:
+		e.writeArrayHeader(len(v))
+		for _, item := range v {
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+	case []string:
+		e.writeArrayHeader(len(v))
+		for _, item := range v {
+			e.writeString(item)
+		}
+	case map[string]interface{}:
+		keys := sortedKeys(v)
+		e.writeMapHeader(len(keys))
+		for _, key := range keys {
+			e.writeString(key)
+			if err := e.encode(v[key]); err != nil {
+				return fmt.Errorf("key %q: %w", key, err)
+			}
+		}
+	case map[string]string:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		e.writeMapHeader(len(keys))
+		for _, key := range keys {
+			e.writeString(key)
+			e.writeString(v[key])
+		}
+	default:
+		// Convert anything else through JSON, as the JSON codec would
+		converted, err := jsonRoundTrip(v)
+		if err != nil {
+			return fmt.Errorf("unsupported metadata value of type %T: %w", v, err)
+		}
+		return e.encode(converted)
+	}
+	return nil
+}
+
+func (e *msgpackEncoder) writeInt(v int64) {
+	switch {
+	case v >= 0:
+		e.writeUint(uint64(v))
+	case v >= -32:
+		e.buf = append(e.buf, byte(v)) // negative fixint
+	case v >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		e.buf = append(e.buf, 0xd1)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v))
+	case v >= math.MinInt32:
+		e.buf = append(e.buf, 0xd2)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v))
+	default:
+		e.buf = append(e.buf, 0xd3)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v))
+	}
+}
+
+func (e *msgpackEncoder) writeUint(v uint64) {
+	switch {
+	case v < 0x80:
+		e.buf = append(e.buf, byte(v)) // positive fixint
+	case v <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		e.buf = append(e.buf, 0xcd)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v))
+	case v <= math.MaxUint32:
+		e.buf = append(e.buf, 0xce)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v))
+	default:
+		e.buf = append(e.buf, 0xcf)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, v)
+	}
+}
+
+func (e *msgpackEncoder) writeString(s string) {
+	if len(s) < 32 {
+		e.buf = append(e.buf, 0xa0|byte(len(s))) // fixstr
+	} else {
+		e.writeHeader(len(s), 0xd9, 0xda, 0xdb)
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *msgpackEncoder) writeArrayHeader(n int) {
+	if n < 16 {
+		e.buf = append(e.buf, 0x90|byte(n)) // fixarray
+		return
+	}
+	e.writeHeader(n, 0, 0xdc, 0xdd)
+}
+
+func (e *msgpackEncoder) writeMapHeader(n int) {
+	if n < 16 {
+		e.buf = append(e.buf, 0x80|byte(n)) // fixmap
+		return
+	}
+	e.writeHeader(n, 0, 0xde, 0xdf)
+}
+
+// writeHeader writes a length with the 8, 16 or 32-bit variant of a format.
+// code8 is 0 for formats without an 8-bit variant.
+func (e *msgpackEncoder) writeHeader(n int, code8, code16, code32 byte) {
+	switch {
+	case code8 != 0 && n <= math.MaxUint8:
+		e.buf = append(e.buf, code8, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, code16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, code32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+}
+
+// sortedKeys returns the map's keys in sorted order, so encoding is deterministic
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonRoundTrip converts a value to the generic form of its JSON representation,
+// keeping numbers as json.Number so integers stay integers
+func jsonRoundTrip(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	var converted interface{}
+	if err := decoder.Decode(&converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+var errMsgpackTruncated = errors.New("unexpected end of MessagePack data")
+
+// msgpackDecoder reads MessagePack values from data
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, errMsgpackTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// length reads a 1, 2 or 4 byte big-endian length
+func (d *msgpackDecoder) length(size int) (int, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return int(b[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(b)), nil
+	default:
+		return int(binary.BigEndian.Uint32(b)), nil
+	}
+}
+
+func (d *msgpackDecoder) decode(depth int) (interface{}, error) {
+	if depth > maxMsgpackDepth {
+		return nil, fmt.Errorf("MessagePack data nested deeper than %d levels", maxMsgpackDepth)
+	}
+
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+
+	switch {
+	case code <= 0x7f: // positive fixint
+		return int64(code), nil
+	case code >= 0xe0: // negative fixint
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80: // fixmap
+		return d.decodeMap(int(code&0x0f), depth)
+	case code&0xf0 == 0x90: // fixarray
+		return d.decodeArray(int(code&0x0f), depth)
+	case code&0xe0 == 0xa0: // fixstr
+		return d.decodeString(int(code & 0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6: // bin 8/16/32
+		n, err := d.length(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), raw...), nil
+	case 0xca:
+		raw, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(raw)), nil
+	case 0xcb:
+		raw, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf: // uint 8/16/32/64
+		raw, err := d.next(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		var v uint64
+		for _, c := range raw {
+			v = v<<8 | uint64(c)
+		}
+		if v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3: // int 8/16/32/64
+		raw, err := d.next(1 << (code - 0xd0))
+		if err != nil {
+			return nil, err
+		}
+		switch len(raw) {
+		case 1:
+			return int64(int8(raw[0])), nil
+		case 2:
+			return int64(int16(binary.BigEndian.Uint16(raw))), nil
+		case 4:
+			return int64(int32(binary.BigEndian.Uint32(raw))), nil
+		default:
+			return int64(binary.BigEndian.Uint64(raw)), nil
+		}
+	case 0xd9, 0xda, 0xdb: // str 8/16/32
+		n, err := d.length(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xdc, 0xdd: // array 16/32
+		n, err := d.length(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n, depth)
+	case 0xde, 0xdf: // map 16/32
+		n, err := d.length(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n, depth)
+	default:
+		return nil, fmt.Errorf("unsupported MessagePack type 0x%02x", code)
+	}
+}
+
+func (d *msgpackDecoder) decodeString(n int) (string, error) {
+	raw, err := d.next(n)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func (d *msgpackDecoder) decodeArray(n int, depth int) ([]interface{}, error) {
+	// Every element takes at least one byte
+	if n > len(d.data)-d.pos {
+		return nil, errMsgpackTruncated
+	}
+
+	items := make([]interface{}, n)
+	for i := range items {
+		item, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n int, depth int) (map[string]interface{}, error) {
+	// Every key and value takes at least one byte
+	if n > (len(d.data)-d.pos)/2 {
+		return nil, errMsgpackTruncated
+	}
+
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := key.(string); ok {
+			m[s] = value
+		} else {
+			m[fmt.Sprint(key)] = value
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,60 @@
+package rag
+
+import (
+	"math"
+
+	"github.com/aqua777/go-lancedb"
+)
+
+// NormalizeVector returns a copy of v scaled to unit L2 norm. A zero vector is returned
+// unchanged, since it has no direction.
+func NormalizeVector(v []float32) []float32 {
+	normalized := append([]float32(nil), v...)
+	NormalizeVectorInPlace(normalized)
+	return normalized
+}
+
+// NormalizeVectorInPlace scales v to unit L2 norm. A zero vector is left unchanged.
+func NormalizeVectorInPlace(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	scale := 1 / math.Sqrt(sum)
+	for i, x := range v {
+		v[i] = float32(float64(x) * scale)
+	}
+}
+
+// userIndexMetric returns the metric of the user's vector index, or the metric it will be
+// built with if it doesn't exist yet
+func (s *RAGStore) userIndexMetric(userID string) lancedb.DistanceMetric {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if metric, built := s.indexMetrics[userID]; built {
+		return metric
+	}
+	if config := s.indexConfigs[userID]; config != nil {
+		return config.Metric
+	}
+	return DefaultIndexConfig().Metric
+}
+
+// normalizeEmbeddings returns docs with normalized embeddings if the user's index uses the
+// cosine metric, and docs itself otherwise
+func (s *RAGStore) normalizeEmbeddings(userID string, docs []Document) []Document {
+	if s.userIndexMetric(userID) != lancedb.DistanceMetricCosine {
+		return docs
+	}
+
+	normalized := make([]Document, len(docs))
+	for i, doc := range docs {
+		doc.Embedding = NormalizeVector(doc.Embedding)
+		normalized[i] = doc
+	}
+	return normalized
+}
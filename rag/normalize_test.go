@@ -0,0 +1,127 @@
+package rag
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aqua777/go-lancedb"
+	"github.com/stretchr/testify/suite"
+)
+
+// NormalizeTestSuite tests vector normalization and normalizing embeddings on insert
+type NormalizeTestSuite struct {
+	suite.Suite
+	store  *RAGStore
+	dbPath string
+	ctx    context.Context
+}
+
+// SetupTest runs before each test
+func (s *NormalizeTestSuite) SetupTest() {
+	tmpDir, err := os.MkdirTemp("", "rag_normalize_test_*")
+	s.Require().NoError(err)
+	s.dbPath = filepath.Join(tmpDir, "test.db")
+	s.ctx = context.Background()
+
+	store, err := NewRAGStoreWithConfig(s.dbPath, 128, 100, &noopLogger{}, DefaultRetryConfig(), nil)
+	s.Require().NoError(err)
+	s.store = store
+}
+
+// TearDownTest runs after each test
+func (s *NormalizeTestSuite) TearDownTest() {
+	if s.store != nil {
+		s.store.Close()
+	}
+	if s.dbPath != "" {
+		os.RemoveAll(filepath.Dir(s.dbPath))
+	}
+}
+
+// TestNormalizeTestSuite runs the normalization test suite
+func TestNormalizeTestSuite(t *testing.T) {
+	suite.Run(t, new(NormalizeTestSuite))
+}
+
+// l2Norm returns the Euclidean length of v
+func l2Norm(v []float32) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	return math.Sqrt(sum)
+}
+
+// scaledDocs returns documents whose embeddings have length 5 or more
+func scaledDocs(n int) []Document {
+	docs := makeTestDocs(n, 128, "scaled.txt")
+	for i := range docs {
+		for j := range docs[i].Embedding {
+			docs[i].Embedding[j] = 0
+		}
+		docs[i].Embedding[0] = 3
+		docs[i].Embedding[1+i%127] = 4 + float32(i)
+	}
+	return docs
+}
+
+func (s *NormalizeTestSuite) TestNormalizeVector() {
+	v := []float32{3, 4}
+	normalized := NormalizeVector(v)
+	s.InDelta(0.6, normalized[0], 1e-6)
+	s.InDelta(0.8, normalized[1], 1e-6)
+	s.Equal([]float32{3, 4}, v, "NormalizeVector must not modify its input")
+
+	for _, vec := range [][]float32{{1e-3, 2e-3, 3e-3}, {100, -200, 300, 0.5}, {0.1}} {
+		s.InDelta(1.0, l2Norm(NormalizeVector(vec)), 1e-6)
+		NormalizeVectorInPlace(vec)
+		s.InDelta(1.0, l2Norm(vec), 1e-6)
+	}
+
+	zero := []float32{0, 0, 0}
+	NormalizeVectorInPlace(zero)
+	s.Equal([]float32{0, 0, 0}, zero)
+	s.Empty(NormalizeVector(nil))
+}
+
+func (s *NormalizeTestSuite) TestAddDocumentsNormalizes() {
+	userID := "normalize_user"
+	docs := scaledDocs(3)
+	s.Require().NoError(s.store.AddDocumentsWithOptions(s.ctx, userID, docs, &AddDocumentsOptions{NormalizeEmbeddings: true}))
+	s.InDelta(5.0, l2Norm(docs[0].Embedding), 1e-6, "the caller's documents are not modified")
+
+	results, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 3})
+	s.Require().NoError(err)
+	s.Require().Len(results, 3)
+	for _, r := range results {
+		s.InDelta(1.0, l2Norm(r.Embedding), 1e-5)
+	}
+}
+
+func (s *NormalizeTestSuite) TestNormalizationOnlyForCosine() {
+	userID := "l2_user"
+	config := DefaultIndexConfig()
+	config.Metric = lancedb.DistanceMetricL2
+	s.Require().NoError(s.store.SetIndexConfig(userID, config))
+	docs := scaledDocs(1)
+	s.Require().NoError(s.store.AddDocumentsWithOptions(s.ctx, userID, docs, &AddDocumentsOptions{NormalizeEmbeddings: true}))
+
+	results, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.InDelta(5.0, l2Norm(results[0].Embedding), 1e-5)
+}
+
+func (s *NormalizeTestSuite) TestDisabledByDefault() {
+	userID := "default_user"
+	docs := scaledDocs(1)
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	results, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.InDelta(5.0, l2Norm(results[0].Embedding), 1e-5)
+}
@@ -95,6 +95,24 @@ func (p *ConnectionPool) Close() error {
 	return nil
 }
 
+// replace swaps a connection owned by the pool for a new one, so the pool closes
+// the new connection instead of the old one when it is closed
+func (p *ConnectionPool) replace(old, conn *lancedb.Connection) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("connection pool is closed")
+	}
+	for i, c := range p.connections {
+		if c == old {
+			p.connections[i] = conn
+			return nil
+		}
+	}
+	return fmt.Errorf("connection does not belong to the pool")
+}
+
 // Size returns the current number of connections in the pool
 func (p *ConnectionPool) Size() int {
 	p.mu.Lock()
@@ -129,27 +147,7 @@ func NewPooledRAGStore(pool *ConnectionPool, embeddingDim int, maxBatchSize int,
 		return nil, fmt.Errorf("failed to get connection from pool: %w", err)
 	}
 
-	if logger == nil {
-		logger = &noopLogger{}
-	}
-	
-	if metrics == nil {
-		metrics = &noopMetrics{}
-	}
-
-	store := &RAGStore{
-		conn:                conn,
-		dbPath:              pool.dbPath,
-		embeddingDim:        embeddingDim,
-		maxBatchSize:        maxBatchSize,
-		maxDocumentsForBM25: 10000, // default limit for BM25
-		logger:              logger,
-		retryConfig:         retryConfig,
-		metrics:             metrics,
-		indexConfigs:        make(map[string]*IndexConfig),
-		indexCreated:        make(map[string]bool),
-		userLocks:           make(map[string]*sync.Mutex),
-	}
+	store := newRAGStore(conn, pool.dbPath, embeddingDim, maxBatchSize, logger, retryConfig, metrics)
 
 	return &PooledRAGStore{
 		RAGStore: store,
@@ -159,9 +157,35 @@ func NewPooledRAGStore(pool *ConnectionPool, embeddingDim int, maxBatchSize int,
 
 // Close returns the connection to the pool instead of closing it
 func (s *PooledRAGStore) Close() error {
-	if s.conn != nil {
-		return s.pool.Put(s.conn)
+	s.tables.closeAll()
+
+	s.mu.Lock()
+	conn := s.conn
+	s.conn = nil
+	s.mu.Unlock()
+
+	if conn != nil {
+		return s.pool.Put(conn)
+	}
+	return nil
+}
+
+// Reconnect replaces the store's connection with a new one, which also takes the
+// old connection's place in the pool. See RAGStore.Reconnect.
+func (s *PooledRAGStore) Reconnect() error {
+	conn, err := lancedb.Connect(s.pool.dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to reconnect to database: %w", err)
+	}
+	if err := s.pool.replace(s.getConn(), conn); err != nil {
+		conn.Close()
+		return err
+	}
+
+	if old := s.swapConnection(conn); old != nil {
+		old.Close()
 	}
+	s.logger.Printf("Reconnected to database at %s", s.pool.dbPath)
 	return nil
 }
 
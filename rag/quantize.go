@@ -0,0 +1,293 @@
+package rag
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/aqua777/go-lancedb"
+)
+
+// EmbeddingStorage is the type a user's table stores embedding values as
+type EmbeddingStorage int
+
+const (
+	// EmbeddingStorageFloat32 stores embeddings as 32-bit floats (the default)
+	EmbeddingStorageFloat32 EmbeddingStorage = iota
+	// EmbeddingStorageFloat16 stores embeddings as 16-bit floats, halving their size.
+	// Values keep about three significant digits; searches use the vector index as usual.
+	EmbeddingStorageFloat16
+	// EmbeddingStorageInt8 stores each embedding as signed bytes plus a float32 scale,
+	// a quarter of the float32 size. LanceDB can't search int8 vectors, so searches
+	// quantize the query the same way and scan every matching row; no vector index is built.
+	EmbeddingStorageInt8
+)
+
+// String returns the storage type's name
+func (e EmbeddingStorage) String() string {
+	switch e {
+	case EmbeddingStorageFloat32:
+		return "float32"
+	case EmbeddingStorageFloat16:
+		return "float16"
+	case EmbeddingStorageInt8:
+		return "int8"
+	default:
+		return fmt.Sprintf("EmbeddingStorage(%d)", int(e))
+	}
+}
+
+// embeddingScaleColumn holds the per-row scale of int8 embeddings
+const embeddingScaleColumn = "embedding_scale"
+
+// QuantizeInt8 quantizes v to signed bytes with a symmetric scale: v[i] is approximately
+// codes[i] * scale, with an error of at most scale/2. The largest magnitude maps to ±127.
+func QuantizeInt8(v []float32) ([]int8, float32) {
+	var maxAbs float32
+	for _, x := range v {
+		if abs := float32(math.Abs(float64(x))); abs > maxAbs {
+			maxAbs = abs
+		}
+	}
+
+	codes := make([]int8, len(v))
+	if maxAbs == 0 {
+		return codes, 0
+	}
+	scale := maxAbs / 127
+	for i, x := range v {
+		q := math.Round(float64(x / scale))
+		codes[i] = int8(math.Max(-127, math.Min(127, q)))
+	}
+	return codes, scale
+}
+
+// DequantizeInt8 returns the float32 values approximated by codes and scale
+func DequantizeInt8(codes []int8, scale float32) []float32 {
+	v := make([]float32, len(codes))
+	for i, c := range codes {
+		v[i] = float32(c) * scale
+	}
+	return v
+}
+
+// embeddingType returns the Arrow type of embedding values for a storage type
+func (e EmbeddingStorage) embeddingType() arrow.DataType {
+	switch e {
+	case EmbeddingStorageFloat16:
+		return arrow.FixedWidthTypes.Float16
+	case EmbeddingStorageInt8:
+		return arrow.PrimitiveTypes.Int8
+	default:
+		return arrow.PrimitiveTypes.Float32
+	}
+}
+
+// embeddingStorageFromSchema detects the storage type of a user table's embeddings
+func embeddingStorageFromSchema(schema *arrow.Schema) EmbeddingStorage {
+	indices := schema.FieldIndices("embedding")
+	if len(indices) == 0 {
+		return EmbeddingStorageFloat32
+	}
+	listType, ok := schema.Field(indices[0]).Type.(*arrow.FixedSizeListType)
+	if !ok {
+		return EmbeddingStorageFloat32
+	}
+	switch listType.Elem().ID() {
+	case arrow.FLOAT16:
+		return EmbeddingStorageFloat16
+	case arrow.INT8:
+		return EmbeddingStorageInt8
+	default:
+		return EmbeddingStorageFloat32
+	}
+}
+
+// AddDocumentsQuantized adds documents like AddDocuments, storing the embeddings of a new
+// user table as storage. Embeddings are converted on insert and converted back to float32
+// in search results, so callers always work with float32. Later AddDocuments calls for the
+// user use the table's storage. Returns an error if the user's table already uses another
+// storage type. If the user's first insert fails, the storage type is forgotten, so it can
+// be retried with another.
+func (s *RAGStore) AddDocumentsQuantized(ctx context.Context, userID string, docs []Document, storage EmbeddingStorage) error {
+	if err := s.validateUserID(userID); err != nil {
+		return err
+	}
+	unregister, err := s.registerUserEmbeddingStorage(userID, storage)
+	if err != nil {
+		return err
+	}
+	if err := s.AddDocumentsWithProgress(ctx, userID, docs, nil); err != nil {
+		unregister()
+		return err
+	}
+	return nil
+}
+
+// registerUserEmbeddingStorage records the storage type for a user and returns a function
+// that forgets it again if the user's first insert fails.
+// Returns an error if the user already has a table, or a pending first insert, with a
+// different storage type.
+func (s *RAGStore) registerUserEmbeddingStorage(userID string, storage EmbeddingStorage) (func(), error) {
+	switch storage {
+	case EmbeddingStorageFloat32, EmbeddingStorageFloat16, EmbeddingStorageInt8:
+	default:
+		return nil, fmt.Errorf("unknown embedding storage %v", storage)
+	}
+
+	// Detect the storage of an existing table before taking the lock
+	s.lookupUserEmbeddingDim(userID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, registered := s.userStorages[userID]
+	if _, hasTable := s.userDims[userID]; hasTable || registered {
+		if existing != storage {
+			return nil, fmt.Errorf("embedding storage mismatch for user %s: table uses %v, got %v", userID, existing, storage)
+		}
+		return func() {}, nil
+	}
+	s.userStorages[userID] = storage
+
+	return func() {
+		// A table created before the failure uses the storage, so it stays recorded
+		if exists, err := s.TableExists(context.Background(), userID); err != nil || exists {
+			return
+		}
+		s.mu.Lock()
+		if s.userStorages[userID] == storage {
+			delete(s.userStorages, userID)
+		}
+		s.mu.Unlock()
+	}, nil
+}
+
+// userEmbeddingStorage returns the storage type of a user's embeddings, float32 for users
+// without a table unless set by AddDocumentsQuantized
+func (s *RAGStore) userEmbeddingStorage(userID string) EmbeddingStorage {
+	// Detecting the dimension from an existing table also records its storage
+	s.lookupUserEmbeddingDim(userID)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.userStorages[userID]
+}
+
+// documentColumns returns the columns to select to parse documents stored as storage
+func documentColumns(storage EmbeddingStorage) []string {
+	columns := []string{"id", "text", "document_name", "embedding", "metadata"}
+	if storage == EmbeddingStorageInt8 {
+		columns = append(columns, embeddingScaleColumn)
+	}
+	return columns
+}
+
+// searchQuantized runs a vector search over int8 embeddings by scanning the rows matching
+// the filters. The query is quantized like the stored embeddings, and distances are computed
+// from the codes and scales, so they match the distances between the dequantized vectors.
+// Results are sorted nearest first.
+func (s *RAGStore) searchQuantized(ctx context.Context, table *lancedb.Table, queryEmbedding []float32, opts *SearchOptions, dim int) ([]SearchResult, error) {
+	// The scan always reads the embeddings to compute distances; results are then
+	// projected to the selected columns
+	columns, err := searchColumns(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := searchFetchLimit(opts)
+	queryCodes, queryScale := QuantizeInt8(queryEmbedding)
+	var queryNormSq int64
+	for _, q := range queryCodes {
+		queryNormSq += int64(q) * int64(q)
+	}
+
+	predicate := ""
+	if len(opts.Filters) > 0 {
+		predicate = buildPredicate(opts.Filters)
+	}
+	codec := s.getMetadataCodec()
+
+	nearest := &distanceHeap{}
+	err = streamRecords(ctx, table, predicate, documentColumns(EmbeddingStorageInt8), func(record arrow.Record) error {
+		embeddingCol := recordColumn(record, "embedding").(*array.FixedSizeList)
+		codes := embeddingCol.ListValues().(*array.Int8)
+		scales := recordColumn(record, embeddingScaleColumn).(*array.Float32)
+
+		var batch []SearchResult
+		for i := 0; i < int(record.NumRows()); i++ {
+			var dot, normSq int64
+			start := (embeddingCol.Offset() + i) * dim
+			for j := 0; j < dim; j++ {
+				c := int64(codes.Value(start + j))
+				dot += c * int64(queryCodes[j])
+				normSq += c * c
+			}
+			distance := quantizedDistance(opts.DistanceType, dot, normSq, queryNormSq, scales.Value(i), queryScale)
+			if nearest.Len() == limit && distance >= (*nearest)[0].Score {
+				continue
+			}
+
+			// Parse the record only once one of its rows is near enough
+			if batch == nil {
+				parsed, err := parseSearchResults(record, dim, codec)
+				if err != nil {
+					return fmt.Errorf("failed to parse results: %w", err)
+				}
+				batch = parsed
+			}
+			result := projectSearchResult(batch[i], columns)
+			result.Score = distance
+			if nearest.Len() == limit {
+				heap.Pop(nearest)
+			}
+			heap.Push(nearest, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute search: %w", err)
+	}
+
+	results := make([]SearchResult, nearest.Len())
+	for i := len(results) - 1; i >= 0; i-- {
+		result := heap.Pop(nearest).(SearchResult)
+		result.Similarity = NormalizeScore(opts.DistanceType, result.Score)
+		results[i] = result
+	}
+	return results, nil
+}
+
+// quantizedDistance computes the distance under dt between two int8 vectors with scales
+// a and b, given the dot product and squared norms of their codes
+func quantizedDistance(dt lancedb.DistanceType, dot, normSqA, normSqB int64, a, b float32) float32 {
+	switch dt {
+	case lancedb.DistanceTypeCosine:
+		if normSqA == 0 || normSqB == 0 {
+			return 1
+		}
+		return float32(1 - float64(dot)/math.Sqrt(float64(normSqA)*float64(normSqB)))
+	case lancedb.DistanceTypeDot:
+		return float32(1 - float64(dot)*float64(a)*float64(b))
+	default:
+		sa, sb := float64(a), float64(b)
+		return float32(float64(normSqA)*sa*sa + float64(normSqB)*sb*sb - 2*float64(dot)*sa*sb)
+	}
+}
+
+// distanceHeap is a max-heap of results by distance, holding the nearest results seen so far
+type distanceHeap []SearchResult
+
+func (h distanceHeap) Len() int            { return len(h) }
+func (h distanceHeap) Less(i, j int) bool  { return h[i].Score > h[j].Score }
+func (h distanceHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *distanceHeap) Push(x interface{}) { *h = append(*h, x.(SearchResult)) }
+func (h *distanceHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,228 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aqua777/go-lancedb"
+	"github.com/stretchr/testify/suite"
+)
+
+// QuantizeTestSuite tests float16 and int8 embedding storage
+type QuantizeTestSuite struct {
+	suite.Suite
+	store  *RAGStore
+	dbPath string
+	ctx    context.Context
+}
+
+// SetupTest runs before each test
+func (s *QuantizeTestSuite) SetupTest() {
+	tmpDir, err := os.MkdirTemp("", "rag_quantize_test_*")
+	s.Require().NoError(err)
+	s.dbPath = filepath.Join(tmpDir, "test.db")
+	s.ctx = context.Background()
+
+	store, err := NewRAGStoreWithConfig(s.dbPath, 128, 100, &noopLogger{}, DefaultRetryConfig(), nil)
+	s.Require().NoError(err)
+	s.store = store
+}
+
+// TearDownTest runs after each test
+func (s *QuantizeTestSuite) TearDownTest() {
+	if s.store != nil {
+		s.store.Close()
+	}
+	if s.dbPath != "" {
+		os.RemoveAll(filepath.Dir(s.dbPath))
+	}
+}
+
+// TestQuantizeTestSuite runs the quantization test suite
+func TestQuantizeTestSuite(t *testing.T) {
+	suite.Run(t, new(QuantizeTestSuite))
+}
+
+// randomDocs returns n documents with distinct random unit embeddings
+func randomDocs(n, dim int, seed int64) []Document {
+	rng := rand.New(rand.NewSource(seed))
+	docs := make([]Document, n)
+	for i := range docs {
+		embedding := make([]float32, dim)
+		for j := range embedding {
+			embedding[j] = float32(rng.NormFloat64())
+		}
+		NormalizeVectorInPlace(embedding)
+		docs[i] = Document{
+			ID:           fmt.Sprintf("doc_%d", i),
+			Text:         fmt.Sprintf("document %d", i),
+			DocumentName: "random.txt",
+			Embedding:    embedding,
+		}
+	}
+	return docs
+}
+
+// perturb returns a copy of v with small noise added
+func perturb(v []float32, seed int64) []float32 {
+	rng := rand.New(rand.NewSource(seed))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x + float32(rng.NormFloat64()*0.01)
+	}
+	return out
+}
+
+func (s *QuantizeTestSuite) TestInt8RoundTrip() {
+	v := randomDocs(1, 128, 1)[0].Embedding
+	v[5] = -0.9
+
+	codes, scale := QuantizeInt8(v)
+	s.Len(codes, len(v))
+	s.InDelta(0.9/127, scale, 1e-6, "the largest magnitude maps to 127")
+	s.Equal(int8(-127), codes[5])
+
+	restored := DequantizeInt8(codes, scale)
+	for i := range v {
+		s.InDelta(v[i], restored[i], float64(scale)/2+1e-7)
+	}
+	s.Greater(cosineSimilarity(v, restored), 0.999)
+
+	zeroCodes, zeroScale := QuantizeInt8(make([]float32, 4))
+	s.Equal([]int8{0, 0, 0, 0}, zeroCodes)
+	s.Zero(zeroScale)
+}
+
+func (s *QuantizeTestSuite) TestQuantizedDistanceMatchesDequantized() {
+	docs := randomDocs(2, 64, 2)
+	a, scaleA := QuantizeInt8(docs[0].Embedding)
+	b, scaleB := QuantizeInt8(docs[1].Embedding)
+	da, db := DequantizeInt8(a, scaleA), DequantizeInt8(b, scaleB)
+
+	var dot, normA, normB int64
+	var fdot, fl2 float64
+	for i := range a {
+		dot += int64(a[i]) * int64(b[i])
+		normA += int64(a[i]) * int64(a[i])
+		normB += int64(b[i]) * int64(b[i])
+		fdot += float64(da[i]) * float64(db[i])
+		fl2 += float64(da[i]-db[i]) * float64(da[i]-db[i])
+	}
+
+	s.InDelta(1-cosineSimilarity(da, db), quantizedDistance(lancedb.DistanceTypeCosine, dot, normA, normB, scaleA, scaleB), 1e-5)
+	s.InDelta(fl2, quantizedDistance(lancedb.DistanceTypeL2, dot, normA, normB, scaleA, scaleB), 1e-5)
+	s.InDelta(1-fdot, quantizedDistance(lancedb.DistanceTypeDot, dot, normA, normB, scaleA, scaleB), 1e-5)
+}
+
+func (s *QuantizeTestSuite) TestRecordRoundTrip() {
+	docs := randomDocs(3, 16, 3)
+	for _, storage := range []EmbeddingStorage{EmbeddingStorageFloat32, EmbeddingStorageFloat16, EmbeddingStorageInt8} {
+		schema := documentSchemaFor(16, storage)
+		s.Equal(storage, embeddingStorageFromSchema(schema))
+
+		record, err := buildDocumentRecord(schema, docs, JSONMetadataCodec{})
+		s.Require().NoError(err)
+		results, err := parseSearchResults(record, 16, JSONMetadataCodec{})
+		record.Release()
+		s.Require().NoError(err)
+		s.Require().Len(results, 3)
+
+		for i, result := range results {
+			s.Equal(docs[i].ID, result.ID)
+			s.Zero(result.Score, "%v: the scale column must not be read as a distance", storage)
+			for j := range docs[i].Embedding {
+				s.InDelta(docs[i].Embedding[j], result.Embedding[j], 0.005, "%v", storage)
+			}
+		}
+	}
+}
+
+func (s *QuantizeTestSuite) TestSearchQuantized() {
+	docs := randomDocs(50, 128, 4)
+	for _, storage := range []EmbeddingStorage{EmbeddingStorageFloat16, EmbeddingStorageInt8} {
+		userID := "quantized_" + storage.String()
+		s.Require().NoError(s.store.AddDocumentsQuantized(s.ctx, userID, docs, storage))
+
+		for _, target := range []int{0, 17, 42} {
+			query := perturb(docs[target].Embedding, int64(target))
+			results, err := s.store.Search(s.ctx, userID, query, &SearchOptions{Limit: 3, DistanceType: lancedb.DistanceTypeCosine})
+			s.Require().NoError(err)
+			s.Require().NotEmpty(results)
+			s.Equal(docs[target].ID, results[0].ID, "%v: nearest neighbor of doc %d", storage, target)
+			s.Less(results[0].Score, results[1].Score)
+			s.Greater(cosineSimilarity(docs[target].Embedding, results[0].Embedding), 0.999)
+			for j := range docs[target].Embedding {
+				s.InDelta(docs[target].Embedding[j], results[0].Embedding[j], 0.01)
+			}
+		}
+
+		// Filters still apply to quantized searches
+		results, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{
+			Limit:        5,
+			DistanceType: lancedb.DistanceTypeCosine,
+			Filters:      map[string]interface{}{"id": docs[7].ID},
+		})
+		s.Require().NoError(err)
+		s.Require().Len(results, 1)
+		s.Equal(docs[7].ID, results[0].ID)
+	}
+}
+
+func (s *QuantizeTestSuite) TestStorageIsKeptForLaterInserts() {
+	userID := "int8_user"
+	docs := randomDocs(10, 128, 5)
+	s.Require().NoError(s.store.AddDocumentsQuantized(s.ctx, userID, docs[:5], EmbeddingStorageInt8))
+
+	// Plain inserts use the table's storage
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs[5:]))
+	count, err := s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(10), count)
+
+	err = s.store.AddDocumentsQuantized(s.ctx, userID, docs[:1], EmbeddingStorageFloat16)
+	s.Error(err)
+	s.Contains(err.Error(), "storage mismatch")
+
+	// A new store detects the storage from the table
+	other, err := NewRAGStoreWithConfig(s.dbPath, 128, 100, &noopLogger{}, DefaultRetryConfig(), nil)
+	s.Require().NoError(err)
+	defer other.Close()
+	s.Equal(EmbeddingStorageInt8, other.userEmbeddingStorage(userID))
+	results, err := other.Search(s.ctx, userID, docs[8].Embedding, &SearchOptions{Limit: 1, DistanceType: lancedb.DistanceTypeCosine})
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.Equal(docs[8].ID, results[0].ID)
+}
+
+func (s *QuantizeTestSuite) TestFailedFirstInsertForgetsStorage() {
+	userID := "retry_user"
+	docs := randomDocs(3, 128, 6)
+
+	invalid := append([]Document(nil), docs...)
+	invalid[1].Embedding = invalid[1].Embedding[:64]
+	s.Error(s.store.AddDocumentsQuantized(s.ctx, userID, invalid, EmbeddingStorageInt8))
+
+	// The failed insert created no table, so another storage type can be used
+	s.Require().NoError(s.store.AddDocumentsQuantized(s.ctx, userID, docs, EmbeddingStorageFloat16))
+	s.Equal(EmbeddingStorageFloat16, s.store.userEmbeddingStorage(userID))
+}
+
+func (s *QuantizeTestSuite) TestPendingStorageRegistrationConflicts() {
+	userID := "pending_user"
+	unregister, err := s.store.registerUserEmbeddingStorage(userID, EmbeddingStorageInt8)
+	s.Require().NoError(err)
+
+	// A concurrent first insert with another storage type is rejected
+	_, err = s.store.registerUserEmbeddingStorage(userID, EmbeddingStorageFloat16)
+	s.Error(err)
+	s.Contains(err.Error(), "storage mismatch")
+
+	unregister()
+	unregister, err = s.store.registerUserEmbeddingStorage(userID, EmbeddingStorageFloat16)
+	s.Require().NoError(err)
+	unregister()
+}
@@ -3,7 +3,10 @@ package rag
 import (
 	"context"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/array"
@@ -18,6 +21,46 @@ type SearchResult struct {
 	Embedding    []float32
 	Metadata     map[string]interface{}
 	Score        float32 // Distance score (lower is better for L2, higher for cosine)
+
+	// Similarity is Score converted by NormalizeScore for the metric that computed it: the
+	// search's DistanceType, or the index's metric if the index was built with another one.
+	// Between 0 and 1, higher is more similar. It is only set by vector searches.
+	Similarity float32
+
+	// EmbeddingHandle owns the Arrow buffer Embedding points into when the search set
+	// SearchOptions.UnsafeZeroCopyEmbeddings; nil when Embedding is a copy.
+	EmbeddingHandle *EmbeddingHandle
+}
+
+// NormalizeScore maps a raw distance returned by a search with the given metric to a
+// similarity between 0 and 1, where higher is more similar:
+//
+//   - Cosine: (2 - distance) / 2, i.e. the cosine similarity mapped from [-1, 1] to [0, 1]
+//   - L2: 1 / (1 + distance)
+//   - Dot: the logistic function of the dot product (1 - distance)
+//
+// Scores from different metrics aren't comparable with each other, but within a metric a
+// closer vector always gets a higher similarity.
+func NormalizeScore(dt lancedb.DistanceType, rawDistance float32) float32 {
+	switch dt {
+	case lancedb.DistanceTypeL2:
+		if rawDistance < 0 {
+			return 1
+		}
+		return 1 / (1 + rawDistance)
+	case lancedb.DistanceTypeDot:
+		return float32(1 / (1 + math.Exp(float64(rawDistance)-1)))
+	default:
+		// Cosine distances range from 0 (same direction) to 2 (opposite directions)
+		similarity := (2 - rawDistance) / 2
+		if similarity < 0 {
+			return 0
+		}
+		if similarity > 1 {
+			return 1
+		}
+		return similarity
+	}
 }
 
 // SearchOptions configures search behavior
@@ -25,13 +68,170 @@ type SearchOptions struct {
 	Limit        int                    // Maximum number of results (default: 10)
 	Filters      map[string]interface{} // Metadata filters (applied as SQL predicates)
 	DistanceType lancedb.DistanceType   // Distance metric (default: Cosine)
+	BypassIndex  bool                   // Scan every row instead of using the vector index, so DistanceType is honored even if the index uses another metric
+	Timeout      time.Duration          // Deadline for the search on top of the caller's context; zero means no timeout
+
+	// DedupeByDocument keeps at most MaxChunksPerDocument of the best chunks from each
+	// DocumentName, so one long document can't crowd the others out of the results.
+	// The search fetches five times Limit candidates to fill the results.
+	DedupeByDocument     bool
+	MaxChunksPerDocument int // Chunks kept per document with DedupeByDocument (default: 1)
+
+	// EmbeddingModel names the model that produced the query embedding (default: the store's,
+	// see SetEmbeddingModel). The search fails with ErrEmbeddingModelMismatch if the user's
+	// table records a different model.
+	EmbeddingModel string
+
+	// IncludeEmbedding controls whether results carry their embeddings (default: true).
+	// Set it to false when only text and metadata are needed: the search then doesn't read
+	// the embedding column and leaves SearchResult.Embedding nil, saving memory and copying
+	// for large result sets.
+	IncludeEmbedding *bool
+
+	// Columns selects which document columns the search returns, from "id", "text",
+	// "document_name", "embedding" and "metadata" (default: all of them). The id and
+	// distance are always returned, and document_name is added for DedupeByDocument.
+	// Fields of unselected columns are left zero, so a titles-only search can skip
+	// reading large texts and embeddings.
+	Columns []string
+
+	// MinSimilarity drops results whose Similarity (see NormalizeScore) is below it, so a
+	// search may return fewer than Limit results. Zero keeps every result.
+	MinSimilarity float32
+
+	// UnsafeZeroCopyEmbeddings makes each result's Embedding a view of the Arrow buffer the
+	// search read instead of a copy, for hot paths that read the embeddings and discard them
+	// straight away. It only applies to float32 embedding storage with Search, SearchBatch
+	// and SearchManyUsers; otherwise embeddings are copied and EmbeddingHandle is nil.
+	//
+	// UNSAFE: the results of a search share one EmbeddingHandle, and the caller MUST call
+	// its Release exactly once (or ReleaseEmbeddings on the results) when done with them.
+	// After that, reading any of the embeddings is a use-after-free that neither the
+	// compiler nor the race detector reports. Don't modify the embeddings, append to them
+	// or keep them beyond the handle's lifetime; copy what must outlive it, and Retain the
+	// handle before sharing the results with another goroutine.
+	UnsafeZeroCopyEmbeddings bool
+}
+
+// includeEmbedding reports whether the search results should carry embeddings
+func (opts *SearchOptions) includeEmbedding() bool {
+	return opts.IncludeEmbedding == nil || *opts.IncludeEmbedding
+}
+
+// searchableColumns are the document columns a search can return, in table order
+var searchableColumns = []string{"id", "text", "document_name", "embedding", "metadata"}
+
+// searchColumns returns the columns a search selects, followed by the distance.
+// Returns an error if opts.Columns names a column that can't be selected.
+func searchColumns(opts *SearchOptions) ([]string, error) {
+	selected := map[string]bool{"id": true}
+	if len(opts.Columns) == 0 {
+		for _, column := range searchableColumns {
+			selected[column] = true
+		}
+	}
+	for _, column := range opts.Columns {
+		valid := false
+		for _, searchable := range searchableColumns {
+			valid = valid || column == searchable
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid search column %q", column)
+		}
+		selected[column] = true
+	}
+	if opts.DedupeByDocument {
+		selected["document_name"] = true
+	}
+	if !opts.includeEmbedding() {
+		selected["embedding"] = false
+	}
+
+	columns := make([]string, 0, len(searchableColumns)+1)
+	for _, column := range searchableColumns {
+		if selected[column] {
+			columns = append(columns, column)
+		}
+	}
+	return append(columns, "_distance"), nil
+}
+
+// projectSearchResult zeroes the fields of result whose columns aren't in columns
+func projectSearchResult(result SearchResult, columns []string) SearchResult {
+	projected := SearchResult{Score: result.Score, Similarity: result.Similarity}
+	for _, column := range columns {
+		switch column {
+		case "id":
+			projected.ID = result.ID
+		case "text":
+			projected.Text = result.Text
+		case "document_name":
+			projected.DocumentName = result.DocumentName
+		case "embedding":
+			projected.Embedding = result.Embedding
+		case "metadata":
+			projected.Metadata = result.Metadata
+		}
+	}
+	return projected
+}
+
+// defaultSearchOptions returns the options used when a search is given none
+func defaultSearchOptions() SearchOptions {
+	return SearchOptions{
+		Limit:        10,
+		DistanceType: lancedb.DistanceTypeCosine,
+	}
+}
+
+// SearchInfo describes how a search was executed
+type SearchInfo struct {
+	IndexUsed bool          // Whether the search used a vector index; false means a brute-force scan
+	TotalRows int64         // Rows in the user's table, i.e. the candidates considered
+	Elapsed   time.Duration // Time taken by the search
+
+	// DistanceTypeMismatch is set when the requested DistanceType differs from the metric the
+	// user's index was built with, so results may not be ranked by the requested metric.
+	// Set SearchOptions.BypassIndex to search with the requested metric.
+	DistanceTypeMismatch bool
 }
 
 // Search performs vector similarity search on the user's documents
 func (s *RAGStore) Search(ctx context.Context, userID string, queryEmbedding []float32, opts *SearchOptions) ([]SearchResult, error) {
-	if len(queryEmbedding) != s.embeddingDim {
+	return s.search(ctx, userID, queryEmbedding, opts, nil)
+}
+
+// SearchWithInfo performs a vector similarity search like Search, and also reports whether
+// a vector index was used. Until a user's table reaches the index threshold (see
+// SetMinRowsForIndex), searches scan every row.
+func (s *RAGStore) SearchWithInfo(ctx context.Context, userID string, queryEmbedding []float32, opts *SearchOptions) ([]SearchResult, *SearchInfo, error) {
+	start := time.Now()
+	info := &SearchInfo{}
+	results, err := s.search(ctx, userID, queryEmbedding, opts, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	info.Elapsed = time.Since(start)
+	return results, info, nil
+}
+
+// search runs a vector search, filling in info if it is non-nil
+func (s *RAGStore) search(ctx context.Context, userID string, queryEmbedding []float32, opts *SearchOptions, info *SearchInfo) (results []SearchResult, err error) {
+	ctx, span := s.startSpan(ctx, "rag.Search", userID)
+	defer func() {
+		span.SetAttribute(SpanAttrResultCount, len(results))
+		endSpan(span, err)
+	}()
+
+	if err := s.validateUserID(userID); err != nil {
+		return nil, err
+	}
+
+	// Validate the query against the user's embedding dimension
+	dim := s.userEmbeddingDim(userID)
+	if len(queryEmbedding) != dim {
 		return nil, fmt.Errorf("query embedding dimension mismatch: expected %d, got %d",
-			s.embeddingDim, len(queryEmbedding))
+			dim, len(queryEmbedding))
 	}
 
 	// Check for context cancellation
@@ -43,14 +243,17 @@ func (s *RAGStore) Search(ctx context.Context, userID string, queryEmbedding []f
 
 	// Set defaults
 	if opts == nil {
-		opts = &SearchOptions{
-			Limit:        10,
-			DistanceType: lancedb.DistanceTypeCosine,
-		}
+		searchOpts := defaultSearchOptions()
+		opts = &searchOpts
 	}
 	if opts.Limit <= 0 {
 		opts.Limit = 10
 	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
 
 	// Check if table exists
 	exists, err := s.TableExists(ctx, userID)
@@ -61,52 +264,414 @@ func (s *RAGStore) Search(ctx context.Context, userID string, queryEmbedding []f
 		return []SearchResult{}, nil // No documents yet
 	}
 
-	table, err := s.conn.OpenTable(s.getTableName(userID))
+	table, release, err := s.acquireTable(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open table for user %s: %w", userID, err)
 	}
-	defer table.Close()
+	defer release()
 
-	// Build query
-	query := table.Query()
-	defer query.Close()
+	if err := s.checkEmbeddingModel(table, userID, s.searchEmbeddingModel(opts)); err != nil {
+		return nil, err
+	}
 
-	query = query.
-		NearestTo(queryEmbedding).
-		SetDistanceType(opts.DistanceType).
-		Limit(opts.Limit).
-		Select("id", "text", "document_name", "embedding", "metadata", "_distance")
+	scoreType, mismatch := s.checkDistanceType(userID, opts)
+	storage := s.userEmbeddingStorage(userID)
 
-	// Apply filters if provided
-	if len(opts.Filters) > 0 {
-		predicate := buildPredicate(opts.Filters)
-		query = query.Where(predicate)
+	if info != nil {
+		info.DistanceTypeMismatch = mismatch
+		info.TotalRows, err = table.CountRows()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count rows: %w", err)
+		}
+
+		// int8 embeddings are always scanned, and BypassIndex skips the index even if it exists
+		if !opts.BypassIndex && storage != EmbeddingStorageInt8 {
+			s.mu.RLock()
+			info.IndexUsed = s.indexCreated[userID]
+			s.mu.RUnlock()
+			if !info.IndexUsed {
+				// The index may have been built by an earlier store on the same database
+				info.IndexUsed = hasVectorIndex(table)
+			}
+		}
+	}
+
+	return s.searchTable(ctx, table, queryEmbedding, opts, dim, storage, scoreType)
+}
+
+// searchEmbeddingModel returns the model a search's query embeddings come from
+func (s *RAGStore) searchEmbeddingModel(opts *SearchOptions) string {
+	if opts.EmbeddingModel != "" {
+		return opts.EmbeddingModel
+	}
+	return s.GetEmbeddingModel()
+}
+
+// SearchBatch runs several vector searches against the user's documents, opening the
+// table once. This suits pipelines that expand a query into several sub-queries.
+// The returned slice has one group of results per query embedding, in the same order,
+// and each group holds at most opts.Limit results.
+func (s *RAGStore) SearchBatch(ctx context.Context, userID string, queryEmbeddings [][]float32, opts *SearchOptions) (groups [][]SearchResult, err error) {
+	ctx, span := s.startSpan(ctx, "rag.SearchBatch", userID)
+	defer func() {
+		total := 0
+		for _, group := range groups {
+			total += len(group)
+		}
+		span.SetAttribute(SpanAttrResultCount, total)
+		endSpan(span, err)
+	}()
+
+	if err := s.validateUserID(userID); err != nil {
+		return nil, err
+	}
+
+	// Validate every query before running any of them
+	dim := s.userEmbeddingDim(userID)
+	for i, queryEmbedding := range queryEmbeddings {
+		if len(queryEmbedding) != dim {
+			return nil, fmt.Errorf("query embedding %d dimension mismatch: expected %d, got %d",
+				i, dim, len(queryEmbedding))
+		}
+	}
+
+	// Set defaults without modifying the caller's options
+	searchOpts := defaultSearchOptions()
+	if opts != nil {
+		searchOpts = *opts
+	}
+	if searchOpts.Limit <= 0 {
+		searchOpts.Limit = 10
+	}
+	if searchOpts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, searchOpts.Timeout)
+		defer cancel()
+	}
+
+	groups = make([][]SearchResult, len(queryEmbeddings))
+	if len(queryEmbeddings) == 0 {
+		return groups, nil
+	}
+
+	// Check if table exists
+	exists, err := s.TableExists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		// No documents yet
+		for i := range groups {
+			groups[i] = []SearchResult{}
+		}
+		return groups, nil
 	}
 
-	// Execute query
-	records, err := query.Execute()
+	table, release, err := s.acquireTable(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	defer release()
+
+	if err := s.checkEmbeddingModel(table, userID, s.searchEmbeddingModel(&searchOpts)); err != nil {
+		return nil, err
+	}
+
+	scoreType, _ := s.checkDistanceType(userID, &searchOpts)
+	storage := s.userEmbeddingStorage(userID)
+
+	for i, queryEmbedding := range queryEmbeddings {
+		// Check for context cancellation between queries
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		results, err := s.searchTable(ctx, table, queryEmbedding, &searchOpts, dim, storage, scoreType)
+		if err != nil {
+			for _, group := range groups[:i] {
+				ReleaseEmbeddings(group)
+			}
+			return nil, fmt.Errorf("query %d: %w", i, err)
+		}
+		groups[i] = results
+	}
+
+	return groups, nil
+}
+
+// checkDistanceType reports whether the search's distance type differs from the metric the user's
+// index was built with, and returns the distance type of the search's result distances: the
+// index's metric on a mismatch, since the index computes them, and opts.DistanceType otherwise.
+// The first mismatch for a user is logged as a warning.
+// Searches that bypass the index, or users whose index wasn't built by this store, are not checked.
+func (s *RAGStore) checkDistanceType(userID string, opts *SearchOptions) (lancedb.DistanceType, bool) {
+	if opts.BypassIndex {
+		return opts.DistanceType, false
+	}
+
+	s.mu.RLock()
+	indexMetric, built := s.indexMetrics[userID]
+	warned := s.metricWarnings[userID]
+	s.mu.RUnlock()
+
+	if !built || distanceMetricFor(opts.DistanceType) == indexMetric {
+		return opts.DistanceType, false
+	}
+
+	if !warned {
+		s.mu.Lock()
+		s.metricWarnings[userID] = true
+		s.mu.Unlock()
+		s.logger.Printf("Warning: search for user %s requested distance type %s but the index was built with %s; "+
+			"set SearchOptions.BypassIndex to search with the requested metric",
+			userID, distanceTypeName(opts.DistanceType), distanceMetricName(indexMetric))
+	}
+	return distanceTypeForMetric(indexMetric), true
+}
+
+// distanceMetricFor returns the index metric that corresponds to a query distance type
+func distanceMetricFor(dt lancedb.DistanceType) lancedb.DistanceMetric {
+	switch dt {
+	case lancedb.DistanceTypeCosine:
+		return lancedb.DistanceMetricCosine
+	case lancedb.DistanceTypeDot:
+		return lancedb.DistanceMetricDot
+	default:
+		return lancedb.DistanceMetricL2
+	}
+}
+
+// distanceTypeForMetric returns the query distance type that corresponds to an index metric
+func distanceTypeForMetric(metric lancedb.DistanceMetric) lancedb.DistanceType {
+	switch metric {
+	case lancedb.DistanceMetricCosine:
+		return lancedb.DistanceTypeCosine
+	case lancedb.DistanceMetricDot:
+		return lancedb.DistanceTypeDot
+	default:
+		return lancedb.DistanceTypeL2
+	}
+}
+
+// distanceTypeName returns a readable name for a query distance type
+func distanceTypeName(dt lancedb.DistanceType) string {
+	return distanceMetricName(distanceMetricFor(dt))
+}
+
+// distanceMetricName returns a readable name for an index metric
+func distanceMetricName(metric lancedb.DistanceMetric) string {
+	switch metric {
+	case lancedb.DistanceMetricCosine:
+		return "cosine"
+	case lancedb.DistanceMetricDot:
+		return "dot"
+	case lancedb.DistanceMetricL2:
+		return "L2"
+	default:
+		return fmt.Sprintf("metric(%d)", int(metric))
+	}
+}
+
+// searchTable runs a vector search against an open table whose embeddings are stored as
+// storage and parses the results. scoreType is the distance type of the distances the search
+// returns (see checkDistanceType); int8 searches compute theirs with opts.DistanceType.
+func (s *RAGStore) searchTable(ctx context.Context, table *lancedb.Table, queryEmbedding []float32, opts *SearchOptions, dim int, storage EmbeddingStorage, scoreType lancedb.DistanceType) ([]SearchResult, error) {
+	if storage == EmbeddingStorageInt8 {
+		results, err := s.searchQuantized(ctx, table, queryEmbedding, opts, dim)
+		if err != nil {
+			return nil, err
+		}
+		if opts.DedupeByDocument {
+			results = dedupeByDocument(results, opts.MaxChunksPerDocument, opts.Limit)
+		}
+		return aboveMinSimilarity(results, opts.MinSimilarity), nil
+	}
+
+	columns, err := searchColumns(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build query
+	query := buildSearchQuery(table, queryEmbedding, opts, columns)
+	defer query.Close()
+
+	// Execute query, cancelling it if ctx is done first
+	var records []arrow.Record
+	err = s.withRetry(ctx, "rag.Search", func() error {
+		var execErr error
+		records, execErr = query.ExecuteContext(ctx)
+		return execErr
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute search: %w", err)
 	}
 
+	var handle *EmbeddingHandle
+	if opts.UnsafeZeroCopyEmbeddings {
+		handle = newEmbeddingHandle()
+	}
+
 	// Parse results
 	results := make([]SearchResult, 0)
-	for _, record := range records {
-		recordResults, err := parseSearchResults(record, s.embeddingDim)
+	for i, record := range records {
+		recordResults, err := parseVectorSearchResults(record, dim, s.getMetadataCodec(), scoreType, handle)
 		if err != nil {
 			// Clean up
-			for _, r := range records {
+			for _, r := range records[i:] {
 				r.Release()
 			}
+			handle.Release()
 			return nil, fmt.Errorf("failed to parse results: %w", err)
 		}
 		results = append(results, recordResults...)
 		record.Release()
 	}
 
+	if opts.DedupeByDocument {
+		results = dedupeByDocument(results, opts.MaxChunksPerDocument, opts.Limit)
+	}
+	results = aboveMinSimilarity(results, opts.MinSimilarity)
+	if handle != nil && (len(results) == 0 || results[0].EmbeddingHandle == nil) {
+		// No result shares the buffers, so the caller has nothing to release
+		handle.Release()
+	}
 	return results, nil
 }
 
+// aboveMinSimilarity drops the results whose similarity is below minSimilarity, keeping the
+// others in order. Every result is checked, since an index's approximate distances needn't
+// be sorted by similarity. The results are filtered in place.
+func aboveMinSimilarity(results []SearchResult, minSimilarity float32) []SearchResult {
+	if minSimilarity <= 0 {
+		return results
+	}
+	kept := results[:0]
+	for _, result := range results {
+		if result.Similarity >= minSimilarity {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// dedupeOverfetchFactor is how many candidates per result a deduplicated search fetches
+const dedupeOverfetchFactor = 5
+
+// searchFetchLimit returns how many candidates to fetch for the search options
+func searchFetchLimit(opts *SearchOptions) int {
+	if opts.DedupeByDocument {
+		return opts.Limit * dedupeOverfetchFactor
+	}
+	return opts.Limit
+}
+
+// documentChunkLimiter counts results per document for DedupeByDocument
+type documentChunkLimiter struct {
+	maxChunks int
+	counts    map[string]int
+}
+
+func newDocumentChunkLimiter(maxChunks int) *documentChunkLimiter {
+	if maxChunks <= 0 {
+		maxChunks = 1
+	}
+	return &documentChunkLimiter{maxChunks: maxChunks, counts: make(map[string]int)}
+}
+
+// allow reports whether another chunk of the document may be kept, counting it if so
+func (l *documentChunkLimiter) allow(documentName string) bool {
+	if l.counts[documentName] >= l.maxChunks {
+		return false
+	}
+	l.counts[documentName]++
+	return true
+}
+
+// dedupeByDocument keeps the first maxChunks results of each document, up to limit results.
+// Results must be ordered best first.
+func dedupeByDocument(results []SearchResult, maxChunks, limit int) []SearchResult {
+	limiter := newDocumentChunkLimiter(maxChunks)
+	kept := results[:0]
+	for _, result := range results {
+		if len(kept) == limit {
+			break
+		}
+		if limiter.allow(result.DocumentName) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// buildSearchQuery builds the vector search query for the given options, selecting columns.
+// The caller is responsible for closing the returned query.
+func buildSearchQuery(table *lancedb.Table, queryEmbedding []float32, opts *SearchOptions, columns []string) *lancedb.Query {
+	query := table.Query().
+		NearestTo(queryEmbedding).
+		SetDistanceType(opts.DistanceType).
+		Limit(searchFetchLimit(opts)).
+		Select(columns...)
+
+	if opts.BypassIndex {
+		query = query.BypassVectorIndex()
+	}
+
+	// Apply filters if provided
+	if len(opts.Filters) > 0 {
+		predicate := buildPredicate(opts.Filters)
+		query = query.Where(predicate)
+	}
+
+	return query
+}
+
+// SearchStream performs vector similarity search and streams results as they are decoded,
+// instead of materializing all result batches first. Useful for large limits or when
+// feeding results to an LLM incrementally.
+// Both channels are closed when the search finishes; at most one error is sent.
+// Cancel ctx to stop early; the background goroutine exits promptly.
+func (s *RAGStore) SearchStream(ctx context.Context, userID string, queryEmbedding []float32, opts *SearchOptions) (<-chan SearchResult, <-chan error) {
+	results := make(chan SearchResult)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(results)
+		defer close(errc)
+
+		if err := s.searchStream(ctx, userID, queryEmbedding, opts, results); err != nil {
+			errc <- err
+		}
+	}()
+
+	return results, errc
+}
+
+// searchStream runs a streaming search, sending each result to out as it is parsed
+func (s *RAGStore) searchStream(ctx context.Context, userID string, queryEmbedding []float32, opts *SearchOptions, out chan<- SearchResult) error {
+	iter, err := s.openSearchIterator(ctx, userID, queryEmbedding, opts)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for {
+		result, ok, err := iter.Next()
+		if err != nil || !ok {
+			return err
+		}
+		// Wait on the caller's context: the iterator cancels its timeout once it closes,
+		// which may happen before its last result is sent
+		select {
+		case out <- *result:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 // SearchByDocument searches within a specific document's chunks
 func (s *RAGStore) SearchByDocument(ctx context.Context, userID string, queryEmbedding []float32, documentName string, limit int) ([]SearchResult, error) {
 	if documentName == "" {
@@ -212,6 +777,9 @@ func buildPredicate(filters map[string]interface{}) string {
 		return ""
 	}
 
+	// Sort so the same filters always give the same predicate (map order is random)
+	sort.Strings(predicates)
+
 	// Join with AND
 	result := predicates[0]
 	for i := 1; i < len(predicates); i++ {
@@ -220,47 +788,109 @@ func buildPredicate(filters map[string]interface{}) string {
 	return result
 }
 
-// parseSearchResults parses Arrow records into SearchResult structs
-func parseSearchResults(record arrow.Record, embeddingDim int) ([]SearchResult, error) {
+// searchResultColumnTypes are the types of the columns parseSearchResults reads
+var searchResultColumnTypes = map[string]arrow.Type{
+	"id":                 arrow.STRING,
+	"text":               arrow.STRING,
+	"document_name":      arrow.STRING,
+	"embedding":          arrow.FIXED_SIZE_LIST,
+	"metadata":           arrow.STRING,
+	"_distance":          arrow.FLOAT32,
+	embeddingScaleColumn: arrow.FLOAT32,
+}
+
+// recordColumn returns the column of record named name, or nil if there is none
+func recordColumn(record arrow.Record, name string) arrow.Array {
+	if indices := record.Schema().FieldIndices(name); len(indices) > 0 {
+		return record.Column(indices[0])
+	}
+	return nil
+}
+
+// parseSearchResults parses Arrow records into SearchResult structs, decoding metadata with codec.
+// Columns are looked up by name, in any order; fields of missing columns are left zero and
+// unknown columns are ignored. Returns an error if a known column has an unexpected type.
+func parseSearchResults(record arrow.Record, embeddingDim int, codec MetadataCodec) ([]SearchResult, error) {
+	return parseSearchResultsSharing(record, embeddingDim, codec, nil)
+}
+
+// parseSearchResultsSharing parses like parseSearchResults. If handle isn't nil and the
+// embeddings are float32, the results' embeddings point into the record's buffer instead
+// of being copied, and handle retains the record.
+func parseSearchResultsSharing(record arrow.Record, embeddingDim int, codec MetadataCodec, handle *EmbeddingHandle) ([]SearchResult, error) {
 	numRows := int(record.NumRows())
 	results := make([]SearchResult, numRows)
 
-	idCol := record.Column(0).(*array.String)
-	textCol := record.Column(1).(*array.String)
-	docNameCol := record.Column(2).(*array.String)
-	embeddingCol := record.Column(3).(*array.FixedSizeList)
-	metadataCol := record.Column(4).(*array.String)
-	
-	// Distance column is optional (column 5 if present)
-	var distanceCol *array.Float32
-	if record.NumCols() > 5 {
-		distanceCol = record.Column(5).(*array.Float32)
+	// Check the types first, so the lookups below only miss for absent columns
+	for _, field := range record.Schema().Fields() {
+		if want, ok := searchResultColumnTypes[field.Name]; ok && field.Type.ID() != want {
+			return nil, fmt.Errorf("column %s has type %s, expected %s", field.Name, field.Type, want)
+		}
 	}
+	column := func(name string) arrow.Array {
+		return recordColumn(record, name)
+	}
+	idCol, _ := column("id").(*array.String)
+	textCol, _ := column("text").(*array.String)
+	docNameCol, _ := column("document_name").(*array.String)
+	embeddingCol, _ := column("embedding").(*array.FixedSizeList)
+	metadataCol, _ := column("metadata").(*array.String)
+	distanceCol, _ := column("_distance").(*array.Float32)
+	scaleCol, _ := column(embeddingScaleColumn).(*array.Float32)
 
-	// Get the underlying float32 values array
-	embeddingValues := embeddingCol.ListValues().(*array.Float32)
+	// Embeddings are returned as float32 whatever type they are stored as
+	var embeddingValue func(int) float32
+	var sharedValues []float32
+	if embeddingCol != nil {
+		if size := embeddingCol.DataType().(*arrow.FixedSizeListType).Len(); int(size) != embeddingDim {
+			return nil, fmt.Errorf("embedding column has dimension %d, expected %d", size, embeddingDim)
+		}
+		if values, ok := embeddingCol.ListValues().(*array.Float32); ok && handle != nil {
+			sharedValues = values.Float32Values()
+		} else {
+			var err error
+			embeddingValue, err = embeddingValueReader(embeddingCol.ListValues(), scaleCol, embeddingDim)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	for i := 0; i < numRows; i++ {
 		// IMPORTANT: Copy strings explicitly to avoid referencing freed Arrow memory
 		// Arrow string columns point to the record's buffer, which gets freed on Release()
-		results[i].ID = string([]byte(idCol.Value(i)))
-		results[i].Text = string([]byte(textCol.Value(i)))
-		results[i].DocumentName = string([]byte(docNameCol.Value(i)))
+		if idCol != nil {
+			results[i].ID = string([]byte(idCol.Value(i)))
+		}
+		if textCol != nil {
+			results[i].Text = string([]byte(textCol.Value(i)))
+		}
+		if docNameCol != nil {
+			results[i].DocumentName = string([]byte(docNameCol.Value(i)))
+		}
 
-		// Extract embedding for this row
-		start := i * embeddingDim
-		results[i].Embedding = make([]float32, embeddingDim)
-		for j := 0; j < embeddingDim; j++ {
-			results[i].Embedding[j] = embeddingValues.Value(start + j)
+		// Extract embedding for this row; the capacity is capped so appends copy
+		if sharedValues != nil {
+			start := i * embeddingDim
+			results[i].Embedding = sharedValues[start : start+embeddingDim : start+embeddingDim]
+			results[i].EmbeddingHandle = handle
+		}
+		if embeddingValue != nil {
+			start := i * embeddingDim
+			results[i].Embedding = make([]float32, embeddingDim)
+			for j := 0; j < embeddingDim; j++ {
+				results[i].Embedding[j] = embeddingValue(start + j)
+			}
 		}
 
-		// Parse metadata (JSON unmarshal already creates new strings, so no copy needed)
-		metaJSON := metadataCol.Value(i)
-		meta, err := decodeMetadata(metaJSON)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode metadata for row %d: %w", i, err)
+		// Parse metadata (decoding already creates new strings, so no copy needed)
+		if metadataCol != nil {
+			meta, err := codec.Decode(metadataCol.Value(i))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode metadata for row %d: %w", i, err)
+			}
+			results[i].Metadata = meta
 		}
-		results[i].Metadata = meta
 		
 		// Extract distance score if available
 		if distanceCol != nil {
@@ -268,6 +898,40 @@ func parseSearchResults(record arrow.Record, embeddingDim int) ([]SearchResult,
 		}
 	}
 
+	if sharedValues != nil && numRows > 0 {
+		handle.keep(record)
+	}
+	return results, nil
+}
+
+// embeddingValueReader returns a function reading the embedding value at an index of values
+// as float32. Int8 values are dequantized with the scale of their row in scales.
+func embeddingValueReader(values arrow.Array, scales *array.Float32, embeddingDim int) (func(int) float32, error) {
+	switch v := values.(type) {
+	case *array.Float32:
+		return v.Value, nil
+	case *array.Float16:
+		return func(i int) float32 { return v.Value(i).Float32() }, nil
+	case *array.Int8:
+		if scales == nil {
+			return nil, fmt.Errorf("int8 embeddings require the %s column", embeddingScaleColumn)
+		}
+		return func(i int) float32 { return float32(v.Value(i)) * scales.Value(i/embeddingDim) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported embedding type %s", values.DataType())
+	}
+}
+
+// parseVectorSearchResults parses the results of a vector search like parseSearchResultsSharing,
+// and sets each result's Similarity from its distance under dt
+func parseVectorSearchResults(record arrow.Record, embeddingDim int, codec MetadataCodec, dt lancedb.DistanceType, handle *EmbeddingHandle) ([]SearchResult, error) {
+	results, err := parseSearchResultsSharing(record, embeddingDim, codec, handle)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Similarity = NormalizeScore(dt, results[i].Score)
+	}
 	return results, nil
 }
 
@@ -305,7 +969,7 @@ func (s *RAGStore) ListDocumentNamesPaginated(ctx context.Context, userID string
 		return &DocumentNamePage{Names: []string{}, TotalCount: 0, Offset: offset, Limit: limit, HasMore: false}, nil
 	}
 
-	table, err := s.conn.OpenTable(s.getTableName(userID))
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open table for user %s: %w", userID, err)
 	}
@@ -2,10 +2,21 @@ package rag
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/aqua777/go-lancedb"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -44,3 +55,1054 @@ func TestQueryTestSuite(t *testing.T) {
 	suite.Run(t, new(QueryTestSuite))
 }
 
+func (s *QueryTestSuite) TestConcurrentSearchManyUsers() {
+	if testing.Short() {
+		s.T().Skip("skipping multi-user concurrency test in short mode")
+	}
+
+	const numUsers = 50
+	const maxOpen = 8
+	s.Require().NoError(s.store.SetMaxOpenTables(maxOpen))
+
+	for u := 0; u < numUsers; u++ {
+		userID := fmt.Sprintf("user_%d", u)
+		s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(300, 128, userID+".txt")))
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, numUsers*2)
+	for round := 0; round < 2; round++ {
+		for u := 0; u < numUsers; u++ {
+			wg.Add(1)
+			go func(u int) {
+				defer wg.Done()
+				userID := fmt.Sprintf("user_%d", u)
+				results, err := s.store.Search(s.ctx, userID, makeTestDocs(1, 128, "q")[0].Embedding, &SearchOptions{Limit: 3})
+				if err != nil {
+					errs <- err
+					return
+				}
+				if len(results) != 3 {
+					errs <- fmt.Errorf("user %s: expected 3 results, got %d", userID, len(results))
+					return
+				}
+				for _, r := range results {
+					if r.DocumentName != userID+".txt" {
+						errs <- fmt.Errorf("user %s: got result from %s", userID, r.DocumentName)
+						return
+					}
+				}
+				if n := s.store.tables.len(); n > maxOpen {
+					errs <- fmt.Errorf("open table handles exceeded limit: %d > %d", n, maxOpen)
+				}
+			}(u)
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		s.NoError(err)
+	}
+	s.LessOrEqual(s.store.tables.len(), maxOpen)
+
+	s.Require().NoError(s.store.Close())
+	s.Equal(0, s.store.tables.len())
+}
+
+func (s *QueryTestSuite) TestSearchSeesWritesAfterCaching() {
+	userID := "cache_user"
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(300, 128, "first.txt")))
+
+	// Warm the handle cache
+	_, err := s.store.Search(s.ctx, userID, make([]float32, 128), &SearchOptions{Limit: 1})
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.store.DeleteByDocumentName(s.ctx, userID, "first.txt"))
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(5, 128, "second.txt")))
+
+	results, err := s.store.Search(s.ctx, userID, make([]float32, 128), &SearchOptions{Limit: 10})
+	s.Require().NoError(err)
+	s.Len(results, 5)
+	for _, r := range results {
+		s.Equal("second.txt", r.DocumentName)
+	}
+}
+
+func (s *QueryTestSuite) TestSearchStream() {
+	userID := "stream_user"
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(300, 128, "stream.txt")))
+
+	results, errc := s.store.SearchStream(s.ctx, userID, make([]float32, 128), &SearchOptions{Limit: 20})
+
+	count := 0
+	for r := range results {
+		s.Equal("stream.txt", r.DocumentName)
+		s.Len(r.Embedding, 128)
+		count++
+	}
+	s.NoError(<-errc)
+	s.Equal(20, count)
+}
+
+func (s *QueryTestSuite) TestSearchStreamCancel() {
+	userID := "stream_cancel_user"
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(300, 128, "stream.txt")))
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	results, errc := s.store.SearchStream(ctx, userID, make([]float32, 128), &SearchOptions{Limit: 200})
+
+	// Read a few results, then stop
+	for i := 0; i < 3; i++ {
+		_, ok := <-results
+		s.Require().True(ok)
+	}
+	cancel()
+
+	// The goroutine must exit and close both channels
+	done := make(chan struct{})
+	go func() {
+		for range results {
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		s.Fail("search stream goroutine did not exit after cancellation")
+	}
+
+	err := <-errc
+	if err != nil {
+		s.ErrorIs(err, context.Canceled)
+	}
+}
+
+func (s *QueryTestSuite) TestSearchStreamDimensionMismatch() {
+	results, errc := s.store.SearchStream(s.ctx, "stream_user", make([]float32, 3), nil)
+
+	for range results {
+		s.Fail("expected no results")
+	}
+	s.Error(<-errc)
+}
+
+func (s *QueryTestSuite) TestSearchBatch() {
+	userID := "batch_user"
+	docs := makeTestDocs(300, 128, "batch.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	queries := [][]float32{docs[0].Embedding, docs[5].Embedding, docs[9].Embedding}
+	groups, err := s.store.SearchBatch(s.ctx, userID, queries, &SearchOptions{Limit: 4})
+	s.Require().NoError(err)
+	s.Require().Len(groups, 3)
+
+	for i, group := range groups {
+		s.Len(group, 4, "group %d", i)
+
+		// Each group matches what a single search returns
+		single, err := s.store.Search(s.ctx, userID, queries[i], &SearchOptions{Limit: 4})
+		s.Require().NoError(err)
+		s.Equal(resultIDs(single), resultIDs(group), "group %d", i)
+	}
+}
+
+func (s *QueryTestSuite) TestSearchBatchDimensionMismatch() {
+	queries := [][]float32{make([]float32, 128), make([]float32, 3)}
+	_, err := s.store.SearchBatch(s.ctx, "batch_user", queries, nil)
+	s.Error(err)
+	s.Contains(err.Error(), "query embedding 1")
+}
+
+func (s *QueryTestSuite) TestSearchBatchNoTable() {
+	groups, err := s.store.SearchBatch(s.ctx, "empty_user", [][]float32{make([]float32, 128), make([]float32, 128)}, nil)
+	s.Require().NoError(err)
+	s.Require().Len(groups, 2)
+	for _, group := range groups {
+		s.Empty(group)
+	}
+}
+
+// resultIDs returns the IDs of results in order
+func resultIDs(results []SearchResult) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func (s *QueryTestSuite) TestSearchManyUsers() {
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "tenant_a", makeTestDocs(300, 128, "a.txt")))
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "tenant_b", makeTestDocs(300, 128, "b.txt")))
+
+	query := makeTestDocs(1, 128, "q")[0].Embedding
+	results, err := s.store.SearchManyUsers(s.ctx, []string{"tenant_a", "tenant_b", "tenant_c"}, query, &SearchOptions{Limit: 3})
+	s.Require().NoError(err)
+	s.Require().Len(results, 3)
+
+	// Each user only sees their own documents
+	s.Require().Len(results["tenant_a"], 3)
+	for _, r := range results["tenant_a"] {
+		s.Equal("a.txt", r.DocumentName)
+	}
+	s.Require().Len(results["tenant_b"], 3)
+	for _, r := range results["tenant_b"] {
+		s.Equal("b.txt", r.DocumentName)
+	}
+
+	// A user without a table gets an empty slice, not a failure
+	s.NotNil(results["tenant_c"])
+	s.Empty(results["tenant_c"])
+}
+
+func (s *QueryTestSuite) TestSearchManyUsersCollectsErrors() {
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "tenant_a", makeTestDocs(300, 128, "a.txt")))
+
+	query := makeTestDocs(1, 128, "q")[0].Embedding
+	results, err := s.store.SearchManyUsers(s.ctx, []string{"tenant_a", "bad user!"}, query, &SearchOptions{Limit: 3})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "bad user!")
+
+	// The other user's search still completed
+	s.Len(results["tenant_a"], 3)
+	s.NotContains(results, "bad user!")
+}
+
+func (s *QueryTestSuite) TestSearchManyUsersCancelled() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	_, err := s.store.SearchManyUsers(ctx, []string{"tenant_a", "tenant_b"}, make([]float32, 128), nil)
+	s.ErrorIs(err, context.Canceled)
+}
+
+func (s *QueryTestSuite) TestSearchTimeout() {
+	userID := "timeout_user"
+	docs := makeTestDocs(50, 128, "doc.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	_, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5, Timeout: time.Nanosecond})
+	s.Require().Error(err)
+	s.True(errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+
+	_, err = s.store.SearchBatch(s.ctx, userID, [][]float32{docs[0].Embedding}, &SearchOptions{Timeout: time.Nanosecond})
+	s.True(errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+
+	// A generous timeout doesn't affect the search
+	results, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5, Timeout: time.Minute})
+	s.Require().NoError(err)
+	s.Len(results, 5)
+}
+
+func (s *QueryTestSuite) TestSearchWithInfoReportsIndexUsage() {
+	userID := "info_user"
+	docs := makeTestDocs(300, 128, "info.txt")
+
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs[:100]))
+	results, info, err := s.store.SearchWithInfo(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	s.Len(results, 5)
+	s.False(info.IndexUsed)
+	s.Equal(int64(100), info.TotalRows)
+	s.Greater(info.Elapsed, time.Duration(0))
+
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs[100:]))
+	results, info, err = s.store.SearchWithInfo(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	s.Len(results, 5)
+	s.True(info.IndexUsed)
+	s.Equal(int64(300), info.TotalRows)
+
+	_, info, err = s.store.SearchWithInfo(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5, BypassIndex: true})
+	s.Require().NoError(err)
+	s.False(info.IndexUsed)
+}
+
+func (s *QueryTestSuite) TestSearchWithInfoInt8NeverUsesIndex() {
+	userID := "info_int8_user"
+	docs := makeTestDocs(300, 128, "info.txt")
+
+	s.Require().NoError(s.store.AddDocumentsQuantized(s.ctx, userID, docs, EmbeddingStorageInt8))
+	results, info, err := s.store.SearchWithInfo(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	s.Len(results, 5)
+	s.False(info.IndexUsed)
+}
+
+func (s *QueryTestSuite) TestSearchWithInfoNoTable() {
+	results, info, err := s.store.SearchWithInfo(s.ctx, "info_empty_user", make([]float32, 128), nil)
+	s.Require().NoError(err)
+	s.Empty(results)
+	s.False(info.IndexUsed)
+	s.Equal(int64(0), info.TotalRows)
+}
+
+// recordingLogger keeps every formatted log line
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) Println(v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprint(v...))
+}
+
+// count returns the number of lines containing substr
+func (l *recordingLogger) count(substr string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, line := range l.lines {
+		if strings.Contains(line, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *QueryTestSuite) TestDistanceTypeMismatchIsSurfaced() {
+	logger := &recordingLogger{}
+	s.store.SetLogger(logger)
+
+	userID := "metric_user"
+	docs := makeTestDocs(300, 128, "metric.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs)) // default cosine index
+
+	stats, err := s.store.IndexStats(s.ctx, userID)
+	s.Require().NoError(err)
+	s.True(stats.IndexExists)
+	s.Equal(lancedb.DistanceMetricCosine, stats.Metric)
+	s.Equal(int64(300), stats.NumRows)
+
+	// Matching metric: no mismatch
+	_, info, err := s.store.SearchWithInfo(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 3, DistanceType: lancedb.DistanceTypeCosine})
+	s.Require().NoError(err)
+	s.False(info.DistanceTypeMismatch)
+
+	// L2 against a cosine index is reported, and logged once
+	for i := 0; i < 2; i++ {
+		results, info, err := s.store.SearchWithInfo(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 3, DistanceType: lancedb.DistanceTypeL2})
+		s.Require().NoError(err)
+		s.Len(results, 3)
+		s.True(info.DistanceTypeMismatch)
+
+		// The index computed cosine distances, so similarities are normalized as cosine
+		for _, r := range results {
+			s.Equal(NormalizeScore(lancedb.DistanceTypeCosine, r.Score), r.Similarity)
+		}
+	}
+	s.Equal(1, logger.count("distance type L2 but the index was built with cosine"))
+
+	// Bypassing the index honors the requested metric
+	results, info, err := s.store.SearchWithInfo(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 3, DistanceType: lancedb.DistanceTypeL2, BypassIndex: true})
+	s.Require().NoError(err)
+	s.False(info.DistanceTypeMismatch)
+	s.Require().Len(results, 3)
+	s.InDelta(0, results[0].Score, 1e-6) // exact L2 match
+}
+
+// pageThrough lists every page of matches for filter and returns the IDs in order
+func (s *QueryTestSuite) pageThrough(userID string, filter map[string]interface{}, pageSize int) ([]string, int64) {
+	var ids []string
+	var total int64
+	for offset := 0; ; offset += pageSize {
+		page, count, err := s.store.ListByFilter(s.ctx, userID, filter, offset, pageSize)
+		s.Require().NoError(err)
+		s.Require().LessOrEqual(len(page), pageSize)
+		total = count
+		for _, result := range page {
+			ids = append(ids, result.ID)
+		}
+		if len(page) < pageSize {
+			return ids, total
+		}
+	}
+}
+
+func (s *QueryTestSuite) TestListByFilter() {
+	userID := "list_user"
+	docs := makeTestDocs(33, 128, "doc.txt")
+	for i := range docs {
+		category := "b"
+		if i%3 != 0 {
+			category = "a"
+		}
+		docs[i].Metadata["category"] = category
+	}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, append(docs, makeTestDocs(7, 128, "other.txt")...)))
+
+	// Metadata filter: pages cover every match exactly once
+	ids, total := s.pageThrough(userID, map[string]interface{}{"category": "a"}, 5)
+	s.Equal(int64(22), total)
+	s.Len(ids, 22)
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		s.False(seen[id], "document %s returned twice", id)
+		seen[id] = true
+	}
+	for i, doc := range docs {
+		s.Equal(i%3 != 0, seen[doc.ID], "document %s", doc.ID)
+	}
+	s.True(sort.StringsAreSorted(ids), "pages come in ID order")
+
+	// Column filter: paging is pushed down to LanceDB
+	ids, total = s.pageThrough(userID, map[string]interface{}{"document_name": "other.txt"}, 3)
+	s.Equal(int64(7), total)
+	s.Equal([]string{"other.txt_0", "other.txt_1", "other.txt_2", "other.txt_3", "other.txt_4", "other.txt_5", "other.txt_6"}, ids)
+	s.True(sort.StringsAreSorted(ids), "pages come in ID order")
+
+	// Both kinds combined, with numbers compared by value
+	page, total, err := s.store.ListByFilter(s.ctx, userID, map[string]interface{}{"document_name": "doc.txt", "category": "b", "index": 3}, 0, 10)
+	s.Require().NoError(err)
+	s.Equal(int64(1), total)
+	s.Require().Len(page, 1)
+	s.Equal("doc.txt_3", page[0].ID)
+	s.Len(page[0].Embedding, 128)
+
+	// No filter lists everything
+	_, total, err = s.store.ListByFilter(s.ctx, userID, nil, 0, 1)
+	s.Require().NoError(err)
+	s.Equal(int64(40), total)
+}
+
+func (s *QueryTestSuite) TestListByFilterErrors() {
+	page, total, err := s.store.ListByFilter(s.ctx, "missing_user", nil, 0, 10)
+	s.Require().NoError(err)
+	s.Empty(page)
+	s.Zero(total)
+
+	_, _, err = s.store.ListByFilter(s.ctx, "list_user", nil, -1, 10)
+	s.Error(err)
+	_, _, err = s.store.ListByFilter(s.ctx, "list_user", map[string]interface{}{"tags": []string{"a"}}, 0, 10)
+	s.Error(err)
+	_, _, err = s.store.ListByFilter(s.ctx, "bad user!", nil, 0, 10)
+	s.Error(err)
+}
+
+func (s *QueryTestSuite) TestSearchDedupeByDocument() {
+	userID := "dedupe_user"
+	query := make([]float32, 128)
+	query[0] = 1
+
+	// long.txt has many chunks closer to the query than any other document
+	var docs []Document
+	for i := 0; i < 20; i++ {
+		embedding := make([]float32, 128)
+		embedding[0] = 1
+		embedding[1] = float32(i) * 0.001
+		docs = append(docs, Document{ID: DocumentID("long.txt", i), Text: fmt.Sprintf("long chunk %d", i), DocumentName: "long.txt", Embedding: embedding})
+	}
+	for i := 0; i < 4; i++ {
+		embedding := make([]float32, 128)
+		embedding[0] = 1
+		embedding[2+i] = 0.5
+		name := fmt.Sprintf("short_%d.txt", i)
+		docs = append(docs, Document{ID: DocumentID(name, 0), Text: "short chunk", DocumentName: name, Embedding: embedding})
+	}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	results, err := s.store.Search(s.ctx, userID, query, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	for _, r := range results {
+		s.Equal("long.txt", r.DocumentName)
+	}
+
+	results, err = s.store.Search(s.ctx, userID, query, &SearchOptions{Limit: 5, DedupeByDocument: true, MaxChunksPerDocument: 2})
+	s.Require().NoError(err)
+	s.Len(results, 5)
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.DocumentName]++
+	}
+	s.Equal(2, counts["long.txt"])
+	s.Len(counts, 4)
+	s.Equal("long.txt", results[0].DocumentName)
+
+	// The default keeps one chunk per document, and streaming applies the same rule
+	stream, errc := s.store.SearchStream(s.ctx, userID, query, &SearchOptions{Limit: 5, DedupeByDocument: true})
+	streamed := make(map[string]int)
+	for r := range stream {
+		streamed[r.DocumentName]++
+	}
+	s.Require().NoError(<-errc)
+	s.Len(streamed, 5)
+	for name, n := range streamed {
+		s.Equal(1, n, name)
+	}
+}
+
+func (s *QueryTestSuite) TestSearchSimilarity() {
+	userID := "similarity_user"
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(50, 128, "similarity.txt")))
+	query := makeTestDocs(3, 128, "q")[2].Embedding
+
+	for _, dt := range []lancedb.DistanceType{lancedb.DistanceTypeCosine, lancedb.DistanceTypeL2} {
+		results, err := s.store.Search(s.ctx, userID, query, &SearchOptions{Limit: 20, DistanceType: dt, BypassIndex: true})
+		s.Require().NoError(err)
+		s.Require().Len(results, 20)
+
+		for i, r := range results {
+			s.GreaterOrEqual(r.Similarity, float32(0), "metric %d rank %d", dt, i)
+			s.LessOrEqual(r.Similarity, float32(1), "metric %d rank %d", dt, i)
+			s.Equal(NormalizeScore(dt, r.Score), r.Similarity)
+			if i > 0 {
+				s.LessOrEqual(r.Similarity, results[i-1].Similarity, "metric %d rank %d", dt, i)
+			}
+		}
+		// makeTestDocs repeats embeddings, so the query itself is among the documents
+		s.InDelta(1, results[0].Similarity, 1e-4)
+	}
+}
+
+func (s *QueryTestSuite) TestSearchWithoutEmbeddings() {
+	userID := "no_embedding_user"
+	docs := makeTestDocs(30, 128, "projection.txt")
+	for i := range docs {
+		docs[i].Metadata = map[string]interface{}{"chunk": float64(i)}
+	}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+	query := docs[3].Embedding
+
+	withEmbeddings, err := s.store.Search(s.ctx, userID, query, &SearchOptions{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(withEmbeddings, 10)
+
+	include := false
+	results, err := s.store.Search(s.ctx, userID, query, &SearchOptions{Limit: 10, IncludeEmbedding: &include})
+	s.Require().NoError(err)
+	s.Require().Len(results, 10)
+	for i, r := range results {
+		s.Nil(r.Embedding)
+		s.Equal(withEmbeddings[i].ID, r.ID)
+		s.Equal(withEmbeddings[i].Text, r.Text)
+		s.Equal(withEmbeddings[i].DocumentName, r.DocumentName)
+		s.Equal(withEmbeddings[i].Metadata, r.Metadata)
+		s.Equal(withEmbeddings[i].Score, r.Score)
+		s.Equal(withEmbeddings[i].Similarity, r.Similarity)
+		s.Len(withEmbeddings[i].Embedding, 128)
+	}
+
+	stream, errc := s.store.SearchStream(s.ctx, userID, query, &SearchOptions{Limit: 5, IncludeEmbedding: &include})
+	count := 0
+	for r := range stream {
+		s.Nil(r.Embedding)
+		s.NotEmpty(r.Text)
+		count++
+	}
+	s.Require().NoError(<-errc)
+	s.Equal(5, count)
+}
+
+func (s *QueryTestSuite) TestParseSearchResultsWithoutEmbeddingColumn() {
+	docs := makeTestDocs(3, 16, "parse.txt")
+	docs[1].Metadata = map[string]interface{}{"page": float64(2)}
+	record, err := buildDocumentRecord(documentSchema(16), docs, JSONMetadataCodec{})
+	s.Require().NoError(err)
+	defer record.Release()
+
+	// Drop the embedding column as a search that omits it would
+	fields := record.Schema().Fields()
+	projected := array.NewRecord(
+		arrow.NewSchema([]arrow.Field{fields[0], fields[1], fields[2], fields[4]}, nil),
+		[]arrow.Array{record.Column(0), record.Column(1), record.Column(2), record.Column(4)},
+		record.NumRows())
+	defer projected.Release()
+
+	results, err := parseSearchResults(projected, 16, JSONMetadataCodec{})
+	s.Require().NoError(err)
+	s.Require().Len(results, 3)
+	for i, r := range results {
+		s.Equal(docs[i].ID, r.ID)
+		s.Equal(docs[i].Text, r.Text)
+		s.Nil(r.Embedding)
+	}
+	s.EqualValues(2, results[1].Metadata["page"])
+}
+
+func (s *QueryTestSuite) TestSearchColumns() {
+	userID := "columns_user"
+	docs := makeTestDocs(30, 128, "titles.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	full, err := s.store.Search(s.ctx, userID, docs[4].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	s.Require().Len(full, 5)
+
+	results, err := s.store.Search(s.ctx, userID, docs[4].Embedding, &SearchOptions{Limit: 5, Columns: []string{"document_name"}})
+	s.Require().NoError(err)
+	s.Require().Len(results, 5)
+	for i, r := range results {
+		// The id and distance are always returned
+		s.Equal(full[i].ID, r.ID)
+		s.Equal(full[i].Score, r.Score)
+		s.Equal("titles.txt", r.DocumentName)
+		s.Empty(r.Text)
+		s.Nil(r.Embedding)
+		s.Nil(r.Metadata)
+	}
+
+	stream, errc := s.store.SearchStream(s.ctx, userID, docs[4].Embedding, &SearchOptions{Limit: 5, Columns: []string{"text"}})
+	for r := range stream {
+		s.NotEmpty(r.ID)
+		s.NotEmpty(r.Text)
+		s.Empty(r.DocumentName)
+	}
+	s.Require().NoError(<-errc)
+
+	_, err = s.store.Search(s.ctx, userID, docs[4].Embedding, &SearchOptions{Limit: 5, Columns: []string{"secret"}})
+	s.Error(err)
+	s.Contains(err.Error(), "invalid search column")
+}
+
+func (s *QueryTestSuite) TestSearchColumnsSelection() {
+	includeEmbedding := false
+	cases := []struct {
+		opts SearchOptions
+		want []string
+	}{
+		{SearchOptions{}, []string{"id", "text", "document_name", "embedding", "metadata", "_distance"}},
+		{SearchOptions{IncludeEmbedding: &includeEmbedding}, []string{"id", "text", "document_name", "metadata", "_distance"}},
+		{SearchOptions{Columns: []string{"document_name"}}, []string{"id", "document_name", "_distance"}},
+		{SearchOptions{Columns: []string{"metadata", "id", "text"}}, []string{"id", "text", "metadata", "_distance"}},
+		{SearchOptions{Columns: []string{"text"}, DedupeByDocument: true}, []string{"id", "text", "document_name", "_distance"}},
+		{SearchOptions{Columns: []string{"embedding"}, IncludeEmbedding: &includeEmbedding}, []string{"id", "_distance"}},
+	}
+	for i, tc := range cases {
+		columns, err := searchColumns(&tc.opts)
+		s.Require().NoError(err, "case %d", i)
+		s.Equal(tc.want, columns, "case %d", i)
+	}
+
+	_, err := searchColumns(&SearchOptions{Columns: []string{"_distance"}})
+	s.Error(err)
+}
+
+func (s *QueryTestSuite) TestProjectSearchResult() {
+	result := SearchResult{
+		ID:           "a",
+		Text:         "text",
+		DocumentName: "doc.txt",
+		Embedding:    []float32{1, 2},
+		Metadata:     map[string]interface{}{"k": "v"},
+		Score:        0.5,
+		Similarity:   0.75,
+	}
+	projected := projectSearchResult(result, []string{"id", "document_name", "_distance"})
+	s.Equal(SearchResult{ID: "a", DocumentName: "doc.txt", Score: 0.5, Similarity: 0.75}, projected)
+}
+
+func (s *QueryTestSuite) TestParseSearchResultsByColumnName() {
+	docs := makeTestDocs(4, 8, "shuffled.txt")
+	docs[2].Metadata = map[string]interface{}{"section": "intro"}
+	record, err := buildDocumentRecord(documentSchema(8), docs, JSONMetadataCodec{})
+	s.Require().NoError(err)
+	defer record.Release()
+
+	mem := memory.NewGoAllocator()
+	distanceBuilder := array.NewFloat32Builder(mem)
+	defer distanceBuilder.Release()
+	distanceBuilder.AppendValues([]float32{0.1, 0.2, 0.3, 0.4}, nil)
+	distances := distanceBuilder.NewArray()
+	defer distances.Release()
+
+	// project builds a record from the named document columns, plus the distance
+	fields := record.Schema().Fields()
+	project := func(names ...string) arrow.Record {
+		var projectedFields []arrow.Field
+		var columns []arrow.Array
+		for _, name := range names {
+			if name == "_distance" {
+				projectedFields = append(projectedFields, arrow.Field{Name: "_distance", Type: arrow.PrimitiveTypes.Float32})
+				columns = append(columns, distances)
+				continue
+			}
+			i := record.Schema().FieldIndices(name)[0]
+			projectedFields = append(projectedFields, fields[i])
+			columns = append(columns, record.Column(i))
+		}
+		return array.NewRecord(arrow.NewSchema(projectedFields, nil), columns, record.NumRows())
+	}
+
+	// Shuffled columns parse the same as the table order
+	shuffled := project("_distance", "metadata", "embedding", "document_name", "id", "text")
+	defer shuffled.Release()
+	results, err := parseSearchResults(shuffled, 8, JSONMetadataCodec{})
+	s.Require().NoError(err)
+	s.Require().Len(results, 4)
+	for i, r := range results {
+		s.Equal(docs[i].ID, r.ID)
+		s.Equal(docs[i].Text, r.Text)
+		s.Equal("shuffled.txt", r.DocumentName)
+		s.Equal(docs[i].Embedding, r.Embedding)
+		s.InDelta(0.1*float64(i+1), r.Score, 1e-6)
+	}
+	s.Equal("intro", results[2].Metadata["section"])
+
+	// Missing columns leave their fields zero
+	partial := project("text", "_distance")
+	defer partial.Release()
+	results, err = parseSearchResults(partial, 8, JSONMetadataCodec{})
+	s.Require().NoError(err)
+	s.Require().Len(results, 4)
+	for i, r := range results {
+		s.Empty(r.ID)
+		s.Equal(docs[i].Text, r.Text)
+		s.Empty(r.DocumentName)
+		s.Nil(r.Embedding)
+		s.Nil(r.Metadata)
+		s.InDelta(0.1*float64(i+1), r.Score, 1e-6)
+	}
+
+	// A known column with the wrong type is an error, not a panic
+	wrongType := array.NewRecord(
+		arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Float32}}, nil),
+		[]arrow.Array{distances}, record.NumRows())
+	defer wrongType.Release()
+	_, err = parseSearchResults(wrongType, 8, JSONMetadataCodec{})
+	s.Error(err)
+	s.Contains(err.Error(), "column id")
+
+	// So is an embedding of another dimension
+	_, err = parseSearchResults(shuffled, 16, JSONMetadataCodec{})
+	s.Error(err)
+	s.Contains(err.Error(), "dimension")
+}
+
+// countingRecord counts the references taken and dropped on a record
+type countingRecord struct {
+	arrow.Record
+	refs *int64
+}
+
+func (r countingRecord) Retain()  { atomic.AddInt64(r.refs, 1); r.Record.Retain() }
+func (r countingRecord) Release() { atomic.AddInt64(r.refs, -1); r.Record.Release() }
+
+func (s *QueryTestSuite) TestParseSearchResultsZeroCopy() {
+	docs := makeTestDocs(6, 8, "zerocopy.txt")
+	record, err := buildDocumentRecord(documentSchema(8), docs, JSONMetadataCodec{})
+	s.Require().NoError(err)
+	defer record.Release()
+
+	var refs int64
+	counted := countingRecord{Record: record, refs: &refs}
+
+	copied, err := parseSearchResults(counted, 8, JSONMetadataCodec{})
+	s.Require().NoError(err)
+	s.EqualValues(0, atomic.LoadInt64(&refs))
+
+	handle := newEmbeddingHandle()
+	shared, err := parseSearchResultsSharing(counted, 8, JSONMetadataCodec{}, handle)
+	s.Require().NoError(err)
+	s.EqualValues(1, atomic.LoadInt64(&refs), "the handle retains the record")
+
+	values := record.Column(record.Schema().FieldIndices("embedding")[0]).(*array.FixedSizeList).ListValues().(*array.Float32).Float32Values()
+	for i, r := range shared {
+		s.Equal(copied[i].Embedding, r.Embedding)
+		s.Equal(copied[i].ID, r.ID)
+		s.Nil(copied[i].EmbeddingHandle)
+		s.Same(handle, r.EmbeddingHandle)
+		s.Same(&values[i*8], &r.Embedding[0], "the embedding aliases the Arrow buffer")
+		s.Equal(8, cap(r.Embedding), "appends must not write into the buffer")
+	}
+
+	// Readers on other goroutines hold their own references
+	var wg sync.WaitGroup
+	sums := make([]float32, 4)
+	for w := range sums {
+		handle.Retain()
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			defer handle.Release()
+			for _, r := range shared {
+				for _, v := range r.Embedding {
+					sums[w] += v
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	for _, sum := range sums[1:] {
+		s.Equal(sums[0], sum)
+	}
+	s.EqualValues(1, atomic.LoadInt64(&refs), "the caller's reference is still held")
+
+	ReleaseEmbeddings(append(shared, copied...))
+	s.EqualValues(0, atomic.LoadInt64(&refs))
+	s.Panics(handle.Release)
+	s.Panics(handle.Retain)
+
+	// The nil handle of copied results is a no-op
+	var nilHandle *EmbeddingHandle
+	nilHandle.Retain()
+	nilHandle.Release()
+}
+
+func (s *QueryTestSuite) TestSearchZeroCopyEmbeddings() {
+	userID := "zerocopy_user"
+	docs := makeTestDocs(40, 128, "zerocopy.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	copied, err := s.store.Search(s.ctx, userID, docs[3].Embedding, &SearchOptions{Limit: 10})
+	s.Require().NoError(err)
+	shared, err := s.store.Search(s.ctx, userID, docs[3].Embedding, &SearchOptions{Limit: 10, UnsafeZeroCopyEmbeddings: true})
+	s.Require().NoError(err)
+	s.Require().Len(shared, len(copied))
+
+	handle := shared[0].EmbeddingHandle
+	s.Require().NotNil(handle)
+	for i, r := range shared {
+		s.Same(handle, r.EmbeddingHandle, "results of a search share one handle")
+		s.Nil(copied[i].EmbeddingHandle)
+		s.Equal(copied[i].ID, r.ID)
+		s.Equal(copied[i].Embedding, r.Embedding)
+	}
+
+	// Read the embeddings concurrently, each reader holding a reference
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		handle.Retain()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer handle.Release()
+			for i, r := range shared {
+				s.Equal(copied[i].Embedding, r.Embedding)
+			}
+		}()
+	}
+	wg.Wait()
+	ReleaseEmbeddings(shared)
+	s.Panics(handle.Release, "the caller's reference was the last one")
+
+	// A search whose results are all filtered out leaves nothing to release
+	none, err := s.store.Search(s.ctx, userID, docs[3].Embedding, &SearchOptions{Limit: 10, MinSimilarity: 1.5, UnsafeZeroCopyEmbeddings: true})
+	s.Require().NoError(err)
+	s.Empty(none)
+
+	// SearchMMR reads embeddings without copying them, but returns copies
+	mmr, err := s.store.SearchMMR(s.ctx, userID, docs[3].Embedding, &MMROptions{Lambda: 0.5, Limit: 5})
+	s.Require().NoError(err)
+	s.Require().NotEmpty(mmr)
+	for _, r := range mmr {
+		s.Nil(r.EmbeddingHandle)
+		s.Len(r.Embedding, 128)
+	}
+}
+
+func (s *QueryTestSuite) TestSearchMinSimilarity() {
+	userID := "threshold_user"
+	docs := make([]Document, 20)
+	for i := range docs {
+		embedding := make([]float32, 128)
+		name := "far.txt"
+		if i < 5 {
+			// Near documents point almost along the first axis
+			embedding[0] = 1
+			embedding[1+i] = 0.05
+			name = "near.txt"
+		} else {
+			// Far documents are orthogonal to it
+			embedding[i] = 1
+		}
+		docs[i] = Document{ID: fmt.Sprintf("doc_%d", i), Text: fmt.Sprintf("text %d", i), DocumentName: name, Embedding: embedding}
+	}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	query := make([]float32, 128)
+	query[0] = 1
+
+	results, err := s.store.Search(s.ctx, userID, query, &SearchOptions{Limit: 10, DistanceType: lancedb.DistanceTypeCosine})
+	s.Require().NoError(err)
+	s.Len(results, 10, "without a threshold the limit is filled")
+
+	opts := &SearchOptions{Limit: 10, DistanceType: lancedb.DistanceTypeCosine, MinSimilarity: 0.9}
+	results, err = s.store.Search(s.ctx, userID, query, opts)
+	s.Require().NoError(err)
+	s.Len(results, 5)
+	for _, r := range results {
+		s.Equal("near.txt", r.DocumentName)
+		s.GreaterOrEqual(r.Similarity, float32(0.9))
+	}
+
+	stream, errc := s.store.SearchStream(s.ctx, userID, query, opts)
+	count := 0
+	for r := range stream {
+		s.Equal("near.txt", r.DocumentName)
+		count++
+	}
+	s.Require().NoError(<-errc)
+	s.Equal(5, count)
+}
+
+func (s *QueryTestSuite) TestAboveMinSimilarity() {
+	results := []SearchResult{{ID: "a", Similarity: 0.9}, {ID: "b", Similarity: 0.7}, {ID: "c", Similarity: 0.4}}
+	s.Len(aboveMinSimilarity(results, 0), 3)
+	s.Len(aboveMinSimilarity(results, 0.7), 2)
+	s.Len(aboveMinSimilarity(results, 0.8), 1)
+	s.Empty(aboveMinSimilarity(results, 0.95))
+
+	// Results out of similarity order are each checked
+	unordered := []SearchResult{{ID: "a", Similarity: 0.9}, {ID: "b", Similarity: 0.4}, {ID: "c", Similarity: 0.8}}
+	s.Equal([]string{"a", "c"}, resultIDs(aboveMinSimilarity(unordered, 0.7)))
+}
+
+// tableRefs returns how many callers are using the cached table handle of a user
+func tableRefs(store *RAGStore, userID string) int {
+	store.tables.mu.Lock()
+	defer store.tables.mu.Unlock()
+	elem, ok := store.tables.items[store.getTableName(userID)]
+	if !ok {
+		return 0
+	}
+	return elem.Value.(*tableHandle).refs
+}
+
+func (s *QueryTestSuite) TestSearchIter() {
+	userID := "iter_user"
+	docs := makeTestDocs(10, 128, "iter.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	expected, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(expected, 10)
+
+	iter, err := s.store.SearchIter(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 10})
+	s.Require().NoError(err)
+	s.Equal(1, tableRefs(s.store, userID), "the open stream holds the table")
+
+	var seen []SearchResult
+	for {
+		result, ok, err := iter.Next()
+		s.Require().NoError(err)
+		if !ok {
+			break
+		}
+		seen = append(seen, *result)
+		if len(seen) == 3 {
+			break
+		}
+	}
+	iter.Close()
+
+	s.Require().Len(seen, 3)
+	for i := range seen {
+		s.Equal(expected[i].ID, seen[i].ID)
+		s.Equal(expected[i].Score, seen[i].Score)
+	}
+	s.Equal(0, tableRefs(s.store, userID), "closing the iterator releases the stream and table")
+
+	// Closed iterators stay exhausted, and Close is idempotent
+	result, ok, err := iter.Next()
+	s.NoError(err)
+	s.False(ok)
+	s.Nil(result)
+	iter.Close()
+}
+
+func (s *QueryTestSuite) TestSearchIterExhausts() {
+	userID := "iter_all_user"
+	docs := makeTestDocs(10, 128, "iter.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	iter, err := s.store.SearchIter(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 4})
+	s.Require().NoError(err)
+	defer iter.Close()
+
+	count := 0
+	for {
+		_, ok, err := iter.Next()
+		s.Require().NoError(err)
+		if !ok {
+			break
+		}
+		count++
+	}
+	s.Equal(4, count)
+	s.Equal(0, tableRefs(s.store, userID), "reaching the limit releases the table")
+
+	// No table and invalid queries
+	empty, err := s.store.SearchIter(s.ctx, "iter_nobody", docs[0].Embedding, nil)
+	s.Require().NoError(err)
+	_, ok, err := empty.Next()
+	s.NoError(err)
+	s.False(ok)
+	empty.Close()
+
+	_, err = s.store.SearchIter(s.ctx, userID, make([]float32, 3), nil)
+	s.Error(err)
+	s.Equal(0, tableRefs(s.store, userID))
+}
+
+func (s *QueryTestSuite) TestSearchDocuments() {
+	userID := "grouped_user"
+	near := func(offset float32) []float32 {
+		embedding := make([]float32, 128)
+		embedding[0] = 1
+		embedding[1] = offset
+		return embedding
+	}
+	far := func(axis int) []float32 {
+		embedding := make([]float32, 128)
+		embedding[axis] = 1
+		embedding[0] = 0.2
+		return embedding
+	}
+	docs := []Document{
+		{ID: "long_1", Text: "long 1", DocumentName: "long.txt", Embedding: near(0.01)},
+		{ID: "long_2", Text: "long 2", DocumentName: "long.txt", Embedding: near(0.02)},
+		{ID: "long_3", Text: "long 3", DocumentName: "long.txt", Embedding: near(0.03)},
+		{ID: "long_4", Text: "long 4", DocumentName: "long.txt", Embedding: near(0.3)},
+		{ID: "short_1", Text: "short 1", DocumentName: "short.txt", Embedding: near(0.5)},
+		{ID: "other_1", Text: "other 1", DocumentName: "other.txt", Embedding: far(5)},
+		{ID: "other_2", Text: "other 2", DocumentName: "other.txt", Embedding: far(6)},
+	}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	query := near(0)
+	results, err := s.store.SearchDocuments(s.ctx, userID, query, &SearchOptions{Limit: 10, DistanceType: lancedb.DistanceTypeCosine})
+	s.Require().NoError(err)
+	s.Require().Len(results, 3)
+
+	s.Equal("long.txt", results[0].DocumentName)
+	s.Equal("long_1", results[0].BestChunk.ID)
+	s.Equal(4, results[0].MatchedChunkCount)
+	s.Equal("short.txt", results[1].DocumentName)
+	s.Equal(1, results[1].MatchedChunkCount)
+	s.Equal("other.txt", results[2].DocumentName)
+	s.Equal(2, results[2].MatchedChunkCount)
+	s.Less(results[0].BestChunk.Score, results[1].BestChunk.Score)
+	s.Less(results[1].BestChunk.Score, results[2].BestChunk.Score)
+
+	// Limit counts documents, and grouping works with a narrow column selection
+	results, err = s.store.SearchDocuments(s.ctx, userID, query, &SearchOptions{Limit: 1, Columns: []string{"text"}})
+	s.Require().NoError(err)
+	s.Require().Len(results, 1)
+	s.Equal("long.txt", results[0].DocumentName)
+	s.Equal("long 1", results[0].BestChunk.Text)
+}
+
+func (s *QueryTestSuite) TestGroupByDocument() {
+	results := []SearchResult{
+		{ID: "a1", DocumentName: "a", Score: 0.1},
+		{ID: "b1", DocumentName: "b", Score: 0.2},
+		{ID: "a2", DocumentName: "a", Score: 0.3},
+		{ID: "c1", DocumentName: "c", Score: 0.4},
+		{ID: "b2", DocumentName: "b", Score: 0.5},
+	}
+	groups := groupByDocument(results, 2)
+	s.Require().Len(groups, 2)
+	s.Equal(DocumentResult{DocumentName: "a", BestChunk: results[0], MatchedChunkCount: 2}, groups[0])
+	s.Equal(DocumentResult{DocumentName: "b", BestChunk: results[1], MatchedChunkCount: 2}, groups[1])
+	s.Empty(groupByDocument(nil, 5))
+}
@@ -0,0 +1,96 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrQuotaExceeded is matched by errors.Is when an insert would exceed a user's document quota.
+// Use errors.As with *QuotaExceededError for the counts.
+var ErrQuotaExceeded = errors.New("document quota exceeded")
+
+// QuotaExceededError reports an insert rejected by the per-user document quota
+type QuotaExceededError struct {
+	UserID  string
+	Current int64 // Documents the user has now
+	Adding  int64 // Documents the rejected insert would add
+	Limit   int64 // The quota
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("document quota exceeded for user %s: %d documents plus %d new would exceed the limit of %d",
+		e.UserID, e.Current, e.Adding, e.Limit)
+}
+
+// Is reports whether target is ErrQuotaExceeded
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// SetMaxDocumentsPerUser sets the maximum number of document chunks a user can store.
+// Inserts and upserts that would take a user past the limit fail with ErrQuotaExceeded
+// before anything is written. Set to 0 for no limit (the default).
+func (s *RAGStore) SetMaxDocumentsPerUser(max int) error {
+	if max < 0 {
+		return fmt.Errorf("max documents per user cannot be negative, got %d", max)
+	}
+	s.mu.Lock()
+	s.maxDocumentsPerUser = max
+	s.mu.Unlock()
+	return nil
+}
+
+// GetMaxDocumentsPerUser returns the per-user document quota, 0 meaning no limit
+func (s *RAGStore) GetMaxDocumentsPerUser() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maxDocumentsPerUser
+}
+
+// checkQuota returns a QuotaExceededError if inserting docs into the user's table would exceed
+// the quota. Documents whose IDs already exist are replaced by upserts rather than added, so
+// with replacing set they don't count towards the quota. It runs before the table is created,
+// so a rejected first write leaves no empty table behind. The caller must hold the user lock.
+func (s *RAGStore) checkQuota(ctx context.Context, userID string, docs []Document, replacing bool) error {
+	limit := int64(s.GetMaxDocumentsPerUser())
+	if limit == 0 {
+		return nil
+	}
+
+	exists, err := s.TableExists(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	var current int64
+	adding := int64(len(docs))
+	if exists {
+		table, err := s.getConn().OpenTable(s.getTableName(userID))
+		if err != nil {
+			return fmt.Errorf("failed to open table for user %s: %w", userID, err)
+		}
+		defer table.Close()
+
+		current, err = table.CountRows()
+		if err != nil {
+			return fmt.Errorf("failed to count documents for quota: %w", err)
+		}
+
+		if replacing {
+			// Count in batches, so large upserts don't build one huge IN list
+			for _, predicate := range idPredicates(documentIDs(docs)) {
+				existing, err := table.CountRowsWhere(predicate)
+				if err != nil {
+					return fmt.Errorf("failed to count existing documents for quota: %w", err)
+				}
+				adding -= existing
+			}
+		}
+	}
+
+	if current+adding > limit {
+		return &QuotaExceededError{UserID: userID, Current: current, Adding: adding, Limit: limit}
+	}
+	return nil
+}
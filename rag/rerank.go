@@ -110,6 +110,126 @@ func (r *CrossEncoderReranker) scoreTexts(ctx context.Context, query string, tex
 	return response.Scores, nil
 }
 
+// CohereReranker re-ranks results with Cohere's Rerank API (/v1/rerank)
+type CohereReranker struct {
+	APIKey     string
+	Model      string // e.g., "rerank-english-v3.0", "rerank-multilingual-v3.0"
+	BaseURL    string
+	TopN       int // Return only the top N results (0 returns all)
+	httpClient *http.Client
+}
+
+// NewCohereReranker creates a reranker backed by the Cohere Rerank API
+func NewCohereReranker(apiKey, model string) *CohereReranker {
+	return &CohereReranker{
+		APIKey:     apiKey,
+		Model:      model,
+		BaseURL:    "https://api.cohere.com/v1",
+		httpClient: &http.Client{},
+	}
+}
+
+// Rerank re-ranks search results by Cohere relevance score (higher is more relevant).
+// When TopN is set, only the TopN most relevant results are returned.
+func (r *CohereReranker) Rerank(ctx context.Context, query string, results []SearchResult) ([]SearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	// Check for context cancellation
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	texts := make([]string, len(results))
+	for i, result := range results {
+		texts[i] = result.Text
+	}
+
+	scored, err := r.rerankTexts(ctx, query, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reranking scores: %w", err)
+	}
+
+	// Cohere returns entries by relevance; map them back to the original results by index
+	reranked := make([]SearchResult, 0, len(scored))
+	seen := make(map[int]bool, len(scored))
+	for _, item := range scored {
+		if item.Index < 0 || item.Index >= len(results) {
+			return nil, fmt.Errorf("Cohere returned out-of-range index %d for %d documents", item.Index, len(results))
+		}
+		if seen[item.Index] {
+			return nil, fmt.Errorf("Cohere returned duplicate index %d", item.Index)
+		}
+		seen[item.Index] = true
+
+		result := results[item.Index]
+		result.Score = item.RelevanceScore
+		reranked = append(reranked, result)
+	}
+
+	// Sort by score descending
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].Score > reranked[j].Score
+	})
+
+	return reranked, nil
+}
+
+// cohereRerankResult is a single entry of a Cohere rerank response
+type cohereRerankResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float32 `json:"relevance_score"`
+}
+
+// rerankTexts calls the Cohere rerank endpoint
+func (r *CohereReranker) rerankTexts(ctx context.Context, query string, texts []string) ([]cohereRerankResult, error) {
+	requestBody := map[string]interface{}{
+		"model":     r.Model,
+		"query":     query,
+		"documents": texts,
+	}
+	if r.TopN > 0 {
+		requestBody["top_n"] = r.TopN
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.BaseURL+"/rerank", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+r.APIKey)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Cohere API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Results []cohereRerankResult `json:"results"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return response.Results, nil
+}
+
 // ReciprocRankFusionReranker implements Reciprocal Rank Fusion (RRF) for combining multiple result sets
 // Useful when you have results from multiple sources (e.g., vector + keyword search)
 type ReciprocalRankFusionReranker struct {
@@ -214,6 +334,66 @@ func (r *CustomScorerReranker) Rerank(ctx context.Context, query string, results
 	return reranked, nil
 }
 
+// defaultRerankBatchSize is the batch size used by BatchingReranker when none is given
+const defaultRerankBatchSize = 100
+
+// BatchingReranker splits large candidate sets into batches for rerankers whose
+// endpoints cap the number of inputs per request, then merges all batches by score.
+// The inner reranker must produce scores that are comparable across batches
+// (e.g., cross-encoder relevance scores, not rank-based RRF scores).
+type BatchingReranker struct {
+	Inner    Reranker
+	MaxBatch int // Maximum results sent to Inner per call (default: 100)
+}
+
+// NewBatchingReranker wraps a reranker so it is called with at most maxBatch results at a time
+func NewBatchingReranker(inner Reranker, maxBatch int) *BatchingReranker {
+	if maxBatch <= 0 {
+		maxBatch = defaultRerankBatchSize
+	}
+	return &BatchingReranker{Inner: inner, MaxBatch: maxBatch}
+}
+
+// Rerank reranks results batch by batch and returns them sorted globally by descending score.
+// A MaxBatch of zero or less uses the default batch size.
+func (r *BatchingReranker) Rerank(ctx context.Context, query string, results []SearchResult) ([]SearchResult, error) {
+	maxBatch := r.MaxBatch
+	if maxBatch <= 0 {
+		maxBatch = defaultRerankBatchSize
+	}
+	if len(results) <= maxBatch {
+		return r.Inner.Rerank(ctx, query, results)
+	}
+
+	reranked := make([]SearchResult, 0, len(results))
+	for start := 0; start < len(results); start += maxBatch {
+		// Check for context cancellation between batches
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		end := start + maxBatch
+		if end > len(results) {
+			end = len(results)
+		}
+
+		batch, err := r.Inner.Rerank(ctx, query, results[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to rerank batch %d-%d: %w", start, end, err)
+		}
+		reranked = append(reranked, batch...)
+	}
+
+	// Merge batches by score
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].Score > reranked[j].Score
+	})
+
+	return reranked, nil
+}
+
 // SearchWithRerank performs search and applies reranking
 func (s *RAGStore) SearchWithRerank(ctx context.Context, userID string, queryText string, provider EmbeddingProvider, reranker Reranker, opts *SearchOptions) ([]SearchResult, error) {
 	// First, perform regular vector search
@@ -0,0 +1,271 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// RerankTestSuite tests result rerankers
+type RerankTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+// TestRerankTestSuite runs the rerank test suite
+func TestRerankTestSuite(t *testing.T) {
+	suite.Run(t, new(RerankTestSuite))
+}
+
+func (s *RerankTestSuite) SetupTest() {
+	s.ctx = context.Background()
+}
+
+// cohereRequest captures the body sent to the fake Cohere server
+type cohereRequest struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n"`
+}
+
+// newCohereTestServer returns a fake Cohere rerank endpoint that scores documents by a fixed table
+// and returns them sorted by relevance (i.e., not in request order), honoring top_n
+func newCohereTestServer(scores map[string]float32, captured *cohereRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rerank" || r.Header.Get("Authorization") != "Bearer test-key" {
+			http.Error(w, `{"message":"invalid api token"}`, http.StatusUnauthorized)
+			return
+		}
+
+		var req cohereRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if captured != nil {
+			*captured = req
+		}
+
+		results := make([]cohereRerankResult, len(req.Documents))
+		for i, doc := range req.Documents {
+			results[i] = cohereRerankResult{Index: i, RelevanceScore: scores[doc]}
+		}
+		// Sort by relevance descending, like the real API
+		for i := 1; i < len(results); i++ {
+			for j := i; j > 0 && results[j].RelevanceScore > results[j-1].RelevanceScore; j-- {
+				results[j], results[j-1] = results[j-1], results[j]
+			}
+		}
+		if req.TopN > 0 && req.TopN < len(results) {
+			results = results[:req.TopN]
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+}
+
+// rerankInputs returns search results in vector order
+func rerankInputs() []SearchResult {
+	return []SearchResult{
+		{ID: "a", Text: "alpha", DocumentName: "a.txt", Score: 0.1},
+		{ID: "b", Text: "bravo", DocumentName: "b.txt", Score: 0.2},
+		{ID: "c", Text: "charlie", DocumentName: "c.txt", Score: 0.3},
+		{ID: "d", Text: "delta", DocumentName: "d.txt", Score: 0.4},
+	}
+}
+
+func (s *RerankTestSuite) TestCohereRerankerRemapsIndices() {
+	var captured cohereRequest
+	server := newCohereTestServer(map[string]float32{
+		"alpha": 0.2, "bravo": 0.05, "charlie": 0.9, "delta": 0.6,
+	}, &captured)
+	defer server.Close()
+
+	reranker := NewCohereReranker("test-key", "rerank-english-v3.0")
+	reranker.BaseURL = server.URL
+
+	results, err := reranker.Rerank(s.ctx, "which one?", rerankInputs())
+	s.Require().NoError(err)
+	s.Require().Len(results, 4)
+
+	s.Equal("rerank-english-v3.0", captured.Model)
+	s.Equal("which one?", captured.Query)
+	s.Equal([]string{"alpha", "bravo", "charlie", "delta"}, captured.Documents)
+	s.Zero(captured.TopN)
+
+	expected := []struct {
+		id    string
+		text  string
+		score float32
+	}{
+		{"c", "charlie", 0.9},
+		{"d", "delta", 0.6},
+		{"a", "alpha", 0.2},
+		{"b", "bravo", 0.05},
+	}
+	for i, e := range expected {
+		s.Equal(e.id, results[i].ID)
+		s.Equal(e.text, results[i].Text)
+		s.Equal(e.id+".txt", results[i].DocumentName)
+		s.InDelta(e.score, results[i].Score, 1e-6)
+	}
+}
+
+func (s *RerankTestSuite) TestCohereRerankerTopN() {
+	var captured cohereRequest
+	server := newCohereTestServer(map[string]float32{
+		"alpha": 0.2, "bravo": 0.05, "charlie": 0.9, "delta": 0.6,
+	}, &captured)
+	defer server.Close()
+
+	reranker := NewCohereReranker("test-key", "rerank-english-v3.0")
+	reranker.BaseURL = server.URL
+	reranker.TopN = 2
+
+	results, err := reranker.Rerank(s.ctx, "which one?", rerankInputs())
+	s.Require().NoError(err)
+	s.Equal(2, captured.TopN)
+	s.Require().Len(results, 2)
+	s.Equal("c", results[0].ID)
+	s.Equal("d", results[1].ID)
+}
+
+func (s *RerankTestSuite) TestCohereRerankerAPIError() {
+	server := newCohereTestServer(nil, nil)
+	defer server.Close()
+
+	reranker := NewCohereReranker("wrong-key", "rerank-english-v3.0")
+	reranker.BaseURL = server.URL
+
+	_, err := reranker.Rerank(s.ctx, "query", rerankInputs())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "status 401")
+	s.Contains(err.Error(), "invalid api token")
+}
+
+func (s *RerankTestSuite) TestCohereRerankerInvalidIndex() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results":[{"index":7,"relevance_score":0.5}]}`)
+	}))
+	defer server.Close()
+
+	reranker := NewCohereReranker("test-key", "rerank-english-v3.0")
+	reranker.BaseURL = server.URL
+
+	_, err := reranker.Rerank(s.ctx, "query", rerankInputs())
+	s.Error(err)
+}
+
+func (s *RerankTestSuite) TestCohereRerankerContextCanceled() {
+	server := newCohereTestServer(map[string]float32{}, nil)
+	defer server.Close()
+
+	reranker := NewCohereReranker("test-key", "rerank-english-v3.0")
+	reranker.BaseURL = server.URL
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	_, err := reranker.Rerank(ctx, "query", rerankInputs())
+	s.ErrorIs(err, context.Canceled)
+}
+
+func (s *RerankTestSuite) TestCohereRerankerEmpty() {
+	reranker := NewCohereReranker("test-key", "rerank-english-v3.0")
+	results, err := reranker.Rerank(s.ctx, "query", nil)
+	s.NoError(err)
+	s.Empty(results)
+}
+
+// recordingReranker records the size of each batch it is asked to rerank
+type recordingReranker struct {
+	inner   Reranker
+	batches []int
+	err     error
+}
+
+func (r *recordingReranker) Rerank(ctx context.Context, query string, results []SearchResult) ([]SearchResult, error) {
+	r.batches = append(r.batches, len(results))
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.inner.Rerank(ctx, query, results)
+}
+
+func (s *RerankTestSuite) TestBatchingReranker() {
+	inputs := make([]SearchResult, 250)
+	for i := range inputs {
+		inputs[i] = SearchResult{ID: fmt.Sprintf("doc_%d", i), Text: fmt.Sprintf("text %d", i)}
+	}
+
+	// Score is a scrambled function of the position so batches interleave in the global order
+	scores := make(map[string]float32, len(inputs))
+	for i, input := range inputs {
+		scores[input.ID] = float32((i * 37) % 250)
+	}
+
+	inner := &recordingReranker{inner: NewCustomScorerReranker(func(query string, result SearchResult) float32 {
+		return scores[result.ID]
+	})}
+	reranker := NewBatchingReranker(inner, 100)
+
+	results, err := reranker.Rerank(s.ctx, "query", inputs)
+	s.Require().NoError(err)
+	s.Equal([]int{100, 100, 50}, inner.batches)
+	s.Require().Len(results, 250)
+
+	seen := make(map[string]bool, len(results))
+	for i, result := range results {
+		if i > 0 {
+			s.GreaterOrEqual(results[i-1].Score, result.Score)
+		}
+		s.Equal(scores[result.ID], result.Score)
+		s.Equal("text "+result.ID[len("doc_"):], result.Text)
+		seen[result.ID] = true
+	}
+	s.Len(seen, 250)
+
+	// Inputs are not reordered or rescored
+	s.Equal("doc_0", inputs[0].ID)
+	s.Zero(inputs[0].Score)
+}
+
+func (s *RerankTestSuite) TestBatchingRerankerSmallInput() {
+	inner := &recordingReranker{inner: NewCustomScorerReranker(func(query string, result SearchResult) float32 {
+		return 1
+	})}
+
+	results, err := NewBatchingReranker(inner, 100).Rerank(s.ctx, "query", rerankInputs())
+	s.Require().NoError(err)
+	s.Len(results, 4)
+	s.Equal([]int{4}, inner.batches)
+}
+
+func (s *RerankTestSuite) TestBatchingRerankerError() {
+	inner := &recordingReranker{err: fmt.Errorf("endpoint unavailable")}
+
+	inputs := make([]SearchResult, 5)
+	_, err := NewBatchingReranker(inner, 2).Rerank(s.ctx, "query", inputs)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "endpoint unavailable")
+	s.Equal([]int{2}, inner.batches)
+}
+
+func (s *RerankTestSuite) TestBatchingRerankerDefaultBatch() {
+	s.Equal(defaultRerankBatchSize, NewBatchingReranker(&recordingReranker{}, 0).MaxBatch)
+
+	// A reranker built without the constructor uses the default too
+	inner := &recordingReranker{inner: NewCustomScorerReranker(func(query string, result SearchResult) float32 {
+		return 1
+	})}
+	results, err := (&BatchingReranker{Inner: inner}).Rerank(s.ctx, "query", make([]SearchResult, 250))
+	s.Require().NoError(err)
+	s.Len(results, 250)
+	s.Equal([]int{100, 100, 50}, inner.batches)
+}
@@ -2,9 +2,12 @@ package rag
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"time"
+
+	"github.com/aqua777/go-lancedb"
 )
 
 // RetryConfig configures retry behavior for transient failures
@@ -31,9 +34,13 @@ func retryWithBackoff(ctx context.Context, config *RetryConfig, fn func() error)
 	if config == nil {
 		config = DefaultRetryConfig()
 	}
+	maxAttempts := config.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultRetryConfig().MaxAttempts
+	}
 
 	var lastErr error
-	for attempt := 0; attempt < config.MaxAttempts; attempt++ {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
 		// Check for context cancellation before attempting
 		select {
 		case <-ctx.Done():
@@ -50,7 +57,7 @@ func retryWithBackoff(ctx context.Context, config *RetryConfig, fn func() error)
 		lastErr = err
 
 		// Check if this is the last attempt
-		if attempt >= config.MaxAttempts-1 {
+		if attempt >= maxAttempts-1 {
 			break
 		}
 
@@ -60,10 +67,7 @@ func retryWithBackoff(ctx context.Context, config *RetryConfig, fn func() error)
 		}
 
 		// Calculate backoff delay with exponential growth
-		delay := time.Duration(float64(config.InitialDelay) * math.Pow(config.BackoffMultiple, float64(attempt)))
-		if delay > config.MaxDelay {
-			delay = config.MaxDelay
-		}
+		delay := backoffDelay(config, attempt)
 
 		// Wait with context cancellation support
 		select {
@@ -74,17 +78,79 @@ func retryWithBackoff(ctx context.Context, config *RetryConfig, fn func() error)
 		}
 	}
 
-	return fmt.Errorf("max retry attempts (%d) exceeded: %w", config.MaxAttempts, lastErr)
+	return fmt.Errorf("max retry attempts (%d) exceeded: %w", maxAttempts, lastErr)
+}
+
+// withRetry runs fn, retrying transient failures with backoff as the store's RetryConfig
+// describes. Each retry is logged. Without a RetryConfig, fn runs once. A
+// *lancedb.RefreshError means fn's write was committed, so it is logged and counts as
+// success rather than being retried.
+func (s *RAGStore) withRetry(ctx context.Context, operation string, fn func() error) error {
+	// A write that was committed before its handle failed to refresh has succeeded;
+	// running it again would apply it twice
+	run := func() error {
+		err := fn()
+		var refreshErr *lancedb.RefreshError
+		if errors.As(err, &refreshErr) {
+			s.logger.Printf("%s was committed, but refreshing the table handle failed: %v", operation, refreshErr.Err)
+			return nil
+		}
+		return err
+	}
+
+	config := s.retryConfig
+	if config == nil {
+		return run()
+	}
+
+	attempt := 0
+	var lastErr error
+	return retryWithBackoff(ctx, config, func() error {
+		attempt++
+		if attempt > 1 {
+			s.logger.Printf("Retrying %s after transient error (attempt %d): %v", operation, attempt, lastErr)
+		}
+		lastErr = run()
+		return lastErr
+	})
+}
+
+// backoffDelay returns the exponential backoff delay before the retry following attempt (0-based)
+func backoffDelay(config *RetryConfig, attempt int) time.Duration {
+	delay := time.Duration(float64(config.InitialDelay) * math.Pow(config.BackoffMultiple, float64(attempt)))
+	if delay > config.MaxDelay {
+		delay = config.MaxDelay
+	}
+	return delay
 }
 
 // isRetryableError determines if an error is transient and worth retrying.
-// This is a simple heuristic - you may want to customize based on specific LanceDB errors.
+// LanceDB errors are classified by their code, of which only I/O failures are transient;
+// LanceDB errors without a code fall back to a heuristic on their message. Other errors,
+// e.g. cancellation, the store's sentinel errors or failures to build a batch, come from
+// outside the database and are never retried.
 func isRetryableError(err error) bool {
 	if err == nil {
 		return false
 	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrEmbeddingModelMismatch) {
+		return false
+	}
+	// The write behind a refresh failure was committed
+	var refreshErr *lancedb.RefreshError
+	if errors.As(err, &refreshErr) {
+		return false
+	}
+	var lerr *lancedb.Error
+	if !errors.As(err, &lerr) {
+		return false
+	}
+	if lerr.Code != lancedb.ErrorCodeUnknown {
+		return errors.Is(lerr, lancedb.ErrIO)
+	}
 
-	// Simple heuristic: retry on most errors except for specific non-retryable ones
+	// Simple heuristic: retry on most database errors except for specific non-retryable ones
 	errStr := err.Error()
 
 	// Non-retryable errors (validation, schema issues, etc.)
@@ -104,7 +170,7 @@ func isRetryableError(err error) bool {
 		}
 	}
 
-	// Default to retryable for database/network errors
+	// Default to retryable for database/storage errors
 	return true
 }
 
@@ -0,0 +1,161 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aqua777/go-lancedb"
+	"github.com/stretchr/testify/suite"
+)
+
+// RetryTestSuite tests the store's retries of transient failures
+type RetryTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func TestRetrySuite(t *testing.T) {
+	suite.Run(t, new(RetryTestSuite))
+}
+
+func (s *RetryTestSuite) SetupTest() {
+	s.ctx = context.Background()
+}
+
+// transientError is a storage failure as the table operations report it
+func transientError() error {
+	return fmt.Errorf("failed to add documents: %w", &lancedb.Error{Code: lancedb.ErrorCodeIO, Message: "connection reset by peer"})
+}
+
+// retryStore returns a store retrying with config and logging to logger
+func retryStore(config *RetryConfig, logger Logger) *RAGStore {
+	return &RAGStore{retryConfig: config, logger: newSwappableLogger(logger)}
+}
+
+func (s *RetryTestSuite) TestIsRetryableError() {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{nil, false},
+		{transientError(), true},
+		{lancedb.ErrIO, true},
+		{fmt.Errorf("search: %w", &lancedb.Error{Code: lancedb.ErrorCodeInvalidArgument, Message: "bad filter"}), false},
+		{&lancedb.Error{Code: lancedb.ErrorCodeTableNotFound, Message: "users_alice"}, false},
+		{&lancedb.Error{Code: lancedb.ErrorCodeInvalidSchema, Message: "schema mismatch"}, false},
+		{&lancedb.Error{Code: lancedb.ErrorCodeClosed, Message: "table is closed"}, false},
+		{fmt.Errorf("user alice: %w", ErrQuotaExceeded), false},
+		{fmt.Errorf("user alice: %w", ErrEmbeddingModelMismatch), false},
+		{fmt.Errorf("query: %w", context.Canceled), false},
+		{context.DeadlineExceeded, false},
+		// The write behind a refresh failure was committed
+		{fmt.Errorf("failed to add documents: %w", &lancedb.RefreshError{Err: lancedb.ErrIO}), false},
+		// Unclassified LanceDB errors fall back to the message heuristic
+		{&lancedb.Error{Code: lancedb.ErrorCodeUnknown, Message: "object store timeout"}, true},
+		{&lancedb.Error{Code: lancedb.ErrorCodeUnknown, Message: "invalid vector column"}, false},
+		// Errors from outside the database are never retried
+		{errors.New("query embedding dimension mismatch"), false},
+		{errors.New("failed to build record: unsupported metadata value"), false},
+	}
+	for i, tc := range cases {
+		s.Equal(tc.retryable, isRetryableError(tc.err), "case %d: %v", i, tc.err)
+	}
+}
+
+func (s *RetryTestSuite) TestWithRetrySucceedsAfterTransientFailures() {
+	logger := &recordingLogger{}
+	store := retryStore(fastRetryConfig(3), logger)
+
+	calls := 0
+	err := store.withRetry(s.ctx, "rag.AddDocuments", func() error {
+		calls++
+		if calls < 3 {
+			return transientError()
+		}
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Equal(3, calls)
+	s.Equal(2, logger.count("Retrying rag.AddDocuments after transient error"))
+}
+
+func (s *RetryTestSuite) TestWithRetryGivesUp() {
+	store := retryStore(fastRetryConfig(3), &noopLogger{})
+
+	calls := 0
+	err := store.withRetry(s.ctx, "rag.Search", func() error {
+		calls++
+		return transientError()
+	})
+	s.Error(err)
+	s.ErrorIs(err, lancedb.ErrIO)
+	s.Equal(3, calls)
+
+	// Permanent failures aren't retried
+	calls = 0
+	err = store.withRetry(s.ctx, "rag.Search", func() error {
+		calls++
+		return &lancedb.Error{Code: lancedb.ErrorCodeInvalidArgument, Message: "bad filter"}
+	})
+	s.ErrorIs(err, lancedb.ErrInvalidArgument)
+	s.Equal(1, calls)
+}
+
+func (s *RetryTestSuite) TestWithRetryDoesNotRepeatCommittedWrites() {
+	for _, config := range []*RetryConfig{fastRetryConfig(3), nil} {
+		logger := &recordingLogger{}
+		store := retryStore(config, logger)
+
+		calls := 0
+		err := store.withRetry(s.ctx, "rag.AddDocuments", func() error {
+			calls++
+			return fmt.Errorf("failed to add documents: %w", &lancedb.RefreshError{Err: transientError()})
+		})
+		s.NoError(err, "the write was committed")
+		s.Equal(1, calls, "a committed write must not be applied again")
+		s.Equal(1, logger.count("rag.AddDocuments was committed, but refreshing the table handle failed"))
+		s.Equal(0, logger.count("Retrying"))
+	}
+}
+
+func (s *RetryTestSuite) TestWithRetryWithoutConfig() {
+	store := retryStore(nil, &noopLogger{})
+
+	calls := 0
+	err := store.withRetry(s.ctx, "rag.AddDocuments", func() error {
+		calls++
+		return transientError()
+	})
+	s.ErrorIs(err, lancedb.ErrIO)
+	s.Equal(1, calls)
+}
+
+func (s *RetryTestSuite) TestWithRetryStopsOnCancel() {
+	store := retryStore(&RetryConfig{MaxAttempts: 5, InitialDelay: time.Hour, MaxDelay: time.Hour, BackoffMultiple: 2}, &noopLogger{})
+
+	ctx, cancel := context.WithTimeout(s.ctx, 20*time.Millisecond)
+	defer cancel()
+	calls := 0
+	err := store.withRetry(ctx, "rag.AddDocuments", func() error {
+		calls++
+		return transientError()
+	})
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.Equal(1, calls)
+}
+
+func (s *RetryTestSuite) TestRetryWithBackoffDefaultsMaxAttempts() {
+	calls := 0
+	err := retryWithBackoff(s.ctx, &RetryConfig{InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffMultiple: 1}, func() error {
+		calls++
+		if calls < 2 {
+			return transientError()
+		}
+		return nil
+	})
+	s.NoError(err)
+	s.Equal(2, calls)
+}
@@ -0,0 +1,65 @@
+package rag
+
+import (
+	"context"
+)
+
+// DocumentResult is a document matched by SearchDocuments
+type DocumentResult struct {
+	DocumentName      string
+	BestChunk         SearchResult // The document's most relevant chunk
+	MatchedChunkCount int          // How many of the document's chunks were among the search candidates
+}
+
+// SearchDocuments searches the user's chunks and groups them by DocumentName, returning
+// up to opts.Limit documents ranked by their best chunk. The search fetches five times
+// Limit candidate chunks to fill the results, so MatchedChunkCount counts the document's
+// chunks among those candidates rather than every matching chunk.
+// DedupeByDocument is ignored.
+func (s *RAGStore) SearchDocuments(ctx context.Context, userID string, queryEmbedding []float32, opts *SearchOptions) ([]DocumentResult, error) {
+	// Set defaults without modifying the caller's options
+	searchOpts := defaultSearchOptions()
+	if opts != nil {
+		searchOpts = *opts
+	}
+	if searchOpts.Limit <= 0 {
+		searchOpts.Limit = 10
+	}
+	limit := searchOpts.Limit
+	searchOpts.Limit = limit * dedupeOverfetchFactor
+	searchOpts.DedupeByDocument = false
+	if len(searchOpts.Columns) > 0 {
+		// Grouping needs the document name
+		searchOpts.Columns = append(append([]string(nil), searchOpts.Columns...), "document_name")
+	}
+
+	candidates, err := s.Search(ctx, userID, queryEmbedding, &searchOpts)
+	if err != nil {
+		return nil, err
+	}
+	return groupByDocument(candidates, limit), nil
+}
+
+// groupByDocument groups results by DocumentName, keeping the first result of each document
+// as its best chunk, and returns up to limit documents. Results must be ordered best first.
+func groupByDocument(results []SearchResult, limit int) []DocumentResult {
+	documents := make([]DocumentResult, 0, limit)
+	positions := make(map[string]int)
+	for _, result := range results {
+		if i, ok := positions[result.DocumentName]; ok {
+			documents[i].MatchedChunkCount++
+			continue
+		}
+		if len(documents) == limit {
+			// Later chunks of documents already kept are still counted
+			continue
+		}
+		positions[result.DocumentName] = len(documents)
+		documents = append(documents, DocumentResult{
+			DocumentName:      result.DocumentName,
+			BestChunk:         result,
+			MatchedChunkCount: 1,
+		})
+	}
+	return documents
+}
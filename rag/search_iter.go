@@ -0,0 +1,212 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aqua777/go-lancedb"
+)
+
+// ResultIterator yields search results one at a time, best first
+type ResultIterator interface {
+	// Next returns the next result. ok is false once the results are exhausted or the
+	// iterator is closed; err is set if the search failed.
+	Next() (result *SearchResult, ok bool, err error)
+	// Close stops the search and releases its resources. It is safe to call more than once.
+	Close()
+}
+
+// SearchIter runs a vector search like Search, but returns an iterator that decodes one
+// result batch at a time from a streaming query instead of materializing every result.
+// Callers may stop early; Close must be called once done, and releases the underlying
+// stream immediately.
+func (s *RAGStore) SearchIter(ctx context.Context, userID string, queryEmbedding []float32, opts *SearchOptions) (ResultIterator, error) {
+	return s.openSearchIterator(ctx, userID, queryEmbedding, opts)
+}
+
+// searchIterator reads the results of a streaming vector search.
+// Int8 tables can't be streamed, so their results are computed up front into pending.
+type searchIterator struct {
+	ctx     context.Context
+	cancel  context.CancelFunc // Ends the search's timeout, if any
+	release func()             // Returns the table handle to the cache
+	query   *lancedb.Query
+	stream  lancedb.RecordIterator
+
+	opts      *SearchOptions
+	dim       int
+	scoreType lancedb.DistanceType // Distance type of the streamed distances (see checkDistanceType)
+	codec     MetadataCodec
+	limiter   *documentChunkLimiter
+	pending   []SearchResult // Results decoded but not yet returned
+	sent      int
+	closed    bool
+}
+
+// openSearchIterator validates the search and starts it. The caller must close the iterator.
+func (s *RAGStore) openSearchIterator(ctx context.Context, userID string, queryEmbedding []float32, opts *SearchOptions) (_ *searchIterator, err error) {
+	if err := s.validateUserID(userID); err != nil {
+		return nil, err
+	}
+
+	// Validate the query against the user's embedding dimension
+	dim := s.userEmbeddingDim(userID)
+	if len(queryEmbedding) != dim {
+		return nil, fmt.Errorf("query embedding dimension mismatch: expected %d, got %d",
+			dim, len(queryEmbedding))
+	}
+
+	// Set defaults
+	if opts == nil {
+		searchOpts := defaultSearchOptions()
+		opts = &searchOpts
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+
+	it := &searchIterator{opts: opts, dim: dim, codec: s.getMetadataCodec(), ctx: ctx}
+	if opts.Timeout > 0 {
+		it.ctx, it.cancel = context.WithTimeout(ctx, opts.Timeout)
+	}
+	defer func() {
+		if err != nil {
+			it.Close()
+		}
+	}()
+
+	exists, err := s.TableExists(it.ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		it.closed = true // No documents yet
+		return it, nil
+	}
+
+	table, release, err := s.acquireTable(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	it.release = release
+
+	if err := s.checkEmbeddingModel(table, userID, s.searchEmbeddingModel(opts)); err != nil {
+		return nil, err
+	}
+
+	it.scoreType, _ = s.checkDistanceType(userID, opts)
+
+	// Quantized searches scan the whole table before returning the nearest rows
+	if storage := s.userEmbeddingStorage(userID); storage == EmbeddingStorageInt8 {
+		it.pending, err = s.searchTable(it.ctx, table, queryEmbedding, opts, dim, storage, it.scoreType)
+		if err != nil {
+			return nil, err
+		}
+		return it, nil
+	}
+
+	columns, err := searchColumns(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	it.query = buildSearchQuery(table, queryEmbedding, opts, columns)
+	it.stream, err = it.query.ExecuteStreaming()
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute search: %w", err)
+	}
+	if opts.DedupeByDocument {
+		it.limiter = newDocumentChunkLimiter(opts.MaxChunksPerDocument)
+	}
+	return it, nil
+}
+
+// Next returns the next result, reading another batch from the stream when needed
+func (it *searchIterator) Next() (*SearchResult, bool, error) {
+	for !it.closed {
+		if len(it.pending) == 0 {
+			if err := it.fill(); err != nil {
+				it.Close()
+				return nil, false, err
+			}
+			continue
+		}
+
+		result := it.pending[0]
+		it.pending = it.pending[1:]
+
+		// Results of the int8 path are already filtered
+		if it.stream != nil {
+			// Every result is checked, since an index's approximate distances needn't be sorted
+			if it.opts.MinSimilarity > 0 && result.Similarity < it.opts.MinSimilarity {
+				continue
+			}
+			if it.limiter != nil && !it.limiter.allow(result.DocumentName) {
+				continue
+			}
+		}
+
+		it.sent++
+		if it.sent == it.opts.Limit {
+			it.Close()
+		}
+		return &result, true, nil
+	}
+	return nil, false, nil
+}
+
+// fill decodes the next batch of the stream into pending, closing the iterator at the end
+func (it *searchIterator) fill() error {
+	if it.stream == nil {
+		it.Close()
+		return nil
+	}
+
+	// Check for context cancellation between batches
+	select {
+	case <-it.ctx.Done():
+		return it.ctx.Err()
+	default:
+	}
+
+	record, err := it.stream.Next()
+	if err != nil {
+		return fmt.Errorf("failed to read search results: %w", err)
+	}
+	if record == nil {
+		it.Close() // End of stream
+		return nil
+	}
+
+	// parseSearchResults copies strings out of the Arrow buffers,
+	// so results stay valid after the record is released
+	batch, err := parseVectorSearchResults(record, it.dim, it.codec, it.scoreType, nil)
+	record.Release()
+	if err != nil {
+		return fmt.Errorf("failed to parse results: %w", err)
+	}
+	it.pending = batch
+	return nil
+}
+
+// Close releases the stream, the query and the table handle
+func (it *searchIterator) Close() {
+	it.closed = true
+	it.pending = nil
+	if it.stream != nil {
+		it.stream.Close()
+		it.stream = nil
+	}
+	if it.query != nil {
+		it.query.Close()
+		it.query = nil
+	}
+	if it.release != nil {
+		it.release()
+		it.release = nil
+	}
+	if it.cancel != nil {
+		it.cancel()
+		it.cancel = nil
+	}
+}
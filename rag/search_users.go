@@ -0,0 +1,91 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// searchManyUsersWorkers is the maximum number of concurrent searches run by SearchManyUsers
+const searchManyUsersWorkers = 8
+
+// SearchManyUsers runs the same vector search against each user's documents, for
+// cross-tenant admin queries. Searches run concurrently, with at most 8 in flight.
+// Users without a table get an empty result slice.
+//
+// A failed search doesn't stop the others: the returned map holds results for every
+// user whose search succeeded, and the error lists the users that failed. If ctx is
+// cancelled, the remaining searches are abandoned and only ctx's error is returned.
+func (s *RAGStore) SearchManyUsers(ctx context.Context, userIDs []string, queryEmbedding []float32, opts *SearchOptions) (map[string][]SearchResult, error) {
+	// Set defaults once; each search gets its own copy, since Search updates its options
+	searchOpts := defaultSearchOptions()
+	if opts != nil {
+		searchOpts = *opts
+	}
+
+	// Search each user once, even if listed several times
+	seen := make(map[string]bool, len(userIDs))
+	unique := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if !seen[userID] {
+			seen[userID] = true
+			unique = append(unique, userID)
+		}
+	}
+
+	workers := searchManyUsersWorkers
+	if workers > len(unique) {
+		workers = len(unique)
+	}
+
+	var (
+		mu       sync.Mutex
+		results  = make(map[string][]SearchResult, len(unique))
+		failures []error
+	)
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for userID := range jobs {
+				userOpts := searchOpts
+				userResults, err := s.Search(ctx, userID, queryEmbedding, &userOpts)
+
+				mu.Lock()
+				if err != nil {
+					failures = append(failures, fmt.Errorf("user %s: %w", userID, err))
+				} else {
+					results[userID] = userResults
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Feed work, stopping early on cancellation
+feed:
+	for _, userID := range unique {
+		select {
+		case jobs <- userID:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		for _, userResults := range results {
+			ReleaseEmbeddings(userResults)
+		}
+		return nil, err
+	}
+	if len(failures) > 0 {
+		return results, fmt.Errorf("search failed for %d of %d users: %w", len(failures), len(unique), errors.Join(failures...))
+	}
+	return results, nil
+}
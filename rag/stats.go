@@ -0,0 +1,179 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/aqua777/go-lancedb"
+)
+
+// UserStats summarizes a user's documents, e.g. for a dashboard
+type UserStats struct {
+	ChunkCount          int64             // Number of document chunks (rows) in the user's table
+	UniqueDocumentCount int               // Number of distinct DocumentName values
+	AvgTextLength       float64           // Mean chunk text length in characters; zero without chunks
+	IndexCreated        bool              // Whether the table has a vector index
+	IndexType           lancedb.IndexType // Type of the user's vector index, or the type it will be built with
+}
+
+// GetUserStats returns statistics about a user's documents, reading the table once.
+// Users without a table get zero counts.
+func (s *RAGStore) GetUserStats(ctx context.Context, userID string) (*UserStats, error) {
+	indexStats, err := s.IndexStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stats := &UserStats{
+		IndexCreated: indexStats.IndexExists,
+		IndexType:    indexStats.IndexType,
+	}
+
+	exists, err := s.TableExists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return stats, nil
+	}
+
+	table, release, err := s.acquireTable(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	defer release()
+
+	names := make(map[string]struct{})
+	var totalLength int64
+	err = streamRecords(ctx, table, "", []string{"text", "document_name"}, func(record arrow.Record) error {
+		texts, err := recordStrings(record, "text")
+		if err != nil {
+			return err
+		}
+		docNames, err := recordStrings(record, "document_name")
+		if err != nil {
+			return err
+		}
+		for i, text := range texts {
+			totalLength += int64(utf8.RuneCountInString(text))
+
+			// Copy new names out of the record's buffer, which is freed after fn returns
+			name := docNames[i]
+			if _, ok := names[name]; !ok {
+				names[string([]byte(name))] = struct{}{}
+			}
+		}
+		stats.ChunkCount += record.NumRows()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute stats for user %s: %w", userID, err)
+	}
+
+	stats.UniqueDocumentCount = len(names)
+	if stats.ChunkCount > 0 {
+		stats.AvgTextLength = float64(totalLength) / float64(stats.ChunkCount)
+	}
+	return stats, nil
+}
+
+// GlobalStats summarizes the documents of every user in the store
+type GlobalStats struct {
+	TotalUsers     int   // Number of users with a table
+	TotalChunks    int64 // Document chunks across all users
+	TotalDocuments int64 // Sum of each user's distinct document names
+	DiskBytes      int64 // Approximate size of the user tables on disk; zero for remote databases
+}
+
+// globalStatsWorkers is the maximum number of users GlobalStats reads concurrently
+const globalStatsWorkers = 4
+
+// GlobalStats aggregates GetUserStats over every user table, reading at most 4 tables
+// concurrently. Returns an error listing the users whose stats couldn't be read; if ctx is
+// cancelled, the remaining users are abandoned and only ctx's error is returned.
+func (s *RAGStore) GlobalStats(ctx context.Context) (*GlobalStats, error) {
+	userIDs, err := s.listUserIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := globalStatsWorkers
+	if workers > len(userIDs) {
+		workers = len(userIDs)
+	}
+
+	var (
+		mu       sync.Mutex
+		stats    = &GlobalStats{TotalUsers: len(userIDs)}
+		failures []error
+	)
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for userID := range jobs {
+				userStats, err := s.GetUserStats(ctx, userID)
+				var size int64
+				if err == nil {
+					size, err = s.tableDiskBytes(userID)
+				}
+
+				mu.Lock()
+				if err != nil {
+					failures = append(failures, fmt.Errorf("user %s: %w", userID, err))
+				} else {
+					stats.TotalChunks += userStats.ChunkCount
+					stats.TotalDocuments += int64(userStats.UniqueDocumentCount)
+					stats.DiskBytes += size
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Feed work, stopping early on cancellation
+feed:
+	for _, userID := range userIDs {
+		select {
+		case jobs <- userID:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("stats failed for %d of %d users: %w", len(failures), len(userIDs), errors.Join(failures...))
+	}
+	return stats, nil
+}
+
+// tableDiskBytes returns the approximate size of a user's table on disk, or zero if the
+// database isn't on the local filesystem
+func (s *RAGStore) tableDiskBytes(userID string) (int64, error) {
+	table, release, err := s.acquireTable(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	defer release()
+
+	size, err := table.DiskUsage()
+	var lerr *lancedb.Error
+	if errors.As(err, &lerr) && lerr.Code == lancedb.ErrorCodeInvalidArgument {
+		return 0, nil // Remote table
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure table size: %w", err)
+	}
+	return size, nil
+}
@@ -0,0 +1,174 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/aqua777/go-lancedb"
+	"github.com/stretchr/testify/suite"
+)
+
+// StatsTestSuite tests per-user statistics
+type StatsTestSuite struct {
+	suite.Suite
+	store  *RAGStore
+	dbPath string
+	ctx    context.Context
+}
+
+// SetupTest runs before each test
+func (s *StatsTestSuite) SetupTest() {
+	tmpDir, err := os.MkdirTemp("", "rag_stats_test_*")
+	s.Require().NoError(err)
+	s.dbPath = filepath.Join(tmpDir, "test.db")
+	s.ctx = context.Background()
+
+	store, err := NewRAGStoreWithConfig(s.dbPath, 128, 100, &noopLogger{}, DefaultRetryConfig(), nil)
+	s.Require().NoError(err)
+	s.store = store
+}
+
+// TearDownTest runs after each test
+func (s *StatsTestSuite) TearDownTest() {
+	if s.store != nil {
+		s.store.Close()
+	}
+	if s.dbPath != "" {
+		os.RemoveAll(filepath.Dir(s.dbPath))
+	}
+}
+
+// TestStatsTestSuite runs the stats test suite
+func TestStatsTestSuite(t *testing.T) {
+	suite.Run(t, new(StatsTestSuite))
+}
+
+func (s *StatsTestSuite) TestGetUserStats() {
+	userID := "stats_user"
+	docs := append(makeTestDocs(3, 128, "a.txt"), makeTestDocs(5, 128, "b.txt")...)
+	docs = append(docs, makeTestDocs(2, 128, "c.txt")...)
+
+	// Give every chunk a known length: 10 chunks of 10 or 30 characters ("é" is one character)
+	for i := range docs {
+		if i%2 == 0 {
+			docs[i].Text = strings.Repeat("é", 10)
+		} else {
+			docs[i].Text = strings.Repeat("x", 30)
+		}
+	}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	stats, err := s.store.GetUserStats(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(10), stats.ChunkCount)
+	s.Equal(3, stats.UniqueDocumentCount)
+	s.InDelta(20, stats.AvgTextLength, 1e-9)
+	s.False(stats.IndexCreated, "10 rows are below the index threshold")
+	s.Equal(DefaultIndexConfig().IndexType, stats.IndexType)
+}
+
+func (s *StatsTestSuite) TestGetUserStatsWithIndex() {
+	userID := "indexed_user"
+	s.store.SetMinRowsForIndex(256)
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(300, 128, "big.txt")))
+
+	stats, err := s.store.GetUserStats(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(300), stats.ChunkCount)
+	s.Equal(1, stats.UniqueDocumentCount)
+	s.True(stats.IndexCreated)
+	s.Equal(lancedb.IndexTypeIVFPQ, stats.IndexType)
+}
+
+func (s *StatsTestSuite) TestGetUserStatsNoTable() {
+	stats, err := s.store.GetUserStats(s.ctx, "nobody")
+	s.Require().NoError(err)
+	s.Zero(stats.ChunkCount)
+	s.Zero(stats.UniqueDocumentCount)
+	s.Zero(stats.AvgTextLength)
+	s.False(stats.IndexCreated)
+}
+
+func (s *StatsTestSuite) TestGetUserStatsCancelled() {
+	userID := "cancel_user"
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(10, 128, "a.txt")))
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+	_, err := s.store.GetUserStats(ctx, userID)
+	s.ErrorIs(err, context.Canceled)
+
+	_, err = s.store.GetUserStats(s.ctx, "bad user!")
+	s.Error(err)
+}
+
+func (s *StatsTestSuite) TestRecordStringsByName() {
+	// Columns in another order, with a large string column, are read by name
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "document_name", Type: arrow.BinaryTypes.LargeString},
+		{Name: "text", Type: arrow.BinaryTypes.String},
+	}, nil)
+
+	names := array.NewLargeStringBuilder(memory.DefaultAllocator)
+	defer names.Release()
+	names.AppendValues([]string{"a.txt", "b.txt"}, nil)
+	texts := array.NewStringBuilder(memory.DefaultAllocator)
+	defer texts.Release()
+	texts.AppendValues([]string{"first", "second"}, nil)
+
+	nameArr, textArr := names.NewArray(), texts.NewArray()
+	defer nameArr.Release()
+	defer textArr.Release()
+	record := array.NewRecord(schema, []arrow.Array{nameArr, textArr}, 2)
+	defer record.Release()
+
+	values, err := recordStrings(record, "text")
+	s.Require().NoError(err)
+	s.Equal([]string{"first", "second"}, values)
+	values, err = recordStrings(record, "document_name")
+	s.Require().NoError(err)
+	s.Equal([]string{"a.txt", "b.txt"}, values)
+
+	_, err = recordStrings(record, "metadata")
+	s.Error(err)
+}
+
+func (s *StatsTestSuite) TestGlobalStats() {
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user_a", makeTestDocs(4, 128, "a.txt")))
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user_b", append(makeTestDocs(3, 128, "b1.txt"), makeTestDocs(6, 128, "b2.txt")...)))
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user_c", makeTestDocs(2, 128, "c.txt")))
+
+	var chunks, documents int64
+	for _, userID := range []string{"user_a", "user_b", "user_c"} {
+		userStats, err := s.store.GetUserStats(s.ctx, userID)
+		s.Require().NoError(err)
+		chunks += userStats.ChunkCount
+		documents += int64(userStats.UniqueDocumentCount)
+	}
+
+	stats, err := s.store.GlobalStats(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(3, stats.TotalUsers)
+	s.Equal(chunks, stats.TotalChunks)
+	s.Equal(int64(15), stats.TotalChunks)
+	s.Equal(documents, stats.TotalDocuments)
+	s.Equal(int64(4), stats.TotalDocuments)
+	s.Greater(stats.DiskBytes, int64(0))
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+	_, err = s.store.GlobalStats(ctx)
+	s.ErrorIs(err, context.Canceled)
+}
+
+func (s *StatsTestSuite) TestGlobalStatsEmpty() {
+	stats, err := s.store.GlobalStats(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(&GlobalStats{}, stats)
+}
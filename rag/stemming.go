@@ -0,0 +1,354 @@
+package rag
+
+// Tokenizer splits text into the terms matched by in-memory BM25 keyword search.
+// Documents and queries go through the same tokenizer, so a tokenizer that normalizes
+// terms (e.g. by stemming) lets inflected forms of a word match each other.
+type Tokenizer interface {
+	Tokenize(text string) []string
+}
+
+// SimpleTokenizer lowercases text and splits it on anything that isn't an ASCII letter or
+// digit. Terms only match exactly, and common words count like any other.
+type SimpleTokenizer struct{}
+
+// Tokenize splits text into lowercase alphanumeric terms
+func (SimpleTokenizer) Tokenize(text string) []string {
+	return tokenize(text)
+}
+
+// StemmingTokenizer splits text like SimpleTokenizer, drops stopwords and reduces the
+// remaining terms to their Porter stems, so "running" and "runs" both match "run".
+// It is the default tokenizer for keyword search.
+type StemmingTokenizer struct {
+	// Stopwords are removed before stemming. Nil means DefaultStopwords; use an empty
+	// map to keep every term.
+	Stopwords map[string]bool
+}
+
+// DefaultStopwords is the English stopword list used by StemmingTokenizer
+var DefaultStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "for": true, "if": true, "in": true, "into": true, "is": true,
+	"it": true, "no": true, "not": true, "of": true, "on": true, "or": true, "such": true,
+	"that": true, "the": true, "their": true, "then": true, "there": true, "these": true,
+	"they": true, "this": true, "to": true, "was": true, "will": true, "with": true,
+}
+
+// NewStemmingTokenizer creates a stemming tokenizer with the default stopwords
+func NewStemmingTokenizer() *StemmingTokenizer {
+	return &StemmingTokenizer{}
+}
+
+// Tokenize splits text into stemmed terms, leaving out stopwords
+func (t *StemmingTokenizer) Tokenize(text string) []string {
+	stopwords := t.Stopwords
+	if stopwords == nil {
+		stopwords = DefaultStopwords
+	}
+
+	tokens := tokenize(text)
+	terms := tokens[:0]
+	for _, token := range tokens {
+		if stopwords[token] {
+			continue
+		}
+		terms = append(terms, PorterStem(token))
+	}
+	return terms
+}
+
+// SetTokenizer sets the tokenizer used by in-memory BM25 keyword search in HybridSearch.
+// Pass nil to restore the default StemmingTokenizer. It doesn't affect searches using a
+// full-text index (see CreateTextIndex), which tokenize in LanceDB.
+func (s *RAGStore) SetTokenizer(tokenizer Tokenizer) {
+	s.mu.Lock()
+	s.tokenizer = tokenizer
+	s.tokenizerGen++
+	s.mu.Unlock()
+}
+
+// getTokenizer returns the configured tokenizer, or a StemmingTokenizer if none is set,
+// along with a generation that changes whenever SetTokenizer is called
+func (s *RAGStore) getTokenizer() (Tokenizer, uint64) {
+	s.mu.RLock()
+	tokenizer, gen := s.tokenizer, s.tokenizerGen
+	s.mu.RUnlock()
+
+	if tokenizer == nil {
+		return NewStemmingTokenizer(), gen
+	}
+	return tokenizer, gen
+}
+
+// PorterStem returns the stem of a lowercase English word using the Porter stemming
+// algorithm, e.g. "connections" and "connected" both become "connect". Words with
+// characters other than a-z, and words of up to two letters, are returned unchanged.
+func PorterStem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	st := &porterStemmer{b: []byte(word), k: len(word) - 1}
+	st.step1ab()
+	if st.k > 0 {
+		st.step1c()
+		st.step2()
+		st.step3()
+		st.step4()
+		st.step5()
+	}
+	return string(st.b[:st.k+1])
+}
+
+// porterStep2Suffixes lists the suffixes step2 replaces, keyed by their second-to-last letter
+var porterStep2Suffixes = map[byte][][2]string{
+	'a': {{"ational", "ate"}, {"tional", "tion"}},
+	'c': {{"enci", "ence"}, {"anci", "ance"}},
+	'e': {{"izer", "ize"}},
+	'l': {{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"}},
+	'o': {{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}},
+	's': {{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"}},
+	't': {{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}},
+	'g': {{"logi", "log"}},
+}
+
+// porterStep3Suffixes lists the suffixes step3 replaces, keyed by their last letter
+var porterStep3Suffixes = map[byte][][2]string{
+	'e': {{"icate", "ic"}, {"ative", ""}, {"alize", "al"}},
+	'i': {{"iciti", "ic"}},
+	'l': {{"ical", "ic"}, {"ful", ""}},
+	's': {{"ness", ""}},
+}
+
+// porterStemmer holds a word being stemmed: b[0..k] is the current word and j marks
+// the end of the stem before a matched suffix
+type porterStemmer struct {
+	b    []byte
+	k, j int
+}
+
+// cons reports whether b[i] is a consonant
+func (st *porterStemmer) cons(i int) bool {
+	switch st.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !st.cons(i-1)
+	default:
+		return true
+	}
+}
+
+// m measures the number of vowel-consonant sequences in b[0..j]
+func (st *porterStemmer) m() int {
+	n := 0
+	i := 0
+	for {
+		if i > st.j {
+			return n
+		}
+		if !st.cons(i) {
+			break
+		}
+		i++
+	}
+	i++
+	for {
+		for {
+			if i > st.j {
+				return n
+			}
+			if st.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+		n++
+		for {
+			if i > st.j {
+				return n
+			}
+			if !st.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+	}
+}
+
+// vowelInStem reports whether b[0..j] contains a vowel
+func (st *porterStemmer) vowelInStem() bool {
+	for i := 0; i <= st.j; i++ {
+		if !st.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleC reports whether b[i-1..i] is a double consonant
+func (st *porterStemmer) doubleC(i int) bool {
+	return i >= 1 && st.b[i] == st.b[i-1] && st.cons(i)
+}
+
+// cvc reports whether b[i-2..i] is consonant-vowel-consonant with the last consonant
+// not w, x or y, which marks a short syllable as in "hop" or "fil"
+func (st *porterStemmer) cvc(i int) bool {
+	if i < 2 || !st.cons(i) || st.cons(i-1) || !st.cons(i-2) {
+		return false
+	}
+	switch st.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// ends reports whether b[0..k] ends with s, setting j to the end of the stem if so
+func (st *porterStemmer) ends(s string) bool {
+	n := len(s)
+	if n > st.k+1 || string(st.b[st.k-n+1:st.k+1]) != s {
+		return false
+	}
+	st.j = st.k - n
+	return true
+}
+
+// setTo replaces b[j+1..k] with s
+func (st *porterStemmer) setTo(s string) {
+	st.b = append(st.b[:st.j+1], s...)
+	st.k = st.j + len(s)
+}
+
+// r replaces the matched suffix with s if the stem has a measure above zero
+func (st *porterStemmer) r(s string) {
+	if st.m() > 0 {
+		st.setTo(s)
+	}
+}
+
+// step1ab removes plurals and -ed or -ing
+func (st *porterStemmer) step1ab() {
+	if st.b[st.k] == 's' {
+		switch {
+		case st.ends("sses"):
+			st.k -= 2
+		case st.ends("ies"):
+			st.setTo("i")
+		case st.b[st.k-1] != 's':
+			st.k--
+		}
+	}
+	if st.ends("eed") {
+		if st.m() > 0 {
+			st.k--
+		}
+	} else if (st.ends("ed") || st.ends("ing")) && st.vowelInStem() {
+		st.k = st.j
+		switch {
+		case st.ends("at"):
+			st.setTo("ate")
+		case st.ends("bl"):
+			st.setTo("ble")
+		case st.ends("iz"):
+			st.setTo("ize")
+		case st.doubleC(st.k):
+			switch st.b[st.k] {
+			case 'l', 's', 'z':
+			default:
+				st.k--
+			}
+		default:
+			st.j = st.k
+			if st.m() == 1 && st.cvc(st.k) {
+				st.setTo("e")
+			}
+		}
+	}
+}
+
+// step1c turns a terminal y into i when there is another vowel in the stem
+func (st *porterStemmer) step1c() {
+	if st.ends("y") && st.vowelInStem() {
+		st.b[st.k] = 'i'
+	}
+}
+
+// step2 maps double suffixes to single ones, e.g. -ization to -ize
+func (st *porterStemmer) step2() {
+	st.replaceSuffix(porterStep2Suffixes[st.b[st.k-1]])
+}
+
+// step3 handles -ic-, -full, -ness and similar suffixes
+func (st *porterStemmer) step3() {
+	st.replaceSuffix(porterStep3Suffixes[st.b[st.k]])
+}
+
+// replaceSuffix replaces the first of the suffixes b ends with, if the stem's measure
+// is above zero
+func (st *porterStemmer) replaceSuffix(suffixes [][2]string) {
+	for _, suffix := range suffixes {
+		if st.ends(suffix[0]) {
+			st.r(suffix[1])
+			return
+		}
+	}
+}
+
+// step4 removes -ant, -ence and similar suffixes from stems with a measure above one
+func (st *porterStemmer) step4() {
+	if st.k < 1 {
+		return
+	}
+	var matched bool
+	switch st.b[st.k-1] {
+	case 'a':
+		matched = st.ends("al")
+	case 'c':
+		matched = st.ends("ance") || st.ends("ence")
+	case 'e':
+		matched = st.ends("er")
+	case 'i':
+		matched = st.ends("ic")
+	case 'l':
+		matched = st.ends("able") || st.ends("ible")
+	case 'n':
+		matched = st.ends("ant") || st.ends("ement") || st.ends("ment") || st.ends("ent")
+	case 'o':
+		matched = (st.ends("ion") && st.j >= 0 && (st.b[st.j] == 's' || st.b[st.j] == 't')) || st.ends("ou")
+	case 's':
+		matched = st.ends("ism")
+	case 't':
+		matched = st.ends("ate") || st.ends("iti")
+	case 'u':
+		matched = st.ends("ous")
+	case 'v':
+		matched = st.ends("ive")
+	case 'z':
+		matched = st.ends("ize")
+	}
+	if matched && st.m() > 1 {
+		st.k = st.j
+	}
+}
+
+// step5 removes a final -e and reduces a final -ll in stems with a large enough measure
+func (st *porterStemmer) step5() {
+	st.j = st.k
+	if st.b[st.k] == 'e' {
+		a := st.m()
+		if a > 1 || (a == 1 && !st.cvc(st.k-1)) {
+			st.k--
+		}
+	}
+	if st.b[st.k] == 'l' && st.doubleC(st.k) && st.m() > 1 {
+		st.k--
+	}
+}
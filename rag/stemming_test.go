@@ -0,0 +1,121 @@
+package rag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// StemmingTestSuite tests keyword search tokenizers
+type StemmingTestSuite struct {
+	suite.Suite
+}
+
+// TestStemmingTestSuite runs the stemming test suite
+func TestStemmingTestSuite(t *testing.T) {
+	suite.Run(t, new(StemmingTestSuite))
+}
+
+func (s *StemmingTestSuite) TestPorterStem() {
+	// Examples from Porter's paper and reference implementation
+	cases := map[string]string{
+		"caresses":        "caress",
+		"ponies":          "poni",
+		"cats":            "cat",
+		"feed":            "feed",
+		"agreed":          "agre",
+		"plastered":       "plaster",
+		"motoring":        "motor",
+		"sing":            "sing",
+		"hopping":         "hop",
+		"falling":         "fall",
+		"filing":          "file",
+		"happy":           "happi",
+		"sky":             "sky",
+		"relational":      "relat",
+		"generalizations": "gener",
+		"oscillators":     "oscil",
+		"adjustment":      "adjust",
+		"effective":       "effect",
+		"goodness":        "good",
+		"hopeful":         "hope",
+		"connections":     "connect",
+		"connected":       "connect",
+		"running":         "run",
+		"runs":            "run",
+		"is":              "is",
+		"abc123":          "abc123",
+	}
+	for word, stem := range cases {
+		s.Equal(stem, PorterStem(word), word)
+	}
+}
+
+func (s *StemmingTestSuite) TestTokenizers() {
+	text := "The runner was running to the finish line"
+
+	s.Equal([]string{"the", "runner", "was", "running", "to", "the", "finish", "line"}, SimpleTokenizer{}.Tokenize(text))
+	s.Equal([]string{"runner", "run", "finish", "line"}, NewStemmingTokenizer().Tokenize(text))
+
+	custom := &StemmingTokenizer{Stopwords: map[string]bool{"line": true}}
+	s.Equal([]string{"the", "runner", "wa", "run", "to", "the", "finish"}, custom.Tokenize(text))
+}
+
+func (s *StemmingTestSuite) TestStemmedQueryMatchesInflections() {
+	docs := []SearchResult{
+		{ID: "runs", Text: "She runs every morning"},
+		{ID: "swims", Text: "He swims every evening"},
+		{ID: "cooks", Text: "They cook dinner"},
+	}
+	tokenizer := NewStemmingTokenizer()
+
+	scored := calculateBM25(docs, tokenizer.Tokenize("running"), tokenizer)
+	s.Greater(scored[0].Score, float32(0))
+	s.Zero(scored[1].Score)
+	s.Zero(scored[2].Score)
+
+	// Without stemming, "running" doesn't match "runs"
+	scored = calculateBM25(docs, SimpleTokenizer{}.Tokenize("running"), SimpleTokenizer{})
+	s.Zero(scored[0].Score)
+}
+
+func (s *StemmingTestSuite) TestStopwordsDontDominate() {
+	docs := []SearchResult{
+		{ID: "stopwords", Text: "the the the the the report"},
+		{ID: "zebra", Text: "zebra report"},
+		{ID: "quarterly", Text: "quarterly report"},
+		{ID: "annual", Text: "annual report"},
+	}
+	best := func(scored []SearchResult) string {
+		top := scored[0]
+		for _, doc := range scored[1:] {
+			if doc.Score > top.Score {
+				top = doc
+			}
+		}
+		return top.ID
+	}
+
+	// Repeating "the" outweighs the one meaningful query term without stopword removal
+	s.Equal("stopwords", best(calculateBM25(docs, SimpleTokenizer{}.Tokenize("the zebra"), SimpleTokenizer{})))
+
+	tokenizer := NewStemmingTokenizer()
+	scored := calculateBM25(docs, tokenizer.Tokenize("the zebra"), tokenizer)
+	s.Equal("zebra", best(scored))
+	s.Zero(scored[0].Score)
+}
+
+func (s *StemmingTestSuite) TestStoreTokenizer() {
+	store := &RAGStore{}
+	tokenizer, gen := store.getTokenizer()
+	s.IsType(&StemmingTokenizer{}, tokenizer)
+
+	store.SetTokenizer(SimpleTokenizer{})
+	tokenizer, newGen := store.getTokenizer()
+	s.Equal(SimpleTokenizer{}, tokenizer)
+	s.NotEqual(gen, newGen, "changing the tokenizer must invalidate cached corpora")
+
+	store.SetTokenizer(nil)
+	tokenizer, _ = store.getTokenizer()
+	s.IsType(&StemmingTokenizer{}, tokenizer)
+}
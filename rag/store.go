@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/array"
@@ -37,6 +40,41 @@ type noopLogger struct{}
 func (n *noopLogger) Printf(format string, v ...interface{}) {}
 func (n *noopLogger) Println(v ...interface{})              {}
 
+// swappableLogger forwards to a Logger that can be replaced while operations are logging.
+// It has its own lock, since the store logs while holding s.mu.
+type swappableLogger struct {
+	mu     sync.RWMutex
+	logger Logger
+}
+
+// newSwappableLogger returns a swappableLogger forwarding to logger, or discarding if nil
+func newSwappableLogger(logger Logger) *swappableLogger {
+	l := &swappableLogger{}
+	l.set(logger)
+	return l
+}
+
+func (l *swappableLogger) set(logger Logger) {
+	if logger == nil {
+		logger = &noopLogger{}
+	}
+	l.mu.Lock()
+	l.logger = logger
+	l.mu.Unlock()
+}
+
+func (l *swappableLogger) get() Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.logger
+}
+
+func (l *swappableLogger) Printf(format string, v ...interface{}) { l.get().Printf(format, v...) }
+func (l *swappableLogger) Println(v ...interface{})               { l.get().Println(v...) }
+
+// LogOperation forwards the event to the current logger, formatting it if that isn't structured
+func (l *swappableLogger) LogOperation(event OperationEvent) { logOperation(l.get(), event) }
+
 // IndexConfig defines vector index configuration options
 type IndexConfig struct {
 	IndexType     lancedb.IndexType      // Type of index (IVFPQ, etc.)
@@ -57,27 +95,65 @@ func DefaultIndexConfig() *IndexConfig {
 	}
 }
 
+// DefaultMinRowsForIndex is the default number of rows required before a user's vector index is built
+const DefaultMinRowsForIndex = 256
+
 // RAGStore manages RAG operations with per-user table isolation
 type RAGStore struct {
 	conn               *lancedb.Connection
+	borrowedConn       bool                    // conn belongs to the caller, so Close leaves it open
+	tablePrefix        string                  // user table names are the prefix followed by the user ID
+	maxUserIDLength    int                     // longest accepted user ID, DefaultMaxUserIDLength if zero
 	dbPath             string
 	embeddingDim       int
 	maxBatchSize       int                     // maximum number of documents per batch insert
-	maxDocumentsForBM25 int                    // maximum documents for BM25 keyword search (default: 10000)
-	logger             Logger                  // logger for RAG operations
+	maxDocumentsForBM25 int                    // maximum documents BM25 keyword search loads into memory (default: 10000)
+	logger             *swappableLogger        // logger for RAG operations (see SetLogger)
 	retryConfig        *RetryConfig            // retry configuration for transient failures
-	metrics            MetricsCollector        // metrics collector for monitoring
+	metrics            *swappableMetrics       // metrics collector for monitoring (see SetMetrics)
 	indexConfigs       map[string]*IndexConfig // per-user index configurations
-	indexCreated       map[string]bool         // track per-user table index status
-	mu                 sync.RWMutex            // protect indexCreated and indexConfigs maps
-	userLocks          map[string]*sync.Mutex  // per-user locks for concurrent write protection
-	locksMu            sync.RWMutex            // protect userLocks map
+	indexCreated       map[string]bool         // users whose index needs no building: built, or int8 storage
+	userDims           map[string]int          // per-user embedding dimensions (recorded at table creation)
+	mu                 sync.RWMutex            // protect indexCreated, indexConfigs and userDims maps
+	userLocks          map[string]*userLock    // per-user locks for concurrent write protection
+	locksMu            sync.Mutex              // protect userLocks map and lock refs
+	tables             *tableCache             // LRU of open table handles used by searches
+	tracer             Tracer                  // creates spans around operations (protected by mu)
+	metadataCodec      MetadataCodec           // encodes the metadata column, nil means JSON (protected by mu)
+	minRowsForIndex    int                     // rows required before the vector index is built (protected by mu)
+	indexMetrics       map[string]lancedb.DistanceMetric // metric each user's index was built with (protected by mu)
+	metricWarnings     map[string]bool                   // users already warned about a distance type mismatch (protected by mu)
+	maxDocumentsPerUser int                              // per-user document quota, 0 means unlimited (protected by mu)
+	embeddingModel      string                           // model assumed for embeddings passed without one (protected by mu)
+	userModels          map[string]string                // embedding model recorded in each user's table (protected by mu)
+	tokenizer           Tokenizer                        // tokenizer for in-memory BM25, nil means stemming (protected by mu)
+	tokenizerGen        uint64                           // incremented by SetTokenizer to invalidate cached BM25 corpora (protected by mu)
+	bm25Cache           *bm25Cache                       // tokenized documents for in-memory BM25, keyed by table version
+	userStorages        map[string]EmbeddingStorage      // per-user embedding storage type, float32 if absent (protected by mu)
+	verifiedProviders   map[EmbeddingProvider]int        // declared dimension each provider was verified at (protected by mu)
 }
 
 // NewRAGStore creates a new RAG store with the specified database path and embedding dimension.
-// Uses default configuration: batch size 1000, standard logging, default retry behavior, no metrics.
-func NewRAGStore(dbPath string, embeddingDim int) (*RAGStore, error) {
-	return NewRAGStoreWithConfig(dbPath, embeddingDim, 1000, &defaultLogger{}, DefaultRetryConfig(), nil)
+// Uses default configuration unless changed by opts: batch size 1000, standard logging, default
+// retry behavior, no metrics and user tables named "rag_user_<userID>".
+func NewRAGStore(dbPath string, embeddingDim int, opts ...Option) (*RAGStore, error) {
+	if embeddingDim <= 0 {
+		return nil, fmt.Errorf("embedding dimension must be positive, got %d", embeddingDim)
+	}
+	options, err := resolveOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := lancedb.Connect(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	store := newRAGStore(conn, dbPath, embeddingDim, options.maxBatchSize, options.logger, options.retryConfig, options.metrics)
+	store.tablePrefix = options.tablePrefix
+	store.maxUserIDLength = options.maxUserIDLength
+	return store, nil
 }
 
 // NewRAGStoreWithConfig creates a new RAG store with custom configuration.
@@ -98,10 +174,117 @@ func NewRAGStoreWithConfig(dbPath string, embeddingDim int, maxBatchSize int, lo
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	return newRAGStore(conn, dbPath, embeddingDim, maxBatchSize, logger, retryConfig, metrics), nil
+}
+
+// NewRAGStoreWithConnection creates a RAG store on an existing connection, e.g. one shared
+// with direct lancedb usage or from lancedb.ConnectMemory. Without options it uses the same
+// defaults as NewRAGStore. The connection stays the caller's: Close leaves it open, and
+// Reconnect isn't supported. Stores sharing a connection should use different table
+// prefixes (see WithTablePrefix) unless they are meant to share users.
+func NewRAGStoreWithConnection(conn *lancedb.Connection, embeddingDim int, opts ...Option) (*RAGStore, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("connection cannot be nil")
+	}
+	if embeddingDim <= 0 {
+		return nil, fmt.Errorf("embedding dimension must be positive, got %d", embeddingDim)
+	}
+
+	options, err := resolveOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	store := newRAGStore(conn, "", embeddingDim, options.maxBatchSize, options.logger, options.retryConfig, options.metrics)
+	store.borrowedConn = true
+	store.tablePrefix = options.tablePrefix
+	store.maxUserIDLength = options.maxUserIDLength
+	return store, nil
+}
+
+// defaultTablePrefix starts the table names of users' documents
+const defaultTablePrefix = "rag_user_"
+
+// Option configures a store created by NewRAGStore or NewRAGStoreWithConnection
+type Option func(*storeOptions)
+
+// storeOptions holds the settings applied by Options
+type storeOptions struct {
+	maxBatchSize int
+	logger       Logger
+	retryConfig  *RetryConfig
+	metrics         MetricsCollector
+	tablePrefix     string
+	maxUserIDLength int
+}
+
+// resolveOptions applies opts to the default settings and validates the result
+func resolveOptions(opts []Option) (storeOptions, error) {
+	options := storeOptions{
+		maxBatchSize: 1000,
+		logger:       &defaultLogger{},
+		retryConfig:  DefaultRetryConfig(),
+		tablePrefix:     defaultTablePrefix,
+		maxUserIDLength: DefaultMaxUserIDLength,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.maxBatchSize <= 0 {
+		return options, fmt.Errorf("max batch size must be positive, got %d", options.maxBatchSize)
+	}
+	if !userIDPattern.MatchString(options.tablePrefix) {
+		return options, fmt.Errorf("invalid table prefix %q (only alphanumeric, underscores, and hyphens allowed)", options.tablePrefix)
+	}
+	if options.maxUserIDLength <= 0 || len(options.tablePrefix)+options.maxUserIDLength > maxTableNameBytes {
+		return options, fmt.Errorf("max user ID length must be between 1 and %d with table prefix %q, got %d",
+			maxTableNameBytes-len(options.tablePrefix), options.tablePrefix, options.maxUserIDLength)
+	}
+	return options, nil
+}
+
+// WithMaxBatchSize sets how many documents are inserted in a single operation (default: 1000)
+func WithMaxBatchSize(maxBatchSize int) Option {
+	return func(o *storeOptions) { o.maxBatchSize = maxBatchSize }
+}
+
+// WithLogger sets the store's logger; nil disables logging
+func WithLogger(logger Logger) Option {
+	return func(o *storeOptions) { o.logger = logger }
+}
+
+// WithRetryConfig sets the retry behavior for transient failures; nil disables retries
+func WithRetryConfig(retryConfig *RetryConfig) Option {
+	return func(o *storeOptions) { o.retryConfig = retryConfig }
+}
+
+// WithMetrics sets the collector of operation metrics; nil disables metrics collection
+func WithMetrics(metrics MetricsCollector) Option {
+	return func(o *storeOptions) { o.metrics = metrics }
+}
+
+// WithTablePrefix sets the prefix of the store's user table names (default: "rag_user_"),
+// so applications sharing a database can keep their users apart. The prefix may only contain
+// alphanumerics, underscores and hyphens. Operations over all users (GlobalStats, VacuumAll,
+// ExportAllUsers, HealthCheckWithDetails) only see tables with the store's prefix, so one
+// application's prefix shouldn't start with another's.
+func WithTablePrefix(prefix string) Option {
+	return func(o *storeOptions) { o.tablePrefix = prefix }
+}
+
+// WithMaxUserIDLength sets the longest user ID the store accepts (default:
+// DefaultMaxUserIDLength). Together with the table prefix it may not exceed 249 characters,
+// so table directory names stay within filesystem limits.
+func WithMaxUserIDLength(maxLength int) Option {
+	return func(o *storeOptions) { o.maxUserIDLength = maxLength }
+}
+
+// newRAGStore creates a store on conn with the given settings, defaulting a nil
+// logger and metrics collector to no-op ones
+func newRAGStore(conn *lancedb.Connection, dbPath string, embeddingDim int, maxBatchSize int, logger Logger, retryConfig *RetryConfig, metrics MetricsCollector) *RAGStore {
 	if logger == nil {
 		logger = &noopLogger{}
 	}
-	
 	if metrics == nil {
 		metrics = &noopMetrics{}
 	}
@@ -109,28 +292,94 @@ func NewRAGStoreWithConfig(dbPath string, embeddingDim int, maxBatchSize int, lo
 	return &RAGStore{
 		conn:                conn,
 		dbPath:              dbPath,
+		tablePrefix:         defaultTablePrefix,
+		maxUserIDLength:     DefaultMaxUserIDLength,
 		embeddingDim:        embeddingDim,
 		maxBatchSize:        maxBatchSize,
 		maxDocumentsForBM25: 10000, // default limit for BM25 to prevent memory exhaustion
-		logger:              logger,
+		logger:              newSwappableLogger(logger),
 		retryConfig:         retryConfig,
-		metrics:             metrics,
+		metrics:             newSwappableMetrics(metrics),
 		indexConfigs:        make(map[string]*IndexConfig),
 		indexCreated:        make(map[string]bool),
-		userLocks:           make(map[string]*sync.Mutex),
-	}, nil
+		indexMetrics:        make(map[string]lancedb.DistanceMetric),
+		metricWarnings:      make(map[string]bool),
+		userDims:            make(map[string]int),
+		userStorages:        make(map[string]EmbeddingStorage),
+		userModels:          make(map[string]string),
+		userLocks:           make(map[string]*userLock),
+		tables:              newTableCache(defaultMaxOpenTables),
+		bm25Cache:           newBM25Cache(defaultBM25CacheSize),
+		tracer:              &noopTracer{},
+		minRowsForIndex:     DefaultMinRowsForIndex,
+	}
 }
 
-// Close closes the database connection and performs cleanup.
-// This is safe to call multiple times.
+// Close closes the database connection and performs cleanup. A connection passed to
+// NewRAGStoreWithConnection is left open. This is safe to call multiple times.
 func (s *RAGStore) Close() error {
-	if s.conn != nil {
-		s.conn.Close()
-		s.conn = nil
+	s.tables.closeAll()
+
+	s.mu.Lock()
+	conn := s.conn
+	s.conn = nil
+	s.mu.Unlock()
+
+	if conn != nil && !s.borrowedConn {
+		conn.Close()
+	}
+	return nil
+}
+
+// getConn returns the current database connection
+func (s *RAGStore) getConn() *lancedb.Connection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.conn
+}
+
+// Reconnect closes the database connection and opens a new one to the same path,
+// e.g. after the storage became temporarily unavailable. Cached table handles and
+// index state are dropped, as for a newly created store: tables are reopened as they
+// are needed, and each user's existing vector index is found again on their next write
+// instead of being rebuilt. Index configurations, embedding dimensions, the tracer and
+// other settings are kept.
+//
+// Operations running during Reconnect may fail and can be retried afterwards.
+func (s *RAGStore) Reconnect() error {
+	if s.dbPath == "" {
+		return fmt.Errorf("store has no database path to reconnect to")
+	}
+
+	conn, err := lancedb.Connect(s.dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to reconnect to database: %w", err)
+	}
+
+	if old := s.swapConnection(conn); old != nil {
+		old.Close()
 	}
+	s.logger.Printf("Reconnected to database at %s", s.dbPath)
 	return nil
 }
 
+// swapConnection installs conn as the store's connection and resets state cached
+// from the previous connection. It returns the previous connection, which the caller
+// must close or return to its pool.
+func (s *RAGStore) swapConnection(conn *lancedb.Connection) *lancedb.Connection {
+	s.mu.Lock()
+	old := s.conn
+	s.conn = conn
+	s.indexCreated = make(map[string]bool)
+	s.indexMetrics = make(map[string]lancedb.DistanceMetric)
+	s.metricWarnings = make(map[string]bool)
+	s.mu.Unlock()
+
+	// Drop handles opened through the old connection; new searches reopen them
+	s.tables.closeAll()
+	return old
+}
+
 // CloseWithContext closes the database connection with context support.
 // This allows for graceful shutdown with timeout/cancellation.
 func (s *RAGStore) CloseWithContext(ctx context.Context) error {
@@ -158,16 +407,35 @@ func (s *RAGStore) CloseWithContext(ctx context.Context) error {
 	}
 }
 
-// userIDPattern defines valid characters for user IDs (alphanumeric, underscores, hyphens)
+// userIDPattern defines valid characters for user IDs (ASCII alphanumeric, underscores, hyphens)
 var userIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 
-// validateUserID checks if a user ID is valid
-func validateUserID(userID string) error {
-	if userID == "" {
+// DefaultMaxUserIDLength is the longest user ID a store accepts unless WithMaxUserIDLength
+// changes it
+const DefaultMaxUserIDLength = 100
+
+// maxTableNameBytes bounds a table's name, prefix included, so that its directory name
+// (with the ".lance" suffix) fits common 255-byte filesystem limits
+const maxTableNameBytes = 255 - len(".lance")
+
+// ValidateUserID checks a user ID against the rules a store with default options applies:
+// 1 to DefaultMaxUserIDLength ASCII letters, digits, underscores and hyphens. IDs that are
+// blank or only dots, which could read as paths, are rejected explicitly. API servers can
+// call it to reject bad IDs before they reach a store.
+func ValidateUserID(userID string) error {
+	return validateUserIDLength(userID, DefaultMaxUserIDLength)
+}
+
+// validateUserIDLength checks a user ID, allowing IDs of up to maxLength characters
+func validateUserIDLength(userID string, maxLength int) error {
+	if strings.TrimSpace(userID) == "" {
 		return fmt.Errorf("user ID cannot be empty")
 	}
-	if len(userID) > 100 {
-		return fmt.Errorf("user ID too long (max 100 characters)")
+	if len(userID) > maxLength {
+		return fmt.Errorf("user ID too long (max %d characters)", maxLength)
+	}
+	if strings.Trim(userID, ".") == "" {
+		return fmt.Errorf("user ID %q is reserved", userID)
 	}
 	if !userIDPattern.MatchString(userID) {
 		return fmt.Errorf("user ID contains invalid characters (only alphanumeric, underscores, and hyphens allowed)")
@@ -175,62 +443,236 @@ func validateUserID(userID string) error {
 	return nil
 }
 
+// validateUserID checks if a user ID is valid for the store
+func (s *RAGStore) validateUserID(userID string) error {
+	maxLength := s.maxUserIDLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxUserIDLength
+	}
+	return validateUserIDLength(userID, maxLength)
+}
+
 // getTableName returns the table name for a given user ID
 func (s *RAGStore) getTableName(userID string) string {
-	return fmt.Sprintf("rag_user_%s", userID)
+	return s.tablePrefix + userID
+}
+
+// listUserIDs returns the IDs of all users with a table, in sorted order.
+// Tables that don't follow the user table naming scheme are ignored.
+func (s *RAGStore) listUserIDs(ctx context.Context) ([]string, error) {
+	// Check for context cancellation
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	tableNames, err := s.getConn().TableNames()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	userIDs := make([]string, 0, len(tableNames))
+	for _, name := range tableNames {
+		userID, ok := strings.CutPrefix(name, s.tablePrefix)
+		if !ok || s.validateUserID(userID) != nil || userID == healthCheckUserID {
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+// documentSchema returns the Arrow schema used for user tables with the given embedding dimension
+func documentSchema(embeddingDim int) *arrow.Schema {
+	return documentSchemaFor(embeddingDim, EmbeddingStorageFloat32)
+}
+
+// documentSchemaFor returns the Arrow schema used for user tables storing embeddings of the
+// given dimension as storage. Int8 tables have an extra column with each embedding's scale.
+func documentSchemaFor(embeddingDim int, storage EmbeddingStorage) *arrow.Schema {
+	fields := []arrow.Field{
+		{Name: "id", Type: arrow.BinaryTypes.String, Nullable: false},
+		{Name: "text", Type: arrow.BinaryTypes.String, Nullable: false},
+		{Name: "document_name", Type: arrow.BinaryTypes.String, Nullable: false},
+		{Name: "embedding", Type: arrow.FixedSizeListOf(int32(embeddingDim), storage.embeddingType()), Nullable: false},
+		{Name: "metadata", Type: arrow.BinaryTypes.String, Nullable: true},
+	}
+	if storage == EmbeddingStorageInt8 {
+		fields = append(fields, arrow.Field{Name: embeddingScaleColumn, Type: arrow.PrimitiveTypes.Float32, Nullable: false})
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// embeddingDimFromSchema extracts the embedding dimension from a user table schema
+func embeddingDimFromSchema(schema *arrow.Schema) (int, bool) {
+	indices := schema.FieldIndices("embedding")
+	if len(indices) == 0 {
+		return 0, false
+	}
+	listType, ok := schema.Field(indices[0]).Type.(*arrow.FixedSizeListType)
+	if !ok {
+		return 0, false
+	}
+	return int(listType.Len()), true
+}
+
+// lookupUserEmbeddingDim returns the recorded embedding dimension for a user.
+// If no dimension is recorded yet, it is detected from the user's existing table schema.
+// The second return value is false if the user has no recorded dimension and no table.
+func (s *RAGStore) lookupUserEmbeddingDim(userID string) (int, bool) {
+	s.mu.RLock()
+	dim, exists := s.userDims[userID]
+	s.mu.RUnlock()
+	if exists {
+		return dim, true
+	}
+
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
+	if err != nil {
+		return 0, false
+	}
+	defer table.Close()
+
+	schema, err := table.Schema()
+	if err != nil {
+		return 0, false
+	}
+	dim, ok := embeddingDimFromSchema(schema)
+	if !ok {
+		return 0, false
+	}
+
+	s.mu.Lock()
+	s.userDims[userID] = dim
+	s.userStorages[userID] = embeddingStorageFromSchema(schema)
+	s.mu.Unlock()
+	return dim, true
+}
+
+// userEmbeddingDim returns the embedding dimension for a user,
+// falling back to the store's default dimension for users without a table.
+func (s *RAGStore) userEmbeddingDim(userID string) int {
+	if dim, ok := s.lookupUserEmbeddingDim(userID); ok {
+		return dim
+	}
+	return s.embeddingDim
+}
+
+// registerUserEmbeddingDim records the embedding dimension for a user.
+// Returns an error if the user already has a table, or a pending registration, with a
+// different dimension. The check and the record happen under one lock, so concurrent
+// callers can't register different dimensions. The returned function undoes a new
+// registration, for when the user's first insert fails; it does nothing otherwise.
+func (s *RAGStore) registerUserEmbeddingDim(userID string, dim int) (func(), error) {
+	if dim <= 0 {
+		return nil, fmt.Errorf("embedding dimension must be positive, got %d", dim)
+	}
+
+	// Detect the dimension of an existing table before taking the lock
+	s.lookupUserEmbeddingDim(userID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.userDims[userID]; ok {
+		if existing != dim {
+			return nil, fmt.Errorf("embedding dimension mismatch for user %s: table uses %d, got %d", userID, existing, dim)
+		}
+		return func() {}, nil
+	}
+	s.userDims[userID] = dim
+
+	return func() {
+		// A table created before the failure still records the dimension in its schema
+		s.mu.Lock()
+		if s.userDims[userID] == dim {
+			delete(s.userDims, userID)
+		}
+		s.mu.Unlock()
+	}, nil
 }
 
-// getOrCreateTable returns the table for a user, creating it if it doesn't exist
+// getOrCreateTable returns the table for a user, creating it if it doesn't exist.
+// New tables are created with the user's embedding dimension, which is then recorded.
 func (s *RAGStore) getOrCreateTable(userID string) (*lancedb.Table, error) {
-	if err := validateUserID(userID); err != nil {
+	if err := s.validateUserID(userID); err != nil {
 		return nil, err
 	}
 	
 	tableName := s.getTableName(userID)
 
-	// Try to open existing table first
-	table, err := s.conn.OpenTable(tableName)
-	if err == nil {
-		return table, nil
-	}
-
-	// Table doesn't exist, create it with schema
-	schema := arrow.NewSchema(
-		[]arrow.Field{
-			{Name: "id", Type: arrow.BinaryTypes.String, Nullable: false},
-			{Name: "text", Type: arrow.BinaryTypes.String, Nullable: false},
-			{Name: "document_name", Type: arrow.BinaryTypes.String, Nullable: false},
-			{Name: "embedding", Type: arrow.FixedSizeListOf(int32(s.embeddingDim), arrow.PrimitiveTypes.Float32), Nullable: false},
-			{Name: "metadata", Type: arrow.BinaryTypes.String, Nullable: true},
-		},
-		nil,
-	)
-
-	table, err = s.conn.CreateTableWithSchema(tableName, schema)
+	// Creating and opening in one call avoids racing another writer between the two
+	dim := s.userEmbeddingDim(userID)
+	storage := s.userEmbeddingStorage(userID)
+	table, err := s.getConn().CreateTableIfNotExists(tableName, documentSchemaFor(dim, storage))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create table %s: %w", tableName, err)
+		return nil, fmt.Errorf("failed to open or create table %s: %w", tableName, err)
 	}
 
+	s.mu.Lock()
+	s.userDims[userID] = dim
+	s.mu.Unlock()
+
 	return table, nil
 }
 
 // ensureIndex creates a vector index on the embedding column if not already created.
 // This uses double-checked locking for thread-safety and logs the operation.
-func (s *RAGStore) ensureIndex(table *lancedb.Table, userID string) error {
+func (s *RAGStore) ensureIndex(ctx context.Context, table *lancedb.Table, userID string) error {
+	_, err := s.buildIndex(ctx, table, userID, false)
+	return err
+}
+
+// buildIndex creates the user's vector index unless it has already been created or can't be
+// built. It returns why no index was built, or "" if one was built or already existed.
+// An index found on the table (e.g. built by an earlier store or before a reconnect) is
+// reused, unless rebuild is set.
+func (s *RAGStore) buildIndex(ctx context.Context, table *lancedb.Table, userID string, rebuild bool) (skipped string, err error) {
 	s.mu.RLock()
 	if s.indexCreated[userID] {
 		s.mu.RUnlock()
-		return nil
+		return "", nil
 	}
 	config := s.indexConfigs[userID]
+	minRows := s.minRowsForIndex
 	s.mu.RUnlock()
 
+	// LanceDB can't index int8 vectors; quantized searches scan the table instead.
+	// Mark the user as done so later writes skip straight past this check.
+	if s.userEmbeddingStorage(userID) == EmbeddingStorageInt8 {
+		s.mu.Lock()
+		s.indexCreated[userID] = true
+		s.mu.Unlock()
+		return "int8 embeddings can't be indexed", nil
+	}
+
+	if !rebuild && hasVectorIndex(table) {
+		s.mu.Lock()
+		s.indexCreated[userID] = true
+		s.mu.Unlock()
+		return "", nil
+	}
+
+	// IVF_PQ needs enough rows to train; wait until the table has them.
+	// Counting reads the table, so it happens without holding the store lock.
+	if minRows > 0 {
+		rows, err := table.CountRows()
+		if err != nil {
+			return "", fmt.Errorf("failed to count rows: %w", err)
+		}
+		if rows < int64(minRows) {
+			s.logger.Printf("Deferring vector index for user %s: %d rows, need %d", userID, rows, minRows)
+			return fmt.Sprintf("the table has %d rows, the index needs %d", rows, minRows), nil
+		}
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Double-check after acquiring write lock
 	if s.indexCreated[userID] {
-		return nil
+		return "", nil
 	}
 
 	// Use default config if none specified
@@ -238,6 +680,12 @@ func (s *RAGStore) ensureIndex(table *lancedb.Table, userID string) error {
 		config = DefaultIndexConfig()
 	}
 
+	// Trace the build itself, not the already-indexed fast path. The store lock is held,
+	// so the tracer is read directly rather than through startSpan.
+	_, span := s.startSpanWith(s.tracerLocked(), ctx, "rag.CreateIndex", userID)
+	span.SetAttribute("rag.index_type", fmt.Sprintf("%v", config.IndexType))
+	defer func() { endSpan(span, err) }()
+
 	s.logger.Printf("Creating vector index for user %s with config: %+v", userID, config)
 
 	// Create index with user's configuration
@@ -249,21 +697,88 @@ func (s *RAGStore) ensureIndex(table *lancedb.Table, userID string) error {
 		NumSubVectors: config.NumSubVectors,
 	}
 
-	if err := table.CreateIndex("embedding", indexOpts); err != nil {
+	if err = table.CreateIndex("embedding", indexOpts); err != nil {
 		s.logger.Printf("Failed to create index for user %s: %v", userID, err)
-		return fmt.Errorf("failed to create index: %w", err)
+		return "", fmt.Errorf("failed to create index: %w", err)
 	}
 
 	s.indexCreated[userID] = true
+	s.indexMetrics[userID] = config.Metric
+	delete(s.metricWarnings, userID)
 	s.logger.Printf("Successfully created vector index for user %s", userID)
-	return nil
+	return "", nil
+}
+
+// IndexStats describes a user's vector index
+type IndexStats struct {
+	IndexExists bool                   // Whether the table has a vector index on the embedding column
+	IndexType   lancedb.IndexType      // Type of the index
+	Metric      lancedb.DistanceMetric // Metric the index was built with; searches should use the matching DistanceType
+	NumRows     int64                  // Number of rows in the user's table
+}
+
+// IndexStats returns information about the user's vector index, including the metric it was
+// built with. For indexes built by another store on the same database, the type and metric
+// are taken from the user's index configuration (see SetIndexConfig).
+func (s *RAGStore) IndexStats(ctx context.Context, userID string) (*IndexStats, error) {
+	if err := s.validateUserID(userID); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	config := s.indexConfigs[userID]
+	s.mu.RUnlock()
+
+	if config == nil {
+		config = DefaultIndexConfig()
+	}
+	stats := &IndexStats{
+		IndexType: config.IndexType,
+		Metric:    s.userIndexMetric(userID),
+	}
+
+	exists, err := s.TableExists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return stats, nil
+	}
+
+	table, release, err := s.acquireTable(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	defer release()
+
+	stats.NumRows, err = table.CountRows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+	stats.IndexExists = hasVectorIndex(table)
+
+	return stats, nil
+}
+
+// hasVectorIndex reports whether the table has an index on the embedding column
+func hasVectorIndex(table *lancedb.Table) bool {
+	indices, err := table.ListIndices()
+	if err != nil {
+		return false
+	}
+	for _, idx := range indices {
+		if len(idx.Columns) == 1 && idx.Columns[0] == "embedding" {
+			return true
+		}
+	}
+	return false
 }
 
 // SetIndexConfig sets the index configuration for a specific user.
 // This must be called before adding documents; it won't affect existing indexes.
 // To rebuild with new config, clear the user's data first.
 func (s *RAGStore) SetIndexConfig(userID string, config *IndexConfig) error {
-	if err := validateUserID(userID); err != nil {
+	if err := s.validateUserID(userID); err != nil {
 		return err
 	}
 	if config == nil {
@@ -285,7 +800,7 @@ func (s *RAGStore) SetIndexConfig(userID string, config *IndexConfig) error {
 
 // GetIndexConfig returns the current index configuration for a user
 func (s *RAGStore) GetIndexConfig(userID string) (*IndexConfig, error) {
-	if err := validateUserID(userID); err != nil {
+	if err := s.validateUserID(userID); err != nil {
 		return nil, err
 	}
 
@@ -307,11 +822,14 @@ func (s *RAGStore) RebuildIndex(ctx context.Context, userID string, config *Inde
 
 // RebuildIndexWithProgress rebuilds the index with progress reporting.
 // The callback receives progress updates during the rebuild operation.
-func (s *RAGStore) RebuildIndexWithProgress(ctx context.Context, userID string, config *IndexConfig, callback ProgressCallback) error {
-	if err := validateUserID(userID); err != nil {
+func (s *RAGStore) RebuildIndexWithProgress(ctx context.Context, userID string, config *IndexConfig, callback ProgressCallback) (err error) {
+	if err := s.validateUserID(userID); err != nil {
 		return err
 	}
 
+	ctx, span := s.startSpan(ctx, "rag.RebuildIndex", userID)
+	defer func() { endSpan(span, err) }()
+
 	// Check for context cancellation
 	select {
 	case <-ctx.Done():
@@ -327,11 +845,11 @@ func (s *RAGStore) RebuildIndexWithProgress(ctx context.Context, userID string,
 	}
 
 	// Acquire user lock
-	lock := s.getUserLock(userID)
-	lock.Lock()
-	defer lock.Unlock()
+	unlock := s.lockUser(userID)
+	defer unlock()
+	defer s.invalidateTable(userID)
 
-	table, err := s.conn.OpenTable(s.getTableName(userID))
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
 	if err != nil {
 		return fmt.Errorf("failed to open table: %w", err)
 	}
@@ -357,9 +875,13 @@ func (s *RAGStore) RebuildIndexWithProgress(ctx context.Context, userID string,
 	}
 
 	// Create new index
-	if err := s.ensureIndex(table, userID); err != nil {
+	skipped, err := s.buildIndex(ctx, table, userID, true)
+	if err != nil {
 		return fmt.Errorf("failed to rebuild index: %w", err)
 	}
+	if skipped != "" {
+		return fmt.Errorf("index not rebuilt for user %s: %s", userID, skipped)
+	}
 
 	if tracker != nil {
 		tracker.Complete()
@@ -372,7 +894,7 @@ func (s *RAGStore) RebuildIndexWithProgress(ctx context.Context, userID string,
 
 // TableExists checks if a table exists for the given user
 func (s *RAGStore) TableExists(ctx context.Context, userID string) (bool, error) {
-	if err := validateUserID(userID); err != nil {
+	if err := s.validateUserID(userID); err != nil {
 		return false, err
 	}
 	
@@ -383,7 +905,7 @@ func (s *RAGStore) TableExists(ctx context.Context, userID string) (bool, error)
 	default:
 	}
 	
-	tableNames, err := s.conn.TableNames()
+	tableNames, err := s.getConn().TableNames()
 	if err != nil {
 		return false, fmt.Errorf("failed to list tables: %w", err)
 	}
@@ -397,14 +919,33 @@ func (s *RAGStore) TableExists(ctx context.Context, userID string) (bool, error)
 	return false, nil
 }
 
-// GetEmbeddingDim returns the configured embedding dimension
+// GetEmbeddingDim returns the default embedding dimension.
+// Users whose tables were created with a different dimension keep their own; see GetUserEmbeddingDim.
 func (s *RAGStore) GetEmbeddingDim() int {
 	return s.embeddingDim
 }
 
-// SetMaxDocumentsForBM25 sets the maximum number of documents for BM25 keyword search.
-// This limit prevents memory exhaustion when loading all documents for BM25 scoring.
-// Default is 10,000. Set to 0 to disable the limit (not recommended for production).
+// GetUserEmbeddingDim returns the embedding dimension used by a user's table.
+// For users without a table, the store's default dimension is returned.
+func (s *RAGStore) GetUserEmbeddingDim(ctx context.Context, userID string) (int, error) {
+	if err := s.validateUserID(userID); err != nil {
+		return 0, err
+	}
+
+	// Check for context cancellation
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	return s.userEmbeddingDim(userID), nil
+}
+
+// SetMaxDocumentsForBM25 sets the maximum number of documents BM25 keyword search loads
+// into memory. Larger collections are scored by streaming the documents instead, which
+// bounds memory use but reads the table twice per search.
+// Default is 10,000. Set to 0 to always load every document (not recommended for production).
 func (s *RAGStore) SetMaxDocumentsForBM25(max int) {
 	s.maxDocumentsForBM25 = max
 }
@@ -414,28 +955,71 @@ func (s *RAGStore) GetMaxDocumentsForBM25() int {
 	return s.maxDocumentsForBM25
 }
 
-// getUserLock returns the lock for a specific user, creating it if needed.
-// This ensures concurrent writes to the same user's table are serialized.
-func (s *RAGStore) getUserLock(userID string) *sync.Mutex {
-	s.locksMu.RLock()
-	lock, exists := s.userLocks[userID]
-	s.locksMu.RUnlock()
-	
-	if exists {
-		return lock
+// SetMinRowsForIndex sets how many rows a user's table needs before the vector index is built.
+// Until then inserts skip indexing and searches scan the table, which is fast at that size;
+// the index is built by the first insert that reaches the threshold.
+// Default is 256, the minimum IVF_PQ needs to train. Set to 0 to index after the first insert.
+func (s *RAGStore) SetMinRowsForIndex(rows int) {
+	if rows < 0 {
+		rows = 0
 	}
-	
+	s.mu.Lock()
+	s.minRowsForIndex = rows
+	s.mu.Unlock()
+}
+
+// GetMinRowsForIndex returns the number of rows required before the vector index is built
+func (s *RAGStore) GetMinRowsForIndex() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.minRowsForIndex
+}
+
+// SetLogger replaces the store's logger, e.g. to change verbosity at runtime. It is safe to
+// call while operations are running; they log to the new logger from their next message.
+// Pass nil to disable logging.
+func (s *RAGStore) SetLogger(logger Logger) {
+	s.logger.set(logger)
+}
+
+// SetMetrics replaces the store's metrics collector. It is safe to call while operations are
+// running. Pass nil to disable metrics collection.
+func (s *RAGStore) SetMetrics(metrics MetricsCollector) {
+	s.metrics.set(metrics)
+}
+
+// userLock serializes writes to one user's table. refs counts the goroutines holding or
+// waiting for it, so its entry can be removed once none are left.
+type userLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lockUser acquires the write lock for a specific user, creating it if needed, and
+// returns the function that releases it. This ensures concurrent writes to the same
+// user's table are serialized. The lock's entry is removed when the last goroutine
+// holding or waiting for it releases it, so dropped and idle users don't keep locks.
+func (s *RAGStore) lockUser(userID string) func() {
 	s.locksMu.Lock()
-	defer s.locksMu.Unlock()
-	
-	// Double-check after acquiring write lock
-	if lock, exists := s.userLocks[userID]; exists {
-		return lock
+	lock, exists := s.userLocks[userID]
+	if !exists {
+		lock = &userLock{}
+		s.userLocks[userID] = lock
+	}
+	lock.refs++
+	s.locksMu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		s.locksMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(s.userLocks, userID)
+		}
+		s.locksMu.Unlock()
 	}
-	
-	lock = &sync.Mutex{}
-	s.userLocks[userID] = lock
-	return lock
 }
 
 // HealthStatus contains detailed health check information
@@ -458,7 +1042,7 @@ func (s *RAGStore) HealthCheck(ctx context.Context) error {
 	}
 
 	// Try to list tables (lightweight operation)
-	_, err := s.conn.TableNames()
+	_, err := s.getConn().TableNames()
 	if err != nil {
 		return fmt.Errorf("health check failed: unable to list tables: %w", err)
 	}
@@ -485,7 +1069,7 @@ func (s *RAGStore) HealthCheckWithDetails(ctx context.Context) *HealthStatus {
 	}
 
 	// List all tables
-	tableNames, err := s.conn.TableNames()
+	tableNames, err := s.getConn().TableNames()
 	if err != nil {
 		status.Healthy = false
 		status.Error = fmt.Sprintf("failed to list tables: %v", err)
@@ -495,7 +1079,7 @@ func (s *RAGStore) HealthCheckWithDetails(ctx context.Context) *HealthStatus {
 	status.TablesCount = len(tableNames)
 
 	// Get document counts for user tables (sample up to 10 users)
-	userTablePrefix := "rag_user_"
+	userTablePrefix := s.tablePrefix
 	sampleCount := 0
 	for _, tableName := range tableNames {
 		if len(tableName) > len(userTablePrefix) && tableName[:len(userTablePrefix)] == userTablePrefix {
@@ -503,7 +1087,7 @@ func (s *RAGStore) HealthCheckWithDetails(ctx context.Context) *HealthStatus {
 				break // Limit sampling to avoid expensive operations
 			}
 			
-			table, err := s.conn.OpenTable(tableName)
+			table, err := s.getConn().OpenTable(tableName)
 			if err != nil {
 				continue // Skip tables that can't be opened
 			}
@@ -522,6 +1106,91 @@ func (s *RAGStore) HealthCheckWithDetails(ctx context.Context) *HealthStatus {
 	return status
 }
 
+// healthCheckUserID is the reserved user whose table DeepHealthCheck writes probe documents to
+const healthCheckUserID = "__healthcheck__"
+
+// DeepHealthCheck verifies the full write, search and delete path. On the reserved
+// "__healthcheck__" user, it writes a probe document, searches for it and deletes it,
+// returning an error if any step fails or the search doesn't find the probe.
+// The probe is removed even if a later step fails. Concurrent calls are serialized.
+func (s *RAGStore) DeepHealthCheck(ctx context.Context) error {
+	// Check for context cancellation
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	// Hold the health check user's lock for the whole round trip
+	unlock := s.lockUser(healthCheckUserID)
+	defer unlock()
+	defer s.invalidateTable(healthCheckUserID)
+
+	table, err := s.getOrCreateTable(healthCheckUserID)
+	if err != nil {
+		return fmt.Errorf("deep health check failed: unable to open table: %w", err)
+	}
+	defer table.Close()
+
+	dim := s.userEmbeddingDim(healthCheckUserID)
+	probe := Document{
+		ID:           fmt.Sprintf("probe-%d", time.Now().UnixNano()),
+		Text:         "health check probe",
+		DocumentName: "__healthcheck__",
+		Embedding:    make([]float32, dim),
+	}
+	for i := range probe.Embedding {
+		probe.Embedding[i] = 1
+	}
+	predicate := fmt.Sprintf("id = '%s'", escapeSQLString(probe.ID))
+
+	// Write the probe directly, without building a vector index for the single row
+	if err := s.addDocumentsBatch(table, []Document{probe}, dim, s.userEmbeddingStorage(healthCheckUserID)); err != nil {
+		return fmt.Errorf("deep health check failed: write: %w", err)
+	}
+
+	deleted := false
+	defer func() {
+		if deleted {
+			return
+		}
+		if cleanupErr := table.Delete(predicate); cleanupErr != nil {
+			s.logger.Printf("Deep health check: failed to remove probe %s: %v", probe.ID, cleanupErr)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	results, err := s.searchTable(ctx, table, probe.Embedding, &SearchOptions{
+		Limit:        1,
+		Filters:      map[string]interface{}{"id": probe.ID},
+		DistanceType: lancedb.DistanceTypeCosine,
+	}, dim, s.userEmbeddingStorage(healthCheckUserID), lancedb.DistanceTypeCosine)
+	if err != nil {
+		return fmt.Errorf("deep health check failed: search: %w", err)
+	}
+	if len(results) == 0 || results[0].ID != probe.ID {
+		return fmt.Errorf("deep health check failed: search did not return the probe document")
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	if err := table.Delete(predicate); err != nil {
+		return fmt.Errorf("deep health check failed: delete: %w", err)
+	}
+	deleted = true
+
+	return nil
+}
+
 // ValidationResult contains the results of database validation
 type ValidationResult struct {
 	Valid            bool     // Overall validation status
@@ -544,7 +1213,7 @@ func (s *RAGStore) ValidateDatabase(ctx context.Context, userID string) (*Valida
 	}
 
 	// Validate user ID
-	if err := validateUserID(userID); err != nil {
+	if err := s.validateUserID(userID); err != nil {
 		result.Valid = false
 		result.Issues = append(result.Issues, fmt.Sprintf("Invalid user ID: %v", err))
 		return result, err
@@ -574,7 +1243,7 @@ func (s *RAGStore) ValidateDatabase(ctx context.Context, userID string) (*Valida
 	}
 
 	// Open table
-	table, err := s.conn.OpenTable(s.getTableName(userID))
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
 	if err != nil {
 		result.Valid = false
 		result.Issues = append(result.Issues, fmt.Sprintf("Failed to open table: %v", err))
@@ -592,12 +1261,15 @@ func (s *RAGStore) ValidateDatabase(ctx context.Context, userID string) (*Valida
 	result.DocumentCount = count
 
 	// Check index status
+	result.IndexExists = hasVectorIndex(table)
 	s.mu.RLock()
-	result.IndexExists = s.indexCreated[userID]
+	minRows := s.minRowsForIndex
 	s.mu.RUnlock()
 
-	// If we have documents but no index, that's a concern
-	if count > 0 && !result.IndexExists {
+	// If we have enough documents for an index but no index, that's a concern.
+	// Tables of int8 embeddings are never indexed.
+	indexable := s.userEmbeddingStorage(userID) != EmbeddingStorageInt8
+	if count > 0 && count >= int64(minRows) && !result.IndexExists && indexable {
 		result.Issues = append(result.Issues, "Documents exist but index not created")
 		// This is a warning, not a fatal error
 	}
@@ -614,6 +1286,7 @@ func (s *RAGStore) ValidateDatabase(ctx context.Context, userID string) (*Valida
 			return result, err
 		}
 
+		expectedDim := s.userEmbeddingDim(userID)
 		result.EmbeddingDimOK = true
 		for _, record := range records {
 			embeddingCol := record.Column(1).(*array.FixedSizeList)
@@ -621,13 +1294,13 @@ func (s *RAGStore) ValidateDatabase(ctx context.Context, userID string) (*Valida
 			
 			if actualDim > 0 {
 				// Check if the embedding dimension matches
-				embeddingValues := embeddingCol.ListValues().(*array.Float32)
-				expectedValues := actualDim * s.embeddingDim
+				embeddingValues := embeddingCol.ListValues()
+				expectedValues := actualDim * expectedDim
 				if embeddingValues.Len() != expectedValues {
 					result.Valid = false
 					result.EmbeddingDimOK = false
 					result.Issues = append(result.Issues, 
-						fmt.Sprintf("Embedding dimension mismatch: expected %d, found inconsistent dimensions", s.embeddingDim))
+						fmt.Sprintf("Embedding dimension mismatch: expected %d, found inconsistent dimensions", expectedDim))
 				}
 			}
 			
@@ -660,15 +1333,16 @@ func (s *RAGStore) RepairDatabase(ctx context.Context, userID string) error {
 	if validation.TableExists && validation.DocumentCount > 0 && !validation.IndexExists {
 		s.logger.Printf("Recreating missing index for user %s", userID)
 		
-		table, err := s.conn.OpenTable(s.getTableName(userID))
+		table, err := s.getConn().OpenTable(s.getTableName(userID))
 		if err != nil {
 			return fmt.Errorf("failed to open table for repair: %w", err)
 		}
 		defer table.Close()
 
-		if err := s.ensureIndex(table, userID); err != nil {
+		if err := s.ensureIndex(ctx, table, userID); err != nil {
 			return fmt.Errorf("failed to recreate index: %w", err)
 		}
+		s.invalidateTable(userID)
 
 		s.logger.Printf("Successfully recreated index for user %s", userID)
 	}
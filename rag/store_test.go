@@ -2,10 +2,15 @@ package rag
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aqua777/go-lancedb"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -46,3 +51,498 @@ func TestStoreTestSuite(t *testing.T) {
 	suite.Run(t, new(StoreTestSuite))
 }
 
+// makeTestDocs creates n documents with embeddings of the given dimension
+func makeTestDocs(n, dim int, docName string) []Document {
+	docs := make([]Document, n)
+	for i := 0; i < n; i++ {
+		embedding := make([]float32, dim)
+		for j := range embedding {
+			embedding[j] = float32((i+j)%17) / 17.0
+		}
+		docs[i] = Document{
+			ID:           fmt.Sprintf("%s_%d", docName, i),
+			Text:         fmt.Sprintf("document %d of %s", i, docName),
+			DocumentName: docName,
+			Embedding:    embedding,
+			Metadata:     map[string]interface{}{"index": i},
+		}
+	}
+	return docs
+}
+
+func (s *StoreTestSuite) TestPerUserEmbeddingDim() {
+	// User A uses the store default (128), user B uses 256 (need 256+ rows for the index)
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user_a", makeTestDocs(300, 128, "a.txt")))
+	s.Require().NoError(s.store.AddDocumentsForDim(s.ctx, "user_b", makeTestDocs(300, 256, "b.txt"), 256))
+
+	dimA, err := s.store.GetUserEmbeddingDim(s.ctx, "user_a")
+	s.Require().NoError(err)
+	s.Equal(128, dimA)
+
+	dimB, err := s.store.GetUserEmbeddingDim(s.ctx, "user_b")
+	s.Require().NoError(err)
+	s.Equal(256, dimB)
+
+	// Each user searches with their own dimension
+	resultsA, err := s.store.Search(s.ctx, "user_a", makeTestDocs(1, 128, "q")[0].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	s.Len(resultsA, 5)
+	for _, r := range resultsA {
+		s.Equal("a.txt", r.DocumentName)
+		s.Len(r.Embedding, 128)
+	}
+
+	resultsB, err := s.store.Search(s.ctx, "user_b", makeTestDocs(1, 256, "q")[0].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	s.Len(resultsB, 5)
+	for _, r := range resultsB {
+		s.Equal("b.txt", r.DocumentName)
+		s.Len(r.Embedding, 256)
+	}
+
+	// Inserts and searches are validated against the user's dimension, not the global one
+	err = s.store.AddDocuments(s.ctx, "user_b", makeTestDocs(1, 128, "wrong.txt"))
+	s.Error(err)
+	s.Contains(err.Error(), "dimension")
+
+	_, err = s.store.Search(s.ctx, "user_b", make([]float32, 128), nil)
+	s.Error(err)
+
+	s.NoError(s.store.AddDocuments(s.ctx, "user_b", makeTestDocs(1, 256, "more.txt")))
+
+	// Re-registering a user with a conflicting dimension fails
+	err = s.store.AddDocumentsForDim(s.ctx, "user_a", makeTestDocs(1, 256, "c.txt"), 256)
+	s.Error(err)
+}
+
+func (s *StoreTestSuite) TestFailedFirstInsertForgetsDim() {
+	// The documents don't match the requested dimension, so nothing is written
+	err := s.store.AddDocumentsForDim(s.ctx, "user_c", makeTestDocs(5, 64, "c.txt"), 256)
+	s.Require().Error(err)
+
+	exists, err := s.store.TableExists(s.ctx, "user_c")
+	s.Require().NoError(err)
+	s.False(exists)
+
+	// The failed registration doesn't pin the user to 256
+	s.Require().NoError(s.store.AddDocumentsForDim(s.ctx, "user_c", makeTestDocs(5, 64, "c.txt"), 64))
+	dim, err := s.store.GetUserEmbeddingDim(s.ctx, "user_c")
+	s.Require().NoError(err)
+	s.Equal(64, dim)
+}
+
+func (s *StoreTestSuite) TestPerUserEmbeddingDimDetectedOnReopen() {
+	s.Require().NoError(s.store.AddDocumentsForDim(s.ctx, "user_b", makeTestDocs(300, 256, "b.txt"), 256))
+	s.Require().NoError(s.store.Close())
+
+	// A fresh store has no recorded dimensions and must detect them from the table schema
+	store, err := NewRAGStoreWithConfig(s.dbPath, 128, 100, &noopLogger{}, DefaultRetryConfig(), nil)
+	s.Require().NoError(err)
+	s.store = store
+
+	dim, err := s.store.GetUserEmbeddingDim(s.ctx, "user_b")
+	s.Require().NoError(err)
+	s.Equal(256, dim)
+
+	results, err := s.store.Search(s.ctx, "user_b", make([]float32, 256), &SearchOptions{Limit: 3})
+	s.Require().NoError(err)
+	s.Len(results, 3)
+}
+
+func (s *StoreTestSuite) TestDeepHealthCheck() {
+	s.Require().NoError(s.store.DeepHealthCheck(s.ctx))
+
+	// The probe is removed and the reserved user isn't listed as a real user
+	count, err := s.store.CountDocuments(s.ctx, healthCheckUserID)
+	s.Require().NoError(err)
+	s.Equal(int64(0), count)
+
+	userIDs, err := s.store.listUserIDs(s.ctx)
+	s.Require().NoError(err)
+	s.NotContains(userIDs, healthCheckUserID)
+}
+
+func (s *StoreTestSuite) TestDeepHealthCheckConcurrent() {
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.store.DeepHealthCheck(s.ctx)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		s.NoError(err)
+	}
+}
+
+func (s *StoreTestSuite) TestIndexDeferredUntilMinRows() {
+	s.Equal(DefaultMinRowsForIndex, s.store.GetMinRowsForIndex())
+
+	userID := "small_user"
+	docs := makeTestDocs(300, 128, "small.txt")
+
+	// 100 rows are too few to train IVF_PQ, so no index is built yet
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs[:100]))
+	s.False(s.hasVectorIndex(userID))
+
+	results, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	s.Len(results, 5)
+
+	// Reaching the threshold builds the index
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs[100:]))
+	s.True(s.hasVectorIndex(userID))
+}
+
+func (s *StoreTestSuite) TestReconnect() {
+	userID := "reconnect_user"
+	docs := makeTestDocs(300, 128, "r.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	_, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+
+	// Simulate the connection becoming unusable underneath the store
+	s.store.getConn().Close()
+
+	_, err = s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5})
+	s.Error(err)
+	_, err = s.store.CountDocuments(s.ctx, userID)
+	s.True(errors.Is(err, lancedb.ErrClosed), "expected ErrClosed, got %v", err)
+
+	s.Require().NoError(s.store.Reconnect())
+
+	count, err := s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(300), count)
+
+	results, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	s.Len(results, 5)
+
+	// Writes work again and the existing index is picked up from the table, not rebuilt
+	recorder := &spanRecorder{}
+	s.store.SetTracer(recorder)
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, makeTestDocs(10, 128, "more.txt")))
+	s.True(s.hasVectorIndex(userID))
+	s.Empty(recorder.find("rag.CreateIndex"))
+	s.store.mu.RLock()
+	s.True(s.store.indexCreated[userID])
+	s.store.mu.RUnlock()
+}
+
+func (s *StoreTestSuite) TestReconnectAfterClose() {
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user_a", makeTestDocs(5, 128, "a.txt")))
+	s.Require().NoError(s.store.Close())
+
+	s.Require().NoError(s.store.Reconnect())
+
+	count, err := s.store.CountDocuments(s.ctx, "user_a")
+	s.Require().NoError(err)
+	s.Equal(int64(5), count)
+}
+
+// hasVectorIndex reports whether the user's table has an index on the embedding column
+func (s *StoreTestSuite) hasVectorIndex(userID string) bool {
+	table, err := s.store.getConn().OpenTable(s.store.getTableName(userID))
+	s.Require().NoError(err)
+	defer table.Close()
+
+	return hasVectorIndex(table)
+}
+
+func (s *StoreTestSuite) TestNewRAGStoreWithConnectionInMemory() {
+	dir := s.T().TempDir()
+	s.T().Chdir(dir)
+
+	conn, err := lancedb.ConnectMemory()
+	s.Require().NoError(err)
+	defer conn.Close()
+
+	store, err := NewRAGStoreWithConnection(conn, 64)
+	s.Require().NoError(err)
+
+	docs := makeTestDocs(12, 64, "memory.txt")
+	s.Require().NoError(store.AddDocuments(s.ctx, "memory_user", docs))
+	results, err := store.Search(s.ctx, "memory_user", docs[5].Embedding, &SearchOptions{Limit: 3})
+	s.Require().NoError(err)
+	s.Require().NotEmpty(results)
+	s.Equal(docs[5].ID, results[0].ID)
+
+	// Closing the store leaves the caller's connection usable
+	s.Require().NoError(store.Close())
+	s.True(conn.IsOpen())
+	names, err := conn.TableNames()
+	s.Require().NoError(err)
+	s.Contains(names, "rag_user_memory_user")
+	s.Error(store.Reconnect())
+
+	entries, err := os.ReadDir(dir)
+	s.Require().NoError(err)
+	s.Empty(entries, "no files should be written")
+
+	_, err = NewRAGStoreWithConnection(nil, 64)
+	s.Error(err)
+}
+
+func (s *StoreTestSuite) TestStoresSharingConnection() {
+	conn, err := lancedb.ConnectMemory()
+	s.Require().NoError(err)
+	defer conn.Close()
+
+	storeA, err := NewRAGStoreWithConnection(conn, 64, WithTablePrefix("app_a_"), WithLogger(nil))
+	s.Require().NoError(err)
+	defer storeA.Close()
+	storeB, err := NewRAGStoreWithConnection(conn, 32, WithTablePrefix("app_b_"), WithMaxBatchSize(5), WithRetryConfig(nil))
+	s.Require().NoError(err)
+	s.Equal(5, storeB.maxBatchSize)
+	s.Nil(storeB.retryConfig)
+
+	// The same user has separate documents, of different dimensions, in each store
+	docsA := makeTestDocs(10, 64, "a.txt")
+	docsB := makeTestDocs(12, 32, "b.txt")
+	s.Require().NoError(storeA.AddDocuments(s.ctx, "shared_user", docsA))
+	s.Require().NoError(storeB.AddDocuments(s.ctx, "shared_user", docsB))
+
+	resultsA, err := storeA.Search(s.ctx, "shared_user", docsA[2].Embedding, &SearchOptions{Limit: 20})
+	s.Require().NoError(err)
+	s.Len(resultsA, 10)
+	for _, result := range resultsA {
+		s.Equal("a.txt", result.DocumentName)
+		s.Len(result.Embedding, 64)
+	}
+
+	resultsB, err := storeB.Search(s.ctx, "shared_user", docsB[2].Embedding, &SearchOptions{Limit: 20})
+	s.Require().NoError(err)
+	s.Len(resultsB, 12)
+	for _, result := range resultsB {
+		s.Equal("b.txt", result.DocumentName)
+	}
+
+	names, err := conn.TableNames()
+	s.Require().NoError(err)
+	s.Contains(names, "app_a_shared_user")
+	s.Contains(names, "app_b_shared_user")
+
+	// Closing one store affects neither the connection nor the other store
+	s.Require().NoError(storeB.Close())
+	s.True(conn.IsOpen())
+	resultsA, err = storeA.Search(s.ctx, "shared_user", docsA[2].Embedding, &SearchOptions{Limit: 1})
+	s.Require().NoError(err)
+	s.Equal(docsA[2].ID, resultsA[0].ID)
+
+	_, err = NewRAGStoreWithConnection(conn, 64, WithTablePrefix("bad prefix/"))
+	s.Error(err)
+	_, err = NewRAGStoreWithConnection(conn, 64, WithMaxBatchSize(0))
+	s.Error(err)
+}
+
+func (s *StoreTestSuite) TestTablePrefixesKeepApplicationsApart() {
+	storeA, err := NewRAGStore(s.dbPath, 128, WithTablePrefix("appA_user_"), WithLogger(nil))
+	s.Require().NoError(err)
+	defer storeA.Close()
+	storeB, err := NewRAGStore(s.dbPath, 128, WithTablePrefix("appB_user_"), WithLogger(nil))
+	s.Require().NoError(err)
+	defer storeB.Close()
+
+	s.Require().NoError(storeA.AddDocuments(s.ctx, "alice", makeTestDocs(4, 128, "a.txt")))
+	s.Require().NoError(storeB.AddDocuments(s.ctx, "alice", makeTestDocs(7, 128, "b.txt")))
+	s.Require().NoError(storeB.AddDocuments(s.ctx, "bob", makeTestDocs(2, 128, "b.txt")))
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "carol", makeTestDocs(3, 128, "default.txt")))
+
+	names, err := storeA.ListDocumentNames(s.ctx, "alice")
+	s.Require().NoError(err)
+	s.Equal([]string{"a.txt"}, names)
+	names, err = storeB.ListDocumentNames(s.ctx, "alice")
+	s.Require().NoError(err)
+	s.Equal([]string{"b.txt"}, names)
+
+	// Operations over all users only see the store's own tables
+	statsA, err := storeA.GlobalStats(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(1, statsA.TotalUsers)
+	s.EqualValues(4, statsA.TotalChunks)
+	statsB, err := storeB.GlobalStats(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(2, statsB.TotalUsers)
+	s.EqualValues(9, statsB.TotalChunks)
+	stats, err := s.store.GlobalStats(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(1, stats.TotalUsers)
+
+	health := storeB.HealthCheckWithDetails(s.ctx)
+	s.True(health.Healthy)
+	s.Equal(map[string]int64{"alice": 7, "bob": 2}, health.UserTableCount)
+
+	_, err = NewRAGStore(s.dbPath, 128, WithTablePrefix(""))
+	s.Error(err)
+}
+
+func (s *StoreTestSuite) TestValidateUserID() {
+	for _, id := range []string{"alice", "user_42", "team-a", healthCheckUserID} {
+		s.NoError(ValidateUserID(id), id)
+	}
+	for _, id := range []string{"", "   ", "\t", ".", "..", "...", "../etc", "alice/../bob", "alice\n", " alice",
+		"użytkownik", "ａlice", "alice\x00"} {
+		s.Error(ValidateUserID(id), "%q", id)
+	}
+
+	long := make([]byte, DefaultMaxUserIDLength+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+	s.ErrorContains(ValidateUserID(string(long)), "too long")
+	s.NoError(ValidateUserID(string(long[:DefaultMaxUserIDLength])))
+
+	// Store methods reject the same IDs before touching any table
+	_, err := s.store.CountDocuments(s.ctx, "..")
+	s.Error(err)
+	err = s.store.AddDocuments(s.ctx, "../etc", makeTestDocs(1, 128, "x.txt"))
+	s.Error(err)
+}
+
+func (s *StoreTestSuite) TestWithMaxUserIDLength() {
+	store, err := NewRAGStore(s.dbPath, 128, WithMaxUserIDLength(8), WithLogger(nil))
+	s.Require().NoError(err)
+	defer store.Close()
+
+	s.NoError(store.AddDocuments(s.ctx, "abcdefgh", makeTestDocs(1, 128, "a.txt")))
+	s.ErrorContains(store.AddDocuments(s.ctx, "abcdefghi", makeTestDocs(1, 128, "a.txt")), "max 8 characters")
+
+	_, err = NewRAGStore(s.dbPath, 128, WithMaxUserIDLength(0))
+	s.Error(err)
+	_, err = NewRAGStore(s.dbPath, 128, WithMaxUserIDLength(maxTableNameBytes))
+	s.Error(err, "prefix plus user ID must fit the table name limit")
+}
+
+func (s *StoreTestSuite) TestAddDocumentsLogsOperationEvent() {
+	logger := &eventLogger{}
+	s.store.SetLogger(logger)
+
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "event_user", makeTestDocs(6, 128, "events.txt")))
+
+	events := logger.find("rag.AddDocuments")
+	s.Require().Len(events, 1)
+	s.Equal(LogLevelInfo, events[0].Level)
+	s.Equal("event_user", events[0].UserID)
+	s.Equal(6, events[0].DocCount)
+	s.Greater(events[0].Duration, time.Duration(0))
+	s.NoError(events[0].Err)
+
+	// A failed operation is logged at error level
+	s.Error(s.store.AddDocuments(s.ctx, "event_user", makeTestDocs(1, 64, "wrong_dim.txt")))
+	events = logger.find("rag.AddDocuments")
+	s.Require().Len(events, 2)
+	s.Equal(LogLevelError, events[1].Level)
+	s.Error(events[1].Err)
+}
+
+func (s *StoreTestSuite) TestSetLoggerWhileAdding() {
+	loggers := []*recordingLogger{{}, {}}
+	s.store.SetMinRowsForIndex(1000) // Every insert logs that the index is deferred
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			userID := fmt.Sprintf("logger_user_%d", w)
+			for i := 0; i < 5; i++ {
+				if err := s.store.AddDocuments(s.ctx, userID, makeTestDocs(5, 128, fmt.Sprintf("doc_%d.txt", i))); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+
+	// Swap loggers and metrics while the inserts run, including back to the noop ones
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			switch i % 3 {
+			case 0:
+				s.store.SetLogger(loggers[0])
+				s.store.SetMetrics(NewSimpleMetrics())
+			case 1:
+				s.store.SetLogger(loggers[1])
+				s.store.SetMetrics(nil)
+			default:
+				s.store.SetLogger(nil)
+			}
+		}
+	}()
+
+	wg.Wait()
+	<-done
+	close(errs)
+	for err := range errs {
+		s.NoError(err)
+	}
+
+	// Later operations log to the logger set last
+	final := &recordingLogger{}
+	s.store.SetLogger(final)
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "logger_user_0", makeTestDocs(1, 128, "last.txt")))
+	s.Positive(final.count("Deferring vector index"))
+}
+
+func (s *StoreTestSuite) TestSwappableLoggerConcurrent() {
+	logger := newSwappableLogger(nil)
+	metrics := newSwappableMetrics(nil)
+	recorder := &recordingLogger{}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				logger.Printf("message %d", i)
+				logger.Println("message")
+				metrics.RecordOperation("search", 0, true)
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			logger.set(recorder)
+			metrics.set(NewSimpleMetrics())
+		} else {
+			logger.set(nil)
+			metrics.set(nil)
+		}
+	}
+	wg.Wait()
+
+	logger.set(recorder)
+	before := recorder.count("final")
+	logger.Printf("final")
+	s.Equal(before+1, recorder.count("final"))
+}
+
+func (s *StoreTestSuite) TestRebuildIndexReportsSkippedBuild() {
+	s.store.SetMinRowsForIndex(1000)
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "small_user", makeTestDocs(10, 128, "small.txt")))
+
+	err := s.store.RebuildIndex(s.ctx, "small_user", DefaultIndexConfig())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "needs 1000")
+
+	// int8 tables are never indexed; the user is marked done after the first insert
+	s.Require().NoError(s.store.AddDocumentsQuantized(s.ctx, "int8_user", makeTestDocs(10, 128, "int8.txt"), EmbeddingStorageInt8))
+	s.store.mu.RLock()
+	s.True(s.store.indexCreated["int8_user"])
+	s.store.mu.RUnlock()
+
+	err = s.store.RebuildIndex(s.ctx, "int8_user", DefaultIndexConfig())
+	s.Require().Error(err)
+	s.Contains(err.Error(), "int8")
+}
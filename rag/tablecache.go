@@ -0,0 +1,174 @@
+package rag
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/aqua777/go-lancedb"
+)
+
+// defaultMaxOpenTables is the default number of table handles kept open by a RAGStore
+const defaultMaxOpenTables = 64
+
+// tableHandle is a cached open table together with the number of callers using it
+type tableHandle struct {
+	name    string
+	table   *lancedb.Table
+	refs    int
+	evicted bool
+}
+
+// tableCache is a thread-safe LRU of open table handles keyed by table name.
+// Evicted handles are closed as soon as no caller is using them anymore.
+type tableCache struct {
+	mu      sync.Mutex
+	maxSize int
+	ll      *list.List
+	items   map[string]*list.Element
+}
+
+// newTableCache creates a table handle cache holding at most maxSize open tables
+func newTableCache(maxSize int) *tableCache {
+	if maxSize <= 0 {
+		maxSize = defaultMaxOpenTables
+	}
+	return &tableCache{
+		maxSize: maxSize,
+		ll:      list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+// acquire returns the open table for name, calling open on a cache miss.
+// The returned release function must be called once the caller is done with the table.
+func (c *tableCache) acquire(name string, open func() (*lancedb.Table, error)) (*lancedb.Table, func(), error) {
+	c.mu.Lock()
+	if elem, ok := c.items[name]; ok {
+		h := c.use(elem)
+		c.mu.Unlock()
+		return h.table, c.releaseFunc(h), nil
+	}
+	c.mu.Unlock()
+
+	// Open outside the lock so slow opens don't block other tables
+	table, err := open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another caller may have opened the same table concurrently; keep theirs
+	if elem, ok := c.items[name]; ok {
+		table.Close()
+		h := c.use(elem)
+		return h.table, c.releaseFunc(h), nil
+	}
+
+	h := &tableHandle{name: name, table: table, refs: 1}
+	c.items[name] = c.ll.PushFront(h)
+	c.evictOverflow()
+	return h.table, c.releaseFunc(h), nil
+}
+
+// use marks a cached handle as most recently used and takes a reference (caller holds c.mu)
+func (c *tableCache) use(elem *list.Element) *tableHandle {
+	c.ll.MoveToFront(elem)
+	h := elem.Value.(*tableHandle)
+	h.refs++
+	return h
+}
+
+// releaseFunc returns a function that drops one reference to h exactly once
+func (c *tableCache) releaseFunc(h *tableHandle) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			h.refs--
+			if h.evicted && h.refs == 0 {
+				h.table.Close()
+			}
+		})
+	}
+}
+
+// remove drops an element from the cache, closing its table if unused (caller holds c.mu)
+func (c *tableCache) remove(elem *list.Element) {
+	h := elem.Value.(*tableHandle)
+	c.ll.Remove(elem)
+	delete(c.items, h.name)
+	h.evicted = true
+	if h.refs == 0 {
+		h.table.Close()
+	}
+}
+
+// evictOverflow evicts least recently used handles until the cache fits (caller holds c.mu)
+func (c *tableCache) evictOverflow() {
+	for c.ll.Len() > c.maxSize {
+		c.remove(c.ll.Back())
+	}
+}
+
+// invalidate removes the handle for name so the next acquire reopens the table
+func (c *tableCache) invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[name]; ok {
+		c.remove(elem)
+	}
+}
+
+// setMaxSize changes the cache capacity, evicting handles if needed
+func (c *tableCache) setMaxSize(maxSize int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxSize = maxSize
+	c.evictOverflow()
+}
+
+// closeAll evicts every cached handle
+func (c *tableCache) closeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.ll.Len() > 0 {
+		c.remove(c.ll.Back())
+	}
+}
+
+// len returns the number of cached handles
+func (c *tableCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// acquireTable returns a cached open table for the user.
+// The returned release function must be called when the caller is done with the table.
+func (s *RAGStore) acquireTable(userID string) (*lancedb.Table, func(), error) {
+	tableName := s.getTableName(userID)
+	return s.tables.acquire(tableName, func() (*lancedb.Table, error) {
+		return s.getConn().OpenTable(tableName)
+	})
+}
+
+// invalidateTable drops the cached handle for a user after their table was modified,
+// so subsequent searches see the latest data.
+func (s *RAGStore) invalidateTable(userID string) {
+	s.tables.invalidate(s.getTableName(userID))
+	s.bm25Cache.invalidateUser(userID)
+}
+
+// SetMaxOpenTables sets how many user table handles are kept open for searches.
+// Least recently used handles are closed when the limit is exceeded. Default is 64.
+func (s *RAGStore) SetMaxOpenTables(max int) error {
+	if max <= 0 {
+		return fmt.Errorf("max open tables must be positive, got %d", max)
+	}
+	s.tables.setMaxSize(max)
+	return nil
+}
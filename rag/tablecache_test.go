@@ -0,0 +1,143 @@
+package rag
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aqua777/go-lancedb"
+	"github.com/stretchr/testify/suite"
+)
+
+// TableCacheTestSuite tests the LRU of open table handles
+type TableCacheTestSuite struct {
+	suite.Suite
+	opens int
+}
+
+func TestTableCacheSuite(t *testing.T) {
+	suite.Run(t, new(TableCacheTestSuite))
+}
+
+func (s *TableCacheTestSuite) SetupTest() {
+	s.opens = 0
+}
+
+// opener returns an open function that counts how many times it was called
+func (s *TableCacheTestSuite) opener() func() (*lancedb.Table, error) {
+	return func() (*lancedb.Table, error) {
+		s.opens++
+		return &lancedb.Table{}, nil
+	}
+}
+
+func (s *TableCacheTestSuite) TestAcquireReusesHandle() {
+	cache := newTableCache(2)
+
+	t1, release1, err := cache.acquire("a", s.opener())
+	s.Require().NoError(err)
+	release1()
+
+	t2, release2, err := cache.acquire("a", s.opener())
+	s.Require().NoError(err)
+	release2()
+
+	s.Same(t1, t2)
+	s.Equal(1, s.opens)
+	s.Equal(1, cache.len())
+}
+
+func (s *TableCacheTestSuite) TestEvictsLeastRecentlyUsed() {
+	cache := newTableCache(2)
+
+	for _, name := range []string{"a", "b", "a", "c"} {
+		_, release, err := cache.acquire(name, s.opener())
+		s.Require().NoError(err)
+		release()
+	}
+
+	s.Equal(2, cache.len())
+	s.Equal(3, s.opens)
+
+	// "b" was least recently used and must be reopened
+	_, release, err := cache.acquire("b", s.opener())
+	s.Require().NoError(err)
+	release()
+	s.Equal(4, s.opens)
+
+	// "a" was evicted by "b"; "c" is still cached
+	_, release, err = cache.acquire("c", s.opener())
+	s.Require().NoError(err)
+	release()
+	s.Equal(4, s.opens)
+}
+
+func (s *TableCacheTestSuite) TestEvictedHandleInUseStaysOpen() {
+	cache := newTableCache(1)
+
+	_, releaseA, err := cache.acquire("a", s.opener())
+	s.Require().NoError(err)
+
+	// Evict "a" while it is still in use
+	_, releaseB, err := cache.acquire("b", s.opener())
+	s.Require().NoError(err)
+	releaseB()
+
+	cache.mu.Lock()
+	_, cached := cache.items["a"]
+	cache.mu.Unlock()
+	s.False(cached)
+
+	// Releasing twice must not double-decrement
+	releaseA()
+	releaseA()
+	s.Equal(1, cache.len())
+}
+
+func (s *TableCacheTestSuite) TestInvalidateAndCloseAll() {
+	cache := newTableCache(4)
+
+	for _, name := range []string{"a", "b", "c"} {
+		_, release, err := cache.acquire(name, s.opener())
+		s.Require().NoError(err)
+		release()
+	}
+
+	cache.invalidate("b")
+	s.Equal(2, cache.len())
+
+	cache.closeAll()
+	s.Equal(0, cache.len())
+}
+
+func (s *TableCacheTestSuite) TestOpenError() {
+	cache := newTableCache(2)
+
+	_, _, err := cache.acquire("a", func() (*lancedb.Table, error) {
+		return nil, fmt.Errorf("open failed")
+	})
+	s.Error(err)
+	s.Equal(0, cache.len())
+}
+
+func (s *TableCacheTestSuite) TestConcurrentAcquire() {
+	cache := newTableCache(5)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("t%d", i%20)
+			_, release, err := cache.acquire(name, func() (*lancedb.Table, error) {
+				return &lancedb.Table{}, nil
+			})
+			if err == nil {
+				release()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	s.LessOrEqual(cache.len(), 5)
+}
@@ -0,0 +1,220 @@
+package rag
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenCounter counts how many tokens a model's tokenizer produces for a text
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// HeuristicTokenCounter approximates token counts as 1 token ≈ 4 characters.
+// It needs no vocabulary and works reasonably for English prose, but can be far off
+// for code, non-Latin scripts or text with many numbers and symbols.
+type HeuristicTokenCounter struct{}
+
+// CountTokens returns the approximate number of tokens in text
+func (HeuristicTokenCounter) CountTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Supported tiktoken encodings
+const (
+	EncodingCL100KBase = "cl100k_base" // GPT-4, GPT-3.5-turbo, text-embedding-3-*
+	EncodingO200KBase  = "o200k_base"  // GPT-4o and later
+)
+
+// Character classes matching Unicode White_Space, as used by tiktoken's \s
+const (
+	bpeSpace    = `\t\n\v\f\r \x{85}\p{Z}`
+	bpeContract = `(?i:'s|'t|'re|'ve|'m|'ll|'d)`
+)
+
+// Pre-tokenization patterns of the tiktoken encodings. tiktoken's final `\s+(?!\S)` alternative
+// needs a lookahead RE2 doesn't support; it is handled in splitPieces via the trailing
+// whitespace capture group.
+var bpePatterns = map[string]string{
+	EncodingCL100KBase: bpeContract +
+		`|[^\r\n\p{L}\p{N}]?\p{L}+` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + bpeSpace + `\p{L}\p{N}]+[\r\n]*` +
+		`|[` + bpeSpace + `]*[\r\n]+` +
+		`|([` + bpeSpace + `]+)`,
+	EncodingO200KBase: `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+` + bpeContract + `?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*` + bpeContract + `?` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + bpeSpace + `\p{L}\p{N}]+[\r\n/]*` +
+		`|[` + bpeSpace + `]*[\r\n]+` +
+		`|([` + bpeSpace + `]+)`,
+}
+
+// BPETokenCounter counts tokens with a tiktoken-compatible byte pair encoding.
+// Special tokens such as <|endoftext|> are counted as ordinary text.
+type BPETokenCounter struct {
+	encoding string
+	ranks    map[string]int
+	pattern  *regexp.Regexp
+}
+
+// NewBPETokenCounter creates a BPE token counter for a tiktoken encoding (cl100k_base or o200k_base).
+// ranks must be in the tiktoken vocabulary format: one "<base64 token> <rank>" pair per line,
+// as found in the published cl100k_base.tiktoken and o200k_base.tiktoken files.
+func NewBPETokenCounter(encoding string, ranks io.Reader) (*BPETokenCounter, error) {
+	pattern, ok := bpePatterns[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q (supported: %s, %s)", encoding, EncodingCL100KBase, EncodingO200KBase)
+	}
+
+	vocab, err := parseTiktokenRanks(ranks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s vocabulary: %w", encoding, err)
+	}
+
+	return &BPETokenCounter{
+		encoding: encoding,
+		ranks:    vocab,
+		pattern:  regexp.MustCompile(`^(?:` + pattern + `)`),
+	}, nil
+}
+
+// LoadBPETokenCounter creates a BPE token counter from a tiktoken vocabulary file on disk
+func LoadBPETokenCounter(encoding string, path string) (*BPETokenCounter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vocabulary file: %w", err)
+	}
+	defer f.Close()
+
+	return NewBPETokenCounter(encoding, f)
+}
+
+// parseTiktokenRanks reads "<base64 token> <rank>" lines into a token→rank map
+func parseTiktokenRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"<token> <rank>\"", lineNo)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: failed to decode token: %w", lineNo, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rank: %w", lineNo, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("vocabulary is empty")
+	}
+	return ranks, nil
+}
+
+// Encoding returns the name of the tiktoken encoding
+func (c *BPETokenCounter) Encoding() string {
+	return c.encoding
+}
+
+// Encode returns the token ranks for text
+func (c *BPETokenCounter) Encode(text string) []int {
+	tokens := make([]int, 0, len(text)/3)
+	for _, piece := range c.splitPieces(text) {
+		if rank, ok := c.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		for _, part := range c.bytePairMerge(piece) {
+			tokens = append(tokens, c.ranks[part])
+		}
+	}
+	return tokens
+}
+
+// CountTokens returns the number of tokens in text
+func (c *BPETokenCounter) CountTokens(text string) int {
+	count := 0
+	for _, piece := range c.splitPieces(text) {
+		if _, ok := c.ranks[piece]; ok {
+			count++
+			continue
+		}
+		count += len(c.bytePairMerge(piece))
+	}
+	return count
+}
+
+// splitPieces pre-tokenizes text with the encoding's regex
+func (c *BPETokenCounter) splitPieces(text string) []string {
+	pieces := make([]string, 0, len(text)/4+1)
+	for len(text) > 0 {
+		loc := c.pattern.FindStringSubmatchIndex(text)
+		if loc == nil || loc[1] == 0 {
+			// Unmatched input (should not happen with these patterns): take one rune
+			_, size := utf8.DecodeRuneInString(text)
+			pieces = append(pieces, text[:size])
+			text = text[size:]
+			continue
+		}
+
+		end := loc[1]
+		// Emulate `\s+(?!\S)`: a whitespace run followed by non-whitespace leaves its last
+		// character to be picked up as the prefix of the next piece
+		if loc[2] >= 0 && end < len(text) {
+			_, lastSize := utf8.DecodeLastRuneInString(text[:end])
+			if end-lastSize > 0 {
+				end -= lastSize
+			}
+		}
+
+		pieces = append(pieces, text[:end])
+		text = text[end:]
+	}
+	return pieces
+}
+
+// bytePairMerge splits a piece into vocabulary tokens by repeatedly merging the
+// adjacent pair with the lowest rank, as tiktoken does
+func (c *BPETokenCounter) bytePairMerge(piece string) []string {
+	parts := make([]string, len(piece))
+	for i := 0; i < len(piece); i++ {
+		parts[i] = piece[i : i+1]
+	}
+
+	for len(parts) > 1 {
+		best, bestRank := -1, 0
+		for i := 0; i < len(parts)-1; i++ {
+			if rank, ok := c.ranks[parts[i]+parts[i+1]]; ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+
+	return parts
+}
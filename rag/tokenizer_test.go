@@ -0,0 +1,166 @@
+package rag
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// TokenizerTestSuite tests token counting and token-based chunking
+type TokenizerTestSuite struct {
+	suite.Suite
+	counter *BPETokenCounter
+}
+
+// TestTokenizerTestSuite runs the tokenizer test suite
+func TestTokenizerTestSuite(t *testing.T) {
+	suite.Run(t, new(TokenizerTestSuite))
+}
+
+// testVocabulary builds a tiny tiktoken-format vocabulary: every single byte plus a few merges
+func testVocabulary() string {
+	var b strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	merges := []string{"he", "ll", "hell", "hello", " w", "or", "ld", " wor", " world", "wor", "world"}
+	for i, merge := range merges {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(merge)), 256+i)
+	}
+	return b.String()
+}
+
+func (s *TokenizerTestSuite) SetupTest() {
+	counter, err := NewBPETokenCounter(EncodingCL100KBase, strings.NewReader(testVocabulary()))
+	s.Require().NoError(err)
+	s.counter = counter
+}
+
+func (s *TokenizerTestSuite) TestInvalidVocabulary() {
+	_, err := NewBPETokenCounter("p50k_base", strings.NewReader(testVocabulary()))
+	s.Error(err)
+
+	_, err = NewBPETokenCounter(EncodingCL100KBase, strings.NewReader(""))
+	s.Error(err)
+
+	_, err = NewBPETokenCounter(EncodingCL100KBase, strings.NewReader("not-base64! 1\n"))
+	s.Error(err)
+
+	_, err = NewBPETokenCounter(EncodingCL100KBase, strings.NewReader("aGk= x\n"))
+	s.Error(err)
+}
+
+func (s *TokenizerTestSuite) TestPreTokenization() {
+	cases := map[string][]string{
+		"hello world":             {"hello", " world"},
+		"a   b":                   {"a", "  ", " b"},
+		"I'm 12345 ok!\n\nbye":    {"I", "'m", " ", "123", "45", " ok", "!\n\n", "bye"},
+		"trailing spaces   ":      {"trailing", " spaces", "   "},
+		"tabs\tand\nnew lines\n ": {"tabs", "\tand", "\n", "new", " lines", "\n", " "},
+	}
+	for text, expected := range cases {
+		s.Equal(expected, s.counter.splitPieces(text), "pieces of %q", text)
+	}
+}
+
+func (s *TokenizerTestSuite) TestCountTokens() {
+	cases := map[string]int{
+		"":            0,
+		"hello":       1,
+		"hello world": 2,
+		"help":        3, // he + l + p
+		"worldly":     3, // world + l + y
+		" hello":      2, // space + hello
+	}
+	for text, expected := range cases {
+		s.Equal(expected, s.counter.CountTokens(text), "tokens in %q", text)
+		s.Len(s.counter.Encode(text), expected)
+	}
+
+	s.Equal([]int{259, 264}, s.counter.Encode("hello world"))
+}
+
+func (s *TokenizerTestSuite) TestHeuristicTokenCounter() {
+	counter := HeuristicTokenCounter{}
+	s.Equal(0, counter.CountTokens(""))
+	s.Equal(1, counter.CountTokens("abc"))
+	s.Equal(1, counter.CountTokens("abcd"))
+	s.Equal(2, counter.CountTokens("abcde"))
+}
+
+func (s *TokenizerTestSuite) TestTokenChunkerWithCounter() {
+	chunker, err := NewTokenChunkerWithCounter(4, 0, s.counter)
+	s.Require().NoError(err)
+
+	// Per word: hello=1, world=1, " world"=1, " hello"=2
+	chunks, err := chunker.Chunk("hello world hello world hello world")
+	s.Require().NoError(err)
+	s.Require().Len(chunks, 2)
+	s.Equal("hello world hello", chunks[0].Text)
+	s.Equal("world hello world", chunks[1].Text)
+
+	for _, chunk := range chunks {
+		s.Equal(4, chunk.Metadata["tokens"])
+		s.Equal(4, s.counter.CountTokens(chunk.Text))
+	}
+}
+
+func (s *TokenizerTestSuite) TestTokenChunkerWithCounterOverlap() {
+	chunker, err := NewTokenChunkerWithCounter(4, 2, s.counter)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk("hello world hello world hello world")
+	s.Require().NoError(err)
+	s.Require().Len(chunks, 3)
+	s.Equal("hello world hello", chunks[0].Text)
+	s.Equal("hello world", chunks[1].Text)
+	s.Equal("world hello world", chunks[2].Text)
+
+	for _, chunk := range chunks {
+		s.LessOrEqual(s.counter.CountTokens(chunk.Text), 4)
+	}
+}
+
+func (s *TokenizerTestSuite) TestTokenChunkerSplitsOversizedWords() {
+	chunker, err := NewTokenChunkerWithCounter(3, 0, s.counter)
+	s.Require().NoError(err)
+
+	chunks, err := chunker.Chunk("abcdefgh")
+	s.Require().NoError(err)
+	s.Require().Len(chunks, 3)
+	s.Equal("abc", chunks[0].Text)
+	s.Equal("def", chunks[1].Text)
+	s.Equal("gh", chunks[2].Text)
+}
+
+func (s *TokenizerTestSuite) TestTokenChunkerDefaultsToHeuristic() {
+	chunker, err := NewTokenChunker(2, 0)
+	s.Require().NoError(err)
+	s.Nil(chunker.Counter)
+
+	chunks, err := chunker.Chunk("abcdefghij")
+	s.Require().NoError(err)
+	s.Require().Len(chunks, 2)
+	s.Equal("abcdefgh", chunks[0].Text)
+	s.Equal("ij", chunks[1].Text)
+}
+
+// TestCL100KKnownCounts checks counts against the real cl100k_base vocabulary.
+// Set TIKTOKEN_CL100K_BASE to the path of cl100k_base.tiktoken to run it.
+func (s *TokenizerTestSuite) TestCL100KKnownCounts() {
+	path := os.Getenv("TIKTOKEN_CL100K_BASE")
+	if path == "" {
+		s.T().Skip("TIKTOKEN_CL100K_BASE not set")
+	}
+
+	counter, err := LoadBPETokenCounter(EncodingCL100KBase, path)
+	s.Require().NoError(err)
+
+	s.Equal(2, counter.CountTokens("hello world"))
+	s.Equal(6, counter.CountTokens("tiktoken is great!"))
+	s.Equal([]int{83, 1609, 5963, 374, 2294, 0}, counter.Encode("tiktoken is great!"))
+}
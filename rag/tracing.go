@@ -0,0 +1,146 @@
+package rag
+
+import (
+	"context"
+	"time"
+)
+
+// Tracer starts spans around RAG store operations for distributed tracing.
+// Implement this interface to integrate with your tracing system. With OpenTelemetry,
+// StartSpan wraps trace.Tracer.Start and returns a Span backed by the trace.Span.
+type Tracer interface {
+	// StartSpan starts a span for the named operation as a child of any span in ctx.
+	// The returned context carries the new span and is passed to nested operations.
+	StartSpan(ctx context.Context, operation string) (context.Context, Span)
+}
+
+// Span is a single traced operation
+type Span interface {
+	// SetAttribute tags the span with a key/value pair (string, int or float64 values)
+	SetAttribute(key string, value interface{})
+
+	// RecordError records that the operation failed
+	RecordError(err error)
+
+	// End completes the span
+	End()
+}
+
+// Span attribute keys set by the store
+const (
+	SpanAttrUserID        = "rag.user_id"        // user the operation ran for
+	SpanAttrDocumentCount = "rag.document_count" // documents written by the operation
+	SpanAttrResultCount   = "rag.result_count"   // results returned by a search
+	SpanAttrDocumentName  = "rag.document_name"  // document name for document-level operations
+)
+
+// noopTracer is a Tracer that creates no spans
+type noopTracer struct{}
+
+func (n *noopTracer) StartSpan(ctx context.Context, operation string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+// noopSpan is a Span that records nothing
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+// SetTracer sets the tracer used to create a span per store operation
+// (adding, upserting and deleting documents, searches, and index builds).
+// Pass nil to disable tracing.
+func (s *RAGStore) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = &noopTracer{}
+	}
+
+	s.mu.Lock()
+	s.tracer = tracer
+	s.mu.Unlock()
+}
+
+// startSpan starts a span for an operation on a user's data
+func (s *RAGStore) startSpan(ctx context.Context, operation string, userID string) (context.Context, Span) {
+	s.mu.RLock()
+	tracer := s.tracerLocked()
+	s.mu.RUnlock()
+
+	return s.startSpanWith(tracer, ctx, operation, userID)
+}
+
+// tracerLocked returns the tracer to start spans with, never nil (must be called with mu held)
+func (s *RAGStore) tracerLocked() Tracer {
+	if s.tracer == nil {
+		return &noopTracer{}
+	}
+	return s.tracer
+}
+
+// startSpanWith starts a span on tracer for an operation on a user's data
+func (s *RAGStore) startSpanWith(tracer Tracer, ctx context.Context, operation string, userID string) (context.Context, Span) {
+	ctx, span := tracer.StartSpan(ctx, operation)
+	span = s.logOnEnd(span, operation, userID)
+	span.SetAttribute(SpanAttrUserID, userID)
+	return ctx, span
+}
+
+// logOnEnd wraps span so that ending it also logs the operation as an OperationEvent
+func (s *RAGStore) logOnEnd(span Span, operation string, userID string) Span {
+	return &operationSpan{Span: span, logger: s.logger, operation: operation, userID: userID, start: time.Now()}
+}
+
+// operationSpan records the attributes and error of a span for the operation's log event
+type operationSpan struct {
+	Span
+	logger      Logger
+	operation   string
+	userID      string
+	start       time.Time
+	docCount    int
+	resultCount int
+	err         error
+}
+
+func (o *operationSpan) SetAttribute(key string, value interface{}) {
+	if n, ok := value.(int); ok {
+		switch key {
+		case SpanAttrDocumentCount:
+			o.docCount = n
+		case SpanAttrResultCount:
+			o.resultCount = n
+		}
+	}
+	o.Span.SetAttribute(key, value)
+}
+
+func (o *operationSpan) RecordError(err error) {
+	o.err = err
+	o.Span.RecordError(err)
+}
+
+func (o *operationSpan) End() {
+	event := OperationEvent{
+		Level:       LogLevelInfo,
+		Operation:   o.operation,
+		UserID:      o.userID,
+		DocCount:    o.docCount,
+		ResultCount: o.resultCount,
+		Duration:    time.Since(o.start),
+		Err:         o.err,
+	}
+	if o.err != nil {
+		event.Level = LogLevelError
+	}
+	logOperation(o.logger, event)
+	o.Span.End()
+}
+
+// endSpan records err on the span, if any, and ends it
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,195 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// recordedSpan is a finished span captured by spanRecorder
+type recordedSpan struct {
+	name       string
+	parent     string
+	attributes map[string]interface{}
+	errors     []error
+	ended      bool
+}
+
+// spanRecorder is an in-memory Tracer that keeps every span it starts
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type spanContextKey struct{}
+
+func (r *spanRecorder) StartSpan(ctx context.Context, operation string) (context.Context, Span) {
+	span := &recordedSpan{name: operation, attributes: make(map[string]interface{})}
+	if parent, ok := ctx.Value(spanContextKey{}).(*recordedSpan); ok {
+		span.parent = parent.name
+	}
+
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+
+	return context.WithValue(ctx, spanContextKey{}, span), &recordingSpan{recorder: r, span: span}
+}
+
+// find returns the spans with the given name
+func (r *spanRecorder) find(name string) []*recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var found []*recordedSpan
+	for _, span := range r.spans {
+		if span.name == name {
+			found = append(found, span)
+		}
+	}
+	return found
+}
+
+// recordingSpan writes span data into the recorder
+type recordingSpan struct {
+	recorder *spanRecorder
+	span     *recordedSpan
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.span.attributes[key] = value
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.span.errors = append(s.span.errors, err)
+}
+
+func (s *recordingSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.span.ended = true
+}
+
+// TracingTestSuite tests spans created around store operations
+type TracingTestSuite struct {
+	suite.Suite
+	tmpDir   string
+	store    *RAGStore
+	recorder *spanRecorder
+	ctx      context.Context
+}
+
+func TestTracingSuite(t *testing.T) {
+	suite.Run(t, new(TracingTestSuite))
+}
+
+func (s *TracingTestSuite) SetupTest() {
+	tmpDir, err := os.MkdirTemp("", "rag_tracing_test_*")
+	s.Require().NoError(err)
+	s.tmpDir = tmpDir
+
+	store, err := NewRAGStoreWithConfig(filepath.Join(tmpDir, "test.db"), 128, 100, &noopLogger{}, DefaultRetryConfig(), nil)
+	s.Require().NoError(err)
+	s.store = store
+
+	s.recorder = &spanRecorder{}
+	s.store.SetTracer(s.recorder)
+	s.ctx = context.Background()
+}
+
+func (s *TracingTestSuite) TearDownTest() {
+	if s.store != nil {
+		s.store.Close()
+	}
+	if s.tmpDir != "" {
+		os.RemoveAll(s.tmpDir)
+	}
+}
+
+// addTracingDocs adds n documents with distinct embeddings
+func (s *TracingTestSuite) addTracingDocs(userID string, n int) []Document {
+	docs := make([]Document, n)
+	for i := range docs {
+		docs[i] = Document{
+			ID:           fmt.Sprintf("doc%d", i),
+			Text:         fmt.Sprintf("document %d", i),
+			DocumentName: "test.txt",
+			Embedding:    make([]float32, 128),
+		}
+		docs[i].Embedding[i%128] = float32(i + 1)
+	}
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+	return docs
+}
+
+func (s *TracingTestSuite) TestSearchSpan() {
+	docs := s.addTracingDocs("user1", 300)
+
+	results, err := s.store.Search(s.ctx, "user1", docs[3].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+
+	spans := s.recorder.find("rag.Search")
+	s.Require().Len(spans, 1)
+	span := spans[0]
+	s.True(span.ended)
+	s.Empty(span.errors)
+	s.Equal("user1", span.attributes[SpanAttrUserID])
+	s.Equal(len(results), span.attributes[SpanAttrResultCount])
+	s.Equal(5, span.attributes[SpanAttrResultCount])
+}
+
+func (s *TracingTestSuite) TestAddDocumentsSpanWithIndexBuild() {
+	s.addTracingDocs("user1", 300)
+
+	spans := s.recorder.find("rag.AddDocuments")
+	s.Require().Len(spans, 1)
+	s.True(spans[0].ended)
+	s.Equal("user1", spans[0].attributes[SpanAttrUserID])
+	s.Equal(300, spans[0].attributes[SpanAttrDocumentCount])
+
+	// The first insert builds the vector index inside the AddDocuments span
+	indexSpans := s.recorder.find("rag.CreateIndex")
+	s.Require().Len(indexSpans, 1)
+	s.Equal("rag.AddDocuments", indexSpans[0].parent)
+	s.True(indexSpans[0].ended)
+}
+
+func (s *TracingTestSuite) TestFailedOperationRecordsError() {
+	_, err := s.store.Search(s.ctx, "user1", []float32{1, 2}, nil)
+	s.Require().Error(err)
+
+	spans := s.recorder.find("rag.Search")
+	s.Require().Len(spans, 1)
+	s.Require().Len(spans[0].errors, 1)
+	s.Equal(err, spans[0].errors[0])
+	s.True(spans[0].ended)
+}
+
+func (s *TracingTestSuite) TestNilTracerDisablesTracing() {
+	s.store.SetTracer(nil)
+	s.addTracingDocs("user1", 10)
+
+	s.Empty(s.recorder.find("rag.AddDocuments"))
+}
+
+func (s *TracingTestSuite) TestUnsetTracerIndexBuild() {
+	// A store whose tracer was never set still builds its index
+	s.store.mu.Lock()
+	s.store.tracer = nil
+	s.store.mu.Unlock()
+
+	s.addTracingDocs("user1", 300)
+
+	stats, err := s.store.IndexStats(s.ctx, "user1")
+	s.Require().NoError(err)
+	s.True(stats.IndexExists)
+}
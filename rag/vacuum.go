@@ -0,0 +1,82 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aqua777/go-lancedb"
+)
+
+// Vacuum compacts a user's table and removes table versions older than olderThan,
+// reclaiming the disk space held by data that upserts and deletes have replaced.
+// Pass 0 to keep only the latest version. Pruned versions can no longer be restored.
+func (s *RAGStore) Vacuum(ctx context.Context, userID string, olderThan time.Duration) (*lancedb.OptimizeStats, error) {
+	if err := s.validateUserID(userID); err != nil {
+		return nil, err
+	}
+
+	// Check for context cancellation
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	// Acquire per-user lock for write protection
+	unlock := s.lockUser(userID)
+	defer unlock()
+
+	// Cached handles may read versions that are about to be pruned
+	s.invalidateTable(userID)
+	defer s.invalidateTable(userID)
+
+	table, err := s.getConn().OpenTable(s.getTableName(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open table for user %s: %w", userID, err)
+	}
+	defer table.Close()
+
+	stats, err := table.Optimize(olderThan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to vacuum table for user %s: %w", userID, err)
+	}
+
+	s.logger.Printf("Vacuumed user %s: compacted %d fragments into %d, pruned %d versions, reclaimed %d bytes",
+		userID, stats.FragmentsRemoved, stats.FragmentsAdded, stats.VersionsRemoved, stats.BytesRemoved)
+	return stats, nil
+}
+
+// VacuumAll runs Vacuum for every user and returns the statistics keyed by user ID.
+// A failed user doesn't stop the others: the returned map holds stats for every user
+// that was vacuumed, and the error lists the users that failed.
+func (s *RAGStore) VacuumAll(ctx context.Context, olderThan time.Duration) (map[string]*lancedb.OptimizeStats, error) {
+	userIDs, err := s.listUserIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]*lancedb.OptimizeStats, len(userIDs))
+	var failures []error
+	var reclaimed int64
+	for _, userID := range userIDs {
+		stats, err := s.Vacuum(ctx, userID, olderThan)
+		if err != nil {
+			// Never continue past a cancellation
+			if ctx.Err() != nil {
+				return results, ctx.Err()
+			}
+			failures = append(failures, fmt.Errorf("user %s: %w", userID, err))
+			continue
+		}
+		results[userID] = stats
+		reclaimed += stats.BytesRemoved
+	}
+
+	s.logger.Printf("Vacuumed %d of %d users, reclaimed %d bytes", len(results), len(userIDs), reclaimed)
+	if len(failures) > 0 {
+		return results, fmt.Errorf("vacuum failed for %d of %d users: %w", len(failures), len(userIDs), errors.Join(failures...))
+	}
+	return results, nil
+}
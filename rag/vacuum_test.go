@@ -0,0 +1,108 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// VacuumTestSuite tests table maintenance
+type VacuumTestSuite struct {
+	suite.Suite
+	store  *RAGStore
+	dbPath string
+	ctx    context.Context
+}
+
+// SetupTest runs before each test
+func (s *VacuumTestSuite) SetupTest() {
+	tmpDir, err := os.MkdirTemp("", "rag_vacuum_test_*")
+	s.Require().NoError(err)
+	s.dbPath = filepath.Join(tmpDir, "test.db")
+	s.ctx = context.Background()
+
+	store, err := NewRAGStoreWithConfig(s.dbPath, 128, 100, &noopLogger{}, DefaultRetryConfig(), nil)
+	s.Require().NoError(err)
+	s.store = store
+}
+
+// TearDownTest runs after each test
+func (s *VacuumTestSuite) TearDownTest() {
+	if s.store != nil {
+		s.store.Close()
+	}
+	if s.dbPath != "" {
+		os.RemoveAll(filepath.Dir(s.dbPath))
+	}
+}
+
+// TestVacuumTestSuite runs the vacuum test suite
+func TestVacuumTestSuite(t *testing.T) {
+	suite.Run(t, new(VacuumTestSuite))
+}
+
+// dataFileCount returns the number of data files on disk for the user's table
+func (s *VacuumTestSuite) dataFileCount(userID string) int {
+	entries, err := os.ReadDir(filepath.Join(s.dbPath, s.store.getTableName(userID)+".lance", "data"))
+	s.Require().NoError(err)
+	return len(entries)
+}
+
+func (s *VacuumTestSuite) TestVacuumAfterUpserts() {
+	userID := "vacuum_user"
+	docs := makeTestDocs(20, 128, "doc.txt")
+	s.Require().NoError(s.store.AddDocuments(s.ctx, userID, docs))
+
+	// Each upsert rewrites the same documents, leaving the old files behind
+	for i := 0; i < 10; i++ {
+		s.Require().NoError(s.store.UpsertDocuments(s.ctx, userID, docs[:5]))
+	}
+
+	filesBefore := s.dataFileCount(userID)
+
+	stats, err := s.store.Vacuum(s.ctx, userID, 0)
+	s.Require().NoError(err)
+	s.Greater(stats.VersionsRemoved, int64(0))
+	s.Greater(stats.BytesRemoved, int64(0))
+
+	s.Less(s.dataFileCount(userID), filesBefore)
+
+	count, err := s.store.CountDocuments(s.ctx, userID)
+	s.Require().NoError(err)
+	s.Equal(int64(20), count)
+
+	results, err := s.store.Search(s.ctx, userID, docs[0].Embedding, &SearchOptions{Limit: 5})
+	s.Require().NoError(err)
+	s.Len(results, 5)
+}
+
+func (s *VacuumTestSuite) TestVacuumAll() {
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user_a", makeTestDocs(5, 128, "a.txt")))
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user_b", makeTestDocs(5, 128, "b.txt")))
+	s.Require().NoError(s.store.DeleteByDocumentName(s.ctx, "user_b", "b.txt"))
+
+	results, err := s.store.VacuumAll(s.ctx, 0)
+	s.Require().NoError(err)
+	s.Len(results, 2)
+	s.Contains(results, "user_a")
+	s.Contains(results, "user_b")
+
+	count, err := s.store.CountDocuments(s.ctx, "user_a")
+	s.Require().NoError(err)
+	s.Equal(int64(5), count)
+}
+
+func (s *VacuumTestSuite) TestVacuumErrors() {
+	_, err := s.store.Vacuum(s.ctx, "bad user!", 0)
+	s.Error(err)
+
+	_, err = s.store.Vacuum(s.ctx, "missing_user", 0)
+	s.Error(err)
+
+	s.Require().NoError(s.store.AddDocuments(s.ctx, "user_a", makeTestDocs(5, 128, "a.txt")))
+	_, err = s.store.Vacuum(s.ctx, "user_a", -1)
+	s.Error(err)
+}
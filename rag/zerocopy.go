@@ -0,0 +1,82 @@
+package rag
+
+import (
+	"sync"
+
+	"github.com/apache/arrow/go/v17/arrow"
+)
+
+// EmbeddingHandle keeps the Arrow buffers behind zero-copy embeddings alive (see
+// SearchOptions.UnsafeZeroCopyEmbeddings). All results of one search share a handle,
+// which starts with one reference owned by the caller.
+//
+// WARNING: once the last reference is released, the embeddings of every result sharing
+// the handle point to freed memory. Reading them is a use-after-free that the race
+// detector can't catch, since the buffers aren't allocated by Go. Copy any embedding that
+// must outlive the handle.
+type EmbeddingHandle struct {
+	mu      sync.Mutex
+	refs    int
+	records []arrow.Record
+}
+
+func newEmbeddingHandle() *EmbeddingHandle {
+	return &EmbeddingHandle{refs: 1}
+}
+
+// keep retains record until the handle is released
+func (h *EmbeddingHandle) keep(record arrow.Record) {
+	record.Retain()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, record)
+}
+
+// Retain adds a reference, e.g. before handing the results to another goroutine.
+// Each Retain must be matched by a Release. A nil handle is a no-op.
+func (h *EmbeddingHandle) Retain() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.refs <= 0 {
+		panic("rag: EmbeddingHandle retained after release")
+	}
+	h.refs++
+}
+
+// Release drops a reference, freeing the embeddings' buffers with the last one.
+// A nil handle is a no-op.
+func (h *EmbeddingHandle) Release() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	if h.refs <= 0 {
+		h.mu.Unlock()
+		panic("rag: EmbeddingHandle released too many times")
+	}
+	h.refs--
+	var records []arrow.Record
+	if h.refs == 0 {
+		records, h.records = h.records, nil
+	}
+	h.mu.Unlock()
+
+	for _, record := range records {
+		record.Release()
+	}
+}
+
+// ReleaseEmbeddings releases each distinct EmbeddingHandle of results once, freeing the
+// buffers of zero-copy embeddings. Results without a handle are skipped.
+func ReleaseEmbeddings(results []SearchResult) {
+	released := make(map[*EmbeddingHandle]bool)
+	for _, result := range results {
+		if h := result.EmbeddingHandle; h != nil && !released[h] {
+			released[h] = true
+			h.Release()
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: Copyright The LanceDB Authors
+
+package lancedb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+)
+
+// QueryResults owns the record batches of a query result and releases them on Close,
+// so callers don't need to release each batch on every return path:
+//
+//	results, err := table.Query().Limit(10).ExecuteResults()
+//	if err != nil {
+//		return err
+//	}
+//	defer results.Close()
+//
+// QueryResults is not safe for concurrent use.
+type QueryResults struct {
+	records []arrow.Record
+	rows    int
+	closed  bool
+}
+
+// NewQueryResults wraps records, taking ownership of them
+func NewQueryResults(records []arrow.Record) *QueryResults {
+	rows := 0
+	for _, record := range records {
+		rows += int(record.NumRows())
+	}
+	return &QueryResults{records: records, rows: rows}
+}
+
+// Rows returns the total number of rows across all batches
+func (r *QueryResults) Rows() int {
+	if r.closed {
+		return 0
+	}
+	return r.rows
+}
+
+// Records returns the record batches. They stay owned by r and are only valid until
+// Close; retain a batch to keep it longer.
+func (r *QueryResults) Records() []arrow.Record {
+	return r.records
+}
+
+// Column returns the named column of each batch, in batch order. The arrays are only
+// valid until Close.
+func (r *QueryResults) Column(name string) ([]arrow.Array, error) {
+	if r.closed {
+		return nil, &Error{Code: ErrorCodeClosed, Message: "query results are closed"}
+	}
+	columns := make([]arrow.Array, len(r.records))
+	for i, record := range r.records {
+		indices := record.Schema().FieldIndices(name)
+		if len(indices) == 0 {
+			return nil, fmt.Errorf("column %q not found in query results", name)
+		}
+		columns[i] = record.Column(indices[0])
+	}
+	return columns, nil
+}
+
+// EachRow calls fn for each row in order, stopping at the first error, which it
+// returns. The RowView is only valid during the call; values read from it are copies
+// and may be kept.
+func (r *QueryResults) EachRow(fn func(RowView) error) error {
+	if r.closed {
+		return &Error{Code: ErrorCodeClosed, Message: "query results are closed"}
+	}
+	index := 0
+	for _, record := range r.records {
+		for row := 0; row < int(record.NumRows()); row++ {
+			if err := fn(RowView{record: record, row: row, index: index}); err != nil {
+				return err
+			}
+			index++
+		}
+	}
+	return nil
+}
+
+// Close releases the record batches. It is safe to call more than once.
+func (r *QueryResults) Close() {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	for _, record := range r.records {
+		record.Release()
+	}
+	r.records = nil
+}
+
+// RowView reads the values of one row of a QueryResults. Getters look columns up by
+// name, convert between compatible types and copy strings and vectors out of the
+// Arrow buffers.
+type RowView struct {
+	record arrow.Record
+	row    int
+	index  int
+}
+
+// Index returns the row's position across all batches
+func (v RowView) Index() int {
+	return v.index
+}
+
+// column returns the named column of the row's batch
+func (v RowView) column(name string) (arrow.Array, error) {
+	indices := v.record.Schema().FieldIndices(name)
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("column %q not found in query results", name)
+	}
+	return v.record.Column(indices[0]), nil
+}
+
+// IsNull reports whether the named column is null in this row. Missing columns
+// count as null.
+func (v RowView) IsNull(name string) bool {
+	col, err := v.column(name)
+	return err != nil || col.IsNull(v.row)
+}
+
+// String returns the value of a string column, decoding dictionary-encoded columns.
+// Nulls are returned as empty strings.
+func (v RowView) String(name string) (string, error) {
+	col, err := v.column(name)
+	if err != nil {
+		return "", err
+	}
+	return stringValue(col, v.row, name)
+}
+
+func stringValue(col arrow.Array, row int, name string) (string, error) {
+	if col.IsNull(row) {
+		return "", nil
+	}
+	switch c := col.(type) {
+	case *array.String:
+		return strings.Clone(c.Value(row)), nil
+	case *array.LargeString:
+		return strings.Clone(c.Value(row)), nil
+	case *array.Dictionary:
+		return stringValue(c.Dictionary(), c.GetValueIndex(row), name)
+	default:
+		return "", fmt.Errorf("column %q of type %s is not a string column", name, col.DataType())
+	}
+}
+
+// Int64 returns the value of an integer column. Nulls are returned as 0.
+func (v RowView) Int64(name string) (int64, error) {
+	col, err := v.column(name)
+	if err != nil {
+		return 0, err
+	}
+	if col.IsNull(v.row) {
+		return 0, nil
+	}
+	switch c := col.(type) {
+	case *array.Int8:
+		return int64(c.Value(v.row)), nil
+	case *array.Int16:
+		return int64(c.Value(v.row)), nil
+	case *array.Int32:
+		return int64(c.Value(v.row)), nil
+	case *array.Int64:
+		return c.Value(v.row), nil
+	case *array.Uint8:
+		return int64(c.Value(v.row)), nil
+	case *array.Uint16:
+		return int64(c.Value(v.row)), nil
+	case *array.Uint32:
+		return int64(c.Value(v.row)), nil
+	case *array.Uint64:
+		return int64(c.Value(v.row)), nil
+	default:
+		return 0, fmt.Errorf("column %q of type %s is not an integer column", name, col.DataType())
+	}
+}
+
+// Float64 returns the value of a floating point column, such as _distance. Nulls
+// are returned as 0.
+func (v RowView) Float64(name string) (float64, error) {
+	col, err := v.column(name)
+	if err != nil {
+		return 0, err
+	}
+	if col.IsNull(v.row) {
+		return 0, nil
+	}
+	switch c := col.(type) {
+	case *array.Float32:
+		return float64(c.Value(v.row)), nil
+	case *array.Float64:
+		return c.Value(v.row), nil
+	default:
+		return 0, fmt.Errorf("column %q of type %s is not a floating point column", name, col.DataType())
+	}
+}
+
+// Bool returns the value of a boolean column. Nulls are returned as false.
+func (v RowView) Bool(name string) (bool, error) {
+	col, err := v.column(name)
+	if err != nil {
+		return false, err
+	}
+	c, ok := col.(*array.Boolean)
+	if !ok {
+		return false, fmt.Errorf("column %q of type %s is not a boolean column", name, col.DataType())
+	}
+	return !c.IsNull(v.row) && c.Value(v.row), nil
+}
+
+// Float32s returns a copy of the row's vector in a list column of float32, such as
+// an embedding column. Nulls are returned as nil.
+func (v RowView) Float32s(name string) ([]float32, error) {
+	col, err := v.column(name)
+	if err != nil {
+		return nil, err
+	}
+	if col.IsNull(v.row) {
+		return nil, nil
+	}
+	var values arrow.Array
+	var start, end int64
+	switch c := col.(type) {
+	case *array.FixedSizeList:
+		values = c.ListValues()
+		start, end = c.ValueOffsets(v.row)
+	case *array.List:
+		values = c.ListValues()
+		start, end = c.ValueOffsets(v.row)
+	default:
+		return nil, fmt.Errorf("column %q of type %s is not a list column", name, col.DataType())
+	}
+	floats, ok := values.(*array.Float32)
+	if !ok {
+		return nil, fmt.Errorf("column %q of type %s is not a float32 list column", name, col.DataType())
+	}
+	vector := make([]float32, end-start)
+	copy(vector, floats.Float32Values()[start:end])
+	return vector, nil
+}
@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: Copyright The LanceDB Authors
+
+package lancedb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+// buildResultBatch builds a batch of rows [start, start+n) with the given allocator
+func buildResultBatch(t *testing.T, mem memory.Allocator, start, n int) arrow.Record {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "text", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "vector", Type: arrow.FixedSizeListOf(2, arrow.PrimitiveTypes.Float32)},
+		{Name: "_distance", Type: arrow.PrimitiveTypes.Float32},
+	}, nil)
+
+	builder := array.NewRecordBuilder(mem, schema)
+	defer builder.Release()
+	ids := builder.Field(0).(*array.Int32Builder)
+	texts := builder.Field(1).(*array.StringBuilder)
+	vectors := builder.Field(2).(*array.FixedSizeListBuilder)
+	vectorValues := vectors.ValueBuilder().(*array.Float32Builder)
+	distances := builder.Field(3).(*array.Float32Builder)
+	for i := start; i < start+n; i++ {
+		ids.Append(int32(i))
+		if i%3 == 0 {
+			texts.AppendNull()
+		} else {
+			texts.Append(fmt.Sprintf("doc-%d", i))
+		}
+		vectors.Append(true)
+		vectorValues.AppendValues([]float32{float32(i), float32(-i)}, nil)
+		distances.Append(float32(i) / 2)
+	}
+	return builder.NewRecord()
+}
+
+func TestQueryResultsEachRow(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	results := NewQueryResults([]arrow.Record{
+		buildResultBatch(t, mem, 0, 4),
+		buildResultBatch(t, mem, 4, 3),
+		buildResultBatch(t, mem, 7, 5),
+	})
+
+	if results.Rows() != 12 {
+		t.Fatalf("Expected 12 rows, got %d", results.Rows())
+	}
+	columns, err := results.Column("id")
+	if err != nil {
+		t.Fatalf("Column failed: %v", err)
+	}
+	if len(columns) != 3 || columns[1].Len() != 3 {
+		t.Fatalf("Expected one id column per batch, got %d", len(columns))
+	}
+	if _, err := results.Column("missing"); err == nil {
+		t.Fatal("Expected an error for a missing column")
+	}
+
+	var texts []string
+	var vectors [][]float32
+	err = results.EachRow(func(row RowView) error {
+		id, err := row.Int64("id")
+		if err != nil {
+			return err
+		}
+		if id != int64(row.Index()) {
+			return fmt.Errorf("row %d has id %d", row.Index(), id)
+		}
+		text, err := row.String("text")
+		if err != nil {
+			return err
+		}
+		if row.IsNull("text") != (id%3 == 0) {
+			return fmt.Errorf("row %d: unexpected null state", id)
+		}
+		distance, err := row.Float64("_distance")
+		if err != nil {
+			return err
+		}
+		if distance != float64(id)/2 {
+			return fmt.Errorf("row %d has distance %v", id, distance)
+		}
+		vector, err := row.Float32s("vector")
+		if err != nil {
+			return err
+		}
+		if _, err := row.Bool("id"); err == nil {
+			return errors.New("expected an error reading an integer column as bool")
+		}
+		texts = append(texts, text)
+		vectors = append(vectors, vector)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachRow failed: %v", err)
+	}
+
+	// Stopping early returns the callback's error
+	stop := errors.New("stop")
+	visited := 0
+	err = results.EachRow(func(row RowView) error {
+		visited++
+		if row.Index() == 5 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || visited != 6 {
+		t.Fatalf("Expected EachRow to stop after 6 rows with the callback's error, got %d rows and %v", visited, err)
+	}
+
+	results.Close()
+	results.Close()
+
+	// Values read from rows are copies, valid after Close
+	if len(texts) != 12 || texts[0] != "" || texts[4] != "doc-4" || texts[11] != "doc-11" {
+		t.Fatalf("Unexpected texts: %v", texts)
+	}
+	if vectors[8][0] != 8 || vectors[8][1] != -8 {
+		t.Fatalf("Unexpected vector for row 8: %v", vectors[8])
+	}
+
+	if results.Rows() != 0 {
+		t.Fatalf("Expected no rows after Close, got %d", results.Rows())
+	}
+	if err := results.EachRow(func(RowView) error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed after Close, got %v", err)
+	}
+}
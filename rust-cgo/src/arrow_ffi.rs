@@ -186,7 +186,7 @@ pub unsafe extern "C" fn lancedb_arrow_schema_release(schema: *mut FFI_ArrowSche
 #[cfg(test)]
 mod tests {
     use super::*;
-    use arrow_array::{Int32Array, RecordBatch, StringArray};
+    use arrow_array::{BinaryArray, Int32Array, LargeStringArray, RecordBatch, StringArray};
     use arrow_schema::{DataType, Field, Schema};
     use std::sync::Arc;
 
@@ -227,6 +227,40 @@ mod tests {
         }
     }
 
+    #[test]
+    fn test_roundtrip_binary_and_large_string() {
+        let schema = Arc::new(Schema::new(vec![
+            Field::new("blob", DataType::Binary, true),
+            Field::new("body", DataType::LargeUtf8, true),
+        ]));
+
+        let blob: Vec<u8> = (0..=255).collect();
+        let blob_array = BinaryArray::from(vec![Some(&blob[..]), None, Some(&b""[..])]);
+        let body = "a".repeat(1 << 20);
+        let body_array = LargeStringArray::from(vec![Some(body.as_str()), Some(""), None]);
+
+        let batch = RecordBatch::try_new(
+            schema.clone(),
+            vec![Arc::new(blob_array), Arc::new(body_array)],
+        )
+        .unwrap();
+
+        let mut array_out = std::mem::MaybeUninit::<FFI_ArrowArray>::uninit();
+        let mut schema_out = std::mem::MaybeUninit::<FFI_ArrowSchema>::uninit();
+
+        unsafe {
+            export_record_batch_to_c(&batch, array_out.as_mut_ptr(), schema_out.as_mut_ptr())
+                .unwrap();
+
+            let imported_batch =
+                import_record_batch_from_c(array_out.as_mut_ptr(), schema_out.as_mut_ptr())
+                    .unwrap();
+
+            assert_eq!(imported_batch.schema(), schema);
+            assert_eq!(imported_batch, batch);
+        }
+    }
+
     #[test]
     fn test_roundtrip_schema() {
         let schema = Schema::new(vec![
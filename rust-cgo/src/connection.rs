@@ -34,6 +34,21 @@ impl ConnectionHandle {
         }
         Ok(RT.block_on(op.execute())?)
     }
+
+    /// Drop a table. A table that doesn't exist is reported as TableNotFound, whichever
+    /// not-found error the storage backend raised for its directory.
+    pub fn drop_table(&self, name: &str) -> Result<()> {
+        match RT.block_on(self.inner.drop_table(name)) {
+            Ok(()) => Ok(()),
+            Err(lancedb::Error::Lance {
+                source: lance::Error::NotFound { .. } | lance::Error::DatasetNotFound { .. },
+            }) => Err(lancedb::Error::TableNotFound {
+                name: name.to_string(),
+            }
+            .into()),
+            Err(err) => Err(err.into()),
+        }
+    }
 }
 
 // C API for connections
@@ -109,9 +124,7 @@ pub extern "C" fn lancedb_connection_table_names(
     let table_names = match connection.table_names(start_after_opt, limit_opt) {
         Ok(names) => names,
         Err(err) => {
-            let error_msg = format!("{}", err);
-            let c_error = CString::new(error_msg).unwrap();
-            crate::lancedb_set_last_error(c_error.as_ptr());
+            crate::set_last_error(&err);
             return -1;
         }
     };
@@ -150,6 +163,39 @@ pub extern "C" fn lancedb_connection_table_names(
     0
 }
 
+/// Drop a table from the database, deleting all of its data.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_connection_drop_table(
+    handle: *const ConnectionHandle,
+    name: *const c_char,
+) -> c_int {
+    if handle.is_null() || name.is_null() {
+        let error_msg = "connection handle and table name cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let connection = unsafe { &*handle };
+    let name_str = match unsafe { CStr::from_ptr(name) }.to_str() {
+        Ok(s) => s,
+        Err(err) => {
+            let error_msg = format!("invalid UTF-8 in table name: {}", err);
+            let c_error = CString::new(error_msg).unwrap();
+            crate::lancedb_set_last_error(c_error.as_ptr());
+            return -1;
+        }
+    };
+
+    if let Err(err) = connection.drop_table(name_str) {
+        crate::set_last_error(&err);
+        return -1;
+    }
+
+    0
+}
+
 /// Free an array of C strings allocated by lancedb_connection_table_names.
 #[no_mangle]
 pub extern "C" fn lancedb_free_string_array(array: *mut *mut c_char, count: c_int) {
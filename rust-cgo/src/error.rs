@@ -1,6 +1,7 @@
 // SPDX-License-Identifier: Apache-2.0
 // SPDX-FileCopyrightText: Copyright The LanceDB Authors
 
+use std::os::raw::c_int;
 use std::str::Utf8Error;
 
 use arrow_schema::ArrowError;
@@ -23,6 +24,13 @@ pub enum Error {
     Arrow { message: String, location: Location },
     #[snafu(display("Index error: {message}, {location}"))]
     Index { message: String, location: Location },
+    #[snafu(display("Index not found: {identity}, {location}"))]
+    IndexNotFound {
+        identity: String,
+        location: Location,
+    },
+    #[snafu(display("Schema error: {message}, {location}"))]
+    Schema { message: String, location: Location },
     #[snafu(display("JSON error: {message}, {location}"))]
     JSON { message: String, location: Location },
     #[snafu(display("Dataset at path {path} was not found, {location}"))]
@@ -52,6 +60,8 @@ pub enum Error {
     NullPointer { location: Location },
     #[snafu(display("UTF-8 conversion error: {message}, {location}"))]
     Utf8Error { message: String, location: Location },
+    #[snafu(display("Query was cancelled, {location}"))]
+    Cancelled { location: Location },
 }
 
 pub type Result<T> = std::result::Result<T, Error>;
@@ -101,6 +111,10 @@ impl From<lance::Error> for Error {
             lance::Error::IO { source, location } => Self::IO { source, location },
             lance::Error::Arrow { message, location } => Self::Arrow { message, location },
             lance::Error::Index { message, location } => Self::Index { message, location },
+            lance::Error::IndexNotFound { identity, location } => {
+                Self::IndexNotFound { identity, location }
+            }
+            lance::Error::Schema { message, location } => Self::Schema { message, location },
             lance::Error::InvalidInput { source, location } => Self::InvalidArgument {
                 message: source.to_string(),
                 location,
@@ -149,6 +163,58 @@ impl From<lancedb::Error> for Error {
     }
 }
 
+// Error codes returned by lancedb_get_last_error_code
+pub const ERROR_CODE_UNKNOWN: c_int = 0;
+pub const ERROR_CODE_INVALID_ARGUMENT: c_int = 1;
+pub const ERROR_CODE_TABLE_NOT_FOUND: c_int = 2;
+pub const ERROR_CODE_TABLE_ALREADY_EXISTS: c_int = 3;
+pub const ERROR_CODE_INVALID_TABLE_NAME: c_int = 4;
+pub const ERROR_CODE_INVALID_SCHEMA: c_int = 5;
+pub const ERROR_CODE_INDEX_NOT_FOUND: c_int = 6;
+pub const ERROR_CODE_IO: c_int = 7;
+
+/// Classifies an error into one of the codes reported to callers
+pub trait ErrorCode {
+    fn error_code(&self) -> c_int;
+}
+
+impl ErrorCode for Error {
+    fn error_code(&self) -> c_int {
+        match self {
+            Self::InvalidArgument { .. } | Self::NullPointer { .. } | Self::Utf8Error { .. } => {
+                ERROR_CODE_INVALID_ARGUMENT
+            }
+            Self::TableNotFound { .. } | Self::DatasetNotFound { .. } => ERROR_CODE_TABLE_NOT_FOUND,
+            Self::TableAlreadyExists { .. } | Self::DatasetAlreadyExists { .. } => {
+                ERROR_CODE_TABLE_ALREADY_EXISTS
+            }
+            Self::InvalidTableName { .. } => ERROR_CODE_INVALID_TABLE_NAME,
+            Self::Schema { .. } => ERROR_CODE_INVALID_SCHEMA,
+            Self::IndexNotFound { .. } => ERROR_CODE_INDEX_NOT_FOUND,
+            Self::IO { .. } => ERROR_CODE_IO,
+            _ => ERROR_CODE_UNKNOWN,
+        }
+    }
+}
+
+impl ErrorCode for lancedb::Error {
+    fn error_code(&self) -> c_int {
+        match self {
+            lancedb::Error::InvalidInput { .. } => ERROR_CODE_INVALID_ARGUMENT,
+            lancedb::Error::TableNotFound { .. } => ERROR_CODE_TABLE_NOT_FOUND,
+            lancedb::Error::TableAlreadyExists { .. } => ERROR_CODE_TABLE_ALREADY_EXISTS,
+            lancedb::Error::InvalidTableName { .. } => ERROR_CODE_INVALID_TABLE_NAME,
+            _ => ERROR_CODE_UNKNOWN,
+        }
+    }
+}
+
+impl ErrorCode for Utf8Error {
+    fn error_code(&self) -> c_int {
+        ERROR_CODE_INVALID_ARGUMENT
+    }
+}
+
 trait ToSnafuLocation {
     fn to_snafu_location(&'static self) -> snafu::Location;
 }
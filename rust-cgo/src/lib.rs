@@ -12,9 +12,7 @@ macro_rules! c_result {
         match $result {
             Ok(value) => value,
             Err(err) => {
-                let error_msg = format!("{}", err);
-                let c_error = std::ffi::CString::new(error_msg).unwrap();
-                $crate::lancedb_set_last_error(c_error.as_ptr());
+                $crate::set_last_error(&err);
                 return std::ptr::null_mut();
             }
         }
@@ -27,9 +25,7 @@ macro_rules! c_result_int {
         match $result {
             Ok(value) => value as std::os::raw::c_int,
             Err(err) => {
-                let error_msg = format!("{}", err);
-                let c_error = std::ffi::CString::new(error_msg).unwrap();
-                $crate::lancedb_set_last_error(c_error.as_ptr());
+                $crate::set_last_error(&err);
                 return -1;
             }
         }
@@ -41,8 +37,9 @@ mod connection;
 pub mod error;
 mod query;
 mod table;
+mod txn;
 
-pub use error::{Error, Result};
+pub use error::{Error, ErrorCode, Result};
 
 lazy_static! {
     static ref RT: tokio::runtime::Runtime = tokio::runtime::Builder::new_multi_thread()
@@ -87,13 +84,30 @@ pub extern "C" fn lancedb_free_string(s: *mut c_char) {
     }
 }
 
-// Thread-local storage for error messages
+/// Get the code classifying the last error (see the ERROR_CODE_* constants in error.rs).
+/// Errors recorded without a classification report ERROR_CODE_UNKNOWN.
+#[no_mangle]
+pub extern "C" fn lancedb_get_last_error_code() -> c_int {
+    LAST_ERROR_CODE.with(|c| c.get())
+}
+
+// Thread-local storage for error messages and their codes
 thread_local! {
     static LAST_ERROR: std::cell::RefCell<Option<CString>> = std::cell::RefCell::new(None);
+    static LAST_ERROR_CODE: std::cell::Cell<c_int> = std::cell::Cell::new(error::ERROR_CODE_UNKNOWN);
+}
+
+/// Record an error's message and code as the last error
+pub fn set_last_error<E: std::fmt::Display + ErrorCode>(err: &E) {
+    let error_msg = format!("{}", err);
+    let c_error = CString::new(error_msg).unwrap();
+    lancedb_set_last_error(c_error.as_ptr());
+    LAST_ERROR_CODE.with(|c| c.set(err.error_code()));
 }
 
 #[no_mangle]
 pub extern "C" fn lancedb_set_last_error(error: *const c_char) {
+    LAST_ERROR_CODE.with(|c| c.set(error::ERROR_CODE_UNKNOWN));
     if error.is_null() {
         LAST_ERROR.with(|e| *e.borrow_mut() = None);
         return;
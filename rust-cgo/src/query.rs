@@ -3,39 +3,44 @@
 
 use std::ffi::{CStr, CString};
 use std::os::raw::{c_char, c_float, c_int};
+use std::sync::atomic::{AtomicBool, Ordering};
+use std::sync::Arc;
 
 use arrow::ffi::FFI_ArrowArray;
 use arrow::ffi::FFI_ArrowSchema;
 use arrow_array::RecordBatch;
+use futures::future::{self, Either};
 use futures::stream::BoxStream;
 use futures::StreamExt;
 
 use crate::arrow_ffi::export_record_batch_to_c;
 use crate::error::Result;
 use crate::RT;
-use lancedb::query::{ExecutableQuery, Query as LanceQuery, QueryBase, VectorQuery};
+use lance::dataset::scanner::ColumnOrdering;
+use lancedb::index::scalar::FullTextSearchQuery;
+use lancedb::query::{
+    ExecutableQuery, Query as LanceQuery, QueryBase, QueryExecutionOptions, VectorQuery,
+};
+use lancedb::table::Table;
 use lancedb::DistanceType;
+use tokio::sync::Notify;
 
-/// Opaque handle to a LanceDB query
+/// The query being built
 /// Can be either a regular Query or a VectorQuery
-pub enum QueryHandle {
+pub enum QueryKind {
     Plain(LanceQuery),
     Vector(VectorQuery),
 }
 
-impl QueryHandle {
-    pub fn new(query: LanceQuery) -> Self {
-        Self::Plain(query)
-    }
-
+impl QueryKind {
     pub fn nearest_to(&mut self, vector: Vec<f32>) -> Result<()> {
         match self {
-            QueryHandle::Plain(q) => {
+            QueryKind::Plain(q) => {
                 let vector_query = q.clone().nearest_to(vector)?;
-                *self = QueryHandle::Vector(vector_query);
+                *self = QueryKind::Vector(vector_query);
                 Ok(())
             }
-            QueryHandle::Vector(_) => Err(crate::error::Error::InvalidArgument {
+            QueryKind::Vector(_) => Err(crate::error::Error::InvalidArgument {
                 message: "nearest_to can only be called once on a query".to_string(),
                 location: snafu::Location::new(file!(), line!(), column!()),
             }),
@@ -44,25 +49,54 @@ impl QueryHandle {
 
     pub fn distance_type(&mut self, distance_type: DistanceType) -> Result<()> {
         match self {
-            QueryHandle::Vector(q) => {
-                *self = QueryHandle::Vector(q.clone().distance_type(distance_type));
+            QueryKind::Vector(q) => {
+                *self = QueryKind::Vector(q.clone().distance_type(distance_type));
                 Ok(())
             }
-            QueryHandle::Plain(_) => Err(crate::error::Error::InvalidArgument {
+            QueryKind::Plain(_) => Err(crate::error::Error::InvalidArgument {
                 message: "distance_type can only be set on vector queries".to_string(),
                 location: snafu::Location::new(file!(), line!(), column!()),
             }),
         }
     }
 
+    pub fn bypass_vector_index(&mut self) -> Result<()> {
+        match self {
+            QueryKind::Vector(q) => {
+                *self = QueryKind::Vector(q.clone().bypass_vector_index());
+                Ok(())
+            }
+            QueryKind::Plain(_) => Err(crate::error::Error::InvalidArgument {
+                message: "bypass_vector_index can only be set on vector queries".to_string(),
+                location: snafu::Location::new(file!(), line!(), column!()),
+            }),
+        }
+    }
+
+    pub fn full_text_search(&mut self, query: &str) -> Result<()> {
+        match self {
+            QueryKind::Plain(q) => {
+                *self = QueryKind::Plain(
+                    q.clone()
+                        .full_text_search(FullTextSearchQuery::new(query.to_string())),
+                );
+                Ok(())
+            }
+            QueryKind::Vector(_) => Err(crate::error::Error::InvalidArgument {
+                message: "full_text_search cannot be combined with nearest_to".to_string(),
+                location: snafu::Location::new(file!(), line!(), column!()),
+            }),
+        }
+    }
+
     pub fn limit(&mut self, limit: usize) -> Result<()> {
         match self {
-            QueryHandle::Plain(q) => {
-                *self = QueryHandle::Plain(q.clone().limit(limit));
+            QueryKind::Plain(q) => {
+                *self = QueryKind::Plain(q.clone().limit(limit));
                 Ok(())
             }
-            QueryHandle::Vector(q) => {
-                *self = QueryHandle::Vector(q.clone().limit(limit));
+            QueryKind::Vector(q) => {
+                *self = QueryKind::Vector(q.clone().limit(limit));
                 Ok(())
             }
         }
@@ -70,12 +104,12 @@ impl QueryHandle {
 
     pub fn offset(&mut self, offset: usize) -> Result<()> {
         match self {
-            QueryHandle::Plain(q) => {
-                *self = QueryHandle::Plain(q.clone().offset(offset));
+            QueryKind::Plain(q) => {
+                *self = QueryKind::Plain(q.clone().offset(offset));
                 Ok(())
             }
-            QueryHandle::Vector(q) => {
-                *self = QueryHandle::Vector(q.clone().offset(offset));
+            QueryKind::Vector(q) => {
+                *self = QueryKind::Vector(q.clone().offset(offset));
                 Ok(())
             }
         }
@@ -83,12 +117,12 @@ impl QueryHandle {
 
     pub fn filter(&mut self, filter: &str) -> Result<()> {
         match self {
-            QueryHandle::Plain(q) => {
-                *self = QueryHandle::Plain(q.clone().only_if(filter));
+            QueryKind::Plain(q) => {
+                *self = QueryKind::Plain(q.clone().only_if(filter));
                 Ok(())
             }
-            QueryHandle::Vector(q) => {
-                *self = QueryHandle::Vector(q.clone().only_if(filter));
+            QueryKind::Vector(q) => {
+                *self = QueryKind::Vector(q.clone().only_if(filter));
                 Ok(())
             }
         }
@@ -96,39 +130,226 @@ impl QueryHandle {
 
     pub fn select(&mut self, columns: Vec<String>) -> Result<()> {
         match self {
-            QueryHandle::Plain(q) => {
+            QueryKind::Plain(q) => {
                 *self =
-                    QueryHandle::Plain(q.clone().select(lancedb::query::Select::columns(&columns)));
+                    QueryKind::Plain(q.clone().select(lancedb::query::Select::columns(&columns)));
                 Ok(())
             }
-            QueryHandle::Vector(q) => {
-                *self = QueryHandle::Vector(
-                    q.clone().select(lancedb::query::Select::columns(&columns)),
-                );
+            QueryKind::Vector(q) => {
+                *self =
+                    QueryKind::Vector(q.clone().select(lancedb::query::Select::columns(&columns)));
                 Ok(())
             }
         }
     }
 
+    async fn open_stream(
+        &self,
+        options: QueryExecutionOptions,
+    ) -> lancedb::Result<BoxStream<'static, lancedb::Result<RecordBatch>>> {
+        match self {
+            QueryKind::Plain(q) => q.execute_with_options(options).await,
+            QueryKind::Vector(q) => q.execute_with_options(options).await,
+        }
+    }
+}
+
+/// A flag that stops a running query when set.
+/// Shared between the caller, which cancels it, and the queries it is attached to.
+#[derive(Default)]
+pub struct CancelToken {
+    cancelled: AtomicBool,
+    notify: Notify,
+}
+
+impl CancelToken {
+    pub fn cancel(&self) {
+        self.cancelled.store(true, Ordering::SeqCst);
+        self.notify.notify_waiters();
+    }
+
+    /// Completes once the token is cancelled
+    async fn cancelled(&self) {
+        loop {
+            // Register for the notification before checking, so a cancel in between isn't missed
+            let notified = self.notify.notified();
+            if self.cancelled.load(Ordering::SeqCst) {
+                return;
+            }
+            notified.await;
+        }
+    }
+}
+
+/// The parameters of a plain query, kept so that an ordered query can run as a scan of
+/// the table's dataset; LanceDB's query builder can't sort its results
+#[derive(Default)]
+struct ScanRequest {
+    filter: Option<String>,
+    columns: Option<Vec<String>>,
+    limit: Option<i64>,
+    offset: Option<i64>,
+    full_text_search: bool,
+    order_by: Vec<ColumnOrdering>,
+}
+
+/// Opaque handle to a LanceDB query and the options used to execute it
+pub struct QueryHandle {
+    kind: QueryKind,
+    table: Table,
+    scan: ScanRequest,
+    options: QueryExecutionOptions,
+    cancel: Option<Arc<CancelToken>>,
+}
+
+impl QueryHandle {
+    pub fn new(table: Table) -> Self {
+        Self {
+            kind: QueryKind::Plain(table.query()),
+            table,
+            scan: ScanRequest::default(),
+            options: QueryExecutionOptions::default(),
+            cancel: None,
+        }
+    }
+
+    pub fn limit(&mut self, limit: usize) -> Result<()> {
+        self.kind.limit(limit)?;
+        self.scan.limit = Some(limit as i64);
+        Ok(())
+    }
+
+    pub fn offset(&mut self, offset: usize) -> Result<()> {
+        self.kind.offset(offset)?;
+        self.scan.offset = Some(offset as i64);
+        Ok(())
+    }
+
+    pub fn filter(&mut self, filter: &str) -> Result<()> {
+        self.kind.filter(filter)?;
+        self.scan.filter = Some(filter.to_string());
+        Ok(())
+    }
+
+    pub fn select(&mut self, columns: Vec<String>) -> Result<()> {
+        self.kind.select(columns.clone())?;
+        self.scan.columns = Some(columns);
+        Ok(())
+    }
+
+    pub fn full_text_search(&mut self, query: &str) -> Result<()> {
+        self.kind.full_text_search(query)?;
+        self.scan.full_text_search = true;
+        Ok(())
+    }
+
+    /// Sort the results by column. Later calls break ties of earlier ones.
+    /// Only plain queries can be ordered; vector and full-text searches are ranked.
+    pub fn order_by(&mut self, column: &str, ascending: bool) -> Result<()> {
+        self.scan.order_by.push(if ascending {
+            ColumnOrdering::asc_nulls_first(column.to_string())
+        } else {
+            ColumnOrdering::desc_nulls_last(column.to_string())
+        });
+        Ok(())
+    }
+
+    pub fn batch_size(&mut self, rows: u32) {
+        self.options.max_batch_length = rows;
+    }
+
+    pub fn set_cancel_token(&mut self, token: Option<Arc<CancelToken>>) {
+        self.cancel = token;
+    }
+
     pub fn execute(&self) -> Result<Vec<RecordBatch>> {
-        let stream = match self {
-            QueryHandle::Plain(q) => RT.block_on(q.execute())?,
-            QueryHandle::Vector(q) => RT.block_on(q.execute())?,
+        let collect = async {
+            use futures::TryStreamExt;
+            let stream = self.open_stream().await?;
+            Ok::<_, crate::error::Error>(stream.try_collect::<Vec<_>>().await?)
         };
 
-        let batches: Vec<RecordBatch> = RT.block_on(async {
-            use futures::TryStreamExt;
-            stream.try_collect::<Vec<_>>().await
-        })?;
-        Ok(batches)
+        let token = match &self.cancel {
+            Some(token) => token,
+            None => return RT.block_on(collect),
+        };
+
+        // Stop as soon as either the query finishes or the token is cancelled
+        RT.block_on(async {
+            let cancelled = token.cancelled();
+            futures::pin_mut!(collect, cancelled);
+            match future::select(collect, cancelled).await {
+                Either::Left((batches, _)) => batches,
+                Either::Right(_) => Err(crate::error::Error::Cancelled {
+                    location: snafu::Location::new(file!(), line!(), column!()),
+                }),
+            }
+        })
     }
 
     pub fn execute_stream(&self) -> Result<BoxStream<'static, lancedb::Result<RecordBatch>>> {
-        let stream = match self {
-            QueryHandle::Plain(q) => RT.block_on(q.execute())?,
-            QueryHandle::Vector(q) => RT.block_on(q.execute())?,
-        };
-        Ok(stream)
+        RT.block_on(self.open_stream())
+    }
+
+    async fn open_stream(&self) -> Result<BoxStream<'static, lancedb::Result<RecordBatch>>> {
+        if self.scan.order_by.is_empty() {
+            return Ok(self.kind.open_stream(self.options.clone()).await?);
+        }
+        if matches!(self.kind, QueryKind::Vector(_)) || self.scan.full_text_search {
+            return Err(crate::error::Error::InvalidArgument {
+                message: "order_by cannot be combined with nearest_to or full_text_search"
+                    .to_string(),
+                location: snafu::Location::new(file!(), line!(), column!()),
+            });
+        }
+        self.open_ordered_stream().await
+    }
+
+    /// Run the query as a sorted scan of the dataset the table handle sees, so an
+    /// ordered query reads the same version as the handle's other queries
+    async fn open_ordered_stream(
+        &self,
+    ) -> Result<BoxStream<'static, lancedb::Result<RecordBatch>>> {
+        let wrapper = self
+            .table
+            .dataset()
+            .ok_or_else(|| crate::error::Error::InvalidArgument {
+                message: "ordered queries are not supported on remote tables".to_string(),
+                location: snafu::Location::new(file!(), line!(), column!()),
+            })?;
+
+        let dataset = wrapper.get().await?;
+        let mut scanner = dataset.scan();
+        if let Some(filter) = &self.scan.filter {
+            scanner.filter(filter)?;
+        }
+        if let Some(columns) = &self.scan.columns {
+            scanner.project(columns)?;
+        }
+        scanner.limit(self.scan.limit, self.scan.offset)?;
+        scanner.order_by(Some(self.scan.order_by.clone()))?;
+        if self.options.max_batch_length > 0 {
+            scanner.batch_size(self.options.max_batch_length as usize);
+        }
+
+        let stream = scanner.try_into_stream().await?;
+        Ok(stream
+            .map(|batch| batch.map_err(lancedb::Error::from))
+            .boxed())
+    }
+}
+
+impl std::ops::Deref for QueryHandle {
+    type Target = QueryKind;
+
+    fn deref(&self) -> &QueryKind {
+        &self.kind
+    }
+}
+
+impl std::ops::DerefMut for QueryHandle {
+    fn deref_mut(&mut self) -> &mut QueryKind {
+        &mut self.kind
     }
 }
 
@@ -150,8 +371,7 @@ pub extern "C" fn lancedb_query_new(table: *const super::table::TableHandle) ->
     }
 
     let table = unsafe { &*table };
-    let query = table.inner.query();
-    let handle = QueryHandle::new(query);
+    let handle = QueryHandle::new(table.inner.clone());
     Box::into_raw(Box::new(handle))
 }
 
@@ -189,9 +409,7 @@ pub extern "C" fn lancedb_query_nearest_to(
     match query.nearest_to(vector_vec) {
         Ok(_) => 0,
         Err(err) => {
-            let error_msg = format!("{}", err);
-            let c_error = CString::new(error_msg).unwrap();
-            crate::lancedb_set_last_error(c_error.as_ptr());
+            crate::set_last_error(&err);
             -1
         }
     }
@@ -229,14 +447,98 @@ pub extern "C" fn lancedb_query_distance_type(
     match query.distance_type(dist_type) {
         Ok(_) => 0,
         Err(err) => {
-            let error_msg = format!("{}", err);
-            let c_error = CString::new(error_msg).unwrap();
-            crate::lancedb_set_last_error(c_error.as_ptr());
+            crate::set_last_error(&err);
             -1
         }
     }
 }
 
+/// Search every row with a flat scan instead of using the vector index.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_query_bypass_vector_index(handle: *mut QueryHandle) -> c_int {
+    if handle.is_null() {
+        let error_msg = "handle cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let query = unsafe { &mut *handle };
+
+    match query.bypass_vector_index() {
+        Ok(_) => 0,
+        Err(err) => {
+            crate::set_last_error(&err);
+            -1
+        }
+    }
+}
+
+/// Set the maximum number of rows in each batch returned by the query.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_query_batch_size(handle: *mut QueryHandle, rows: c_int) -> c_int {
+    if handle.is_null() || rows <= 0 {
+        let error_msg = "handle cannot be null and rows must be positive";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let query = unsafe { &mut *handle };
+    query.batch_size(rows as u32);
+    0
+}
+
+/// Create a cancellation token that can be attached to queries.
+/// The token must be freed with lancedb_cancel_token_free.
+#[no_mangle]
+pub extern "C" fn lancedb_cancel_token_new() -> *mut Arc<CancelToken> {
+    Box::into_raw(Box::new(Arc::new(CancelToken::default())))
+}
+
+/// Cancel every query the token is attached to.
+/// Running executions return an error; later executions fail immediately.
+#[no_mangle]
+pub extern "C" fn lancedb_cancel_token_cancel(token: *const Arc<CancelToken>) {
+    if !token.is_null() {
+        let token = unsafe { &*token };
+        token.cancel();
+    }
+}
+
+/// Free a cancellation token. Queries it is attached to keep their own reference.
+#[no_mangle]
+pub extern "C" fn lancedb_cancel_token_free(token: *mut Arc<CancelToken>) {
+    if !token.is_null() {
+        unsafe {
+            let _ = Box::from_raw(token);
+        }
+    }
+}
+
+/// Attach a cancellation token to a query, replacing any previous token.
+/// Pass a null token to detach the current one.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_query_set_cancel_token(
+    handle: *mut QueryHandle,
+    token: *const Arc<CancelToken>,
+) -> c_int {
+    if handle.is_null() {
+        let error_msg = "handle cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let query = unsafe { &mut *handle };
+    let token = unsafe { token.as_ref() }.map(Arc::clone);
+    query.set_cancel_token(token);
+    0
+}
+
 /// Set the maximum number of results to return.
 /// Returns 0 on success, -1 on failure.
 #[no_mangle]
@@ -253,9 +555,7 @@ pub extern "C" fn lancedb_query_limit(handle: *mut QueryHandle, limit: c_int) ->
     match query.limit(limit as usize) {
         Ok(_) => 0,
         Err(err) => {
-            let error_msg = format!("{}", err);
-            let c_error = CString::new(error_msg).unwrap();
-            crate::lancedb_set_last_error(c_error.as_ptr());
+            crate::set_last_error(&err);
             -1
         }
     }
@@ -277,9 +577,44 @@ pub extern "C" fn lancedb_query_offset(handle: *mut QueryHandle, offset: c_int)
     match query.offset(offset as usize) {
         Ok(_) => 0,
         Err(err) => {
-            let error_msg = format!("{}", err);
+            crate::set_last_error(&err);
+            -1
+        }
+    }
+}
+
+/// Sort the results of a plain query by a column; later calls break ties of earlier ones.
+/// ascending: nonzero for ascending order (nulls first), 0 for descending (nulls last)
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_query_order_by(
+    handle: *mut QueryHandle,
+    column: *const c_char,
+    ascending: c_int,
+) -> c_int {
+    if handle.is_null() || column.is_null() {
+        let error_msg = "handle and column cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let query = unsafe { &mut *handle };
+    let c_str = unsafe { CStr::from_ptr(column) };
+    let column_str = match c_str.to_str() {
+        Ok(s) => s,
+        Err(err) => {
+            let error_msg = format!("invalid UTF-8 in column name: {}", err);
             let c_error = CString::new(error_msg).unwrap();
             crate::lancedb_set_last_error(c_error.as_ptr());
+            return -1;
+        }
+    };
+
+    match query.order_by(column_str, ascending != 0) {
+        Ok(_) => 0,
+        Err(err) => {
+            crate::set_last_error(&err);
             -1
         }
     }
@@ -309,6 +644,42 @@ pub extern "C" fn lancedb_query_filter(handle: *mut QueryHandle, filter: *const
     };
 
     match query.filter(filter_str) {
+        Ok(_) => 0,
+        Err(err) => {
+            crate::set_last_error(&err);
+            -1
+        }
+    }
+}
+
+/// Run the query as a full-text search over columns with an FTS index.
+/// Results include a `_score` column (BM25, higher is better).
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_query_full_text_search(
+    handle: *mut QueryHandle,
+    query: *const c_char,
+) -> c_int {
+    if handle.is_null() || query.is_null() {
+        let error_msg = "handle and query cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let handle = unsafe { &mut *handle };
+    let c_str = unsafe { CStr::from_ptr(query) };
+    let query_str = match c_str.to_str() {
+        Ok(s) => s,
+        Err(err) => {
+            let error_msg = format!("invalid UTF-8 in query: {}", err);
+            let c_error = CString::new(error_msg).unwrap();
+            crate::lancedb_set_last_error(c_error.as_ptr());
+            return -1;
+        }
+    };
+
+    match handle.full_text_search(query_str) {
         Ok(_) => 0,
         Err(err) => {
             let error_msg = format!("{}", err);
@@ -363,9 +734,7 @@ pub extern "C" fn lancedb_query_select(
     match query.select(column_names) {
         Ok(_) => 0,
         Err(err) => {
-            let error_msg = format!("{}", err);
-            let c_error = CString::new(error_msg).unwrap();
-            crate::lancedb_set_last_error(c_error.as_ptr());
+            crate::set_last_error(&err);
             -1
         }
     }
@@ -393,9 +762,7 @@ pub extern "C" fn lancedb_query_execute(
     let batches = match query.execute() {
         Ok(b) => b,
         Err(err) => {
-            let error_msg = format!("{}", err);
-            let c_error = CString::new(error_msg).unwrap();
-            crate::lancedb_set_last_error(c_error.as_ptr());
+            crate::set_last_error(&err);
             return -1;
         }
     };
@@ -481,9 +848,7 @@ pub extern "C" fn lancedb_query_execute_stream(handle: *const QueryHandle) -> *m
     let stream = match query.execute_stream() {
         Ok(s) => s,
         Err(err) => {
-            let error_msg = format!("{}", err);
-            let c_error = CString::new(error_msg).unwrap();
-            crate::lancedb_set_last_error(c_error.as_ptr());
+            crate::set_last_error(&err);
             return std::ptr::null_mut();
         }
     };
@@ -1,22 +1,26 @@
 // SPDX-License-Identifier: Apache-2.0
 // SPDX-FileCopyrightText: Copyright The LanceDB Authors
 
+use std::collections::HashMap;
 use std::ffi::{CStr, CString};
 use std::os::raw::{c_char, c_int};
 use std::sync::Arc;
 
 use arrow::ffi::FFI_ArrowArray;
 use arrow::ffi::FFI_ArrowSchema;
-use arrow_array::{RecordBatch, RecordBatchIterator};
+use arrow_array::{new_null_array, RecordBatch, RecordBatchIterator};
 use arrow_schema::{DataType, Field, Schema};
 
 use crate::arrow_ffi::import_record_batch_from_c;
 use crate::error::Result;
 use crate::{c_result, RT};
+use lance::dataset::BatchUDF;
+use lancedb::connection::CreateTableMode;
+use lancedb::index::scalar::FtsIndexBuilder;
 use lancedb::index::vector::IvfPqIndexBuilder;
 use lancedb::index::{Index, IndexConfig};
 use lancedb::query::{ExecutableQuery, QueryBase};
-use lancedb::table::{AddDataMode, Table};
+use lancedb::table::{AddDataMode, NewColumnTransform, Table};
 use lancedb::DistanceType;
 
 /// Opaque handle to a LanceDB table
@@ -40,11 +44,34 @@ impl TableHandle {
         Ok(Self { inner: table })
     }
 
+    /// Create a table, or open it if it already exists. LanceDB opens the existing
+    /// table when the create fails because another writer created it first.
+    pub fn create_if_not_exists(
+        connection: &super::connection::ConnectionHandle,
+        name: &str,
+        schema: Arc<Schema>,
+    ) -> Result<Self> {
+        let table = RT.block_on(
+            connection
+                .inner
+                .create_empty_table(name, schema)
+                .mode(CreateTableMode::exist_ok(|builder| builder))
+                .execute(),
+        )?;
+        Ok(Self { inner: table })
+    }
+
     pub fn count_rows(&self) -> Result<i64> {
         let count = RT.block_on(self.inner.count_rows(None))?;
         Ok(count as i64)
     }
 
+    /// Count the rows matching a predicate
+    pub fn count_rows_filtered(&self, predicate: &str) -> Result<i64> {
+        let count = RT.block_on(self.inner.count_rows(Some(predicate.to_string())))?;
+        Ok(count as i64)
+    }
+
     pub fn add_data(&self, batch: RecordBatch, mode: AddDataMode) -> Result<()> {
         let schema = batch.schema();
         let reader = RecordBatchIterator::new(vec![Ok(batch)], schema);
@@ -79,7 +106,7 @@ impl TableHandle {
         Ok(batches)
     }
 
-    /// Create a vector index on a column
+    /// Create an index on a column (vector index, or full-text index for "FTS")
     pub fn create_index(
         &self,
         column: &str,
@@ -102,6 +129,7 @@ impl TableHandle {
                 Index::IvfPq(builder)
             }
             "AUTO" => Index::Auto,
+            "FTS" => Index::FTS(FtsIndexBuilder::default()),
             _ => {
                 return Err(crate::error::Error::InvalidArgument {
                     message: format!("Unsupported index type: {}", index_type),
@@ -132,6 +160,29 @@ impl TableHandle {
         Ok(())
     }
 
+    /// Get the version of the table this handle currently reads
+    pub fn version(&self) -> Result<u64> {
+        let version = RT.block_on(self.inner.version())?;
+        Ok(version)
+    }
+
+    /// URI of the table's dataset; a local path for tables on the local filesystem
+    pub fn uri(&self) -> &str {
+        self.inner.dataset_uri()
+    }
+
+    /// Pin the handle to a historical version (read-only until checkout_latest)
+    pub fn checkout(&self, version: u64) -> Result<()> {
+        RT.block_on(self.inner.checkout(version))?;
+        Ok(())
+    }
+
+    /// Return the handle to the latest version of the table
+    pub fn checkout_latest(&self) -> Result<()> {
+        RT.block_on(self.inner.checkout_latest())?;
+        Ok(())
+    }
+
     /// Optimize the table to reclaim space after deletions
     pub fn compact(&self) -> Result<()> {
         use lancedb::table::{OptimizeAction, CompactionOptions};
@@ -142,6 +193,112 @@ impl TableHandle {
         }))?;
         Ok(())
     }
+
+    /// Compact the table's fragments, then remove versions older than `older_than_secs`
+    /// seconds. Returns the combined statistics as a JSON object.
+    pub fn optimize(&self, older_than_secs: i64) -> Result<String> {
+        use lancedb::table::{CompactionOptions, OptimizeAction};
+
+        let compacted = RT.block_on(self.inner.optimize(OptimizeAction::Compact {
+            options: CompactionOptions::default(),
+            remap_options: None,
+        }))?;
+        let pruned = RT.block_on(self.inner.optimize(OptimizeAction::Prune {
+            older_than: Some(chrono::Duration::seconds(older_than_secs)),
+            delete_unverified: None,
+        }))?;
+
+        let (fragments_removed, fragments_added, files_removed, files_added) = compacted
+            .compaction
+            .map(|m| {
+                (
+                    m.fragments_removed,
+                    m.fragments_added,
+                    m.files_removed,
+                    m.files_added,
+                )
+            })
+            .unwrap_or_default();
+        let (bytes_removed, versions_removed) = pruned
+            .prune
+            .map(|p| (p.bytes_removed, p.old_versions))
+            .unwrap_or_default();
+
+        Ok(format!(
+            r#"{{"fragments_removed":{},"fragments_added":{},"files_removed":{},"files_added":{},"bytes_removed":{},"versions_removed":{}}}"#,
+            fragments_removed,
+            fragments_added,
+            files_removed,
+            files_added,
+            bytes_removed,
+            versions_removed
+        ))
+    }
+
+    /// Merge key/value pairs from a JSON object of strings into the table's config.
+    /// The config is stored in the dataset manifest, so this commits a new version.
+    /// The dataset is reached through the table's own handle, so the write uses the
+    /// connection's object store and session, and the handle sees the new version.
+    pub fn set_metadata(&self, metadata_json: &str) -> Result<()> {
+        let values: HashMap<String, String> = serde_json::from_str(metadata_json)?;
+        let wrapper = self
+            .inner
+            .dataset()
+            .ok_or_else(|| crate::error::Error::InvalidArgument {
+                message: "table metadata is not supported on remote tables".to_string(),
+                location: snafu::Location::new(file!(), line!(), column!()),
+            })?;
+
+        RT.block_on(async {
+            let mut dataset = wrapper.get_mut().await?;
+            dataset.update_config(values).await?;
+            Ok::<(), crate::error::Error>(())
+        })?;
+        Ok(())
+    }
+
+    /// Read the config of the latest table version as a JSON object of strings.
+    /// A handle checked out to an older version is left on that version.
+    pub fn metadata(&self) -> Result<String> {
+        let wrapper = self
+            .inner
+            .dataset()
+            .ok_or_else(|| crate::error::Error::InvalidArgument {
+                message: "table metadata is not supported on remote tables".to_string(),
+                location: snafu::Location::new(file!(), line!(), column!()),
+            })?;
+
+        let config = RT.block_on(async {
+            let mut latest = (*wrapper.get().await?).clone();
+            latest.checkout_latest().await?;
+            Ok::<_, crate::error::Error>(latest.manifest().config.clone())
+        })?;
+        Ok(serde_json::to_string(&config)?)
+    }
+
+    /// Add the fields of schema as new columns, filled with nulls for the existing rows
+    pub fn add_null_columns(&self, schema: Arc<Schema>) -> Result<()> {
+        let output_schema = schema.clone();
+        let mapper = move |batch: &RecordBatch| -> lance::Result<RecordBatch> {
+            let columns = output_schema
+                .fields()
+                .iter()
+                .map(|field| new_null_array(field.data_type(), batch.num_rows()))
+                .collect();
+            Ok(RecordBatch::try_new(output_schema.clone(), columns)?)
+        };
+        let transform = NewColumnTransform::BatchUDF(BatchUDF {
+            mapper: Box::new(mapper),
+            output_schema: schema,
+            result_checkpoint: None,
+        });
+
+        // Only the row count of each batch is needed, so read a single existing column
+        let existing = RT.block_on(self.inner.schema())?;
+        let read_columns = existing.fields().first().map(|f| vec![f.name().clone()]);
+        RT.block_on(self.inner.add_columns(transform, read_columns))?;
+        Ok(())
+    }
 }
 
 // C API for tables
@@ -225,9 +382,75 @@ pub extern "C" fn lancedb_table_count_rows(handle: *const TableHandle) -> i64 {
     match table.count_rows() {
         Ok(count) => count,
         Err(err) => {
-            let error_msg = format!("{}", err);
+            crate::set_last_error(&err);
+            -1
+        }
+    }
+}
+
+/// Count the rows matching a SQL-like predicate.
+/// Returns the count on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_table_count_rows_filtered(
+    handle: *const TableHandle,
+    predicate: *const c_char,
+) -> i64 {
+    if handle.is_null() || predicate.is_null() {
+        let error_msg = "table handle and predicate cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let table = unsafe { &*handle };
+    let predicate_str = match unsafe { CStr::from_ptr(predicate) }.to_str() {
+        Ok(s) => s,
+        Err(err) => {
+            let error_msg = format!("invalid UTF-8 in predicate: {}", err);
             let c_error = CString::new(error_msg).unwrap();
             crate::lancedb_set_last_error(c_error.as_ptr());
+            return -1;
+        }
+    };
+
+    match table.count_rows_filtered(predicate_str) {
+        Ok(count) => count,
+        Err(err) => {
+            crate::set_last_error(&err);
+            -1
+        }
+    }
+}
+
+/// Add the fields of an Arrow schema to a table as nullable columns, filled with nulls
+/// for the existing rows.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_table_add_columns(
+    handle: *const TableHandle,
+    schema: *mut FFI_ArrowSchema,
+) -> c_int {
+    if handle.is_null() || schema.is_null() {
+        let error_msg = "table handle and schema cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let table = unsafe { &*handle };
+
+    let schema = match unsafe { crate::arrow_ffi::import_schema_from_c(schema) } {
+        Ok(s) => s,
+        Err(err) => {
+            crate::set_last_error(&err);
+            return -1;
+        }
+    };
+
+    match table.add_null_columns(Arc::new(schema)) {
+        Ok(_) => 0,
+        Err(err) => {
+            crate::set_last_error(&err);
             -1
         }
     }
@@ -279,9 +502,7 @@ pub extern "C" fn lancedb_table_add(
     match table.add_data(batch, add_mode) {
         Ok(_) => 0,
         Err(err) => {
-            let error_msg = format!("{}", err);
-            let c_error = CString::new(error_msg).unwrap();
-            crate::lancedb_set_last_error(c_error.as_ptr());
+            crate::set_last_error(&err);
             -1
         }
     }
@@ -307,9 +528,7 @@ pub extern "C" fn lancedb_table_schema(
     let schema = match table.schema() {
         Ok(s) => s,
         Err(err) => {
-            let error_msg = format!("{}", err);
-            let c_error = CString::new(error_msg).unwrap();
-            crate::lancedb_set_last_error(c_error.as_ptr());
+            crate::set_last_error(&err);
             return -1;
         }
     };
@@ -358,6 +577,37 @@ pub extern "C" fn lancedb_table_create_with_schema(
     Box::into_raw(Box::new(handle))
 }
 
+/// Create a table with a custom schema, or open it if a table with that name already exists.
+/// The schema of an existing table is not checked against the given schema.
+/// Returns a pointer to TableHandle on success, null on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_table_create_if_not_exists(
+    connection: *const super::connection::ConnectionHandle,
+    name: *const c_char,
+    schema: *mut FFI_ArrowSchema,
+) -> *mut TableHandle {
+    if connection.is_null() || name.is_null() || schema.is_null() {
+        let error_msg = "connection, name, and schema cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return std::ptr::null_mut();
+    }
+
+    let connection = unsafe { &*connection };
+    let c_str = unsafe { CStr::from_ptr(name) };
+    let table_name = c_result!(c_str.to_str());
+
+    // Import the schema
+    let imported_schema = c_result!(unsafe { crate::arrow_ffi::import_schema_from_c(schema) });
+
+    let handle = c_result!(TableHandle::create_if_not_exists(
+        connection,
+        table_name,
+        Arc::new(imported_schema)
+    ));
+    Box::into_raw(Box::new(handle))
+}
+
 /// Read data from a table as Arrow C Data Interface structures.
 /// Returns the number of batches on success, -1 on failure.
 /// limit: maximum number of rows to read (-1 for no limit)
@@ -385,9 +635,7 @@ pub extern "C" fn lancedb_table_to_arrow(
     let batches = match table.to_arrow(limit_opt) {
         Ok(b) => b,
         Err(err) => {
-            let error_msg = format!("{}", err);
-            let c_error = CString::new(error_msg).unwrap();
-            crate::lancedb_set_last_error(c_error.as_ptr());
+            crate::set_last_error(&err);
             return -1;
         }
     };
@@ -570,9 +818,7 @@ pub extern "C" fn lancedb_table_list_indices(
     let indices = match table.list_indices() {
         Ok(idx) => idx,
         Err(err) => {
-            let error_msg = format!("{}", err);
-            let c_error = CString::new(error_msg).unwrap();
-            crate::lancedb_set_last_error(c_error.as_ptr());
+            crate::set_last_error(&err);
             return -1;
         }
     };
@@ -660,3 +906,221 @@ pub extern "C" fn lancedb_table_delete(
 
     0
 }
+
+/// Compact a table and prune versions older than the given number of seconds.
+/// Writes the optimization statistics as a JSON object to `stats_json_out`,
+/// which the caller must free with lancedb_free_string.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_table_optimize(
+    handle: *const TableHandle,
+    older_than_secs: i64,
+    stats_json_out: *mut *mut c_char,
+) -> c_int {
+    if handle.is_null() || stats_json_out.is_null() || older_than_secs < 0 {
+        let error_msg =
+            "table handle and stats_json_out cannot be null and older_than_secs must be non-negative";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let table = unsafe { &*handle };
+    let json = match table.optimize(older_than_secs) {
+        Ok(json) => json,
+        Err(err) => {
+            crate::set_last_error(&err);
+            return -1;
+        }
+    };
+
+    let c_string = match CString::new(json) {
+        Ok(s) => s,
+        Err(err) => {
+            let error_msg = format!("{}", err);
+            let c_error = CString::new(error_msg).unwrap();
+            crate::lancedb_set_last_error(c_error.as_ptr());
+            return -1;
+        }
+    };
+
+    unsafe {
+        *stats_json_out = c_string.into_raw();
+    }
+
+    0
+}
+
+/// Get the current version of a table.
+/// Returns the version on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_table_version(handle: *const TableHandle) -> i64 {
+    if handle.is_null() {
+        let error_msg = "table handle cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let table = unsafe { &*handle };
+    match table.version() {
+        Ok(version) => version as i64,
+        Err(err) => {
+            crate::set_last_error(&err);
+            -1
+        }
+    }
+}
+
+/// Get the URI of a table's dataset.
+/// Returns 0 on success, -1 on failure.
+/// uri_out will be populated with the URI.
+/// Caller is responsible for freeing the string with lancedb_free_string.
+#[no_mangle]
+pub extern "C" fn lancedb_table_uri(
+    handle: *const TableHandle,
+    uri_out: *mut *mut c_char,
+) -> c_int {
+    if handle.is_null() || uri_out.is_null() {
+        let error_msg = "table handle and uri_out cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let table = unsafe { &*handle };
+    let c_string = match CString::new(table.uri()) {
+        Ok(s) => s,
+        Err(err) => {
+            let error_msg = format!("{}", err);
+            let c_error = CString::new(error_msg).unwrap();
+            crate::lancedb_set_last_error(c_error.as_ptr());
+            return -1;
+        }
+    };
+
+    unsafe {
+        *uri_out = c_string.into_raw();
+    }
+    0
+}
+
+/// Check out a historical version of a table.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_table_checkout(handle: *const TableHandle, version: u64) -> c_int {
+    if handle.is_null() {
+        let error_msg = "table handle cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let table = unsafe { &*handle };
+    if let Err(err) = table.checkout(version) {
+        let error_msg = format!("checkout of version {} failed: {}", version, err);
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    0
+}
+
+/// Return a table to its latest version after a checkout.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_table_checkout_latest(handle: *const TableHandle) -> c_int {
+    if handle.is_null() {
+        let error_msg = "table handle cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let table = unsafe { &*handle };
+    if let Err(err) = table.checkout_latest() {
+        let error_msg = format!("checkout of latest version failed: {}", err);
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    0
+}
+
+/// Merge key/value pairs into a table's metadata.
+/// metadata_json must be a JSON object whose values are strings.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_table_set_metadata(
+    handle: *const TableHandle,
+    metadata_json: *const c_char,
+) -> c_int {
+    if handle.is_null() || metadata_json.is_null() {
+        let error_msg = "table handle and metadata_json cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let table = unsafe { &*handle };
+    let c_str = unsafe { CStr::from_ptr(metadata_json) };
+    let json = match c_str.to_str() {
+        Ok(s) => s,
+        Err(err) => {
+            let error_msg = format!("{}", err);
+            let c_error = CString::new(error_msg).unwrap();
+            crate::lancedb_set_last_error(c_error.as_ptr());
+            return -1;
+        }
+    };
+
+    match table.set_metadata(json) {
+        Ok(()) => 0,
+        Err(err) => {
+            crate::set_last_error(&err);
+            -1
+        }
+    }
+}
+
+/// Get a table's metadata as a JSON object of strings.
+/// On success, metadata_json_out receives a string the caller must free with lancedb_free_string.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_table_get_metadata(
+    handle: *const TableHandle,
+    metadata_json_out: *mut *mut c_char,
+) -> c_int {
+    if handle.is_null() || metadata_json_out.is_null() {
+        let error_msg = "table handle and metadata_json_out cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let table = unsafe { &*handle };
+    let json = match table.metadata() {
+        Ok(json) => json,
+        Err(err) => {
+            crate::set_last_error(&err);
+            return -1;
+        }
+    };
+
+    let c_string = match CString::new(json) {
+        Ok(s) => s,
+        Err(err) => {
+            let error_msg = format!("{}", err);
+            let c_error = CString::new(error_msg).unwrap();
+            crate::lancedb_set_last_error(c_error.as_ptr());
+            return -1;
+        }
+    };
+
+    unsafe {
+        *metadata_json_out = c_string.into_raw();
+    }
+    0
+}
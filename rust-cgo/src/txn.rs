@@ -0,0 +1,262 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: Copyright The LanceDB Authors
+
+use std::ffi::{CStr, CString};
+use std::os::raw::{c_char, c_int};
+
+use arrow::ffi::FFI_ArrowArray;
+use arrow::ffi::FFI_ArrowSchema;
+use arrow_array::{RecordBatch, RecordBatchIterator};
+use lance::dataset::transaction::Operation;
+use lance::dataset::{write_fragments, Dataset, WriteMode, WriteParams};
+use lance::io::ObjectStoreParams;
+use lancedb::table::Table;
+use url::Url;
+
+use crate::arrow_ffi::import_record_batch_from_c;
+use crate::error::Result;
+use crate::table::TableHandle;
+use crate::RT;
+
+/// Mutations staged against a table, committed together as a single version
+pub struct TxnHandle {
+    table: Table,
+    read_version: u64,
+    deletes: Vec<String>,
+    batches: Vec<RecordBatch>,
+}
+
+impl TxnHandle {
+    /// Start a transaction reading the version the table handle currently sees
+    pub fn begin(table: &TableHandle) -> Result<Self> {
+        let read_version = RT.block_on(table.inner.version())?;
+        Ok(Self {
+            table: table.inner.clone(),
+            read_version,
+            deletes: Vec::new(),
+            batches: Vec::new(),
+        })
+    }
+
+    pub fn add(&mut self, batch: RecordBatch) {
+        self.batches.push(batch);
+    }
+
+    pub fn delete(&mut self, predicate: &str) {
+        self.deletes.push(predicate.to_string());
+    }
+
+    /// Write the staged data and commit every mutation as one Update operation.
+    /// Deletes apply to the rows of the read version, not to rows added in the transaction.
+    /// The dataset is reached through the table's own handle, so the commit uses the
+    /// connection's object store and session (in-memory and object store tables included).
+    /// The staged mutations are only dropped once the commit succeeds, so a failed commit
+    /// can be retried. The table handle isn't moved to the new version; the caller refreshes
+    /// it, and reports a failure to do so apart from the committed write.
+    pub fn commit(&mut self) -> Result<()> {
+        if self.deletes.is_empty() && self.batches.is_empty() {
+            return Ok(());
+        }
+
+        let wrapper = self
+            .table
+            .dataset()
+            .ok_or_else(|| crate::error::Error::InvalidArgument {
+                message: "transactions are not supported on remote tables".to_string(),
+                location: snafu::Location::new(file!(), line!(), column!()),
+            })?;
+
+        RT.block_on(async {
+            let latest = wrapper.get().await?;
+            let dataset = latest.checkout_version(self.read_version).await?;
+            let uri = dataset.uri().to_string();
+
+            // Write through the dataset's object store, in the table's file format. Plain
+            // local paths resolve to the same local filesystem store anyway.
+            let store_params = match Url::parse(&uri) {
+                Ok(base) if base.scheme().len() > 1 => ObjectStoreParams {
+                    object_store: Some((dataset.object_store().inner.clone(), base)),
+                    ..Default::default()
+                },
+                _ => ObjectStoreParams::default(),
+            };
+            let write_params = WriteParams {
+                mode: WriteMode::Append,
+                store_params: Some(store_params.clone()),
+                data_storage_version: Some(
+                    dataset
+                        .manifest()
+                        .data_storage_format
+                        .lance_file_version()?,
+                ),
+                ..Default::default()
+            };
+
+            // Deletes only write deletion files; nothing is visible until the commit
+            let mut removed_fragment_ids = Vec::new();
+            let mut updated_fragments = Vec::new();
+            if !self.deletes.is_empty() {
+                for fragment in dataset.get_fragments() {
+                    let original = fragment.metadata().clone();
+                    let mut current = Some(fragment);
+                    for predicate in &self.deletes {
+                        current = match current {
+                            Some(f) => f.delete(predicate).await?,
+                            None => break,
+                        };
+                    }
+                    match current {
+                        None => removed_fragment_ids.push(original.id),
+                        Some(f) if *f.metadata() != original => {
+                            updated_fragments.push(f.metadata().clone())
+                        }
+                        Some(_) => {}
+                    }
+                }
+            }
+
+            // New rows are written as fragments that no version references yet. The
+            // batches stay staged until the commit succeeds.
+            let mut new_fragments = Vec::new();
+            if let Some(first) = self.batches.first() {
+                let schema = first.schema();
+                let reader =
+                    RecordBatchIterator::new(self.batches.clone().into_iter().map(Ok), schema);
+                new_fragments = write_fragments(&uri, reader, write_params).await?;
+            }
+
+            let operation = Operation::Update {
+                removed_fragment_ids,
+                updated_fragments,
+                new_fragments,
+            };
+            Dataset::commit(
+                &uri,
+                operation,
+                Some(self.read_version),
+                Some(store_params),
+                None,
+                dataset.session(),
+                false,
+            )
+            .await?;
+            Ok::<(), crate::error::Error>(())
+        })?;
+
+        self.deletes.clear();
+        self.batches.clear();
+        Ok(())
+    }
+}
+
+// C API for transactions
+
+/// Begin a transaction on a table.
+/// Returns a transaction handle, or null on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_txn_begin(table: *const TableHandle) -> *mut TxnHandle {
+    if table.is_null() {
+        let error_msg = "table handle cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return std::ptr::null_mut();
+    }
+
+    let table = unsafe { &*table };
+    match TxnHandle::begin(table) {
+        Ok(txn) => Box::into_raw(Box::new(txn)),
+        Err(err) => {
+            crate::set_last_error(&err);
+            std::ptr::null_mut()
+        }
+    }
+}
+
+/// Close a transaction and free resources. Uncommitted mutations are discarded.
+#[no_mangle]
+pub extern "C" fn lancedb_txn_close(handle: *mut TxnHandle) {
+    if !handle.is_null() {
+        unsafe {
+            let _ = Box::from_raw(handle);
+        }
+    }
+}
+
+/// Stage a record batch to be appended when the transaction commits.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_txn_add(
+    handle: *mut TxnHandle,
+    array: *mut FFI_ArrowArray,
+    schema: *mut FFI_ArrowSchema,
+) -> c_int {
+    if handle.is_null() || array.is_null() || schema.is_null() {
+        let error_msg = "transaction handle, array, and schema cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let txn = unsafe { &mut *handle };
+
+    let batch = match unsafe { import_record_batch_from_c(array, schema) } {
+        Ok(b) => b,
+        Err(err) => {
+            let error_msg = format!("{}", err);
+            let c_error = CString::new(error_msg).unwrap();
+            crate::lancedb_set_last_error(c_error.as_ptr());
+            return -1;
+        }
+    };
+
+    txn.add(batch);
+    0
+}
+
+/// Stage a delete of the rows matching predicate.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_txn_delete(handle: *mut TxnHandle, predicate: *const c_char) -> c_int {
+    if handle.is_null() || predicate.is_null() {
+        let error_msg = "transaction handle and predicate cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let txn = unsafe { &mut *handle };
+    let c_str = unsafe { CStr::from_ptr(predicate) };
+    let predicate_str = match c_str.to_str() {
+        Ok(s) => s,
+        Err(err) => {
+            let error_msg = format!("invalid UTF-8 in predicate: {}", err);
+            let c_error = CString::new(error_msg).unwrap();
+            crate::lancedb_set_last_error(c_error.as_ptr());
+            return -1;
+        }
+    };
+
+    txn.delete(predicate_str);
+    0
+}
+
+/// Commit the staged mutations as a single table version.
+/// Returns 0 on success, -1 on failure.
+#[no_mangle]
+pub extern "C" fn lancedb_txn_commit(handle: *mut TxnHandle) -> c_int {
+    if handle.is_null() {
+        let error_msg = "transaction handle cannot be null";
+        let c_error = CString::new(error_msg).unwrap();
+        crate::lancedb_set_last_error(c_error.as_ptr());
+        return -1;
+    }
+
+    let txn = unsafe { &mut *handle };
+    match txn.commit() {
+        Ok(_) => 0,
+        Err(err) => {
+            crate::set_last_error(&err);
+            -1
+        }
+    }
+}
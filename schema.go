@@ -0,0 +1,139 @@
+package lancedb
+
+import (
+	"fmt"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+)
+
+// ValidateRecordAgainstSchema checks that every row of the record's list columns has
+// the number of values declared by the matching fixed-size list column of the schema,
+// such as a FixedSizeListOf(128, Float32) embedding column. The error names the column,
+// the offending row, and the expected and actual lengths.
+//
+// The values of a FixedSizeList column are stored end to end, so a row that was built
+// with too few values shifts every row after it. In that case the first row the
+// remaining values can't fill is reported, which is the short row itself when it's the
+// last one. Columns are matched by name; columns the schema doesn't have are ignored.
+func ValidateRecordAgainstSchema(record arrow.Record, schema *arrow.Schema) error {
+	if record == nil || schema == nil {
+		return &Error{Code: ErrorCodeInvalidArgument, Message: "record and schema cannot be nil"}
+	}
+
+	for _, field := range schema.Fields() {
+		listType, ok := field.Type.(*arrow.FixedSizeListType)
+		if !ok {
+			continue
+		}
+		indices := record.Schema().FieldIndices(field.Name)
+		if len(indices) == 0 {
+			continue
+		}
+		if err := checkListLengths(field.Name, record.Column(indices[0]), int(listType.Len())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkListLengths checks that every non-null row of a list column holds n values
+func checkListLengths(name string, column arrow.Array, n int) error {
+	switch col := column.(type) {
+	case *array.FixedSizeList:
+		size := int(col.DataType().(*arrow.FixedSizeListType).Len())
+		if size != n {
+			if col.Len() == 0 {
+				return nil
+			}
+			return listLengthError(name, 0, size, n)
+		}
+		available := col.ListValues().Len() - col.Data().Offset()*n
+		if available < col.Len()*n {
+			row := available / n
+			return listLengthError(name, row, available-row*n, n)
+		}
+	case array.ListLike:
+		for i := 0; i < col.Len(); i++ {
+			if col.IsNull(i) {
+				continue
+			}
+			start, end := col.ValueOffsets(i)
+			if int(end-start) != n {
+				return listLengthError(name, i, int(end-start), n)
+			}
+		}
+	}
+	return nil
+}
+
+func listLengthError(name string, row, actual, expected int) error {
+	return &Error{Code: ErrorCodeInvalidSchema, Message: fmt.Sprintf("column %q row %d has %d values, expected %d", name, row, actual, expected)}
+}
+
+// checkRecordSchema reports the first column of recordSchema that doesn't match
+// tableSchema: a table column the record lacks, a record column the table lacks,
+// or a column with a different type. Columns are matched by name, so their order
+// doesn't matter.
+func checkRecordSchema(tableSchema, recordSchema *arrow.Schema) error {
+	for _, field := range tableSchema.Fields() {
+		indices := recordSchema.FieldIndices(field.Name)
+		if len(indices) == 0 {
+			return schemaMismatchError("record is missing column %q (%s)", field.Name, field.Type)
+		}
+		recordType := recordSchema.Field(indices[0]).Type
+		if !dataTypesMatch(field.Type, recordType) {
+			return schemaMismatchError("column %q has type %s, table expects %s", field.Name, recordType, field.Type)
+		}
+	}
+
+	for _, field := range recordSchema.Fields() {
+		if !tableSchema.HasField(field.Name) {
+			return schemaMismatchError("record has column %q (%s), which the table doesn't have", field.Name, field.Type)
+		}
+	}
+	return nil
+}
+
+// dataTypesMatch compares two types the way they are stored: the nullability and
+// names of nested fields are ignored, since writers disagree on them, and a
+// dictionary-encoded column matches its value type
+func dataTypesMatch(expected, actual arrow.DataType) bool {
+	if dict, ok := expected.(*arrow.DictionaryType); ok {
+		expected = dict.ValueType
+	}
+	if dict, ok := actual.(*arrow.DictionaryType); ok {
+		actual = dict.ValueType
+	}
+	if expected.ID() != actual.ID() {
+		return false
+	}
+
+	switch e := expected.(type) {
+	case *arrow.FixedSizeListType:
+		a := actual.(*arrow.FixedSizeListType)
+		return e.Len() == a.Len() && dataTypesMatch(e.Elem(), a.Elem())
+	case *arrow.ListType:
+		return dataTypesMatch(e.Elem(), actual.(*arrow.ListType).Elem())
+	case *arrow.LargeListType:
+		return dataTypesMatch(e.Elem(), actual.(*arrow.LargeListType).Elem())
+	case *arrow.StructType:
+		a := actual.(*arrow.StructType)
+		if e.NumFields() != a.NumFields() {
+			return false
+		}
+		for _, field := range e.Fields() {
+			other, ok := a.FieldByName(field.Name)
+			if !ok || !dataTypesMatch(field.Type, other.Type) {
+				return false
+			}
+		}
+		return true
+	default:
+		return arrow.TypeEqual(expected, actual)
+	}
+}
+
+func schemaMismatchError(format string, args ...interface{}) error {
+	return &Error{Code: ErrorCodeInvalidSchema, Message: "record does not match table schema: " + fmt.Sprintf(format, args...)}
+}
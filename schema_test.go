@@ -0,0 +1,392 @@
+package lancedb
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+var schemaCheckSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "name", Type: arrow.BinaryTypes.String},
+	{Name: "vector", Type: arrow.FixedSizeListOf(4, arrow.PrimitiveTypes.Float32)},
+}, nil)
+
+// createSchemaCheckTable creates an empty table with schemaCheckSchema
+func createSchemaCheckTable(t *testing.T) (*Connection, *Table) {
+	db, err := Connect(filepath.Join(t.TempDir(), "schema_db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	table, err := db.CreateTableWithSchema("schema_table", schemaCheckSchema)
+	if err != nil {
+		db.Close()
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	return db, table
+}
+
+// buildRecord builds a one-row record for the schema
+func buildRecord(t *testing.T, schema *arrow.Schema) arrow.Record {
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer builder.Release()
+
+	for i, field := range schema.Fields() {
+		switch b := builder.Field(i).(type) {
+		case *array.Int64Builder:
+			b.Append(1)
+		case *array.Int32Builder:
+			b.Append(1)
+		case *array.StringBuilder:
+			b.Append("row")
+		case *array.FixedSizeListBuilder:
+			b.Append(true)
+			n := int(field.Type.(*arrow.FixedSizeListType).Len())
+			for j := 0; j < n; j++ {
+				b.ValueBuilder().(*array.Float32Builder).Append(0.5)
+			}
+		default:
+			t.Fatalf("Unsupported builder for column %s", field.Name)
+		}
+	}
+	return builder.NewRecord()
+}
+
+// TestAddValidatesSchema tests that Add rejects mismatched records with a precise error
+func TestAddValidatesSchema(t *testing.T) {
+	db, table := createSchemaCheckTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	tests := []struct {
+		name   string
+		schema *arrow.Schema
+		want   string
+	}{
+		{
+			name: "missing column",
+			schema: arrow.NewSchema([]arrow.Field{
+				{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+				{Name: "vector", Type: arrow.FixedSizeListOf(4, arrow.PrimitiveTypes.Float32)},
+			}, nil),
+			want: `missing column "name"`,
+		},
+		{
+			name: "type mismatch",
+			schema: arrow.NewSchema([]arrow.Field{
+				{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+				{Name: "name", Type: arrow.BinaryTypes.String},
+				{Name: "vector", Type: arrow.FixedSizeListOf(4, arrow.PrimitiveTypes.Float32)},
+			}, nil),
+			want: `column "id" has type int32, table expects int64`,
+		},
+		{
+			name: "wrong vector length",
+			schema: arrow.NewSchema([]arrow.Field{
+				{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+				{Name: "name", Type: arrow.BinaryTypes.String},
+				{Name: "vector", Type: arrow.FixedSizeListOf(3, arrow.PrimitiveTypes.Float32)},
+			}, nil),
+			want: `column "vector"`,
+		},
+		{
+			name: "extra column",
+			schema: arrow.NewSchema([]arrow.Field{
+				{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+				{Name: "name", Type: arrow.BinaryTypes.String},
+				{Name: "vector", Type: arrow.FixedSizeListOf(4, arrow.PrimitiveTypes.Float32)},
+				{Name: "score", Type: arrow.PrimitiveTypes.Int32},
+			}, nil),
+			want: `column "score"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := buildRecord(t, tt.schema)
+			defer record.Release()
+
+			err := table.Add(record, AddModeAppend)
+			if !errors.Is(err, ErrInvalidSchema) {
+				t.Fatalf("Expected ErrInvalidSchema, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error to contain %q, got %q", tt.want, err.Error())
+			}
+		})
+	}
+
+	// Columns may come in any order
+	reordered := arrow.NewSchema([]arrow.Field{
+		{Name: "vector", Type: arrow.FixedSizeListOf(4, arrow.PrimitiveTypes.Float32)},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+	if err := checkRecordSchema(schemaCheckSchema, reordered); err != nil {
+		t.Errorf("Expected reordered columns to match, got %v", err)
+	}
+}
+
+// TestAddSchemaCacheInvalidatedByOverwrite tests that appends follow a schema replaced by overwrite
+func TestAddSchemaCacheInvalidatedByOverwrite(t *testing.T) {
+	db, table := createSchemaCheckTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	original := buildRecord(t, schemaCheckSchema)
+	defer original.Release()
+	if err := table.Add(original, AddModeAppend); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	replaced := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+	}, nil)
+	record := buildRecord(t, replaced)
+	defer record.Release()
+	if err := table.Add(record, AddModeOverwrite); err != nil {
+		t.Fatalf("Overwrite failed: %v", err)
+	}
+
+	// The new schema is used for validation, and the old one is rejected
+	if err := table.Add(record, AddModeAppend); err != nil {
+		t.Fatalf("Append with the new schema failed: %v", err)
+	}
+	if err := table.Add(original, AddModeAppend); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("Expected ErrInvalidSchema for the old schema, got %v", err)
+	}
+}
+
+// TestDataTypesMatch tests that nested field names and nullability are ignored
+func TestDataTypesMatch(t *testing.T) {
+	nonNullable := arrow.FixedSizeListOfField(4, arrow.Field{Name: "element", Type: arrow.PrimitiveTypes.Float32})
+	if !dataTypesMatch(arrow.FixedSizeListOf(4, arrow.PrimitiveTypes.Float32), nonNullable) {
+		t.Error("Expected lists differing only in element field to match")
+	}
+	if dataTypesMatch(arrow.FixedSizeListOf(4, arrow.PrimitiveTypes.Float32), arrow.FixedSizeListOf(4, arrow.PrimitiveTypes.Float64)) {
+		t.Error("Expected lists with different element types not to match")
+	}
+	if !dataTypesMatch(arrow.BinaryTypes.String, DictionaryStringType) {
+		t.Error("Expected a dictionary column to match its value type")
+	}
+}
+
+var embeddingSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "embedding", Type: arrow.FixedSizeListOf(128, arrow.PrimitiveTypes.Float32)},
+}, nil)
+
+// buildEmbeddingRecord builds a record with one row per length, each embedding
+// holding that many values
+func buildEmbeddingRecord(lengths []int) arrow.Record {
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), embeddingSchema)
+	defer builder.Release()
+
+	idBuilder := builder.Field(0).(*array.Int64Builder)
+	embeddingBuilder := builder.Field(1).(*array.FixedSizeListBuilder)
+	valueBuilder := embeddingBuilder.ValueBuilder().(*array.Float32Builder)
+	for i, n := range lengths {
+		idBuilder.Append(int64(i))
+		embeddingBuilder.Append(true)
+		for j := 0; j < n; j++ {
+			valueBuilder.Append(float32(j))
+		}
+	}
+	return builder.NewRecord()
+}
+
+// TestValidateRecordAgainstSchema tests that short embeddings are reported by row
+func TestValidateRecordAgainstSchema(t *testing.T) {
+	valid := buildEmbeddingRecord([]int{128, 128, 128})
+	defer valid.Release()
+	if err := ValidateRecordAgainstSchema(valid, embeddingSchema); err != nil {
+		t.Fatalf("Expected a valid record, got %v", err)
+	}
+
+	short := buildEmbeddingRecord([]int{128, 128, 127})
+	defer short.Release()
+	err := ValidateRecordAgainstSchema(short, embeddingSchema)
+	if !errors.Is(err, ErrInvalidSchema) {
+		t.Fatalf("Expected ErrInvalidSchema, got %v", err)
+	}
+	if want := `column "embedding" row 2 has 127 values, expected 128`; !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected error to contain %q, got %q", want, err.Error())
+	}
+
+	// Variable-length lists are checked row by row
+	listSchema := arrow.NewSchema([]arrow.Field{
+		{Name: "embedding", Type: arrow.ListOf(arrow.PrimitiveTypes.Float32)},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), listSchema)
+	defer builder.Release()
+	listBuilder := builder.Field(0).(*array.ListBuilder)
+	for _, n := range []int{128, 127, 128} {
+		listBuilder.Append(true)
+		for j := 0; j < n; j++ {
+			listBuilder.ValueBuilder().(*array.Float32Builder).Append(float32(j))
+		}
+	}
+	lists := builder.NewRecord()
+	defer lists.Release()
+	err = ValidateRecordAgainstSchema(lists, embeddingSchema)
+	if err == nil || !strings.Contains(err.Error(), `column "embedding" row 1 has 127 values, expected 128`) {
+		t.Errorf("Expected row 1 to be reported, got %v", err)
+	}
+
+	if err := ValidateRecordAgainstSchema(nil, embeddingSchema); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument for a nil record, got %v", err)
+	}
+}
+
+// TestAddRejectsShortEmbedding tests that Add reports the row with a short embedding
+func TestAddRejectsShortEmbedding(t *testing.T) {
+	db, err := Connect(filepath.Join(t.TempDir(), "embedding_db"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	table, err := db.CreateTableWithSchema("embeddings", embeddingSchema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	record := buildEmbeddingRecord([]int{128, 128, 128, 127})
+	defer record.Release()
+
+	err = table.Add(record, AddModeAppend)
+	if !errors.Is(err, ErrInvalidSchema) {
+		t.Fatalf("Expected ErrInvalidSchema, got %v", err)
+	}
+	if want := "row 3 has 127 values, expected 128"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected error to contain %q, got %q", want, err.Error())
+	}
+
+	count, err := table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no rows to be written, got %d", count)
+	}
+}
+
+// TestAddMergeAppendEvolvesSchema tests that AddModeMergeAppend adds new nullable columns
+// and fills columns missing from the record with nulls
+func TestAddMergeAppendEvolvesSchema(t *testing.T) {
+	db, table := createSchemaCheckTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	original := buildRecord(t, schemaCheckSchema)
+	defer original.Release()
+	if err := table.Add(original, AddModeAppend); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	// A new column must be nullable, since existing rows have no value for it
+	required := arrow.NewSchema(append(schemaCheckSchema.Fields(),
+		arrow.Field{Name: "score", Type: arrow.PrimitiveTypes.Int32}), nil)
+	record := buildRecord(t, required)
+	defer record.Release()
+	if err := table.Add(record, AddModeMergeAppend); !errors.Is(err, ErrInvalidSchema) {
+		t.Fatalf("Expected ErrInvalidSchema for a non-nullable new column, got %v", err)
+	}
+
+	extended := arrow.NewSchema(append(schemaCheckSchema.Fields(),
+		arrow.Field{Name: "score", Type: arrow.PrimitiveTypes.Int32, Nullable: true}), nil)
+	extra := buildRecord(t, extended)
+	defer extra.Release()
+	if err := table.Add(extra, AddModeMergeAppend); err != nil {
+		t.Fatalf("Merge append failed: %v", err)
+	}
+
+	schema, err := table.Schema()
+	if err != nil {
+		t.Fatalf("Failed to get schema: %v", err)
+	}
+	if !schema.HasField("score") {
+		t.Fatalf("Expected the table schema to have the score column, got %v", schema)
+	}
+
+	// The original record now lacks score, which is filled with null
+	if err := table.Add(original, AddModeMergeAppend); err != nil {
+		t.Fatalf("Merge append without the new column failed: %v", err)
+	}
+
+	records, err := table.ToArrow(-1)
+	if err != nil {
+		t.Fatalf("Failed to read table: %v", err)
+	}
+	rows, nulls := 0, 0
+	for _, rec := range records {
+		scores := rec.Column(rec.Schema().FieldIndices("score")[0]).(*array.Int32)
+		for i := 0; i < scores.Len(); i++ {
+			rows++
+			if scores.IsNull(i) {
+				nulls++
+			} else if scores.Value(i) != 1 {
+				t.Errorf("Expected score 1, got %d", scores.Value(i))
+			}
+		}
+		rec.Release()
+	}
+	if rows != 3 || nulls != 2 {
+		t.Errorf("Expected 3 rows with 2 null scores, got %d rows with %d nulls", rows, nulls)
+	}
+}
+
+// TestAddMergeAppendConcurrentWithAppends tests that appends through the same handle don't
+// interleave with a merge append that adds a column
+func TestAddMergeAppendConcurrentWithAppends(t *testing.T) {
+	db, table := createSchemaCheckTable(t)
+	defer db.Close()
+	defer table.Close()
+
+	original := buildRecord(t, schemaCheckSchema)
+	defer original.Release()
+	extended := arrow.NewSchema(append(schemaCheckSchema.Fields(),
+		arrow.Field{Name: "score", Type: arrow.PrimitiveTypes.Int32, Nullable: true}), nil)
+	extra := buildRecord(t, extended)
+	defer extra.Release()
+
+	const appends = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, appends+1)
+	for i := 0; i < appends; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- table.Add(original, AddModeMergeAppend)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs <- table.Add(extra, AddModeMergeAppend)
+	}()
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Concurrent add failed: %v", err)
+		}
+	}
+
+	count, err := table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != appends+1 {
+		t.Errorf("Expected %d rows, got %d", appends+1, count)
+	}
+}
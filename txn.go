@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: Copyright The LanceDB Authors
+
+package lancedb
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+
+// Arrow C Data Interface structures
+// See: https://arrow.apache.org/docs/format/CDataInterface.html
+
+struct ArrowSchema {
+    const char* format;
+    const char* name;
+    const char* metadata;
+    int64_t flags;
+    int64_t n_children;
+    struct ArrowSchema** children;
+    struct ArrowSchema* dictionary;
+    void (*release)(struct ArrowSchema*);
+    void* private_data;
+};
+
+struct ArrowArray {
+    int64_t length;
+    int64_t null_count;
+    int64_t offset;
+    int64_t n_buffers;
+    int64_t n_children;
+    const void** buffers;
+    struct ArrowArray** children;
+    struct ArrowArray* dictionary;
+    void (*release)(struct ArrowArray*);
+    void* private_data;
+};
+
+
+typedef void* TableHandle;
+typedef void* TxnHandle;
+
+extern TxnHandle lancedb_txn_begin(TableHandle);
+extern void lancedb_txn_close(TxnHandle);
+extern int lancedb_txn_add(TxnHandle, struct ArrowArray*, struct ArrowSchema*);
+extern int lancedb_txn_delete(TxnHandle, const char* predicate);
+extern int lancedb_txn_commit(TxnHandle);
+*/
+import "C"
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/apache/arrow/go/v17/arrow"
+)
+
+// Txn stages mutations of a table and commits them as a single table version, so
+// readers see either none of them or all of them. Deletes apply to the rows committed
+// before Begin; rows added in the same transaction are never deleted by it. Nothing is
+// written until Commit. If another writer changed the same rows since Begin, Commit
+// fails and the table is unchanged.
+//
+// Always end a transaction with Commit or Rollback:
+//
+//	txn, err := table.Begin()
+//	if err != nil {
+//	    return err
+//	}
+//	defer txn.Rollback()
+//	// ... txn.Delete, txn.Add ...
+//	return txn.Commit()
+type Txn struct {
+	mu     sync.Mutex
+	handle C.TxnHandle
+	table  *Table
+}
+
+// Begin starts a transaction on the table version the handle currently reads
+func (t *Table) Begin() (*Txn, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.handle == nil {
+		return nil, &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	handle := C.lancedb_txn_begin(C.TableHandle(t.handle))
+	if handle == nil {
+		return nil, getLastError()
+	}
+
+	txn := &Txn{handle: handle, table: t}
+	runtime.SetFinalizer(txn, (*Txn).Rollback)
+	return txn, nil
+}
+
+// Add stages a record to be appended on commit. Like Table.Add with AddModeAppend,
+// the record must match the table's schema. The record may be released after Add returns.
+func (tx *Txn) Add(record arrow.Record) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.handle == nil {
+		return errTxnFinished
+	}
+	if record == nil {
+		return &Error{Code: ErrorCodeInvalidArgument, Message: "record cannot be nil"}
+	}
+
+	tx.table.mu.RLock()
+	defer tx.table.mu.RUnlock()
+
+	if tx.table.handle == nil {
+		return &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+	if err := tx.table.validateRecord(record); err != nil {
+		return err
+	}
+
+	cArray, cSchema, err := RecordToC(record)
+	if err != nil {
+		return err
+	}
+	defer ReleaseArrowArray(cArray)
+	defer ReleaseArrowSchema(cSchema)
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_txn_add(tx.handle, cArray, cSchema)
+	if int(result) != 0 {
+		return getLastError()
+	}
+	return nil
+}
+
+// Delete stages a delete of the rows matching predicate, evaluated against the rows
+// committed before Begin
+func (tx *Txn) Delete(predicate string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.handle == nil {
+		return errTxnFinished
+	}
+	if predicate == "" {
+		return &Error{Code: ErrorCodeInvalidArgument, Message: "predicate cannot be empty"}
+	}
+
+	cPredicate := C.CString(predicate)
+	defer C.free(unsafe.Pointer(cPredicate))
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_txn_delete(tx.handle, cPredicate)
+	if int(result) != 0 {
+		return getLastError()
+	}
+	return nil
+}
+
+// Commit applies the staged mutations as a single new table version and ends the
+// transaction. A transaction with nothing staged commits no version. After a failed
+// commit the table is unchanged and the transaction stays open with its mutations
+// staged: Commit may be retried, and Rollback discards them.
+//
+// Like Add and Delete, Commit then refreshes the table handle (see SetAutoRefresh). If
+// only that fails, the transaction has ended and a *RefreshError is returned; the commit
+// must not be retried.
+func (tx *Txn) Commit() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.handle == nil {
+		return errTxnFinished
+	}
+
+	tx.table.mu.RLock()
+	defer tx.table.mu.RUnlock()
+
+	if tx.table.handle == nil {
+		return &Error{Code: ErrorCodeClosed, Message: "table is closed"}
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.lancedb_txn_commit(tx.handle)
+	if int(result) != 0 {
+		return getLastError()
+	}
+	tx.close()
+	return tx.table.autoRefresh()
+}
+
+// Rollback discards the staged mutations and ends the transaction. It does nothing if
+// the transaction has already ended, so it is safe to defer.
+func (tx *Txn) Rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.close()
+	return nil
+}
+
+// close frees the native transaction; the caller must hold tx.mu
+func (tx *Txn) close() {
+	if tx.handle != nil {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		C.lancedb_txn_close(tx.handle)
+		tx.handle = nil
+	}
+}
+
+var errTxnFinished = &Error{Code: ErrorCodeClosed, Message: "transaction is already committed or rolled back"}
@@ -0,0 +1,294 @@
+package lancedb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+// buildUpdatedRows builds rows with ids [0, n) in the "updated" category
+func buildUpdatedRows(table *Table, n int) (arrow.Record, error) {
+	schema, err := table.Schema()
+	if err != nil {
+		return nil, err
+	}
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer builder.Release()
+
+	for i := 0; i < n; i++ {
+		builder.Field(0).(*array.Int32Builder).Append(int32(i))
+		builder.Field(1).(*array.StringBuilder).Append(fmt.Sprintf("doc_%d_v2", i))
+		builder.Field(2).(*array.StringBuilder).Append("updated")
+	}
+	return builder.NewRecord(), nil
+}
+
+// TestTxnCommitsOneVersion tests that a delete and an insert land together as one version
+func TestTxnCommitsOneVersion(t *testing.T) {
+	dbPath := "./test_txn_commit_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	before, err := table.Version()
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+
+	record, err := buildUpdatedRows(table, 10)
+	if err != nil {
+		t.Fatalf("Failed to build record: %v", err)
+	}
+	defer record.Release()
+
+	txn, err := table.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer txn.Rollback()
+
+	if err := txn.Delete("id < 10"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := txn.Add(record); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	// A reader sees none of the staged mutations before the commit
+	reader, err := db.OpenTable("test_table")
+	if err != nil {
+		t.Fatalf("Failed to open table: %v", err)
+	}
+	defer reader.Close()
+	if count, err := reader.CountRowsWhere("id < 10 AND category = 'old'"); err != nil || count != 10 {
+		t.Fatalf("Expected the 10 original rows before commit, got %d (%v)", count, err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	after, err := table.Version()
+	if err != nil {
+		t.Fatalf("Failed to get version after commit: %v", err)
+	}
+	if after != before+1 {
+		t.Fatalf("Expected the commit to add exactly one version after %d, got %d", before, after)
+	}
+
+	if err := reader.CheckoutLatest(); err != nil {
+		t.Fatalf("CheckoutLatest failed: %v", err)
+	}
+	count, err := reader.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 100 {
+		t.Errorf("Expected 100 rows after replacing 10, got %d", count)
+	}
+	updated, err := reader.CountRowsWhere("category = 'updated'")
+	if err != nil {
+		t.Fatalf("Failed to count updated rows: %v", err)
+	}
+	if updated != 10 {
+		t.Errorf("Expected 10 updated rows, got %d", updated)
+	}
+
+	// The transaction has ended
+	if err := txn.Delete("id = 1"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after commit, got %v", err)
+	}
+}
+
+// TestTxnRollback tests that a rolled back transaction leaves the table unchanged
+func TestTxnRollback(t *testing.T) {
+	dbPath := "./test_txn_rollback_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	before, err := table.Version()
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+
+	txn, err := table.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := txn.Delete("id < 50"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := txn.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if err := txn.Commit(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed committing a rolled back transaction, got %v", err)
+	}
+
+	after, err := table.Version()
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+	if after != before {
+		t.Errorf("Expected version %d after rollback, got %d", before, after)
+	}
+	count, err := table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 100 {
+		t.Errorf("Expected 100 rows after rollback, got %d", count)
+	}
+}
+
+// TestTxnFailedCommitStaysOpen tests that a conflicting commit leaves the table unchanged
+// and the transaction open
+func TestTxnFailedCommitStaysOpen(t *testing.T) {
+	dbPath := "./test_txn_conflict_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	txn, err := table.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer txn.Rollback()
+	if err := txn.Delete("id < 50"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// Another writer deletes some of the same rows first
+	if err := table.Delete("id < 10"); err != nil {
+		t.Fatalf("Concurrent delete failed: %v", err)
+	}
+	version, err := table.Version()
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+
+	if err := txn.Commit(); err == nil {
+		t.Fatal("Expected the conflicting commit to fail")
+	}
+	if after, err := table.Version(); err != nil || after != version {
+		t.Fatalf("Expected version %d after the failed commit, got %d (%v)", version, after, err)
+	}
+	if count, err := table.CountRows(); err != nil || count != 90 {
+		t.Fatalf("Expected 90 rows after the failed commit, got %d (%v)", count, err)
+	}
+
+	// The transaction is still open until rolled back
+	if err := txn.Delete("id = 99"); err != nil {
+		t.Fatalf("Expected the transaction to stay open after a failed commit, got %v", err)
+	}
+	if err := txn.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if err := txn.Commit(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Rollback, got %v", err)
+	}
+}
+
+// TestTxnInMemory tests transactions on a table of an in-memory connection
+func TestTxnInMemory(t *testing.T) {
+	db, err := ConnectMemory()
+	if err != nil {
+		t.Fatalf("ConnectMemory failed: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "category", Type: arrow.BinaryTypes.String},
+	}, nil)
+	table, err := db.CreateTableWithSchema("test_table", schema)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer table.Close()
+
+	record, err := buildUpdatedRows(table, 5)
+	if err != nil {
+		t.Fatalf("Failed to build record: %v", err)
+	}
+	defer record.Release()
+	if err := table.Add(record, AddModeAppend); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	txn, err := table.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer txn.Rollback()
+	if err := txn.Delete("id < 2"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := txn.Add(record); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	count, err := table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 8 {
+		t.Errorf("Expected 8 rows after the transaction, got %d", count)
+	}
+}
+
+// TestTxnCommitHonorsAutoRefresh tests that a commit refreshes the handle like Add does,
+// unless auto-refresh is disabled
+func TestTxnCommitHonorsAutoRefresh(t *testing.T) {
+	dbPath := "./test_txn_refresh_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	table.SetAutoRefresh(false)
+	before, err := table.Version()
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+
+	txn, err := table.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer txn.Rollback()
+	if err := txn.Delete("id < 10"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// The handle still reads the version it had until refreshed
+	if version, err := table.Version(); err != nil || version != before {
+		t.Fatalf("Expected version %d before Refresh, got %d (%v)", before, version, err)
+	}
+	if err := table.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if count, err := table.CountRows(); err != nil || count != 90 {
+		t.Fatalf("Expected 90 rows after Refresh, got %d (%v)", count, err)
+	}
+}
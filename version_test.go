@@ -0,0 +1,290 @@
+package lancedb
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+// TestTableVersion tests that writes advance the table version
+func TestTableVersion(t *testing.T) {
+	dbPath := "./test_table_version_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	before, err := table.Version()
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+	if before == 0 {
+		t.Fatalf("Expected a positive version, got %d", before)
+	}
+
+	if err := table.Delete("id < 10"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	after, err := table.Version()
+	if err != nil {
+		t.Fatalf("Failed to get version after delete: %v", err)
+	}
+	if after <= before {
+		t.Fatalf("Expected version to advance past %d, got %d", before, after)
+	}
+}
+
+// TestTableCheckout tests reading a historical version and returning to the latest
+func TestTableCheckout(t *testing.T) {
+	dbPath := "./test_table_checkout_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	version, err := table.Version()
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+
+	if err := table.Delete("id >= 50"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// The old version still has all rows
+	if err := table.Checkout(version); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	count, err := table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 100 {
+		t.Fatalf("Expected 100 rows at version %d, got %d", version, count)
+	}
+
+	// Other handles still see the latest data
+	other, err := db.OpenTable("test_table")
+	if err != nil {
+		t.Fatalf("Failed to open table: %v", err)
+	}
+	defer other.Close()
+	count, err = other.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 50 {
+		t.Fatalf("Expected 50 rows on a separate handle, got %d", count)
+	}
+
+	// Writes are rejected while a historical version is checked out
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "category", Type: arrow.BinaryTypes.String},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer builder.Release()
+	builder.Field(0).(*array.Int32Builder).Append(1000)
+	builder.Field(1).(*array.StringBuilder).Append("doc_1000")
+	builder.Field(2).(*array.StringBuilder).Append("new")
+	record := builder.NewRecord()
+	defer record.Release()
+	if err := table.Add(record, AddModeAppend); err == nil {
+		t.Fatal("Expected Add to fail on a checked-out version")
+	}
+
+	if err := table.CheckoutLatest(); err != nil {
+		t.Fatalf("CheckoutLatest failed: %v", err)
+	}
+	count, err = table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 50 {
+		t.Fatalf("Expected 50 rows at the latest version, got %d", count)
+	}
+}
+
+// TestTableCheckoutInvalidVersion tests checking out a version that doesn't exist
+func TestTableCheckoutInvalidVersion(t *testing.T) {
+	dbPath := "./test_table_checkout_invalid_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	if err := table.Checkout(1_000_000); err == nil {
+		t.Fatal("Expected checkout of a nonexistent version to fail")
+	}
+}
+
+// TestVersionClosedTable tests versioning calls on a closed table
+func TestVersionClosedTable(t *testing.T) {
+	dbPath := "./test_version_closed_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	table.Close()
+
+	if _, err := table.Version(); err == nil {
+		t.Fatal("Expected error from Version on a closed table")
+	}
+	if err := table.Checkout(1); err == nil {
+		t.Fatal("Expected error from Checkout on a closed table")
+	}
+	if err := table.CheckoutLatest(); err == nil {
+		t.Fatal("Expected error from CheckoutLatest on a closed table")
+	}
+}
+
+// TestTableOptimize tests compacting small appends and pruning old versions
+func TestTableOptimize(t *testing.T) {
+	dbPath := "./test_table_optimize_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	// Each append writes its own small fragment and version
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "category", Type: arrow.BinaryTypes.String},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer builder.Release()
+	for i := 0; i < 5; i++ {
+		builder.Field(0).(*array.Int32Builder).Append(int32(100 + i))
+		builder.Field(1).(*array.StringBuilder).Append("extra")
+		builder.Field(2).(*array.StringBuilder).Append("new")
+		record := builder.NewRecord()
+		err := table.Add(record, AddModeAppend)
+		record.Release()
+		if err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+	}
+
+	firstVersion := uint64(1)
+	stats, err := table.Optimize(0)
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	if stats.FragmentsRemoved <= stats.FragmentsAdded {
+		t.Errorf("Expected compaction to reduce fragments, removed %d added %d", stats.FragmentsRemoved, stats.FragmentsAdded)
+	}
+	if stats.VersionsRemoved == 0 {
+		t.Error("Expected old versions to be pruned")
+	}
+
+	count, err := table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 105 {
+		t.Errorf("Expected 105 rows after optimize, got %d", count)
+	}
+
+	if err := table.Checkout(firstVersion); err == nil {
+		t.Error("Expected checkout of a pruned version to fail")
+	}
+
+	if _, err := table.Optimize(-1); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Expected ErrInvalidArgument for a negative duration, got %v", err)
+	}
+
+	table.Close()
+	if _, err := table.Optimize(0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed on a closed table, got %v", err)
+	}
+}
+
+// TestAddReadYourWrites tests that reads on the writing handle see its writes without reopening
+func TestAddReadYourWrites(t *testing.T) {
+	dbPath := "./test_read_your_writes_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	record, err := buildUpdatedRows(table, 10)
+	if err != nil {
+		t.Fatalf("Failed to build record: %v", err)
+	}
+	defer record.Release()
+	if err := table.Add(record, AddModeAppend); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	count, err := table.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 110 {
+		t.Errorf("Expected 110 rows after adding 10, got %d", count)
+	}
+
+	query := table.Query().Where("category = 'updated'")
+	defer query.Close()
+	records, err := query.Execute()
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var rows int64
+	for _, rec := range records {
+		rows += rec.NumRows()
+		rec.Release()
+	}
+	if rows != 10 {
+		t.Errorf("Expected the query to find the 10 added rows, got %d", rows)
+	}
+
+	if err := table.Delete("category = 'updated'"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if count, err := table.CountRows(); err != nil || count != 100 {
+		t.Errorf("Expected 100 rows after the delete, got %d (%v)", count, err)
+	}
+}
+
+// TestRefreshSeesOtherHandles tests that Refresh picks up writes made through another handle
+func TestRefreshSeesOtherHandles(t *testing.T) {
+	dbPath := "./test_refresh_db"
+	defer os.RemoveAll(dbPath)
+
+	db, table := createTestTableWithData(t, dbPath, "test_table")
+	defer db.Close()
+	defer table.Close()
+
+	reader, err := db.OpenTable("test_table")
+	if err != nil {
+		t.Fatalf("Failed to open table: %v", err)
+	}
+	defer reader.Close()
+
+	if err := table.Delete("id < 10"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := reader.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	count, err := reader.CountRows()
+	if err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 90 {
+		t.Errorf("Expected 90 rows after refreshing, got %d", count)
+	}
+}